@@ -18,7 +18,9 @@ type EventStream interface {
 	SaveEvent(topic string, eventData interface{}, inTx func() error) error
 }
 
-// SaveEvent writes an event to a service's database using a standardised format.
+// SaveEvent writes an event to a service's database using a
+// standardised format. The event is left unpublished, to be picked
+// up by the service's EventRelay once the transaction commits.
 func SaveEvent(tx *sqlx.Tx, topic string, eventData interface{}, inTx func() error) error {
 	d, _ := json.Marshal(eventData)
 	_, err := tx.Exec(`INSERT INTO events (label, event_data) VALUES ($1, $2)`,
@@ -30,14 +32,12 @@ func SaveEvent(tx *sqlx.Tx, topic string, eventData interface{}, inTx func() err
 }
 
 // Emit emits an event, writing it to the local event log (stored in
-// the "events" table in the service database) and sending it out on
-// the Pub/Sub service.
+// the "events" table in the service database). The event log acts as
+// a transactional outbox: events are sent out on the Pub/Sub service
+// by the EventRelay started with Server.StartEventRelay, so an event
+// is published if and only if it was committed.
 func Emit(str EventStream, topic string, eventData interface{}) error {
-	// Save event to database and publish within a single transaction.
-	err := str.SaveEvent(topic, eventData, func() error {
-		// Publish to Pub/Sub within transaction.
-		return str.Publish(topic, eventData)
-	})
+	err := str.SaveEvent(topic, eventData, nil)
 	if err != nil {
 		d, _ := json.Marshal(eventData)
 		log.Error().
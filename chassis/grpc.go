@@ -0,0 +1,27 @@
+package chassis
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// AuthUnaryInterceptor is the gRPC equivalent of the AuthCtx
+// middleware. It injects authentication information into the request
+// context, taken from the "x-auth-method", "x-auth-user-id" and
+// "x-auth-is-admin" metadata keys, which have the same meanings as the
+// corresponding inter-service HTTP headers.
+func AuthUnaryInterceptor(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	get := func(key string) string {
+		if vals := md.Get(key); len(vals) > 0 {
+			return vals[0]
+		}
+		return ""
+	}
+	authInfo := parseAuthInfo(get("x-auth-method"),
+		get("x-auth-user-id"), get("x-auth-is-admin"))
+	return handler(NewAuthContext(ctx, authInfo), req)
+}
@@ -1,8 +1,11 @@
 package chassis
 
 import (
-	"github.com/segmentio/ksuid"
+	crand "crypto/rand"
+	"math/big"
 	"math/rand"
+
+	"github.com/segmentio/ksuid"
 )
 
 // NewID creates a new random ID prefixed by an entity type indicator.
@@ -33,6 +36,22 @@ func NewBareID(len int) string {
 	return RandString(idChars, idCharIdxBits, idCharIdxMask, idCharIdxMax, len)
 }
 
+// NewSecretID creates a new random ID using a cryptographically
+// secure random source, for use as a credential (API keys and
+// secrets, for example).
+func NewSecretID(n int) (string, error) {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(idChars)))
+	for i := range b {
+		idx, err := crand.Int(crand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = idChars[idx.Int64()]
+	}
+	return string(b), nil
+}
+
 func NewPurchaseID() string {
 	prefix := RandString(letters, idCharIdxBits, idCharIdxMask, idCharIdxMax, 3)
 	dig := RandString(digits, idCharIdxBits, idCharIdxMask, idCharIdxMax, 6)
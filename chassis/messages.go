@@ -3,6 +3,7 @@ package chassis
 import (
 	"bytes"
 	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
 	"github.com/jmoiron/sqlx/types"
 )
@@ -52,6 +53,36 @@ type GenericEmailMsg struct {
 	Data GenericMap `json:"data"`
 }
 
+// EmailAttachment is a file attached to an email, with its content
+// base64-encoded.
+type EmailAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"`
+}
+
+// NewEmailAttachment creates an attachment from the content of a file.
+func NewEmailAttachment(filename, contentType string, content []byte) EmailAttachment {
+	return EmailAttachment{
+		Filename:    filename,
+		ContentType: contentType,
+		Content:     base64.StdEncoding.EncodeToString(content),
+	}
+}
+
+// Attach adds files to the attachments of an email. Attachments are
+// passed to the email service in the "attachments" data field.
+func (msg *GenericEmailMsg) Attach(atts ...EmailAttachment) {
+	if len(atts) == 0 {
+		return
+	}
+	if msg.Data == nil {
+		msg.Data = GenericMap{}
+	}
+	existing, _ := msg.Data["attachments"].([]EmailAttachment)
+	msg.Data["attachments"] = append(existing, atts...)
+}
+
 func (msg *GenericEmailMsg) MarshalJSON() ([]byte, error) {
 	// Marshal fixed fields.
 	jsonFixed, err := json.Marshal(msg.FixedFields)
@@ -202,53 +202,27 @@ const (
 // authenticated user is an administrator.
 func AuthCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authMethod := NoAuth
-		userID := ""
-		isAdmin := false
-
-		// Authentication method header.
-		methodHeader, ok := r.Header["X-Auth-Method"]
-		isServiceClient := false
-		if ok && len(methodHeader) > 0 {
-			switch methodHeader[0] {
-			default:
-				authMethod = NoAuth
-			case "session":
-				authMethod = SessionAuth
-			case "api-key":
-				authMethod = APIKeyAuth
-			case "service-client":
-				isServiceClient = true
-				authMethod = SessionAuth
-				userID = "service-client"
-				isAdmin = true
-			}
-		}
-
-		// User ID header.
-		if !isServiceClient {
-			userIDHeader, ok := r.Header["X-Auth-User-Id"]
-			if ok && len(userIDHeader) > 0 {
-				userID = userIDHeader[0]
-			}
-		}
-
-		// Administrator status header.
-		if !isServiceClient {
-			isAdminHeader, ok := r.Header["X-Auth-Is-Admin"]
-			if ok && len(isAdminHeader) > 0 {
-				if strings.ToLower(isAdminHeader[0]) == "true" {
-					isAdmin = true
-				}
-			}
-		}
-
-		// Create and inject authentication information.
-		authInfo := &AuthInfo{authMethod, userID, isAdmin}
+		authInfo := parseAuthInfo(r.Header.Get("X-Auth-Method"),
+			r.Header.Get("X-Auth-User-Id"), r.Header.Get("X-Auth-Is-Admin"))
 		next.ServeHTTP(w, r.WithContext(NewAuthContext(r.Context(), authInfo)))
 	})
 }
 
+// Create authentication information from the values of the
+// inter-service authentication headers (or equivalent gRPC metadata).
+func parseAuthInfo(method, userID, isAdmin string) *AuthInfo {
+	switch method {
+	case "session":
+		return &AuthInfo{SessionAuth, userID, strings.ToLower(isAdmin) == "true"}
+	case "api-key":
+		return &AuthInfo{APIKeyAuth, userID, strings.ToLower(isAdmin) == "true"}
+	case "service-client":
+		return &AuthInfo{SessionAuth, "service-client", true}
+	default:
+		return &AuthInfo{NoAuth, userID, strings.ToLower(isAdmin) == "true"}
+	}
+}
+
 // NewAuthContext returns a new Context that carries authentication
 // information.
 func NewAuthContext(ctx context.Context, info *AuthInfo) context.Context {
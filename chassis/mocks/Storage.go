@@ -23,6 +23,50 @@ func (_m *Storage) Delete(id string, format string) error {
 	return r0
 }
 
+// Exists provides a mock function with given fields: id, format
+func (_m *Storage) Exists(id string, format string) (bool, error) {
+	ret := _m.Called(id, format)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, string) bool); ok {
+		r0 = rf(id, format)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(id, format)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Read provides a mock function with given fields: id, format
+func (_m *Storage) Read(id string, format string) ([]byte, error) {
+	ret := _m.Called(id, format)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(string, string) []byte); ok {
+		r0 = rf(id, format)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(id, format)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Write provides a mock function with given fields: id, format, data
 func (_m *Storage) Write(id string, format string, data []byte) (string, int64, error) {
 	ret := _m.Called(id, format, data)
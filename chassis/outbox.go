@@ -0,0 +1,276 @@
+package chassis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/rs/zerolog/log"
+
+	"github.com/veganbase/backend/chassis/pubsub"
+)
+
+// Advisory lock key used to make sure that only one replica of a
+// service relays events from the service's outbox at a time, which is
+// what keeps per-aggregate ordering intact.
+const eventRelayLockID = 0x76626f7574626f78
+
+// OutboxEvent is an event recorded in a service's "events" table that
+// has not yet been published.
+type OutboxEvent struct {
+	ID            int64          `db:"id"`
+	Label         string         `db:"label"`
+	EventData     types.JSONText `db:"event_data"`
+	Attempts      int            `db:"attempts"`
+	NextAttemptAt *time.Time     `db:"next_attempt_at"`
+}
+
+// Aggregate returns the key used to order events: events with the
+// same key are always published in the order they were saved. Events
+// whose data has a top-level "id" field are keyed by topic and ID;
+// other events are ordered within their topic.
+func (ev *OutboxEvent) Aggregate() string {
+	data := struct {
+		ID interface{} `json:"id"`
+	}{}
+	if err := json.Unmarshal(ev.EventData, &data); err == nil {
+		if id, ok := data.ID.(string); ok && id != "" {
+			return ev.Label + ":" + id
+		}
+	}
+	return ev.Label
+}
+
+// EventRelayStats is a snapshot of the counters kept by an event
+// relay.
+type EventRelayStats struct {
+	Published uint64    `json:"published"`
+	Failed    uint64    `json:"failed"`
+	Pending   int       `json:"pending"`
+	LastError string    `json:"last_error,omitempty"`
+	LastRun   time.Time `json:"last_run"`
+}
+
+// EventRelay drains unpublished events from a service's "events"
+// table (the outbox written by SaveEvent) to pub/sub. Events that
+// fail to publish are retried with exponential backoff, and later
+// events for the same aggregate are held back until the failed event
+// has been published. Events that are waiting for a retry, or held
+// back behind one, are not fetched at all, so they can't fill up a
+// batch and stall events for other aggregates. Delivery is
+// at-least-once: an event published just before a failed database
+// commit will be published again.
+type EventRelay struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxBackoff   time.Duration
+
+	db        *sqlx.DB
+	ps        pubsub.PubSub
+	published uint64
+	failed    uint64
+	mu        sync.Mutex
+	pending   int
+	lastError string
+	lastRun   time.Time
+}
+
+// NewEventRelay creates an event relay for a service database.
+func NewEventRelay(db *sqlx.DB, ps pubsub.PubSub) *EventRelay {
+	return &EventRelay{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		MaxBackoff:   5 * time.Minute,
+		db:           db,
+		ps:           ps,
+	}
+}
+
+// Run relays events until the context is cancelled.
+func (r *EventRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+	lastLog := time.Now()
+	for {
+		for {
+			n, err := r.RelayOnce()
+			if err != nil {
+				log.Error().Err(err).Msg("relaying events from outbox")
+				break
+			}
+			// Keep going while there are events being published.
+			if n == 0 {
+				break
+			}
+		}
+
+		if time.Since(lastLog) >= time.Minute {
+			stats := r.Stats()
+			log.Info().
+				Uint64("published", stats.Published).
+				Uint64("failed", stats.Failed).
+				Int("pending", stats.Pending).
+				Msg("event relay statistics")
+			lastLog = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes a single batch of unpublished events, returning
+// the number of events published.
+func (r *EventRelay) RelayOnce() (n int, err error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	// Another replica is relaying events.
+	locked := false
+	if err = tx.Get(&locked, `SELECT pg_try_advisory_xact_lock($1)`, eventRelayLockID); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	now := time.Now()
+	events := []OutboxEvent{}
+	if err = tx.Select(&events, readyEvents, now, r.BatchSize); err != nil {
+		return 0, err
+	}
+
+	// An event that fails holds back the events after it in the batch
+	// for the same aggregate. (Events after it outside the batch are
+	// excluded by the query next time round.)
+	blocked := map[string]bool{}
+	published := 0
+	for i := range events {
+		ev := &events[i]
+		agg := ev.Aggregate()
+		if blocked[agg] {
+			continue
+		}
+
+		perr := r.ps.Publish(ev.Label, json.RawMessage(ev.EventData))
+		if perr != nil {
+			blocked[agg] = true
+			atomic.AddUint64(&r.failed, 1)
+			r.setLastError(perr)
+			log.Error().Err(perr).
+				Int64("event-id", ev.ID).
+				Str("event-topic", ev.Label).
+				Int("attempts", ev.Attempts+1).
+				Msg("failed publishing event from outbox")
+			retry := now.Add(r.backoff(ev.Attempts + 1))
+			if _, err = tx.Exec(markEventFailed, ev.ID, perr.Error(), retry); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		if _, err = tx.Exec(markEventPublished, ev.ID); err != nil {
+			return 0, err
+		}
+		atomic.AddUint64(&r.published, 1)
+		published++
+	}
+
+	pending := 0
+	if err = tx.Get(&pending, unpublishedCount); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	r.pending = pending
+	r.lastRun = now
+	r.mu.Unlock()
+
+	return published, nil
+}
+
+// SQL version of OutboxEvent.Aggregate.
+const eventAggregate = `
+CASE WHEN jsonb_typeof(%[1]s.event_data->'id') = 'string' AND
+          %[1]s.event_data->>'id' <> ''
+     THEN %[1]s.label || ':' || (%[1]s.event_data->>'id')
+     ELSE %[1]s.label END`
+
+// Unpublished events that are due to be published: events waiting
+// for a retry are skipped, along with any later events for the same
+// aggregate.
+var readyEvents = `
+SELECT id, label, event_data, attempts, next_attempt_at
+  FROM events e
+ WHERE published_at IS NULL
+   AND (next_attempt_at IS NULL OR next_attempt_at <= $1)
+   AND NOT EXISTS
+       (SELECT 1 FROM events w
+         WHERE w.published_at IS NULL
+           AND w.next_attempt_at > $1
+           AND w.id < e.id
+           AND ` + fmt.Sprintf(eventAggregate, "w") + ` = ` +
+	fmt.Sprintf(eventAggregate, "e") + `)
+ ORDER BY id
+ LIMIT $2`
+
+const unpublishedCount = `
+SELECT COUNT(*) FROM events WHERE published_at IS NULL`
+
+const markEventPublished = `
+UPDATE events SET published_at = now(), attempts = attempts + 1
+ WHERE id = $1`
+
+const markEventFailed = `
+UPDATE events
+   SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+ WHERE id = $1`
+
+// Stats returns a snapshot of the relay's counters.
+func (r *EventRelay) Stats() EventRelayStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return EventRelayStats{
+		Published: atomic.LoadUint64(&r.published),
+		Failed:    atomic.LoadUint64(&r.failed),
+		Pending:   r.pending,
+		LastError: r.lastError,
+		LastRun:   r.lastRun,
+	}
+}
+
+func (r *EventRelay) setLastError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastError = err.Error()
+}
+
+// Exponential backoff for retries, starting at one second.
+func (r *EventRelay) backoff(attempts int) time.Duration {
+	d := time.Second
+	for i := 1; i < attempts && d < r.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	return d
+}
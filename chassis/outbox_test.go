@@ -0,0 +1,233 @@
+package chassis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/veganbase/backend/chassis/pubsub"
+)
+
+// Run with VB_TEST_DB set to a Postgres connection URL. The relay
+// works on a temporary "events" table, so the database connection is
+// limited to a single session.
+func withOutbox(t *testing.T, test func(db *sqlx.DB)) {
+	pgdsn := os.Getenv("VB_TEST_DB")
+	if pgdsn == "" {
+		t.Skip("VB_TEST_DB not set")
+	}
+	db, err := sqlx.Connect("postgres", pgdsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(`
+CREATE TEMPORARY TABLE events (
+  id              SERIAL PRIMARY KEY,
+  timestamp       TIMESTAMPTZ NOT NULL DEFAULT now(),
+  label           TEXT,
+  event_data      JSONB,
+  published_at    TIMESTAMPTZ,
+  attempts        INTEGER NOT NULL DEFAULT 0,
+  last_error      TEXT,
+  next_attempt_at TIMESTAMPTZ)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test(db)
+}
+
+// Add events to the outbox: each event has an ID, used as its
+// aggregate key, and a sequence number.
+var seqNo = 0
+
+func saveEvents(t *testing.T, db *sqlx.DB, topic string, ids ...string) {
+	for _, id := range ids {
+		d, _ := json.Marshal(map[string]interface{}{"id": id, "seq": seqNo})
+		seqNo++
+		if _, err := db.Exec(`INSERT INTO events (label, event_data) VALUES ($1, $2)`,
+			topic, string(d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// relayPubSub records published events, failing to publish events
+// for aggregates listed in "fail".
+type relayPubSub struct {
+	pubsub.PubSub
+	fail      map[string]bool
+	published []string
+}
+
+func (ps *relayPubSub) Publish(topic string, data interface{}) error {
+	ev := struct {
+		ID  string `json:"id"`
+		Seq int    `json:"seq"`
+	}{}
+	json.Unmarshal(data.(json.RawMessage), &ev)
+	if ps.fail[ev.ID] {
+		return errors.New("publish failed for " + ev.ID)
+	}
+	ps.published = append(ps.published, fmt.Sprintf("%s:%s/%d", topic, ev.ID, ev.Seq))
+	return nil
+}
+
+func TestOutboxEventAggregate(t *testing.T) {
+	cases := []struct {
+		data string
+		agg  string
+	}{
+		{`{"id":"itm_123","name":"x"}`, "item-change:itm_123"},
+		{`{"id":""}`, "item-change"},
+		{`{"id":42}`, "item-change"},
+		{`"itm_123"`, "item-change"},
+		{`{"item_id":"itm_123"}`, "item-change"},
+	}
+	for _, c := range cases {
+		ev := OutboxEvent{Label: "item-change", EventData: []byte(c.data)}
+		if agg := ev.Aggregate(); agg != c.agg {
+			t.Errorf("aggregate for %s = %q, expected %q", c.data, agg, c.agg)
+		}
+	}
+}
+
+func TestEventRelayBackoff(t *testing.T) {
+	r := NewEventRelay(nil, nil)
+	r.MaxBackoff = 10 * time.Second
+	expected := []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second,
+		10 * time.Second, 10 * time.Second,
+	}
+	for i, e := range expected {
+		if d := r.backoff(i + 1); d != e {
+			t.Errorf("backoff after %d attempts = %v, expected %v", i+1, d, e)
+		}
+	}
+}
+
+func TestEventRelayOrdering(t *testing.T) {
+	withOutbox(t, func(db *sqlx.DB) {
+		seqNo = 0
+		saveEvents(t, db, "item-change", "a", "b", "a")
+		saveEvents(t, db, "user-change", "a")
+		saveEvents(t, db, "item-change", "b", "a")
+
+		ps := &relayPubSub{}
+		r := NewEventRelay(db, ps)
+		r.BatchSize = 4
+		n, err := r.RelayOnce()
+		if err != nil || n != 4 {
+			t.Fatalf("first batch: n = %d, err = %v", n, err)
+		}
+		n, err = r.RelayOnce()
+		if err != nil || n != 2 {
+			t.Fatalf("second batch: n = %d, err = %v", n, err)
+		}
+		expected := []string{
+			"item-change:a/0", "item-change:b/1", "item-change:a/2",
+			"user-change:a/3", "item-change:b/4", "item-change:a/5",
+		}
+		if fmt.Sprint(ps.published) != fmt.Sprint(expected) {
+			t.Errorf("published %v, expected %v", ps.published, expected)
+		}
+		if n, err := r.RelayOnce(); err != nil || n != 0 {
+			t.Errorf("nothing left: n = %d, err = %v", n, err)
+		}
+		if stats := r.Stats(); stats.Published != 6 || stats.Pending != 0 {
+			t.Errorf("stats = %+v", stats)
+		}
+	})
+}
+
+func TestEventRelayRetry(t *testing.T) {
+	withOutbox(t, func(db *sqlx.DB) {
+		seqNo = 0
+		saveEvents(t, db, "item-change", "a", "b", "a", "b")
+
+		// A failed event holds back later events for the same aggregate
+		// but not for others.
+		ps := &relayPubSub{fail: map[string]bool{"a": true}}
+		r := NewEventRelay(db, ps)
+		n, err := r.RelayOnce()
+		if err != nil || n != 2 {
+			t.Fatalf("n = %d, err = %v", n, err)
+		}
+		expected := []string{"item-change:b/1", "item-change:b/3"}
+		if fmt.Sprint(ps.published) != fmt.Sprint(expected) {
+			t.Errorf("published %v, expected %v", ps.published, expected)
+		}
+		stats := r.Stats()
+		if stats.Failed != 1 || stats.Pending != 2 ||
+			stats.LastError != "publish failed for a" {
+			t.Errorf("stats = %+v", stats)
+		}
+		ev := struct {
+			Attempts      int        `db:"attempts"`
+			LastError     *string    `db:"last_error"`
+			NextAttemptAt *time.Time `db:"next_attempt_at"`
+		}{}
+		if err := db.Get(&ev, `SELECT attempts, last_error, next_attempt_at
+                             FROM events WHERE id = 1`); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Attempts != 1 || ev.LastError == nil || ev.NextAttemptAt == nil ||
+			!ev.NextAttemptAt.After(time.Now()) {
+			t.Errorf("failed event = %+v", ev)
+		}
+
+		// Nothing is retried before the backoff period is up.
+		ps.fail = nil
+		if n, err := r.RelayOnce(); err != nil || n != 0 {
+			t.Errorf("during backoff: n = %d, err = %v", n, err)
+		}
+
+		// Once it is, the events go out in order.
+		if _, err := db.Exec(`UPDATE events SET next_attempt_at = now() - interval '1 second'
+                           WHERE id = 1`); err != nil {
+			t.Fatal(err)
+		}
+		ps.published = nil
+		if n, err := r.RelayOnce(); err != nil || n != 2 {
+			t.Fatalf("after backoff: n = %d, err = %v", n, err)
+		}
+		expected = []string{"item-change:a/0", "item-change:a/2"}
+		if fmt.Sprint(ps.published) != fmt.Sprint(expected) {
+			t.Errorf("published %v, expected %v", ps.published, expected)
+		}
+		if err := db.Get(&ev.Attempts, `SELECT attempts FROM events WHERE id = 1`); err != nil || ev.Attempts != 2 {
+			t.Errorf("attempts = %d (err %v)", ev.Attempts, err)
+		}
+	})
+}
+
+func TestEventRelayPoisonedEvent(t *testing.T) {
+	withOutbox(t, func(db *sqlx.DB) {
+		seqNo = 0
+		saveEvents(t, db, "item-change", "bad", "bad", "bad", "bad", "good")
+
+		// Events waiting for a retry, and events held back behind them,
+		// don't fill the batch and stall other events.
+		ps := &relayPubSub{fail: map[string]bool{"bad": true}}
+		r := NewEventRelay(db, ps)
+		r.BatchSize = 2
+		for i := 0; i < 3; i++ {
+			if _, err := r.RelayOnce(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expected := []string{"item-change:good/4"}
+		if fmt.Sprint(ps.published) != fmt.Sprint(expected) {
+			t.Errorf("published %v, expected %v", ps.published, expected)
+		}
+		if stats := r.Stats(); stats.Failed != 1 || stats.Pending != 4 {
+			t.Errorf("stats = %+v", stats)
+		}
+	})
+}
@@ -140,7 +140,10 @@ func (ps *GooglePubSub) makeUniqueSubscription(topic, sub string) (*google.Subsc
 	}
 }
 
-// Publish sends a message on a given topic.
+// Publish sends a message on a given topic, waiting until the
+// message has been accepted by Pub/Sub, so that callers (in
+// particular the outbox event relay) only see success once the
+// message really has been published.
 func (ps *GooglePubSub) Publish(topic string, msg interface{}) error {
 	err := ps.ensureTopic(topic)
 	if err != nil {
@@ -160,20 +163,17 @@ func (ps *GooglePubSub) Publish(topic string, msg interface{}) error {
 	res := t.Publish(ps.ctx, &m)
 
 	// Wait for publication completion and log.
-	go func() {
-		id, err := res.Get(ps.ctx)
-		if err != nil {
-			log.Error().Err(err).
-				Str("topic", topic).
-				Msg("message publication failed")
-		} else {
-			log.Info().
-				Str("topic", topic).
-				Str("event-id", id).
-				Msg("event published successfully")
-		}
-	}()
-
+	id, err := res.Get(ps.ctx)
+	if err != nil {
+		log.Error().Err(err).
+			Str("topic", topic).
+			Msg("message publication failed")
+		return err
+	}
+	log.Info().
+		Str("topic", topic).
+		Str("event-id", id).
+		Msg("event published successfully")
 	return nil
 }
 
@@ -0,0 +1,359 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultAckDeadline is the time a subscriber has to acknowledge a
+// message received through MemoryPubSub.SubscribeMessages before it
+// is redelivered.
+const DefaultAckDeadline = 30 * time.Second
+
+// MemoryPubSub is an in-process implementation of the PubSub
+// interface. It is used in development mode and in integration tests
+// so that event flows between components in a single process work
+// without an external message broker.
+//
+// Subscriptions follow the same rules as Google Pub/Sub: a
+// CompetingConsumers subscription is named by topic and subscription
+// name and outlives its subscribers, so messages published while no
+// subscriber is attached are kept until one arrives; a Fanout
+// subscription is unique to each call to Subscribe and is deleted
+// when the subscriber cancels. Messages published to a topic before
+// any subscription exists are dropped.
+type MemoryPubSub struct {
+	// AckDeadline is the redelivery timeout for messages received
+	// through SubscribeMessages.
+	AckDeadline time.Duration
+
+	mu      sync.Mutex
+	strict  bool
+	topics  map[string]*memTopic
+	nextSub int
+	nextMsg int
+}
+
+// Message is a single delivery of a message from a MemoryPubSub
+// subscription. Exactly one of Ack or Nack should be called: a
+// message that is not acknowledged within the subscription's ack
+// deadline is treated as if Nack had been called.
+type Message struct {
+	ID      string
+	Data    []byte
+	Attempt int
+
+	sub   *memSub
+	once  sync.Once
+	timer *time.Timer
+}
+
+type memTopic struct {
+	subs map[string]*memSub
+}
+
+type memSub struct {
+	name    string
+	topic   string
+	mu      sync.Mutex
+	queue   []*Message
+	ready   chan struct{}
+	cancels map[int]context.CancelFunc
+}
+
+// NewMemoryPubSub creates a new in-process pub/sub system. If any
+// topics are given, only those topics may be used, and Publish or
+// Subscribe calls for any other topic return ErrUnknownTopic.
+// Otherwise, topics are created on first use, as for GooglePubSub.
+func NewMemoryPubSub(topics ...string) *MemoryPubSub {
+	ps := &MemoryPubSub{
+		AckDeadline: DefaultAckDeadline,
+		strict:      len(topics) > 0,
+		topics:      map[string]*memTopic{},
+	}
+	for _, t := range topics {
+		ps.topics[t] = &memTopic{subs: map[string]*memSub{}}
+	}
+	return ps
+}
+
+// EnsureTopic makes sure that a topic exists.
+func (ps *MemoryPubSub) EnsureTopic(topic string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if _, ok := ps.topics[topic]; !ok {
+		ps.topics[topic] = &memTopic{subs: map[string]*memSub{}}
+	}
+}
+
+// lookupTopic finds a topic, creating it if topics are created on
+// demand. Must be called with the lock held.
+func (ps *MemoryPubSub) lookupTopic(topic string) (*memTopic, error) {
+	t, ok := ps.topics[topic]
+	if ok {
+		return t, nil
+	}
+	if ps.strict {
+		return nil, ErrUnknownTopic
+	}
+	t = &memTopic{subs: map[string]*memSub{}}
+	ps.topics[topic] = t
+	return t, nil
+}
+
+// Publish sends a message on a given topic, delivering a copy to
+// each existing subscription.
+func (ps *MemoryPubSub) Publish(topic string, msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	ps.mu.Lock()
+	t, err := ps.lookupTopic(topic)
+	if err != nil {
+		ps.mu.Unlock()
+		return err
+	}
+	ps.nextMsg++
+	id := fmt.Sprintf("%s-%d", topic, ps.nextMsg)
+	subs := make([]*memSub, 0, len(t.subs))
+	for _, s := range t.subs {
+		subs = append(subs, s)
+	}
+	ps.mu.Unlock()
+
+	for _, s := range subs {
+		s.push(&Message{ID: id, Data: data, Attempt: 1})
+	}
+
+	log.Info().
+		Str("topic", topic).
+		Str("event-id", id).
+		Msg("event published successfully")
+	return nil
+}
+
+// Subscribe sets up a subscription, with message content being sent
+// on a channel. Messages are acknowledged as soon as they are
+// received from the channel; a message that is being offered to a
+// subscriber when it cancels is redelivered to another subscriber.
+func (ps *MemoryPubSub) Subscribe(topic, sub string,
+	mode SubscriptionMode) (chan []byte, func(), error) {
+	s, ctx, cancel, err := ps.attach(topic, sub, mode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan []byte)
+	go func() {
+		for {
+			m := s.next(ctx)
+			if m == nil {
+				return
+			}
+			select {
+			case ch <- m.Data:
+			case <-ctx.Done():
+				s.push(m)
+				return
+			}
+		}
+	}()
+	return ch, cancel, nil
+}
+
+// SubscribeMessages sets up a subscription where each message must be
+// explicitly acknowledged by calling Ack or Nack. Messages that are
+// negatively acknowledged or not acknowledged within the ack deadline
+// are redelivered with an incremented attempt count.
+func (ps *MemoryPubSub) SubscribeMessages(topic, sub string,
+	mode SubscriptionMode) (chan *Message, func(), error) {
+	s, ctx, cancel, err := ps.attach(topic, sub, mode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan *Message)
+	go func() {
+		for {
+			m := s.next(ctx)
+			if m == nil {
+				return
+			}
+			m.sub = s
+			m.startTimer(ps.ackDeadline())
+			select {
+			case ch <- m:
+			case <-ctx.Done():
+				// Never delivered, so requeue without counting an attempt.
+				m.once.Do(func() {
+					m.timer.Stop()
+					s.push(&Message{ID: m.ID, Data: m.Data, Attempt: m.Attempt})
+				})
+				return
+			}
+		}
+	}()
+	return ch, cancel, nil
+}
+
+// Close cancels all subscriptions and discards all topics.
+func (ps *MemoryPubSub) Close() {
+	log.Info().Msg("closing down pub/sub")
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for _, t := range ps.topics {
+		for _, s := range t.subs {
+			s.cancelAll()
+		}
+	}
+	ps.topics = map[string]*memTopic{}
+}
+
+// Ack acknowledges successful processing of a message.
+func (m *Message) Ack() {
+	m.once.Do(func() {
+		if m.timer != nil {
+			m.timer.Stop()
+		}
+	})
+}
+
+// Nack signals that a message was not processed and should be
+// redelivered.
+func (m *Message) Nack() {
+	m.once.Do(func() {
+		if m.timer != nil {
+			m.timer.Stop()
+		}
+		m.redeliver()
+	})
+}
+
+func (m *Message) startTimer(d time.Duration) {
+	m.timer = time.AfterFunc(d, func() {
+		m.once.Do(m.redeliver)
+	})
+}
+
+func (m *Message) redeliver() {
+	if m.sub == nil {
+		return
+	}
+	m.sub.push(&Message{ID: m.ID, Data: m.Data, Attempt: m.Attempt + 1})
+}
+
+func (ps *MemoryPubSub) ackDeadline() time.Duration {
+	if ps.AckDeadline <= 0 {
+		return DefaultAckDeadline
+	}
+	return ps.AckDeadline
+}
+
+// attach finds or creates the subscription for a new subscriber and
+// registers a cancellation function for it.
+func (ps *MemoryPubSub) attach(topic, sub string,
+	mode SubscriptionMode) (*memSub, context.Context, func(), error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	t, err := ps.lookupTopic(topic)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ps.nextSub++
+	id := ps.nextSub
+	subName := topic + "." + sub
+	if mode == Fanout {
+		subName = fmt.Sprintf("%s.%s-%04x", topic, sub, id)
+		log.Info().Str("subscription", subName).
+			Msg("new pub/sub fanout subscription")
+	}
+	s, ok := t.subs[subName]
+	if !ok {
+		s = &memSub{
+			name:    subName,
+			topic:   topic,
+			ready:   make(chan struct{}, 1),
+			cancels: map[int]context.CancelFunc{},
+		}
+		t.subs[subName] = s
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.cancels[id] = cancel
+	s.mu.Unlock()
+
+	unsubscribe := func() {
+		cancel()
+		s.mu.Lock()
+		delete(s.cancels, id)
+		s.mu.Unlock()
+		if mode == Fanout {
+			ps.mu.Lock()
+			if t, ok := ps.topics[topic]; ok {
+				delete(t.subs, subName)
+			}
+			ps.mu.Unlock()
+		}
+	}
+	return s, ctx, unsubscribe, nil
+}
+
+// push adds a message to the end of a subscription's queue.
+func (s *memSub) push(m *Message) {
+	s.mu.Lock()
+	s.queue = append(s.queue, m)
+	s.mu.Unlock()
+	s.signal()
+}
+
+// next waits for the next message on a subscription's queue,
+// returning nil if the context is cancelled first.
+func (s *memSub) next(ctx context.Context) *Message {
+	for {
+		s.mu.Lock()
+		if len(s.queue) > 0 {
+			m := s.queue[0]
+			s.queue = s.queue[1:]
+			more := len(s.queue) > 0
+			s.mu.Unlock()
+			if more {
+				// Wake up any other consumer waiting on this subscription.
+				s.signal()
+			}
+			return m
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.ready:
+		}
+	}
+}
+
+func (s *memSub) signal() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (s *memSub) cancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, cancel := range s.cancels {
+		cancel()
+		delete(s.cancels, id)
+	}
+}
@@ -0,0 +1,155 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, ch chan []byte) string {
+	t.Helper()
+	select {
+	case d := <-ch:
+		var s string
+		if err := json.Unmarshal(d, &s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+	return ""
+}
+
+func expectNothing(t *testing.T, ch chan []byte) {
+	t.Helper()
+	select {
+	case d := <-ch:
+		t.Errorf("unexpected message %s", string(d))
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMemoryCompetingConsumers(t *testing.T) {
+	ps := NewMemoryPubSub()
+	defer ps.Close()
+
+	ch1, cancel1, err := ps.Subscribe("topic", "svc", CompetingConsumers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch2, cancel2, err := ps.Subscribe("topic", "svc", CompetingConsumers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel2()
+
+	if err := ps.Publish("topic", "a"); err != nil {
+		t.Fatal(err)
+	}
+	got := 0
+	select {
+	case <-ch1:
+		got++
+	case <-ch2:
+		got++
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+	expectNothing(t, ch1)
+	expectNothing(t, ch2)
+	if got != 1 {
+		t.Error("message delivered to more than one competing consumer")
+	}
+
+	// Messages published with only one consumer attached go there,
+	// and the subscription survives its subscribers.
+	cancel1()
+	ps.Publish("topic", "b")
+	if s := receive(t, ch2); s != "b" {
+		t.Errorf("got %q, expected %q", s, "b")
+	}
+	cancel2()
+	ps.Publish("topic", "c")
+	ch3, cancel3, _ := ps.Subscribe("topic", "svc", CompetingConsumers)
+	defer cancel3()
+	if s := receive(t, ch3); s != "c" {
+		t.Errorf("got %q, expected %q", s, "c")
+	}
+}
+
+func TestMemoryFanout(t *testing.T) {
+	ps := NewMemoryPubSub()
+	defer ps.Close()
+
+	ch1, cancel1, _ := ps.Subscribe("topic", "svc", Fanout)
+	defer cancel1()
+	ch2, cancel2, _ := ps.Subscribe("topic", "svc", Fanout)
+
+	ps.Publish("topic", "a")
+	if s := receive(t, ch1); s != "a" {
+		t.Errorf("got %q, expected %q", s, "a")
+	}
+	if s := receive(t, ch2); s != "a" {
+		t.Errorf("got %q, expected %q", s, "a")
+	}
+
+	// Cancelled fanout subscriptions are removed.
+	cancel2()
+	ps.Publish("topic", "b")
+	if s := receive(t, ch1); s != "b" {
+		t.Errorf("got %q, expected %q", s, "b")
+	}
+	expectNothing(t, ch2)
+}
+
+func TestMemoryAckNack(t *testing.T) {
+	ps := NewMemoryPubSub()
+	ps.AckDeadline = 50 * time.Millisecond
+	defer ps.Close()
+
+	ch, cancel, _ := ps.SubscribeMessages("topic", "svc", CompetingConsumers)
+	defer cancel()
+
+	ps.Publish("topic", "a")
+	m := <-ch
+	if m.Attempt != 1 {
+		t.Errorf("first delivery attempt = %d", m.Attempt)
+	}
+	m.Nack()
+	m = <-ch
+	if m.Attempt != 2 {
+		t.Errorf("redelivery attempt = %d", m.Attempt)
+	}
+
+	// Not acknowledging within the deadline causes redelivery.
+	m = <-ch
+	if m.Attempt != 3 {
+		t.Errorf("deadline redelivery attempt = %d", m.Attempt)
+	}
+	m.Ack()
+	select {
+	case m := <-ch:
+		t.Errorf("unexpected redelivery of acknowledged message %s", m.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMemoryUnknownTopic(t *testing.T) {
+	ps := NewMemoryPubSub("known")
+	defer ps.Close()
+
+	if err := ps.Publish("unknown", "a"); err != ErrUnknownTopic {
+		t.Errorf("Publish on unknown topic: err = %v", err)
+	}
+	if _, _, err := ps.Subscribe("unknown", "svc", Fanout); err != ErrUnknownTopic {
+		t.Errorf("Subscribe on unknown topic: err = %v", err)
+	}
+	if err := ps.Publish("known", "a"); err != nil {
+		t.Errorf("Publish on known topic: err = %v", err)
+	}
+	ps.EnsureTopic("unknown")
+	if err := ps.Publish("unknown", "a"); err != nil {
+		t.Errorf("Publish on added topic: err = %v", err)
+	}
+}
@@ -0,0 +1,416 @@
+package pubsub
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// Name of the notification channel used to wake up subscribers: the
+// notification payload is the name of the topic with new messages.
+const pgNotifyChannel = "vb_pubsub"
+
+// How long a message received by a subscriber stays invisible to
+// other subscribers before it is redelivered, how often subscribers
+// poll for messages without notifications (this picks up redelivered
+// messages and covers dropped listener connections), and how often
+// fanout subscriptions are marked as alive.
+const (
+	pgAckDeadline    = 30 * time.Second
+	pgPollInterval   = 5 * time.Second
+	pgHeartbeat      = 30 * time.Second
+	pgFanoutLifetime = 5 * time.Minute
+)
+
+// PostgresPubSub is an implementation of the PubSub interface that
+// uses a Postgres database as its message broker, allowing the whole
+// backend to run against a single self-hosted Postgres server.
+//
+// Each subscription has a durable queue of messages in the
+// pubsub_messages table. Publishing a message copies it into the
+// queue of every subscription on the topic and sends a notification
+// on the topic; subscribers claim messages with SELECT ... FOR
+// UPDATE SKIP LOCKED, so competing consumers never see the same
+// message at the same time. A claimed message is deleted once the
+// subscriber has received it, and becomes visible again if the
+// subscriber dies before then.
+type PostgresPubSub struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   func()
+	db       *sqlx.DB
+	listener *pq.Listener
+	topics   map[string]bool
+	waiters  map[string]map[chan struct{}]bool
+	fanout   map[string]bool
+}
+
+// NewPostgresClient creates a new Postgres-backed pub/sub system
+// using the database at the given URL, creating the tables it needs
+// if they don't already exist.
+func NewPostgresClient(ctx context.Context, dbURL string) (*PostgresPubSub, error) {
+	db, err := sqlx.Open("postgres", dbURL)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+	if _, err = db.Exec(pgPubSubSchema); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	ps := &PostgresPubSub{
+		ctx:     ctx,
+		cancel:  cancel,
+		db:      db,
+		topics:  map[string]bool{},
+		waiters: map[string]map[chan struct{}]bool{},
+		fanout:  map[string]bool{},
+	}
+
+	ps.listener = pq.NewListener(dbURL, time.Second, time.Minute,
+		func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Error().Err(err).Msg("pub/sub listener connection problem")
+			}
+		})
+	if err = ps.listener.Listen(pgNotifyChannel); err != nil {
+		cancel()
+		db.Close()
+		return nil, err
+	}
+
+	go ps.dispatch()
+	go ps.heartbeat()
+	return ps, nil
+}
+
+const pgPubSubSchema = `
+CREATE TABLE IF NOT EXISTS pubsub_topics (
+  name       TEXT         PRIMARY KEY,
+  created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS pubsub_subscriptions (
+  name       TEXT         PRIMARY KEY,
+  topic      TEXT         NOT NULL REFERENCES pubsub_topics(name) ON DELETE CASCADE,
+  fanout     BOOLEAN      NOT NULL DEFAULT FALSE,
+  last_seen  TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS pubsub_subscriptions_topic_idx
+  ON pubsub_subscriptions(topic);
+
+CREATE TABLE IF NOT EXISTS pubsub_messages (
+  id           BIGSERIAL    PRIMARY KEY,
+  subscription TEXT         NOT NULL REFERENCES pubsub_subscriptions(name) ON DELETE CASCADE,
+  data         BYTEA        NOT NULL,
+  attempts     INTEGER      NOT NULL DEFAULT 0,
+  visible_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+  created_at   TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS pubsub_messages_queue_idx
+  ON pubsub_messages(subscription, visible_at, id);
+`
+
+// ensureTopic makes sure that a topic exists.
+func (ps *PostgresPubSub) ensureTopic(topic string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.topics[topic] {
+		return nil
+	}
+	_, err := ps.db.Exec(`INSERT INTO pubsub_topics (name) VALUES ($1)
+                        ON CONFLICT DO NOTHING`, topic)
+	if err != nil {
+		return err
+	}
+	ps.topics[topic] = true
+	return nil
+}
+
+// Publish sends a message on a given topic.
+func (ps *PostgresPubSub) Publish(topic string, msg interface{}) (err error) {
+	if err = ps.ensureTopic(topic); err != nil {
+		return err
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	tx, err := ps.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	res, err := tx.Exec(`
+INSERT INTO pubsub_messages (subscription, data)
+SELECT name, $2 FROM pubsub_subscriptions WHERE topic = $1`, topic, data)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`SELECT pg_notify($1, $2)`, pgNotifyChannel, topic); err != nil {
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	log.Info().
+		Str("topic", topic).
+		Int64("subscriptions", n).
+		Msg("event published successfully")
+	return nil
+}
+
+// Subscribe sets up a subscription, with message content being sent
+// on a channel.
+func (ps *PostgresPubSub) Subscribe(topic, sub string,
+	mode SubscriptionMode) (chan []byte, func(), error) {
+	err := ps.ensureTopic(topic)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subName := topic + "." + sub
+	if mode == Fanout {
+		subName = fmt.Sprintf("%s.%s-%08x", topic, sub, rand.Uint32())
+	}
+	_, err = ps.db.Exec(`
+INSERT INTO pubsub_subscriptions (name, topic, fanout) VALUES ($1, $2, $3)
+    ON CONFLICT (name) DO UPDATE SET last_seen = now()`,
+		subName, topic, mode == Fanout)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mode == Fanout {
+		ps.mu.Lock()
+		ps.fanout[subName] = true
+		ps.mu.Unlock()
+		log.Info().Str("subscription", subName).
+			Msg("new pub/sub fanout subscription")
+	}
+
+	wake := ps.addWaiter(topic)
+	ctx, cancel := context.WithCancel(ps.ctx)
+	ch := make(chan []byte)
+	go ps.receive(ctx, subName, wake, ch)
+
+	unsubscribe := func() {
+		cancel()
+		ps.removeWaiter(topic, wake)
+		if mode == Fanout {
+			ps.dropFanout(subName)
+		}
+	}
+	return ch, unsubscribe, nil
+}
+
+// receive delivers messages from a subscription's queue to a
+// subscriber channel until the subscription is cancelled.
+func (ps *PostgresPubSub) receive(ctx context.Context, subName string,
+	wake chan struct{}, ch chan []byte) {
+	for {
+		id, data, err := ps.claim(subName)
+		if err != nil {
+			log.Error().Err(err).
+				Str("subscription", subName).
+				Msg("claiming pub/sub message")
+		}
+		if err != nil || data == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-wake:
+			case <-time.After(pgPollInterval):
+			}
+			continue
+		}
+
+		select {
+		case ch <- data:
+			_, err = ps.db.Exec(`DELETE FROM pubsub_messages WHERE id = $1`, id)
+		case <-ctx.Done():
+			// Make the message immediately available to other
+			// subscribers.
+			_, err = ps.db.Exec(`UPDATE pubsub_messages SET visible_at = now()
+                            WHERE id = $1`, id)
+		}
+		if err != nil {
+			log.Error().Err(err).
+				Str("subscription", subName).
+				Msg("releasing pub/sub message")
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// claim takes the next visible message from a subscription's queue,
+// hiding it from other subscribers until the ack deadline passes.
+// Returns nil data if there are no messages waiting.
+func (ps *PostgresPubSub) claim(subName string) (int64, []byte, error) {
+	msg := struct {
+		ID   int64  `db:"id"`
+		Data []byte `db:"data"`
+	}{}
+	err := ps.db.Get(&msg, claimMessage, subName,
+		fmt.Sprintf("%d seconds", int(pgAckDeadline.Seconds())))
+	if err == sql.ErrNoRows {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return msg.ID, msg.Data, nil
+}
+
+const claimMessage = `
+UPDATE pubsub_messages
+   SET attempts = attempts + 1, visible_at = now() + $2::interval
+ WHERE id = (SELECT id FROM pubsub_messages
+              WHERE subscription = $1 AND visible_at <= now()
+              ORDER BY id
+              LIMIT 1
+                FOR UPDATE SKIP LOCKED)
+RETURNING id, data`
+
+// dispatch wakes up subscribers when notifications arrive.
+func (ps *PostgresPubSub) dispatch() {
+	for {
+		select {
+		case <-ps.ctx.Done():
+			return
+		case n := <-ps.listener.Notify:
+			ps.mu.Lock()
+			for topic, waiters := range ps.waiters {
+				// A nil notification means the listener reconnected and
+				// may have missed notifications, so wake everyone.
+				if n != nil && n.Extra != topic {
+					continue
+				}
+				for w := range waiters {
+					select {
+					case w <- struct{}{}:
+					default:
+					}
+				}
+			}
+			ps.mu.Unlock()
+		}
+	}
+}
+
+// heartbeat keeps this process's fanout subscriptions alive and
+// removes fanout subscriptions left behind by processes that exited
+// without closing down cleanly.
+func (ps *PostgresPubSub) heartbeat() {
+	ticker := time.NewTicker(pgHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ps.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ps.mu.Lock()
+		names := make([]string, 0, len(ps.fanout))
+		for n := range ps.fanout {
+			names = append(names, n)
+		}
+		ps.mu.Unlock()
+
+		if len(names) > 0 {
+			_, err := ps.db.Exec(`UPDATE pubsub_subscriptions SET last_seen = now()
+                             WHERE name = ANY($1)`, pq.Array(names))
+			if err != nil {
+				log.Error().Err(err).Msg("updating pub/sub fanout subscriptions")
+			}
+		}
+		_, err := ps.db.Exec(`DELETE FROM pubsub_subscriptions
+                           WHERE fanout AND last_seen < now() - $1::interval`,
+			fmt.Sprintf("%d seconds", int(pgFanoutLifetime.Seconds())))
+		if err != nil {
+			log.Error().Err(err).Msg("removing stale pub/sub fanout subscriptions")
+		}
+	}
+}
+
+func (ps *PostgresPubSub) addWaiter(topic string) chan struct{} {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	wake := make(chan struct{}, 1)
+	if ps.waiters[topic] == nil {
+		ps.waiters[topic] = map[chan struct{}]bool{}
+	}
+	ps.waiters[topic][wake] = true
+	return wake
+}
+
+func (ps *PostgresPubSub) removeWaiter(topic string, wake chan struct{}) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	delete(ps.waiters[topic], wake)
+}
+
+func (ps *PostgresPubSub) dropFanout(subName string) {
+	ps.mu.Lock()
+	delete(ps.fanout, subName)
+	ps.mu.Unlock()
+
+	log.Info().Str("subscription", subName).Msg("deleting subscription")
+	_, err := ps.db.Exec(`DELETE FROM pubsub_subscriptions WHERE name = $1`, subName)
+	if err != nil {
+		log.Error().Err(err).
+			Str("subscription", subName).
+			Msg("deleting pub/sub fanout subscription")
+	}
+}
+
+// Close removes this process's fanout subscriptions and closes the
+// database connections.
+func (ps *PostgresPubSub) Close() {
+	log.Info().Msg("closing down pub/sub")
+	ps.cancel()
+
+	ps.mu.Lock()
+	names := make([]string, 0, len(ps.fanout))
+	for n := range ps.fanout {
+		names = append(names, n)
+	}
+	ps.mu.Unlock()
+	for _, n := range names {
+		ps.dropFanout(n)
+	}
+
+	ps.listener.Close()
+	ps.db.Close()
+}
+
+// IsPostgresURL determines whether a pub/sub configuration string is
+// a Postgres connection URL.
+func IsPostgresURL(s string) bool {
+	return strings.HasPrefix(s, "postgres://") || strings.HasPrefix(s, "postgresql://")
+}
@@ -0,0 +1,87 @@
+package pubsub
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+// Run with VB_TEST_DB set to a Postgres connection URL.
+func withPostgres(t *testing.T, test func(ps *PostgresPubSub)) {
+	pgdsn := os.Getenv("VB_TEST_DB")
+	if pgdsn == "" {
+		t.Skip("VB_TEST_DB not set")
+	}
+	ps, err := NewPostgresClient(context.Background(), pgdsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		ps.db.Exec(`DELETE FROM pubsub_topics WHERE name LIKE 'pgtest-%'`)
+		ps.Close()
+	}()
+	test(ps)
+}
+
+func TestPostgresCompetingConsumers(t *testing.T) {
+	withPostgres(t, func(ps *PostgresPubSub) {
+		ch1, cancel1, err := ps.Subscribe("pgtest-competing", "svc", CompetingConsumers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch2, cancel2, err := ps.Subscribe("pgtest-competing", "svc", CompetingConsumers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cancel2()
+
+		if err := ps.Publish("pgtest-competing", "a"); err != nil {
+			t.Fatal(err)
+		}
+		var s string
+		select {
+		case d := <-ch1:
+			s = string(d)
+		case d := <-ch2:
+			s = string(d)
+		}
+		if s != `"a"` {
+			t.Errorf("got %s, expected %q", s, "a")
+		}
+		expectNothing(t, ch1)
+		expectNothing(t, ch2)
+
+		// The subscription survives its subscribers.
+		cancel1()
+		cancel2()
+		ps.Publish("pgtest-competing", "b")
+		ch3, cancel3, _ := ps.Subscribe("pgtest-competing", "svc", CompetingConsumers)
+		defer cancel3()
+		if s := receive(t, ch3); s != "b" {
+			t.Errorf("got %q, expected %q", s, "b")
+		}
+	})
+}
+
+func TestPostgresFanout(t *testing.T) {
+	withPostgres(t, func(ps *PostgresPubSub) {
+		ch1, cancel1, _ := ps.Subscribe("pgtest-fanout", "svc", Fanout)
+		defer cancel1()
+		ch2, cancel2, _ := ps.Subscribe("pgtest-fanout", "svc", Fanout)
+
+		ps.Publish("pgtest-fanout", "a")
+		if s := receive(t, ch1); s != "a" {
+			t.Errorf("got %q, expected %q", s, "a")
+		}
+		if s := receive(t, ch2); s != "a" {
+			t.Errorf("got %q, expected %q", s, "a")
+		}
+
+		cancel2()
+		ps.Publish("pgtest-fanout", "b")
+		if s := receive(t, ch1); s != "b" {
+			t.Errorf("got %q, expected %q", s, "b")
+		}
+		expectNothing(t, ch2)
+	})
+}
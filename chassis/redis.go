@@ -1,8 +1,12 @@
 package chassis
 
 import (
-	"github.com/go-redis/redis"
+	"errors"
+	"math"
+	"strconv"
 	"time"
+
+	"github.com/go-redis/redis"
 )
 
 // locker implementation with Redis
@@ -30,3 +34,51 @@ func (s *RedisClient) Lock(key string) (bool, error) {
 func (s *RedisClient) Unlock(key string) error {
 	return s.cache.Del(key).Err()
 }
+
+// TakeToken takes a token from a token bucket stored in Redis under
+// the given key. The bucket holds up to burst tokens and refills at
+// rate tokens per second, using the Redis server's clock so that
+// buckets shared between several servers behave consistently. It
+// returns whether a token was available and the number of tokens
+// left in the bucket.
+func (s *RedisClient) TakeToken(key string, rate float64, burst int) (bool, float64, error) {
+	ttl := int64(math.Ceil(float64(burst) / rate * 1000))
+	res, err := takeTokenScript.Run(s.cache, []string{key}, rate, burst, ttl).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 2 {
+		return false, 0, errors.New("unexpected token bucket result")
+	}
+	allowed, _ := vals[0].(int64)
+	tokens, _ := vals[1].(string)
+	remaining, err := strconv.ParseFloat(tokens, 64)
+	if err != nil {
+		return false, 0, err
+	}
+	return allowed == 1, remaining, nil
+}
+
+// Refill a token bucket for the time since it was last used and take
+// a token if there is one. Numbers are returned as strings because
+// Redis truncates Lua numbers to integers.
+var takeTokenScript = redis.NewScript(`
+redis.replicate_commands()
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1]) or burst
+local ts = tonumber(b[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return {allowed, tostring(tokens)}
+`)
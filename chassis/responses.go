@@ -63,6 +63,30 @@ func Unauthorized(w http.ResponseWriter, msg string) (interface{}, error) {
 	return nil, nil
 }
 
+// Conflict sets up an HTTP 409 Conflict with a given error message
+// and returns the (nil, nil) pair used by SimpleHandler to signal
+// that the response has been dealt with.
+func Conflict(w http.ResponseWriter, msg string) (interface{}, error) {
+	rsp := ErrResp{msg}
+	body, _ := json.Marshal(rsp)
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	w.Write(body)
+	return nil, nil
+}
+
+// TooManyRequests sets up an HTTP 429 Too Many Requests and returns
+// the (nil, nil) pair used by SimpleHandler to signal that the
+// response has been dealt with.
+func TooManyRequests(w http.ResponseWriter) (interface{}, error) {
+	rsp := ErrResp{"Too many requests"}
+	body, _ := json.Marshal(rsp)
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write(body)
+	return nil, nil
+}
+
 // NoContent sets up an HTTP 204 No Content and returns the (nil, nil)
 // pair used by SimpleHandler to signal that the response has been
 // dealt with.
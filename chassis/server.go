@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog/log"
 
 	"github.com/veganbase/backend/chassis/pubsub"
@@ -25,6 +26,7 @@ type Server struct {
 	Ctx      context.Context
 	Srv      *http.Server
 	PubSub   pubsub.PubSub
+	Relay    *EventRelay
 	muAtExit sync.Mutex
 	atExit   []func()
 }
@@ -89,15 +91,24 @@ func (s *Server) Init(appname string, project string, port int, credentials stri
 		Addr:    fmt.Sprintf(":%d", port),
 	}
 
-	// Initialise Pub/Sub.
-	if credentials != "dev" {
-		var err error
+	// Initialise Pub/Sub: "dev" credentials use an in-process pub/sub
+	// system, a Postgres connection URL uses a Postgres database as the
+	// message broker, and anything else is a path to Google Cloud
+	// credentials (or "emulator" for the Pub/Sub emulator).
+	var err error
+	switch {
+	case credentials == "dev":
+		s.PubSub = pubsub.NewMemoryPubSub()
+	case pubsub.IsPostgresURL(credentials):
+		s.PubSub, err = pubsub.NewPostgresClient(s.Ctx, credentials)
+		if err != nil {
+			log.Fatal().Err(err).Msg("couldn't connect to Postgres pub/sub database")
+		}
+	default:
 		s.PubSub, err = pubsub.NewGoogleClient(s.Ctx, project, credentials)
 		if err != nil {
 			log.Fatal().Err(err).Msg("couldn't connect to Google Pub/Sub service")
 		}
-	} else {
-		s.PubSub = pubsub.NewMockPubSub(map[string][][]byte{})
 	}
 
 	// Make sure pub/sub gets cleaned up on exit: this removes unique
@@ -105,6 +116,17 @@ func (s *Server) Init(appname string, project string, port int, credentials stri
 	s.AddAtExit(s.PubSub.Close)
 }
 
+// StartEventRelay starts a goroutine relaying events saved in the
+// service's "events" table to the server's Pub/Sub stream. It should
+// be called by every service that emits events, once its database
+// connection is set up.
+func (s *Server) StartEventRelay(db *sqlx.DB) {
+	s.Relay = NewEventRelay(db, s.PubSub)
+	ctx, cancel := context.WithCancel(s.Ctx)
+	go s.Relay.Run(ctx)
+	s.AddAtExit(cancel)
+}
+
 // InitSimple initialises all the common infrastructure used by
 // servers that only provide healthcheck REST endpoints.
 func (s *Server) InitSimple(appname, project string, port int, credentials string) {
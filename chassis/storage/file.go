@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStorage represents blob storage in a directory on the local
+// filesystem.
+type FileStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewFileStorage creates blob storage rooted at the given directory,
+// creating the directory if needed. The URL returned for a stored
+// blob is the blob's object name appended to the base URL.
+func NewFileStorage(dir, baseURL string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileStorage{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// Write writes data to a file named using a blob ID and file
+// extension. The data is written to a temporary file which is then
+// renamed, so readers never see partially written blobs.
+func (s *FileStorage) Write(id, format string, data []byte) (string, int64, error) {
+	name := objectName(id, format)
+	tmp, err := ioutil.TempFile(s.dir, "."+name+".*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", 0, err
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", 0, err
+	}
+	if err = os.Rename(tmp.Name(), s.path(id, format)); err != nil {
+		return "", 0, err
+	}
+	return s.baseURL + "/" + name, int64(len(data)), nil
+}
+
+// Read reads the data for a blob with a given ID and file type.
+func (s *FileStorage) Read(id, format string) ([]byte, error) {
+	data, err := ioutil.ReadFile(s.path(id, format))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Exists checks whether data is stored for a blob with a given ID
+// and file type.
+func (s *FileStorage) Exists(id, format string) (bool, error) {
+	_, err := os.Stat(s.path(id, format))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete deletes the data for a blob with a given ID and file type.
+// Deleting a blob that doesn't exist is not an error.
+func (s *FileStorage) Delete(id, format string) error {
+	err := os.Remove(s.path(id, format))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Path of file used to store a blob. Path separators are stripped
+// from the object name, so blobs can't be written outside of the
+// storage directory.
+func (s *FileStorage) path(id, format string) string {
+	return filepath.Join(s.dir, filepath.Base(objectName(id, format)))
+}
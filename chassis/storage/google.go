@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 
 	google "cloud.google.com/go/storage"
 	"google.golang.org/api/option"
@@ -59,3 +60,32 @@ func (s *GoogleStorage) Delete(id, format string) error {
 	blob := s.bucket.Object(fmt.Sprintf("%v.%v", id, format))
 	return blob.Delete(s.ctx)
 }
+
+// Read reads the data for a blob with a given ID and file type from
+// the Google Cloud Storage bucket.
+func (s *GoogleStorage) Read(id, format string) ([]byte, error) {
+	blob := s.bucket.Object(fmt.Sprintf("%v.%v", id, format))
+	r, err := blob.NewReader(s.ctx)
+	if err == google.ErrObjectNotExist {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// Exists checks whether a blob with a given ID and file type exists
+// in the Google Cloud Storage bucket.
+func (s *GoogleStorage) Exists(id, format string) (bool, error) {
+	blob := s.bucket.Object(fmt.Sprintf("%v.%v", id, format))
+	_, err := blob.Attrs(s.ctx)
+	if err == google.ErrObjectNotExist {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -7,6 +7,7 @@ import (
 type stored struct {
 	format string
 	size   int64
+	data   []byte
 }
 
 type MockStorage struct {
@@ -16,16 +17,29 @@ type MockStorage struct {
 func NewMockStorage(blobs *map[string]*model.Blob) *MockStorage {
 	store := map[string]stored{}
 	for id, blob := range *blobs {
-		store[id] = stored{blob.Format, int64(blob.Size)}
+		store[id] = stored{blob.Format, int64(blob.Size), nil}
 	}
 	return &MockStorage{store}
 }
 
 func (mock *MockStorage) Write(id, format string, data []byte) (string, int64, error) {
-	mock.B[id] = stored{format, int64(len(data))}
+	mock.B[id] = stored{format, int64(len(data)), data}
 	return "http://dummy", int64(len(data)), nil
 }
 
+func (mock *MockStorage) Read(id, format string) ([]byte, error) {
+	b, ok := mock.B[id]
+	if !ok || b.format != format {
+		return nil, ErrNotFound
+	}
+	return b.data, nil
+}
+
+func (mock *MockStorage) Exists(id, format string) (bool, error) {
+	b, ok := mock.B[id]
+	return ok && b.format == format, nil
+}
+
 func (mock *MockStorage) Delete(id, format string) error {
 	delete(mock.B, id)
 	return nil
@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config contains the information needed to connect to an
+// S3-compatible object store (AWS S3, MinIO, etc.).
+type S3Config struct {
+	// Endpoint is the base URL of the object store, e.g.
+	// "https://s3.eu-west-1.amazonaws.com" or "http://minio:9000".
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+
+	// PublicURL is the base URL used for the links returned by Write.
+	// If empty, links point at the bucket on the endpoint.
+	PublicURL string
+}
+
+// S3Storage represents blob storage in a bucket in an S3-compatible
+// object store. Requests use path-style bucket addressing and AWS
+// Signature Version 4 authentication, which are supported by all
+// common S3-compatible servers.
+type S3Storage struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	publicURL string
+	now       func() time.Time
+}
+
+// NewS3Storage creates a new connection to an S3-compatible object
+// store for blob storage.
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint '%s'", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("missing S3 bucket name")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	publicURL := strings.TrimSuffix(cfg.PublicURL, "/")
+	if publicURL == "" {
+		publicURL = endpoint.String() + "/" + cfg.Bucket
+	}
+	return &S3Storage{
+		client:    &http.Client{Timeout: 60 * time.Second},
+		endpoint:  endpoint,
+		bucket:    cfg.Bucket,
+		region:    region,
+		accessKey: cfg.AccessKey,
+		secretKey: cfg.SecretKey,
+		publicURL: publicURL,
+		now:       time.Now,
+	}, nil
+}
+
+// Write writes data to the bucket using a name and file extension to
+// generate the object name.
+func (s *S3Storage) Write(id, format string, data []byte) (string, int64, error) {
+	rsp, err := s.do(http.MethodPut, objectName(id, format), data, contentType(format))
+	if err != nil {
+		return "", 0, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return "", 0, s3Error(rsp)
+	}
+	return s.publicURL + "/" + objectName(id, format), int64(len(data)), nil
+}
+
+// Read reads the data for a blob with a given ID and file type from
+// the bucket.
+func (s *S3Storage) Read(id, format string) ([]byte, error) {
+	rsp, err := s.do(http.MethodGet, objectName(id, format), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	switch rsp.StatusCode {
+	case http.StatusOK:
+		return ioutil.ReadAll(rsp.Body)
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, s3Error(rsp)
+	}
+}
+
+// Exists checks whether a blob with a given ID and file type exists
+// in the bucket.
+func (s *S3Storage) Exists(id, format string) (bool, error) {
+	rsp, err := s.do(http.MethodHead, objectName(id, format), nil, "")
+	if err != nil {
+		return false, err
+	}
+	defer rsp.Body.Close()
+	switch rsp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, s3Error(rsp)
+	}
+}
+
+// Delete deletes a blob with a given ID and file type from the
+// bucket.
+func (s *S3Storage) Delete(id, format string) error {
+	rsp, err := s.do(http.MethodDelete, objectName(id, format), nil, "")
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	switch rsp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return s3Error(rsp)
+	}
+}
+
+// Make a signed request for an object in the bucket.
+func (s *S3Storage) do(method, key string, body []byte, ctype string) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = s.endpoint.Path + "/" + s.bucket + "/" + key
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if ctype != "" {
+		req.Header.Set("Content-Type", ctype)
+	}
+	s.sign(req, body, s.now())
+	return s.client.Do(req)
+}
+
+// sign adds AWS Signature Version 4 authentication headers to a
+// request. All headers already set on the request are signed, along
+// with the host.
+func (s *S3Storage) sign(req *http.Request, body []byte, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Canonical headers: lower-case names, sorted, trimmed values.
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	canonHeaders := strings.Builder{}
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{}
+	for _, k := range keys {
+		vs := q[k]
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, url.QueryEscape(k)+"="+strings.Replace(url.QueryEscape(v), "+", "%20", -1))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// Error from an S3 response, including the start of the response
+// body, which holds an XML error description.
+func s3Error(rsp *http.Response) error {
+	msg, _ := ioutil.ReadAll(io.LimitReader(rsp.Body, 512))
+	return fmt.Errorf("S3 request failed: %s: %s", rsp.Status, strings.TrimSpace(string(msg)))
+}
+
+// Content type for blob file formats.
+func contentType(format string) string {
+	switch format {
+	case "jpg", "jpeg":
+		return "image/jpeg"
+	case "png":
+		return "image/png"
+	case "webp":
+		return "image/webp"
+	case "gif":
+		return "image/gif"
+	default:
+		return "application/octet-stream"
+	}
+}
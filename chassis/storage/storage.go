@@ -1,11 +1,28 @@
 package storage
 
+import "errors"
+
+// ErrNotFound is the error returned when an attempt is made to read
+// blob data that doesn't exist in the blob storage.
+var ErrNotFound = errors.New("blob data not found")
+
 // Storage describes the blob storage operations used by the blob
 // service.
 type Storage interface {
 	// Write uploads data for a given blob ID to the blob storage.
 	Write(id, format string, data []byte) (string, int64, error)
 
+	// Read downloads the data stored for a given blob ID.
+	Read(id, format string) ([]byte, error)
+
+	// Exists checks whether data is stored for a given blob ID.
+	Exists(id, format string) (bool, error)
+
 	// Delete deletes the data stored for a given blob ID.
 	Delete(id, format string) error
 }
+
+// Object name used to store the data for a blob.
+func objectName(id, format string) string {
+	return id + "." + format
+}
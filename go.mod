@@ -3,23 +3,19 @@ module github.com/veganbase/backend
 go 1.15
 
 require (
-	cloud.google.com/go v0.46.3
 	cloud.google.com/go/pubsub v1.0.1
 	cloud.google.com/go/storage v1.0.0
 	github.com/ajg/form v1.5.1 // indirect
 	github.com/c2h5oh/datasize v0.0.0-20171227191756-4eba002a5eae
-	github.com/denisenkom/go-mssqldb v0.10.0 // indirect
 	github.com/fasthttp-contrib/websocket v0.0.0-20160511215533-1f3b11f56072 // indirect
 	github.com/fatih/structs v1.1.0 // indirect
 	github.com/fsnotify/fsnotify v1.4.9 // indirect
 	github.com/gavv/httpexpect v2.0.0+incompatible
-	github.com/go-bindata/go-bindata v3.1.2+incompatible // indirect
 	github.com/go-chi/chi v4.0.2+incompatible
-	github.com/go-co-op/gocron v0.1.1
 	github.com/go-redis/redis v6.15.5+incompatible
-	github.com/gobuffalo/packr v1.30.1 // indirect
 	github.com/google/go-querystring v1.0.0 // indirect
 	github.com/gorilla/csrf v1.6.2
+	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/gosimple/slug v1.5.0
 	github.com/h2non/filetype v1.0.8
 	github.com/hashicorp/golang-lru v0.5.1
@@ -28,13 +24,11 @@ require (
 	github.com/joeshaw/envdecode v0.0.0-20190604014844-d6d9849fcc2c
 	github.com/k0kubun/colorstring v0.0.0-20150214042306-9440f1994b88 // indirect
 	github.com/lib/pq v1.10.0
-	github.com/magiconair/properties v1.8.5 // indirect
 	github.com/mailjet/mailjet-apiv3-go v0.0.0-20190529142618-4dadbd625a6a
 	github.com/mattn/go-colorable v0.1.4 // indirect
-	github.com/mitchellh/mapstructure v1.4.1 // indirect
 	github.com/moul/http2curl v1.0.0 // indirect
 	github.com/onsi/ginkgo v1.10.2 // indirect
-	github.com/pelletier/go-toml v1.9.1 // indirect
+	github.com/onsi/gomega v1.7.0 // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/rainycape/unidecode v0.0.0-20150907023854-cb7f23ec59be // indirect
 	github.com/rs/cors v1.6.0
@@ -42,17 +36,11 @@ require (
 	github.com/rubenv/sql-migrate v0.0.0-20210408115534-a32ed26c37ea
 	github.com/segmentio/ksuid v1.0.2
 	github.com/sergi/go-diff v1.0.0 // indirect
-	github.com/spf13/afero v1.6.0 // indirect
-	github.com/spf13/cast v1.3.1 // indirect
-	github.com/spf13/cobra v1.1.3 // indirect
-	github.com/spf13/jwalterweatherman v1.1.0 // indirect
-	github.com/spf13/viper v1.7.1 // indirect
+	github.com/smartystreets/goconvey v1.6.4 // indirect
 	github.com/stretchr/objx v0.3.0 // indirect
 	github.com/stretchr/testify v1.7.0
 	github.com/stripe/stripe-go v66.0.0+incompatible
 	github.com/valyala/fasthttp v1.4.0 // indirect
-	github.com/vektra/mockery v1.1.2 // indirect
-	github.com/vektra/mockery/v2 v2.7.5 // indirect
 	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
 	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
 	github.com/xeipuuv/gojsonschema v1.1.0
@@ -61,17 +49,14 @@ require (
 	github.com/yudai/golcs v0.0.0-20170316035057-ecda9a501e82 // indirect
 	github.com/yudai/pp v2.0.1+incompatible // indirect
 	github.com/ziutek/mymysql v1.5.4 // indirect
-	golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a
-	golang.org/x/sys v0.0.0-20210514084401-e8d321eab015 // indirect
-	golang.org/x/term v0.0.0-20210503060354-a79de5458b56 // indirect
-	golang.org/x/text v0.3.6 // indirect
-	golang.org/x/tools v0.1.1 // indirect
+	golang.org/x/crypto v0.23.0
+	golang.org/x/exp v0.0.0-20191030013958-a1ab85dbe136 // indirect
+	golang.org/x/image v0.18.0
+	golang.org/x/lint v0.0.0-20190930215403-16217165b5de // indirect
 	google.golang.org/api v0.13.0
+	google.golang.org/genproto v0.0.0-20191108220845-16a3f7862a1a // indirect
 	google.golang.org/grpc v1.22.0
-	google.golang.org/grpc/cmd/protoc-gen-go-grpc v1.1.0 // indirect
-	google.golang.org/protobuf v1.26.0 // indirect
+	google.golang.org/protobuf v1.26.0
 	googlemaps.github.io/maps v0.0.0-20200130222743-aef6b08443c7
-	gopkg.in/gorp.v1 v1.7.2 // indirect
-	gopkg.in/ini.v1 v1.62.0 // indirect
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
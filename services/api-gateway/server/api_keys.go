@@ -0,0 +1,67 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"time"
+
+	"github.com/veganbase/backend/chassis"
+	user_events "github.com/veganbase/backend/services/user-service/events"
+	user_model "github.com/veganbase/backend/services/user-service/model"
+)
+
+// Maximum number of verified API keys held in the cache.
+const apiKeyCacheSize = 4096
+
+// Verified API keys are re-checked with the user service after this
+// time, even if no invalidation message has been received. This also
+// keeps the "last used" times recorded by the user service
+// approximately correct.
+const apiKeyCacheTTL = 5 * time.Minute
+
+// cachedAPIKey is the information cached for a verified API key. The
+// secret is held only as a SHA-256 digest, which is enough to check
+// that later requests use the same secret without keeping the secret
+// itself in memory.
+type cachedAPIKey struct {
+	secretDigest [sha256.Size]byte
+	auth         user_model.APIKeyAuth
+	verifiedAt   time.Time
+}
+
+// newAPIKeyCache creates the cache of verified API keys, which is
+// invalidated by the user service when keys are replaced or deleted.
+func (s *Server) newAPIKeyCache() (*chassis.Cache, error) {
+	return chassis.NewCache(apiKeyCacheSize, s.PubSub,
+		user_events.APIKeyCacheInvalTopic, s.AppName)
+}
+
+// verifyAPIKey checks an API key and secret, using the cache of
+// verified keys if possible and falling back to the user service.
+func (s *Server) verifyAPIKey(key, secret string) (*user_model.APIKeyAuth, bool) {
+	now := time.Now()
+	digest := sha256.Sum256([]byte(secret))
+	if s.apiKeys != nil {
+		if v, ok := s.apiKeys.Get(key); ok {
+			entry := v.(*cachedAPIKey)
+			fresh := now.Sub(entry.verifiedAt) < apiKeyCacheTTL &&
+				(entry.auth.ExpiresAt == nil || now.Before(*entry.auth.ExpiresAt))
+			if fresh && subtle.ConstantTimeCompare(digest[:], entry.secretDigest[:]) == 1 {
+				return &entry.auth, true
+			}
+		}
+	}
+
+	auth, err := s.userSvc.VerifyAPIKey(key, secret)
+	if err != nil {
+		return nil, false
+	}
+	if s.apiKeys != nil {
+		s.apiKeys.Set(key, &cachedAPIKey{
+			secretDigest: digest,
+			auth:         *auth,
+			verifiedAt:   now,
+		})
+	}
+	return auth, true
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/chassis/pubsub"
+	user_events "github.com/veganbase/backend/services/user-service/events"
+	user_mocks "github.com/veganbase/backend/services/user-service/mocks"
+	user_model "github.com/veganbase/backend/services/user-service/model"
+)
+
+func TestVerifyAPIKeyCache(t *testing.T) {
+	cache, err := chassis.NewCache(16, pubsub.NewMockPubSub(map[string][][]byte{}),
+		user_events.APIKeyCacheInvalTopic, "test")
+	assert.Nil(t, err)
+	userSvc := &user_mocks.Client{}
+	s := &Server{userSvc: userSvc, apiKeys: cache}
+
+	auth := &user_model.APIKeyAuth{UserID: "usr_TESTUSER1"}
+	userSvc.On("VerifyAPIKey", "key1", "secret1").Return(auth, nil)
+	userSvc.On("VerifyAPIKey", "key1", "wrong").Return(nil, errors.New("not found"))
+
+	// First use goes to the user service, later uses hit the cache.
+	for i := 0; i < 3; i++ {
+		got, ok := s.verifyAPIKey("key1", "secret1")
+		assert.True(t, ok)
+		assert.Equal(t, "usr_TESTUSER1", got.UserID)
+	}
+	userSvc.AssertNumberOfCalls(t, "VerifyAPIKey", 1)
+
+	// A cached key with the wrong secret is always checked.
+	_, ok := s.verifyAPIKey("key1", "wrong")
+	assert.False(t, ok)
+	userSvc.AssertNumberOfCalls(t, "VerifyAPIKey", 2)
+
+	// Stale entries and invalidated keys are verified again.
+	v, _ := cache.Get("key1")
+	v.(*cachedAPIKey).verifiedAt = time.Now().Add(-2 * apiKeyCacheTTL)
+	_, ok = s.verifyAPIKey("key1", "secret1")
+	assert.True(t, ok)
+	userSvc.AssertNumberOfCalls(t, "VerifyAPIKey", 3)
+	cache.Delete("key1")
+	_, ok = s.verifyAPIKey("key1", "secret1")
+	assert.True(t, ok)
+	userSvc.AssertNumberOfCalls(t, "VerifyAPIKey", 4)
+
+	// Expired keys aren't served from the cache.
+	v, _ = cache.Get("key1")
+	expired := time.Now().Add(-time.Minute)
+	v.(*cachedAPIKey).auth.ExpiresAt = &expired
+	userSvc.ExpectedCalls = nil
+	userSvc.On("VerifyAPIKey", "key1", "secret1").Return(nil, errors.New("not found"))
+	_, ok = s.verifyAPIKey("key1", "secret1")
+	assert.False(t, ok)
+}
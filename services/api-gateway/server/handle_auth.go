@@ -2,6 +2,7 @@ package server
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/veganbase/backend/chassis"
@@ -20,6 +21,15 @@ func (s *Server) requestLoginEmail(w http.ResponseWriter, r *http.Request) (inte
 		return chassis.BadRequest(w, err.Error())
 	}
 
+	// As well as the per-IP limit on this route, login emails to each
+	// address are limited, so that requests for one account spread
+	// across many IP addresses (credential stuffing, or flooding a
+	// user's inbox) are still limited.
+	target := strings.ToLower(strings.TrimSpace(body.Email))
+	if s.rateLimits != nil && !takeToken(s.rateLimits, w, loginTargetPolicy, "email:"+target) {
+		return nil, nil
+	}
+
 	// Look up request site based on Origin header.
 	site := "veganlogin"
 	origins, ok := r.Header["Origin"]
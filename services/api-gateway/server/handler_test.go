@@ -75,8 +75,11 @@ func TestTokenRequest(t *testing.T) {
 			On("SaveEvent", mock.Anything, mock.Anything, mock.Anything).
 			Return(nil).
 			Run(func(args mock.Arguments) {
-				inTx := args.Get(2).(func() error)
-				inTx()
+				// Saved events are published by the outbox relay, so
+				// record them as if they had been published.
+				topic := args.String(0)
+				d, _ := json.Marshal(args.Get(1))
+				messages[topic] = append(messages[topic], d)
 			})
 
 		// No request body.
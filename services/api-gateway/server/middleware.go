@@ -1,6 +1,7 @@
 package server
 
 import (
+	"net"
 	"net/http"
 	"strings"
 
@@ -46,13 +47,13 @@ func (s *Server) addCSRFMiddleware(r chi.Router, devMode bool, csrfSecret string
 // Add middleware specific to API gateway.
 // Composed by CORS protection, RealIP and logging features.
 func (s *Server) addCORSMiddleware(r chi.Router) {
-	r.Use(middleware.RealIP)
+	r.Use(trustedRealIP(s.trustedProxies))
 
 	// Add common middleware.
 	chassis.AddCommonMiddleware(r, false)
 
-	// Very basic concurrent request service throttling.
-	// TODO: PER-ROUTE, PER-IP RATE LIMITING.
+	// Very basic concurrent request service throttling. (Per-client
+	// rate limits are applied to individual routes: see RateLimit.)
 	r.Use(middleware.Throttle(1000))
 
 	// Add CORS configuration. The origin checking function uses a list
@@ -73,6 +74,15 @@ func (s *Server) addCORSMiddleware(r chi.Router) {
 	r.Use(co.Handler)
 }
 
+// Rate limiting middleware, or a pass-through if rate limiting is
+// disabled.
+func (s *Server) rateLimit() func(http.Handler) http.Handler {
+	if s.rateLimits == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return RateLimit(s.rateLimits)
+}
+
 // CORS origin checking is based on a list of known sites. Origin
 // checking can be bypassed for development purposes by setting an
 // X-Relax-Cors header containing a secret key value set up on server
@@ -127,22 +137,111 @@ func CredentialCtx(s *Server) func(http.Handler) http.Handler {
 				return
 			}
 
+			// Only verified credentials identify the client for rate
+			// limiting: an API key sent along with a session cookie
+			// isn't checked, so it mustn't be used.
 			authInfo := chassis.AuthInfo{}
+			identity := ""
 			if session != "" {
 				if userID, _, isAdmin, err := s.db.LookupSession(session); err == nil {
 					authInfo.AuthMethod = chassis.SessionAuth
 					authInfo.UserID = userID
 					authInfo.UserIsAdmin = isAdmin
+					identity = "user:" + userID
 				}
 			} else {
-				//TODO: CACHE MAY BE WORTH IT
-				if user, err := s.userSvc.GetUserByApiKey(apiKey, apiSecret); err == nil {
+				if auth, ok := s.verifyAPIKey(apiKey, apiSecret); ok {
 					authInfo.AuthMethod = chassis.SessionAuth
-					authInfo.UserID = user.ID
-					authInfo.UserIsAdmin = user.IsAdmin
+					authInfo.UserID = auth.UserID
+					authInfo.UserIsAdmin = auth.IsAdmin
+					identity = "key:" + apiKey
 				}
 			}
-			next.ServeHTTP(w, r.WithContext(chassis.NewAuthContext(r.Context(), &authInfo)))
+			ctx := chassis.NewAuthContext(r.Context(), &authInfo)
+			if identity != "" {
+				ctx = withRateLimitIdentity(ctx, identity)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// trustedRealIP is middleware that sets a request's RemoteAddr to the
+// client's IP address from the X-Forwarded-For or X-Real-IP headers,
+// but only when the request comes from one of a list of trusted
+// proxies (e.g. the load balancer). Otherwise anyone could pick their
+// own IP address, and so get round per-IP rate limits. For
+// X-Forwarded-For, the client is the right-most address that isn't a
+// trusted proxy, since proxies append to the header.
+func trustedRealIP(proxies []*net.IPNet) func(http.Handler) http.Handler {
+	trusted := func(addr string) bool {
+		ip := net.ParseIP(strings.TrimSpace(addr))
+		if ip == nil {
+			return false
+		}
+		for _, p := range proxies {
+			if p.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer := r.RemoteAddr
+			if host, _, err := net.SplitHostPort(peer); err == nil {
+				peer = host
+			}
+			if !trusted(peer) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := ""
+			if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+				addrs := strings.Split(xff, ",")
+				for i := len(addrs) - 1; i >= 0; i-- {
+					addr := strings.TrimSpace(addrs[i])
+					if net.ParseIP(addr) == nil {
+						break
+					}
+					client = addr
+					if !trusted(addr) {
+						break
+					}
+				}
+			} else if xrip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(xrip) != nil {
+				client = xrip
+			}
+			if client != "" {
+				r.RemoteAddr = client
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// parseTrustedProxies parses a comma-separated list of IP addresses
+// and CIDR ranges.
+func parseTrustedProxies(list string) ([]*net.IPNet, error) {
+	proxies := []*net.IPNet{}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, ipnet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, ipnet)
+	}
+	return proxies, nil
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/veganbase/backend/chassis"
+)
+
+// ratePolicy is a token bucket rate limit: clients can make burst
+// requests at once, and the bucket refills at rate requests per
+// second.
+type ratePolicy struct {
+	name  string
+	rate  float64
+	burst int
+}
+
+// Rate limit policies. Buckets are kept separately for each policy,
+// so that, for example, heavy item browsing doesn't stop a user from
+// logging in.
+var (
+	loginEmailPolicy  = ratePolicy{"login-email", 5.0 / 3600, 5}
+	loginTargetPolicy = ratePolicy{"login-email-target", 5.0 / 3600, 5}
+	loginPolicy       = ratePolicy{"login", 10.0 / 60, 10}
+	readPolicy        = ratePolicy{"read", 20, 200}
+	defaultPolicy     = ratePolicy{"default", 5, 100}
+)
+
+// routePolicy assigns a rate limit policy to requests with a given
+// method (or any method, if empty) and path prefix.
+type routePolicy struct {
+	method string
+	prefix string
+	policy ratePolicy
+}
+
+// Per-route rate limit policies, checked in order. Requests that
+// don't match any of these use the default policy.
+var routePolicies = []routePolicy{
+	{"POST", "/auth/request-login-email", loginEmailPolicy},
+	{"POST", "/auth/login", loginPolicy},
+	{"GET", "/items", readPolicy},
+	{"GET", "/item/", readPolicy},
+	{"GET", "/item-collection", readPolicy},
+	{"GET", "/tag/", readPolicy},
+	{"GET", "/categories", readPolicy},
+	{"GET", "/category", readPolicy},
+	{"GET", "/search", readPolicy},
+}
+
+// Find the rate limit policy for a request.
+func policyFor(r *http.Request) ratePolicy {
+	for _, rp := range routePolicies {
+		if (rp.method == "" || rp.method == r.Method) &&
+			strings.HasPrefix(r.URL.Path, rp.prefix) {
+			return rp.policy
+		}
+	}
+	return defaultPolicy
+}
+
+// tokenStore holds the token buckets used for rate limiting. The
+// in-memory store is used by default; chassis.RedisClient provides a
+// store shared between API gateway replicas.
+type tokenStore interface {
+	TakeToken(key string, rate float64, burst int) (bool, float64, error)
+}
+
+// Context key for the identity of a client whose credentials have
+// been verified by CredentialCtx.
+type rateLimitCtxKey struct{}
+
+// withRateLimitIdentity records the verified identity of the client
+// making a request, for rate limiting: "user:<user ID>" for session
+// authentication or "key:<API key>" for API key authentication.
+func withRateLimitIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, rateLimitCtxKey{}, identity)
+}
+
+// Identify the client making a request for rate limiting purposes.
+// Requests using an API key are limited per key, and other
+// authenticated requests per user. Only credentials that
+// CredentialCtx has verified are used, so clients can't get a fresh
+// allowance by making up API keys or session cookies. Unauthenticated
+// requests are limited per IP address (the trustedRealIP middleware
+// only takes the address from X-Forwarded-For or X-Real-IP headers
+// set by trusted proxies).
+func rateLimitClient(r *http.Request) string {
+	if identity, ok := r.Context().Value(rateLimitCtxKey{}).(string); ok && identity != "" {
+		return identity
+	}
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return "ip:" + ip
+}
+
+// RateLimit is middleware that applies per-route, per-client rate
+// limits. Responses carry RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers, and requests over the limit are rejected
+// with "429 Too Many Requests" and a Retry-After header. If the token
+// store fails, requests are allowed through.
+func RateLimit(store tokenStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy := policyFor(r)
+			if takeToken(store, w, policy, rateLimitClient(r)) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// takeToken takes a token from the bucket for a client under a rate
+// limit policy, setting the rate limit response headers. If the
+// client is over the limit, a "429 Too Many Requests" response is
+// written and false is returned. If the token store fails, the
+// request is allowed.
+func takeToken(store tokenStore, w http.ResponseWriter, policy ratePolicy, client string) bool {
+	key := "ratelimit:" + policy.name + ":" + client
+	ok, remaining, err := store.TakeToken(key, policy.rate, policy.burst)
+	if err != nil {
+		log.Error().Err(err).Msg("checking rate limit")
+		return true
+	}
+
+	// Time until the bucket is full again.
+	reset := math.Ceil((float64(policy.burst) - remaining) / policy.rate)
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(policy.burst))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(int(remaining)))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(int(reset)))
+	if !ok {
+		// Time until there's a token available.
+		retry := math.Ceil((1 - remaining) / policy.rate)
+		w.Header().Set("Retry-After", strconv.Itoa(int(retry)))
+		chassis.TooManyRequests(w)
+		return false
+	}
+	return true
+}
+
+// memoryTokens is an in-memory token store, used when the API gateway
+// runs as a single replica.
+type memoryTokens struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+// How often to remove idle buckets from the in-memory store.
+const sweepInterval = time.Minute
+
+func newMemoryTokens() *memoryTokens {
+	return &memoryTokens{
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// TakeToken takes a token from a bucket if there is one.
+func (m *memoryTokens) TakeToken(key string, rate float64, burst int) (bool, float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+
+	// Buckets that have refilled completely are the same as new
+	// buckets, so can be dropped.
+	if now.Sub(m.lastSweep) > sweepInterval {
+		for k, b := range m.buckets {
+			if now.After(b.full) {
+				delete(m.buckets, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst),
+		b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second)))
+	return allowed, b.tokens, nil
+}
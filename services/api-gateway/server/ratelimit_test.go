@@ -0,0 +1,227 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/veganbase/backend/chassis/pubsub"
+	"github.com/veganbase/backend/services/api-gateway/mocks"
+)
+
+func TestMemoryTokens(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := newMemoryTokens()
+	m.now = func() time.Time { return now }
+	m.lastSweep = now
+
+	// A new bucket allows a burst of requests.
+	for i := 0; i < 3; i++ {
+		ok, remaining, _ := m.TakeToken("k", 1, 3)
+		if !ok || remaining != float64(2-i) {
+			t.Fatalf("take %d: %v, %v", i, ok, remaining)
+		}
+	}
+	if ok, _, _ := m.TakeToken("k", 1, 3); ok {
+		t.Error("token taken from empty bucket")
+	}
+
+	// Other keys have their own buckets.
+	if ok, _, _ := m.TakeToken("other", 1, 3); !ok {
+		t.Error("no token in new bucket")
+	}
+
+	// Buckets refill over time, up to the burst size.
+	now = now.Add(1500 * time.Millisecond)
+	ok, remaining, _ := m.TakeToken("k", 1, 3)
+	if !ok || remaining != 0.5 {
+		t.Errorf("after refill: %v, %v", ok, remaining)
+	}
+	now = now.Add(time.Hour)
+	if _, remaining, _ := m.TakeToken("k", 1, 3); remaining != 2 {
+		t.Errorf("after full refill: %v", remaining)
+	}
+
+	// Full buckets are swept away.
+	if len(m.buckets) != 1 {
+		t.Errorf("%d buckets after sweep", len(m.buckets))
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := RateLimit(newMemoryTokens())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	do := func(method, path, ip string, identity string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		r.RemoteAddr = ip + ":1234"
+		if identity != "" {
+			r = r.WithContext(withRateLimitIdentity(r.Context(), identity))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// Login email requests are tightly limited per IP address.
+	for i := 0; i < loginEmailPolicy.burst; i++ {
+		w := do("POST", "/auth/request-login-email", "10.0.0.1", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, w.Code)
+		}
+	}
+	w := do("POST", "/auth/request-login-email", "10.0.0.1", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d after burst", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "720" ||
+		w.Header().Get("RateLimit-Limit") != "5" ||
+		w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("headers: %v", w.Header())
+	}
+
+	// Other IP addresses and other routes are unaffected.
+	if w := do("POST", "/auth/request-login-email", "10.0.0.2", ""); w.Code != http.StatusOK {
+		t.Errorf("other IP: status %d", w.Code)
+	}
+	w = do("GET", "/items", "10.0.0.1", "")
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "200" {
+		t.Errorf("item read: status %d, headers %v", w.Code, w.Header())
+	}
+
+	// Authenticated users are limited per user, not per IP address.
+	user := "user:usr_TEST1"
+	for i := 0; i < defaultPolicy.burst; i++ {
+		do("POST", "/items", "10.0.0.3", user)
+	}
+	if w := do("POST", "/items", "10.0.0.4", user); w.Code != http.StatusTooManyRequests {
+		t.Errorf("user over limit: status %d", w.Code)
+	}
+	if w := do("POST", "/items", "10.0.0.3", ""); w.Code != http.StatusOK {
+		t.Errorf("unauthenticated from same IP: status %d", w.Code)
+	}
+}
+
+func TestRateLimitVerifiedIdentity(t *testing.T) {
+	dbm := &mocks.DB{}
+	dbm.On("LookupSession", "SESSION1").Return("usr_TEST1", "test@testing.com", false, nil)
+	dbm.On("LookupSession", mock.Anything).Return("", "", false, errors.New("unknown session"))
+	s := &Server{db: dbm}
+	handler := CredentialCtx(s)(RateLimit(newMemoryTokens())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})))
+	do := func(session, apiKey string) int {
+		r := httptest.NewRequest("POST", "/items", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		if session != "" {
+			r.AddCookie(&http.Cookie{Name: "session", Value: session})
+		}
+		if apiKey != "" {
+			r.Header.Set("X-Api-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// A logged-in user can't get a fresh bucket by sending a different
+	// (unverified) API key with each request.
+	for i := 0; i < defaultPolicy.burst; i++ {
+		if code := do("SESSION1", "key-"+strconv.Itoa(i)); code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, code)
+		}
+	}
+	if code := do("SESSION1", "key-new"); code != http.StatusTooManyRequests {
+		t.Errorf("user over limit with made-up API key: status %d", code)
+	}
+
+	// Invalid session cookies are limited per IP address.
+	if code := do("BAD", ""); code != http.StatusOK {
+		t.Errorf("invalid session: status %d", code)
+	}
+}
+
+func TestTrustedRealIP(t *testing.T) {
+	proxies, err := parseTrustedProxies("10.1.0.0/16, 192.168.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseTrustedProxies("not-an-address"); err == nil {
+		t.Error("invalid proxy address accepted")
+	}
+
+	var got string
+	handler := trustedRealIP(proxies)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.RemoteAddr
+		}))
+
+	tests := []struct {
+		remote  string
+		headers map[string]string
+		client  string
+	}{
+		// Headers from untrusted peers are ignored.
+		{"203.0.113.5:1234", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "203.0.113.5:1234"},
+		{"203.0.113.5:1234", map[string]string{"X-Real-IP": "1.2.3.4"}, "203.0.113.5:1234"},
+		// Trusted proxies are believed, taking the right-most address
+		// that isn't a trusted proxy: addresses to its left are
+		// client-supplied.
+		{"10.1.2.3:1234", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "1.2.3.4"},
+		{"10.1.2.3:1234", map[string]string{"X-Forwarded-For": "6.6.6.6, 1.2.3.4, 192.168.0.1"}, "1.2.3.4"},
+		{"192.168.0.1:1234", map[string]string{"X-Real-IP": "1.2.3.4"}, "1.2.3.4"},
+		// Garbage in the headers is ignored.
+		{"10.1.2.3:1234", map[string]string{"X-Forwarded-For": "junk"}, "10.1.2.3:1234"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = test.remote
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if got != test.client {
+			t.Errorf("%s %v: got client %s, expected %s", test.remote, test.headers, got, test.client)
+		}
+	}
+}
+
+func TestLoginEmailTargetLimit(t *testing.T) {
+	dbm := &mocks.DB{}
+	dbm.On("CreateLoginToken", mock.Anything, "veganlogin", "en").Return("123456", nil)
+	dbm.On("SaveEvent", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	s := &Server{db: dbm, rateLimits: newMemoryTokens(), siteURLs: map[string]string{}}
+	s.PubSub = pubsub.NewMockPubSub(map[string][][]byte{})
+
+	do := func(email, ip string) int {
+		body, _ := json.Marshal(map[string]string{"email": email})
+		r := httptest.NewRequest("POST", "/auth/request-login-email", strings.NewReader(string(body)))
+		r.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		s.requestLoginEmail(w, r)
+		return w.Code
+	}
+
+	// Requests for one address are limited, however many IP addresses
+	// they come from.
+	for i := 0; i < loginTargetPolicy.burst; i++ {
+		if code := do("victim@testing.com", "10.0.0."+strconv.Itoa(i)); code != http.StatusNoContent {
+			t.Fatalf("request %d: status %d", i, code)
+		}
+	}
+	if code := do("Victim@Testing.com ", "10.0.1.1"); code != http.StatusTooManyRequests {
+		t.Errorf("over limit from new IP: status %d", code)
+	}
+	if code := do("other@testing.com", "10.0.1.1"); code != http.StatusNoContent {
+		t.Errorf("other address: status %d", code)
+	}
+}
@@ -27,12 +27,16 @@ func (s *Server) routes(devMode bool, csrfSecret string) chi.Router {
 		// These routes need to be outside of the following block to exempt
 		// them from CSRF protection since they're used before a session is
 		// established.
-		r.Post("/auth/request-login-email", chassis.SimpleHandler(s.requestLoginEmail))
-		r.Post("/auth/login", chassis.SimpleHandler(s.login))
+		r.With(s.rateLimit()).Post("/auth/request-login-email", chassis.SimpleHandler(s.requestLoginEmail))
+		r.With(s.rateLimit()).Post("/auth/login", chassis.SimpleHandler(s.login))
 
 		r.Group(func(r chi.Router) {
 			r.Use(CredentialCtx(s))
 
+			// Rate limiting comes after credential checking so that
+			// authenticated clients are limited per user or API key.
+			r.Use(s.rateLimit())
+
 			// Authentication.
 			r.Post("/auth/logout", chassis.SimpleHandler(s.logout))
 			r.Post("/auth/logout-all", chassis.SimpleHandler(s.logoutAll))
@@ -43,6 +47,7 @@ func (s *Server) routes(devMode bool, csrfSecret string) chi.Router {
 			// Routes for customer (separate from userRoutes because /user/id/customer is internal)
 			r.Route("/me/customer", s.customerRoutes)
 			r.Route("/me/delivery-fees", s.deliveryFeesRoutes)
+			r.Route("/me/tax-settings", s.taxSettingsRoutes)
 
 			//Routes for other user (administrator only apart from GET
 			// /user/{id} which returns a user's public profile).
@@ -88,12 +93,20 @@ func (s *Server) routes(devMode bool, csrfSecret string) chi.Router {
 			r.Method("PUT", "/cart/forget", Forward(s.cartSvcURL))
 			r.Route(`/cart/{cart_id:car_[a-zA-Z0-9]+}`, s.cartRoutes)
 
+			// Promotions
+			r.Method("GET", "/promotions", Forward(s.cartSvcURL))
+			r.Method("POST", "/promotions", Forward(s.cartSvcURL))
+			r.Route(`/promotion/{id:prm_[a-zA-Z0-9]+}`, s.promotionRoutes)
+
 			// Purchase
 			r.Method("GET", "/purchases", Forward(s.purchaseSvcURL))
 			r.Method("GET", "/orders", Forward(s.purchaseSvcURL))
 			r.Method("GET", "/bookings", Forward(s.purchaseSvcURL))
 			r.Method("POST", "/simple-purchase", Forward(s.purchaseSvcURL))
 			r.Method("GET", "/item-subscriptions", Forward(s.purchaseSvcURL))
+			r.Method("GET", "/tax-rates", Forward(s.purchaseSvcURL))
+			r.Method("PUT", "/tax-rates/{country:[a-zA-Z]{2}}", Forward(s.purchaseSvcURL))
+			r.Method("DELETE", "/tax-rates/{country:[a-zA-Z]{2}}", Forward(s.purchaseSvcURL))
 			r.Route(`/purchase`, s.purchaseRoutes)
 			r.Route(`/booking`, s.purchaseRoutes)
 			r.Route(`/order`, s.purchaseRoutes)
@@ -104,6 +117,17 @@ func (s *Server) routes(devMode bool, csrfSecret string) chi.Router {
 			r.Route("/social", s.socialRoutes)
 			r.Route("/post", s.socialRoutes)
 			r.Route("/reply", s.socialRoutes)
+			r.Method("GET", "/me/feed", Forward(s.socialSvcURL))
+			r.Route("/moderation", s.moderationRoutes)
+
+			// Direct messaging
+			r.Method("GET", "/me/threads", Forward(s.socialSvcURL))
+			r.Method("POST", "/threads", Forward(s.socialSvcURL))
+			r.Route("/thread/{thread_id:thr_[a-zA-Z0-9]+}", s.threadRoutes)
+
+			// Exchange rates (setting them is admin-only)
+			r.Method("GET", "/exchange-rates", Forward(s.siteSvcURL))
+			r.Method("PUT", "/exchange-rates", Forward(s.siteSvcURL))
 
 			// Payment
 			r.Method("POST", "/payment-intent", Forward(s.paymentSvcURL))
@@ -124,8 +148,10 @@ func (s *Server) userRoutes(r chi.Router) {
 	r.Method("GET", "/", Forward(s.userSvcURL))
 	r.Method("PATCH", "/", Forward(s.userSvcURL))
 	r.Method("DELETE", "/", Forward(s.userSvcURL))
+	r.Method("GET", "/api-keys", Forward(s.userSvcURL))
 	r.Method("POST", "/api-key", Forward(s.userSvcURL))
 	r.Method("DELETE", "/api-key", Forward(s.userSvcURL))
+	r.Method("DELETE", "/api-key/{name:[a-zA-Z0-9_-]+}", Forward(s.userSvcURL))
 	r.Method("GET", "/blobs", Forward(s.blobSvcURL))
 	r.Method("GET", "/items", Forward(s.itemSvcURL))
 	r.Method("GET", "/tags", Forward(s.userSvcURL))
@@ -136,6 +162,10 @@ func (s *Server) userRoutes(r chi.Router) {
 	r.Method("POST","/payout-account", Forward(s.userSvcURL))
 	r.Method("DELETE","/payout-account", Forward(s.userSvcURL))
 	r.Method("PATCH","/payout-account", Forward(s.userSvcURL))
+	r.Method("POST", "/payout-account/onboarding", Forward(s.userSvcURL))
+	r.Method("GET", "/balance", Forward(s.paymentSvcURL))
+	r.Method("GET", "/payout-statements", Forward(s.paymentSvcURL))
+	r.Method("GET", "/payout-statement/{month:[0-9]{4}-[0-9]{2}}", Forward(s.paymentSvcURL))
 	r.Method("GET", "/payment-methods", Forward(s.userSvcURL))
 	r.Method("GET", "/payment-method/{pmt_id:pmt_[a-zA-Z0-9]+}", Forward(s.userSvcURL))
 	r.Method("GET","/payment-method/default", Forward(s.userSvcURL))
@@ -170,15 +200,29 @@ func (s *Server) orgRoutes(r chi.Router) {
 	r.Method("POST","/payout-account",  Forward(s.userSvcURL))
 	r.Method("DELETE","/payout-account",  Forward(s.userSvcURL))
 	r.Method("PATCH","/payout-account",  Forward(s.userSvcURL))
+	r.Method("POST", "/payout-account/onboarding", Forward(s.userSvcURL))
+	r.Method("GET", "/balance", Forward(s.paymentSvcURL))
+	r.Method("GET", "/payout-statements", Forward(s.paymentSvcURL))
+	r.Method("GET", "/payout-statement/{month:[0-9]{4}-[0-9]{2}}", Forward(s.paymentSvcURL))
 	r.Method("GET","/delivery-fees", Forward(s.userSvcURL))
 	r.Method("POST","/delivery-fees", Forward(s.userSvcURL))
 	r.Method("DELETE","/delivery-fees", Forward(s.userSvcURL))
 	r.Method("PATCH","/delivery-fees", Forward(s.userSvcURL))
+	r.Method("GET", "/tax-settings", Forward(s.userSvcURL))
+	r.Method("PUT", "/tax-settings", Forward(s.userSvcURL))
+	r.Method("DELETE", "/tax-settings", Forward(s.userSvcURL))
+	r.Method("GET", "/threads", Forward(s.socialSvcURL))
 }
 
 func (s *Server) blobsRoutes(r chi.Router) {
 	r.Method("POST", "/", Forward(s.blobSvcURL))
 	r.Method("GET", "/tags", Forward(s.blobSvcURL))
+	r.Method("GET", "/orphans", Forward(s.blobSvcURL))
+	r.Method("POST", "/uploads", Forward(s.blobSvcURL))
+	r.Method("GET", "/upload/{upload_id:upl_[a-zA-Z0-9]+}", Forward(s.blobSvcURL))
+	r.Method("PUT", "/upload/{upload_id:upl_[a-zA-Z0-9]+}/part/{part:[0-9]+}", Forward(s.blobSvcURL))
+	r.Method("POST", "/upload/{upload_id:upl_[a-zA-Z0-9]+}/complete", Forward(s.blobSvcURL))
+	r.Method("DELETE", "/upload/{upload_id:upl_[a-zA-Z0-9]+}", Forward(s.blobSvcURL))
 }
 
 func (s *Server) blobRoutes(r chi.Router) {
@@ -201,6 +245,10 @@ func (s *Server) itemRoutes(r chi.Router) {
 	r.Method("POST", "/claim-ownership", Forward(s.itemSvcURL))
 	r.Method("POST", "/links", Forward(s.itemSvcURL))
 	r.Method("GET", "/links", Forward(s.itemSvcURL))
+	r.Method("GET", "/history", Forward(s.itemSvcURL))
+	r.Method("GET", "/revision/{n:[0-9]+}", Forward(s.itemSvcURL))
+	r.Method("GET", "/diff", Forward(s.itemSvcURL))
+	r.Method("POST", "/rollback", Forward(s.itemSvcURL))
 	r.Method("POST", "/upvote", Forward(s.socialSvcURL))
 	r.Method("DELETE", "/upvote", Forward(s.socialSvcURL))
 	r.Method("GET", "/posts", Forward(s.socialSvcURL))
@@ -232,6 +280,12 @@ func (s *Server) categoryRoutes(r chi.Router) {
 	r.Method("DELETE", "/{category:[a-z-]+}/{label:[a-z-]+}/fix", Forward(s.categorySvcURL))
 }
 
+func (s *Server) threadRoutes(r chi.Router) {
+	r.Method("GET", "/", Forward(s.socialSvcURL))
+	r.Method("PATCH", "/", Forward(s.socialSvcURL))
+	r.Method("POST", "/messages", Forward(s.socialSvcURL))
+}
+
 func (s *Server) socialRoutes(r chi.Router) {
 	r.Method("GET", "/subscriptions/", Forward(s.socialSvcURL))
 	r.Method("GET", "/followers/{subscription_id}", Forward(s.socialSvcURL))
@@ -242,6 +296,15 @@ func (s *Server) socialRoutes(r chi.Router) {
 	//r.Method("POST", "/", Forward(s.socialSvcURL))
 	r.Method("PATCH", "/{reply_id}", Forward(s.socialSvcURL))
 	r.Method("DELETE", "/{reply_id}", Forward(s.socialSvcURL))
+	r.Method("POST", "/{reply_id}/report", Forward(s.socialSvcURL))
+	r.Method("POST", "/{reply_id}/response", Forward(s.socialSvcURL))
+	r.Method("PUT", "/{reply_id}/answer", Forward(s.socialSvcURL))
+	r.Method("DELETE", "/{reply_id}/answer", Forward(s.socialSvcURL))
+}
+
+func (s *Server) moderationRoutes(r chi.Router) {
+	r.Method("GET", "/queue", Forward(s.socialSvcURL))
+	r.Method("POST", "/{type:post|reply}/{id}/{action:hide|restore|dismiss}", Forward(s.socialSvcURL))
 }
 
 func (s *Server) purchaseRoutes(r chi.Router) {
@@ -253,6 +316,11 @@ func (s *Server) purchaseRoutes(r chi.Router) {
 
 	r.Method("GET", "/{bok_id:[A-Z]{3}[-][0-9]{6}}", Forward(s.purchaseSvcURL))
 	r.Method("GET", "/{ord_id:[A-Z]{3}[-][0-9]{6}}", Forward(s.purchaseSvcURL))
+	r.Method("GET", "/{ord_id:[A-Z]{3}[-][0-9]{6}}/invoice", Forward(s.purchaseSvcURL))
+
+	// Refunds of purchases, orders and bookings are handled by the
+	// payment service.
+	r.Method("POST", "/{pur_id:[A-Z]{3}[-][0-9]{6}}/refund", Forward(s.paymentSvcURL))
 
 
 	r.Method("GET","/{sub_id:sub_[a-zA-Z0-9]+}", Forward(s.purchaseSvcURL))
@@ -266,6 +334,9 @@ func (s *Server) cartRoutes(r chi.Router) {
 	r.Method("GET", "/", Forward(s.cartSvcURL))
 	r.Method("PATCH", "/", Forward(s.cartSvcURL))
 	r.Method("PATCH", "/merge", Forward(s.cartSvcURL))
+	r.Method("PUT", "/restore", Forward(s.cartSvcURL))
+	r.Method("PUT", "/promo-code", Forward(s.cartSvcURL))
+	r.Method("DELETE", "/promo-code", Forward(s.cartSvcURL))
 	r.Method("GET", "/items", Forward(s.cartSvcURL))
 	r.Method("GET", "/item/{citem_id:[0-9]+}", Forward(s.cartSvcURL))
 	r.Method("PATCH", "/item/{citem_id:[0-9]+}", Forward(s.cartSvcURL))
@@ -273,6 +344,12 @@ func (s *Server) cartRoutes(r chi.Router) {
 	r.Method("POST", "/item", Forward(s.cartSvcURL))
 }
 
+func (s *Server) promotionRoutes(r chi.Router) {
+	r.Method("GET", "/", Forward(s.cartSvcURL))
+	r.Method("PATCH", "/", Forward(s.cartSvcURL))
+	r.Method("DELETE", "/", Forward(s.cartSvcURL))
+}
+
 func (s *Server) customerRoutes(r chi.Router) {
 	r.Method("GET", "/", Forward(s.userSvcURL))
 	r.Method("POST", "/", Forward(s.userSvcURL))
@@ -286,6 +363,12 @@ func (s *Server) deliveryFeesRoutes(r chi.Router) {
 	r.Method("PATCH", "/", Forward(s.userSvcURL))
 }
 
+func (s *Server) taxSettingsRoutes(r chi.Router) {
+	r.Method("GET", "/", Forward(s.userSvcURL))
+	r.Method("PUT", "/", Forward(s.userSvcURL))
+	r.Method("DELETE", "/", Forward(s.userSvcURL))
+}
+
 func (s *Server) searchRoutes(r chi.Router) {
 	r.Method("GET", "/countries", Forward(s.searchSvcURL))
 	r.Method("GET", "/country/{country_id}/states", Forward(s.searchSvcURL))
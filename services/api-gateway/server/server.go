@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"net"
 	"net/url"
 	"strings"
 	"sync"
@@ -23,6 +24,7 @@ type Server struct {
 	siteSvcURL        *url.URL
 	userSvc           user.Client
 	userSvcURL        *url.URL
+	apiKeys           *chassis.Cache
 	blobSvcURL        *url.URL
 	itemSvcURL        *url.URL
 	categorySvcURL    *url.URL
@@ -40,6 +42,8 @@ type Server struct {
 	muSiteURLs        sync.RWMutex
 	siteURLs          map[string]string
 	disabledCSRF      bool
+	rateLimits        tokenStore
+	trustedProxies    []*net.IPNet
 }
 
 // Config contains the configuration information needed to start
@@ -66,6 +70,18 @@ type Config struct {
 	CORSOrigins        string `env:"CORS_ORIGINS"`
 	RelaxCORSKey       string `env:"RELAX_CORS_KEY,default=no"`
 	DisableCSRF        bool   `env:"DISABLE_CSRF,default=false"`
+
+	// Rate limit token buckets are kept in memory unless a Redis
+	// address is given, in which case they're shared between all API
+	// gateway replicas using the same Redis server.
+	DisableRateLimit bool   `env:"DISABLE_RATE_LIMIT,default=false"`
+	RedisAddress     string `env:"REDIS_ADDRESS"`
+	RedisPassword    string `env:"REDIS_PASSWORD"`
+
+	// Client IP addresses are only taken from X-Forwarded-For and
+	// X-Real-IP headers on requests from these proxies (a
+	// comma-separated list of IP addresses and CIDR ranges).
+	TrustedProxies string `env:"TRUSTED_PROXIES"`
 }
 
 // NewServer creates the server structure for the blob service.
@@ -109,21 +125,39 @@ func NewServer(cfg *Config) *Server {
 		siteURLs:          map[string]string{},
 		disabledCSRF:      cfg.DisableCSRF,
 	}
+	trustedProxies, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid TRUSTED_PROXIES")
+	}
+	s.trustedProxies = trustedProxies
+	if !cfg.DisableRateLimit {
+		if cfg.RedisAddress != "" {
+			redis := chassis.NewRedisClient(cfg.RedisAddress, cfg.RedisPassword)
+			s.rateLimits = &redis
+		} else {
+			s.rateLimits = newMemoryTokens()
+		}
+	}
 
 	s.Init(cfg.AppName, cfg.Project, cfg.Port, cfg.Credentials, s.routes(cfg.DevMode, cfg.CSRFSecret))
 	s.siteSvc = site.New(cfg.SiteServiceURL, s.PubSub, s.AppName)
-	var err error
 	s.userSvc, err = user.New(cfg.UserServiceURL, s.PubSub, s.AppName)
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't initialise user service client")
 	}
+	s.apiKeys, err = s.newAPIKeyCache()
+	if err != nil {
+		log.Fatal().Err(err).Msg("couldn't initialise API key cache")
+	}
 
 	// Connect to database.
 	timeout, _ := context.WithTimeout(context.Background(), time.Second*10)
-	s.db, err = db.NewPGClient(timeout, cfg.DBURL)
+	pg, err := db.NewPGClient(timeout, cfg.DBURL)
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't connect to API gateway database")
 	}
+	s.db = pg
+	s.StartEventRelay(pg.DB)
 
 	return s
 }
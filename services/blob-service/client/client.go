@@ -1,7 +1,25 @@
 package client
 
+import (
+	"net/url"
+)
+
 // Client is the service client API for the blob service.
 type Client interface {
 	AddItemBlobs(itemID string, blobIDs []string) error
 	RemoveItemBlobs(itemID string, blobIDs []string) error
 }
+
+// Connect creates a blob service client, choosing the transport from
+// the scheme of the service URL: "grpc://host:port" uses the gRPC
+// API, and anything else is treated as the base URL for the REST API.
+func Connect(serviceURL string) (Client, error) {
+	u, err := url.Parse(serviceURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "grpc" {
+		return NewGRPC(u.Host)
+	}
+	return New(serviceURL), nil
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/veganbase/backend/services/blob-service/proto"
+)
+
+// GRPCClient is a blob service client that connects via gRPC.
+type GRPCClient struct {
+	conn    *grpc.ClientConn
+	client  pb.BlobServiceClient
+	timeout time.Duration
+}
+
+// NewGRPC creates a new blob service client connecting to the blob
+// service's gRPC API at the given address (host:port). Connections
+// are made lazily, so this doesn't fail if the blob service isn't
+// running yet.
+func NewGRPC(addr string) (*GRPCClient, error) {
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCClient{
+		conn:    conn,
+		client:  pb.NewBlobServiceClient(conn),
+		timeout: 10 * time.Second,
+	}, nil
+}
+
+// Close closes the connection to the blob service.
+func (c *GRPCClient) Close() error {
+	return c.conn.Close()
+}
+
+// AddItemBlobs adds associations between an item and a list of
+// blobs.
+func (c *GRPCClient) AddItemBlobs(itemID string, blobIDs []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	_, err := c.client.AddItemBlobs(ctx,
+		&pb.ItemBlobsRequest{ItemId: itemID, BlobIds: blobIDs})
+	return err
+}
+
+// RemoveItemBlobs removes associations between an item and a list of
+// blobs. (An empty blob ID list means to remove all item/blob
+// associations for the item.)
+func (c *GRPCClient) RemoveItemBlobs(itemID string, blobIDs []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	_, err := c.client.RemoveItemBlobs(ctx,
+		&pb.RemoveItemBlobsRequest{ItemId: itemID, BlobIds: blobIDs})
+	return err
+}
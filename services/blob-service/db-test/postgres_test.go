@@ -9,6 +9,7 @@ import (
 	"os"
 	"sort"
 	"testing"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/rs/zerolog/log"
@@ -319,3 +320,56 @@ func TestDeleteByItem(t *testing.T) {
 		}
 	})
 }
+
+func TestOrphanedBlobs(t *testing.T) {
+	RunWithSchema(t, func(pg *db.PGClient, t *testing.T) {
+		loadDefaultFixture(pg, t)
+		_, err := pg.DB.Exec(`INSERT INTO blobs (id, uri, format, size, owner, tags, created_at)
+VALUES ('blob0016', 'http://image.com/blob0016.jpg', 'image/jpeg', 24000, 'usr_TESTUSER1', '{}', now() - INTERVAL '14 minute')`)
+		assert.Nil(t, err)
+		// Blobs loaded from the fixture are all recent.
+		_, err = pg.DB.Exec(`UPDATE blobs SET unassociated_since = now() + INTERVAL '1 minute'
+                              WHERE unassociated_since IS NOT NULL`)
+		assert.Nil(t, err)
+
+		// Blobs lose their item associations, owned (blob0002) or not
+		// (blob0014, blob0015). Unowned blobs are deleted immediately.
+		_, err = pg.RemoveBlobsFromItem("item0003", []string{})
+		assert.Nil(t, err)
+		_, err = pg.RemoveBlobsFromItem("item0080", []string{})
+		assert.Nil(t, err)
+		cutoff := time.Now().Add(time.Second)
+
+		// Associating a blob with an item takes it off the list.
+		assert.Nil(t, pg.AddBlobsToItem("item0090", []string{"blob0016"}))
+
+		orphans, err := pg.OrphanedBlobs(cutoff, 100)
+		assert.Nil(t, err)
+		ids := []string{}
+		for _, o := range orphans {
+			ids = append(ids, o.ID)
+		}
+		assert.Equal(t, []string{"blob0002"}, ids)
+
+		// Removing the association again makes it an orphan.
+		_, err = pg.RemoveBlobsFromItem("item0090", []string{"blob0016"})
+		assert.Nil(t, err)
+		orphans, err = pg.OrphanedBlobs(cutoff, 100)
+		assert.Nil(t, err)
+		assert.Len(t, orphans, 2)
+
+		var tests = []struct {
+			id      string
+			deleted bool
+		}{
+			{"blob0004", false}, // Unassociated too recently.
+			{"blob0013", false}, // Associated.
+			{"blob0002", true},
+		}
+		for _, test := range tests {
+			deleted, err := pg.DeleteOrphanedBlob(test.id, cutoff)
+			assert.Nil(t, err)
+			assert.Equal(t, test.deleted, deleted, test.id)
+		}
+	})
+}
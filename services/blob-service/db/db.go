@@ -1,13 +1,18 @@
 package db
 
-import "github.com/veganbase/backend/services/blob-service/model"
+import (
+	"time"
+
+	"github.com/veganbase/backend/services/blob-service/model"
+)
 
 // DeletedBlob carries information about blobs deleted from the
 // database that is needed to delete the associated entries from the
 // blob storage.
 type DeletedBlob struct {
-	ID     string
-	Format string
+	ID         string
+	Format     string
+	Renditions model.Renditions
 }
 
 // DB describes the database operations used by the blob service.
@@ -52,6 +57,48 @@ type DB interface {
 	// were deleted as a result of this action.
 	RemoveBlobsFromItem(id string, itemIDs []string) ([]DeletedBlob, error)
 
+	// OrphanedBlobs returns up to limit blobs, owned or not, that have
+	// had no item associations since before the given time, longest
+	// orphaned first.
+	OrphanedBlobs(before time.Time, limit uint) ([]model.Blob, error)
+
+	// DeleteOrphanedBlob deletes a blob only if it has still had no
+	// item associations since before the given time, returning a flag
+	// to say whether it was deleted.
+	DeleteOrphanedBlob(id string, before time.Time) (bool, error)
+
+	// DeleteExpiredUploads deletes resumable uploads that have
+	// expired, returning the number of uploads deleted.
+	DeleteExpiredUploads() (int64, error)
+
+	// CreateUpload creates a new resumable upload, filling in its ID
+	// and creation time.
+	CreateUpload(upload *model.Upload) error
+
+	// UploadByID returns the model for an unexpired upload, including
+	// the list of parts received so far.
+	UploadByID(id string) (*model.Upload, error)
+
+	// PutUploadPart stores the data for a part of an upload, replacing
+	// any data previously uploaded for the same part.
+	PutUploadPart(id string, part int, data []byte) error
+
+	// UploadData returns the data for an upload, made by joining its
+	// parts together in order.
+	UploadData(id string) ([]byte, error)
+
+	// ClaimUpload marks an unexpired upload as being completed. Only
+	// one caller can claim an upload: others get ErrUploadClaimed until
+	// it is released.
+	ClaimUpload(id string) error
+
+	// ReleaseUpload clears the claim on an upload whose completion
+	// failed, so that it can be completed again.
+	ReleaseUpload(id string) error
+
+	// DeleteUpload deletes an upload and its parts.
+	DeleteUpload(id string) error
+
 	// SaveEvent saves an event to the database.
 	SaveEvent(topic string, eventData interface{}, inTx func() error) error
 }
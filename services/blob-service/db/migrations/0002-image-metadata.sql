@@ -0,0 +1,27 @@
+-- +migrate Up
+
+SET ROLE vb_blobs;
+
+/*
+   Information recorded when images are processed on upload. The
+   renditions column maps rendition names to the format, dimensions
+   and size of the scaled images stored alongside the blob data.
+*/
+
+ALTER TABLE blobs
+  ADD COLUMN width           INTEGER,
+  ADD COLUMN height          INTEGER,
+  ADD COLUMN dominant_colour VARCHAR(7),
+  ADD COLUMN blurhash        VARCHAR(128),
+  ADD COLUMN renditions      JSONB NOT NULL DEFAULT '{}';
+
+-- +migrate Down
+
+SET ROLE vb_blobs;
+
+ALTER TABLE blobs
+  DROP COLUMN width,
+  DROP COLUMN height,
+  DROP COLUMN dominant_colour,
+  DROP COLUMN blurhash,
+  DROP COLUMN renditions;
@@ -0,0 +1,37 @@
+-- +migrate Up
+
+SET ROLE vb_blobs;
+
+/*
+   Resumable uploads in progress. Upload parts are held here until the
+   upload is completed and the blob is created from the joined parts.
+   Uploads that aren't completed before they expire are cleaned up.
+*/
+
+CREATE TABLE uploads (
+  id               VARCHAR(24)  PRIMARY KEY,
+  owner            VARCHAR(256) NOT NULL,   /* User ID. */
+  size             INTEGER      NOT NULL,
+  part_size        INTEGER      NOT NULL,
+  tags             JSONB,
+  associated_items TEXT[]       NOT NULL DEFAULT '{}',
+  created_at       TIMESTAMPTZ  NOT NULL DEFAULT now(),
+  expires_at       TIMESTAMPTZ  NOT NULL
+);
+
+CREATE INDEX upload_expires_at_index ON uploads(expires_at);
+
+CREATE TABLE upload_parts (
+  upload_id   VARCHAR(24) NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+  part_number INTEGER     NOT NULL,
+  data        BYTEA       NOT NULL,
+
+  PRIMARY KEY (upload_id, part_number)
+);
+
+-- +migrate Down
+
+SET ROLE vb_blobs;
+
+DROP TABLE upload_parts;
+DROP TABLE uploads;
@@ -0,0 +1,25 @@
+-- +migrate Up
+
+SET ROLE vb_blobs;
+
+-- When a blob lost its last item association (or was created, for
+-- blobs that have never had any), NULL while it has associations.
+-- The garbage collector deletes blobs that have been unassociated
+-- for longer than its grace period.
+ALTER TABLE blobs ADD COLUMN unassociated_since TIMESTAMPTZ DEFAULT now();
+
+-- Existing blobs without associations get a full grace period from
+-- now.
+UPDATE blobs b SET unassociated_since = NULL
+ WHERE EXISTS (SELECT 1 FROM blob_items i WHERE i.blob_id = b.id);
+
+CREATE INDEX blob_unassociated_index ON blobs(unassociated_since)
+ WHERE unassociated_since IS NOT NULL;
+
+
+-- +migrate Down
+
+SET ROLE vb_blobs;
+
+DROP INDEX blob_unassociated_index;
+ALTER TABLE blobs DROP COLUMN unassociated_since;
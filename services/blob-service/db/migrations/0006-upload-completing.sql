@@ -0,0 +1,14 @@
+-- +migrate Up
+
+SET ROLE vb_blobs;
+
+-- Set while an upload is being completed, so that only one request
+-- can create a blob from it.
+ALTER TABLE uploads ADD COLUMN completing BOOLEAN NOT NULL DEFAULT false;
+
+
+-- +migrate Down
+
+SET ROLE vb_blobs;
+
+ALTER TABLE uploads DROP COLUMN completing;
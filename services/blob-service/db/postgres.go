@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
@@ -15,6 +16,14 @@ import (
 // access or manipulate a blob with an unknown ID.
 var ErrBlobNotFound = errors.New("blob ID not found")
 
+// ErrUploadNotFound is the error returned when an attempt is made to
+// access an unknown or expired upload.
+var ErrUploadNotFound = errors.New("upload ID not found")
+
+// ErrUploadClaimed is the error returned when an attempt is made to
+// claim an upload that is already being completed.
+var ErrUploadClaimed = errors.New("upload is already being completed")
+
 // ErrAssocNotFound is the error returned when an attempt is made to
 // access or manipulate an unknown blob/item association.
 var ErrAssocNotFound = errors.New("blob/item association not found")
@@ -60,7 +69,8 @@ func (pg *PGClient) BlobByID(id string) (*model.Blob, error) {
 }
 
 const blobByID = `
-SELECT id, format, size, owner, tags, created_at
+SELECT id, format, size, owner, tags, created_at,
+       width, height, dominant_colour, blurhash, renditions
   FROM blobs WHERE id = $1`
 
 // TODO: MAKE THIS NICER. THIS CAN BE DONE WITH A SINGLE QUERY, BUT
@@ -114,12 +124,14 @@ func (pg *PGClient) BlobsByUser(userID string,
 }
 
 const blobsByUser = `
-SELECT id, format, size, owner, tags, created_at
+SELECT id, format, size, owner, tags, created_at,
+       width, height, dominant_colour, blurhash, renditions
   FROM blobs WHERE owner = $1
  ORDER BY created_at DESC`
 
 const blobsByUserWithTags = `
-SELECT id, format, size, owner, tags, created_at
+SELECT id, format, size, owner, tags, created_at,
+       width, height, dominant_colour, blurhash, renditions
   FROM blobs WHERE owner = $1 AND tags ?| $2
  ORDER BY created_at DESC`
 
@@ -173,8 +185,10 @@ func extensionForMIMEType(mime string) string {
 }
 
 const createBlob = `
-INSERT INTO blobs (id, uri, format, size, owner, tags)
-     VALUES (:id, :uri, :format, :size, :owner, :tags)
+INSERT INTO blobs (id, uri, format, size, owner, tags,
+                   width, height, dominant_colour, blurhash, renditions)
+     VALUES (:id, :uri, :format, :size, :owner, :tags,
+             :width, :height, :dominant_colour, :blurhash, :renditions)
 RETURNING created_at`
 
 // SetBlobTags sets the tag list for a blob.
@@ -272,6 +286,9 @@ func (pg *PGClient) AddBlobsToItem(itemID string, blobIDs []string) error {
 		if err != nil {
 			return err
 		}
+		if _, err = tx.Exec(markBlobAssociated, blobID); err != nil {
+			return err
+		}
 	}
 	return err
 }
@@ -280,6 +297,9 @@ const addBlobsToItem = `
 INSERT INTO blob_items (blob_id, item_id) VALUES ($1, $2)
   ON CONFLICT DO NOTHING`
 
+const markBlobAssociated = `
+UPDATE blobs SET unassociated_since = NULL WHERE id = $1`
+
 // RemoveBlobsFromItem removes associations between a blob and a set
 // of items (if the set passed in is empty, that means to delete all
 // associations for the given item), deleting any resulting unowned
@@ -316,6 +336,18 @@ func (pg *PGClient) RemoveBlobsFromItem(itemID string, blobIDs []string) ([]Dele
 		return nil, err
 	}
 
+	// Start the garbage collection grace period for blobs that have
+	// lost their last item association.
+	if len(deletedIDs) > 0 {
+		query, args, err := sqlx.In(markBlobsUnassociated, deletedIDs)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = tx.Exec(tx.Rebind(query), args...); err != nil {
+			return nil, err
+		}
+	}
+
 	// Determine which blobs are no longer in use.
 	toDelete := []string{}
 	for _, id := range deletedIDs {
@@ -355,9 +387,202 @@ const removeAllBlobsFromItem = `
 DELETE FROM blob_items WHERE item_id = $1
   RETURNING blob_id`
 
+const markBlobsUnassociated = `
+UPDATE blobs b SET unassociated_since = now()
+ WHERE id IN (?)
+   AND NOT EXISTS (SELECT 1 FROM blob_items i WHERE i.blob_id = b.id)`
+
 const deleteUnusedBlobs = `
 DELETE FROM blobs WHERE id in (?)
-RETURNING id, format`
+RETURNING id, format, renditions`
+
+// OrphanedBlobs returns up to limit blobs, owned or not, that have
+// had no item associations since before the given time, longest
+// orphaned first.
+func (pg *PGClient) OrphanedBlobs(before time.Time, limit uint) ([]model.Blob, error) {
+	results := []model.Blob{}
+	err := pg.DB.Select(&results, orphanedBlobs, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+const orphanedBlobs = `
+SELECT id, format, size, owner, tags, created_at,
+       width, height, dominant_colour, blurhash, renditions
+  FROM blobs b
+ WHERE unassociated_since < $1
+   AND NOT EXISTS (SELECT 1 FROM blob_items i WHERE i.blob_id = b.id)
+ ORDER BY unassociated_since
+ LIMIT $2`
+
+// DeleteOrphanedBlob deletes a blob only if it has still had no item
+// associations since before the given time, returning a flag to say
+// whether it was deleted. The check and the deletion happen in a
+// single statement, so a blob that gains an association after being
+// listed by OrphanedBlobs is left alone.
+func (pg *PGClient) DeleteOrphanedBlob(id string, before time.Time) (bool, error) {
+	result, err := pg.DB.Exec(deleteOrphanedBlob, id, before)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+const deleteOrphanedBlob = `
+DELETE FROM blobs b
+ WHERE id = $1
+   AND unassociated_since < $2
+   AND NOT EXISTS (SELECT 1 FROM blob_items i WHERE i.blob_id = b.id)`
+
+// DeleteExpiredUploads deletes resumable uploads that have expired,
+// returning the number of uploads deleted.
+func (pg *PGClient) DeleteExpiredUploads() (int64, error) {
+	result, err := pg.DB.Exec(`DELETE FROM uploads WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CreateUpload creates a new resumable upload, filling in its ID and
+// creation time.
+func (pg *PGClient) CreateUpload(upload *model.Upload) error {
+	upload.ID = chassis.NewID("upl")
+	if upload.AssociatedItems == nil {
+		upload.AssociatedItems = pq.StringArray{}
+	}
+	rows, err := pg.DB.NamedQuery(createUpload, upload)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return sql.ErrNoRows
+	}
+	return rows.Scan(&upload.CreatedAt)
+}
+
+const createUpload = `
+INSERT INTO uploads (id, owner, size, part_size, tags, associated_items, expires_at)
+     VALUES (:id, :owner, :size, :part_size, :tags, :associated_items, :expires_at)
+RETURNING created_at`
+
+// UploadByID returns the model for an unexpired upload, including the
+// list of parts received so far.
+func (pg *PGClient) UploadByID(id string) (*model.Upload, error) {
+	upload := &model.Upload{}
+	err := pg.DB.Get(upload, uploadByID, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	upload.ReceivedParts = []int{}
+	err = pg.DB.Select(&upload.ReceivedParts,
+		`SELECT part_number FROM upload_parts
+          WHERE upload_id = $1 ORDER BY part_number`, id)
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+const uploadByID = `
+SELECT id, owner, size, part_size, tags, associated_items,
+       created_at, expires_at
+  FROM uploads WHERE id = $1 AND expires_at >= now()`
+
+// PutUploadPart stores the data for a part of an upload, replacing
+// any data previously uploaded for the same part.
+func (pg *PGClient) PutUploadPart(id string, part int, data []byte) error {
+	_, err := pg.DB.Exec(putUploadPart, id, part, data)
+	if err, ok := err.(*pq.Error); ok && err.Code.Name() == "foreign_key_violation" {
+		return ErrUploadNotFound
+	}
+	return err
+}
+
+const putUploadPart = `
+INSERT INTO upload_parts (upload_id, part_number, data) VALUES ($1, $2, $3)
+  ON CONFLICT (upload_id, part_number) DO UPDATE SET data = EXCLUDED.data`
+
+// UploadData returns the data for an upload, made by joining its
+// parts together in order.
+func (pg *PGClient) UploadData(id string) ([]byte, error) {
+	data := []byte{}
+	err := pg.DB.Get(&data,
+		`SELECT coalesce(string_agg(data, '' ORDER BY part_number), '')
+           FROM upload_parts WHERE upload_id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ClaimUpload marks an unexpired upload as being completed. Only one
+// caller can claim an upload: others get ErrUploadClaimed until it is
+// released.
+func (pg *PGClient) ClaimUpload(id string) error {
+	result, err := pg.DB.Exec(claimUpload, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 1 {
+		return nil
+	}
+
+	// Either there's no such upload, or someone else has claimed it.
+	exists := false
+	err = pg.DB.Get(&exists,
+		`SELECT EXISTS (SELECT 1 FROM uploads
+                         WHERE id = $1 AND expires_at >= now())`, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUploadNotFound
+	}
+	return ErrUploadClaimed
+}
+
+const claimUpload = `
+UPDATE uploads SET completing = true
+ WHERE id = $1 AND expires_at >= now() AND NOT completing`
+
+// ReleaseUpload clears the claim on an upload whose completion
+// failed, so that it can be completed again.
+func (pg *PGClient) ReleaseUpload(id string) error {
+	_, err := pg.DB.Exec(`UPDATE uploads SET completing = false WHERE id = $1`, id)
+	return err
+}
+
+// DeleteUpload deletes an upload and its parts (which are cleaned up
+// by a foreign key deletion cascade constraint).
+func (pg *PGClient) DeleteUpload(id string) error {
+	result, err := pg.DB.Exec(`DELETE FROM uploads WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		return ErrUploadNotFound
+	}
+	return nil
+}
 
 // SaveEvent saves an event to the database.
 func (pg *PGClient) SaveEvent(topic string, eventData interface{}, inTx func() error) error {
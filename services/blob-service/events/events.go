@@ -0,0 +1,20 @@
+package events
+
+// Event names used in blob service.
+const (
+	BlobDeleted = "blob-deleted"
+)
+
+// Reasons for blob deletion given in blob deletion events.
+const (
+	DeletedByOwner     = "owner-deleted"
+	DeletedItemRemoved = "item-removed"
+	DeletedOrphaned    = "orphaned"
+)
+
+// BlobDeletedEvent is the event data for blob deletion events.
+type BlobDeletedEvent struct {
+	ID     string `json:"id"`
+	Format string `json:"format"`
+	Reason string `json:"reason"`
+}
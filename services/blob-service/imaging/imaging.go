@@ -0,0 +1,218 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/webp"
+)
+
+// Processing of uploaded images. Images are decoded and re-encoded,
+// which strips all metadata (in particular EXIF data, which often
+// contains GPS locations for photos taken on phones), rotated
+// according to any EXIF orientation, and scaled down if they're
+// larger than MaxDimension. We also generate a set of named
+// renditions and a little metadata that's useful for front ends to
+// display placeholders while images are loading.
+
+var (
+	// ErrUnsupportedFormat is returned when trying to process an image
+	// in a format we don't handle.
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+
+	// ErrImageTooLarge is returned for images whose pixel dimensions
+	// are too large to process safely.
+	ErrImageTooLarge = errors.New("image dimensions too large")
+)
+
+const (
+	// MaxDimension is the maximum width or height of stored images.
+	// Larger images are scaled down to fit.
+	MaxDimension = 2560
+
+	// MaxPixels limits the size of images that we're willing to
+	// decode. This keeps "decompression bombs" from using up all our
+	// memory.
+	MaxPixels = 50000000
+
+	// JPEGQuality is the quality setting used when re-encoding JPEG
+	// images.
+	JPEGQuality = 90
+
+	// RenditionFormat is the file format used for renditions, unless
+	// a JPEG encoding of an opaque rendition is smaller (as it usually
+	// is for photos, since our WebP encoder is lossless).
+	RenditionFormat = "webp"
+)
+
+// RenditionSpec defines a named rendition: the image is scaled down
+// (but never up) to fit in a square of the given size.
+type RenditionSpec struct {
+	Name         string
+	MaxDimension int
+}
+
+// Renditions lists the renditions generated for each image.
+var Renditions = []RenditionSpec{
+	{"thumbnail", 160},
+	{"card", 640},
+	{"full", 1600},
+}
+
+// Rendition is the encoded data for a single rendition of an image.
+type Rendition struct {
+	Name   string
+	Format string
+	Data   []byte
+	Width  int
+	Height int
+}
+
+// Result is the result of processing an image.
+type Result struct {
+	// Data is the processed image, in the same format as the original.
+	Data   []byte
+	Width  int
+	Height int
+
+	// DominantColour is the most common colour in the image, as a CSS
+	// hex colour string.
+	DominantColour string
+
+	// BlurHash is a compact representation of a blurred version of
+	// the image (see https://blurha.sh).
+	BlurHash string
+
+	Renditions []Rendition
+}
+
+// Process processes image data in one of the formats accepted for
+// blob uploads, identified by file extension.
+func Process(data []byte, format string) (*Result, error) {
+	// WebP images are stored without re-encoding (we have no lossy
+	// WebP encoder), so metadata chunks are removed from the original
+	// data instead. They aren't rotated or scaled down either.
+	var stripped []byte
+	if format == "webp" {
+		var err error
+		if stripped, _, _, err = stripWebPMetadata(data); err != nil {
+			return nil, err
+		}
+		data = stripped
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	var src image.Image
+	orientation := 1
+	switch format {
+	case "jpg":
+		src, err = jpeg.Decode(bytes.NewReader(data))
+		orientation = jpegOrientation(data)
+	case "png":
+		src, err = png.Decode(bytes.NewReader(data))
+	case "webp":
+		src, err = webp.Decode(bytes.NewReader(data))
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	img := toNRGBA(src)
+	if format != "webp" {
+		img = orient(img, orientation)
+		img = fit(img, MaxDimension)
+	}
+
+	// Re-encode the image in its original format.
+	buf := bytes.Buffer{}
+	switch format {
+	case "jpg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: JPEGQuality})
+	case "png":
+		err = png.Encode(&buf, img)
+	case "webp":
+		_, err = buf.Write(stripped)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Data:   buf.Bytes(),
+		Width:  img.Rect.Dx(),
+		Height: img.Rect.Dy(),
+	}
+
+	// Renditions are generated from largest to smallest, each from the
+	// one before, which is quicker than scaling the full image each
+	// time.
+	scaled := img
+	renditions := make([]Rendition, len(Renditions))
+	for i := len(Renditions) - 1; i >= 0; i-- {
+		spec := Renditions[i]
+		scaled = fit(scaled, spec.MaxDimension)
+		rdata, rformat, err := encodeRendition(scaled)
+		if err != nil {
+			return nil, fmt.Errorf("encoding %s rendition: %v", spec.Name, err)
+		}
+		renditions[i] = Rendition{
+			Name:   spec.Name,
+			Format: rformat,
+			Data:   rdata,
+			Width:  scaled.Rect.Dx(),
+			Height: scaled.Rect.Dy(),
+		}
+	}
+	result.Renditions = renditions
+
+	small := fit(scaled, 32)
+	result.DominantColour = dominantColour(small)
+	result.BlurHash = blurHash(small, 4, 3)
+
+	return result, nil
+}
+
+// Encode a rendition in WebP format, falling back to JPEG if that's
+// smaller. Lossless WebP is best for graphics and screenshots, but
+// photos come out much smaller as JPEG. Images with transparency are
+// always kept as WebP.
+func encodeRendition(img *image.NRGBA) ([]byte, string, error) {
+	webpBuf := bytes.Buffer{}
+	if err := EncodeWebP(&webpBuf, img); err != nil {
+		return nil, "", err
+	}
+	if !img.Opaque() {
+		return webpBuf.Bytes(), RenditionFormat, nil
+	}
+	jpegBuf := bytes.Buffer{}
+	if err := jpeg.Encode(&jpegBuf, img, &jpeg.Options{Quality: JPEGQuality}); err != nil {
+		return nil, "", err
+	}
+	if jpegBuf.Len() < webpBuf.Len() {
+		return jpegBuf.Bytes(), "jpg", nil
+	}
+	return webpBuf.Bytes(), RenditionFormat, nil
+}
+
+// Convert any image to NRGBA format with bounds starting at the
+// origin.
+func toNRGBA(src image.Image) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, src, b.Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,367 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// Test image with a red pixel in the top-left corner and a blue pixel
+// in the top-right corner.
+func markedImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{0, 128, 0, 255})
+		}
+	}
+	img.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.SetNRGBA(w-1, 0, color.NRGBA{0, 0, 255, 255})
+	return img
+}
+
+// Test image that looks something like a photo: smooth gradients with
+// a little noise.
+func photoImage(w, h int) *image.NRGBA {
+	rnd := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := func(f float64) uint8 {
+				c := 128 + 100*math.Sin(f) + float64(rnd.Intn(16))
+				return uint8(math.Max(0, math.Min(255, c)))
+			}
+			img.SetNRGBA(x, y, color.NRGBA{
+				v(float64(x) / 17), v(float64(y) / 23), v(float64(x+y) / 31), 255,
+			})
+		}
+	}
+	return img
+}
+
+// Decode a rendition, checking that it's in the format it says it is.
+func decodeRendition(t *testing.T, r Rendition) image.Image {
+	var img image.Image
+	var err error
+	switch r.Format {
+	case "webp":
+		img, err = webp.Decode(bytes.NewReader(r.Data))
+	case "jpg":
+		img, err = jpeg.Decode(bytes.NewReader(r.Data))
+	default:
+		t.Fatalf("rendition %s: unexpected format %q", r.Name, r.Format)
+	}
+	if err != nil {
+		t.Fatalf("rendition %s: %v", r.Name, err)
+	}
+	if b := img.Bounds(); b.Dx() != r.Width || b.Dy() != r.Height {
+		t.Errorf("rendition %s: decoded size %v, expected %dx%d",
+			r.Name, b, r.Width, r.Height)
+	}
+	return img
+}
+
+// Insert an EXIF segment with an orientation tag into JPEG data.
+func withOrientation(jpg []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0, 0, 0, 0)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+
+	segment := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+func TestOrientation(t *testing.T) {
+	buf := bytes.Buffer{}
+	jpeg.Encode(&buf, markedImage(40, 20), nil)
+	jpg := withOrientation(buf.Bytes(), 6)
+	if o := jpegOrientation(jpg); o != 6 {
+		t.Fatalf("orientation = %d, expected 6", o)
+	}
+
+	// Rotating 90 degrees clockwise puts the top-left corner at the
+	// top right and the top-right corner at the bottom right.
+	img := orient(markedImage(4, 2), 6)
+	if img.Rect.Dx() != 2 || img.Rect.Dy() != 4 {
+		t.Fatalf("rotated size = %v", img.Rect)
+	}
+	if c := img.NRGBAAt(1, 0); c.R != 255 {
+		t.Errorf("top-right pixel = %v, expected red", c)
+	}
+	if c := img.NRGBAAt(1, 3); c.B != 255 {
+		t.Errorf("bottom-right pixel = %v, expected blue", c)
+	}
+
+	for o := 1; o <= 8; o++ {
+		img := orient(markedImage(4, 2), o)
+		if (o >= 5) != (img.Rect.Dx() == 2) {
+			t.Errorf("orientation %d: size = %v", o, img.Rect)
+		}
+	}
+}
+
+func TestProcessJPEG(t *testing.T) {
+	buf := bytes.Buffer{}
+	jpeg.Encode(&buf, markedImage(3000, 1000), nil)
+	jpg := withOrientation(buf.Bytes(), 6)
+
+	res, err := Process(jpg, "jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotated and clamped to the maximum dimension.
+	if res.Width != 853 || res.Height != MaxDimension {
+		t.Errorf("size = %dx%d", res.Width, res.Height)
+	}
+	if bytes.Contains(res.Data, []byte("Exif")) {
+		t.Error("EXIF data not stripped")
+	}
+	if o := jpegOrientation(res.Data); o != 1 {
+		t.Errorf("orientation = %d after processing", o)
+	}
+
+	if len(res.Renditions) != len(Renditions) {
+		t.Fatalf("%d renditions", len(res.Renditions))
+	}
+	for i, r := range res.Renditions {
+		spec := Renditions[i]
+		if r.Name != spec.Name || r.Height != spec.MaxDimension {
+			t.Errorf("rendition %s: %dx%d", r.Name, r.Width, r.Height)
+		}
+		// Flat colour compresses better as lossless WebP than JPEG.
+		if r.Format != "webp" {
+			t.Errorf("rendition %s is %s, expected webp", r.Name, r.Format)
+		}
+		decodeRendition(t, r)
+	}
+
+	if res.DominantColour != "#008000" {
+		t.Errorf("dominant colour = %q", res.DominantColour)
+	}
+	if len(res.BlurHash) != 28 {
+		t.Errorf("blurhash = %q", res.BlurHash)
+	}
+}
+
+func TestProcessSmallPNG(t *testing.T) {
+	buf := bytes.Buffer{}
+	png.Encode(&buf, markedImage(100, 50))
+	res, err := Process(buf.Bytes(), "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Small images aren't scaled up.
+	if res.Width != 100 || res.Height != 50 {
+		t.Errorf("size = %dx%d", res.Width, res.Height)
+	}
+	for _, r := range res.Renditions {
+		if r.Name != "thumbnail" && (r.Width != 100 || r.Height != 50) {
+			t.Errorf("rendition %s: %dx%d", r.Name, r.Width, r.Height)
+		}
+	}
+	if _, err := png.Decode(bytes.NewReader(res.Data)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProcessWebP(t *testing.T) {
+	buf := bytes.Buffer{}
+	EncodeWebP(&buf, markedImage(30, 20))
+	bitstream := buf.Bytes()[12:]
+
+	// Wrap the image in an extended format file with EXIF data.
+	vp8x := make([]byte, 18)
+	copy(vp8x, "VP8X")
+	binary.LittleEndian.PutUint32(vp8x[4:], 10)
+	vp8x[8] = 0x08
+	vp8x[12] = 29
+	vp8x[15] = 19
+	exif := append([]byte("EXIF\x04\x00\x00\x00"), "GPS!"...)
+	data := append([]byte("RIFF\x00\x00\x00\x00WEBP"), vp8x...)
+	data = append(data, exif...)
+	data = append(data, bitstream...)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+
+	res, err := Process(data, "webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Width != 30 || res.Height != 20 {
+		t.Errorf("size = %dx%d", res.Width, res.Height)
+	}
+	if _, err := webp.Decode(bytes.NewReader(res.Data)); err != nil {
+		t.Error(err)
+	}
+	if bytes.Contains(res.Data, []byte("GPS!")) || res.Data[20]&0x08 != 0 {
+		t.Error("EXIF data not stripped")
+	}
+	if len(res.Data) != len(data)-len(exif) ||
+		int(binary.LittleEndian.Uint32(res.Data[4:])) != len(res.Data)-8 {
+		t.Error("bad RIFF size")
+	}
+
+	// WebP uploads get renditions and placeholder information like
+	// other images.
+	if len(res.Renditions) != len(Renditions) {
+		t.Fatalf("%d renditions", len(res.Renditions))
+	}
+	for _, r := range res.Renditions {
+		img := decodeRendition(t, r)
+		if r.Width != 30 || r.Height != 20 {
+			t.Errorf("rendition %s: %dx%d", r.Name, r.Width, r.Height)
+		}
+		if r.Name == "full" {
+			if c := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); c.R != 255 {
+				t.Errorf("top-left pixel = %v, expected red", c)
+			}
+		}
+	}
+	if res.DominantColour != "#008000" {
+		t.Errorf("dominant colour = %q", res.DominantColour)
+	}
+	if len(res.BlurHash) != 28 {
+		t.Errorf("blurhash = %q", res.BlurHash)
+	}
+
+	// Invalid WebP data is rejected.
+	if _, err := Process(data[:40], "webp"); err == nil {
+		t.Error("truncated WebP data accepted")
+	}
+}
+
+func TestEncodeWebP(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	noise := image.NewNRGBA(image.Rect(0, 0, 37, 29))
+	rnd.Read(noise.Pix)
+	alpha := markedImage(50, 40)
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 50; x++ {
+			alpha.Pix[alpha.PixOffset(x, y)+3] = uint8(x * 5)
+		}
+	}
+	stripes := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			stripes.SetNRGBA(x, y, color.NRGBA{uint8(x % 7 * 30), uint8(y % 5 * 50), 90, 255})
+		}
+	}
+
+	tests := map[string]*image.NRGBA{
+		"single pixel": markedImage(1, 1),
+		"marked":       markedImage(30, 20),
+		"photo":        photoImage(123, 77),
+		"noise":        noise,
+		"alpha":        alpha,
+		"stripes":      stripes,
+	}
+	for name, img := range tests {
+		buf := bytes.Buffer{}
+		if err := EncodeWebP(&buf, img); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		dec, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Errorf("%s: decoding: %v", name, err)
+			continue
+		}
+		if dec.Bounds() != img.Rect {
+			t.Errorf("%s: decoded size %v", name, dec.Bounds())
+			continue
+		}
+
+		// Lossless encoding: every pixel comes back exactly.
+		got := toNRGBA(dec)
+		if !bytes.Equal(got.Pix, img.Pix) {
+			for i := range img.Pix {
+				if got.Pix[i] != img.Pix[i] {
+					p := i / 4
+					t.Errorf("%s: pixel (%d, %d) = %v, expected %v", name,
+						p%img.Rect.Dx(), p/img.Rect.Dx(),
+						got.Pix[i-i%4:i-i%4+4], img.Pix[i-i%4:i-i%4+4])
+					break
+				}
+			}
+		}
+	}
+
+	if err := EncodeWebP(&bytes.Buffer{}, image.NewNRGBA(image.Rect(0, 0, 0, 10))); err != ErrImageTooLargeForWebP {
+		t.Errorf("empty image: err = %v", err)
+	}
+}
+
+func TestRenditionFormat(t *testing.T) {
+	// Photos are smaller as JPEG...
+	data, format, err := encodeRendition(photoImage(200, 150))
+	if err != nil || format != "jpg" {
+		t.Errorf("photo rendition format = %q (err %v)", format, err)
+	} else if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+		t.Error(err)
+	}
+
+	// ...graphics are smaller as WebP...
+	_, format, err = encodeRendition(markedImage(200, 150))
+	if err != nil || format != "webp" {
+		t.Errorf("graphic rendition format = %q (err %v)", format, err)
+	}
+
+	// ...and JPEG can't do transparency.
+	img := photoImage(200, 150)
+	img.Pix[3] = 0
+	_, format, err = encodeRendition(img)
+	if err != nil || format != "webp" {
+		t.Errorf("transparent rendition format = %q (err %v)", format, err)
+	}
+}
+
+func TestResize(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.SetNRGBA(x, 0, color.NRGBA{200, 100, 0, 255})
+		img.SetNRGBA(x, 1, color.NRGBA{0, 0, 0, 0})
+	}
+	out := resize(img, 2, 1)
+	// Transparent pixels contribute to alpha but not colour.
+	if c := out.NRGBAAt(0, 0); c != (color.NRGBA{200, 100, 0, 128}) {
+		t.Errorf("pixel = %v", c)
+	}
+
+	if out := fit(markedImage(300, 100), 30); out.Rect.Dx() != 30 || out.Rect.Dy() != 10 {
+		t.Errorf("fit size = %v", out.Rect)
+	}
+}
+
+func TestBlurHash(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []byte{255, 0, 0, 255})
+	}
+	// Size flag for 4x3 components, one character for the AC
+	// magnitude, the DC component (plain red) and two characters for
+	// each AC component.
+	h := blurHash(img, 4, 3)
+	if len(h) != 28 || h[:1] != "L" || h[2:6] != encode83(0xff0000, 4) {
+		t.Errorf("blurhash = %q", h)
+	}
+}
@@ -0,0 +1,175 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+)
+
+// ErrInvalidWebP is returned for WebP data that can't be parsed.
+var ErrInvalidWebP = errors.New("invalid WebP data")
+
+// Find the EXIF orientation of a JPEG image, returning 1 (the normal
+// orientation) if there isn't one.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xda || marker == 0xd9 {
+			// Start of scan or end of image: no more metadata.
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if size < 2 || pos+2+size > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+size]
+		if marker == 0xe1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + size
+	}
+	return 1
+}
+
+// Read the orientation tag from the first IFD of TIFF-format EXIF
+// data.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	n := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < n; i++ {
+		entry := ifd + 2 + 12*i
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		tag := order.Uint16(tiff[entry:])
+		typ := order.Uint16(tiff[entry+2:])
+		if tag == 0x0112 && typ == 3 {
+			o := int(order.Uint16(tiff[entry+8:]))
+			if o < 1 || o > 8 {
+				return 1
+			}
+			return o
+		}
+	}
+	return 1
+}
+
+// Transform an image to undo an EXIF orientation, so that it displays
+// the right way up without the orientation tag.
+func orient(src *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		for dx := 0; dx < dw; dx++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-dx, dy
+			case 3:
+				sx, sy = w-1-dx, h-1-dy
+			case 4:
+				sx, sy = dx, h-1-dy
+			case 5:
+				sx, sy = dy, dx
+			case 6:
+				sx, sy = dy, h-1-dx
+			case 7:
+				sx, sy = w-1-dy, h-1-dx
+			case 8:
+				sx, sy = w-1-dy, dx
+			}
+			si := sy*src.Stride + 4*sx
+			di := dy*dst.Stride + 4*dx
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// Remove EXIF and XMP chunks from a WebP file, returning the
+// resulting data and the image dimensions.
+func stripWebPMetadata(data []byte) ([]byte, int, int, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, 0, 0, ErrInvalidWebP
+	}
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	width, height := 0, 0
+	pos := 12
+	for pos+8 <= len(data) {
+		fourcc := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		if pos+8+size > len(data) {
+			return nil, 0, 0, ErrInvalidWebP
+		}
+		end := pos + 8 + size + size&1
+		if end > len(data) {
+			end = len(data)
+		}
+		chunk := data[pos+8 : pos+8+size]
+		switch fourcc {
+		case "EXIF", "XMP ":
+			pos = end
+			continue
+		case "VP8X":
+			if size < 10 {
+				return nil, 0, 0, ErrInvalidWebP
+			}
+			width = 1 + (int(chunk[4]) | int(chunk[5])<<8 | int(chunk[6])<<16)
+			height = 1 + (int(chunk[7]) | int(chunk[8])<<8 | int(chunk[9])<<16)
+			// Clear the EXIF and XMP flags.
+			start := len(out)
+			out = append(out, data[pos:end]...)
+			out[start+8] &^= 0x0c
+			pos = end
+			continue
+		case "VP8 ":
+			if width == 0 && size >= 10 {
+				width = int(binary.LittleEndian.Uint16(chunk[6:]) & 0x3fff)
+				height = int(binary.LittleEndian.Uint16(chunk[8:]) & 0x3fff)
+			}
+		case "VP8L":
+			if width == 0 && size >= 5 {
+				bits := binary.LittleEndian.Uint32(chunk[1:])
+				width = int(bits&0x3fff) + 1
+				height = int((bits>>14)&0x3fff) + 1
+			}
+		}
+		out = append(out, data[pos:end]...)
+		pos = end
+	}
+	if width == 0 || height == 0 {
+		return nil, 0, 0, ErrInvalidWebP
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, width, height, nil
+}
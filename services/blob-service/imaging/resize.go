@@ -0,0 +1,115 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// Scale an image down (never up) so that neither its width nor its
+// height is greater than max, preserving the aspect ratio.
+func fit(src *image.NRGBA, max int) *image.NRGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w <= max && h <= max {
+		return src
+	}
+	dw, dh := max, max
+	if w > h {
+		dh = int(math.Round(float64(h) * float64(max) / float64(w)))
+	} else {
+		dw = int(math.Round(float64(w) * float64(max) / float64(h)))
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+	return resize(src, dw, dh)
+}
+
+// Contribution of a source pixel to a destination pixel.
+type weight struct {
+	src int
+	w   float32
+}
+
+// Area-averaging weights for scaling n source pixels down to m
+// destination pixels: each destination pixel covers a span of source
+// pixels, partially covering the pixels at either end.
+func boxWeights(n, m int) [][]weight {
+	scale := float64(n) / float64(m)
+	weights := make([][]weight, m)
+	for i := range weights {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < n && float64(j) < end; j++ {
+			overlap := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if overlap > 0 {
+				weights[i] = append(weights[i],
+					weight{j, float32(overlap / scale)})
+			}
+		}
+	}
+	return weights
+}
+
+// Scale an image down to the given size using a box filter. Colours
+// are weighted by alpha while scaling, so that fully transparent
+// pixels don't bleed their colour into their neighbours.
+func resize(src *image.NRGBA, dw, dh int) *image.NRGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	xw := boxWeights(sw, dw)
+	yw := boxWeights(sh, dh)
+
+	// Horizontal pass into premultiplied floating point values.
+	tmp := make([]float32, 4*dw*sh)
+	for y := 0; y < sh; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x, ws := range xw {
+			var r, g, b, a float32
+			for _, w := range ws {
+				p := row[4*w.src : 4*w.src+4]
+				pa := float32(p[3]) * w.w
+				r += float32(p[0]) * pa
+				g += float32(p[1]) * pa
+				b += float32(p[2]) * pa
+				a += pa
+			}
+			i := 4 * (y*dw + x)
+			tmp[i], tmp[i+1], tmp[i+2], tmp[i+3] = r, g, b, a
+		}
+	}
+
+	// Vertical pass, converting back to non-premultiplied colours.
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y, ws := range yw {
+		for x := 0; x < dw; x++ {
+			var r, g, b, a float32
+			for _, w := range ws {
+				i := 4 * (w.src*dw + x)
+				r += tmp[i] * w.w
+				g += tmp[i+1] * w.w
+				b += tmp[i+2] * w.w
+				a += tmp[i+3] * w.w
+			}
+			p := dst.Pix[y*dst.Stride+4*x:]
+			if a > 0 {
+				p[0] = clamp8(r / a)
+				p[1] = clamp8(g / a)
+				p[2] = clamp8(b / a)
+			}
+			p[3] = clamp8(a)
+		}
+	}
+	return dst
+}
+
+func clamp8(v float32) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	default:
+		return uint8(v + 0.5)
+	}
+}
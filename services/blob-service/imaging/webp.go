@@ -0,0 +1,658 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+)
+
+// This is a lossless WebP (VP8L) encoder. It's not as clever as
+// libwebp, but it produces reasonably compact files for the sizes of
+// image we use for renditions using the subtract green and predictor
+// transforms, LZ77 backward references and a single group of
+// Huffman codes. The bitstream format is described in RFC 9649.
+
+// ErrImageTooLargeForWebP is returned when trying to encode an image
+// with dimensions larger than those allowed by the WebP format.
+var ErrImageTooLargeForWebP = errors.New("image too large for WebP encoding")
+
+const (
+	vp8lMaxDimension = 1 << 14
+
+	// Transform types.
+	predictorTransform     = 0
+	subtractGreenTransform = 2
+
+	// Predictor blocks are 2^predictorBits pixels square.
+	predictorBits = 4
+
+	// Prefix code alphabet sizes. There's no colour cache, so the
+	// green alphabet is just the literals plus the length codes.
+	numLiteralCodes  = 256
+	numLengthCodes   = 24
+	numDistanceCodes = 40
+	greenAlphabet    = numLiteralCodes + numLengthCodes
+
+	// Maximum Huffman code lengths.
+	maxCodeLength           = 15
+	maxCodeLengthCodeLength = 7
+
+	// Backward reference limits.
+	minMatchLength = 3
+	maxMatchLength = 4096
+	maxDistance    = (1 << 20) - 120
+	hashBits       = 16
+	maxChain       = 32
+)
+
+// Order in which code length code lengths are written.
+var codeLengthCodeOrder = [19]int{
+	17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// Predictor modes tried for each block: left, top, average of left
+// and top, and gradient ("clamp add subtract full").
+var predictorModes = []int{1, 2, 7, 12}
+
+// EncodeWebP writes an image to w in lossless WebP format.
+func EncodeWebP(w io.Writer, img *image.NRGBA) error {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	if width < 1 || height < 1 ||
+		width > vp8lMaxDimension || height > vp8lMaxDimension {
+		return ErrImageTooLargeForWebP
+	}
+
+	// Convert to ARGB pixels.
+	argb := make([]uint32, width*height)
+	alphaUsed := false
+	for y := 0; y < height; y++ {
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < width; x++ {
+			p := row[4*x : 4*x+4]
+			argb[y*width+x] = uint32(p[3])<<24 | uint32(p[0])<<16 |
+				uint32(p[1])<<8 | uint32(p[2])
+			if p[3] != 0xff {
+				alphaUsed = true
+			}
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if alphaUsed {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3)
+
+	// Transforms are written in the order in which they're applied by
+	// the encoder: the decoder inverts them in reverse order.
+	subtractGreen(argb)
+	bw.write(1, 1)
+	bw.write(subtractGreenTransform, 2)
+
+	modes, bwidth := applyPredictor(argb, width, height)
+	bw.write(1, 1)
+	bw.write(predictorTransform, 2)
+	bw.write(predictorBits-2, 3)
+	writeImageData(bw, modes, bwidth, false)
+
+	bw.write(0, 1)
+	writeImageData(bw, argb, width, true)
+	payload := bw.bytes()
+
+	// RIFF container.
+	size := len(payload)
+	pad := size & 1
+	hdr := make([]byte, 20)
+	copy(hdr[0:], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(4+8+size+pad))
+	copy(hdr[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(hdr[16:], uint32(size))
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+	if pad != 0 {
+		payload = append(payload, 0)
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// Subtract green from the red and blue channels.
+func subtractGreen(argb []uint32) {
+	for i, p := range argb {
+		g := (p >> 8) & 0xff
+		r := ((p >> 16) - g) & 0xff
+		b := (p - g) & 0xff
+		argb[i] = p&0xff00ff00 | r<<16 | b
+	}
+}
+
+// Replace pixels by their residuals after prediction, choosing the
+// best predictor mode for each block, and return the sub-image of
+// predictor modes.
+func applyPredictor(argb []uint32, width, height int) ([]uint32, int) {
+	bsize := 1 << predictorBits
+	bwidth := (width + bsize - 1) >> predictorBits
+	bheight := (height + bsize - 1) >> predictorBits
+	modes := make([]uint32, bwidth*bheight)
+
+	// Choose modes using the original pixels, since those are what the
+	// decoder predicts from.
+	for by := 0; by < bheight; by++ {
+		for bx := 0; bx < bwidth; bx++ {
+			best, bestCost := predictorModes[0], -1
+			for _, mode := range predictorModes {
+				cost := 0
+				for y := by * bsize; y < (by+1)*bsize && y < height; y++ {
+					for x := bx * bsize; x < (bx+1)*bsize && x < width; x++ {
+						cost += residualCost(argb[y*width+x],
+							predict(argb, width, x, y, mode))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			modes[by*bwidth+bx] = 0xff000000 | uint32(best)<<8
+		}
+	}
+
+	// Compute residuals, working backwards so that predictions use
+	// original pixel values.
+	for y := height - 1; y >= 0; y-- {
+		for x := width - 1; x >= 0; x-- {
+			mode := int(modes[(y>>predictorBits)*bwidth+(x>>predictorBits)]>>8) & 0xf
+			i := y*width + x
+			argb[i] = subPixels(argb[i], predict(argb, width, x, y, mode))
+		}
+	}
+	return modes, bwidth
+}
+
+// Prediction for the pixel at (x, y), with the special cases for the
+// top row and left column required by the format.
+func predict(argb []uint32, width, x, y, mode int) uint32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return argb[x-1]
+	case x == 0:
+		return argb[(y-1)*width]
+	}
+	l := argb[y*width+x-1]
+	t := argb[(y-1)*width+x]
+	switch mode {
+	case 1:
+		return l
+	case 2:
+		return t
+	case 7:
+		return average2(l, t)
+	default:
+		return clampAddSubtractFull(l, t, argb[(y-1)*width+x-1])
+	}
+}
+
+func average2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+func clampAddSubtractFull(a, b, c uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		v := int(a>>shift&0xff) + int(b>>shift&0xff) - int(c>>shift&0xff)
+		if v < 0 {
+			v = 0
+		} else if v > 255 {
+			v = 255
+		}
+		out |= uint32(v) << shift
+	}
+	return out
+}
+
+// Per-channel difference modulo 256.
+func subPixels(a, b uint32) uint32 {
+	alphaGreen := 0x00ff00ff + (a & 0xff00ff00) - (b & 0xff00ff00)
+	redBlue := 0xff00ff00 + (a & 0x00ff00ff) - (b & 0x00ff00ff)
+	return alphaGreen&0xff00ff00 | redBlue&0x00ff00ff
+}
+
+// Rough cost of coding a residual: the sum of absolute values of the
+// signed channel residuals.
+func residualCost(p, pred uint32) int {
+	r := subPixels(p, pred)
+	cost := 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		v := int(int8(r >> shift))
+		if v < 0 {
+			v = -v
+		}
+		cost += v
+	}
+	return cost
+}
+
+// A token in the LZ77-coded pixel stream: either a literal pixel or
+// a backward reference.
+type token struct {
+	literal  bool
+	argb     uint32
+	length   int
+	distance int
+}
+
+// Compute LZ77 tokens for pixel data using hash chains.
+func backwardRefs(argb []uint32, width int, useRefs bool) []token {
+	n := len(argb)
+	tokens := make([]token, 0, n/2)
+	if !useRefs || n < minMatchLength {
+		for _, p := range argb {
+			tokens = append(tokens, token{literal: true, argb: p})
+		}
+		return tokens
+	}
+
+	head := make([]int32, 1<<hashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int32, n)
+	hash := func(i int) uint32 {
+		h := argb[i]*0x9e3779b1 ^ argb[i+1]*0x85ebca6b
+		return h >> (32 - hashBits)
+	}
+	insert := func(i int) {
+		if i+1 < n {
+			h := hash(i)
+			prev[i] = head[h]
+			head[h] = int32(i)
+		}
+	}
+	matchLength := func(i, j int) int {
+		l := 0
+		for i+l < n && l < maxMatchLength && argb[j+l] == argb[i+l] {
+			l++
+		}
+		return l
+	}
+
+	for i := 0; i < n; {
+		bestLen, bestDist := 0, 0
+		try := func(j int) {
+			if j < 0 || i-j > maxDistance {
+				return
+			}
+			if l := matchLength(i, j); l > bestLen {
+				bestLen, bestDist = l, i-j
+			}
+		}
+		// Pixels to the left and above are the most likely matches.
+		try(i - 1)
+		try(i - width)
+		if i+1 < n {
+			j := head[hash(i)]
+			for c := 0; j >= 0 && c < maxChain && bestLen < maxMatchLength; c++ {
+				try(int(j))
+				j = prev[j]
+			}
+		}
+
+		if bestLen >= minMatchLength {
+			tokens = append(tokens, token{length: bestLen, distance: bestDist})
+			for k := 0; k < bestLen; k++ {
+				insert(i + k)
+			}
+			i += bestLen
+		} else {
+			tokens = append(tokens, token{literal: true, argb: argb[i]})
+			insert(i)
+			i++
+		}
+	}
+	return tokens
+}
+
+// Split a length or distance value into a prefix code and extra
+// bits.
+func prefixEncode(v int) (code int, nbits uint, extra uint32) {
+	v--
+	if v < 4 {
+		return v, 0, 0
+	}
+	hb := 0
+	for (v >> uint(hb+1)) != 0 {
+		hb++
+	}
+	second := (v >> uint(hb-1)) & 1
+	nbits = uint(hb - 1)
+	return 2*hb + second, nbits, uint32(v) & (1<<nbits - 1)
+}
+
+// Write entropy-coded image data: the main image has an extra "meta
+// prefix codes" flag, which is always zero here since we use a single
+// group of prefix codes for the whole image.
+func writeImageData(bw *bitWriter, argb []uint32, width int, main bool) {
+	tokens := backwardRefs(argb, width, main)
+
+	// Gather symbol histograms.
+	var hist [5][]int
+	hist[0] = make([]int, greenAlphabet)
+	hist[1] = make([]int, numLiteralCodes)
+	hist[2] = make([]int, numLiteralCodes)
+	hist[3] = make([]int, numLiteralCodes)
+	hist[4] = make([]int, numDistanceCodes)
+	for _, t := range tokens {
+		if t.literal {
+			hist[0][(t.argb>>8)&0xff]++
+			hist[1][(t.argb>>16)&0xff]++
+			hist[2][t.argb&0xff]++
+			hist[3][t.argb>>24]++
+			continue
+		}
+		lc, _, _ := prefixEncode(t.length)
+		hist[0][numLiteralCodes+lc]++
+		dc, _, _ := prefixEncode(t.distance + 120)
+		hist[4][dc]++
+	}
+
+	// No colour cache.
+	bw.write(0, 1)
+	if main {
+		bw.write(0, 1)
+	}
+
+	var codes [5]huffmanCode
+	for i := range hist {
+		codes[i] = writePrefixCode(bw, hist[i])
+	}
+
+	for _, t := range tokens {
+		if t.literal {
+			codes[0].write(bw, int(t.argb>>8)&0xff)
+			codes[1].write(bw, int(t.argb>>16)&0xff)
+			codes[2].write(bw, int(t.argb)&0xff)
+			codes[3].write(bw, int(t.argb>>24))
+			continue
+		}
+		lc, lbits, lextra := prefixEncode(t.length)
+		codes[0].write(bw, numLiteralCodes+lc)
+		bw.write(lextra, lbits)
+		dc, dbits, dextra := prefixEncode(t.distance + 120)
+		codes[4].write(bw, dc)
+		bw.write(dextra, dbits)
+	}
+}
+
+// Canonical Huffman code for an alphabet, with bit-reversed codes
+// ready for writing LSB first.
+type huffmanCode struct {
+	lengths []uint8
+	codes   []uint32
+}
+
+func (h huffmanCode) write(bw *bitWriter, sym int) {
+	bw.write(h.codes[sym], uint(h.lengths[sym]))
+}
+
+// Build a Huffman code for a histogram and write its description to
+// the bitstream.
+func writePrefixCode(bw *bitWriter, hist []int) huffmanCode {
+	used := []int{}
+	for sym, n := range hist {
+		if n > 0 {
+			used = append(used, sym)
+		}
+	}
+
+	// Codes with zero or one symbol are written using the "simple"
+	// format, and symbols are coded using zero bits.
+	if len(used) <= 1 {
+		sym := 0
+		if len(used) == 1 {
+			sym = used[0]
+		}
+		bw.write(1, 1)
+		bw.write(0, 1)
+		if sym < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(sym), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(sym), 8)
+		}
+		return huffmanCode{
+			lengths: make([]uint8, len(hist)),
+			codes:   make([]uint32, len(hist)),
+		}
+	}
+
+	lengths := codeLengths(hist, maxCodeLength)
+	bw.write(0, 1)
+
+	// Run-length code the code lengths: only runs of zeros are
+	// compressed.
+	type clToken struct {
+		code  int
+		extra uint32
+	}
+	clTokens := []clToken{}
+	for i := 0; i < len(lengths); {
+		if lengths[i] != 0 {
+			clTokens = append(clTokens, clToken{code: int(lengths[i])})
+			i++
+			continue
+		}
+		run := 0
+		for i+run < len(lengths) && lengths[i+run] == 0 {
+			run++
+		}
+		i += run
+		for run > 0 {
+			switch {
+			case run >= 11:
+				r := run
+				if r > 138 {
+					r = 138
+				}
+				clTokens = append(clTokens, clToken{18, uint32(r - 11)})
+				run -= r
+			case run >= 3:
+				r := run
+				if r > 10 {
+					r = 10
+				}
+				clTokens = append(clTokens, clToken{17, uint32(r - 3)})
+				run -= r
+			default:
+				clTokens = append(clTokens, clToken{code: 0})
+				run--
+			}
+		}
+	}
+
+	// Huffman code for the code length tokens. A single-symbol code
+	// is padded out with a second symbol so that it's complete.
+	clHist := make([]int, 19)
+	for _, t := range clTokens {
+		clHist[t.code]++
+	}
+	nUsed := 0
+	for _, n := range clHist {
+		if n > 0 {
+			nUsed++
+		}
+	}
+	if nUsed == 1 {
+		for sym := range clHist {
+			if clHist[sym] == 0 {
+				clHist[sym] = 1
+				break
+			}
+		}
+	}
+	clLengths := codeLengths(clHist, maxCodeLengthCodeLength)
+	numCodes := 19
+	for numCodes > 4 && clLengths[codeLengthCodeOrder[numCodes-1]] == 0 {
+		numCodes--
+	}
+	bw.write(uint32(numCodes-4), 4)
+	for i := 0; i < numCodes; i++ {
+		bw.write(uint32(clLengths[codeLengthCodeOrder[i]]), 3)
+	}
+
+	// All code lengths are written, so there's no max_symbol.
+	bw.write(0, 1)
+	clCode := canonicalCode(clLengths)
+	for _, t := range clTokens {
+		clCode.write(bw, t.code)
+		switch t.code {
+		case 17:
+			bw.write(t.extra, 3)
+		case 18:
+			bw.write(t.extra, 7)
+		}
+	}
+
+	return canonicalCode(lengths)
+}
+
+// Compute length-limited Huffman code lengths for a histogram with at
+// least two non-zero entries. If the optimal code is too deep, the
+// histogram is flattened and the code rebuilt.
+func codeLengths(hist []int, limit int) []uint8 {
+	counts := make([]int, len(hist))
+	copy(counts, hist)
+	for {
+		lengths, depth := huffmanLengths(counts)
+		if depth <= limit {
+			return lengths
+		}
+		for i, n := range counts {
+			if n > 0 {
+				counts[i] = (n + 1) / 2
+			}
+		}
+	}
+}
+
+// Unrestricted Huffman code lengths, plus the maximum length.
+func huffmanLengths(counts []int) ([]uint8, int) {
+	type node struct {
+		weight      int
+		left, right int
+		sym         int
+	}
+	nodes := []node{}
+	queue := []int{}
+	for sym, n := range counts {
+		if n > 0 {
+			nodes = append(nodes, node{weight: n, left: -1, right: -1, sym: sym})
+			queue = append(queue, len(nodes)-1)
+		}
+	}
+
+	// Simple O(n^2) construction: alphabets are small.
+	popMin := func() int {
+		best := 0
+		for i := range queue {
+			if nodes[queue[i]].weight < nodes[queue[best]].weight {
+				best = i
+			}
+		}
+		n := queue[best]
+		queue = append(queue[:best], queue[best+1:]...)
+		return n
+	}
+	for len(queue) > 1 {
+		a := popMin()
+		b := popMin()
+		nodes = append(nodes, node{
+			weight: nodes[a].weight + nodes[b].weight,
+			left:   a, right: b, sym: -1,
+		})
+		queue = append(queue, len(nodes)-1)
+	}
+
+	lengths := make([]uint8, len(counts))
+	depth := 0
+	var walk func(n, d int)
+	walk = func(n, d int) {
+		if nodes[n].sym >= 0 {
+			lengths[nodes[n].sym] = uint8(d)
+			if d > depth {
+				depth = d
+			}
+			return
+		}
+		walk(nodes[n].left, d+1)
+		walk(nodes[n].right, d+1)
+	}
+	walk(queue[0], 0)
+	return lengths, depth
+}
+
+// Assign canonical codes from code lengths.
+func canonicalCode(lengths []uint8) huffmanCode {
+	var blCount [maxCodeLength + 1]uint32
+	for _, l := range lengths {
+		if l > 0 {
+			blCount[l]++
+		}
+	}
+	var nextCode [maxCodeLength + 1]uint32
+	code := uint32(0)
+	for bits := 1; bits <= maxCodeLength; bits++ {
+		code = (code + blCount[bits-1]) << 1
+		nextCode[bits] = code
+	}
+	codes := make([]uint32, len(lengths))
+	for sym, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		codes[sym] = reverseBits(nextCode[l], uint(l))
+		nextCode[l]++
+	}
+	return huffmanCode{lengths: lengths, codes: codes}
+}
+
+func reverseBits(v uint32, n uint) uint32 {
+	r := uint32(0)
+	for i := uint(0); i < n; i++ {
+		r = r<<1 | (v>>i)&1
+	}
+	return r
+}
+
+// Bit writer for the VP8L bitstream, which packs bits LSB first.
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc uint
+}
+
+func (bw *bitWriter) write(v uint32, nbits uint) {
+	bw.acc |= uint64(v) << bw.nacc
+	bw.nacc += nbits
+	for bw.nacc >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nacc -= 8
+	}
+}
+
+func (bw *bitWriter) bytes() []byte {
+	if bw.nacc > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nacc = 0, 0
+	}
+	return bw.buf
+}
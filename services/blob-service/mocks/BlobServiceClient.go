@@ -42,6 +42,36 @@ func (_m *BlobServiceClient) AddBlobToItem(ctx context.Context, in *blob_service
 	return r0, r1
 }
 
+// AddItemBlobs provides a mock function with given fields: ctx, in, opts
+func (_m *BlobServiceClient) AddItemBlobs(ctx context.Context, in *blob_service.ItemBlobsRequest, opts ...grpc.CallOption) (*blob_service.Empty, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *blob_service.Empty
+	if rf, ok := ret.Get(0).(func(context.Context, *blob_service.ItemBlobsRequest, ...grpc.CallOption) *blob_service.Empty); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*blob_service.Empty)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *blob_service.ItemBlobsRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateBlob provides a mock function with given fields: ctx, in, opts
 func (_m *BlobServiceClient) CreateBlob(ctx context.Context, in *blob_service.CreateBlobRequest, opts ...grpc.CallOption) (*blob_service.BlobInfo, error) {
 	_va := make([]interface{}, len(opts))
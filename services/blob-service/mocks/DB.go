@@ -5,6 +5,7 @@ package mocks
 import db "github.com/veganbase/backend/services/blob-service/db"
 import mock "github.com/stretchr/testify/mock"
 import model "github.com/veganbase/backend/services/blob-service/model"
+import time "time"
 
 // DB is an autogenerated mock type for the DB type
 type DB struct {
@@ -71,6 +72,20 @@ func (_m *DB) BlobsByUser(userID string, tags []string, page uint, perPage uint)
 	return r0, r1
 }
 
+// ClaimUpload provides a mock function with given fields: id
+func (_m *DB) ClaimUpload(id string) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ClearBlobOwner provides a mock function with given fields: id
 func (_m *DB) ClearBlobOwner(id string) (bool, error) {
 	ret := _m.Called(id)
@@ -106,6 +121,20 @@ func (_m *DB) CreateBlob(blob *model.Blob) error {
 	return r0
 }
 
+// CreateUpload provides a mock function with given fields: upload
+func (_m *DB) CreateUpload(upload *model.Upload) error {
+	ret := _m.Called(upload)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.Upload) error); ok {
+		r0 = rf(upload)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteBlob provides a mock function with given fields: id
 func (_m *DB) DeleteBlob(id string) error {
 	ret := _m.Called(id)
@@ -120,6 +149,62 @@ func (_m *DB) DeleteBlob(id string) error {
 	return r0
 }
 
+// DeleteExpiredUploads provides a mock function with given fields:
+func (_m *DB) DeleteExpiredUploads() (int64, error) {
+	ret := _m.Called()
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteOrphanedBlob provides a mock function with given fields: id, before
+func (_m *DB) DeleteOrphanedBlob(id string, before time.Time) (bool, error) {
+	ret := _m.Called(id, before)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, time.Time) bool); ok {
+		r0 = rf(id, before)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, time.Time) error); ok {
+		r1 = rf(id, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteUpload provides a mock function with given fields: id
+func (_m *DB) DeleteUpload(id string) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewBlobID provides a mock function with given fields:
 func (_m *DB) NewBlobID() string {
 	ret := _m.Called()
@@ -134,6 +219,57 @@ func (_m *DB) NewBlobID() string {
 	return r0
 }
 
+// OrphanedBlobs provides a mock function with given fields: before, limit
+func (_m *DB) OrphanedBlobs(before time.Time, limit uint) ([]model.Blob, error) {
+	ret := _m.Called(before, limit)
+
+	var r0 []model.Blob
+	if rf, ok := ret.Get(0).(func(time.Time, uint) []model.Blob); ok {
+		r0 = rf(before, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Blob)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(time.Time, uint) error); ok {
+		r1 = rf(before, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PutUploadPart provides a mock function with given fields: id, part, data
+func (_m *DB) PutUploadPart(id string, part int, data []byte) error {
+	ret := _m.Called(id, part, data)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int, []byte) error); ok {
+		r0 = rf(id, part, data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReleaseUpload provides a mock function with given fields: id
+func (_m *DB) ReleaseUpload(id string) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // RemoveBlobsFromItem provides a mock function with given fields: id, itemIDs
 func (_m *DB) RemoveBlobsFromItem(id string, itemIDs []string) ([]db.DeletedBlob, error) {
 	ret := _m.Called(id, itemIDs)
@@ -207,3 +343,49 @@ func (_m *DB) TagsForUser(userID string) ([]string, error) {
 
 	return r0, r1
 }
+
+// UploadByID provides a mock function with given fields: id
+func (_m *DB) UploadByID(id string) (*model.Upload, error) {
+	ret := _m.Called(id)
+
+	var r0 *model.Upload
+	if rf, ok := ret.Get(0).(func(string) *model.Upload); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Upload)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UploadData provides a mock function with given fields: id
+func (_m *DB) UploadData(id string) ([]byte, error) {
+	ret := _m.Called(id)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(string) []byte); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -23,6 +23,17 @@ type Blob struct {
 	// The size of the blob in bytes.
 	Size int `json:"size" db:"size"`
 
+	// Image dimensions in pixels, dominant colour (as a CSS hex colour
+	// string) and BlurHash placeholder. These are recorded when images
+	// are processed on upload, so may be missing for older blobs.
+	Width          *int    `json:"width,omitempty" db:"width"`
+	Height         *int    `json:"height,omitempty" db:"height"`
+	DominantColour *string `json:"dominant_colour,omitempty" db:"dominant_colour"`
+	BlurHash       *string `json:"blurhash,omitempty" db:"blurhash"`
+
+	// Scaled versions of the image, indexed by rendition name.
+	Renditions Renditions `json:"renditions" db:"renditions"`
+
 	// The user ID of the owner of the blob.
 	Owner *string `json:"owner" db:"owner"`
 
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Rendition describes a scaled version of a blob image. Rendition
+// data is stored alongside the blob data, using an ID made from the
+// blob ID and the rendition name.
+type Rendition struct {
+	// In messages returned by the API, this is the access URL for the
+	// rendition. It's not stored in the database.
+	URI string `json:"uri,omitempty"`
+
+	// The file extension for the type of the rendition.
+	Format string `json:"format"`
+
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// The size of the rendition in bytes.
+	Size int `json:"size"`
+}
+
+// Renditions is the set of renditions for a blob, indexed by
+// rendition name.
+type Renditions map[string]Rendition
+
+// RenditionID returns the ID used to store the data for a rendition
+// of a blob.
+func RenditionID(blobID, name string) string {
+	return blobID + "-" + name
+}
+
+// Scan implements the sql.Scanner interface.
+func (r *Renditions) Scan(src interface{}) error {
+	j := types.JSONText{}
+	err := j.Scan(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(j, r)
+}
+
+// Value implements the driver.Value interface.
+func (r Renditions) Value() (driver.Value, error) {
+	if r == nil {
+		r = Renditions{}
+	}
+	v, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return types.JSONText(v).Value()
+}
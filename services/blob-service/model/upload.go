@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/veganbase/backend/chassis"
+)
+
+// Upload represents a resumable upload in progress. The client
+// uploads the blob data in numbered parts, which are held in the
+// database until the upload is completed, when the parts are joined
+// together and a blob is created from the resulting data.
+type Upload struct {
+	// Unique ID of the upload.
+	ID string `json:"id" db:"id"`
+
+	// The user ID of the user performing the upload, who will be the
+	// owner of the resulting blob.
+	Owner string `json:"owner" db:"owner"`
+
+	// Total size of the blob data in bytes.
+	Size int `json:"size" db:"size"`
+
+	// The size of each part, except the last, which may be shorter.
+	PartSize int `json:"part_size" db:"part_size"`
+
+	// Tags and item associations to apply to the blob when it's
+	// created.
+	Tags            chassis.Tags   `json:"tags" db:"tags"`
+	AssociatedItems pq.StringArray `json:"associated_items" db:"associated_items"`
+
+	// The numbers of the parts received so far (parts are numbered
+	// from 1). This is not stored in the uploads table.
+	ReceivedParts []int `json:"received_parts" db:""`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// Parts returns the number of parts needed for the upload.
+func (u *Upload) Parts() int {
+	return (u.Size + u.PartSize - 1) / u.PartSize
+}
+
+// ExpectedPartSize returns the size that a given part must have.
+func (u *Upload) ExpectedPartSize(n int) int {
+	if n < u.Parts() {
+		return u.PartSize
+	}
+	return u.Size - (n-1)*u.PartSize
+}
@@ -1,3 +1,3 @@
 package blob_service
 
-//go:generate protoc --go-grpc_out=. -I ../../proto ../../proto/blob_service.proto
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative -I ../../proto ../../proto/blob_service.proto
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/blob-service/events"
+	"github.com/veganbase/backend/services/blob-service/model"
+)
+
+// Maximum number of blobs examined in a single garbage collection
+// run. Anything left over is picked up on the next run.
+const gcBatchSize = 500
+
+// gcReport describes the blobs found (and, unless this is a dry run,
+// deleted) by a garbage collection run.
+type gcReport struct {
+	DryRun bool         `json:"dry_run"`
+	Cutoff time.Time    `json:"cutoff"`
+	Count  int          `json:"count"`
+	Bytes  int          `json:"bytes"`
+	Blobs  []model.Blob `json:"blobs"`
+}
+
+// Start the orphaned blob garbage collector, which runs periodically
+// in the background until the server exits.
+func (s *Server) startGC(interval, gracePeriod time.Duration) {
+	ctx, cancel := context.WithCancel(s.Ctx)
+	s.AddAtExit(cancel)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			report, err := s.collectGarbage(gracePeriod, false)
+			if err != nil {
+				log.Error().Err(err).Msg("collecting orphaned blobs")
+			}
+			if report != nil && report.Count > 0 {
+				log.Info().
+					Int("count", report.Count).
+					Int("bytes", report.Bytes).
+					Msg("deleted orphaned blobs")
+			}
+
+			if n, err := s.db.DeleteExpiredUploads(); err != nil {
+				log.Error().Err(err).Msg("deleting expired uploads")
+			} else if n > 0 {
+				log.Info().Int64("count", n).Msg("deleted expired uploads")
+			}
+		}
+	}()
+}
+
+// Find blobs that have had no item associations for longer than the
+// grace period, whether or not they have an owner, and (unless this
+// is a dry run) delete them. This covers blobs uploaded for item
+// drafts that were abandoned, replaced avatars and attachments of
+// deleted posts. Each blob is removed from the database before its
+// data is deleted from storage, so that a blob that gains an item
+// association while the collector is running is never left without
+// data.
+func (s *Server) collectGarbage(gracePeriod time.Duration, dryRun bool) (*gcReport, error) {
+	report := &gcReport{
+		DryRun: dryRun,
+		Cutoff: time.Now().Add(-gracePeriod),
+		Blobs:  []model.Blob{},
+	}
+	orphans, err := s.db.OrphanedBlobs(report.Cutoff, gcBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, blob := range orphans {
+		if !dryRun {
+			deleted, err := s.db.DeleteOrphanedBlob(blob.ID, report.Cutoff)
+			if err != nil {
+				return report, err
+			}
+			if !deleted {
+				continue
+			}
+			err = s.deleteBlobData(blob.ID, blob.Format, blob.Renditions)
+			if err != nil {
+				log.Error().Err(err).Str("blob", blob.ID).
+					Msg("deleting data for orphaned blob")
+			}
+			chassis.Emit(s, events.BlobDeleted, events.BlobDeletedEvent{
+				ID:     blob.ID,
+				Format: blob.Format,
+				Reason: events.DeletedOrphaned,
+			})
+		}
+		blob.URI = s.blobURL(&blob)
+		report.Blobs = append(report.Blobs, blob)
+		report.Count++
+		report.Bytes += blob.Size
+	}
+	return report, nil
+}
+
+// Report on orphaned blobs that would be deleted by the garbage
+// collector, without deleting anything. Administrators only.
+func (s *Server) orphanReport(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth || !authInfo.UserIsAdmin {
+		return chassis.NotFound(w)
+	}
+	return s.collectGarbage(s.gcGracePeriod, true)
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	chassis_mocks "github.com/veganbase/backend/chassis/mocks"
+	"github.com/veganbase/backend/services/blob-service/events"
+	"github.com/veganbase/backend/services/blob-service/mocks"
+	"github.com/veganbase/backend/services/blob-service/model"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	dbm := &mocks.DB{}
+	stm := &chassis_mocks.Storage{}
+	s := &Server{db: dbm, blobstore: stm, imageBaseURL: "http://img.test.com"}
+
+	withRenditions := blob4
+	withRenditions.Renditions = model.Renditions{
+		"thumbnail": model.Rendition{Format: "webp", Width: 160, Height: 80},
+	}
+	raced := blob4
+	raced.ID = "BLOB5"
+	// BLOB2 is owned but has lost its item associations, like a
+	// replaced avatar: it is collected too.
+	dbm.On("OrphanedBlobs", mock.Anything, uint(gcBatchSize)).
+		Return([]model.Blob{withRenditions, blob2, raced}, nil)
+	// BLOB5 gains an item association after being listed.
+	dbm.On("DeleteOrphanedBlob", "BLOB4", mock.Anything).Return(true, nil)
+	dbm.On("DeleteOrphanedBlob", "BLOB2", mock.Anything).Return(true, nil)
+	dbm.On("DeleteOrphanedBlob", "BLOB5", mock.Anything).Return(false, nil)
+	dbm.On("SaveEvent", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	stm.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	// A dry run reports orphaned blobs without deleting anything.
+	report, err := s.collectGarbage(time.Hour, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || report.Count != 3 ||
+		report.Bytes != blob4.Size+blob2.Size+raced.Size {
+		t.Errorf("dry run report: %+v", report)
+	}
+	dbm.AssertNotCalled(t, "DeleteOrphanedBlob", mock.Anything, mock.Anything)
+	stm.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+
+	// A real run deletes the blobs and their renditions from the
+	// database and storage, and emits deletion events, using the same
+	// cutoff time for the deletion check as for the listing.
+	report, err = s.collectGarbage(time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Count != 2 || report.Blobs[0].ID != "BLOB4" || report.Blobs[1].ID != "BLOB2" {
+		t.Errorf("report: %+v", report)
+	}
+	dbm.AssertCalled(t, "DeleteOrphanedBlob", "BLOB4", report.Cutoff)
+	stm.AssertCalled(t, "Delete", "BLOB4", "png")
+	stm.AssertCalled(t, "Delete", "BLOB4-thumbnail", "webp")
+	stm.AssertCalled(t, "Delete", "BLOB2", blob2.Format)
+	stm.AssertNotCalled(t, "Delete", "BLOB5", mock.Anything)
+	dbm.AssertCalled(t, "SaveEvent", events.BlobDeleted, events.BlobDeletedEvent{
+		ID:     "BLOB4",
+		Format: "png",
+		Reason: events.DeletedOrphaned,
+	}, mock.Anything)
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/blob-service/db"
+	"github.com/veganbase/backend/services/blob-service/model"
+	pb "github.com/veganbase/backend/services/blob-service/proto"
+)
+
+// grpcServer implements the BlobService gRPC API. Permissions follow
+// the same rules as the equivalent REST routes: authentication
+// information is passed in request metadata (see
+// chassis.AuthUnaryInterceptor), and the blob/item association
+// methods are only for use by other services.
+type grpcServer struct {
+	pb.UnimplementedBlobServiceServer
+	s *Server
+}
+
+// Start the gRPC server on the given port, alongside the REST server.
+func (s *Server) startGRPC(port int) {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		log.Fatal().Err(err).Msg("couldn't listen for gRPC connections")
+	}
+	srv := grpc.NewServer(grpc.UnaryInterceptor(chassis.AuthUnaryInterceptor))
+	pb.RegisterBlobServiceServer(srv, &grpcServer{s: s})
+	go func() {
+		log.Info().Int("port", port).Msg("gRPC server started")
+		if err := srv.Serve(lis); err != nil {
+			log.Error().Err(err).Msg("gRPC server failed")
+		}
+	}()
+	s.AddAtExit(srv.GracefulStop)
+}
+
+var (
+	errNoAuth       = status.Error(codes.Unauthenticated, "authentication required")
+	errBlobNotFound = status.Error(codes.NotFound, "blob not found")
+)
+
+// CreateBlob creates a blob owned by the authenticated user.
+func (g *grpcServer) CreateBlob(ctx context.Context, req *pb.CreateBlobRequest) (*pb.BlobInfo, error) {
+	authInfo := chassis.AuthInfoFromContext(ctx)
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return nil, errNoAuth
+	}
+	blob, err := g.s.createBlob(authInfo.UserID, req.ImageData, nil)
+	switch err {
+	case nil:
+	case errUnknownFileType, errUnsupportedFileType, errInvalidImage:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return nil, err
+	}
+	return g.s.blobInfo(blob), nil
+}
+
+// RetrieveBlob returns details of a single blob.
+func (g *grpcServer) RetrieveBlob(ctx context.Context, req *pb.SingleBlobRequest) (*pb.BlobInfo, error) {
+	blob, err := g.ownedBlob(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return g.s.blobInfo(blob), nil
+}
+
+// UpdateBlob sets the tags for a blob.
+func (g *grpcServer) UpdateBlob(ctx context.Context, req *pb.UpdateBlobRequest) (*pb.BlobInfo, error) {
+	blob, err := g.ownedBlob(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	tags := chassis.Tags(req.Tags.GetTags())
+	if err = g.s.db.SetBlobTags(blob.ID, tags); err != nil {
+		return nil, err
+	}
+	blob.Tags = tags
+	return g.s.blobInfo(blob), nil
+}
+
+// DeleteBlob deletes a blob from its owner's image gallery, returning
+// the details of the blob.
+func (g *grpcServer) DeleteBlob(ctx context.Context, req *pb.SingleBlobRequest) (*pb.BlobInfo, error) {
+	blob, err := g.ownedBlob(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err = g.s.deleteBlob(blob); err != nil {
+		return nil, err
+	}
+	return g.s.blobInfo(blob), nil
+}
+
+// RetrieveBlobs lists a user's blobs, optionally filtered by tags.
+// Listing another user's blobs requires administrator privileges.
+func (g *grpcServer) RetrieveBlobs(ctx context.Context, req *pb.RetrieveBlobsRequest) (*pb.BlobsInfo, error) {
+	authInfo := chassis.AuthInfoFromContext(ctx)
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return nil, errNoAuth
+	}
+	userID := authInfo.UserID
+	if req.UserId != "" {
+		userID = req.UserId
+	}
+	if userID != authInfo.UserID &&
+		(!authInfo.UserIsAdmin || authInfo.AuthMethod != chassis.SessionAuth) {
+		return nil, status.Error(codes.PermissionDenied, "can't list other user's blobs")
+	}
+
+	var tags []string
+	if len(req.Tags.GetTags()) > 0 {
+		tags = req.Tags.GetTags()
+	}
+	blobs, err := g.s.db.BlobsByUser(userID, tags, uint(req.Page), uint(req.PerPage))
+	if err != nil {
+		return nil, err
+	}
+	result := &pb.BlobsInfo{Blobs: make([]*pb.BlobInfo, len(blobs))}
+	for i := range blobs {
+		result.Blobs[i] = g.s.blobInfo(&blobs[i])
+	}
+	return result, nil
+}
+
+// GetTagList returns all the tags used in the authenticated user's
+// blobs.
+func (g *grpcServer) GetTagList(ctx context.Context, req *pb.GetTagListRequest) (*pb.TagList, error) {
+	authInfo := chassis.AuthInfoFromContext(ctx)
+	if authInfo.AuthMethod == chassis.NoAuth || authInfo.UserID == "" {
+		return nil, errNoAuth
+	}
+	tags, err := g.s.db.TagsForUser(authInfo.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.TagList{Tags: tags}, nil
+}
+
+// AddBlobToItem adds an association between a blob and an item.
+func (g *grpcServer) AddBlobToItem(ctx context.Context, req *pb.BlobItemAssocRequest) (*pb.Empty, error) {
+	err := g.s.db.AddBlobsToItem(req.ItemId, []string{req.BlobId})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Empty{}, nil
+}
+
+// RemoveBlobFromItem removes the association between a blob and an
+// item.
+func (g *grpcServer) RemoveBlobFromItem(ctx context.Context, req *pb.BlobItemAssocRequest) (*pb.Empty, error) {
+	if err := g.s.removeItemAssocs(req.ItemId, []string{req.BlobId}); err != nil {
+		return nil, err
+	}
+	return &pb.Empty{}, nil
+}
+
+// AddItemBlobs adds associations between an item and a list of
+// blobs.
+func (g *grpcServer) AddItemBlobs(ctx context.Context, req *pb.ItemBlobsRequest) (*pb.Empty, error) {
+	if err := g.s.db.AddBlobsToItem(req.ItemId, req.BlobIds); err != nil {
+		return nil, err
+	}
+	return &pb.Empty{}, nil
+}
+
+// RemoveItemBlobs removes associations between an item and a list of
+// blobs, or all of the item's blob associations if the list is
+// empty.
+func (g *grpcServer) RemoveItemBlobs(ctx context.Context, req *pb.RemoveItemBlobsRequest) (*pb.Empty, error) {
+	if err := g.s.removeItemAssocs(req.ItemId, req.BlobIds); err != nil {
+		return nil, err
+	}
+	return &pb.Empty{}, nil
+}
+
+// Look up a blob that must be owned by the authenticated user (or the
+// user must be an administrator).
+func (g *grpcServer) ownedBlob(ctx context.Context, id string) (*model.Blob, error) {
+	authInfo := chassis.AuthInfoFromContext(ctx)
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return nil, errNoAuth
+	}
+	blob, err := g.s.db.BlobByID(id)
+	if err == db.ErrBlobNotFound {
+		return nil, errBlobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !(authInfo.UserIsAdmin ||
+		blob.Owner != nil && *blob.Owner == authInfo.UserID) {
+		return nil, errBlobNotFound
+	}
+	return blob, nil
+}
+
+// Convert a blob model to its gRPC representation, using public URLs
+// for the blob and its renditions.
+func (s *Server) blobInfo(blob *model.Blob) *pb.BlobInfo {
+	info := &pb.BlobInfo{
+		Id:              blob.ID,
+		Url:             s.blobURL(blob),
+		Format:          blob.Format,
+		Size:            uint32(blob.Size),
+		CreatedAt:       timestamppb.New(blob.CreatedAt),
+		Tags:            blob.Tags,
+		AssociatedItems: blob.AssociatedItems,
+		Renditions:      map[string]*pb.RenditionInfo{},
+	}
+	if blob.Owner != nil {
+		info.Owner = *blob.Owner
+	}
+	if blob.Width != nil && blob.Height != nil {
+		info.Width = uint32(*blob.Width)
+		info.Height = uint32(*blob.Height)
+	}
+	if blob.DominantColour != nil {
+		info.DominantColour = *blob.DominantColour
+	}
+	if blob.BlurHash != nil {
+		info.Blurhash = *blob.BlurHash
+	}
+	for name, r := range blob.Renditions {
+		info.Renditions[name] = &pb.RenditionInfo{
+			Url:    r.URI,
+			Format: r.Format,
+			Width:  uint32(r.Width),
+			Height: uint32(r.Height),
+			Size:   uint32(r.Size),
+		}
+	}
+	return info
+}
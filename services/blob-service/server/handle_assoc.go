@@ -7,6 +7,7 @@ import (
 	"github.com/go-chi/chi"
 	"github.com/pkg/errors"
 	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/blob-service/events"
 )
 
 // addItemAssoc adds an association between an item and a blob. This
@@ -68,20 +69,29 @@ func (s *Server) removeItemBlobs(w http.ResponseWriter, r *http.Request) (interf
 		}
 	}
 
-	// Remove blob/item associations.
-	deleted, err := s.db.RemoveBlobsFromItem(itemID, blobIDs)
-	if err != nil {
+	if err = s.removeItemAssocs(itemID, blobIDs); err != nil {
 		return nil, err
 	}
+	return chassis.NoContent(w)
+}
 
-	// Remove blobs that have been deleted from the database from
-	// storage.
+// Remove blob/item associations, and remove the data for any blobs
+// that are deleted as a result from storage.
+func (s *Server) removeItemAssocs(itemID string, blobIDs []string) error {
+	deleted, err := s.db.RemoveBlobsFromItem(itemID, blobIDs)
+	if err != nil {
+		return err
+	}
 	for _, d := range deleted {
-		err := s.blobstore.Delete(d.ID, d.Format)
+		err := s.deleteBlobData(d.ID, d.Format, d.Renditions)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		chassis.Emit(s, events.BlobDeleted, events.BlobDeletedEvent{
+			ID:     d.ID,
+			Format: d.Format,
+			Reason: events.DeletedItemRemoved,
+		})
 	}
-
-	return chassis.NoContent(w)
+	return nil
 }
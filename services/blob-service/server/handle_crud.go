@@ -2,16 +2,26 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/go-chi/chi"
 	"github.com/h2non/filetype"
+	"github.com/rs/zerolog/log"
 	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/chassis/storage"
 	"github.com/veganbase/backend/services/blob-service/db"
+	"github.com/veganbase/backend/services/blob-service/events"
+	"github.com/veganbase/backend/services/blob-service/imaging"
 	"github.com/veganbase/backend/services/blob-service/model"
 )
 
@@ -19,15 +29,20 @@ var (
 	allowedFileTypes = []string{"jpg", "png", "webp"}
 )
 
+// Maximum size of a multipart blob upload request. (Larger images can
+// be uploaded in parts using the resumable upload routes.)
+const maxMultipartSize = 10 * datasize.MB
+
 // TODO: THERE IS IN GENERAL A LACK OF TRANSACTIONALITY IN HERE. NEED
 // TO THINK OF A WAY TO DEAL WITH THIS SO THAT THE DATABASE INTERFACE
 // CAN BE USED NICELY BOTH IN SIMPLE CALLS AND IN SEQUENCES OF CALLS
 // THAT NEED TO RUN INSIDE A TRANSACTION.
 
 // Create a blob from a file upload. Upload size is limited and the
-// permitted file types are restricted.
-// TODO: MAKE THIS A MULTIPART UPLOAD, GIVING A FILE AND A JSON BODY
-// TO ASSIGN TAGS IN A SINGLE STEP.
+// permitted file types are restricted. The request body is either the
+// raw file data, or a multipart form with the file in a "file" part
+// and an optional JSON "metadata" part giving tags and item
+// associations for the blob.
 func (s *Server) create(w http.ResponseWriter, r *http.Request) (interface{}, error) {
 	authInfo := chassis.AuthInfoFromContext(r.Context())
 	if authInfo.AuthMethod == chassis.NoAuth {
@@ -35,15 +50,158 @@ func (s *Server) create(w http.ResponseWriter, r *http.Request) (interface{}, er
 	}
 
 	// Read body, limiting to maximum upload size.
-	data, err := chassis.ReadBody(r, 0)
+	var data []byte
+	opts := &blobOptions{}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var err error
+	if mediaType == "multipart/form-data" {
+		data, opts, err = readMultipartUpload(r)
+	} else {
+		data, err = chassis.ReadBody(r, 0)
+	}
 	if err != nil {
 		return chassis.BadRequest(w, err.Error())
 	}
+	if err = opts.validate(); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if err = s.checkItemAccess(authInfo, opts.AssociatedItems); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	blob, err := s.createBlob(authInfo.UserID, data, opts)
+	switch err {
+	case nil:
+	case errUnknownFileType:
+		return chassis.BadRequest(w, "Can't determine file type")
+	case errUnsupportedFileType:
+		return chassis.BadRequest(w, "Unsupported file type")
+	case errInvalidImage:
+		return chassis.BadRequest(w, "Invalid image data")
+	default:
+		return nil, err
+	}
+
+	// Fix up URL to give image server URL instead of storage URL.
+	blob.URI = s.blobURL(blob)
+	return blob, nil
+}
+
+// blobOptions holds the tags and item associations that can be
+// applied to a blob when it's created.
+type blobOptions struct {
+	Tags            chassis.Tags `json:"tags"`
+	AssociatedItems []string     `json:"associated_items"`
+}
+
+var itemIDRE = regexp.MustCompile(`^[a-z]+_[a-zA-Z0-9]+$`)
+
+// Check item IDs in blob options.
+func (opts *blobOptions) validate() error {
+	for _, id := range opts.AssociatedItems {
+		if !itemIDRE.MatchString(id) {
+			return fmt.Errorf("Invalid item ID '%s'", id)
+		}
+	}
+	return nil
+}
+
+// Check that a user may associate a new blob with items. Unlike the
+// blob/item association routes, which are only used by the item
+// service, uploads come straight from users, so the user must own
+// each item or be a member of the organisation that owns it.
+// Administrators can associate blobs with any item.
+func (s *Server) checkItemAccess(authInfo *chassis.AuthInfo, itemIDs []string) error {
+	if authInfo.UserIsAdmin {
+		return nil
+	}
+	var orgs map[string]bool
+	for _, id := range itemIDs {
+		item, err := s.itemSvc.ItemInfo(id)
+		if err != nil {
+			return fmt.Errorf("Error validating item '%s': %s", id, err.Error())
+		}
+		if item.Owner == authInfo.UserID {
+			continue
+		}
+		if strings.HasPrefix(item.Owner, "org_") {
+			if orgs == nil {
+				if orgs, err = s.userSvc.OrgsForUser(authInfo.UserID); err != nil {
+					return err
+				}
+			}
+			if _, ok := orgs[item.Owner]; ok {
+				continue
+			}
+		}
+		return fmt.Errorf("Not allowed to associate blob with item '%s'", id)
+	}
+	return nil
+}
+
+// Read the file data and blob options from a multipart blob upload.
+func readMultipartUpload(r *http.Request) ([]byte, *blobOptions, error) {
+	defer r.Body.Close()
+	r.Body = http.MaxBytesReader(nil, r.Body, int64(maxMultipartSize))
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, errors.New("Invalid multipart request body")
+	}
+
+	var data []byte
+	opts := &blobOptions{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.New("Invalid multipart request body")
+		}
+		switch part.FormName() {
+		case "file":
+			data, err = ioutil.ReadAll(part)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Request body too large (limit is %s)",
+					maxMultipartSize.String())
+			}
+		case "metadata":
+			dec := json.NewDecoder(part)
+			dec.DisallowUnknownFields()
+			if err = dec.Decode(opts); err != nil {
+				return nil, nil, errors.New("Invalid blob metadata")
+			}
+		default:
+			return nil, nil, fmt.Errorf("Unknown multipart form field '%s'",
+				part.FormName())
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil, errors.New("Missing file data")
+	}
+	return data, opts, nil
+}
+
+// Errors from blob creation caused by bad upload data.
+var (
+	errUnknownFileType     = errors.New("can't determine file type")
+	errUnsupportedFileType = errors.New("unsupported file type")
+	errInvalidImage        = errors.New("invalid image data")
+)
+
+// Create a blob owned by a user from uploaded image data, optionally
+// applying tags and item associations. This is shared between the
+// REST and gRPC APIs.
+func (s *Server) createBlob(owner string, data []byte,
+	opts *blobOptions) (*model.Blob, error) {
+	if opts == nil {
+		opts = &blobOptions{}
+	}
 
 	// Decode and check file type.
 	fileType, err := filetype.Match(data)
 	if err != nil {
-		return chassis.BadRequest(w, "Can't determine file type")
+		return nil, errUnknownFileType
 	}
 	allowed := false
 	for _, t := range allowedFileTypes {
@@ -53,31 +211,71 @@ func (s *Server) create(w http.ResponseWriter, r *http.Request) (interface{}, er
 		}
 	}
 	if !allowed {
-		return chassis.BadRequest(w, "Unsupported file type")
+		return nil, errUnsupportedFileType
+	}
+
+	// Process image data: this strips metadata, normalises orientation
+	// and size, and generates renditions.
+	img, err := imaging.Process(data, fileType.Extension)
+	if err != nil {
+		return nil, errInvalidImage
 	}
 
-	// Write blob data to storage.
+	// Write blob and rendition data to storage. If anything goes wrong
+	// from here on, the data that's already been written is removed.
 	id := s.db.NewBlobID()
-	url, size, err := s.blobstore.Write(id, fileType.Extension, data)
+	url, size, err := s.blobstore.Write(id, fileType.Extension, img.Data)
 	if err != nil {
 		return nil, err
 	}
+	renditions := model.Renditions{}
+	for _, r := range img.Renditions {
+		_, rsize, err := s.blobstore.Write(model.RenditionID(id, r.Name),
+			r.Format, r.Data)
+		if err != nil {
+			s.discardBlobData(id, fileType.Extension, renditions)
+			return nil, err
+		}
+		renditions[r.Name] = model.Rendition{
+			Format: r.Format,
+			Width:  r.Width,
+			Height: r.Height,
+			Size:   int(rsize),
+		}
+	}
 
 	// Create database record for blob.
 	blob := model.Blob{
-		ID:     id,
-		URI:    url,
-		Format: fileType.Extension,
-		Size:   int(size),
-		Owner:  &authInfo.UserID,
+		ID:         id,
+		URI:        url,
+		Format:     fileType.Extension,
+		Size:       int(size),
+		Owner:      &owner,
+		Tags:       opts.Tags,
+		Width:      &img.Width,
+		Height:     &img.Height,
+		Renditions: renditions,
+	}
+	if img.DominantColour != "" {
+		blob.DominantColour = &img.DominantColour
+	}
+	if img.BlurHash != "" {
+		blob.BlurHash = &img.BlurHash
 	}
 	err = s.db.CreateBlob(&blob)
 	if err != nil {
+		s.discardBlobData(id, fileType.Extension, renditions)
 		return nil, err
 	}
-
-	// Fix up URL to give image server URL instead of storage URL.
-	blob.URI = s.blobURL(&blob)
+	if len(opts.AssociatedItems) > 0 {
+		for _, itemID := range opts.AssociatedItems {
+			err = s.db.AddBlobsToItem(itemID, []string{blob.ID})
+			if err != nil {
+				return nil, err
+			}
+		}
+		blob.AssociatedItems = opts.AssociatedItems
+	}
 	return &blob, nil
 }
 
@@ -105,6 +303,25 @@ func (s *Server) detail(w http.ResponseWriter, r *http.Request) (interface{}, er
 	return blob, nil
 }
 
+// Serve the raw data for a blob. Blob data is public, in the same way
+// as images accessed via the image proxy are.
+func (s *Server) content(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	id := chi.URLParam(r, "id")
+	format := chi.URLParam(r, "format")
+	data, err := s.blobstore.Read(id, format)
+	if err == storage.ErrNotFound {
+		return chassis.NotFound(w)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+	return nil, nil
+}
+
 // Update a blob: the only allowed change is to modify the tags.
 func (s *Server) update(w http.ResponseWriter, r *http.Request) (interface{}, error) {
 	authInfo := chassis.AuthInfoFromContext(r.Context())
@@ -167,33 +384,40 @@ func (s *Server) delete(w http.ResponseWriter, r *http.Request) (interface{}, er
 		return chassis.NotFound(w)
 	}
 
-	inUse, err := s.db.ClearBlobOwner(id)
-	if err != nil {
+	if err = s.deleteBlob(blob); err != nil {
 		return nil, err
 	}
+	return chassis.NoContent(w)
+}
+
+// Delete a blob from its owner's image gallery. The blob is only
+// removed from storage and the database if it has no remaining item
+// associations.
+func (s *Server) deleteBlob(blob *model.Blob) error {
+	inUse, err := s.db.ClearBlobOwner(blob.ID)
+	if err != nil {
+		return err
+	}
 	if inUse {
 		// The blob still has item associations, so don't delete it for
 		// real.
-		return chassis.NoContent(w)
+		return nil
 	}
 
-	return s.deleteUnusedBlob(w, id, blob.Format)
-}
-
-// Delete an unused blob.
-func (s *Server) deleteUnusedBlob(w http.ResponseWriter,
-	id string, format string) (interface{}, error) {
 	// Delete the blob from the blob storage and from the database.
-	err := s.blobstore.Delete(id, format)
+	err = s.deleteBlobData(blob.ID, blob.Format, blob.Renditions)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	err = s.db.DeleteBlob(id)
-	if err != nil {
-		return nil, err
+	if err = s.db.DeleteBlob(blob.ID); err != nil {
+		return err
 	}
-
-	return chassis.NoContent(w)
+	chassis.Emit(s, events.BlobDeleted, events.BlobDeletedEvent{
+		ID:     blob.ID,
+		Format: blob.Format,
+		Reason: events.DeletedByOwner,
+	})
+	return nil
 }
 
 // Blob list for a user, possibly filtered by tags. Has "owner or
@@ -251,9 +475,44 @@ func (s *Server) list(w http.ResponseWriter, r *http.Request) (interface{}, erro
 	return blobs, nil
 }
 
+// Delete the stored data for a blob and its renditions.
+func (s *Server) deleteBlobData(id string, format string,
+	renditions model.Renditions) error {
+	for name, r := range renditions {
+		err := s.blobstore.Delete(model.RenditionID(id, name), r.Format)
+		if err != nil {
+			return err
+		}
+	}
+	return s.blobstore.Delete(id, format)
+}
+
+// Remove the data written for a blob that couldn't be created. All
+// the data is removed even if some deletions fail, and failures are
+// only logged, since it's the error that stopped the blob being
+// created that's reported.
+func (s *Server) discardBlobData(id string, format string,
+	renditions model.Renditions) {
+	for name, r := range renditions {
+		if err := s.blobstore.Delete(model.RenditionID(id, name), r.Format); err != nil {
+			log.Error().Err(err).Str("blob", id).Str("rendition", name).
+				Msg("removing rendition data for failed upload")
+		}
+	}
+	if err := s.blobstore.Delete(id, format); err != nil {
+		log.Error().Err(err).Str("blob", id).
+			Msg("removing blob data for failed upload")
+	}
+}
+
 // Generate the public download URL for a blob (as opposed to the
 // private Google Storage URL, which is what we store in the
-// database).
+// database). The URLs for the blob's renditions are filled in too.
 func (s *Server) blobURL(blob *model.Blob) string {
+	for name, r := range blob.Renditions {
+		r.URI = fmt.Sprintf("%s/%s.%s", s.imageBaseURL,
+			model.RenditionID(blob.ID, name), r.Format)
+		blob.Renditions[name] = r
+	}
 	return fmt.Sprintf("%s/%s.%s", s.imageBaseURL, blob.ID, blob.Format)
 }
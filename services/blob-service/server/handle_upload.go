@@ -0,0 +1,264 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog/log"
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/blob-service/db"
+	"github.com/veganbase/backend/services/blob-service/model"
+)
+
+// Limits for resumable uploads. Clients may choose a part size within
+// the limits (smaller parts are better on unreliable connections).
+// Uploads that aren't completed within the upload lifetime are
+// discarded.
+const (
+	maxUploadSize     = 50 * datasize.MB
+	minUploadPartSize = 64 * datasize.KB
+	maxUploadPartSize = 5 * datasize.MB
+	defaultPartSize   = 1 * datasize.MB
+	uploadLifetime    = 24 * time.Hour
+)
+
+// Start a resumable upload. The request body gives the total size of
+// the blob data, and optionally the part size and tags and item
+// associations for the blob. The response gives the upload ID and the
+// number of parts expected.
+func (s *Server) initiateUpload(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	req := struct {
+		blobOptions
+		Size     int `json:"size"`
+		PartSize int `json:"part_size"`
+	}{}
+	if err := chassis.Unmarshal(r.Body, &req); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if req.Size <= 0 || uint64(req.Size) > maxUploadSize.Bytes() {
+		return chassis.BadRequest(w,
+			fmt.Sprintf("Upload size must be between 1 byte and %s",
+				maxUploadSize.String()))
+	}
+	if req.PartSize == 0 {
+		req.PartSize = int(defaultPartSize)
+	}
+	if uint64(req.PartSize) < minUploadPartSize.Bytes() ||
+		uint64(req.PartSize) > maxUploadPartSize.Bytes() {
+		return chassis.BadRequest(w,
+			fmt.Sprintf("Part size must be between %s and %s",
+				minUploadPartSize.String(), maxUploadPartSize.String()))
+	}
+	if err := req.validate(); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if err := s.checkItemAccess(authInfo, req.AssociatedItems); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	upload := model.Upload{
+		Owner:           authInfo.UserID,
+		Size:            req.Size,
+		PartSize:        req.PartSize,
+		Tags:            req.Tags,
+		AssociatedItems: req.AssociatedItems,
+		ReceivedParts:   []int{},
+		ExpiresAt:       time.Now().Add(uploadLifetime),
+	}
+	if err := s.db.CreateUpload(&upload); err != nil {
+		return nil, err
+	}
+	return uploadView(&upload), nil
+}
+
+// Get the status of a resumable upload, so that a client can
+// determine which parts it needs to send to resume an interrupted
+// upload.
+func (s *Server) uploadStatus(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	upload, err := s.ownedUpload(r)
+	if err != nil {
+		return nil, err
+	}
+	if upload == nil {
+		return chassis.NotFound(w)
+	}
+	return uploadView(upload), nil
+}
+
+// Upload a single part of a resumable upload. The request body is
+// the raw part data, which must be exactly the expected size for the
+// part. Parts may be uploaded in any order, and uploading a part more
+// than once replaces the earlier data.
+func (s *Server) uploadPart(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	upload, err := s.ownedUpload(r)
+	if err != nil {
+		return nil, err
+	}
+	if upload == nil {
+		return chassis.NotFound(w)
+	}
+
+	part, err := strconv.Atoi(chi.URLParam(r, "part"))
+	if err != nil || part < 1 || part > upload.Parts() {
+		return chassis.BadRequest(w,
+			fmt.Sprintf("Part number must be between 1 and %d", upload.Parts()))
+	}
+
+	// Read body, limiting to the expected part size (the extra byte
+	// allows us to detect oversized parts).
+	expected := upload.ExpectedPartSize(part)
+	data, err := chassis.ReadBody(r, uint64(expected)+1)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if len(data) != expected {
+		return chassis.BadRequest(w,
+			fmt.Sprintf("Part %d must be %d bytes long", part, expected))
+	}
+
+	err = s.db.PutUploadPart(upload.ID, part, data)
+	if err == db.ErrUploadNotFound {
+		return chassis.NotFound(w)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return chassis.NoContent(w)
+}
+
+// Complete a resumable upload, creating a blob from the uploaded
+// parts. All parts must have been uploaded. The upload is claimed
+// first, so that concurrent requests to complete the same upload
+// can't each create a blob.
+func (s *Server) completeUpload(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	upload, err := s.ownedUpload(r)
+	if err != nil {
+		return nil, err
+	}
+	if upload == nil {
+		return chassis.NotFound(w)
+	}
+	if len(upload.ReceivedParts) != upload.Parts() {
+		return chassis.BadRequest(w,
+			fmt.Sprintf("Upload incomplete (%d of %d parts received)",
+				len(upload.ReceivedParts), upload.Parts()))
+	}
+
+	// The user may have lost access to the items since the upload was
+	// started.
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if err := s.checkItemAccess(authInfo, upload.AssociatedItems); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	switch err = s.db.ClaimUpload(upload.ID); err {
+	case nil:
+	case db.ErrUploadNotFound:
+		return chassis.NotFound(w)
+	case db.ErrUploadClaimed:
+		return chassis.Conflict(w, "Upload is already being completed")
+	default:
+		return nil, err
+	}
+
+	// Release the claim if no blob is created, so the client can try
+	// again.
+	created := false
+	defer func() {
+		if created {
+			return
+		}
+		if err := s.db.ReleaseUpload(upload.ID); err != nil {
+			log.Error().Err(err).Str("upload", upload.ID).Msg("releasing upload")
+		}
+	}()
+
+	data, err := s.db.UploadData(upload.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != upload.Size {
+		return nil, fmt.Errorf("upload %s: data size %d, expected %d",
+			upload.ID, len(data), upload.Size)
+	}
+
+	opts := &blobOptions{
+		Tags:            upload.Tags,
+		AssociatedItems: upload.AssociatedItems,
+	}
+	blob, err := s.createBlob(upload.Owner, data, opts)
+	switch err {
+	case nil:
+	case errUnknownFileType:
+		return chassis.BadRequest(w, "Can't determine file type")
+	case errUnsupportedFileType:
+		return chassis.BadRequest(w, "Unsupported file type")
+	case errInvalidImage:
+		return chassis.BadRequest(w, "Invalid image data")
+	default:
+		return nil, err
+	}
+	created = true
+	if err = s.db.DeleteUpload(upload.ID); err != nil {
+		return nil, err
+	}
+
+	// Fix up URL to give image server URL instead of storage URL.
+	blob.URI = s.blobURL(blob)
+	return blob, nil
+}
+
+// Abandon a resumable upload, discarding any parts uploaded so far.
+func (s *Server) abortUpload(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	upload, err := s.ownedUpload(r)
+	if err != nil {
+		return nil, err
+	}
+	if upload == nil {
+		return chassis.NotFound(w)
+	}
+	err = s.db.DeleteUpload(upload.ID)
+	if err != nil && err != db.ErrUploadNotFound {
+		return nil, err
+	}
+	return chassis.NoContent(w)
+}
+
+// Look up the upload given in the URL parameters. Uploads are only
+// visible to the user performing them, so this returns nil if the
+// upload doesn't exist, has expired or is owned by another user.
+func (s *Server) ownedUpload(r *http.Request) (*model.Upload, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return nil, nil
+	}
+	upload, err := s.db.UploadByID(chi.URLParam(r, "id"))
+	if err == db.ErrUploadNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if upload.Owner != authInfo.UserID {
+		return nil, nil
+	}
+	return upload, nil
+}
+
+// Upload information returned to clients, including the number of
+// parts expected.
+func uploadView(upload *model.Upload) interface{} {
+	return struct {
+		*model.Upload
+		Parts int `json:"parts"`
+	}{upload, upload.Parts()}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"errors"
 	"image"
 	"image/color"
 	"image/gif"
@@ -17,10 +18,28 @@ import (
 	"github.com/veganbase/backend/chassis"
 	chassis_mocks "github.com/veganbase/backend/chassis/mocks"
 	"github.com/veganbase/backend/services/blob-service/db"
+	"github.com/veganbase/backend/services/blob-service/imaging"
 	"github.com/veganbase/backend/services/blob-service/mocks"
 	"github.com/veganbase/backend/services/blob-service/model"
+	item "github.com/veganbase/backend/services/item-service/client"
+	item_model "github.com/veganbase/backend/services/item-service/model"
+	user_mocks "github.com/veganbase/backend/services/user-service/mocks"
 )
 
+// itemOwners looks up item owners in memory.
+type itemOwners struct {
+	item.Client
+	owners map[string]string
+}
+
+func (c *itemOwners) ItemInfo(id string) (*item_model.Item, error) {
+	owner, ok := c.owners[id]
+	if !ok {
+		return nil, errors.New("item not found")
+	}
+	return &item_model.Item{ID: id, Owner: owner}, nil
+}
+
 func assocBlob(in *model.Blob, assocs []string) *model.Blob {
 	return &model.Blob{
 		ID:              in.ID,
@@ -99,8 +118,50 @@ var (
 		"X-Auth-User-Id":  "usr_TESTUSER3",
 		"X-Auth-Is-Admin": "true",
 	}
+	upload1 = model.Upload{
+		ID:            "upl_UPLOAD1",
+		Owner:         user1,
+		Size:          200000,
+		PartSize:      65536,
+		ReceivedParts: []int{1, 2},
+	}
+	upload2 = model.Upload{
+		ID:            "upl_UPLOAD2",
+		Owner:         user1,
+		PartSize:      65536,
+		ReceivedParts: []int{1},
+		Tags:          tags2,
+	}
+	upload3 = model.Upload{
+		ID:              "upl_UPLOAD3",
+		Owner:           user1,
+		Size:            100,
+		PartSize:        65536,
+		AssociatedItems: []string{"xyz_item0002"},
+		ReceivedParts:   []int{1},
+	}
+	upload4 = model.Upload{
+		ID:            "upl_UPLOAD4",
+		Owner:         user1,
+		Size:          100,
+		PartSize:      65536,
+		ReceivedParts: []int{1},
+	}
+	upload5 = model.Upload{
+		ID:            "upl_UPLOAD5",
+		Owner:         user1,
+		Size:          100,
+		PartSize:      65536,
+		ReceivedParts: []int{1},
+	}
 	dbMock      = mocks.DB{}
 	storageMock = chassis_mocks.Storage{}
+	userMock    = user_mocks.Client{}
+	itemOwner   = itemOwners{owners: map[string]string{
+		"xyz_item0001": user1,
+		"xyz_item0002": user2,
+		"xyz_item0003": "org_TESTORG1",
+	}}
 )
 
 func mockSetup() {
@@ -159,6 +220,27 @@ func mockSetup() {
 	dbMock.On("RemoveBlobsFromItem", "xyz_item0006", mock.Anything).Return(nil, nil)
 	dbMock.On("RemoveBlobsFromItem", mock.Anything).Return(db.ErrBlobNotFound)
 
+	pngData := bytes.Buffer{}
+	png.Encode(&pngData, makeImage(200, 100))
+	upload2.Size = pngData.Len()
+	dbMock.On("OrphanedBlobs", mock.Anything, mock.Anything).
+		Return([]model.Blob{blob4}, nil)
+
+	dbMock.On("CreateUpload", mock.Anything).Return(nil)
+	dbMock.On("UploadByID", "upl_UPLOAD1").Return(&upload1, nil)
+	dbMock.On("UploadByID", "upl_UPLOAD2").Return(&upload2, nil)
+	dbMock.On("UploadByID", "upl_UPLOAD3").Return(&upload3, nil)
+	dbMock.On("UploadByID", "upl_UPLOAD4").Return(&upload4, nil)
+	dbMock.On("UploadByID", "upl_UPLOAD5").Return(&upload5, nil)
+	dbMock.On("UploadByID", mock.Anything).Return(nil, db.ErrUploadNotFound)
+	dbMock.On("PutUploadPart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dbMock.On("UploadData", "upl_UPLOAD2").Return(pngData.Bytes(), nil)
+	dbMock.On("UploadData", "upl_UPLOAD5").Return(make([]byte, 100), nil)
+	dbMock.On("ClaimUpload", "upl_UPLOAD4").Return(db.ErrUploadClaimed)
+	dbMock.On("ClaimUpload", mock.Anything).Return(nil)
+	dbMock.On("ReleaseUpload", mock.Anything).Return(nil)
+	dbMock.On("DeleteUpload", mock.Anything).Return(nil)
+
 	dbMock.
 		On("SaveEvent", mock.Anything, mock.Anything, mock.Anything).
 		Return(nil)
@@ -166,6 +248,9 @@ func mockSetup() {
 	storageMock.On("Write", mock.Anything, mock.Anything, mock.Anything).
 		Return("http://media-link/X.png", int64(345), nil)
 	storageMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	userMock.On("OrgsForUser", user1).Return(map[string]bool{"org_TESTORG1": false}, nil)
+	userMock.On("OrgsForUser", mock.Anything).Return(map[string]bool{}, nil)
 }
 
 func RunWithServer(t *testing.T, test func(e *httpexpect.Expect)) {
@@ -175,6 +260,9 @@ func RunWithServer(t *testing.T, test func(e *httpexpect.Expect)) {
 	s.db = &dbMock
 	storageMock = chassis_mocks.Storage{}
 	s.blobstore = &storageMock
+	userMock = user_mocks.Client{}
+	s.userSvc = &userMock
+	s.itemSvc = &itemOwner
 
 	srv := httptest.NewServer(s.Srv.Handler)
 	defer srv.Close()
@@ -355,7 +443,180 @@ func TestCreate(t *testing.T) {
 			Status(http.StatusOK).
 			JSON().Object().
 			ContainsKey("format").ValueEqual("format", "png").
-			ContainsKey("size").ValueEqual("size", len(buf.Bytes()))
+			ContainsKey("width").ValueEqual("width", 200).
+			ContainsKey("height").ValueEqual("height", 100).
+			ContainsKey("renditions").Value("renditions").
+			Object().Keys().ContainsOnly("thumbnail", "card", "full")
+
+		// Multipart upload with tags and item associations.
+		b := e.POST("/blobs").WithHeaders(sess1).WithMultipart().
+			WithFileBytes("file", "test.png", buf.Bytes()).
+			WithFormField("metadata",
+				`{"tags": ["cake"], "associated_items": ["xyz_item0001"]}`).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		b.ContainsKey("tags").Value("tags").Array().Elements("cake")
+		b.ContainsKey("associated_items").Value("associated_items").
+			Array().Elements("xyz_item0001")
+
+		// Items owned by the user's organisation can be associated too.
+		e.POST("/blobs").WithHeaders(sess1).WithMultipart().
+			WithFileBytes("file", "test.png", buf.Bytes()).
+			WithFormField("metadata", `{"associated_items": ["xyz_item0003"]}`).
+			Expect().
+			Status(http.StatusOK)
+
+		// Items owned by other users or unknown items => bad request.
+		e.POST("/blobs").WithHeaders(sess1).WithMultipart().
+			WithFileBytes("file", "test.png", buf.Bytes()).
+			WithFormField("metadata", `{"associated_items": ["xyz_item0001", "xyz_item0002"]}`).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().
+			ValueEqual("message", "Not allowed to associate blob with item 'xyz_item0002'")
+		e.POST("/blobs").WithHeaders(sess2).WithMultipart().
+			WithFileBytes("file", "test.png", buf.Bytes()).
+			WithFormField("metadata", `{"associated_items": ["xyz_item0003"]}`).
+			Expect().
+			Status(http.StatusBadRequest)
+		e.POST("/blobs").WithHeaders(sess1).WithMultipart().
+			WithFileBytes("file", "test.png", buf.Bytes()).
+			WithFormField("metadata", `{"associated_items": ["xyz_item0009"]}`).
+			Expect().
+			Status(http.StatusBadRequest)
+
+		// Administrators can associate blobs with any item.
+		e.POST("/blobs").WithHeaders(sess3).WithMultipart().
+			WithFileBytes("file", "test.png", buf.Bytes()).
+			WithFormField("metadata", `{"associated_items": ["xyz_item0002"]}`).
+			Expect().
+			Status(http.StatusOK)
+
+		// Multipart upload with bad item ID => bad request.
+		e.POST("/blobs").WithHeaders(sess1).WithMultipart().
+			WithFileBytes("file", "test.png", buf.Bytes()).
+			WithFormField("metadata", `{"associated_items": ["not an item"]}`).
+			Expect().
+			Status(http.StatusBadRequest)
+
+		// Multipart upload without file => bad request.
+		e.POST("/blobs").WithHeaders(sess1).WithMultipart().
+			WithFormField("metadata", `{"tags": ["cake"]}`).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().
+			ContainsKey("message").ValueEqual("message", "Missing file data")
+	})
+}
+
+func TestResumableUpload(t *testing.T) {
+	RunWithServer(t, func(e *httpexpect.Expect) {
+		mockSetup()
+
+		// Unauthenticated => not found.
+		e.POST("/blobs/uploads").WithJSON(map[string]int{"size": 200000}).
+			Expect().
+			Status(http.StatusNotFound)
+
+		// Authenticated => upload created, with part count.
+		e.POST("/blobs/uploads").WithHeaders(sess1).
+			WithJSON(map[string]interface{}{"size": 200000, "part_size": 65536}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			ContainsKey("parts").ValueEqual("parts", 4)
+
+		// Item associations are checked when the upload is started.
+		e.POST("/blobs/uploads").WithHeaders(sess1).
+			WithJSON(map[string]interface{}{"size": 200000,
+				"associated_items": []string{"xyz_item0001"}}).
+			Expect().
+			Status(http.StatusOK)
+		e.POST("/blobs/uploads").WithHeaders(sess2).
+			WithJSON(map[string]interface{}{"size": 200000,
+				"associated_items": []string{"xyz_item0001"}}).
+			Expect().
+			Status(http.StatusBadRequest)
+
+		// Bad sizes => bad request.
+		e.POST("/blobs/uploads").WithHeaders(sess1).
+			WithJSON(map[string]int{"size": 100 * 1024 * 1024}).
+			Expect().
+			Status(http.StatusBadRequest)
+		e.POST("/blobs/uploads").WithHeaders(sess1).
+			WithJSON(map[string]int{"size": 200000, "part_size": 100}).
+			Expect().
+			Status(http.StatusBadRequest)
+
+		// Status shows received parts, and only to the uploading user.
+		e.GET("/blobs/upload/upl_UPLOAD1").WithHeaders(sess1).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			ContainsKey("received_parts").Value("received_parts").
+			Array().Elements(1, 2)
+		e.GET("/blobs/upload/upl_UPLOAD1").WithHeaders(sess2).
+			Expect().
+			Status(http.StatusNotFound)
+		e.GET("/blobs/upload/upl_UNKNOWN").WithHeaders(sess1).
+			Expect().
+			Status(http.StatusNotFound)
+
+		// Parts must have exactly the expected size.
+		e.PUT("/blobs/upload/upl_UPLOAD1/part/3").WithHeaders(sess1).
+			WithBytes(make([]byte, 65536)).
+			Expect().
+			Status(http.StatusNoContent)
+		e.PUT("/blobs/upload/upl_UPLOAD1/part/4").WithHeaders(sess1).
+			WithBytes(make([]byte, 65536)).
+			Expect().
+			Status(http.StatusBadRequest)
+		e.PUT("/blobs/upload/upl_UPLOAD1/part/4").WithHeaders(sess1).
+			WithBytes(make([]byte, 200000-3*65536)).
+			Expect().
+			Status(http.StatusNoContent)
+		e.PUT("/blobs/upload/upl_UPLOAD1/part/5").WithHeaders(sess1).
+			WithBytes(make([]byte, 100)).
+			Expect().
+			Status(http.StatusBadRequest)
+
+		// Can't complete an upload with missing parts.
+		e.POST("/blobs/upload/upl_UPLOAD1/complete").WithHeaders(sess1).
+			Expect().
+			Status(http.StatusBadRequest)
+
+		// Completing an upload creates a blob.
+		e.POST("/blobs/upload/upl_UPLOAD2/complete").WithHeaders(sess1).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			ContainsKey("format").ValueEqual("format", "png").
+			ContainsKey("tags").Value("tags").Array().Elements("cake")
+		dbMock.AssertNotCalled(t, "ReleaseUpload", "upl_UPLOAD2")
+
+		// Item associations are checked again on completion.
+		e.POST("/blobs/upload/upl_UPLOAD3/complete").WithHeaders(sess1).
+			Expect().
+			Status(http.StatusBadRequest)
+		dbMock.AssertNotCalled(t, "ClaimUpload", "upl_UPLOAD3")
+
+		// Only one request can complete an upload.
+		e.POST("/blobs/upload/upl_UPLOAD4/complete").WithHeaders(sess1).
+			Expect().
+			Status(http.StatusConflict)
+		dbMock.AssertNotCalled(t, "UploadData", "upl_UPLOAD4")
+
+		// A failed completion releases the upload.
+		e.POST("/blobs/upload/upl_UPLOAD5/complete").WithHeaders(sess1).
+			Expect().
+			Status(http.StatusBadRequest)
+		dbMock.AssertCalled(t, "ReleaseUpload", "upl_UPLOAD5")
+
+		// Abandon an upload.
+		e.DELETE("/blobs/upload/upl_UPLOAD1").WithHeaders(sess1).
+			Expect().
+			Status(http.StatusNoContent)
 	})
 }
 
@@ -457,3 +718,71 @@ func TestItemAssociations(t *testing.T) {
 			Status(http.StatusNoContent)
 	})
 }
+
+func TestOrphanReport(t *testing.T) {
+	RunWithServer(t, func(e *httpexpect.Expect) {
+		mockSetup()
+
+		// Unauthenticated or not administrator => not found.
+		e.GET("/blobs/orphans").
+			Expect().
+			Status(http.StatusNotFound)
+		e.GET("/blobs/orphans").WithHeaders(sess1).
+			Expect().
+			Status(http.StatusNotFound)
+
+		// Administrator => dry run report.
+		rep := e.GET("/blobs/orphans").WithHeaders(sess3).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		rep.ValueEqual("dry_run", true)
+		rep.ValueEqual("count", 1)
+		rep.Value("blobs").Array().Length().Equal(1)
+	})
+}
+
+func TestCreateBlobCleanup(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := png.Encode(&buf, makeImage(200, 100)); err != nil {
+		t.Fatal(err)
+	}
+	writeErr := errors.New("storage unavailable")
+
+	// A failed rendition write removes the main blob and the
+	// renditions already written.
+	dbm := mocks.DB{}
+	dbm.On("NewBlobID").Return("BLOBXYZ")
+	store := chassis_mocks.Storage{}
+	store.On("Write", "BLOBXYZ-full", mock.Anything, mock.Anything).
+		Return("", int64(0), writeErr)
+	store.On("Write", mock.Anything, mock.Anything, mock.Anything).
+		Return("http://media-link/X.png", int64(345), nil)
+	store.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	s := &Server{db: &dbm, blobstore: &store}
+	if _, err := s.createBlob(user1, buf.Bytes(), nil); err != writeErr {
+		t.Errorf("err = %v", err)
+	}
+	store.AssertCalled(t, "Delete", "BLOBXYZ", "png")
+	store.AssertCalled(t, "Delete", "BLOBXYZ-thumbnail", mock.Anything)
+	store.AssertCalled(t, "Delete", "BLOBXYZ-card", mock.Anything)
+	store.AssertNotCalled(t, "Delete", "BLOBXYZ-full", mock.Anything)
+	dbm.AssertNotCalled(t, "CreateBlob", mock.Anything)
+
+	// So does a failure to create the database record.
+	dbm = mocks.DB{}
+	dbm.On("NewBlobID").Return("BLOBXYZ")
+	dbm.On("CreateBlob", mock.Anything).Return(errors.New("database down"))
+	store = chassis_mocks.Storage{}
+	store.On("Write", mock.Anything, mock.Anything, mock.Anything).
+		Return("http://media-link/X.png", int64(345), nil)
+	store.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	if _, err := s.createBlob(user1, buf.Bytes(), nil); err == nil {
+		t.Error("expected error")
+	}
+	store.AssertNumberOfCalls(t, "Delete", 1+len(imaging.Renditions))
+	store.AssertCalled(t, "Delete", "BLOBXYZ", "png")
+	for _, spec := range imaging.Renditions {
+		store.AssertCalled(t, "Delete", "BLOBXYZ-"+spec.Name, mock.Anything)
+	}
+}
@@ -23,6 +23,18 @@ func (s *Server) routes() chi.Router {
 	// Create blob.
 	r.Post("/blobs", chassis.SimpleHandler(s.create))
 
+	// Resumable uploads.
+	r.Post("/blobs/uploads", chassis.SimpleHandler(s.initiateUpload))
+	r.Route("/blobs/upload/{id:upl_[a-zA-Z0-9]+}", func(r chi.Router) {
+		r.Get("/", chassis.SimpleHandler(s.uploadStatus))
+		r.Put("/part/{part:[0-9]+}", chassis.SimpleHandler(s.uploadPart))
+		r.Post("/complete", chassis.SimpleHandler(s.completeUpload))
+		r.Delete("/", chassis.SimpleHandler(s.abortUpload))
+	})
+
+	// Orphaned blob report (administrators only).
+	r.Get("/blobs/orphans", chassis.SimpleHandler(s.orphanReport))
+
 	// Blob tags in use for user.
 	r.Get("/blobs/tags", chassis.SimpleHandler(s.tagList))
 
@@ -33,6 +45,10 @@ func (s *Server) routes() chi.Router {
 		r.Delete("/", chassis.SimpleHandler(s.delete))
 	})
 
+	// Raw blob data, used as a backend for the image proxy when blobs
+	// aren't kept in a publicly readable bucket.
+	r.Get("/content/{id:[a-zA-Z0-9-]+}.{format:[a-z]+}", chassis.SimpleHandler(s.content))
+
 	// Blob list for user.
 	r.Get("/me/blobs", chassis.SimpleHandler(s.list))
 	r.Get("/user/{userid:usr_[a-zA-Z0-9]+}/blobs", chassis.SimpleHandler(s.list))
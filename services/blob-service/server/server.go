@@ -8,6 +8,8 @@ import (
 	"github.com/veganbase/backend/chassis/storage"
 	"github.com/veganbase/backend/services/blob-service/db"
 	"github.com/veganbase/backend/services/blob-service/model"
+	item "github.com/veganbase/backend/services/item-service/client"
+	user "github.com/veganbase/backend/services/user-service/client"
 
 	"github.com/rs/zerolog/log"
 )
@@ -15,9 +17,12 @@ import (
 // Server is the server structure for the blob service.
 type Server struct {
 	chassis.Server
-	db           db.DB
-	blobstore    storage.Storage
-	imageBaseURL string
+	db            db.DB
+	blobstore     storage.Storage
+	itemSvc       item.Client
+	userSvc       user.Client
+	imageBaseURL  string
+	gcGracePeriod time.Duration
 }
 
 // Config contains the configuration information needed to start
@@ -28,42 +33,107 @@ type Config struct {
 	Project      string `env:"PROJECT_ID,default=dev"`
 	DBURL        string `env:"DATABASE_URL,required"`
 	Port         int    `env:"PORT,default=8080"`
+	GRPCPort     int    `env:"GRPC_PORT,default=9090"`
 	Credentials  string `env:"CREDENTIALS_PATH"`
 	BucketName   string `env:"BUCKET_NAME"`
 	ImageBaseURL string `env:"IMAGE_BASE_URL"`
+
+	// The item and user services are used to check that users own
+	// the items they associate uploaded blobs with.
+	ItemServiceURL string `env:"ITEM_SERVICE_URL,default=http://item-service"`
+	UserServiceURL string `env:"USER_SERVICE_URL,default=http://user-service"`
+
+	// Blob storage driver: "gcs", "file" or "s3". If not set, Google
+	// Cloud Storage is used, except in development mode, where blob
+	// data is not stored at all.
+	StorageDriver  string `env:"STORAGE_DRIVER"`
+	StorageDir     string `env:"STORAGE_DIR,default=blobs"`
+	StorageBaseURL string `env:"STORAGE_BASE_URL"`
+	S3Endpoint     string `env:"S3_ENDPOINT"`
+	S3Region       string `env:"S3_REGION"`
+	S3AccessKey    string `env:"S3_ACCESS_KEY"`
+	S3SecretKey    string `env:"S3_SECRET_KEY"`
+
+	// Blobs with no item associations are deleted by a periodic
+	// garbage collector once they're older than the grace period.
+	// Setting the interval to zero disables the garbage collector.
+	GCInterval    time.Duration `env:"GC_INTERVAL,default=6h"`
+	GCGracePeriod time.Duration `env:"GC_GRACE_PERIOD,default=720h"`
 }
 
 // NewServer creates the server structure for the blob service.
 func NewServer(cfg *Config) *Server {
 	// Common server initialisation.
-	s := &Server{imageBaseURL: cfg.ImageBaseURL}
+	s := &Server{
+		imageBaseURL:  cfg.ImageBaseURL,
+		gcGracePeriod: cfg.GCGracePeriod,
+	}
 	s.Init(cfg.AppName, cfg.Project, cfg.Port, cfg.Credentials, s.routes())
+	chassis.CheckURL(cfg.ItemServiceURL, "item service")
+	chassis.CheckURL(cfg.UserServiceURL, "user service")
+
+	// Connect to item and user services.
+	var err error
+	s.itemSvc = item.New(cfg.ItemServiceURL)
+	s.userSvc, err = user.New(cfg.UserServiceURL, s.PubSub, s.AppName)
+	if err != nil {
+		log.Fatal().Err(err).Msg("couldn't initialise user service client")
+	}
 
 	// Connect to blob database.
 	timeout, _ := context.WithTimeout(context.Background(), time.Second*10)
-	var err error
-	s.db, err = db.NewPGClient(timeout, cfg.DBURL)
+	pg, err := db.NewPGClient(timeout, cfg.DBURL)
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't connect to blob database")
 	}
+	s.db = pg
+	s.StartEventRelay(pg.DB)
 
 	// Connect to blob storage.
-	if !cfg.DevMode {
+	driver := cfg.StorageDriver
+	if driver == "" && !cfg.DevMode {
+		driver = "gcs"
+	}
+	switch driver {
+	case "gcs":
 		s.blobstore, err = storage.NewGoogleClient(s.Ctx, cfg.Credentials, cfg.BucketName)
-	} else {
+	case "file":
+		s.blobstore, err = storage.NewFileStorage(cfg.StorageDir, cfg.StorageBaseURL)
+	case "s3":
+		s.blobstore, err = storage.NewS3Storage(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.BucketName,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PublicURL: cfg.StorageBaseURL,
+		})
+	case "":
 		blobs := map[string]*model.Blob{}
 		s.blobstore = storage.NewMockStorage(&blobs)
+	default:
+		log.Fatal().Str("driver", driver).Msg("unknown blob storage driver")
 	}
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't connect to blob storage")
 	}
 
+	// The gRPC API is served alongside the REST API, unless disabled
+	// by setting the port to zero.
+	if cfg.GRPCPort != 0 {
+		s.startGRPC(cfg.GRPCPort)
+	}
+
+	if cfg.GCInterval != 0 {
+		s.startGC(cfg.GCInterval, cfg.GCGracePeriod)
+	}
+
 	return s
 }
 
-// Publish is a placeholder for event publication (not used here yet).
+// Publish routes messages to the server's Pub/Sub stream.
 func (s *Server) Publish(topic string, eventData interface{}) error {
-	return nil
+	return s.PubSub.Publish(topic, eventData)
 }
 
 // SaveEvent saves and publishes an event.
@@ -2,9 +2,21 @@ package client
 
 import "github.com/veganbase/backend/services/cart-service/model"
 
+// PromotionError is the error returned when a promotion can't be
+// redeemed, carrying the cart service's explanation.
+type PromotionError struct {
+	Message string
+}
+
+func (e *PromotionError) Error() string {
+	return e.Message
+}
+
 // Client is the service client API for the cart service.
 type Client interface {
-	Active(userId string) (*model.FullCart, error)
+	Active(userId string, site string) (*model.FullCart, error)
 	FinishCart(cartId string) (*model.Cart, error)
+	RedeemPromotion(redemption *model.Redemption) error
+	ReleasePromotions(reference string) error
 }
 
@@ -7,6 +7,7 @@ import (
 	"github.com/veganbase/backend/services/cart-service/model"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 )
 
 // RESTClient is a cart service client that connects via REST.
@@ -20,9 +21,10 @@ func New(baseURL string) *RESTClient {
 }
 
 // Active invokes the cart GET /cart/{user_id}/active method on the cart service.
-func (c *RESTClient) Active(userId string) (*model.FullCart, error) {
+// The site is used to check whether the cart's promotion code can be used.
+func (c *RESTClient) Active(userId string, site string) (*model.FullCart, error) {
 	// Do GET to endpoint.
-	rsp, err := http.Get(c.baseURL + "/internal/cart/" + userId + "/active")
+	rsp, err := http.Get(c.baseURL + "/internal/cart/" + userId + "/active?site=" + url.QueryEscape(site))
 	if err != nil {
 		return nil, err
 	}
@@ -74,4 +76,44 @@ func (c *RESTClient) FinishCart(cartId string) (*model.Cart, error) {
 		return &cart, nil
 	}
 	return nil, chassis.BuildErrorFromErrMsg(rsp)
-}
\ No newline at end of file
+}
+// RedeemPromotion records the use of a promotion for a purchase. If
+// the promotion has been used up, the error is a *PromotionError.
+func (c *RESTClient) RedeemPromotion(redemption *model.Redemption) error {
+	body, err := json.Marshal(redemption)
+	if err != nil {
+		return err
+	}
+	rsp, err := http.Post(c.baseURL+"/internal/promotion/"+redemption.PromotionID+"/redemptions",
+		"application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	switch rsp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusConflict, http.StatusNotFound:
+		return &PromotionError{chassis.BuildErrorFromErrMsg(rsp).Error()}
+	}
+	return chassis.BuildErrorFromErrMsg(rsp)
+}
+
+// ReleasePromotions removes the promotion uses recorded for a
+// purchase.
+func (c *RESTClient) ReleasePromotions(reference string) error {
+	req, err := http.NewRequest(http.MethodDelete,
+		c.baseURL+"/internal/promotion-redemptions/"+reference, nil)
+	if err != nil {
+		return err
+	}
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusNoContent {
+		return chassis.BuildErrorFromErrMsg(rsp)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// The following environment variables will be used to get a Postgres
+// connection string: VB_TEST_DB.
+//
+package db
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	migrate "github.com/rubenv/sql-migrate"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/veganbase/backend/chassis/test_utils"
+	"github.com/veganbase/backend/services/cart-service/db"
+	"github.com/veganbase/backend/services/cart-service/model"
+	"github.com/veganbase/backend/services/cart-service/model/types"
+)
+
+var pg *db.PGClient
+
+func init() {
+	pgdsn := test_utils.InitTestDB(false)
+	var err error
+	pg, err = db.NewPGClient(context.Background(), pgdsn)
+	if err != nil {
+		log.Fatal().Err(err).Msg("couldn't connect to test database")
+	}
+}
+
+func RunWithSchema(t *testing.T, test func(pg *db.PGClient, t *testing.T)) {
+	defer func() {
+		test_utils.ResetSchema(pg.DB, false)
+	}()
+
+	migrations := &migrate.AssetMigrationSource{
+		Asset:    db.Asset,
+		AssetDir: db.AssetDir,
+		Dir:      "migrations",
+	}
+	_, err := migrate.Exec(pg.DB.DB, "postgres", migrations, migrate.Up)
+	assert.Nil(t, err, "database migrations failed!")
+
+	test(pg, t)
+}
+
+func addItem(t *testing.T, cartID, itemID, variant string, quantity int) {
+	ci := model.CartItem{CartItemFixed: model.CartItemFixed{
+		CartID:   cartID,
+		ItemID:   itemID,
+		Variant:  variant,
+		Quantity: quantity,
+	}}
+	assert.Nil(t, pg.CreateCartItem(&ci))
+}
+
+func TestMoveCartItems(t *testing.T) {
+	RunWithSchema(t, func(pg *db.PGClient, t *testing.T) {
+		from, err := pg.CreateCart(&model.Cart{Owner: "usr_A", CartStatus: types.Active})
+		assert.Nil(t, err)
+		to, err := pg.CreateCart(&model.Cart{Owner: "usr_A", CartStatus: types.Abandoned})
+		assert.Nil(t, err)
+
+		addItem(t, from.ID, "itm_1", "", 2)
+		addItem(t, from.ID, "itm_2", "small", 1)
+		addItem(t, from.ID, "itm_3", "", 4)
+		addItem(t, to.ID, "itm_1", "", 3)
+		addItem(t, to.ID, "itm_2", "large", 1)
+
+		assert.Nil(t, pg.MoveCartItems(from.ID, to.ID))
+
+		items, err := pg.CartItemsByCartId(from.ID)
+		assert.Nil(t, err)
+		assert.Empty(t, *items)
+
+		items, err = pg.CartItemsByCartId(to.ID)
+		assert.Nil(t, err)
+		quantities := map[string]int{}
+		for _, ci := range *items {
+			quantities[ci.ItemID+"/"+ci.Variant] = ci.Quantity
+		}
+		// Matching items have their quantities merged, different
+		// variants of the same item are kept apart.
+		assert.Equal(t, map[string]int{
+			"itm_1/":      5,
+			"itm_2/small": 1,
+			"itm_2/large": 1,
+			"itm_3/":      4,
+		}, quantities)
+
+		// Moving items out of an empty cart changes nothing.
+		assert.Nil(t, pg.MoveCartItems(from.ID, to.ID))
+		items, err = pg.CartItemsByCartId(to.ID)
+		assert.Nil(t, err)
+		assert.Len(t, *items, 4)
+	})
+}
@@ -23,10 +23,10 @@ func (pg *PGClient) CartItemsByCartId(cartId string) (*[]model.CartItem, error)
 	return cartItems, nil
 }
 
-func (pg *PGClient) CartItemByCartIdAndItemId(cartId string, itemId string) (*model.CartItem, error) {
+func (pg *PGClient) CartItemByCartIdAndItemId(cartId string, itemId string, variant string) (*model.CartItem, error) {
 
 	item := &model.CartItem{}
-	err := sqlx.Get(pg.DB, item, qCartItemBy + `cart_id = $1 and item_id = $2`, cartId, itemId)
+	err := sqlx.Get(pg.DB, item, qCartItemBy + `cart_id = $1 and item_id = $2 and variant = $3`, cartId, itemId, variant)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrCartItemNotFound
@@ -50,7 +50,7 @@ func (pg *PGClient) CartItemByCartIdAndCartItemId(cartId string, cItemId int) (*
 }
 
 const qCartItemBy = `
-SELECT id, cart_id, item_id, quantity, item_type, other_info, subscribe, delivery_every
+SELECT id, cart_id, item_id, variant, quantity, item_type, other_info, subscribe, delivery_every
   FROM cart_items WHERE `
 
 // CreateCart creates a new cart item.
@@ -78,14 +78,16 @@ func (pg *PGClient) CreateCartItem(cart *model.CartItem) error {
 			return err
 		}
 	}
+	rows.Close()
 
+	err = touchCart(tx, cart.CartID)
 	return err
 }
 
 const qCreateCartItem = `
 INSERT INTO
-  cart_items (cart_id, item_id, quantity, item_type, other_info, subscribe, delivery_every)
- VALUES (:cart_id, :item_id, :quantity, :item_type, :other_info, :subscribe, :delivery_every)
+  cart_items (cart_id, item_id, variant, quantity, item_type, other_info, subscribe, delivery_every)
+ VALUES (:cart_id, :item_id, :variant, :quantity, :item_type, :other_info, :subscribe, :delivery_every)
  ON CONFLICT DO NOTHING
  RETURNING id`
 
@@ -106,22 +108,21 @@ func (pg *PGClient) DeleteCartItem(id int) error {
 	}()
 
 	// Try to delete the cart item.
-	result, err := tx.Exec(qDeleteItem, id)
-	if err != nil {
-		return err
+	var cartID string
+	err = tx.Get(&cartID, qDeleteItem, id)
+	if err == sql.ErrNoRows {
+		return ErrCartItemNotFound //TODO: CHANGE ERROR
 	}
-	rows, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	if rows != 1 {
-		return ErrCartItemNotFound //TODO: CHANGE ERROR
-	}
+
+	err = touchCart(tx, cartID)
 	return err
 }
 
 const qDeleteItem = `
-DELETE FROM cart_items WHERE id = $1`
+DELETE FROM cart_items WHERE id = $1 RETURNING cart_id`
 
 
 // UpdateCartItem can update the cart item as long as the user is the owner of the cart or the cart has no owner
@@ -158,6 +159,12 @@ func (pg *PGClient) UpdateCartItem(item *model.CartItem) error {
 		return err
 	}
 
+	if err = touchCart(tx, item.CartID); err != nil {
+		return err
+	}
+	if check.CartID != item.CartID {
+		err = touchCart(tx, check.CartID)
+	}
 	return err
 }
 
@@ -168,3 +175,61 @@ UPDATE cart_items
      subscribe=:subscribe, delivery_every=:delivery_every
  WHERE id = :id`
 
+
+// MoveCartItems moves the items in one cart into another. Items
+// already in the destination cart have their quantities added
+// together. Either all the items are moved or none are.
+func (pg *PGClient) MoveCartItems(fromID, toID string) error {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	fromItems := []model.CartItem{}
+	if err = tx.Select(&fromItems, qCartItemBy+`cart_id = $1 ORDER BY id FOR UPDATE`, fromID); err != nil {
+		return err
+	}
+	toItems := []model.CartItem{}
+	if err = tx.Select(&toItems, qCartItemBy+`cart_id = $1 ORDER BY id FOR UPDATE`, toID); err != nil {
+		return err
+	}
+
+	for _, fromItem := range fromItems {
+		merged := false
+		for i := range toItems {
+			item := &toItems[i]
+			if fromItem.ItemID != item.ItemID || fromItem.Variant != item.Variant {
+				continue
+			}
+			item.Quantity += fromItem.Quantity
+			if _, err = tx.NamedExec(qUpdateCartItem, item); err != nil {
+				return err
+			}
+			if _, err = tx.Exec(qDeleteItem, fromItem.ID); err != nil {
+				return err
+			}
+			merged = true
+			break
+		}
+		if !merged {
+			fromItem.CartID = toID
+			if _, err = tx.NamedExec(qUpdateCartItem, fromItem); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = touchCart(tx, fromID); err != nil {
+		return err
+	}
+	err = touchCart(tx, toID)
+	return err
+}
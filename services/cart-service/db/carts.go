@@ -2,13 +2,15 @@ package db
 
 import (
 	"database/sql"
+	"time"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/cart-service/model"
 )
 
 const qCartBy = `
-SELECT id, cart_status, owner, created_at
+SELECT id, cart_status, owner, created_at, updated_at, abandoned_at, promo_code, currency
   FROM carts WHERE `
 
 // CartByID looks up a cart by its ID.
@@ -69,7 +71,7 @@ func (pg *PGClient) CreateCart(cart *model.Cart) (*model.Cart, error) {
 		return nil, err
 	}
 	if rows.Next() {
-		err = rows.Scan(&cart.CreatedAt)
+		err = rows.Scan(&cart.CreatedAt, &cart.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -80,10 +82,10 @@ func (pg *PGClient) CreateCart(cart *model.Cart) (*model.Cart, error) {
 
 const qCreateCart = `
 INSERT INTO
-  carts (id, cart_status, owner)
- VALUES (:id, :cart_status, :owner)
+  carts (id, cart_status, owner, currency)
+ VALUES (:id, :cart_status, :owner, :currency)
  ON CONFLICT DO NOTHING
- RETURNING created_at`
+ RETURNING created_at, updated_at`
 
 // UpdateCart updates the cart status and owner information in the database.
 func (pg *PGClient) UpdateCart(cart *model.Cart) error {
@@ -123,10 +125,12 @@ func (pg *PGClient) UpdateCart(cart *model.Cart) error {
 
 const qUpdateCart = `
 UPDATE carts
- SET cart_status=:cart_status, owner=:owner
+ SET cart_status=:cart_status, owner=:owner, promo_code=:promo_code, currency=:currency, updated_at=now(),
+     abandoned_at=CASE WHEN :cart_status = 'abandoned'
+                       THEN COALESCE(abandoned_at, now()) END
  WHERE id = :id`
 
-// UpdateCart updates the cart status and owner information in the database.
+// DeleteCart deletes a cart in the database if it is not owned by anyone.
 func (pg *PGClient) DeleteCart(cartId string) error {
 	tx, err := pg.DB.Beginx()
 	if err != nil {
@@ -165,3 +169,42 @@ func (pg *PGClient) DeleteCart(cartId string) error {
 }
 
 const qDeleteCart = `DELETE FROM carts WHERE id = $1 and cart_status = 'not logged in'`
+
+// AbandonIdleCarts marks active carts with items in them that haven't
+// changed since a given time as abandoned, returning the carts marked.
+func (pg *PGClient) AbandonIdleCarts(idleSince time.Time) ([]model.Cart, error) {
+	carts := []model.Cart{}
+	if err := pg.DB.Select(&carts, qAbandonIdleCarts, idleSince); err != nil {
+		return nil, err
+	}
+	return carts, nil
+}
+
+const qAbandonIdleCarts = `
+UPDATE carts
+   SET cart_status = 'abandoned', abandoned_at = now()
+ WHERE cart_status = 'active' AND updated_at < $1
+   AND EXISTS (SELECT 1 FROM cart_items WHERE cart_id = carts.id)
+RETURNING id, cart_status, owner, created_at, updated_at, abandoned_at, promo_code, currency`
+
+// PurgeAnonymousCarts deletes carts created by users who weren't
+// logged in that haven't changed since a given time, returning the
+// number of carts deleted.
+func (pg *PGClient) PurgeAnonymousCarts(idleSince time.Time) (int64, error) {
+	result, err := pg.DB.Exec(qPurgeAnonymousCarts, idleSince)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const qPurgeAnonymousCarts = `
+DELETE FROM carts WHERE cart_status = 'not logged in' AND updated_at < $1`
+
+// Record a change to a cart's items as activity on the cart.
+func touchCart(tx *sqlx.Tx, cartID string) error {
+	_, err := tx.Exec(qTouchCart, cartID)
+	return err
+}
+
+const qTouchCart = `UPDATE carts SET updated_at = now() WHERE id = $1`
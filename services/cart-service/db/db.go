@@ -2,6 +2,7 @@ package db
 
 import (
 	"errors"
+	"time"
 
 	"github.com/veganbase/backend/services/cart-service/model"
 )
@@ -12,6 +13,14 @@ var ErrCartNotFound = errors.New("cart ID not found")
 
 var ErrCartItemNotFound = errors.New("cart_item ID not found")
 
+// ErrPromotionNotFound is the error returned when an attempt is made
+// to access a promotion with an unknown ID or code.
+var ErrPromotionNotFound = errors.New("promotion not found")
+
+// ErrPromotionCodeExists is the error returned when an attempt is
+// made to create a promotion with a code that's already in use.
+var ErrPromotionCodeExists = errors.New("promotion code already exists")
+
 // DB describes the database operations used by the item service.
 type DB interface {
 	// Retrieval functions for individual carts: by ID or owner 
@@ -26,8 +35,16 @@ type DB interface {
 	// DeleteCart deletes a cart in the database if it is not owned by anyone.
 	DeleteCart(cartId string) error
 
-	// Retrieval functions for individual cart items: by ID or cartID and itemID
-	CartItemByCartIdAndItemId(cartId string, itemId string) (*model.CartItem, error)
+	// AbandonIdleCarts marks active carts with items in them that
+	// haven't changed since a given time as abandoned, returning the
+	// carts marked.
+	AbandonIdleCarts(idleSince time.Time) ([]model.Cart, error)
+	// PurgeAnonymousCarts deletes carts created by users who weren't
+	// logged in that haven't changed since a given time.
+	PurgeAnonymousCarts(idleSince time.Time) (int64, error)
+
+	// Retrieval functions for individual cart items: by ID or cartID, itemID and variant
+	CartItemByCartIdAndItemId(cartId string, itemId string, variant string) (*model.CartItem, error)
 	CartItemByCartIdAndCartItemId(cartId string, cItemId int) (*model.CartItem, error)
 	CartItemsByCartId(cartId string) (*[]model.CartItem, error)
 
@@ -37,7 +54,32 @@ type DB interface {
 	UpdateCartItem(item *model.CartItem) error
 	// UpdateCartItem updates a cart item.
 	DeleteCartItem(id int) error
+	// MoveCartItems moves the items in one cart into another, adding
+	// together the quantities of items that are in both.
+	MoveCartItems(fromID, toID string) error
+
+	// Retrieval functions for promotions: by ID, code or owner. A nil
+	// owner list returns all promotions.
+	PromotionByID(id string) (*model.Promotion, error)
+	PromotionByCode(code string) (*model.Promotion, error)
+	Promotions(owners []string) ([]model.Promotion, error)
+
+	// CreatePromotion creates a new promotion.
+	CreatePromotion(promo *model.Promotion) error
+	// UpdatePromotion updates the settings of a promotion.
+	UpdatePromotion(promo *model.Promotion) error
+	// DeletePromotion deletes a promotion.
+	DeletePromotion(id string) error
 
+	// UserRedemptions counts the number of times that a user has used a
+	// promotion.
+	UserRedemptions(promotionID string, userID string) (int, error)
+	// RedeemPromotion records the use of a promotion for a purchase,
+	// checking the promotion's usage limits.
+	RedeemPromotion(redemption *model.Redemption) error
+	// ReleaseRedemptions removes the promotion uses recorded for a
+	// purchase.
+	ReleaseRedemptions(reference string) error
 
 	// SaveEvent saves an event to the database.
 	SaveEvent(topic string, eventData interface{}, inTx func() error) error
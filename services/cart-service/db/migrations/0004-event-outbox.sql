@@ -0,0 +1,27 @@
+-- +migrate Up
+
+SET ROLE vb_carts;
+
+ALTER TABLE events
+  ADD COLUMN published_at    TIMESTAMPTZ,
+  ADD COLUMN attempts        INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN last_error      TEXT,
+  ADD COLUMN next_attempt_at TIMESTAMPTZ;
+
+-- Events recorded before the outbox relay existed were published
+-- when they were saved.
+UPDATE events SET published_at = timestamp;
+
+CREATE INDEX events_unpublished_idx ON events(id) WHERE published_at IS NULL;
+
+
+-- +migrate Down
+
+SET ROLE vb_carts;
+
+DROP INDEX events_unpublished_idx;
+ALTER TABLE events
+  DROP COLUMN published_at,
+  DROP COLUMN attempts,
+  DROP COLUMN last_error,
+  DROP COLUMN next_attempt_at;
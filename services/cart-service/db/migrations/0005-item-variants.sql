@@ -0,0 +1,19 @@
+-- +migrate Up
+
+SET ROLE vb_carts;
+
+-- Items with per-variant stock are added to carts by variant. Items
+-- without variants use the empty variant name.
+ALTER TABLE cart_items ADD COLUMN variant TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE cart_items DROP CONSTRAINT cart_items_cart_id_item_id_key;
+ALTER TABLE cart_items ADD UNIQUE (cart_id, item_id, variant);
+
+-- +migrate Down
+
+SET ROLE vb_carts;
+
+DELETE FROM cart_items WHERE variant <> '';
+ALTER TABLE cart_items DROP CONSTRAINT cart_items_cart_id_item_id_variant_key;
+ALTER TABLE cart_items ADD UNIQUE (cart_id, item_id);
+ALTER TABLE cart_items DROP COLUMN variant;
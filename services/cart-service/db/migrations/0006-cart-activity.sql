@@ -0,0 +1,27 @@
+-- +migrate Up
+
+SET ROLE vb_carts;
+
+-- Carts are touched whenever they or their items change, so that
+-- idle carts can be marked abandoned (and anonymous ones purged).
+ALTER TABLE carts
+  ADD COLUMN updated_at   TIMESTAMPTZ,
+  ADD COLUMN abandoned_at TIMESTAMPTZ;
+
+UPDATE carts SET updated_at = created_at;
+UPDATE carts SET abandoned_at = created_at WHERE cart_status = 'abandoned';
+
+ALTER TABLE carts
+  ALTER COLUMN updated_at SET NOT NULL,
+  ALTER COLUMN updated_at SET DEFAULT now();
+
+CREATE INDEX cart_activity_index ON carts(cart_status, updated_at);
+
+-- +migrate Down
+
+SET ROLE vb_carts;
+
+DROP INDEX cart_activity_index;
+ALTER TABLE carts
+  DROP COLUMN updated_at,
+  DROP COLUMN abandoned_at;
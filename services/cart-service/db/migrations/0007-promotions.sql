@@ -0,0 +1,61 @@
+-- +migrate Up
+
+SET ROLE vb_carts;
+
+CREATE TYPE promotion_kind AS ENUM
+  ('percentage', 'fixed_amount', 'free_delivery', 'buy_x_get_y');
+
+-- Discount codes. Promotions with an empty owner are platform
+-- promotions; others belong to the seller (user or organisation) who
+-- created them. Empty scope columns match anything.
+CREATE TABLE promotions (
+  id                 TEXT            PRIMARY KEY,
+  code               TEXT            NOT NULL UNIQUE,
+  owner              TEXT            NOT NULL DEFAULT '',
+  kind               promotion_kind  NOT NULL,
+  value              INTEGER         NOT NULL DEFAULT 0,
+  currency           TEXT            NOT NULL DEFAULT '',
+  buy_quantity       INTEGER         NOT NULL DEFAULT 0,
+  get_quantity       INTEGER         NOT NULL DEFAULT 0,
+  min_spend          INTEGER         NOT NULL DEFAULT 0,
+  site               TEXT            NOT NULL DEFAULT '',
+  seller             TEXT            NOT NULL DEFAULT '',
+  item_type          TEXT            NOT NULL DEFAULT '',
+  collection         TEXT            NOT NULL DEFAULT '',
+  max_uses           INTEGER,
+  max_uses_per_user  INTEGER,
+  uses               INTEGER         NOT NULL DEFAULT 0,
+  starts_at          TIMESTAMPTZ,
+  ends_at            TIMESTAMPTZ,
+  created_at         TIMESTAMPTZ     NOT NULL DEFAULT now(),
+  updated_at         TIMESTAMPTZ     NOT NULL DEFAULT now()
+);
+
+CREATE INDEX promotions_owner_index ON promotions(owner);
+
+-- Uses of promotion codes. The reference is the ID of the purchase
+-- that the code was used for.
+CREATE TABLE promotion_redemptions (
+  promotion_id  TEXT         NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+  reference     TEXT         NOT NULL,
+  user_id       TEXT         NOT NULL,
+  amount        INTEGER      NOT NULL,
+  currency      TEXT         NOT NULL,
+  created_at    TIMESTAMPTZ  NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (promotion_id, reference)
+);
+
+CREATE INDEX promotion_redemptions_user_index ON promotion_redemptions(promotion_id, user_id);
+CREATE INDEX promotion_redemptions_reference_index ON promotion_redemptions(reference);
+
+ALTER TABLE carts ADD COLUMN promo_code TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+
+SET ROLE vb_carts;
+
+ALTER TABLE carts DROP COLUMN promo_code;
+DROP TABLE promotion_redemptions;
+DROP TABLE promotions;
+DROP TYPE promotion_kind;
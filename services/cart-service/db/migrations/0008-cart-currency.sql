@@ -0,0 +1,11 @@
+-- +migrate Up
+
+SET ROLE vb_carts;
+
+ALTER TABLE carts ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+
+SET ROLE vb_carts;
+
+ALTER TABLE carts DROP COLUMN currency;
@@ -0,0 +1,233 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/cart-service/model"
+)
+
+const qPromotionBy = `
+SELECT id, code, owner, kind, value, currency, buy_quantity, get_quantity,
+       min_spend, site, seller, item_type, collection, max_uses,
+       max_uses_per_user, uses, starts_at, ends_at, created_at, updated_at
+  FROM promotions WHERE `
+
+// PromotionByID looks up a promotion by its ID.
+func (pg *PGClient) PromotionByID(id string) (*model.Promotion, error) {
+	return pg.promotionBy(`id = $1`, id)
+}
+
+// PromotionByCode looks up a promotion by its code.
+func (pg *PGClient) PromotionByCode(code string) (*model.Promotion, error) {
+	return pg.promotionBy(`code = $1`, model.NormaliseCode(code))
+}
+
+func (pg *PGClient) promotionBy(where string, arg string) (*model.Promotion, error) {
+	promo := &model.Promotion{}
+	if err := sqlx.Get(pg.DB, promo, qPromotionBy+where, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPromotionNotFound
+		}
+		return nil, err
+	}
+	return promo, nil
+}
+
+// Promotions lists the promotions belonging to a set of owners. If
+// the owner list is nil, all promotions are listed.
+func (pg *PGClient) Promotions(owners []string) ([]model.Promotion, error) {
+	promos := []model.Promotion{}
+	q := qPromotionBy + `true ORDER BY created_at DESC`
+	args := []interface{}{}
+	if owners != nil {
+		q = qPromotionBy + `owner = ANY($1) ORDER BY created_at DESC`
+		args = append(args, pq.Array(owners))
+	}
+	if err := pg.DB.Select(&promos, q, args...); err != nil {
+		return nil, err
+	}
+	return promos, nil
+}
+
+// CreatePromotion creates a new promotion.
+func (pg *PGClient) CreatePromotion(promo *model.Promotion) error {
+	promo.ID = chassis.NewID("prm")
+	rows, err := pg.DB.NamedQuery(qCreatePromotion, promo)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrPromotionCodeExists
+		}
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return rows.Scan(&promo.CreatedAt, &promo.UpdatedAt)
+	}
+	return nil
+}
+
+const qCreatePromotion = `
+INSERT INTO
+  promotions (id, code, owner, kind, value, currency, buy_quantity,
+              get_quantity, min_spend, site, seller, item_type, collection,
+              max_uses, max_uses_per_user, starts_at, ends_at)
+ VALUES (:id, :code, :owner, :kind, :value, :currency, :buy_quantity,
+         :get_quantity, :min_spend, :site, :seller, :item_type, :collection,
+         :max_uses, :max_uses_per_user, :starts_at, :ends_at)
+ RETURNING created_at, updated_at`
+
+// UpdatePromotion updates the settings of a promotion.
+func (pg *PGClient) UpdatePromotion(promo *model.Promotion) error {
+	rows, err := pg.DB.NamedQuery(qUpdatePromotion, promo)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrPromotionCodeExists
+		}
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return ErrPromotionNotFound
+	}
+	return rows.Scan(&promo.UpdatedAt)
+}
+
+const qUpdatePromotion = `
+UPDATE promotions
+   SET code=:code, kind=:kind, value=:value, currency=:currency,
+       buy_quantity=:buy_quantity, get_quantity=:get_quantity,
+       min_spend=:min_spend, site=:site, seller=:seller,
+       item_type=:item_type, collection=:collection, max_uses=:max_uses,
+       max_uses_per_user=:max_uses_per_user, starts_at=:starts_at,
+       ends_at=:ends_at, updated_at=now()
+ WHERE id = :id
+ RETURNING updated_at`
+
+// DeletePromotion deletes a promotion.
+func (pg *PGClient) DeletePromotion(id string) error {
+	result, err := pg.DB.Exec(`DELETE FROM promotions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		return ErrPromotionNotFound
+	}
+	return nil
+}
+
+// UserRedemptions counts the number of times that a user has used a
+// promotion.
+func (pg *PGClient) UserRedemptions(promotionID string, userID string) (int, error) {
+	count := 0
+	err := pg.DB.Get(&count, qUserRedemptions, promotionID, userID)
+	return count, err
+}
+
+const qUserRedemptions = `
+SELECT COUNT(*) FROM promotion_redemptions
+ WHERE promotion_id = $1 AND user_id = $2`
+
+// RedeemPromotion records the use of a promotion for a purchase,
+// checking the promotion's usage limits. Redeeming a promotion again
+// for the same purchase has no effect.
+func (pg *PGClient) RedeemPromotion(redemption *model.Redemption) error {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	promo := model.Promotion{}
+	err = tx.Get(&promo, qPromotionBy+`id = $1 FOR UPDATE`, redemption.PromotionID)
+	if err == sql.ErrNoRows {
+		err = ErrPromotionNotFound
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.Get(&exists, qRedemptionExists, redemption.PromotionID, redemption.Reference)
+	if err != nil || exists {
+		return err
+	}
+
+	if promo.MaxUses != nil && promo.Uses >= *promo.MaxUses {
+		err = model.ErrPromotionUsedUp
+		return err
+	}
+	if promo.MaxUsesPerUser != nil {
+		count := 0
+		err = tx.Get(&count, qUserRedemptions, redemption.PromotionID, redemption.UserID)
+		if err != nil {
+			return err
+		}
+		if count >= *promo.MaxUsesPerUser {
+			err = model.ErrPromotionUsedUp
+			return err
+		}
+	}
+
+	if _, err = tx.NamedExec(qCreateRedemption, redemption); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE promotions SET uses = uses + 1 WHERE id = $1`, redemption.PromotionID)
+	return err
+}
+
+const qRedemptionExists = `
+SELECT EXISTS (SELECT 1 FROM promotion_redemptions
+                WHERE promotion_id = $1 AND reference = $2)`
+
+const qCreateRedemption = `
+INSERT INTO
+  promotion_redemptions (promotion_id, reference, user_id, amount, currency)
+ VALUES (:promotion_id, :reference, :user_id, :amount, :currency)`
+
+// ReleaseRedemptions removes the promotion uses recorded for a
+// purchase, e.g. when payment for the purchase fails.
+func (pg *PGClient) ReleaseRedemptions(reference string) error {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	ids := []string{}
+	err = tx.Select(&ids, qReleaseRedemptions, reference)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		_, err = tx.Exec(`UPDATE promotions SET uses = GREATEST(uses - 1, 0) WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+const qReleaseRedemptions = `
+DELETE FROM promotion_redemptions WHERE reference = $1 RETURNING promotion_id`
@@ -3,6 +3,10 @@ package events
 // Event names used in item service.
 const (
 	CartChange = "cart-change"
+
+	// AbandonedCart events carry the contents of carts marked as
+	// abandoned, for the email service to send cart recovery emails.
+	AbandonedCart = "abandoned-cart"
 )
 // Cart event types for cart and cart item creation, update and deletion.
 const (
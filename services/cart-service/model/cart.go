@@ -5,6 +5,8 @@ import (
 	"github.com/pkg/errors"
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/cart-service/model/types"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -21,6 +23,38 @@ type Cart struct {
 
 	// Creation time of item.
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
+
+	// Time of the last change to the cart or its items.
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+
+	// Time at which the cart was abandoned, if it has been.
+	AbandonedAt *time.Time `db:"abandoned_at" json:"abandoned_at,omitempty"`
+
+	// Promotion code applied to the cart, if any.
+	PromoCode string `db:"promo_code" json:"promo_code,omitempty"`
+
+	// Currency that the buyer wants to see the cart total in, if any.
+	Currency string `db:"currency" json:"currency,omitempty"`
+}
+
+var currencyRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Normalise the cart's display currency and check that it's a
+// currency code.
+func (ct *Cart) checkCurrency() error {
+	ct.Currency = strings.ToUpper(ct.Currency)
+	if ct.Currency != "" && !currencyRegexp.MatchString(ct.Currency) {
+		return errors.New("invalid currency code '" + ct.Currency + "'")
+	}
+	return nil
+}
+
+// PrepareNew clears the fields of a new cart that can't be set when
+// it's created and checks the rest.
+func (ct *Cart) PrepareNew() error {
+	ct.PromoCode = ""
+	ct.AbandonedAt = nil
+	return ct.checkCurrency()
 }
 
 func (ct *Cart) Patch(body []byte) error {
@@ -33,8 +67,11 @@ func (ct *Cart) Patch(body []byte) error {
 
 	// Step 2 - verify if one of the fields that are present is read-only
 	roFields := map[string]string{
-		"id":         "id",
-		"created_at": "created_at",
+		"id":           "id",
+		"created_at":   "created_at",
+		"updated_at":   "updated_at",
+		"abandoned_at": "abandoned_at",
+		"promo_code":   "promo_code",
 	}
 	for fld, label := range roFields {
 		if _, ok := updates[fld]; ok {
@@ -46,11 +83,19 @@ func (ct *Cart) Patch(body []byte) error {
 	if err = chassis.StringField(&ct.Owner, updates, "owner"); err != nil {
 		return err
 	}
+	if err = chassis.StringField(&ct.Currency, updates, "currency"); err != nil {
+		return err
+	}
+	if err = ct.checkCurrency(); err != nil {
+		return err
+	}
 	var tempCartStatus string
 	if err = chassis.StringField(&tempCartStatus, updates, "cart_status"); err != nil {
 		return err
 	}
-	err = ct.CartStatus.FromString(tempCartStatus)
+	if tempCartStatus != "" {
+		err = ct.CartStatus.FromString(tempCartStatus)
+	}
 
 	return err
 }
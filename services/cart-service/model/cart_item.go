@@ -21,6 +21,7 @@ type CartItemFixed struct {
 	ID            int            `db:"id" json:"id"`
 	CartID        string         `db:"cart_id" json:"cart_id,omitempty"`
 	ItemID        string         `db:"item_id" json:"item_id"`
+	Variant       string         `db:"variant" json:"variant,omitempty"`
 	Quantity      int            `db:"quantity" json:"quantity"`
 	Subscribe     bool           `db:"subscribe" json:"subscribe"`
 	DeliveryEvery int            `db:"delivery_every" json:"delivery_every"`
@@ -39,6 +40,9 @@ func (ci *CartItem) UnmarshalJSON(data []byte) error {
 	chassis.IntField(&ci.ID, fields, "id")
 	chassis.StringField(&ci.CartID, fields, "cart_id")
 	chassis.StringField(&ci.ItemID, fields, "item_id")
+	if err = chassis.StringField(&ci.Variant, fields, "variant"); err != nil {
+		return err
+	}
 	if err = chassis.IntField(&ci.Quantity, fields, "quantity"); err != nil {
 		return err
 	}
@@ -148,6 +152,7 @@ func (ci *CartItem) Patch(body []byte, itemType model.ItemType) error {
 		"cart_id":   "cart_id",
 		"item_id":   "item_id",
 		"item_type": "item_type",
+		"variant":   "variant",
 	}
 
 	for fld, label := range roFields {
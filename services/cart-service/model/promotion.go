@@ -0,0 +1,366 @@
+package model
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/veganbase/backend/services/cart-service/model/types"
+	item "github.com/veganbase/backend/services/item-service/model"
+)
+
+// Errors returned when a promotion can't be applied to a cart.
+var (
+	ErrPromotionNotStarted = errors.New("promotion code is not valid yet")
+	ErrPromotionExpired    = errors.New("promotion code has expired")
+	ErrPromotionUsedUp     = errors.New("promotion code has been used the maximum number of times")
+	ErrPromotionWrongSite  = errors.New("promotion code can't be used on this site")
+	ErrMinSpendNotReached  = errors.New("cart total is below the minimum spend for the promotion code")
+	ErrNoEligibleItems     = errors.New("no items in the cart are eligible for the promotion code")
+)
+
+// Who pays for a discount: promotions created by sellers reduce the
+// seller's payout; promotions created by administrators are paid for
+// by the platform.
+const (
+	FundedBySeller   = "seller"
+	FundedByPlatform = "platform"
+)
+
+// Promotion is a discount code that can be applied to a cart. A
+// promotion with no owner is a platform promotion, created by an
+// administrator. Promotions can be restricted to a site, a seller, an
+// item type or an item collection; empty scope fields match anything.
+type Promotion struct {
+	ID    string              `db:"id" json:"id"`
+	Code  string              `db:"code" json:"code"`
+	Owner string              `db:"owner" json:"owner,omitempty"`
+	Kind  types.PromotionKind `db:"kind" json:"kind"`
+
+	// Percentage off for percentage promotions, or amount off in the
+	// smallest currency unit for fixed amount promotions.
+	Value       int    `db:"value" json:"value,omitempty"`
+	Currency    string `db:"currency" json:"currency,omitempty"`
+	BuyQuantity int    `db:"buy_quantity" json:"buy_quantity,omitempty"`
+	GetQuantity int    `db:"get_quantity" json:"get_quantity,omitempty"`
+	MinSpend    int    `db:"min_spend" json:"min_spend,omitempty"`
+
+	Site       string `db:"site" json:"site,omitempty"`
+	Seller     string `db:"seller" json:"seller,omitempty"`
+	ItemType   string `db:"item_type" json:"item_type,omitempty"`
+	Collection string `db:"collection" json:"collection,omitempty"`
+
+	MaxUses        *int       `db:"max_uses" json:"max_uses,omitempty"`
+	MaxUsesPerUser *int       `db:"max_uses_per_user" json:"max_uses_per_user,omitempty"`
+	Uses           int        `db:"uses" json:"uses"`
+	StartsAt       *time.Time `db:"starts_at" json:"starts_at,omitempty"`
+	EndsAt         *time.Time `db:"ends_at" json:"ends_at,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+var promoCodeRegexp = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// NormaliseCode converts a promotion code to the form used for
+// storage and lookup: codes are case-insensitive.
+func NormaliseCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks the settings of a promotion, normalising its code.
+func (p *Promotion) Validate() error {
+	p.Code = NormaliseCode(p.Code)
+	if !promoCodeRegexp.MatchString(p.Code) {
+		return errors.New("promotion code must be 3-32 letters, digits, '-' or '_'")
+	}
+	p.Currency = strings.ToUpper(p.Currency)
+
+	switch p.Kind {
+	case types.Percentage:
+		if p.Value <= 0 || p.Value > 100 {
+			return errors.New("percentage must be between 1 and 100")
+		}
+	case types.FixedAmount:
+		if p.Value <= 0 {
+			return errors.New("amount must be positive")
+		}
+		if p.Currency == "" {
+			return errors.New("fixed amount promotions need a currency")
+		}
+	case types.FreeDelivery:
+		p.Value = 0
+	case types.BuyXGetY:
+		if p.BuyQuantity <= 0 || p.GetQuantity <= 0 {
+			return errors.New("buy and get quantities must be positive")
+		}
+	}
+	if p.MinSpend < 0 {
+		return errors.New("minimum spend can't be negative")
+	}
+	if p.MinSpend > 0 && p.Currency == "" {
+		return errors.New("a minimum spend needs a currency")
+	}
+
+	if p.ItemType != "" {
+		var it item.ItemType
+		if err := it.FromString(p.ItemType); err != nil {
+			return err
+		}
+		if !orderItem(it) {
+			return errors.New("promotions only apply to products and dishes")
+		}
+	}
+	if p.MaxUses != nil && *p.MaxUses <= 0 {
+		return errors.New("maximum uses must be positive")
+	}
+	if p.MaxUsesPerUser != nil && *p.MaxUsesPerUser <= 0 {
+		return errors.New("maximum uses per user must be positive")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return errors.New("promotion must end after it starts")
+	}
+	return nil
+}
+
+// Patch applies a JSON patch to a promotion. The promotion should be
+// validated afterwards.
+func (p *Promotion) Patch(body []byte) error {
+	updates := map[string]interface{}{}
+	if err := json.Unmarshal(body, &updates); err != nil {
+		return errors.Wrap(err, "unmarshaling patch")
+	}
+
+	roFields := []string{"id", "owner", "uses", "created_at", "updated_at"}
+	for _, fld := range roFields {
+		if _, ok := updates[fld]; ok {
+			return errors.New("can't patch promotion " + fld)
+		}
+	}
+
+	// Apply the updates to the current JSON view of the promotion, so
+	// that fields can be cleared by setting them to null.
+	current, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	fields := map[string]interface{}{}
+	if err = json.Unmarshal(current, &fields); err != nil {
+		return err
+	}
+	for k, v := range updates {
+		if v == nil {
+			delete(fields, k)
+		} else {
+			fields[k] = v
+		}
+	}
+	patched, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	updated := Promotion{}
+	if err = json.Unmarshal(patched, &updated); err != nil {
+		return err
+	}
+	*p = updated
+	return nil
+}
+
+// Check whether a promotion can be used at a given time on a given
+// site. Usage limits are checked separately, since they depend on the
+// user.
+func (p *Promotion) Check(now time.Time, site string) error {
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return ErrPromotionNotStarted
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return ErrPromotionExpired
+	}
+	if p.MaxUses != nil && p.Uses >= *p.MaxUses {
+		return ErrPromotionUsedUp
+	}
+	if p.Site != "" && p.Site != site {
+		return ErrPromotionWrongSite
+	}
+	return nil
+}
+
+// FundedBy says who pays for the discounts given by a promotion.
+func (p *Promotion) FundedBy() string {
+	if p.Owner == "" {
+		return FundedByPlatform
+	}
+	return FundedBySeller
+}
+
+// PricedLine is a cart item with the information needed to work out
+// discounts for it.
+type PricedLine struct {
+	ItemID      string
+	ItemType    item.ItemType
+	Seller      string
+	Collections []string
+	Price       int
+	Currency    string
+	Quantity    int
+}
+
+// Discount is the amount taken off a seller's order by a promotion.
+type Discount struct {
+	PromotionID string `json:"promotion_id"`
+	Code        string `json:"code"`
+	Seller      string `json:"seller"`
+	Amount      int    `json:"amount"`
+	Currency    string `json:"currency"`
+	FundedBy    string `json:"funded_by"`
+}
+
+// Redemption is a use of a promotion for a purchase.
+type Redemption struct {
+	PromotionID string `db:"promotion_id" json:"promotion_id"`
+	Reference   string `db:"reference" json:"reference"`
+	UserID      string `db:"user_id" json:"user_id"`
+	Amount      int    `db:"amount" json:"amount"`
+	Currency    string `db:"currency" json:"currency"`
+}
+
+// Apply works out the discounts given by a promotion for a set of
+// cart lines and delivery fees, returning one discount for each
+// seller whose order is discounted. Only products and dishes (which
+// are sold in orders) are discounted.
+func (p *Promotion) Apply(lines []PricedLine, fees []DeliveryFee) ([]Discount, error) {
+	type key struct{ seller, currency string }
+	subtotals := map[key]int{}
+	eligible := []PricedLine{}
+	total := 0
+	for _, l := range lines {
+		if !p.applies(l) {
+			continue
+		}
+		eligible = append(eligible, l)
+		subtotals[key{l.Seller, l.Currency}] += l.Price * l.Quantity
+		total += l.Price * l.Quantity
+	}
+	if len(eligible) == 0 {
+		return nil, ErrNoEligibleItems
+	}
+	if total < p.MinSpend {
+		return nil, ErrMinSpendNotReached
+	}
+
+	// Sellers in a fixed order, so that rounding is repeatable.
+	keys := []key{}
+	for k := range subtotals {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].seller != keys[j].seller {
+			return keys[i].seller < keys[j].seller
+		}
+		return keys[i].currency < keys[j].currency
+	})
+
+	amounts := map[key]int{}
+	switch p.Kind {
+	case types.Percentage:
+		for _, k := range keys {
+			amounts[k] = subtotals[k] * p.Value / 100
+		}
+
+	case types.FixedAmount:
+		// The amount is shared between sellers in proportion to their
+		// subtotals, with any rounding remainder going to the seller with
+		// the largest subtotal.
+		off := p.Value
+		if off > total {
+			off = total
+		}
+		allocated := 0
+		largest := keys[0]
+		for _, k := range keys {
+			amounts[k] = off * subtotals[k] / total
+			allocated += amounts[k]
+			if subtotals[k] > subtotals[largest] {
+				largest = k
+			}
+		}
+		amounts[largest] += off - allocated
+		if amounts[largest] > subtotals[largest] {
+			amounts[largest] = subtotals[largest]
+		}
+
+	case types.FreeDelivery:
+		for _, fee := range fees {
+			k := key{fee.Seller, fee.Currency}
+			for _, sk := range keys {
+				if sk.seller == fee.Seller {
+					amounts[k] += fee.Price
+					break
+				}
+			}
+		}
+		keys = keys[:0]
+		for k := range amounts {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].seller < keys[j].seller })
+
+	case types.BuyXGetY:
+		for _, l := range eligible {
+			free := l.Quantity / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+			amounts[key{l.Seller, l.Currency}] += free * l.Price
+		}
+	}
+
+	discounts := []Discount{}
+	for _, k := range keys {
+		if amounts[k] <= 0 {
+			continue
+		}
+		discounts = append(discounts, Discount{
+			PromotionID: p.ID,
+			Code:        p.Code,
+			Seller:      k.seller,
+			Amount:      amounts[k],
+			Currency:    k.currency,
+			FundedBy:    p.FundedBy(),
+		})
+	}
+	if len(discounts) == 0 {
+		return nil, ErrNoEligibleItems
+	}
+	return discounts, nil
+}
+
+// Determine whether a promotion applies to a cart line.
+func (p *Promotion) applies(l PricedLine) bool {
+	if !orderItem(l.ItemType) {
+		return false
+	}
+	if p.Currency != "" && !strings.EqualFold(p.Currency, l.Currency) {
+		return false
+	}
+	if p.Seller != "" && p.Seller != l.Seller {
+		return false
+	}
+	if p.ItemType != "" && p.ItemType != l.ItemType.String() {
+		return false
+	}
+	if p.Collection != "" {
+		for _, c := range l.Collections {
+			if c == p.Collection {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Products and dishes are sold in orders; everything else is booked.
+func orderItem(it item.ItemType) bool {
+	return it == item.ProductOfferingItem || it == item.DishItem
+}
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/veganbase/backend/services/cart-service/model/types"
+	it "github.com/veganbase/backend/services/item-service/model"
+)
+
+var promoLines = []PricedLine{
+	{ItemID: "prd_a", ItemType: it.ProductOfferingItem, Seller: "usr_1", Price: 1000, Currency: "EUR", Quantity: 2,
+		Collections: []string{"summer"}},
+	{ItemID: "dsh_b", ItemType: it.DishItem, Seller: "usr_2", Price: 500, Currency: "EUR", Quantity: 4},
+	{ItemID: "off_c", ItemType: it.OfferItem, Seller: "usr_2", Price: 9000, Currency: "EUR", Quantity: 1},
+}
+
+var promoFees = []DeliveryFee{
+	{Seller: "usr_1", Price: 300, Currency: "EUR"},
+	{Seller: "usr_3", Price: 400, Currency: "EUR"},
+}
+
+func TestPromotionPercentage(t *testing.T) {
+	p := Promotion{ID: "prm_1", Code: "TEN", Kind: types.Percentage, Value: 10}
+	ds, err := p.Apply(promoLines, promoFees)
+	assert.Nil(t, err)
+	assert.Equal(t, []Discount{
+		{PromotionID: "prm_1", Code: "TEN", Seller: "usr_1", Amount: 200, Currency: "EUR", FundedBy: FundedByPlatform},
+		{PromotionID: "prm_1", Code: "TEN", Seller: "usr_2", Amount: 200, Currency: "EUR", FundedBy: FundedByPlatform},
+	}, ds)
+}
+
+func TestPromotionFixedAmount(t *testing.T) {
+	p := Promotion{Code: "FIVE", Owner: "usr_2", Kind: types.FixedAmount, Value: 1001, Currency: "EUR"}
+	ds, err := p.Apply(promoLines, promoFees)
+	assert.Nil(t, err)
+	assert.Len(t, ds, 2)
+	assert.Equal(t, 1001, ds[0].Amount+ds[1].Amount)
+	assert.Equal(t, FundedBySeller, ds[0].FundedBy)
+
+	p.Value = 100000
+	ds, err = p.Apply(promoLines, promoFees)
+	assert.Nil(t, err)
+	assert.Equal(t, 2000, ds[0].Amount)
+	assert.Equal(t, 2000, ds[1].Amount)
+}
+
+func TestPromotionFreeDelivery(t *testing.T) {
+	p := Promotion{Code: "SHIP", Kind: types.FreeDelivery}
+	ds, err := p.Apply(promoLines, promoFees)
+	assert.Nil(t, err)
+	assert.Len(t, ds, 1)
+	assert.Equal(t, "usr_1", ds[0].Seller)
+	assert.Equal(t, 300, ds[0].Amount)
+}
+
+func TestPromotionBuyXGetY(t *testing.T) {
+	p := Promotion{Code: "3FOR2", Kind: types.BuyXGetY, BuyQuantity: 2, GetQuantity: 1}
+	ds, err := p.Apply(promoLines, promoFees)
+	assert.Nil(t, err)
+	assert.Len(t, ds, 1)
+	assert.Equal(t, "usr_2", ds[0].Seller)
+	assert.Equal(t, 500, ds[0].Amount)
+}
+
+func TestPromotionScope(t *testing.T) {
+	p := Promotion{Code: "SUMMER", Kind: types.Percentage, Value: 50, Collection: "summer"}
+	ds, err := p.Apply(promoLines, promoFees)
+	assert.Nil(t, err)
+	assert.Len(t, ds, 1)
+	assert.Equal(t, 1000, ds[0].Amount)
+
+	p = Promotion{Code: "DISHES", Kind: types.Percentage, Value: 50, ItemType: "dish", Seller: "usr_1"}
+	_, err = p.Apply(promoLines, promoFees)
+	assert.Equal(t, ErrNoEligibleItems, err)
+
+	p = Promotion{Code: "BIG", Kind: types.Percentage, Value: 50, MinSpend: 5000, Currency: "EUR"}
+	_, err = p.Apply(promoLines, promoFees)
+	assert.Equal(t, ErrMinSpendNotReached, err)
+}
+
+func TestPromotionCheck(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	one := 1
+
+	p := Promotion{StartsAt: &future}
+	assert.Equal(t, ErrPromotionNotStarted, p.Check(now, ""))
+	p = Promotion{EndsAt: &past}
+	assert.Equal(t, ErrPromotionExpired, p.Check(now, ""))
+	p = Promotion{MaxUses: &one, Uses: 1}
+	assert.Equal(t, ErrPromotionUsedUp, p.Check(now, ""))
+	p = Promotion{Site: "ethical.id"}
+	assert.Equal(t, ErrPromotionWrongSite, p.Check(now, "other.site"))
+	assert.Nil(t, p.Check(now, "ethical.id"))
+}
+
+func TestPromotionValidate(t *testing.T) {
+	p := Promotion{Code: " summer-21 ", Kind: types.Percentage, Value: 20}
+	assert.Nil(t, p.Validate())
+	assert.Equal(t, "SUMMER-21", p.Code)
+
+	p = Promotion{Code: "X", Kind: types.Percentage, Value: 20}
+	assert.NotNil(t, p.Validate())
+	p = Promotion{Code: "FIXED", Kind: types.FixedAmount, Value: 500}
+	assert.NotNil(t, p.Validate())
+	p = Promotion{Code: "ROOMS", Kind: types.Percentage, Value: 20, ItemType: "room"}
+	assert.NotNil(t, p.Validate())
+}
+
+func TestPromotionPatch(t *testing.T) {
+	max := 10
+	p := Promotion{ID: "prm_1", Code: "TEN", Kind: types.Percentage, Value: 10, MaxUses: &max}
+	assert.Nil(t, p.Patch([]byte(`{"value": 15, "max_uses": null}`)))
+	assert.Equal(t, 15, p.Value)
+	assert.Nil(t, p.MaxUses)
+	assert.Equal(t, "prm_1", p.ID)
+	assert.NotNil(t, p.Patch([]byte(`{"uses": 0}`)))
+}
@@ -0,0 +1,99 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// PromotionKind is an enumerated type representing the way that a
+// promotion discounts the items it applies to.
+type PromotionKind uint
+
+const (
+	// Percentage promotions take a percentage off the price of items.
+	Percentage = iota
+
+	// FixedAmount promotions take a fixed amount off the total price of
+	// items.
+	FixedAmount
+
+	// FreeDelivery promotions waive the delivery fees of sellers.
+	FreeDelivery
+
+	// BuyXGetY promotions give away some units of an item for each
+	// number of units bought.
+	BuyXGetY
+)
+
+// String converts a promotion kind to its string representation.
+func (k PromotionKind) String() string {
+	switch k {
+	case Percentage:
+		return "percentage"
+	case FixedAmount:
+		return "fixed_amount"
+	case FreeDelivery:
+		return "free_delivery"
+	case BuyXGetY:
+		return "buy_x_get_y"
+	default:
+		return "<unknown promotion kind>"
+	}
+}
+
+// FromString does checked conversion from a string to a
+// PromotionKind.
+func (k *PromotionKind) FromString(s string) error {
+	switch s {
+	case "percentage":
+		*k = Percentage
+	case "fixed_amount":
+		*k = FixedAmount
+	case "free_delivery":
+		*k = FreeDelivery
+	case "buy_x_get_y":
+		*k = BuyXGetY
+	default:
+		return errors.New("unknown promotion kind '" + s + "'")
+	}
+	return nil
+}
+
+// MarshalJSON converts an internal promotion kind to JSON.
+func (k PromotionKind) MarshalJSON() ([]byte, error) {
+	s := k.String()
+	if s == "<unknown promotion kind>" {
+		return nil, errors.New("unknown promotion kind")
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON unmarshals a promotion kind from a JSON string.
+func (k *PromotionKind) UnmarshalJSON(d []byte) error {
+	var s string
+	if err := json.Unmarshal(d, &s); err != nil {
+		return errors.Wrap(err, "can't unmarshal promotion kind")
+	}
+	return k.FromString(s)
+}
+
+// Scan implements the sql.Scanner interface.
+func (k *PromotionKind) Scan(src interface{}) error {
+	var s string
+	switch src := src.(type) {
+	case string:
+		s = src
+	case []byte:
+		s = string(src)
+	default:
+		return errors.New("incompatible type for PromotionKind")
+	}
+	return k.FromString(s)
+}
+
+// Value implements the driver.Value interface.
+func (k PromotionKind) Value() (driver.Value, error) {
+	return k.String(), nil
+}
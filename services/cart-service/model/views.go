@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"github.com/pkg/errors"
 	"github.com/veganbase/backend/chassis"
+	site "github.com/veganbase/backend/services/site-service/model"
+	"strings"
 )
 
 type FullCart struct {
@@ -12,6 +14,52 @@ type FullCart struct {
 	Items        []CartItemFull `json:"items"`
 	DeliveryFees []DeliveryFee  `json:"delivery_fees"`
 	IsValid      bool           `json:"is_valid"`
+
+	// Discounts given by the cart's promotion code, or the reason that
+	// the code can't be used.
+	Discounts      []Discount `json:"discounts"`
+	PromoCodeError string     `json:"promo_code_error,omitempty"`
+
+	// Totals to pay in each currency used in the cart, and the overall
+	// total converted into the cart's display currency if it has one.
+	Totals         map[string]int  `json:"totals"`
+	ConvertedTotal *ConvertedTotal `json:"converted_total,omitempty"`
+}
+
+// ConvertedTotal is a cart total converted into a single currency,
+// along with the exchange rates used. Converted totals are only for
+// display: buyers are charged in the currencies of the items they buy
+// unless the purchase is settled in a single currency.
+type ConvertedTotal struct {
+	Currency string              `json:"currency"`
+	Amount   int                 `json:"amount"`
+	Rates    []site.ExchangeRate `json:"rates"`
+}
+
+// AddTotals works out the totals of a cart view from the prices of
+// its items, its delivery fees and its discounts, converting them to
+// the cart's display currency if possible.
+func (fc *FullCart) AddTotals(lines []PricedLine, rates *site.ExchangeRates) {
+	fc.Totals = map[string]int{}
+	fc.ConvertedTotal = nil
+	for _, l := range lines {
+		fc.Totals[strings.ToUpper(l.Currency)] += l.Price * l.Quantity
+	}
+	for _, fee := range fc.DeliveryFees {
+		fc.Totals[strings.ToUpper(fee.Currency)] += fee.Price
+	}
+	for _, d := range fc.Discounts {
+		fc.Totals[strings.ToUpper(d.Currency)] -= d.Amount
+	}
+
+	if fc.Currency == "" || rates == nil {
+		return
+	}
+	amount, used, err := rates.Convert(fc.Totals, fc.Currency)
+	if err != nil {
+		return
+	}
+	fc.ConvertedTotal = &ConvertedTotal{Currency: fc.Currency, Amount: amount, Rates: used}
 }
 
 func FullView(cart *Cart, items *[]CartItem, deliveryFee *[]DeliveryFee, errors map[int][]string) *FullCart {
@@ -33,6 +81,8 @@ func FullView(cart *Cart, items *[]CartItem, deliveryFee *[]DeliveryFee, errors
 	view.Items = fullCIs
 	view.DeliveryFees = *deliveryFee
 	view.IsValid = cartIsValid
+	view.Discounts = []Discount{}
+	view.Totals = map[string]int{}
 	return &view
 }
 
@@ -107,6 +157,7 @@ func (cif *CartItemFull) UnmarshalJSON(data []byte) error {
 	chassis.IntField(&cif.ID, fields, "id")
 	chassis.StringField(&cif.CartID, fields, "cart_id")
 	chassis.StringField(&cif.ItemID, fields, "item_id")
+	chassis.StringField(&cif.Variant, fields, "variant")
 	chassis.IntField(&cif.Quantity, fields, "quantity")
 	chassis.BoolField(&cif.Subscribe, fields, "subscribe")
 	chassis.IntField(&cif.DeliveryEvery, fields, "delivery_every")
@@ -145,6 +196,9 @@ func (fc *FullCart) UnmarshalJSON(data []byte) error {
 	chassis.TimeField(&fc.CreatedAt, fields, "created_at")
 	chassis.BoolField(&fc.IsValid, fields, "is_valid")
 	chassis.StringField(&fc.Owner, fields, "owner")
+	chassis.StringField(&fc.PromoCode, fields, "promo_code")
+	chassis.StringField(&fc.PromoCodeError, fields, "promo_code_error")
+	chassis.StringField(&fc.Currency, fields, "currency")
 
 	items := fields["items"]
 
@@ -167,6 +221,38 @@ func (fc *FullCart) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	fc.Discounts = []Discount{}
+	if discounts, ok := fields["discounts"]; ok && discounts != nil {
+		rawDiscounts, err := json.Marshal(discounts)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(rawDiscounts, &fc.Discounts); err != nil {
+			return err
+		}
+	}
+
+	fc.Totals = map[string]int{}
+	if totals, ok := fields["totals"]; ok && totals != nil {
+		rawTotals, err := json.Marshal(totals)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(rawTotals, &fc.Totals); err != nil {
+			return err
+		}
+	}
+	if converted, ok := fields["converted_total"]; ok && converted != nil {
+		rawConverted, err := json.Marshal(converted)
+		if err != nil {
+			return err
+		}
+		fc.ConvertedTotal = &ConvertedTotal{}
+		if err = json.Unmarshal(rawConverted, fc.ConvertedTotal); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
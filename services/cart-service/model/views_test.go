@@ -0,0 +1,29 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	site "github.com/veganbase/backend/services/site-service/model"
+)
+
+func TestAddTotals(t *testing.T) {
+	view := FullCart{
+		DeliveryFees: []DeliveryFee{{Seller: "usr_1", Price: 300, Currency: "EUR"}},
+		Discounts:    []Discount{{Seller: "usr_1", Amount: 200, Currency: "EUR"}},
+	}
+	view.AddTotals(promoLines, nil)
+	assert.Equal(t, map[string]int{"EUR": 13100}, view.Totals)
+	assert.Nil(t, view.ConvertedTotal)
+
+	rates := &site.ExchangeRates{Base: "EUR", Rates: map[string]float64{"EUR": 1, "GBP": 0.5}}
+	view.Currency = "GBP"
+	view.AddTotals(promoLines, rates)
+	assert.Equal(t, "GBP", view.ConvertedTotal.Currency)
+	assert.Equal(t, 6550, view.ConvertedTotal.Amount)
+	assert.Len(t, view.ConvertedTotal.Rates, 1)
+
+	view.Currency = "JPY"
+	view.AddTotals(promoLines, rates)
+	assert.Nil(t, view.ConvertedTotal)
+}
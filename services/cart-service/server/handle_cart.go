@@ -62,6 +62,11 @@ func (s *Server) cartSearch(w http.ResponseWriter, r *http.Request) (interface{}
 		}
 	}
 	view := model.FullView(cart, items, fees, *errs)
+	if cart.CartStatus == types.Active {
+		if err = s.addPrices(view, authInfo.UserID, requestSite(r)); err != nil {
+			return nil, err
+		}
+	}
 
 	//if the user is not logged in, only "not logged in" carts can be retrieved
 	//if the user is logged in, only carts owned by him can be retrieved
@@ -88,24 +93,7 @@ func (s *Server) getActiveCart(w http.ResponseWriter, r *http.Request) (interfac
 		return nil, err
 	}
 
-	items, err := s.db.CartItemsByCartId(activeCart.ID)
-	if err != nil {
-		return nil, err
-	}
-
-	errs, err := s.CheckInvalidItems(authInfo.UserID, *items)
-	if err != nil {
-		return nil, err
-	}
-
-	fees, err := s.CalculateDeliveryFees(*items)
-	if err != nil {
-		return nil, err
-	}
-
-	view := model.FullView(activeCart, items, fees, *errs)
-
-	return view, err
+	return s.cartView(activeCart, authInfo.UserID, requestSite(r))
 }
 
 func (s *Server) createCart(w http.ResponseWriter, r *http.Request) (interface{}, error) {
@@ -121,6 +109,9 @@ func (s *Server) createCart(w http.ResponseWriter, r *http.Request) (interface{}
 			return chassis.BadRequest(w, err.Error())
 		}
 	}
+	if err := req.PrepareNew(); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
 
 	//creating a cart without an user logged in
 	//the cart_id must be stored on a cookie
@@ -226,53 +217,61 @@ func (s *Server) mergeCarts(w http.ResponseWriter, r *http.Request) (interface{}
 	}
 
 	if activeCart != nil && activeCart.ID != tempCart.ID {
-
-		itemsOnTempCart, err := s.db.CartItemsByCartId(tempCart.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		//get items on active cart
-		itemsOnActiveCart, err := s.db.CartItemsByCartId(activeCart.ID)
-		if err != nil {
+		if err = s.db.MoveCartItems(tempCart.ID, activeCart.ID); err != nil {
 			return nil, err
 		}
-		//TODO: CHANGE TO DO THINS IN TRANSACTION
-		for _, tempItem := range *itemsOnTempCart {
-			isInBothCarts := false
-			for _, item := range *itemsOnActiveCart {
-				//if item is in both carts, update the quantity on active cart
-				if tempItem.ItemID == item.ItemID {
-					isInBothCarts = true
-					item.Quantity += tempItem.Quantity
-					_ = s.db.UpdateCartItem(&item)
-					continue
-				}
-			}
-			if !isInBothCarts {
-				tempItem.CartID = activeCart.ID
-				_ = s.db.UpdateCartItem(&tempItem)
-			}
-		}
 		chassis.Emit(s, events.CartMerged, tempCart)
 		_ = s.db.DeleteCart(tempCart.ID)
 	}
 
-	items, err := s.db.CartItemsByCartId(activeCart.ID)
+	return s.cartView(activeCart, authInfo.UserID, requestSite(r))
+}
+
+// Restore an abandoned cart, e.g. from the link in a cart recovery
+// email. Any items in the user's current active cart are moved into
+// the restored cart, which becomes the user's active cart.
+func (s *Server) restoreCart(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	cart, err := s.db.CartByID(chi.URLParam(r, "cart_id"))
 	if err != nil {
+		if err == db.ErrCartNotFound {
+			return chassis.NotFoundWithMessage(w, "cart not found")
+		}
 		return nil, err
 	}
+	if cart.Owner != authInfo.UserID {
+		return chassis.BadRequest(w, "the user is not the owner of the cart")
+	}
+	if cart.CartStatus != types.Abandoned {
+		return chassis.BadRequest(w, "only abandoned carts can be restored")
+	}
 
-	errs, err := s.CheckInvalidItems(authInfo.UserID, *items)
-	if err != nil {
+	activeCart, err := s.db.GetActiveCartByOwner(authInfo.UserID)
+	if err != nil && err != db.ErrCartNotFound {
 		return nil, err
 	}
+	if activeCart != nil {
+		if err = s.db.MoveCartItems(activeCart.ID, cart.ID); err != nil {
+			return nil, err
+		}
+		activeCart.CartStatus = types.Abandoned
+		if err = s.db.UpdateCart(activeCart); err != nil {
+			return nil, err
+		}
+		chassis.Emit(s, events.CartUpdated, activeCart)
+	}
 
-	fees, err := s.CalculateDeliveryFees(*items)
-	if err != nil {
+	cart.CartStatus = types.Active
+	if err = s.db.UpdateCart(cart); err != nil {
 		return nil, err
 	}
-	return model.FullView(activeCart, items, fees, *errs), nil
+	chassis.Emit(s, events.CartUpdated, cart)
+
+	return s.cartView(cart, authInfo.UserID, requestSite(r))
 }
 
 func (s *Server) patchCart(w http.ResponseWriter, r *http.Request) (interface{}, error) {
@@ -369,22 +368,7 @@ func (s *Server) internalActive(w http.ResponseWriter, r *http.Request) (interfa
 		return nil, err
 	}
 
-	items, err := s.db.CartItemsByCartId(activeCart.ID)
-	if err != nil {
-		return nil, err
-	}
-
-	errs, err := s.CheckInvalidItems(userId, *items)
-	if err != nil {
-		return nil, err
-	}
-
-	fees, err := s.CalculateDeliveryFees(*items)
-	if err != nil {
-		return nil, err
-	}
-
-	return model.FullView(activeCart, items, fees, *errs), err
+	return s.cartView(activeCart, userId, requestSite(r))
 }
 
 var ErrConvertingCart = fmt.Errorf("error converting cart status")
@@ -411,3 +395,41 @@ func (s *Server) getOrClaimCart(userID string, tempCart *model.Cart) (*model.Car
 
 	return activeCart, nil
 }
+
+// Build the full view of a cart, including item errors, delivery
+// fees, discounts and totals.
+func (s *Server) cartView(cart *model.Cart, userID, site string) (*model.FullCart, error) {
+	items, err := s.db.CartItemsByCartId(cart.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	errs, err := s.CheckInvalidItems(userID, *items)
+	if err != nil {
+		return nil, err
+	}
+
+	fees, err := s.CalculateDeliveryFees(*items)
+	if err != nil {
+		return nil, err
+	}
+
+	view := model.FullView(cart, items, fees, *errs)
+	if err = s.addPrices(view, userID, site); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// Work out the discounts and totals of a cart view.
+func (s *Server) addPrices(view *model.FullCart, userID, site string) error {
+	lines, err := s.pricedLines(view.Items)
+	if err != nil {
+		return err
+	}
+	if err = s.addDiscounts(view, lines, userID, site); err != nil {
+		return err
+	}
+	view.AddTotals(lines, s.siteSvc.ExchangeRates())
+	return nil
+}
@@ -122,6 +122,10 @@ func (s *Server) addCartItem(w http.ResponseWriter, r *http.Request) (interface{
 	if err != nil {
 		return chassis.BadRequest(w, err.Error())
 	}
+	stock, err := s.stockLevels(req.ItemID)
+	if err != nil {
+		return nil, err
+	}
 
 	//checking if the cart exists
 	cart, err := s.db.CartByID(cartId)
@@ -161,20 +165,21 @@ func (s *Server) addCartItem(w http.ResponseWriter, r *http.Request) (interface{
 	//while carts can only have one cart_item for each distinct product-offering, (additions will only increase quantity)
 	//every time other type of item is added to the cart, one cart_item will be created
 	if req.Type == itemModel.ProductOfferingItem || req.Type == itemModel.DishItem {
-		return s.handleProducts(w, req, itemInfo, authInfo.UserID)
+		return s.handleProducts(w, req, itemInfo, stock, authInfo.UserID)
 	} else {
-		return s.handleExperiences(w, req, itemInfo)
+		return s.handleExperiences(w, req, itemInfo, stock)
 	}
 
 }
 
-// handleProductOffering checks if the product is already in the cart. if positive, increase quantity.
-func (s *Server) handleProducts(w http.ResponseWriter, req *model.CartItem, itemInfo *itemModel.ItemFullWithLink, user string) (interface{}, error) {
-	item, err := s.db.CartItemByCartIdAndItemId(req.CartID, req.ItemID)
+// handleProductOffering checks if the product (variant) is already in the cart. if positive, increase quantity.
+func (s *Server) handleProducts(w http.ResponseWriter, req *model.CartItem, itemInfo *itemModel.ItemFullWithLink,
+	stock []itemModel.StockLevelView, user string) (interface{}, error) {
+	item, err := s.db.CartItemByCartIdAndItemId(req.CartID, req.ItemID, req.Variant)
 	if err != nil {
 		if err == db.ErrCartItemNotFound {
 			//insert a new item to the cart
-			if err = IsAvailableForSale(req.Quantity, *itemInfo); err != nil {
+			if err = IsAvailableForSale(req.Quantity, req.Variant, *itemInfo, stock); err != nil {
 				return chassis.BadRequest(w, "error adding item '"+itemInfo.Name+" to cart :"+err.Error())
 			}
 
@@ -199,7 +204,7 @@ func (s *Server) handleProducts(w http.ResponseWriter, req *model.CartItem, item
 		return nil, err
 	}
 
-	if err = IsAvailableForSale(item.Quantity, *itemInfo); err != nil {
+	if err = IsAvailableForSale(item.Quantity+req.Quantity, item.Variant, *itemInfo, stock); err != nil {
 		return chassis.BadRequest(w, "error adding item '"+itemInfo.Name+" to cart :"+err.Error())
 	}
 
@@ -216,7 +221,8 @@ func (s *Server) handleProducts(w http.ResponseWriter, req *model.CartItem, item
 
 //handleExperiences will add a cart_item without validate if that item is already in the cart
 //TODO: in the future may be necessary to check if it is duplicate
-func (s *Server) handleExperiences(w http.ResponseWriter, req *model.CartItem, itemInfo *itemModel.ItemFullWithLink) (interface{}, error) {
+func (s *Server) handleExperiences(w http.ResponseWriter, req *model.CartItem, itemInfo *itemModel.ItemFullWithLink,
+	stock []itemModel.StockLevelView) (interface{}, error) {
 	var err error
 
 	switch req.Type {
@@ -250,7 +256,7 @@ func (s *Server) handleExperiences(w http.ResponseWriter, req *model.CartItem, i
 	}
 
 	//checking if item is available
-	if err = IsAvailableForSale(req.Quantity, *itemInfo); err != nil {
+	if err = IsAvailableForSale(req.Quantity, req.Variant, *itemInfo, stock); err != nil {
 		return chassis.BadRequest(w, "error adding item '"+itemInfo.Name+" to cart :"+err.Error())
 	}
 	if err = s.db.CreateCartItem(req); err != nil {
@@ -404,7 +410,11 @@ func (s *Server) patchCartItem(w http.ResponseWriter, r *http.Request) (interfac
 	}
 
 	//check if desired quantity is available
-	if err = IsAvailableForSale(item.Quantity, *itemInfo); err != nil {
+	stock, err := s.stockLevels(item.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	if err = IsAvailableForSale(item.Quantity, item.Variant, *itemInfo, stock); err != nil {
 		return chassis.BadRequest(w, "error updating cart item '"+itemInfo.Name+" :"+err.Error())
 	}
 
@@ -0,0 +1,425 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/cart-service/db"
+	"github.com/veganbase/backend/services/cart-service/events"
+	"github.com/veganbase/backend/services/cart-service/model"
+	"github.com/veganbase/backend/services/cart-service/model/types"
+)
+
+// List the promotions that the user can manage: all promotions for
+// administrators, or those belonging to the user and their
+// organisations.
+func (s *Server) listPromotions(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.Forbidden(w)
+	}
+
+	var owners []string
+	if !authInfo.UserIsAdmin {
+		var err error
+		if owners, err = s.possibleOwners(authInfo.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return s.db.Promotions(owners)
+}
+
+// Create a promotion. Sellers create promotions for their own items,
+// which they pay for. Administrators can create platform promotions,
+// which the platform pays for, by leaving the owner empty.
+func (s *Server) createPromotion(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.Forbidden(w)
+	}
+
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	promo := model.Promotion{}
+	if err = json.Unmarshal(body, &promo); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	if !authInfo.UserIsAdmin {
+		if promo.Owner == "" {
+			promo.Owner = authInfo.UserID
+		}
+		owned, err := s.ownsPromotion(authInfo, &promo)
+		if err != nil {
+			return nil, err
+		}
+		if !owned {
+			return chassis.Forbidden(w)
+		}
+	}
+	promo.Uses = 0
+	if err = s.validatePromotion(&promo); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	if err = s.db.CreatePromotion(&promo); err != nil {
+		if err == db.ErrPromotionCodeExists {
+			return chassis.Conflict(w, err.Error())
+		}
+		return nil, err
+	}
+	return &promo, nil
+}
+
+// Look up a single promotion.
+func (s *Server) getPromotion(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	promo, rsp, err := s.managedPromotion(w, r)
+	if promo == nil {
+		return rsp, err
+	}
+	return promo, nil
+}
+
+// Update a promotion's settings.
+func (s *Server) patchPromotion(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	promo, rsp, err := s.managedPromotion(w, r)
+	if promo == nil {
+		return rsp, err
+	}
+
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if err = promo.Patch(body); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if err = s.validatePromotion(promo); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	if err = s.db.UpdatePromotion(promo); err != nil {
+		switch err {
+		case db.ErrPromotionNotFound:
+			return chassis.NotFoundWithMessage(w, err.Error())
+		case db.ErrPromotionCodeExists:
+			return chassis.Conflict(w, err.Error())
+		}
+		return nil, err
+	}
+	return promo, nil
+}
+
+// Delete a promotion. Carts that have its code applied show an error
+// for the code until it's removed.
+func (s *Server) deletePromotion(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	promo, rsp, err := s.managedPromotion(w, r)
+	if promo == nil {
+		return rsp, err
+	}
+
+	if err = s.db.DeletePromotion(promo.ID); err != nil {
+		if err == db.ErrPromotionNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	return chassis.NoContent(w)
+}
+
+// Apply a promotion code to a cart. Promotion codes can only be used
+// by logged-in users, since usage limits are per user.
+func (s *Server) applyPromoCode(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	req := struct {
+		Code string `json:"code"`
+	}{}
+	if err = json.Unmarshal(body, &req); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	cart, rsp, err := s.promoCodeCart(w, r, authInfo)
+	if cart == nil {
+		return rsp, err
+	}
+
+	promo, err := s.db.PromotionByCode(req.Code)
+	if err != nil {
+		if err == db.ErrPromotionNotFound {
+			return chassis.NotFoundWithMessage(w, "promotion code not found")
+		}
+		return nil, err
+	}
+	site := requestSite(r)
+	if err = promo.Check(time.Now(), site); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if used, err := s.promotionUsedUp(promo, authInfo.UserID); err != nil || used {
+		if err != nil {
+			return nil, err
+		}
+		return chassis.BadRequest(w, model.ErrPromotionUsedUp.Error())
+	}
+
+	cart.PromoCode = promo.Code
+	if err = s.db.UpdateCart(cart); err != nil {
+		return nil, err
+	}
+	chassis.Emit(s, events.CartUpdated, cart)
+
+	return s.cartView(cart, authInfo.UserID, site)
+}
+
+// Remove the promotion code from a cart.
+func (s *Server) removePromoCode(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	cart, rsp, err := s.promoCodeCart(w, r, authInfo)
+	if cart == nil {
+		return rsp, err
+	}
+
+	cart.PromoCode = ""
+	if err = s.db.UpdateCart(cart); err != nil {
+		return nil, err
+	}
+	chassis.Emit(s, events.CartUpdated, cart)
+
+	return s.cartView(cart, authInfo.UserID, requestSite(r))
+}
+
+// redeemPromotion is an internal path called by the purchase-service
+// to record the use of a promotion for a purchase.
+func (s *Server) redeemPromotion(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	redemption := model.Redemption{}
+	if err = json.Unmarshal(body, &redemption); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	redemption.PromotionID = chi.URLParam(r, "id")
+	if redemption.Reference == "" || redemption.UserID == "" {
+		return chassis.BadRequest(w, "missing redemption reference or user")
+	}
+
+	if err = s.db.RedeemPromotion(&redemption); err != nil {
+		switch err {
+		case db.ErrPromotionNotFound:
+			return chassis.NotFoundWithMessage(w, "promotion code not found")
+		case model.ErrPromotionUsedUp:
+			return chassis.Conflict(w, err.Error())
+		}
+		return nil, err
+	}
+	return chassis.NoContent(w)
+}
+
+// releasePromotions is an internal path called by the
+// purchase-service to remove the promotion uses recorded for a
+// purchase whose payment failed.
+func (s *Server) releasePromotions(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	if err := s.db.ReleaseRedemptions(chi.URLParam(r, "reference")); err != nil {
+		return nil, err
+	}
+	return chassis.NoContent(w)
+}
+
+// Look up the promotion named in a request's URL, checking that the
+// user can manage it. If the promotion is nil, the other return
+// values should be returned from the handler.
+func (s *Server) managedPromotion(w http.ResponseWriter, r *http.Request) (*model.Promotion, interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		rsp, err := chassis.Forbidden(w)
+		return nil, rsp, err
+	}
+
+	promo, err := s.db.PromotionByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if err == db.ErrPromotionNotFound {
+			rsp, err := chassis.NotFoundWithMessage(w, err.Error())
+			return nil, rsp, err
+		}
+		return nil, nil, err
+	}
+	if !authInfo.UserIsAdmin {
+		owned, err := s.ownsPromotion(authInfo, promo)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !owned {
+			rsp, err := chassis.Forbidden(w)
+			return nil, rsp, err
+		}
+	}
+	return promo, nil, nil
+}
+
+// Look up the cart named in a request's URL for applying or removing
+// a promotion code. If the cart is nil, the other return values
+// should be returned from the handler.
+func (s *Server) promoCodeCart(w http.ResponseWriter, r *http.Request,
+	authInfo *chassis.AuthInfo) (*model.Cart, interface{}, error) {
+	cart, err := s.db.CartByID(chi.URLParam(r, "cart_id"))
+	if err != nil {
+		if err == db.ErrCartNotFound {
+			rsp, err := chassis.NotFoundWithMessage(w, "cart not found")
+			return nil, rsp, err
+		}
+		return nil, nil, err
+	}
+	if cart.Owner != authInfo.UserID {
+		rsp, err := chassis.BadRequest(w, "the user is not the owner of the cart")
+		return nil, rsp, err
+	}
+	if cart.CartStatus != types.Active {
+		rsp, err := chassis.BadRequest(w, "promotion codes can only be used with active carts")
+		return nil, rsp, err
+	}
+	return cart, nil, nil
+}
+
+// Determine whether a user can manage a promotion. Only
+// administrators can manage platform promotions.
+func (s *Server) ownsPromotion(authInfo *chassis.AuthInfo, promo *model.Promotion) (bool, error) {
+	if promo.Owner == "" {
+		return false, nil
+	}
+	owners, err := s.possibleOwners(authInfo.UserID)
+	if err != nil {
+		return false, err
+	}
+	for _, owner := range owners {
+		if owner == promo.Owner {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Validate a promotion. Seller promotions only apply to the seller's
+// own items.
+func (s *Server) validatePromotion(promo *model.Promotion) error {
+	if promo.Owner != "" {
+		promo.Seller = promo.Owner
+	}
+	return promo.Validate()
+}
+
+// Determine whether a user has used a promotion as many times as
+// they're allowed to.
+func (s *Server) promotionUsedUp(promo *model.Promotion, userID string) (bool, error) {
+	if promo.MaxUsesPerUser == nil {
+		return false, nil
+	}
+	count, err := s.db.UserRedemptions(promo.ID, userID)
+	if err != nil {
+		return false, err
+	}
+	return count >= *promo.MaxUsesPerUser, nil
+}
+
+// Work out the discounts given by a cart's promotion code. Problems
+// with the code are reported in the cart view rather than as errors,
+// so that the rest of the cart can still be shown.
+func (s *Server) addDiscounts(view *model.FullCart, lines []model.PricedLine, userID, site string) error {
+	if view.PromoCode == "" {
+		return nil
+	}
+	promo, err := s.db.PromotionByCode(view.PromoCode)
+	if err != nil {
+		if err == db.ErrPromotionNotFound {
+			view.PromoCodeError = "promotion code no longer exists"
+			return nil
+		}
+		return err
+	}
+	if err = promo.Check(time.Now(), site); err != nil {
+		view.PromoCodeError = err.Error()
+		return nil
+	}
+	used, err := s.promotionUsedUp(promo, userID)
+	if err != nil {
+		return err
+	}
+	if used {
+		view.PromoCodeError = model.ErrPromotionUsedUp.Error()
+		return nil
+	}
+
+	if promo.Collection != "" {
+		if err = s.addCollections(lines); err != nil {
+			return err
+		}
+	}
+	discounts, err := promo.Apply(lines, view.DeliveryFees)
+	if err != nil {
+		view.PromoCodeError = err.Error()
+		return nil
+	}
+	view.Discounts = discounts
+	return nil
+}
+
+// Look up the collections that the items in a cart belong to, for
+// promotions restricted to a collection.
+func (s *Server) addCollections(lines []model.PricedLine) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	ids := []string{}
+	for _, l := range lines {
+		ids = append(ids, l.ItemID)
+	}
+	collections, err := s.itemSvc.ItemsCollections(ids)
+	if err != nil {
+		return err
+	}
+	for i := range lines {
+		lines[i].Collections = collections[lines[i].ItemID]
+	}
+	return nil
+}
+
+// The site that a request comes from, used to check promotions that
+// are restricted to a site. Internal requests give the site as a
+// query parameter.
+func requestSite(r *http.Request) string {
+	if site := r.URL.Query().Get("site"); site != "" {
+		return site
+	}
+	return r.Header.Get("Origin")
+}
+
+// Determine the owners that a user can act for: the user and the
+// organisations they belong to.
+func (s *Server) possibleOwners(userID string) ([]string, error) {
+	owners := []string{userID}
+	orgs, err := s.userSvc.OrgsForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for orgID := range orgs {
+		owners = append(owners, orgID)
+	}
+	return owners, nil
+}
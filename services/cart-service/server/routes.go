@@ -28,6 +28,9 @@ func (s *Server) routes() chi.Router {
 	r.Patch("/cart/{cart_id}", chassis.SimpleHandler(s.patchCart))
 	r.Put("/cart/forget", chassis.SimpleHandler(s.forgetCart))
 	r.Patch("/cart/{cart_id}/merge", chassis.SimpleHandler(s.mergeCarts))
+	r.Put("/cart/{cart_id}/restore", chassis.SimpleHandler(s.restoreCart))
+	r.Put("/cart/{cart_id}/promo-code", chassis.SimpleHandler(s.applyPromoCode))
+	r.Delete("/cart/{cart_id}/promo-code", chassis.SimpleHandler(s.removePromoCode))
 	//r.Delete("/cart/{cart_id}", chassis.SimpleHandler(s.deleteCart))
 
 	r.Get("/cart/{cart_id}/items", chassis.SimpleHandler(s.cartItemsSearch))
@@ -36,10 +39,18 @@ func (s *Server) routes() chi.Router {
 	r.Patch("/cart/{cart_id}/item/{citem_id}", chassis.SimpleHandler(s.patchCartItem))
 	r.Delete("/cart/{cart_id}/item/{citem_id}", chassis.SimpleHandler(s.deleteCartItem))
 
+	r.Get("/promotions", chassis.SimpleHandler(s.listPromotions))
+	r.Post("/promotions", chassis.SimpleHandler(s.createPromotion))
+	r.Get("/promotion/{id}", chassis.SimpleHandler(s.getPromotion))
+	r.Patch("/promotion/{id}", chassis.SimpleHandler(s.patchPromotion))
+	r.Delete("/promotion/{id}", chassis.SimpleHandler(s.deletePromotion))
+
 	// INTERNAL-ONLY ROUTES (I.E. ACCESSED ONLY BY OTHER SERVICES,
 	// EXPOSED VIA SERVICE CLIENT API).
 	r.Get("/internal/cart/{user_id}/active", chassis.SimpleHandler(s.internalActive))
 	r.Patch("/internal/cart/{cart_id}", chassis.SimpleHandler(s.internalPatchCart))
+	r.Post("/internal/promotion/{id}/redemptions", chassis.SimpleHandler(s.redeemPromotion))
+	r.Delete("/internal/promotion-redemptions/{reference}", chassis.SimpleHandler(s.releasePromotions))
 
 
 	return r
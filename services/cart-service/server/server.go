@@ -11,6 +11,7 @@ import (
 	"github.com/veganbase/backend/services/cart-service/db"
 	item "github.com/veganbase/backend/services/item-service/client"
 	search "github.com/veganbase/backend/services/search-service/client"
+	site "github.com/veganbase/backend/services/site-service/client"
 	user "github.com/veganbase/backend/services/user-service/client"
 )
 
@@ -21,7 +22,12 @@ type Server struct {
 	itemSvc      item.Client
 	userSvc      user.Client
 	searchSvc    search.Client
+	siteSvc      site.Client
 	imageBaseURL string
+
+	// Path of the page on the site that restores an abandoned cart,
+	// with "{cart_id}" standing for the cart's ID.
+	cartRestorePath string
 }
 
 // Config contains the configuration information needed to start
@@ -36,7 +42,20 @@ type Config struct {
 	ItemServiceURL   string `env:"ITEM_SERVICE_URL,default=http://item-service"`
 	UserServiceURL   string `env:"USER_SERVICE_URL,default=http://user-service"`
 	SearchServiceURL string `env:"SEARCH_SERVICE_URL,default=http://search-service"`
+	SiteServiceURL   string `env:"SITE_SERVICE_URL,default=http://site-service"`
 	ImageBaseURL     string `env:"IMAGE_BASE_URL"`
+
+	// Carts with items in them that are left idle for longer than
+	// CART_ABANDON_AFTER are marked as abandoned, and anonymous carts
+	// are deleted after ANONYMOUS_CART_TTL. The sweeper runs every
+	// CART_SWEEP_INTERVAL; setting this to zero disables it.
+	CartSweepInterval time.Duration `env:"CART_SWEEP_INTERVAL,default=1h"`
+	CartAbandonAfter  time.Duration `env:"CART_ABANDON_AFTER,default=72h"`
+	AnonymousCartTTL  time.Duration `env:"ANONYMOUS_CART_TTL,default=720h"`
+
+	// The link in abandoned cart emails goes to CART_RESTORE_PATH on
+	// the site, with "{cart_id}" replaced by the cart's ID.
+	CartRestorePath string `env:"CART_RESTORE_PATH,default=/cart/restore/{cart_id}"`
 }
 
 // NewServer creates the server structure for the user service.
@@ -47,7 +66,8 @@ func NewServer(cfg *Config) *Server {
 
 	// Common server initialisation.
 	s := &Server{
-		imageBaseURL: cfg.ImageBaseURL,
+		imageBaseURL:    cfg.ImageBaseURL,
+		cartRestorePath: cfg.CartRestorePath,
 	}
 	s.Init(cfg.AppName, cfg.Project, cfg.Port, cfg.Credentials, s.routes())
 
@@ -58,15 +78,22 @@ func NewServer(cfg *Config) *Server {
 
 	s.itemSvc = item.New(cfg.ItemServiceURL)
 	s.searchSvc = search.New(cfg.SearchServiceURL)
+	s.siteSvc = site.New(cfg.SiteServiceURL, s.PubSub, s.AppName)
 	// Connect to cart database.
 	timeout, _ := context.WithTimeout(context.Background(), time.Second*10)
-	s.db, err = db.NewPGClient(timeout, cfg.DBURL)
+	pg, err := db.NewPGClient(timeout, cfg.DBURL)
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't connect to user database")
 	}
+	s.db = pg
+	s.StartEventRelay(pg.DB)
 	// Load JSON validation schemas.
 	model.LoadSchemas()
 
+	if cfg.CartSweepInterval != 0 {
+		s.startSweeper(cfg.CartSweepInterval, cfg.CartAbandonAfter, cfg.AnonymousCartTTL)
+	}
+
 	return s
 }
 
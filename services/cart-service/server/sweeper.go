@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/cart-service/events"
+	"github.com/veganbase/backend/services/cart-service/model"
+	item "github.com/veganbase/backend/services/item-service/model"
+	usr "github.com/veganbase/backend/services/user-service/model"
+)
+
+// Start the cart sweeper, which periodically marks carts that have
+// been left idle as abandoned and deletes stale anonymous carts,
+// until the server exits.
+func (s *Server) startSweeper(interval, abandonAfter, anonymousTTL time.Duration) {
+	ctx, cancel := context.WithCancel(s.Ctx)
+	s.AddAtExit(cancel)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if abandonAfter != 0 {
+				s.abandonIdleCarts(time.Now().Add(-abandonAfter))
+			}
+
+			if anonymousTTL != 0 {
+				n, err := s.db.PurgeAnonymousCarts(time.Now().Add(-anonymousTTL))
+				if err != nil {
+					log.Error().Err(err).Msg("purging anonymous carts")
+				} else if n > 0 {
+					log.Info().Int64("count", n).Msg("purged anonymous carts")
+				}
+			}
+		}
+	}()
+}
+
+// Mark carts that haven't changed since the given time as abandoned
+// and send abandoned cart events for them.
+func (s *Server) abandonIdleCarts(idleSince time.Time) {
+	carts, err := s.db.AbandonIdleCarts(idleSince)
+	if err != nil {
+		log.Error().Err(err).Msg("marking idle carts as abandoned")
+		return
+	}
+	if len(carts) == 0 {
+		return
+	}
+	log.Info().Int("count", len(carts)).Msg("marked idle carts as abandoned")
+
+	owners := []string{}
+	for _, cart := range carts {
+		owners = append(owners, cart.Owner)
+	}
+	userInfo, err := s.userSvc.Info(owners)
+	if err != nil {
+		log.Error().Err(err).Msg("abandoned-cart: could not obtain users' information from user-service")
+		return
+	}
+
+	site, ok := s.siteSvc.Sites()[recoverySite]
+	if !ok {
+		log.Error().Str("site", recoverySite).
+			Msg("abandoned-cart: unknown site for recovery emails")
+	}
+
+	for i := range carts {
+		cart := &carts[i]
+		chassis.Emit(s, events.CartUpdated, cart)
+
+		owner, ok := userInfo[cart.Owner]
+		if site == nil || !ok || owner.Email == nil || *owner.Email == "" {
+			continue
+		}
+		items, err := s.db.CartItemsByCartId(cart.ID)
+		if err != nil {
+			log.Error().Err(err).Str("cart", cart.ID).
+				Msg("abandoned-cart: could not load cart items")
+			continue
+		}
+		ids := []string{}
+		for _, ci := range *items {
+			ids = append(ids, ci.ItemID)
+		}
+		itemsInfo, err := s.itemSvc.GetItemsInfo(ids)
+		if err != nil {
+			log.Error().Err(err).Str("cart", cart.ID).
+				Msg("abandoned-cart: could not obtain items information from item-service")
+			continue
+		}
+
+		notification := buildAbandonedCartMsg(cart, *items, itemsInfo, owner,
+			s.cartRestoreURL(site.URL, cart.ID))
+		if err = chassis.Emit(s, events.AbandonedCart, notification); err != nil {
+			log.Error().Err(err).Msg("abandoned-cart: could not send event")
+		}
+	}
+}
+
+// The site that abandoned cart recovery emails are sent from.
+const recoverySite = "ethical.id"
+
+// The link to the page on a site that restores an abandoned cart.
+func (s *Server) cartRestoreURL(siteURL, cartID string) string {
+	return strings.TrimSuffix(siteURL, "/") +
+		strings.ReplaceAll(s.cartRestorePath, "{cart_id}", url.PathEscape(cartID))
+}
+
+// Build the message used by the email service to send a recovery
+// email for an abandoned cart. The restore URL is the page on the
+// site that restores the cart, which makes it active again.
+func buildAbandonedCartMsg(cart *model.Cart, items []model.CartItem,
+	itemsInfo map[string]*item.Info, owner *usr.Info, restoreURL string) *chassis.GenericEmailMsg {
+	data := chassis.GenericMap{}
+	data["cart_id"] = cart.ID
+	data["restore_url"] = restoreURL
+	if owner.Name != nil {
+		data["customer_name"] = *owner.Name
+	}
+	data["qty_items"] = len(items)
+
+	cartItems := []chassis.GenericMap{}
+	for _, ci := range items {
+		it := chassis.GenericMap{}
+		it["item_id"] = ci.ItemID
+		if info, ok := itemsInfo[ci.ItemID]; ok {
+			it["name"] = info.Name
+			it["slug"] = info.Slug
+		}
+		if ci.Variant != "" {
+			it["variant"] = ci.Variant
+		}
+		it["quantity"] = strconv.Itoa(ci.Quantity)
+		cartItems = append(cartItems, it)
+	}
+	data["items"] = cartItems
+
+	return &chassis.GenericEmailMsg{
+		FixedFields: chassis.FixedFields{
+			Site:     recoverySite,
+			Language: "en",
+			Email:    *owner.Email,
+		},
+		Data: data,
+	}
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/cart-service/db"
+	"github.com/veganbase/backend/services/cart-service/events"
+	"github.com/veganbase/backend/services/cart-service/model"
+	"github.com/veganbase/backend/services/cart-service/model/types"
+	item "github.com/veganbase/backend/services/item-service/client"
+	it "github.com/veganbase/backend/services/item-service/model"
+	site "github.com/veganbase/backend/services/site-service/client"
+	site_mocks "github.com/veganbase/backend/services/site-service/mocks"
+	site_model "github.com/veganbase/backend/services/site-service/model"
+	user_mocks "github.com/veganbase/backend/services/user-service/mocks"
+	usr "github.com/veganbase/backend/services/user-service/model"
+)
+
+// cartDB keeps carts and their items in memory, recording the events
+// saved.
+type cartDB struct {
+	db.DB
+	carts  map[string]*model.Cart
+	items  map[string][]model.CartItem
+	idle   []model.Cart
+	moved  [][2]string
+	events map[string][]interface{}
+}
+
+func newCartDB(carts ...model.Cart) *cartDB {
+	d := &cartDB{
+		carts:  map[string]*model.Cart{},
+		items:  map[string][]model.CartItem{},
+		events: map[string][]interface{}{},
+	}
+	for i := range carts {
+		d.carts[carts[i].ID] = &carts[i]
+	}
+	return d
+}
+
+func (d *cartDB) AbandonIdleCarts(idleSince time.Time) ([]model.Cart, error) {
+	return d.idle, nil
+}
+
+func (d *cartDB) CartByID(cartID string) (*model.Cart, error) {
+	cart, ok := d.carts[cartID]
+	if !ok {
+		return nil, db.ErrCartNotFound
+	}
+	copy := *cart
+	return &copy, nil
+}
+
+func (d *cartDB) GetActiveCartByOwner(owner string) (*model.Cart, error) {
+	for _, cart := range d.carts {
+		if cart.Owner == owner && cart.CartStatus == types.Active {
+			copy := *cart
+			return &copy, nil
+		}
+	}
+	return nil, db.ErrCartNotFound
+}
+
+func (d *cartDB) UpdateCart(cart *model.Cart) error {
+	copy := *cart
+	d.carts[cart.ID] = &copy
+	return nil
+}
+
+func (d *cartDB) CartItemsByCartId(cartID string) (*[]model.CartItem, error) {
+	items := append([]model.CartItem{}, d.items[cartID]...)
+	return &items, nil
+}
+
+func (d *cartDB) MoveCartItems(fromID, toID string) error {
+	d.moved = append(d.moved, [2]string{fromID, toID})
+	return nil
+}
+
+func (d *cartDB) SaveEvent(topic string, eventData interface{}, inTx func() error) error {
+	d.events[topic] = append(d.events[topic], eventData)
+	return nil
+}
+
+// cartItems looks up items in memory.
+type cartItems struct {
+	item.Client
+	info map[string]*it.Info
+}
+
+func (c *cartItems) GetItemsInfo(ids []string) (map[string]*it.Info, error) {
+	return c.info, nil
+}
+
+func (c *cartItems) GetItems(ids []string, linkType string) (*[]it.ItemFullWithLink, error) {
+	return &[]it.ItemFullWithLink{}, nil
+}
+
+func (c *cartItems) StockLevels(ids []string) (map[string][]it.StockLevelView, error) {
+	return map[string][]it.StockLevelView{}, nil
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestCartRestoreURL(t *testing.T) {
+	s := &Server{cartRestorePath: "/cart/restore/{cart_id}"}
+	assert.Equal(t, "https://ethical.id/cart/restore/crt_1",
+		s.cartRestoreURL("https://ethical.id/", "crt_1"))
+
+	s.cartRestorePath = "/basket?restore={cart_id}"
+	assert.Equal(t, "https://ethical.id/basket?restore=crt_1",
+		s.cartRestoreURL("https://ethical.id", "crt_1"))
+}
+
+func TestAbandonIdleCarts(t *testing.T) {
+	d := newCartDB()
+	d.idle = []model.Cart{
+		{ID: "crt_1", Owner: "usr_A", CartStatus: types.Abandoned},
+		{ID: "crt_2", Owner: "usr_B", CartStatus: types.Abandoned},
+	}
+	d.items["crt_1"] = []model.CartItem{
+		{CartItemFixed: model.CartItemFixed{ID: 1, CartID: "crt_1", ItemID: "itm_1", Quantity: 2}},
+		{CartItemFixed: model.CartItemFixed{ID: 2, CartID: "crt_1", ItemID: "itm_2", Variant: "large", Quantity: 1}},
+	}
+	users := &user_mocks.Client{}
+	users.On("Info", []string{"usr_A", "usr_B"}).Return(map[string]*usr.Info{
+		"usr_A": {ID: "usr_A", Name: strPtr("Ann"), Email: strPtr("ann@example.com")},
+		// Users without an email address can't be sent a recovery email.
+		"usr_B": {ID: "usr_B", Name: strPtr("Bob")},
+	}, nil)
+	sites := &site_mocks.Client{}
+	sites.On("Sites").Return(site.SiteMap{
+		recoverySite: &site_model.Site{ID: recoverySite, URL: "https://ethical.id"},
+	})
+	s := &Server{
+		db:              d,
+		userSvc:         users,
+		siteSvc:         sites,
+		itemSvc:         &cartItems{info: map[string]*it.Info{"itm_1": {ID: "itm_1", Name: strPtr("Tofu")}}},
+		cartRestorePath: "/cart/restore/{cart_id}",
+	}
+
+	s.abandonIdleCarts(time.Now())
+	assert.Len(t, d.events[events.CartUpdated], 2)
+	if assert.Len(t, d.events[events.AbandonedCart], 1) {
+		msg := d.events[events.AbandonedCart][0].(*chassis.GenericEmailMsg)
+		assert.Equal(t, "ann@example.com", msg.Email)
+		assert.Equal(t, recoverySite, msg.Site)
+		assert.Equal(t, "crt_1", msg.Data["cart_id"])
+		assert.Equal(t, "https://ethical.id/cart/restore/crt_1", msg.Data["restore_url"])
+		assert.Equal(t, "Ann", msg.Data["customer_name"])
+		assert.Equal(t, 2, msg.Data["qty_items"])
+		items := msg.Data["items"].([]chassis.GenericMap)
+		if assert.Len(t, items, 2) {
+			assert.Equal(t, "Tofu", *items[0]["name"].(*string))
+			assert.Equal(t, "2", items[0]["quantity"])
+			assert.Equal(t, "large", items[1]["variant"])
+		}
+	}
+
+	// Without the site to link to, no recovery emails are sent.
+	d.events = map[string][]interface{}{}
+	sites.ExpectedCalls = nil
+	sites.On("Sites").Return(site.SiteMap{})
+	s.abandonIdleCarts(time.Now())
+	assert.Len(t, d.events[events.CartUpdated], 2)
+	assert.Empty(t, d.events[events.AbandonedCart])
+}
+
+func TestRestoreCart(t *testing.T) {
+	restore := func(s *Server, userID, cartID string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/cart/"+cartID+"/restore", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("cart_id", cartID)
+		ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+		ctx = chassis.NewAuthContext(ctx, &chassis.AuthInfo{
+			AuthMethod: chassis.SessionAuth,
+			UserID:     userID,
+		})
+		w := httptest.NewRecorder()
+		rsp, err := s.restoreCart(w, r.WithContext(ctx))
+		assert.Nil(t, err)
+		if rsp != nil {
+			json.NewEncoder(w).Encode(rsp)
+		}
+		return w
+	}
+
+	d := newCartDB(
+		model.Cart{ID: "crt_OLD", Owner: "usr_A", CartStatus: types.Abandoned},
+		model.Cart{ID: "crt_NEW", Owner: "usr_A", CartStatus: types.Active},
+		model.Cart{ID: "crt_DONE", Owner: "usr_A", CartStatus: types.Complete},
+	)
+	users := &user_mocks.Client{}
+	users.On("GetDefaultAddress", "usr_A").Return(nil, nil)
+	users.On("GetDeliveryFees", mock.Anything).Return(&map[string]usr.DeliveryFees{}, nil)
+	sites := &site_mocks.Client{}
+	sites.On("ExchangeRates").Return(nil)
+	s := &Server{db: d, userSvc: users, siteSvc: sites, itemSvc: &cartItems{}}
+
+	// Only the owner of an abandoned cart can restore it.
+	assert.Equal(t, http.StatusBadRequest, restore(s, "usr_B", "crt_OLD").Code)
+	assert.Equal(t, http.StatusBadRequest, restore(s, "usr_A", "crt_DONE").Code)
+	assert.Equal(t, http.StatusNotFound, restore(s, "usr_A", "crt_X").Code)
+	assert.Empty(t, d.moved)
+
+	// The items in the user's active cart are moved into the restored
+	// cart, which takes its place.
+	w := restore(s, "usr_A", "crt_OLD")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, [][2]string{{"crt_NEW", "crt_OLD"}}, d.moved)
+	assert.Equal(t, types.CartStatus(types.Active), d.carts["crt_OLD"].CartStatus)
+	assert.Equal(t, types.CartStatus(types.Abandoned), d.carts["crt_NEW"].CartStatus)
+	assert.Len(t, d.events[events.CartUpdated], 2)
+	view := model.FullCart{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &view))
+	assert.Equal(t, "crt_OLD", view.ID)
+}
@@ -17,21 +17,19 @@ var ErrInvalidItemType = errors.New("cannot be added to cart")
 var ErrOutOfStock = errors.New("out of stock")
 var ErrNotAvailable = errors.New("not for sale")
 var ErrCannotDeliver = errors.New("cannot be delivered to your location")
+var ErrUnknownVariant = errors.New("unknown variant")
 
-// isAvailable checks if the item is available and if it has enough available in stock
+// isAvailable checks if the item is available and if it has enough available in stock.
+// stock holds the item's stock levels from the item service's stock ledger: items
+// with no stock levels aren't stock-controlled.
 // TODO: check other types (like media) that can be added to a cart
-func IsAvailableForSale(quantity int, itemInfo it.ItemFullWithLink) error {
+func IsAvailableForSale(quantity int, variant string, itemInfo it.ItemFullWithLink, stock []it.StockLevelView) error {
 	switch itemInfo.ItemType {
 	default:
 		return ErrInvalidItemType
 	case it.ProductOfferingItem, it.DishItem:
 		//check if desired quantity is less than in stock availability
-		inStock := itemInfo.Attrs["available_quantity"]
-		if inStock != nil {
-			if quantity > int(inStock.(float64)) {
-				return ErrOutOfStock
-			}
-		}
+		return checkStock(quantity, variant, stock)
 
 	case it.OfferItem:
 		//first check if the offer is available
@@ -41,13 +39,10 @@ func IsAvailableForSale(quantity int, itemInfo it.ItemFullWithLink) error {
 		}
 		//if positive, check if desired quantity is less than in stock availability
 		if available.(bool) {
-			inStock := itemInfo.Attrs["available_quantity"]
-			if inStock != nil {
-				if quantity <= int(inStock.(float64)) {
-					return nil
-				}
+			if len(stock) == 0 {
+				return ErrOutOfStock
 			}
-			return ErrOutOfStock
+			return checkStock(quantity, variant, stock)
 		}
 	case it.RoomItem:
 		//check if the offer is available
@@ -62,20 +57,54 @@ func IsAvailableForSale(quantity int, itemInfo it.ItemFullWithLink) error {
 	return nil
 }
 
+// checkStock checks that enough of an item variant is available.
+func checkStock(quantity int, variant string, stock []it.StockLevelView) error {
+	if len(stock) == 0 {
+		if variant != "" {
+			return ErrUnknownVariant
+		}
+		return nil
+	}
+	for _, level := range stock {
+		if level.Variant == variant {
+			if quantity > level.Available {
+				return ErrOutOfStock
+			}
+			return nil
+		}
+	}
+	return ErrUnknownVariant
+}
+
+// stockLevels gets the stock levels of an item's variants.
+func (s *Server) stockLevels(itemID string) ([]it.StockLevelView, error) {
+	levels, err := s.itemSvc.StockLevels([]string{itemID})
+	if err != nil {
+		return nil, err
+	}
+	return levels[itemID], nil
+}
+
 func (s *Server) CheckInvalidItems(userID string, items []model.CartItem) (*map[int][]string, error) {
 	ids := []string{}
-	cartMap := map[string]model.CartItem{}
+	cartMap := map[string][]model.CartItem{}
 	cartErrors := map[int][]string{}
 
 	for _, i := range items {
-		ids = append(ids, i.ItemID)
-		cartMap[i.ItemID] = i
+		if _, ok := cartMap[i.ItemID]; !ok {
+			ids = append(ids, i.ItemID)
+		}
+		cartMap[i.ItemID] = append(cartMap[i.ItemID], i)
 	}
 
 	itemInfo, err := s.itemSvc.GetItems(ids, "")
 	if err != nil {
 		return nil, err
 	}
+	stock, err := s.itemSvc.StockLevels(ids)
+	if err != nil {
+		return nil, err
+	}
 
 	var addr *usr.Address
 	if userID != "" {
@@ -105,17 +134,19 @@ func (s *Server) CheckInvalidItems(userID string, items []model.CartItem) (*map[
 				}
 				//case negative, append a cart error to be shown on pre-checkout screen
 				if !*ok {
-					cartItemID := cartMap[info.ID].ID
-					cartErrors[cartItemID] = append(cartErrors[cartItemID],
-						fmt.Sprintf("item '%s' cannot be delivered to your location", info.ID))
+					for _, ci := range cartMap[info.ID] {
+						cartErrors[ci.ID] = append(cartErrors[ci.ID],
+							fmt.Sprintf("item '%s' cannot be delivered to your location", info.ID))
+					}
 				}
 
 			}
 		}
-		if err = IsAvailableForSale(cartMap[info.ID].Quantity, info); err != nil {
-			cartItemID := cartMap[info.ID].ID
-			cartErrors[cartItemID] = append(cartErrors[cartItemID],
-				fmt.Sprintf("item '%s' is not available for sale: %v", info.ID, err))
+		for _, ci := range cartMap[info.ID] {
+			if err = IsAvailableForSale(ci.Quantity, ci.Variant, info, stock[info.ID]); err != nil {
+				cartErrors[ci.ID] = append(cartErrors[ci.ID],
+					fmt.Sprintf("item '%s' is not available for sale: %v", info.ID, err))
+			}
 		}
 	}
 	return &cartErrors, nil
@@ -184,16 +215,16 @@ func (s *Server) CheckDeliveryRegion(latitude, longitude float64, itemInfo it.It
 //groups products (product offering and dishes) by seller and calculates their delivery fee
 func (s *Server) CalculateDeliveryFees(items []model.CartItem) (*[]model.DeliveryFee, error) {
 	sellersIds := map[string][]it.ItemFullWithLink{}
-	cartItems := map[string]model.CartItem{}
-	//get all item ids of products
+	quantities := map[string]int{}
+	//get all item ids of products, totalling quantities across variants
 	for _, i := range items {
 		if i.Type == it.ProductOfferingItem || i.Type == it.DishItem {
-			cartItems[i.ItemID] = i
+			quantities[i.ItemID] += i.Quantity
 		}
 	}
 
 	uniqueItemIds := []string{}
-	for k, _ := range cartItems {
+	for k, _ := range quantities {
 		uniqueItemIds = append(uniqueItemIds, k)
 	}
 	//TODO: unmarshall of FullItem is not complete
@@ -222,7 +253,7 @@ func (s *Server) CalculateDeliveryFees(items []model.CartItem) (*[]model.Deliver
 		var sum int
 		for _, i := range sellerItems {
 			price := i.Attrs["price"].(float64)
-			sum += int(price) * cartItems[i.ID].Quantity //* quantity
+			sum += int(price) * quantities[i.ID] //* quantity
 		}
 		if sum > v.FreeDeliveryAbove {
 			continue
@@ -236,3 +267,41 @@ func (s *Server) CalculateDeliveryFees(items []model.CartItem) (*[]model.Deliver
 	}
 	return &deliveryFees, nil
 }
+
+// Look up the prices and sellers of the items in a cart.
+func (s *Server) pricedLines(items []model.CartItemFull) ([]model.PricedLine, error) {
+	quantities := map[string]int{}
+	for _, i := range items {
+		quantities[i.ItemID] += i.Quantity
+	}
+	if len(quantities) == 0 {
+		return []model.PricedLine{}, nil
+	}
+	ids := []string{}
+	for id := range quantities {
+		ids = append(ids, id)
+	}
+
+	itemsInfo, err := s.itemSvc.GetItems(ids, "")
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []model.PricedLine{}
+	for _, info := range *itemsInfo {
+		price, _ := info.Attrs["price"].(float64)
+		currency, _ := info.Attrs["currency"].(string)
+		line := model.PricedLine{
+			ItemID:   info.ID,
+			ItemType: info.ItemType,
+			Price:    int(price),
+			Currency: currency,
+			Quantity: quantities[info.ID],
+		}
+		if info.Owner != nil {
+			line.Seller = info.Owner.ID
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	it "github.com/veganbase/backend/services/item-service/model"
+	"github.com/veganbase/backend/services/item-service/model/types"
+)
+
+func stockLevel(variant string, onHand, reserved int) it.StockLevelView {
+	return it.ViewStockLevel(it.StockLevel{
+		ItemID: "itm_A", Variant: variant, OnHand: onHand, Reserved: reserved,
+	})
+}
+
+func TestCheckStock(t *testing.T) {
+	stock := []it.StockLevelView{stockLevel("", 5, 2), stockLevel("large", 2, 2)}
+
+	var tests = []struct {
+		name     string
+		quantity int
+		variant  string
+		stock    []it.StockLevelView
+		err      error
+	}{
+		{"not stock-controlled", 100, "", nil, nil},
+		{"variant of item without stock", 1, "large", nil, ErrUnknownVariant},
+		{"all of what's available", 3, "", stock, nil},
+		{"more than is available", 4, "", stock, ErrOutOfStock},
+		{"variant all reserved", 1, "large", stock, ErrOutOfStock},
+		{"unknown variant", 1, "small", stock, ErrUnknownVariant},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.err, checkStock(test.quantity, test.variant, test.stock), test.name)
+	}
+}
+
+func TestIsAvailableForSale(t *testing.T) {
+	item := func(itemType it.ItemType, attrs types.AttrMap) it.ItemFullWithLink {
+		i := it.ItemFullWithLink{Attrs: attrs}
+		i.ItemType = itemType
+		return i
+	}
+	stock := []it.StockLevelView{stockLevel("", 3, 0)}
+
+	var tests = []struct {
+		name  string
+		item  it.ItemFullWithLink
+		stock []it.StockLevelView
+		err   error
+	}{
+		{"product in stock", item(it.ProductOfferingItem, types.AttrMap{}), stock, nil},
+		{"product out of stock", item(it.DishItem, types.AttrMap{}), []it.StockLevelView{stockLevel("", 3, 3)}, ErrOutOfStock},
+		{"available offer", item(it.OfferItem, types.AttrMap{"is_available": true}), stock, nil},
+		{"available offer without stock", item(it.OfferItem, types.AttrMap{"is_available": true}), nil, ErrOutOfStock},
+		{"unavailable offer", item(it.OfferItem, types.AttrMap{}), stock, ErrNotAvailable},
+		{"post", item(it.PostItem, types.AttrMap{}), nil, ErrInvalidItemType},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.err, IsAvailableForSale(2, "", test.item, test.stock), test.name)
+	}
+}
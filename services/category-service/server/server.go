@@ -39,10 +39,12 @@ func NewServer(cfg *Config) *Server {
 	// Connect to category database.
 	timeout, _ := context.WithTimeout(context.Background(), time.Second*10)
 	var err error
-	s.db, err = db.NewPGClient(timeout, cfg.DBURL)
+	pg, err := db.NewPGClient(timeout, cfg.DBURL)
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't connect to category database")
 	}
+	s.db = pg
+	s.StartEventRelay(pg.DB)
 
 	return s
 }
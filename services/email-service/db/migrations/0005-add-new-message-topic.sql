@@ -0,0 +1,12 @@
+-- +migrate Up
+
+SET ROLE vb_email;
+
+INSERT INTO topics (name, send_address, created_at) VALUES
+    ('new-message', 'hello', now());
+
+
+-- +migrate Down
+
+SET ROLE vb_email;
+DELETE FROM topics WHERE name = 'new-message';
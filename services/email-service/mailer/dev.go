@@ -17,6 +17,13 @@ func (m *DevMailer) Send(topic *model.TopicInfo, site *site_model.Site,
 	language string, data map[string]interface{}) error {
 	fmt.Println("====> EMAIL SEND")
 	fmt.Println("  topic =", topic.Name, "   site =", site, "   language =", language)
+	attachments, err := takeAttachments(data)
+	if err != nil {
+		return err
+	}
+	for _, att := range attachments {
+		fmt.Println("  attachment =", att.Filename, "("+att.ContentType+")")
+	}
 	for k, v := range data {
 		fmt.Println(" ", k, "=", v)
 	}
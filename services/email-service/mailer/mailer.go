@@ -1,8 +1,11 @@
 package mailer
 
 import (
+	"encoding/json"
 	"errors"
 
+	"github.com/veganbase/backend/chassis"
+
 	"github.com/veganbase/backend/services/email-service/model"
 	site_model "github.com/veganbase/backend/services/site-service/model"
 )
@@ -16,3 +19,22 @@ type Mailer interface {
 	Send(topic *model.TopicInfo, site *site_model.Site,
 		language string, data map[string]interface{}) error
 }
+
+// takeAttachments removes any file attachments from the variables for
+// an email, so that they aren't passed to the email template.
+func takeAttachments(data map[string]interface{}) ([]chassis.EmailAttachment, error) {
+	raw, ok := data["attachments"]
+	if !ok {
+		return nil, nil
+	}
+	delete(data, "attachments")
+	j, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	atts := []chassis.EmailAttachment{}
+	if err = json.Unmarshal(j, &atts); err != nil {
+		return nil, err
+	}
+	return atts, nil
+}
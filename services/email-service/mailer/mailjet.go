@@ -173,6 +173,11 @@ func (m *MailjetMailer) Send(topic *model.TopicInfo, site *site_model.Site,
 		emailDomain = "veganlogin.com"
 	}
 
+	attachments, err := takeAttachments(data)
+	if err != nil {
+		return err
+	}
+
 	// Build mail information and send mail.
 	sendAddress := topic.SendAddress
 	if sendAddress == "" {
@@ -193,6 +198,13 @@ func (m *MailjetMailer) Send(topic *model.TopicInfo, site *site_model.Site,
 		MjTemplateErrorDeliver: "true",
 		Vars:               data,
 	}
+	for _, att := range attachments {
+		info.Attachments = append(info.Attachments, mailjet.Attachment{
+			ContentType: att.ContentType,
+			Content:     att.Content,
+			Filename:    att.Filename,
+		})
+	}
 	res, err := m.mj.SendMail(info)
 	if err != nil {
 		return err
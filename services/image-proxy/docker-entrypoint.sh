@@ -1,6 +1,12 @@
 #!/usr/bin/env sh
 set -eu
 
-envsubst '${SERVER_NAME} ${IMAGE_BUCKET}' < /etc/nginx/conf.d/default.conf.template > /etc/nginx/conf.d/default.conf
+# Image data comes from a Google Cloud Storage bucket unless another
+# backend (e.g. an S3-compatible bucket, or the blob service's
+# /content route) is given.
+export IMAGE_BACKEND="${IMAGE_BACKEND:-storage.googleapis.com/${IMAGE_BUCKET:-}}"
+export RESOLVER="${RESOLVER:-8.8.8.8}"
+
+envsubst '${SERVER_NAME} ${IMAGE_BACKEND} ${RESOLVER}' < /etc/nginx/conf.d/default.conf.template > /etc/nginx/conf.d/default.conf
 
 exec "$@"
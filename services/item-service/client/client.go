@@ -21,6 +21,17 @@ type SearchInfo struct {
 	Description string              `json:"description"`
 	Content     string              `json:"content,omitempty"`
 	Tags        []string            `json:"tags"`
+	InStock     bool                `json:"in_stock"`
+}
+
+// StockError is the error returned when stock can't be reserved or
+// committed, carrying the item service's explanation.
+type StockError struct {
+	Message string
+}
+
+func (e *StockError) Error() string {
+	return e.Message
 }
 
 
@@ -30,7 +41,12 @@ type Client interface {
 	SearchInfo(id string) (*SearchInfo, error)
 	ItemInfo(id string) (*model.Item, error)
 	ItemFullWithLink(id, linkType string) (*model.ItemFullWithLink, error)
-	UpdateItemAvailability(itemId string, quantity int)  error
+	StockLevels(ids []string) (map[string][]model.StockLevelView, error)
+	ReserveStock(req *model.ReservationRequest) (*model.StockReservation, error)
+	CommitStock(reference string) error
+	ReleaseStock(reference string) error
 	GetItems(ids []string, linkType string) (*[]model.ItemFullWithLink, error)
 	GetItemsInfo(ids []string) (map[string]*model.Info, error)
+	ItemsCollections(ids []string) (map[string][]string, error)
+	CollectionInfo(name string) (*model.ItemCollectionInfo, error)
 }
@@ -8,7 +8,7 @@ import (
 	"github.com/veganbase/backend/services/item-service/model"
 	"io/ioutil"
 	"net/http"
-	"strconv"
+	"net/url"
 	"strings"
 )
 
@@ -190,21 +190,162 @@ func (c *RESTClient) GetItemsInfo(ids []string) (map[string]*model.Info, error)
 	return resp, nil
 }
 
-func (c *RESTClient) UpdateItemAvailability(itemId string, quantity int) error {
+// StockLevels invokes the stock levels method on the item service.
+func (c *RESTClient) StockLevels(ids []string) (map[string][]model.StockLevelView, error) {
+	if len(ids) == 0 {
+		return map[string][]model.StockLevelView{}, nil
+	}
+
+	// Do GET to endpoint.
+	rsp, err := http.Get(c.baseURL + "/internal/stock?ids=" + strings.Join(ids, ","))
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, errors.New("getting stock levels failed")
+	}
+
+	// Decode response.
+	resp := map[string][]model.StockLevelView{}
+	rspBody, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(rspBody, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ReserveStock invokes the stock reservation method on the item
+// service. If the stock can't be reserved, the error is a
+// *StockError.
+func (c *RESTClient) ReserveStock(req *model.ReservationRequest) (*model.StockReservation, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
 
-	// Do PATCH to endpoint.
-	jsonBytes := []byte(`{"quantity":` + strconv.Itoa(quantity) + `}`)
-	req, err := http.NewRequest(http.MethodPatch, c.baseURL+"/internal/item/"+itemId, bytes.NewBuffer(jsonBytes))
+	// Do POST to endpoint.
+	rsp, err := http.Post(c.baseURL+"/internal/stock/reservations",
+		"application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	rspBody, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err = stockError(rsp.StatusCode, rspBody); err != nil {
+		return nil, err
+	}
 
-	client := &http.Client{}
-	rsp, err := client.Do(req)
+	// Decode response.
+	res := model.StockReservation{}
+	if err = json.Unmarshal(rspBody, &res); err != nil {
+		return nil, err
+	}
 
+	return &res, nil
+}
+
+// CommitStock invokes the reservation commit method on the item
+// service for the reservation made for a purchase.
+func (c *RESTClient) CommitStock(reference string) error {
+	return c.reservationAction(reference, "commit")
+}
+
+// ReleaseStock invokes the reservation release method on the item
+// service for the reservation made for a purchase.
+func (c *RESTClient) ReleaseStock(reference string) error {
+	return c.reservationAction(reference, "release")
+}
+
+func (c *RESTClient) reservationAction(reference, action string) error {
+	// Do POST to endpoint.
+	rsp, err := http.Post(c.baseURL+"/internal/stock/reservation/"+reference+"/"+action,
+		"application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	rspBody, err := ioutil.ReadAll(rsp.Body)
 	if err != nil {
 		return err
 	}
-	if rsp.StatusCode == http.StatusOK {
+	return stockError(rsp.StatusCode, rspBody)
+}
+
+// Convert an error response from a stock method into an error.
+func stockError(status int, body []byte) error {
+	switch status {
+	case http.StatusOK:
 		return nil
+	case http.StatusConflict, http.StatusBadRequest, http.StatusNotFound:
+		rsp := chassis.ErrResp{}
+		if err := json.Unmarshal(body, &rsp); err != nil || rsp.Message == "" {
+			return errors.New("item service stock request failed")
+		}
+		return &StockError{rsp.Message}
+	}
+	return errors.New("item service stock request failed")
+}
+
+// ItemsCollections returns the names of the collections that each of
+// a list of items belongs to, keyed by item ID.
+func (c *RESTClient) ItemsCollections(ids []string) (map[string][]string, error) {
+	if len(ids) == 0 {
+		return map[string][]string{}, nil
+	}
+
+	// Do GET to endpoint.
+	rsp, err := http.Get(c.baseURL + "/internal/collections?ids=" + strings.Join(ids, ","))
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, errors.New("getting item collections failed")
+	}
+
+	// Decode response.
+	resp := map[string][]string{}
+	rspBody, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
 	}
-	return errors.New("update item availability failed")
+	if err = json.Unmarshal(rspBody, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
 
+// CollectionInfo invokes the item collection detail method on the item
+// service.
+func (c *RESTClient) CollectionInfo(name string) (*model.ItemCollectionInfo, error) {
+	// Do GET to endpoint.
+	rsp, err := http.Get(c.baseURL + "/item-collection/" + url.PathEscape(name))
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, chassis.BuildErrorFromErrMsg(rsp)
+	}
+
+	// Decode response.
+	resp := model.ItemCollectionInfo{}
+	rspBody, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(rspBody, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
 }
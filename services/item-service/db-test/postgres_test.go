@@ -19,6 +19,8 @@ import (
 
 var pg *db.PGClient
 
+var editor = &db.RevisionInfo{Action: model.RevisionUpdate, Author: "usr_TESTEDITOR"}
+
 func init() {
 	pgdsn := test_utils.InitTestDB(false)
 	var err error
@@ -160,7 +162,7 @@ func TestUpdateItem(t *testing.T) {
 		item, err := pg.ItemByID("htl_EEOBepsfpJKH0EPW")
 		assert.Nil(t, err)
 		item.Slug = "bad-slug"
-		err = pg.UpdateItem(item, []string{})
+		err = pg.UpdateItem(item, []string{}, editor)
 		assert.Equal(t, err, db.ErrReadOnlyField)
 
 		item, err = pg.ItemByID("htl_EEOBepsfpJKH0EPW")
@@ -170,7 +172,7 @@ func TestUpdateItem(t *testing.T) {
 
 		item.Tags = append(item.Tags, "test-tag")
 		item.Name = "Check Name Change"
-		err = pg.UpdateItem(item, []string{})
+		err = pg.UpdateItem(item, []string{}, editor)
 		assert.Nil(t, err)
 
 		item, err = pg.ItemByID("htl_EEOBepsfpJKH0EPW")
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/veganbase/backend/services/item-service/db"
+	"github.com/veganbase/backend/services/item-service/model"
+	"github.com/veganbase/backend/services/item-service/model/types"
+)
+
+const historyItem = "htl_EEOBepsfpJKH0EPW"
+
+func revisions(t *testing.T, pg *db.PGClient) []model.ItemRevision {
+	revs, total, err := pg.ItemRevisions(historyItem, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(len(revs)), *total)
+	return revs
+}
+
+func TestItemRevisions(t *testing.T) {
+	RunWithSchema(t, func(pg *db.PGClient, t *testing.T) {
+		loadDefaultFixture(pg, t)
+		assert.Empty(t, revisions(t, pg))
+
+		// Updates record a revision with the whole item in it.
+		item, err := pg.ItemByID(historyItem)
+		assert.Nil(t, err)
+		item.Name = "The Vegan Lodge & Spa"
+		assert.Nil(t, pg.UpdateItem(item, []string{}, editor))
+		revs := revisions(t, pg)
+		if assert.Len(t, revs, 1) {
+			assert.Equal(t, 1, revs[0].Revision)
+			assert.Equal(t, model.RevisionUpdate, revs[0].Action)
+			assert.Equal(t, "usr_TESTEDITOR", revs[0].Author)
+			assert.Contains(t, []string(revs[0].Fields), "name")
+			assert.Contains(t, []string(revs[0].Fields), "attrs.address")
+		}
+
+		// Updates that fail or change nothing don't.
+		item, err = pg.ItemByID(historyItem)
+		assert.Nil(t, err)
+		item.Slug = "bad-slug"
+		assert.Equal(t, db.ErrReadOnlyField, pg.UpdateItem(item, []string{}, editor))
+		item, err = pg.ItemByID(historyItem)
+		assert.Nil(t, err)
+		assert.Equal(t, db.ErrItemNotOwned, pg.UpdateItem(item, []string{"usr_OTHER"}, editor))
+		assert.Nil(t, pg.UpdateItem(item, []string{}, editor))
+		assert.Len(t, revisions(t, pg), 1)
+
+		// Approval and ownership changes are recorded with their
+		// authors.
+		assert.Nil(t, pg.UpdateItemApproval(historyItem, types.Rejected, "usr_TESTADMIN"))
+		assert.Nil(t, pg.UpdateItemOwnership(historyItem, "org_TESTORG", types.Claimed, "usr_TESTADMIN"))
+		assert.Equal(t, db.ErrItemNotFound,
+			pg.UpdateItemApproval("htl_UNKNOWN", types.Approved, "usr_TESTADMIN"))
+		revs = revisions(t, pg)
+		if assert.Len(t, revs, 3) {
+			assert.Equal(t, 3, revs[0].Revision)
+			assert.Equal(t, model.RevisionOwnership, revs[0].Action)
+			assert.Equal(t, "usr_TESTADMIN", revs[0].Author)
+			assert.Equal(t, []string{"owner", "ownership"}, []string(revs[0].Fields))
+			assert.Equal(t, 2, revs[1].Revision)
+			assert.Equal(t, model.RevisionApproval, revs[1].Action)
+			assert.Equal(t, []string{"approval"}, []string(revs[1].Fields))
+		}
+
+		rev, err := pg.ItemRevision(historyItem, 3)
+		assert.Nil(t, err)
+		assert.Equal(t, "org_TESTORG", rev.Snapshot.Owner)
+		assert.Equal(t, types.ApprovalState(types.Rejected), rev.Snapshot.Approval)
+		assert.Equal(t, "The Vegan Lodge & Spa", rev.Snapshot.Name)
+	})
+}
+
+func TestCreateItemRevision(t *testing.T) {
+	RunWithSchema(t, func(pg *db.PGClient, t *testing.T) {
+		item := model.Item{
+			ItemType:  model.HotelItem,
+			Lang:      "en",
+			Name:      "A New Vegan Hotel",
+			Pictures:  []string{},
+			Tags:      []string{},
+			URLs:      types.URLMap{},
+			Attrs:     types.AttrMap{},
+			Creator:   "usr_TESTCREATOR",
+			Owner:     "usr_TESTCREATOR",
+			Ownership: types.Creator,
+		}
+		assert.Nil(t, pg.CreateItem(&item))
+
+		revs, _, err := pg.ItemRevisions(item.ID, 0, 0)
+		assert.Nil(t, err)
+		if assert.Len(t, revs, 1) {
+			assert.Equal(t, model.RevisionCreate, revs[0].Action)
+			assert.Equal(t, "usr_TESTCREATOR", revs[0].Author)
+		}
+	})
+}
@@ -0,0 +1,259 @@
+package db
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/veganbase/backend/services/item-service/db"
+	"github.com/veganbase/backend/services/item-service/model"
+	"github.com/veganbase/backend/services/item-service/model/types"
+)
+
+const stockItem = "pfd_LTPJkO9wfG6Z2gpH"
+
+func stockOf(t *testing.T, pg *db.PGClient, variant string) model.StockLevel {
+	levels, err := pg.StockLevels([]string{stockItem})
+	assert.Nil(t, err)
+	for _, level := range levels {
+		if level.Variant == variant {
+			return level
+		}
+	}
+	t.Fatalf("no stock for variant '%s'", variant)
+	return model.StockLevel{}
+}
+
+func reserve(pg *db.PGClient, ref string, expires time.Time, lines ...model.StockLine) error {
+	_, err := pg.ReserveStock(&model.StockReservation{
+		Reference: ref,
+		ExpiresAt: expires,
+		Lines:     lines,
+	})
+	return err
+}
+
+func TestReserveStock(t *testing.T) {
+	RunWithSchema(t, func(pg *db.PGClient, t *testing.T) {
+		loadDefaultFixture(pg, t)
+		_, err := pg.SetStock(stockItem, "", 5)
+		assert.Nil(t, err)
+		later := time.Now().Add(time.Hour)
+
+		// Lines for the same variant are reserved together.
+		err = reserve(pg, "pur_1", later,
+			model.StockLine{ItemID: stockItem, Quantity: 2},
+			model.StockLine{ItemID: stockItem, Quantity: 1})
+		assert.Nil(t, err)
+		level := stockOf(t, pg, "")
+		assert.Equal(t, 5, level.OnHand)
+		assert.Equal(t, 3, level.Reserved)
+		item, err := pg.ItemByID(stockItem)
+		assert.Nil(t, err)
+		assert.Equal(t, 2.0, item.Attrs["available_quantity"])
+
+		// A purchase only reserves stock once.
+		err = reserve(pg, "pur_1", later, model.StockLine{ItemID: stockItem, Quantity: 1})
+		assert.Equal(t, db.ErrReservationExists, err)
+
+		// Nothing is reserved if there isn't enough stock.
+		err = reserve(pg, "pur_2", later, model.StockLine{ItemID: stockItem, Quantity: 3})
+		if assert.IsType(t, &db.InsufficientStockError{}, err) {
+			assert.Equal(t, 2, err.(*db.InsufficientStockError).Available)
+		}
+		assert.Equal(t, 3, stockOf(t, pg, "").Reserved)
+		_, err = pg.ReservationByReference("pur_2")
+		assert.Equal(t, db.ErrReservationNotFound, err)
+
+		// Stock-controlled items only have the variants they have stock
+		// for, and items without stock aren't reserved.
+		err = reserve(pg, "pur_3", later, model.StockLine{ItemID: stockItem, Variant: "large", Quantity: 1})
+		assert.Equal(t, db.ErrUnknownVariant, err)
+		err = reserve(pg, "pur_4", later, model.StockLine{ItemID: "pfd_acKtRkF9avvS3SVJ", Quantity: 1})
+		assert.Nil(t, err)
+		res, err := pg.ReservationByReference("pur_4")
+		assert.Nil(t, err)
+		assert.Empty(t, res.Lines)
+	})
+}
+
+func TestReservationTransitions(t *testing.T) {
+	RunWithSchema(t, func(pg *db.PGClient, t *testing.T) {
+		loadDefaultFixture(pg, t)
+		_, err := pg.SetStock(stockItem, "", 5)
+		assert.Nil(t, err)
+		later := time.Now().Add(time.Hour)
+		line := model.StockLine{ItemID: stockItem, Quantity: 2}
+
+		// Releasing returns the stock, and releasing again does nothing.
+		assert.Nil(t, reserve(pg, "pur_1", later, line))
+		levels, err := pg.ReleaseReservation("pur_1")
+		assert.Nil(t, err)
+		assert.Len(t, levels, 1)
+		levels, err = pg.ReleaseReservation("pur_1")
+		assert.Nil(t, err)
+		assert.Empty(t, levels)
+		level := stockOf(t, pg, "")
+		assert.Equal(t, 5, level.OnHand)
+		assert.Equal(t, 0, level.Reserved)
+
+		// Committing a held reservation sells the stock, and committing
+		// again does nothing.
+		assert.Nil(t, reserve(pg, "pur_2", later, line))
+		_, err = pg.CommitReservation("pur_2")
+		assert.Nil(t, err)
+		_, err = pg.CommitReservation("pur_2")
+		assert.Nil(t, err)
+		level = stockOf(t, pg, "")
+		assert.Equal(t, 3, level.OnHand)
+		assert.Equal(t, 0, level.Reserved)
+		res, err := pg.ReservationByReference("pur_2")
+		assert.Nil(t, err)
+		assert.Equal(t, types.ReservationStatus(types.Committed), res.Status)
+
+		// A committed reservation can't be released.
+		levels, err = pg.ReleaseReservation("pur_2")
+		assert.Nil(t, err)
+		assert.Empty(t, levels)
+		assert.Equal(t, 3, stockOf(t, pg, "").OnHand)
+
+		// Reservations expire once they're past their time, and
+		// committing one afterwards takes the stock from what's
+		// available, if there's enough.
+		assert.Nil(t, reserve(pg, "pur_3", time.Now().Add(-time.Minute), line))
+		assert.Nil(t, reserve(pg, "pur_4", later, model.StockLine{ItemID: stockItem, Quantity: 1}))
+		levels, err = pg.ExpireReservations(time.Now())
+		assert.Nil(t, err)
+		assert.Len(t, levels, 1)
+		res, err = pg.ReservationByReference("pur_3")
+		assert.Nil(t, err)
+		assert.Equal(t, types.ReservationStatus(types.Expired), res.Status)
+		assert.Equal(t, 1, stockOf(t, pg, "").Reserved)
+
+		_, err = pg.CommitReservation("pur_3")
+		assert.Nil(t, err)
+		level = stockOf(t, pg, "")
+		assert.Equal(t, 1, level.OnHand)
+		assert.Equal(t, 1, level.Reserved)
+
+		// Once the stock has gone, a late commit fails and the
+		// reservation stays expired.
+		_, err = pg.ReleaseReservation("pur_4")
+		assert.Nil(t, err)
+		assert.Nil(t, reserve(pg, "pur_5", time.Now().Add(-time.Minute),
+			model.StockLine{ItemID: stockItem, Quantity: 1}))
+		_, err = pg.ExpireReservations(time.Now())
+		assert.Nil(t, err)
+		assert.Nil(t, reserve(pg, "pur_6", later, model.StockLine{ItemID: stockItem, Quantity: 1}))
+		_, err = pg.CommitReservation("pur_6")
+		assert.Nil(t, err)
+		_, err = pg.CommitReservation("pur_5")
+		assert.IsType(t, &db.InsufficientStockError{}, err)
+		res, err = pg.ReservationByReference("pur_5")
+		assert.Nil(t, err)
+		assert.Equal(t, types.ReservationStatus(types.Expired), res.Status)
+		level = stockOf(t, pg, "")
+		assert.Equal(t, 0, level.OnHand)
+		assert.Equal(t, 0, level.Reserved)
+
+		_, err = pg.CommitReservation("pur_X")
+		assert.Equal(t, db.ErrReservationNotFound, err)
+	})
+}
+
+func TestConcurrentReservations(t *testing.T) {
+	RunWithSchema(t, func(pg *db.PGClient, t *testing.T) {
+		loadDefaultFixture(pg, t)
+		_, err := pg.SetStock(stockItem, "large", 3)
+		assert.Nil(t, err)
+		later := time.Now().Add(time.Hour)
+
+		// Only as many purchases as there is stock for get a
+		// reservation.
+		var wg sync.WaitGroup
+		errs := make([]error, 10)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = reserve(pg, "pur_"+strconv.Itoa(i), later,
+					model.StockLine{ItemID: stockItem, Variant: "large", Quantity: 1})
+			}(i)
+		}
+		wg.Wait()
+
+		reserved := 0
+		for _, err := range errs {
+			if err == nil {
+				reserved++
+				continue
+			}
+			assert.IsType(t, &db.InsufficientStockError{}, err)
+		}
+		assert.Equal(t, 3, reserved)
+		level := stockOf(t, pg, "large")
+		assert.Equal(t, 3, level.OnHand)
+		assert.Equal(t, 3, level.Reserved)
+	})
+}
+
+func TestVariantStockRetiresDefaultStock(t *testing.T) {
+	RunWithSchema(t, func(pg *db.PGClient, t *testing.T) {
+		loadDefaultFixture(pg, t)
+		later := time.Now().Add(time.Hour)
+		retired := func(itemID string) []int {
+			deltas := []int{}
+			err := pg.DB.Select(&deltas, `SELECT on_hand_delta FROM stock_movements
+                                     WHERE item_id = $1 AND variant = '' AND reason = 'retired'
+                                     ORDER BY id`, itemID)
+			assert.Nil(t, err)
+			return deltas
+		}
+
+		// Stock without a variant name (as created by the migration from
+		// available_quantity) goes as soon as the item has a named
+		// variant.
+		plain := "pfd_acKtRkF9avvS3SVJ"
+		_, err := pg.SetStock(plain, "", 4)
+		assert.Nil(t, err)
+		_, err = pg.SetStock(plain, "small", 2)
+		assert.Nil(t, err)
+		levels, err := pg.StockLevels([]string{plain})
+		assert.Nil(t, err)
+		if assert.Len(t, levels, 1) {
+			assert.Equal(t, "small", levels[0].Variant)
+		}
+		assert.Equal(t, []int{-4}, retired(plain))
+
+		// Reserved stock is kept until the reservation is done with, but
+		// nothing more can be sold.
+		_, err = pg.SetStock(stockItem, "", 5)
+		assert.Nil(t, err)
+		assert.Nil(t, reserve(pg, "pur_1", later, model.StockLine{ItemID: stockItem, Quantity: 2}))
+		_, err = pg.SetStock(stockItem, "large", 3)
+		assert.Nil(t, err)
+		level := stockOf(t, pg, "")
+		assert.Equal(t, 2, level.OnHand)
+		assert.Equal(t, 2, level.Reserved)
+		item, err := pg.ItemByID(stockItem)
+		assert.Nil(t, err)
+		assert.Equal(t, 3.0, item.Attrs["available_quantity"])
+		err = reserve(pg, "pur_2", later, model.StockLine{ItemID: stockItem, Quantity: 1})
+		assert.IsType(t, &db.InsufficientStockError{}, err)
+
+		// Released stock doesn't go back on sale.
+		_, err = pg.ReleaseReservation("pur_1")
+		assert.Nil(t, err)
+		levels, err = pg.StockLevels([]string{stockItem})
+		assert.Nil(t, err)
+		if assert.Len(t, levels, 1) {
+			assert.Equal(t, "large", levels[0].Variant)
+		}
+		assert.Equal(t, []int{-3, -2}, retired(stockItem))
+		item, err = pg.ItemByID(stockItem)
+		assert.Nil(t, err)
+		assert.Equal(t, 3.0, item.Attrs["available_quantity"])
+	})
+}
@@ -2,6 +2,8 @@ package db
 
 import (
 	"errors"
+	"time"
+
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/item-service/model"
 	"github.com/veganbase/backend/services/item-service/model/types"
@@ -58,6 +60,20 @@ var ErrLinkOwnershipInvalid = errors.New("item ownership doesn't match link type
 // to create an inter-item link to an unknown target item.
 var ErrLinkTargetNotFound = errors.New("link target item ID not found")
 
+// ErrReservationNotFound is the error returned when an attempt is
+// made to access or manipulate a stock reservation for an unknown
+// purchase.
+var ErrReservationNotFound = errors.New("stock reservation not found")
+
+// ErrReservationExists is the error returned when an attempt is made
+// to reserve stock for a purchase that already has a reservation.
+var ErrReservationExists = errors.New("stock already reserved for purchase")
+
+// ErrUnknownVariant is the error returned when an attempt is made to
+// reserve stock of a variant that a stock-controlled item doesn't
+// have.
+var ErrUnknownVariant = errors.New("unknown item variant")
+
 // ErrBadLinkOriginType is the error returned when an attempt is made
 // to create an inter-item link with an origin whose item type does
 // not match the requirements of the link type.
@@ -86,6 +102,10 @@ var ErrCollectionNameAlreadyExists = errors.New("collection name is already in u
 // not own.
 var ErrItemCollectionNotOwned = errors.New("user does not own the item collection")
 
+// ErrRevisionNotFound is the error returned when an attempt is made
+// to access an item revision that doesn't exist.
+var ErrRevisionNotFound = errors.New("item revision not found")
+
 var ErrStatisticNotFound = errors.New("statistic not found")
 
 // SearchParams represents the search parameters that are accessible
@@ -124,18 +144,52 @@ type DB interface {
 	// ItemIDs gets all the existing item IDs.
 	ItemIDs() ([]string, error)
 
-	// CreateItem creates a new item.
+	// CreateItem creates a new item, starting its revision history.
 	CreateItem(item *model.Item) error
 
-	// UpdateItem updates the item's details in the database. The id,
-	// item_type, slug, approval, creator, owner, ownership and
-	// created_at fields are read-only using this method.
-	UpdateItem(item *model.Item, allowedOwner []string) error
+	// UpdateItem updates the item's details in the database, recording
+	// the change in the item's revision history. The id, item_type,
+	// slug, approval, creator, owner, ownership and created_at fields
+	// are read-only using this method.
+	UpdateItem(item *model.Item, allowedOwner []string, rev *RevisionInfo) error
+
+	// ItemRevisions lists the revision history of an item, most recent
+	// first, with pagination.
+	ItemRevisions(itemID string, page, perPage uint) ([]model.ItemRevision, *uint, error)
+
+	// ItemRevision retrieves a single revision of an item.
+	ItemRevision(itemID string, revision int) (*model.ItemRevision, error)
+
+	// StockLevels gets the stock levels of all the variants of a list
+	// of items.
+	StockLevels(itemIDs []string) ([]model.StockLevel, error)
+
+	// SetStock sets the stock of an item variant so that the given
+	// quantity is available for sale.
+	SetStock(itemID, variant string, available int) (*model.StockLevel, error)
+
+	// SyncAvailableQuantity rewrites an item's available_quantity
+	// attribute from its stock levels.
+	SyncAvailableQuantity(itemID string) error
+
+	// ReserveStock atomically reserves stock for a purchase, failing
+	// with an InsufficientStockError if any line can't be reserved.
+	ReserveStock(res *model.StockReservation) ([]model.StockLevel, error)
+
+	// ReservationByReference looks up the stock reservation for a
+	// purchase.
+	ReservationByReference(reference string) (*model.StockReservation, error)
+
+	// CommitReservation turns the stock held for a purchase into a
+	// sale.
+	CommitReservation(reference string) ([]model.StockLevel, error)
+
+	// ReleaseReservation returns the stock held for a purchase to sale.
+	ReleaseReservation(reference string) ([]model.StockLevel, error)
 
-	// UpdateAvailability updates the item's availability. More precisely, the attrs column.
-	// The other fields won't be affected because the Patch method is not used. item.UpdateAvailability
-	// is used instead.
-	UpdateAvailability(item *model.Item) error
+	// ExpireReservations releases held reservations that have passed
+	// their expiry time.
+	ExpireReservations(now time.Time) ([]model.StockLevel, error)
 
 	// DeleteItem performs the actual deletion of a item. If the owner
 	// user ID parameter is non-empty, then the item will only be
@@ -149,12 +203,12 @@ type DB interface {
 	TagsForUser(userID string) ([]string, error)
 
 	// UpdateItemApproval updates an item's approval state in the
-	// database.
-	UpdateItemApproval(id string, approval types.ApprovalState) error
+	// database, recording the change in the item's revision history.
+	UpdateItemApproval(id string, approval types.ApprovalState, author string) error
 
 	// UpdateItemOwnership updates an item's ownership state in the
-	// database.
-	UpdateItemOwnership(id string, owner string, ownership types.OwnershipStatus) error
+	// database, recording the change in the item's revision history.
+	UpdateItemOwnership(id string, owner string, ownership types.OwnershipStatus, author string) error
 
 	// CreateClaim creates a new ownership claim.
 	CreateClaim(claim *model.Claim) error
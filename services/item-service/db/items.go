@@ -269,7 +269,8 @@ func (pg *PGClient) ItemNames(ids []string) (map[string]string, error) {
 
 const qItemNames = `SELECT id, name FROM items WHERE id IN (?)`
 
-// CreateItem creates a new item.
+// CreateItem creates a new item, starting its revision history with
+// a revision authored by the item's creator.
 func (pg *PGClient) CreateItem(item *model.Item) error {
 	tx, err := pg.DB.Beginx()
 	if err != nil {
@@ -302,6 +303,7 @@ func (pg *PGClient) CreateItem(item *model.Item) error {
 		}
 		if rows.Next() {
 			err = rows.Scan(&item.CreatedAt)
+			rows.Close()
 			if err != nil {
 				return err
 			}
@@ -312,6 +314,10 @@ func (pg *PGClient) CreateItem(item *model.Item) error {
 		item.Slug = slug.Make(item.Name + " " + chassis.NewBareID(4))
 	}
 
+	err = addItemRevision(tx, item.ID, &RevisionInfo{
+		Action: model.RevisionCreate,
+		Author: item.Creator,
+	})
 	return err
 }
 
@@ -326,10 +332,11 @@ INSERT INTO
  ON CONFLICT DO NOTHING
  RETURNING created_at`
 
-// UpdateItem updates the item's details in the database. The id,
-// item_type, slug, approval, creator, owner, ownership and created_at
-// fields are read-only using this method.
-func (pg *PGClient) UpdateItem(item *model.Item, allowedOwner []string) error {
+// UpdateItem updates the item's details in the database, recording
+// the change in the item's revision history. The id, item_type, slug,
+// approval, creator, owner, ownership and created_at fields are
+// read-only using this method.
+func (pg *PGClient) UpdateItem(item *model.Item, allowedOwner []string, rev *RevisionInfo) error {
 	tx, err := pg.DB.Beginx()
 	if err != nil {
 		return err
@@ -344,7 +351,7 @@ func (pg *PGClient) UpdateItem(item *model.Item, allowedOwner []string) error {
 	}()
 
 	check := &model.Item{}
-	err = tx.Get(check, qItemBy+`i.id = $1`, item.ID)
+	err = tx.Get(check, qItemBy+`i.id = $1 FOR UPDATE`, item.ID)
 	if err == sql.ErrNoRows {
 		return ErrItemNotFound
 	}
@@ -398,6 +405,8 @@ func (pg *PGClient) UpdateItem(item *model.Item, allowedOwner []string) error {
 		// Slug collision: try again...
 		item.Slug = slug.Make(item.Name + " " + chassis.NewBareID(4))
 	}
+
+	err = addItemRevision(tx, item.ID, rev)
 	return err
 }
 
@@ -409,8 +418,8 @@ UPDATE items
  WHERE id = :id `
 
 // UpdateItemApproval updates an item's approval state in the
-// database.
-func (pg *PGClient) UpdateItemApproval(id string, approval types.ApprovalState) error {
+// database, recording the change in the item's revision history.
+func (pg *PGClient) UpdateItemApproval(id string, approval types.ApprovalState, author string) error {
 	tx, err := pg.DB.Beginx()
 	if err != nil {
 		return err
@@ -433,18 +442,23 @@ func (pg *PGClient) UpdateItemApproval(id string, approval types.ApprovalState)
 		return err
 	}
 	if rows != 1 {
-		return ErrItemNotFound
+		err = ErrItemNotFound
+		return err
 	}
 
+	err = addItemRevision(tx, id, &RevisionInfo{
+		Action: model.RevisionApproval,
+		Author: author,
+	})
 	return err
 }
 
 const qUpdateApproval = `UPDATE items SET approval=$2 WHERE id = $1`
 
 // UpdateItemOwnership updates an item's ownership state in the
-// database.
+// database, recording the change in the item's revision history.
 func (pg *PGClient) UpdateItemOwnership(id string,
-	owner string, ownership types.OwnershipStatus) error {
+	owner string, ownership types.OwnershipStatus, author string) error {
 	tx, err := pg.DB.Beginx()
 	if err != nil {
 		return err
@@ -467,9 +481,14 @@ func (pg *PGClient) UpdateItemOwnership(id string,
 		return err
 	}
 	if rows != 1 {
-		return ErrItemNotFound
+		err = ErrItemNotFound
+		return err
 	}
 
+	err = addItemRevision(tx, id, &RevisionInfo{
+		Action: model.RevisionOwnership,
+		Author: author,
+	})
 	return err
 }
 
@@ -574,46 +593,3 @@ func makeOrderMap(ids []string) map[string]int {
 	}
 	return res
 }
-
-// UpdateAvailability updates the item's availability. More precisely, the attrs column.
-// The other fields won't be affected because the Patch method is not used. item.UpdateAvailability
-// is used instead.
-func (pg *PGClient) UpdateAvailability(item *model.Item) error {
-	tx, err := pg.DB.Beginx()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		switch err {
-		case nil:
-			err = tx.Commit()
-		default:
-			tx.Rollback()
-		}
-	}()
-
-	check := &model.Item{}
-	err = tx.Get(check, qItemBy+`i.id = $1`, item.ID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return ErrItemNotFound
-		}
-		return err
-	}
-
-	result, err := tx.NamedExec(qUpdateItemAvailability, item)
-	if err != nil {
-		return err
-	}
-	_, err = result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	return err
-}
-
-const qUpdateItemAvailability = `
-UPDATE items
- SET attrs=:attrs
- WHERE id = :id`
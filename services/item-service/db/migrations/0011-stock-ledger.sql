@@ -0,0 +1,76 @@
+-- +migrate Up
+
+SET ROLE vb_items;
+
+-- Stock levels, per item and variant. Items that sell without
+-- variants use the empty variant name. Items with no stock rows
+-- aren't stock-controlled.
+CREATE TABLE stock (
+  item_id     TEXT         NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+  variant     TEXT         NOT NULL DEFAULT '',
+  on_hand     INTEGER      NOT NULL DEFAULT 0 CHECK (on_hand >= 0),
+  reserved    INTEGER      NOT NULL DEFAULT 0 CHECK (reserved >= 0),
+  updated_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (item_id, variant),
+  CHECK (reserved <= on_hand)
+);
+
+CREATE TYPE reservation_status AS ENUM ('held', 'committed', 'released', 'expired');
+
+-- Stock held for a purchase between checkout and payment. The
+-- reference is the purchase ID.
+CREATE TABLE stock_reservations (
+  id          TEXT                PRIMARY KEY,
+  reference   TEXT                NOT NULL UNIQUE,
+  status      reservation_status  NOT NULL DEFAULT 'held',
+  expires_at  TIMESTAMPTZ         NOT NULL,
+  created_at  TIMESTAMPTZ         NOT NULL DEFAULT now(),
+  updated_at  TIMESTAMPTZ         NOT NULL DEFAULT now()
+);
+
+CREATE INDEX stock_reservations_held_idx
+  ON stock_reservations(expires_at) WHERE status = 'held';
+
+CREATE TABLE stock_reservation_lines (
+  reservation_id  TEXT     NOT NULL REFERENCES stock_reservations(id) ON DELETE CASCADE,
+  item_id         TEXT     NOT NULL,
+  variant         TEXT     NOT NULL DEFAULT '',
+  quantity        INTEGER  NOT NULL CHECK (quantity > 0),
+
+  PRIMARY KEY (reservation_id, item_id, variant)
+);
+
+-- Every change to on-hand or reserved stock, for auditing.
+CREATE TABLE stock_movements (
+  id                SERIAL       PRIMARY KEY,
+  item_id           TEXT         NOT NULL,
+  variant           TEXT         NOT NULL,
+  reason            TEXT         NOT NULL,
+  on_hand_delta     INTEGER      NOT NULL DEFAULT 0,
+  reserved_delta    INTEGER      NOT NULL DEFAULT 0,
+  reservation_id    TEXT,
+  created_at        TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX stock_movements_item_idx ON stock_movements(item_id, variant);
+
+-- Existing available quantities become the initial stock.
+INSERT INTO stock (item_id, on_hand)
+  SELECT id, GREATEST((attrs->>'available_quantity')::numeric::integer, 0)
+    FROM items
+   WHERE attrs ? 'available_quantity';
+
+INSERT INTO stock_movements (item_id, variant, reason, on_hand_delta)
+  SELECT item_id, variant, 'adjusted', on_hand FROM stock;
+
+
+-- +migrate Down
+
+SET ROLE vb_items;
+
+DROP TABLE stock_movements;
+DROP TABLE stock_reservation_lines;
+DROP TABLE stock_reservations;
+DROP TYPE reservation_status;
+DROP TABLE stock;
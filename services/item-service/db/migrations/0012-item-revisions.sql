@@ -0,0 +1,47 @@
+-- +migrate Up
+
+SET ROLE vb_items;
+
+-- Append-only history of changes to items. Each revision holds a
+-- snapshot of the item as it was after the change, along with the
+-- fields that the change touched. There's no foreign key to items so
+-- that the history of deleted items is kept.
+CREATE TABLE item_revisions (
+  item_id        VARCHAR(24)  NOT NULL,
+  revision       INTEGER      NOT NULL,
+  action         TEXT         NOT NULL,
+  author         TEXT         NOT NULL,
+  fields         TEXT[]       NOT NULL DEFAULT '{}',
+  snapshot       JSONB        NOT NULL,
+  restored_from  INTEGER,
+  created_at     TIMESTAMPTZ  NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (item_id, revision)
+);
+
+-- Existing items start their history with a baseline revision
+-- holding their current state. Stock levels are kept in the stock
+-- ledger, so available quantities aren't included in snapshots.
+INSERT INTO item_revisions (item_id, revision, action, author, snapshot, created_at)
+  SELECT id, 1, 'baseline', creator,
+         jsonb_build_object(
+           'lang', lang,
+           'name', name,
+           'description', COALESCE(description, ''),
+           'featured_picture', COALESCE(featured_picture, ''),
+           'pictures', COALESCE(to_jsonb(pictures), '[]'),
+           'tags', COALESCE(to_jsonb(tags), '[]'),
+           'urls', COALESCE(urls, '{}'),
+           'attrs', COALESCE(attrs, '{}') - 'available_quantity',
+           'approval', approval::text,
+           'owner', owner,
+           'ownership', ownership::text),
+         created_at
+    FROM items;
+
+
+-- +migrate Down
+
+SET ROLE vb_items;
+
+DROP TABLE item_revisions;
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/item-service/model"
+)
+
+// RevisionInfo describes a change to an item for its revision
+// history: what was done, who did it and, for rollbacks, which
+// revision was restored.
+type RevisionInfo struct {
+	Action       string
+	Author       string
+	RestoredFrom *int
+}
+
+// Append a revision to the history of an item from inside the
+// transaction that changes the item, recording the fields that
+// changed since the previous revision. The item row must already be
+// locked by the transaction, which keeps revisions in the same order
+// as the changes they record. If nothing has changed, no revision is
+// added.
+func addItemRevision(tx *sqlx.Tx, itemID string, info *RevisionInfo) error {
+	item := model.Item{}
+	if err := tx.Get(&item, qItemBy+`i.id = $1`, itemID); err != nil {
+		return err
+	}
+	snapshot, err := model.NewSnapshot(&item)
+	if err != nil {
+		return err
+	}
+
+	latest := model.ItemRevision{}
+	err = tx.Get(&latest, qLatestRevision, itemID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	// With no history yet, everything in the snapshot counts as
+	// changed.
+	fields, err := model.ChangedFields(latest.Snapshot, snapshot)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	rev := model.ItemRevision{
+		ItemID:       itemID,
+		Revision:     latest.Revision + 1,
+		Action:       info.Action,
+		Author:       info.Author,
+		Fields:       fields,
+		Snapshot:     snapshot,
+		RestoredFrom: info.RestoredFrom,
+	}
+	_, err = tx.NamedExec(qAddRevision, rev)
+	return err
+}
+
+const qLatestRevision = `
+SELECT item_id, revision, action, author, fields, snapshot, restored_from, created_at
+  FROM item_revisions
+ WHERE item_id = $1
+ ORDER BY revision DESC LIMIT 1`
+
+const qAddRevision = `
+INSERT INTO
+  item_revisions (item_id, revision, action, author, fields, snapshot, restored_from)
+VALUES (:item_id, :revision, :action, :author, :fields, :snapshot, :restored_from)`
+
+// ItemRevisions lists the revision history of an item, most recent
+// first, without snapshots.
+func (pg *PGClient) ItemRevisions(itemID string, page, perPage uint) ([]model.ItemRevision, *uint, error) {
+	results := []model.ItemRevision{}
+	var total uint
+	q := `
+SELECT item_id, revision, action, author, fields, restored_from, created_at
+  FROM item_revisions
+ WHERE item_id = $1
+ ORDER BY revision DESC ` + chassis.Paginate(page, perPage)
+	if err := pg.DB.Select(&results, q, itemID); err != nil {
+		return nil, nil, err
+	}
+	if err := pg.DB.Get(&total, qRevisionCount, itemID); err != nil {
+		return nil, nil, err
+	}
+	return results, &total, nil
+}
+
+const qRevisionCount = `SELECT COUNT(*) FROM item_revisions WHERE item_id = $1`
+
+// ItemRevision retrieves a single revision of an item, including its
+// snapshot.
+func (pg *PGClient) ItemRevision(itemID string, revision int) (*model.ItemRevision, error) {
+	rev := &model.ItemRevision{}
+	err := pg.DB.Get(rev, qRevision, itemID, revision)
+	if err == sql.ErrNoRows {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+const qRevision = `
+SELECT item_id, revision, action, author, fields, snapshot, restored_from, created_at
+  FROM item_revisions
+ WHERE item_id = $1 AND revision = $2`
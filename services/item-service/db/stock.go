@@ -0,0 +1,551 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/item-service/model"
+	"github.com/veganbase/backend/services/item-service/model/types"
+)
+
+// InsufficientStockError is the error returned when there isn't
+// enough stock of an item variant available to make a reservation.
+type InsufficientStockError struct {
+	ItemID    string
+	Variant   string
+	Available int
+}
+
+func (e *InsufficientStockError) Error() string {
+	if e.Variant == "" {
+		return fmt.Sprintf("only %d of item '%s' available", e.Available, e.ItemID)
+	}
+	return fmt.Sprintf("only %d of variant '%s' of item '%s' available",
+		e.Available, e.Variant, e.ItemID)
+}
+
+// Reasons recorded in the stock movement ledger.
+const (
+	movementAdjusted  = "adjusted"
+	movementReserved  = "reserved"
+	movementCommitted = "committed"
+	movementReleased  = "released"
+	movementExpired   = "expired"
+	movementRetired   = "retired"
+)
+
+// StockLevels gets the stock levels of all the variants of a list of
+// items.
+func (pg *PGClient) StockLevels(itemIDs []string) ([]model.StockLevel, error) {
+	levels := []model.StockLevel{}
+	if len(itemIDs) == 0 {
+		return levels, nil
+	}
+	q, args, err := sqlx.In(qStockLevelsBy+`item_id IN (?) ORDER BY item_id, variant`, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err = pg.DB.Select(&levels, pg.DB.Rebind(q), args...); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// SetStock sets the stock of an item variant so that the given
+// quantity is available for sale over and above what is currently
+// reserved. Setting the stock of a named variant retires the stock
+// held without a variant name (from before the item was sold in
+// variants), so that it can't be sold any more.
+func (pg *PGClient) SetStock(itemID, variant string, available int) (*model.StockLevel, error) {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	before := model.StockLevel{}
+	err = tx.Get(&before, qStockLevelsBy+`item_id = $1 AND variant = $2 FOR UPDATE`, itemID, variant)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	level := &model.StockLevel{}
+	if err = tx.Get(level, qSetStock, itemID, variant, available); err != nil {
+		return nil, err
+	}
+	err = addMovement(tx, level.ItemID, level.Variant, movementAdjusted,
+		level.OnHand-before.OnHand, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	if variant != "" {
+		if err = retireDefaultStock(tx, itemID); err != nil {
+			return nil, err
+		}
+	}
+	if err = syncAvailableQuantity(tx, []string{itemID}); err != nil {
+		return nil, err
+	}
+	return level, nil
+}
+
+// Take the stock held without a variant name out of sale once an item
+// has named variants. The row is deleted if none of it is reserved;
+// otherwise only the reserved stock is kept, and the row is deleted
+// once those reservations are committed or released.
+func retireDefaultStock(tx *sqlx.Tx, itemID string) error {
+	level := model.StockLevel{}
+	err := tx.Get(&level, qStockLevelsBy+`item_id = $1 AND variant = '' FOR UPDATE`, itemID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var named bool
+	if err = tx.Get(&named, qHasNamedVariants, itemID); err != nil || !named {
+		return err
+	}
+
+	if level.Reserved == 0 {
+		_, err = tx.Exec(`DELETE FROM stock WHERE item_id = $1 AND variant = ''`, itemID)
+	} else {
+		_, err = tx.Exec(qAdjustStock, itemID, "", -level.Available(), 0)
+	}
+	if err != nil {
+		return err
+	}
+	if level.Available() == 0 {
+		return nil
+	}
+	return addMovement(tx, itemID, "", movementRetired, -level.Available(), 0, nil)
+}
+
+const qHasNamedVariants = `
+SELECT EXISTS (SELECT 1 FROM stock WHERE item_id = $1 AND variant <> '')`
+
+// Retire stock held without a variant name for the items in a list
+// of reservation lines, once the reservation no longer holds it.
+func retireDefaultStockFor(tx *sqlx.Tx, lines []model.StockLine) error {
+	for _, line := range lines {
+		if line.Variant != "" {
+			continue
+		}
+		if err := retireDefaultStock(tx, line.ItemID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const qSetStock = `
+INSERT INTO stock (item_id, variant, on_hand)
+ VALUES ($1, $2, $3)
+ ON CONFLICT (item_id, variant)
+ DO UPDATE SET on_hand = stock.reserved + $3, updated_at = now()
+ RETURNING item_id, variant, on_hand, reserved, updated_at`
+
+// SyncAvailableQuantity rewrites an item's available_quantity
+// attribute from its stock levels, if it is stock-controlled.
+func (pg *PGClient) SyncAvailableQuantity(itemID string) error {
+	_, err := pg.DB.Exec(qSyncAvailableQuantity, itemID)
+	return err
+}
+
+// ReserveStock reserves stock for a purchase. Either all the lines of
+// the reservation are reserved or none are. Lines for items that
+// aren't stock-controlled are dropped from the reservation. Returns
+// the updated stock levels.
+func (pg *PGClient) ReserveStock(res *model.StockReservation) ([]model.StockLevel, error) {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	var exists bool
+	if err = tx.Get(&exists, qReservationExists, res.Reference); err != nil {
+		return nil, err
+	}
+	if exists {
+		err = ErrReservationExists
+		return nil, err
+	}
+
+	res.ID = chassis.NewID("res")
+	res.Status = types.Held
+	rows, err := tx.NamedQuery(qCreateReservation, res)
+	if err != nil {
+		return nil, err
+	}
+	if rows.Next() {
+		if err = rows.Scan(&res.CreatedAt, &res.UpdatedAt); err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Close(); err != nil {
+		return nil, err
+	}
+
+	// Rows are locked in a fixed order so that concurrent reservations
+	// can't deadlock.
+	lines := mergeLines(res.Lines)
+	levels := []model.StockLevel{}
+	reserved := []model.StockLine{}
+	for _, line := range lines {
+		level := model.StockLevel{}
+		err = tx.Get(&level, qStockLevelsBy+`item_id = $1 AND variant = $2 FOR UPDATE`,
+			line.ItemID, line.Variant)
+		if err == sql.ErrNoRows {
+			var tracked bool
+			if err = tx.Get(&tracked, qStockTracked, line.ItemID); err != nil {
+				return nil, err
+			}
+			if tracked {
+				err = ErrUnknownVariant
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if level.Available() < line.Quantity {
+			err = &InsufficientStockError{
+				ItemID:    line.ItemID,
+				Variant:   line.Variant,
+				Available: level.Available(),
+			}
+			return nil, err
+		}
+
+		err = tx.Get(&level, qAdjustStock, line.ItemID, line.Variant, 0, line.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		err = addMovement(tx, line.ItemID, line.Variant, movementReserved, 0, line.Quantity, &res.ID)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(qAddReservationLine, res.ID, line.ItemID, line.Variant, line.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, level)
+		reserved = append(reserved, line)
+	}
+	res.Lines = reserved
+
+	if err = syncAvailableQuantity(tx, levelItemIDs(levels)); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+const qReservationExists = `
+SELECT EXISTS (SELECT 1 FROM stock_reservations WHERE reference = $1)`
+
+const qCreateReservation = `
+INSERT INTO stock_reservations (id, reference, status, expires_at)
+ VALUES (:id, :reference, :status, :expires_at)
+ RETURNING created_at, updated_at`
+
+const qStockTracked = `SELECT EXISTS (SELECT 1 FROM stock WHERE item_id = $1)`
+
+const qAddReservationLine = `
+INSERT INTO stock_reservation_lines (reservation_id, item_id, variant, quantity)
+ VALUES ($1, $2, $3, $4)`
+
+// ReservationByReference looks up a stock reservation by the ID of
+// the purchase it was made for.
+func (pg *PGClient) ReservationByReference(reference string) (*model.StockReservation, error) {
+	res := &model.StockReservation{}
+	if err := pg.DB.Get(res, qReservationBy+`reference = $1`, reference); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReservationNotFound
+		}
+		return nil, err
+	}
+	res.Lines = []model.StockLine{}
+	if err := pg.DB.Select(&res.Lines, qReservationLines, res.ID); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CommitReservation turns the stock held by a reservation into a
+// sale. Committing a committed reservation does nothing. If the
+// reservation has already been released or has expired (a payment
+// that succeeds after a retry, for example), the stock is taken from
+// what is available, if there still is enough. Returns the updated
+// stock levels.
+func (pg *PGClient) CommitReservation(reference string) ([]model.StockLevel, error) {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	res := model.StockReservation{}
+	err = tx.Get(&res, qReservationBy+`reference = $1 FOR UPDATE`, reference)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrReservationNotFound
+		}
+		return nil, err
+	}
+	levels := []model.StockLevel{}
+	if res.Status == types.Committed {
+		return levels, nil
+	}
+	if err = tx.Select(&res.Lines, qReservationLines, res.ID); err != nil {
+		return nil, err
+	}
+
+	held := res.Status == types.Held
+	for _, line := range res.Lines {
+		level := model.StockLevel{}
+		if !held {
+			err = tx.Get(&level, qStockLevelsBy+`item_id = $1 AND variant = $2 FOR UPDATE`,
+				line.ItemID, line.Variant)
+			if err != nil {
+				return nil, err
+			}
+			if level.Available() < line.Quantity {
+				err = &InsufficientStockError{
+					ItemID:    line.ItemID,
+					Variant:   line.Variant,
+					Available: level.Available(),
+				}
+				return nil, err
+			}
+		}
+
+		reservedDelta := 0
+		if held {
+			reservedDelta = -line.Quantity
+		}
+		err = tx.Get(&level, qAdjustStock, line.ItemID, line.Variant, -line.Quantity, reservedDelta)
+		if err != nil {
+			return nil, err
+		}
+		err = addMovement(tx, line.ItemID, line.Variant, movementCommitted,
+			-line.Quantity, reservedDelta, &res.ID)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, level)
+	}
+	if err = retireDefaultStockFor(tx, res.Lines); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(qSetReservationStatus, res.ID, types.ReservationStatus(types.Committed)); err != nil {
+		return nil, err
+	}
+	if err = syncAvailableQuantity(tx, levelItemIDs(levels)); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// ReleaseReservation returns the stock held by a reservation to sale.
+// Releasing a reservation that isn't held does nothing. Returns the
+// updated stock levels.
+func (pg *PGClient) ReleaseReservation(reference string) ([]model.StockLevel, error) {
+	return pg.release(reference, types.Released, movementReleased)
+}
+
+// ExpireReservations releases all held reservations that have passed
+// their expiry time. Returns the updated stock levels, including those
+// updated before any error.
+func (pg *PGClient) ExpireReservations(now time.Time) ([]model.StockLevel, error) {
+	refs := []string{}
+	if err := pg.DB.Select(&refs, qExpiredReservations, now); err != nil {
+		return nil, err
+	}
+	levels := []model.StockLevel{}
+	for _, ref := range refs {
+		ls, err := pg.release(ref, types.Expired, movementExpired)
+		if err != nil {
+			return levels, err
+		}
+		levels = append(levels, ls...)
+	}
+	return levels, nil
+}
+
+const qExpiredReservations = `
+SELECT reference FROM stock_reservations
+ WHERE status = 'held' AND expires_at < $1
+ ORDER BY expires_at`
+
+func (pg *PGClient) release(reference string,
+	status types.ReservationStatus, reason string) ([]model.StockLevel, error) {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	res := model.StockReservation{}
+	err = tx.Get(&res, qReservationBy+`reference = $1 FOR UPDATE`, reference)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrReservationNotFound
+		}
+		return nil, err
+	}
+	levels := []model.StockLevel{}
+	if res.Status != types.Held {
+		return levels, nil
+	}
+	if err = tx.Select(&res.Lines, qReservationLines, res.ID); err != nil {
+		return nil, err
+	}
+
+	for _, line := range res.Lines {
+		level := model.StockLevel{}
+		err = tx.Get(&level, qAdjustStock, line.ItemID, line.Variant, 0, -line.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		err = addMovement(tx, line.ItemID, line.Variant, reason, 0, -line.Quantity, &res.ID)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, level)
+	}
+	if err = retireDefaultStockFor(tx, res.Lines); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(qSetReservationStatus, res.ID, status); err != nil {
+		return nil, err
+	}
+	if err = syncAvailableQuantity(tx, levelItemIDs(levels)); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+const qStockLevelsBy = `
+SELECT item_id, variant, on_hand, reserved, updated_at
+  FROM stock WHERE `
+
+const qAdjustStock = `
+UPDATE stock
+   SET on_hand = on_hand + $3, reserved = reserved + $4, updated_at = now()
+ WHERE item_id = $1 AND variant = $2
+ RETURNING item_id, variant, on_hand, reserved, updated_at`
+
+const qReservationBy = `
+SELECT id, reference, status, expires_at, created_at, updated_at
+  FROM stock_reservations WHERE `
+
+const qReservationLines = `
+SELECT item_id, variant, quantity
+  FROM stock_reservation_lines WHERE reservation_id = $1
+ ORDER BY item_id, variant`
+
+const qSetReservationStatus = `
+UPDATE stock_reservations SET status = $2, updated_at = now() WHERE id = $1`
+
+func addMovement(tx *sqlx.Tx, itemID, variant, reason string,
+	onHandDelta, reservedDelta int, reservationID *string) error {
+	_, err := tx.Exec(qAddMovement, itemID, variant, reason,
+		onHandDelta, reservedDelta, reservationID)
+	return err
+}
+
+const qAddMovement = `
+INSERT INTO stock_movements
+  (item_id, variant, reason, on_hand_delta, reserved_delta, reservation_id)
+ VALUES ($1, $2, $3, $4, $5, $6)`
+
+// The available_quantity item attribute is kept as a denormalised
+// copy of the total available stock of an item, for the benefit of
+// clients that only look at item attributes.
+func syncAvailableQuantity(tx *sqlx.Tx, itemIDs []string) error {
+	for _, id := range itemIDs {
+		if _, err := tx.Exec(qSyncAvailableQuantity, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const qSyncAvailableQuantity = `
+UPDATE items
+   SET attrs = jsonb_set(attrs, '{available_quantity}',
+                         to_jsonb(s.available))
+  FROM (SELECT SUM(on_hand - reserved)::integer AS available
+          FROM stock WHERE item_id = $1) s
+ WHERE id = $1 AND s.available IS NOT NULL`
+
+// Merge duplicate lines and sort by item and variant.
+func mergeLines(lines []model.StockLine) []model.StockLine {
+	merged := []model.StockLine{}
+	idx := map[[2]string]int{}
+	for _, line := range lines {
+		key := [2]string{line.ItemID, line.Variant}
+		if i, ok := idx[key]; ok {
+			merged[i].Quantity += line.Quantity
+			continue
+		}
+		idx[key] = len(merged)
+		merged = append(merged, line)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].ItemID != merged[j].ItemID {
+			return merged[i].ItemID < merged[j].ItemID
+		}
+		return merged[i].Variant < merged[j].Variant
+	})
+	return merged
+}
+
+func levelItemIDs(levels []model.StockLevel) []string {
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, level := range levels {
+		if !seen[level.ItemID] {
+			seen[level.ItemID] = true
+			ids = append(ids, level.ItemID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/veganbase/backend/services/item-service/model"
+)
+
+func TestMergeLines(t *testing.T) {
+	var tests = []struct {
+		name   string
+		lines  []model.StockLine
+		merged []model.StockLine
+	}{
+		{"empty", []model.StockLine{}, []model.StockLine{}},
+		{
+			"sorted by item and variant",
+			[]model.StockLine{
+				{ItemID: "itm_B", Quantity: 1},
+				{ItemID: "itm_A", Variant: "large", Quantity: 2},
+				{ItemID: "itm_A", Quantity: 3},
+			},
+			[]model.StockLine{
+				{ItemID: "itm_A", Quantity: 3},
+				{ItemID: "itm_A", Variant: "large", Quantity: 2},
+				{ItemID: "itm_B", Quantity: 1},
+			},
+		},
+		{
+			"duplicates merged",
+			[]model.StockLine{
+				{ItemID: "itm_A", Variant: "large", Quantity: 2},
+				{ItemID: "itm_B", Quantity: 1},
+				{ItemID: "itm_A", Variant: "large", Quantity: 4},
+				{ItemID: "itm_A", Variant: "small", Quantity: 1},
+				{ItemID: "itm_B", Quantity: 1},
+			},
+			[]model.StockLine{
+				{ItemID: "itm_A", Variant: "large", Quantity: 6},
+				{ItemID: "itm_A", Variant: "small", Quantity: 1},
+				{ItemID: "itm_B", Quantity: 2},
+			},
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.merged, mergeLines(test.lines), test.name)
+	}
+
+	// The lines passed in aren't changed.
+	lines := []model.StockLine{{ItemID: "itm_A", Quantity: 1}, {ItemID: "itm_A", Quantity: 1}}
+	mergeLines(lines)
+	assert.Equal(t, 1, lines[0].Quantity)
+}
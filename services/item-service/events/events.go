@@ -2,7 +2,8 @@ package events
 
 // Event names used in item service.
 const (
-	ItemChange = "item-change"
+	ItemChange  = "item-change"
+	StockChange = "stock-change"
 )
 
 // ItemEventType is the event type published on the item service's
@@ -25,3 +26,26 @@ type ItemEvent struct {
 	ItemID       string        `json:"id"`
 	CollectionID string        `json:"collection_id,omitempty"`
 }
+
+// StockEvent is the message structure published on the item
+// service's "stock-change" notification topic whenever the stock of
+// an item variant changes. InStock says whether any variant of the
+// item is available for sale.
+type StockEvent struct {
+	ItemID    string `json:"id"`
+	Variant   string `json:"variant"`
+	OnHand    int    `json:"on_hand"`
+	Reserved  int    `json:"reserved"`
+	Available int    `json:"available"`
+	InStock   bool   `json:"in_stock"`
+	Reason    string `json:"reason"`
+}
+
+// Reasons for stock changes.
+const (
+	StockAdjusted  = "adjusted"
+	StockReserved  = "reserved"
+	StockCommitted = "committed"
+	StockReleased  = "released"
+	StockExpired   = "expired"
+)
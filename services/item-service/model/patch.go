@@ -3,6 +3,8 @@ package model
 import (
 	"encoding/json"
 	"github.com/veganbase/backend/chassis"
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -89,8 +91,38 @@ func (it *Item) Patch(patch []byte) error {
 	if err = urlMapField(&it.URLs, updates); err != nil {
 		return err
 	}
-	fixed := FixedValidate(it)
-	fixedData, err := json.Marshal(fixed)
+	if err = validateFixed(it); err != nil {
+		return err
+	}
+
+	// Step 4.
+	attrs := map[string]interface{}(it.Attrs)
+
+	// Step 5.
+	for k, v := range updates {
+		attrs[k] = v
+	}
+
+	// Steps 6 and 7.
+	if err = validateAttrs(it.ItemType, attrs); err != nil {
+		return err
+	}
+
+	// Step 8.
+	if err = validateSemantics(updates); err != nil {
+		return err
+	}
+
+	// Step 9.
+	it.Attrs = attrs
+
+	return nil
+}
+
+// validateFixed validates the fixed fields of an item against the
+// item-fixed JSON schema.
+func validateFixed(it *Item) error {
+	fixedData, err := json.Marshal(FixedValidate(it))
 	if err != nil {
 		return errors.Wrap(err, "marshalling fixed fields for validation")
 	}
@@ -105,23 +137,17 @@ func (it *Item) Patch(patch []byte) error {
 		}
 		return errors.New("validation errors for fixed item fields: " + strings.Join(msgs, "; "))
 	}
+	return nil
+}
 
-	// Step 4.
-	attrs := map[string]interface{}(it.Attrs)
-
-	// Step 5.
-	for k, v := range updates {
-		attrs[k] = v
-	}
-
-	// Step 6.
+// validateAttrs validates item attributes against the JSON schema
+// for the item type.
+func validateAttrs(itemType ItemType, attrs map[string]interface{}) error {
 	attrFields, err := json.Marshal(attrs)
 	if err != nil {
 		return errors.New("couldn't marshal patched attributes back to JSON")
 	}
-
-	// Step 7.
-	itt := it.ItemType.String()
+	itt := itemType.String()
 	attrRes, err := Validate(itt, attrFields)
 	if err != nil {
 		return errors.Wrap(err, "processing item attributes for '"+itt+"'")
@@ -134,9 +160,13 @@ func (it *Item) Patch(patch []byte) error {
 		return errors.New("validation errors in item attributes for '" + itt +
 			"': " + strings.Join(msgs, "; "))
 	}
+	return nil
+}
 
-	// Step 8.
-	for k, v := range updates {
+// validateSemantics runs the semantic validators for any complex
+// fields in a set of item attributes.
+func validateSemantics(fields map[string]interface{}) error {
+	for k, v := range fields {
 		val, ok := semanticValidators[k]
 		if !ok {
 			continue
@@ -145,42 +175,100 @@ func (it *Item) Patch(patch []byte) error {
 			return err
 		}
 	}
-
-	// Step 9.
-	it.Attrs = attrs
-
 	return nil
 }
 
-// UpdateAvailability patches available_inventory_quantity field on attrs attribute.
-func (it *Item) UpdateAvailability(patch []byte) error {
-	// Step 1 - unmarshal fields to be updated
-	updates := map[string]interface{}{}
-	err := json.Unmarshal(patch, &updates)
+// Diff compares two snapshots of an item, returning the changes to
+// each field in field name order. A nil "from" snapshot is treated as
+// an item with no fields set, which lists everything in the "to"
+// snapshot as changed.
+func Diff(from, to *ItemSnapshot) ([]FieldDiff, error) {
+	before, err := flattenSnapshot(from)
 	if err != nil {
-		return errors.Wrap(err, "unmarshaling patch")
+		return nil, err
+	}
+	after, err := flattenSnapshot(to)
+	if err != nil {
+		return nil, err
 	}
 
-	// Step 2 - turns attrs into an map of interfaces
-	attrs := map[string]interface{}(it.Attrs)
-
-	//Step 3 - getting current item availability
-	var currentAvailability int
-	if err = chassis.IntField(&currentAvailability, attrs, "available_quantity"); err != nil {
-		return err
+	fields := []string{}
+	for k := range before {
+		fields = append(fields, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			fields = append(fields, k)
+		}
 	}
+	sort.Strings(fields)
 
-	// Step 4 - getting value to be increased or decreased on the availability
-	var value int
-	if err = chassis.IntField(&value, updates, "quantity"); err != nil {
-		return err
+	diffs := []FieldDiff{}
+	for _, fld := range fields {
+		if !reflect.DeepEqual(before[fld], after[fld]) {
+			diffs = append(diffs, FieldDiff{Field: fld, From: before[fld], To: after[fld]})
+		}
 	}
+	return diffs, nil
+}
 
-	//Step 5 -summing them up (if value will decrease availability, it will be negative. positive otherwise)
-	attrs["available_quantity"] = currentAvailability + value
+// ChangedFields lists the names of the fields that differ between
+// two snapshots of an item.
+func ChangedFields(from, to *ItemSnapshot) ([]string, error) {
+	diffs, err := Diff(from, to)
+	if err != nil {
+		return nil, err
+	}
+	fields := []string{}
+	for _, d := range diffs {
+		fields = append(fields, d.Field)
+	}
+	return fields, nil
+}
 
-	// Step 6 - updating item attrs attribute.
-	it.Attrs = attrs
+// flattenSnapshot converts a snapshot to a generic map with one entry
+// per field. Item attributes are listed as "attrs.<name>", so that
+// they can't be confused with the fixed fields. Empty values are left
+// out, so that fields being cleared and fields being unset compare
+// equal.
+func flattenSnapshot(snap *ItemSnapshot) (map[string]interface{}, error) {
+	flat := map[string]interface{}{}
+	if snap == nil {
+		return flat, nil
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling item snapshot")
+	}
+	fields := map[string]interface{}{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling item snapshot")
+	}
+	if attrs, ok := fields["attrs"].(map[string]interface{}); ok {
+		for k, v := range attrs {
+			fields["attrs."+k] = v
+		}
+	}
+	delete(fields, "attrs")
 
-	return nil
-}
\ No newline at end of file
+	for k, v := range fields {
+		switch v := v.(type) {
+		case nil:
+			continue
+		case string:
+			if v == "" {
+				continue
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				continue
+			}
+		case map[string]interface{}:
+			if len(v) == 0 {
+				continue
+			}
+		}
+		flat[k] = v
+	}
+	return flat, nil
+}
@@ -0,0 +1,167 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	sqlxTypes "github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/veganbase/backend/services/item-service/model/types"
+)
+
+// Actions recorded in the revision history of an item.
+const (
+	RevisionCreate    = "create"
+	RevisionUpdate    = "update"
+	RevisionApproval  = "approval"
+	RevisionOwnership = "ownership"
+	RevisionRollback  = "rollback"
+	RevisionBaseline  = "baseline"
+)
+
+// Item attributes that aren't kept in revision snapshots. Stock
+// levels have their own ledger, and rolling back an item mustn't
+// change its stock.
+var untrackedAttrs = []string{"available_quantity"}
+
+// ItemSnapshot is a copy of the state of an item recorded in its
+// revision history: the fields that can be edited, plus approval and
+// ownership.
+type ItemSnapshot struct {
+	Lang            string                `json:"lang"`
+	Name            string                `json:"name"`
+	Description     string                `json:"description"`
+	FeaturedPicture string                `json:"featured_picture"`
+	Pictures        []string              `json:"pictures"`
+	Tags            []string              `json:"tags"`
+	URLs            types.URLMap          `json:"urls"`
+	Attrs           types.AttrMap         `json:"attrs"`
+	Approval        types.ApprovalState   `json:"approval"`
+	Owner           string                `json:"owner"`
+	Ownership       types.OwnershipStatus `json:"ownership"`
+}
+
+// NewSnapshot takes a snapshot of an item. The snapshot is a deep
+// copy, so it's not affected by later patches to the item.
+func NewSnapshot(item *Item) (*ItemSnapshot, error) {
+	snap := ItemSnapshot{
+		Lang:            item.Lang,
+		Name:            item.Name,
+		Description:     item.Description,
+		FeaturedPicture: item.FeaturedPicture,
+		Pictures:        item.Pictures,
+		Tags:            item.Tags,
+		URLs:            item.URLs,
+		Attrs:           item.Attrs,
+		Approval:        item.Approval,
+		Owner:           item.Owner,
+		Ownership:       item.Ownership,
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling item snapshot")
+	}
+	deep := ItemSnapshot{}
+	if err = json.Unmarshal(data, &deep); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling item snapshot")
+	}
+	for _, attr := range untrackedAttrs {
+		delete(deep.Attrs, attr)
+	}
+	return &deep, nil
+}
+
+// Restore sets the content of an item back to what it was in a
+// snapshot, and checks that the result is still valid for the item's
+// type, since schemas may have changed since the snapshot was taken.
+// Approval and ownership are not restored, and neither are attributes
+// that aren't kept in snapshots.
+func (snap *ItemSnapshot) Restore(item *Item) error {
+	attrs := map[string]interface{}{}
+	for k, v := range snap.Attrs {
+		attrs[k] = v
+	}
+	for _, attr := range untrackedAttrs {
+		if v, ok := item.Attrs[attr]; ok {
+			attrs[attr] = v
+		}
+	}
+
+	item.Lang = snap.Lang
+	item.Name = snap.Name
+	item.Description = snap.Description
+	item.FeaturedPicture = snap.FeaturedPicture
+	item.Pictures = snap.Pictures
+	item.Tags = snap.Tags
+	item.URLs = snap.URLs
+	if item.URLs == nil {
+		item.URLs = types.URLMap{}
+	}
+	if !item.checkFeaturedPicture() {
+		return errors.New("featured_picture must be a member of pictures list")
+	}
+	if err := validateFixed(item); err != nil {
+		return err
+	}
+	if err := validateAttrs(item.ItemType, attrs); err != nil {
+		return err
+	}
+	if err := validateSemantics(attrs); err != nil {
+		return err
+	}
+	item.Attrs = attrs
+	return nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (snap *ItemSnapshot) Scan(src interface{}) error {
+	j := sqlxTypes.JSONText{}
+	err := j.Scan(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(j, snap)
+}
+
+// Value implements the driver.Value interface.
+func (snap ItemSnapshot) Value() (driver.Value, error) {
+	v, err := json.Marshal(snap)
+	if err != nil {
+		return nil, err
+	}
+	return sqlxTypes.JSONText(v).Value()
+}
+
+// ItemRevision is an entry in the revision history of an item.
+// Revisions are numbered from one for each item. Snapshots are only
+// included when a single revision is retrieved.
+type ItemRevision struct {
+	ItemID       string         `db:"item_id" json:"item_id"`
+	Revision     int            `db:"revision" json:"revision"`
+	Action       string         `db:"action" json:"action"`
+	Author       string         `db:"author" json:"author"`
+	Fields       pq.StringArray `db:"fields" json:"fields"`
+	Snapshot     *ItemSnapshot  `db:"snapshot" json:"snapshot,omitempty"`
+	RestoredFrom *int           `db:"restored_from" json:"restored_from,omitempty"`
+	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
+}
+
+// FieldDiff is a change to a single field of an item between two
+// revisions. Item attributes are listed as "attrs.<name>", e.g.
+// "attrs.price". A nil value means that the field wasn't set.
+type FieldDiff struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// RevisionDiff is the difference between two revisions of an item.
+type RevisionDiff struct {
+	ItemID  string      `json:"item_id"`
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Changes []FieldDiff `json:"changes"`
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	category_mocks "github.com/veganbase/backend/services/category-service/mocks"
+	"github.com/veganbase/backend/services/item-service/model/types"
+)
+
+func TestDiff(t *testing.T) {
+	from := &ItemSnapshot{
+		Lang:     "en",
+		Name:     "Cafe",
+		Pictures: []string{"a.jpg"},
+		Tags:     []string{},
+		URLs:     types.URLMap{types.WebsiteURL: "https://example.com"},
+		Attrs:    types.AttrMap{"price": 100.0, "content": "old"},
+		Approval: types.Pending,
+		Owner:    "usr_1",
+	}
+	to := &ItemSnapshot{
+		Lang:     "en",
+		Name:     "Better Cafe",
+		Pictures: []string{"a.jpg"},
+		URLs:     types.URLMap{types.WebsiteURL: "https://example.com"},
+		Attrs:    types.AttrMap{"price": 100.0, "currency": "EUR"},
+		Approval: types.Approved,
+		Owner:    "usr_1",
+	}
+
+	diffs, err := Diff(from, to)
+	assert.Nil(t, err)
+	assert.Equal(t, []FieldDiff{
+		{Field: "approval", From: "pending", To: "approved"},
+		{Field: "attrs.content", From: "old", To: nil},
+		{Field: "attrs.currency", From: nil, To: "EUR"},
+		{Field: "name", From: "Cafe", To: "Better Cafe"},
+	}, diffs)
+
+	fields, err := ChangedFields(to, to)
+	assert.Nil(t, err)
+	assert.Empty(t, fields)
+
+	fields, err = ChangedFields(nil, from)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"approval", "attrs.content", "attrs.price",
+		"lang", "name", "owner", "ownership", "pictures", "urls"}, fields)
+
+	// Attributes can't overwrite fixed fields with the same name.
+	clash := &ItemSnapshot{Name: "Cafe", Attrs: types.AttrMap{"name": "Other"}}
+	diffs, err = Diff(nil, clash)
+	assert.Nil(t, err)
+	assert.Equal(t, []FieldDiff{
+		{Field: "approval", From: nil, To: "pending"},
+		{Field: "attrs.name", From: nil, To: "Other"},
+		{Field: "name", From: nil, To: "Cafe"},
+		{Field: "ownership", From: nil, To: "creator"},
+	}, diffs)
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	c := category_mocks.Client{}
+	c.On("Categories").Return(categoryMap)
+	c.On("IsValidLabel", mock.Anything, mock.Anything).Return(true)
+	LoadSchemas(&c)
+
+	b, _ := ioutil.ReadFile("../testdata/good/dish-1.json")
+	item := Item{}
+	assert.Nil(t, json.Unmarshal(b, &item))
+	item.Approval = types.Approved
+	item.Ownership = types.Creator
+
+	before, err := NewSnapshot(&item)
+	assert.Nil(t, err)
+	assert.NotContains(t, before.Attrs, "available_quantity")
+
+	err = item.Patch([]byte(`{"name": "Melon smoothie", "price": 120, "available_quantity": 5}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 100.0, before.Attrs["price"])
+
+	after, err := NewSnapshot(&item)
+	assert.Nil(t, err)
+	fields, err := ChangedFields(before, after)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"attrs.price", "name"}, fields)
+
+	// Restoring keeps the current stock.
+	assert.Nil(t, before.Restore(&item))
+	assert.Equal(t, "Watermelon smoothie", item.Name)
+	assert.Equal(t, 100.0, item.Attrs["price"])
+	assert.Equal(t, 5.0, item.Attrs["available_quantity"])
+
+	// Snapshots that no longer pass validation can't be restored.
+	before.Attrs["price"] = "free"
+	assert.NotNil(t, before.Restore(&item))
+}
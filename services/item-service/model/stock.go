@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+
+	"github.com/veganbase/backend/services/item-service/model/types"
+)
+
+// StockLevel is the stock held of an item, or of one variant of an
+// item. Items sold without variants use the empty variant name.
+type StockLevel struct {
+	ItemID    string    `db:"item_id" json:"item_id"`
+	Variant   string    `db:"variant" json:"variant"`
+	OnHand    int       `db:"on_hand" json:"on_hand"`
+	Reserved  int       `db:"reserved" json:"reserved"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Available is the quantity that can still be sold: what's on hand
+// less what's reserved for unpaid purchases.
+func (sl *StockLevel) Available() int {
+	return sl.OnHand - sl.Reserved
+}
+
+// StockLevelView is the JSON view of a stock level.
+type StockLevelView struct {
+	StockLevel
+	Available int `json:"available"`
+}
+
+// ViewStockLevel creates a view of a stock level.
+func ViewStockLevel(sl StockLevel) StockLevelView {
+	return StockLevelView{StockLevel: sl, Available: sl.Available()}
+}
+
+// StockUpdate is the request body used to set the stock of an item
+// variant: the quantity given is what should be available for sale.
+type StockUpdate struct {
+	Variant   string `json:"variant"`
+	Available int    `json:"available"`
+}
+
+// StockLine is a quantity of an item variant to be reserved.
+type StockLine struct {
+	ItemID   string `db:"item_id" json:"item_id"`
+	Variant  string `db:"variant" json:"variant"`
+	Quantity int    `db:"quantity" json:"quantity"`
+}
+
+// StockReservation is stock set aside for a purchase from checkout
+// until the purchase is paid for or abandoned.
+type StockReservation struct {
+	ID        string                  `db:"id" json:"id"`
+	Reference string                  `db:"reference" json:"reference"`
+	Status    types.ReservationStatus `db:"status" json:"status"`
+	ExpiresAt time.Time               `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time               `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time               `db:"updated_at" json:"updated_at"`
+	Lines     []StockLine             `db:"-" json:"lines"`
+}
+
+// ReservationRequest is the request body used to reserve stock.
+// TTL is the time in seconds that the reservation is held for if the
+// purchase isn't paid for; if it's zero, a default is used.
+type ReservationRequest struct {
+	Reference string      `json:"reference"`
+	TTL       int         `json:"ttl,omitempty"`
+	Lines     []StockLine `json:"lines"`
+}
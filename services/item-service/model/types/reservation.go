@@ -0,0 +1,102 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// ReservationStatus is an enumerated type representing the state of
+// a stock reservation: reservations are held from checkout until the
+// purchase is paid for (when they are committed), the payment fails
+// (when they are released) or they time out (when they expire).
+type ReservationStatus uint
+
+const (
+	// Held represents a reservation whose stock is set aside but not
+	// yet sold.
+	Held = iota
+
+	// Committed represents a reservation whose stock has been sold.
+	Committed
+
+	// Released represents a reservation whose stock has been returned
+	// to sale.
+	Released
+
+	// Expired represents a reservation whose stock was returned to sale
+	// because the purchase wasn't paid for in time.
+	Expired
+)
+
+// String converts a reservation status to its string representation.
+func (s ReservationStatus) String() string {
+	switch s {
+	case Held:
+		return "held"
+	case Committed:
+		return "committed"
+	case Released:
+		return "released"
+	case Expired:
+		return "expired"
+	default:
+		return "<unknown reservation status>"
+	}
+}
+
+// FromString does checked conversion from a string to a
+// ReservationStatus.
+func (s *ReservationStatus) FromString(str string) error {
+	switch str {
+	case "held":
+		*s = Held
+	case "committed":
+		*s = Committed
+	case "released":
+		*s = Released
+	case "expired":
+		*s = Expired
+	default:
+		return errors.New("unknown reservation status '" + str + "'")
+	}
+	return nil
+}
+
+// MarshalJSON converts an internal reservation status to JSON.
+func (s ReservationStatus) MarshalJSON() ([]byte, error) {
+	str := s.String()
+	if str == "<unknown reservation status>" {
+		return nil, errors.New("unknown reservation status")
+	}
+	return json.Marshal(str)
+}
+
+// UnmarshalJSON unmarshals a reservation status from a JSON string.
+func (s *ReservationStatus) UnmarshalJSON(d []byte) error {
+	var str string
+	if err := json.Unmarshal(d, &str); err != nil {
+		return errors.Wrap(err, "can't unmarshal reservation status")
+	}
+	return s.FromString(str)
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *ReservationStatus) Scan(src interface{}) error {
+	var str string
+	switch src := src.(type) {
+	case string:
+		str = src
+	case []byte:
+		str = string(src)
+	default:
+		return errors.New("incompatible type for ReservationStatus")
+	}
+	return s.FromString(str)
+}
+
+// Value implements the driver.Value interface.
+func (s ReservationStatus) Value() (driver.Value, error) {
+	return s.String(), nil
+}
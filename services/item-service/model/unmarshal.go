@@ -166,6 +166,13 @@ func (item *Item) UnrestrictedUnmarshalJSON(data []byte) error {
 	chassis.StringField(&item.ID, fields, "id")
 	chassis.StringField(&item.Lang, fields, "lang")
 	chassis.StringField(&item.Owner, owner, "id")
+	approval := ""
+	chassis.StringField(&approval, fields, "approval")
+	if approval != "" {
+		if err = item.Approval.FromString(approval); err != nil {
+			return err
+		}
+	}
 	chassis.StringField(&item.Name, fields, "name")
 	chassis.StringField(&item.Description, fields, "description")
 	chassis.StringField(&item.FeaturedPicture, fields, "featured_picture")
@@ -43,7 +43,7 @@ func (s *Server) changeItemApproval(w http.ResponseWriter, r *http.Request) (int
 		return chassis.BadRequest(w, err.Error())
 	}
 
-	err = s.db.UpdateItemApproval(item.ID, update.Approval)
+	err = s.db.UpdateItemApproval(item.ID, update.Approval, authInfo.UserID)
 	if err != nil {
 		return chassis.BadRequest(w, err.Error())
 	}
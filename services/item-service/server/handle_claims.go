@@ -288,7 +288,8 @@ func (s *Server) changeOwnershipClaimStatus(w http.ResponseWriter, r *http.Reque
 
 	// If the claim was approved, update the item ownership.
 	if update.Status == types.Approved {
-		err = s.db.UpdateItemOwnership(claim.ItemID, claim.OwnerID, types.Claimed)
+		err = s.db.UpdateItemOwnership(claim.ItemID, claim.OwnerID, types.Claimed,
+			authInfo.UserID)
 		if err == db.ErrItemNotFound {
 			return chassis.NotFound(w)
 			log.Error().
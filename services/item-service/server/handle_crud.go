@@ -74,6 +74,9 @@ func (s *Server) createItem(w http.ResponseWriter, r *http.Request) (interface{}
 		return nil, err
 	}
 	s.emit(events.ItemCreated, item.ID)
+	if err = s.syncItemStock(&item, nil); err != nil {
+		return nil, err
+	}
 
 	// Add item/blob associations for new item.
 	err = s.addItemBlobs(&item)
@@ -192,6 +195,7 @@ func (s *Server) patchItem(w http.ResponseWriter, r *http.Request) (interface{},
 	for _, pic := range item.Pictures {
 		picsBefore[pic] = true
 	}
+	qtyBefore := availableQuantity(item)
 	err = item.Patch(body)
 	if err != nil {
 		return chassis.BadRequest(w, err.Error())
@@ -208,13 +212,17 @@ func (s *Server) patchItem(w http.ResponseWriter, r *http.Request) (interface{},
 	}
 
 	// Do the update.
-	if err = s.db.UpdateItem(item, allowedOwners); err != nil {
+	rev := &db.RevisionInfo{Action: model.RevisionUpdate, Author: authInfo.UserID}
+	if err = s.db.UpdateItem(item, allowedOwners, rev); err != nil {
 		return nil, err
 	}
 	if err == db.ErrItemNotFound {
 		return chassis.NotFound(w)
 	}
 	s.emit(events.ItemUpdated, item.ID)
+	if err = s.syncItemStock(item, qtyBefore); err != nil {
+		return nil, err
+	}
 
 	// Update the item/blob associations.
 	err = s.updateItemBlobs(item.ID, picsBefore, picsAfter)
@@ -324,45 +332,3 @@ func (s *Server) urlsToBlobIDs(urls []string) []string {
 	}
 	return result
 }
-
-//updateAvailability is a method used to perform changes in item available quantity.
-func (s *Server) updateAvailability(w http.ResponseWriter, r *http.Request) (interface{}, error) {
-
-	// Get the item ID to update.
-	itemID := chi.URLParam(r, "id")
-	if itemID == "" {
-		return chassis.BadRequest(w, "missing item ID")
-	}
-
-	// Read patch request body.
-	body, err := chassis.ReadBody(r, 0)
-	if err != nil {
-		return chassis.BadRequest(w, err.Error())
-	}
-
-	// Look up item value.
-	item, err := s.db.ItemByID(itemID)
-	if err != nil {
-		if err == db.ErrItemNotFound {
-			return chassis.NotFound(w)
-		}
-		return nil, err
-	}
-
-	//patching availability information
-	if err = item.UpdateAvailability(body); err != nil {
-		return chassis.BadRequest(w, err.Error())
-	}
-
-	// Do the update.
-	if err = s.db.UpdateAvailability(item); err != nil {
-		if err == db.ErrItemNotFound {
-			return chassis.NotFound(w)
-		}
-		return nil, err
-	}
-
-	s.emit(events.ItemUpdated, item.ID)
-
-	return item, nil
-}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/item-service/db"
+	"github.com/veganbase/backend/services/item-service/events"
+	"github.com/veganbase/backend/services/item-service/model"
+)
+
+// Revision history of an item, most recent first, visible to the
+// item's owners and administrators.
+func (s *Server) getItemHistory(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.Forbidden(w)
+	}
+
+	var page, perPage uint
+	if err := chassis.PaginationParams(r.URL.Query(), &page, &perPage); err != nil {
+		return chassis.BadRequest(w, "invalid pagination parameters")
+	}
+
+	item, err := s.ownedItem(authInfo, chi.URLParam(r, "id"))
+	if err != nil {
+		return itemAccessError(w, err)
+	}
+
+	revisions, total, err := s.db.ItemRevisions(item.ID, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+	chassis.BuildPaginationResponse(w, r, page, perPage, *total)
+	return revisions, nil
+}
+
+// A single revision of an item, including the item's state after the
+// revision was made.
+func (s *Server) getItemRevision(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.Forbidden(w)
+	}
+
+	n, err := revisionNumber(chi.URLParam(r, "n"))
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	item, err := s.ownedItem(authInfo, chi.URLParam(r, "id"))
+	if err != nil {
+		return itemAccessError(w, err)
+	}
+
+	rev, err := s.db.ItemRevision(item.ID, n)
+	if err == db.ErrRevisionNotFound {
+		return chassis.NotFoundWithMessage(w, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// The changes made to an item between two revisions. The "to"
+// revision is required: the "from" revision defaults to the one
+// before it. Revision zero stands for the item before it was created.
+func (s *Server) diffItemRevisions(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.Forbidden(w)
+	}
+
+	qs := r.URL.Query()
+	to, err := revisionNumber(qs.Get("to"))
+	if err != nil {
+		return chassis.BadRequest(w, "invalid 'to' parameter: "+err.Error())
+	}
+	from := to - 1
+	if p := qs.Get("from"); p != "" {
+		if from, err = strconv.Atoi(p); err != nil || from < 0 {
+			return chassis.BadRequest(w, "invalid 'from' parameter")
+		}
+	}
+
+	item, err := s.ownedItem(authInfo, chi.URLParam(r, "id"))
+	if err != nil {
+		return itemAccessError(w, err)
+	}
+
+	snapshots := map[int]*model.ItemSnapshot{}
+	for _, n := range []int{from, to} {
+		if n == 0 {
+			continue
+		}
+		rev, err := s.db.ItemRevision(item.ID, n)
+		if err == db.ErrRevisionNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapshots[n] = rev.Snapshot
+	}
+
+	changes, err := model.Diff(snapshots[from], snapshots[to])
+	if err != nil {
+		return nil, err
+	}
+	return &model.RevisionDiff{
+		ItemID:  item.ID,
+		From:    from,
+		To:      to,
+		Changes: changes,
+	}, nil
+}
+
+type rollbackRequest struct {
+	Revision int `json:"revision"`
+}
+
+// Roll an item's content back to an earlier revision. Only
+// administrators can do this. The restored content has to pass the
+// current validation rules for the item type, and the rollback is
+// itself recorded as a new revision.
+func (s *Server) rollbackItem(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	// Get authentication information from context and only allow
+	// administrators to proceed.
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth || !authInfo.UserIsAdmin {
+		return chassis.NotFound(w)
+	}
+
+	// Read and unmarshal request body.
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	req := rollbackRequest{}
+	if err = json.Unmarshal(body, &req); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if req.Revision < 1 {
+		return chassis.BadRequest(w, "a revision to roll back to must be given")
+	}
+
+	// Look up the item and the revision to restore.
+	item, err := s.db.ItemByID(chi.URLParam(r, "id"))
+	if err == db.ErrItemNotFound {
+		return chassis.NotFound(w)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rev, err := s.db.ItemRevision(item.ID, req.Revision)
+	if err == db.ErrRevisionNotFound {
+		return chassis.NotFoundWithMessage(w, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore and revalidate the item content.
+	picsBefore := map[string]bool{}
+	for _, pic := range item.Pictures {
+		picsBefore[pic] = true
+	}
+	qtyBefore := availableQuantity(item)
+	if err = rev.Snapshot.Restore(item); err != nil {
+		return chassis.BadRequest(w, "can't restore revision "+
+			strconv.Itoa(req.Revision)+": "+err.Error())
+	}
+	picsAfter := map[string]bool{}
+	for _, pic := range item.Pictures {
+		picsAfter[pic] = true
+	}
+
+	// Do the update.
+	change := &db.RevisionInfo{
+		Action:       model.RevisionRollback,
+		Author:       authInfo.UserID,
+		RestoredFrom: &req.Revision,
+	}
+	if err = s.db.UpdateItem(item, []string{}, change); err != nil {
+		if err == db.ErrItemNotFound {
+			return chassis.NotFound(w)
+		}
+		return nil, err
+	}
+	s.emit(events.ItemUpdated, item.ID)
+	if err = s.syncItemStock(item, qtyBefore); err != nil {
+		return nil, err
+	}
+
+	// Update the item/blob associations.
+	if err = s.updateItemBlobs(item.ID, picsBefore, picsAfter); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// Convert errors from looking up an item for its owners to responses.
+func itemAccessError(w http.ResponseWriter, err error) (interface{}, error) {
+	switch err {
+	case db.ErrItemNotFound:
+		return chassis.NotFound(w)
+	case db.ErrItemNotOwned:
+		return chassis.Forbidden(w)
+	}
+	return nil, err
+}
+
+// Parse a revision number.
+func revisionNumber(s string) (int, error) {
+	if s == "" {
+		return 0, errors.New("missing revision number")
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, errors.New("invalid revision number '" + s + "'")
+	}
+	return n, nil
+}
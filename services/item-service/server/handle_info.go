@@ -48,3 +48,30 @@ func (s *Server) getItemsBasicInfo(w http.ResponseWriter, r *http.Request) (inte
 
 	return info, nil
 }
+
+// Names of the collections that each of a list of items belongs to,
+// keyed by item ID.
+func (s *Server) getItemsCollections(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	qs, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		return chassis.BadRequest(w, "invalid query parameters")
+	}
+
+	idparam := qs.Get("ids")
+	if idparam == "" {
+		return chassis.BadRequest(w, "invalid items ID list")
+	}
+
+	ids := strings.Split(idparam, ",")
+	for _, id := range ids {
+		if strings.ContainsAny(id, "'\\") {
+			return chassis.BadRequest(w, "invalid items ID list")
+		}
+	}
+	names, err := s.db.CollectionsNamesByItemId(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
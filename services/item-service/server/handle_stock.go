@@ -0,0 +1,369 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog/log"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/item-service/db"
+	"github.com/veganbase/backend/services/item-service/events"
+	"github.com/veganbase/backend/services/item-service/model"
+)
+
+// Item types whose stock is kept in the stock ledger. These are the
+// item types with an available_quantity attribute.
+var stockControlled = map[model.ItemType]bool{
+	model.ProductOfferingItem: true,
+	model.DishItem:            true,
+	model.OfferItem:           true,
+}
+
+// Stock levels of all variants of an item, visible to the item's
+// owners and administrators.
+func (s *Server) getStock(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.Forbidden(w)
+	}
+
+	item, err := s.ownedItem(authInfo, chi.URLParam(r, "id"))
+	if err != nil {
+		switch err {
+		case db.ErrItemNotFound:
+			return chassis.NotFound(w)
+		case db.ErrItemNotOwned:
+			return chassis.Forbidden(w)
+		}
+		return nil, err
+	}
+
+	levels, err := s.db.StockLevels([]string{item.ID})
+	if err != nil {
+		return nil, err
+	}
+	return viewStockLevels(levels), nil
+}
+
+// Set the stock of one or more variants of an item. The request body
+// is a list of variants and the quantity of each that should be
+// available for sale.
+func (s *Server) setStock(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.Forbidden(w)
+	}
+
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	updates := []model.StockUpdate{}
+	if err = json.Unmarshal(body, &updates); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if len(updates) == 0 {
+		return chassis.BadRequest(w, "no stock updates")
+	}
+	for _, u := range updates {
+		if u.Available < 0 {
+			return chassis.BadRequest(w, "available stock can't be negative")
+		}
+	}
+
+	item, err := s.ownedItem(authInfo, chi.URLParam(r, "id"))
+	if err != nil {
+		switch err {
+		case db.ErrItemNotFound:
+			return chassis.NotFound(w)
+		case db.ErrItemNotOwned:
+			return chassis.Forbidden(w)
+		}
+		return nil, err
+	}
+	if !stockControlled[item.ItemType] {
+		return chassis.BadRequest(w, "stock isn't tracked for items of type '"+item.ItemType.String()+"'")
+	}
+
+	changed := []model.StockLevel{}
+	for _, u := range updates {
+		level, err := s.db.SetStock(item.ID, u.Variant, u.Available)
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, *level)
+	}
+	s.emitStock(changed, events.StockAdjusted)
+
+	levels, err := s.db.StockLevels([]string{item.ID})
+	if err != nil {
+		return nil, err
+	}
+	return viewStockLevels(levels), nil
+}
+
+// Stock levels for a list of items, keyed by item ID. Items that
+// aren't stock-controlled are left out.
+func (s *Server) getStockLevels(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	ids := []string{}
+	if param := r.URL.Query().Get("ids"); param != "" {
+		ids = strings.Split(param, ",")
+	}
+
+	levels, err := s.db.StockLevels(ids)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string][]model.StockLevelView{}
+	for _, level := range levels {
+		result[level.ItemID] = append(result[level.ItemID], model.ViewStockLevel(level))
+	}
+	return result, nil
+}
+
+// Reserve stock for a purchase at checkout. Reservations either
+// succeed for all lines or fail with a 409 Conflict.
+func (s *Server) reserveStock(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	req := model.ReservationRequest{}
+	if err = json.Unmarshal(body, &req); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if req.Reference == "" {
+		return chassis.BadRequest(w, "missing reservation reference")
+	}
+	for _, line := range req.Lines {
+		if line.ItemID == "" || line.Quantity <= 0 {
+			return chassis.BadRequest(w, "invalid reservation line")
+		}
+	}
+
+	ttl := s.reservationTTL
+	if req.TTL > 0 {
+		ttl = time.Duration(req.TTL) * time.Second
+	}
+	res := model.StockReservation{
+		Reference: req.Reference,
+		ExpiresAt: time.Now().Add(ttl),
+		Lines:     req.Lines,
+	}
+	levels, err := s.db.ReserveStock(&res)
+	if err != nil {
+		switch err := err.(type) {
+		case *db.InsufficientStockError:
+			return chassis.Conflict(w, err.Error())
+		}
+		switch err {
+		case db.ErrReservationExists:
+			return chassis.Conflict(w, err.Error())
+		case db.ErrUnknownVariant:
+			return chassis.BadRequest(w, err.Error())
+		}
+		return nil, err
+	}
+	s.emitStock(levels, events.StockReserved)
+
+	return &res, nil
+}
+
+// Commit the stock reserved for a purchase once it has been paid for.
+func (s *Server) commitReservation(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	ref := chi.URLParam(r, "ref")
+	levels, err := s.db.CommitReservation(ref)
+	if err != nil {
+		if err == db.ErrReservationNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		if err, ok := err.(*db.InsufficientStockError); ok {
+			return chassis.Conflict(w, err.Error())
+		}
+		return nil, err
+	}
+	s.emitStock(levels, events.StockCommitted)
+
+	return s.db.ReservationByReference(ref)
+}
+
+// Release the stock reserved for a purchase whose payment failed.
+func (s *Server) releaseReservation(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	ref := chi.URLParam(r, "ref")
+	levels, err := s.db.ReleaseReservation(ref)
+	if err != nil {
+		if err == db.ErrReservationNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	s.emitStock(levels, events.StockReleased)
+
+	return s.db.ReservationByReference(ref)
+}
+
+// Start the reservation expiry process, which periodically returns
+// stock held for purchases that haven't been paid for in time to
+// sale, until the server exits.
+func (s *Server) startReservationExpiry(interval time.Duration) {
+	ctx, cancel := context.WithCancel(s.Ctx)
+	s.AddAtExit(cancel)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			levels, err := s.db.ExpireReservations(time.Now())
+			if err != nil {
+				log.Error().Err(err).Msg("expiring stock reservations")
+			}
+			s.emitStock(levels, events.StockExpired)
+		}
+	}()
+}
+
+// Look up an item, checking that the user is allowed to manage it.
+func (s *Server) ownedItem(authInfo *chassis.AuthInfo, itemID string) (*model.Item, error) {
+	item, err := s.db.ItemByID(itemID)
+	if err != nil {
+		return nil, err
+	}
+	if authInfo.UserIsAdmin {
+		return item, nil
+	}
+	owners, err := s.possibleOwners(authInfo.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, owner := range owners {
+		if owner == item.Owner {
+			return item, nil
+		}
+	}
+	return nil, db.ErrItemNotOwned
+}
+
+// Emit stock change events for a list of changed stock levels.
+func (s *Server) emitStock(levels []model.StockLevel, reason string) {
+	if len(levels) == 0 {
+		return
+	}
+	ids := []string{}
+	for _, level := range levels {
+		ids = append(ids, level.ItemID)
+	}
+	inStock, err := s.inStock(ids)
+	if err != nil {
+		log.Error().Err(err).Msg("looking up stock levels for stock events")
+		return
+	}
+	for _, level := range levels {
+		chassis.Emit(s, events.StockChange, events.StockEvent{
+			ItemID:    level.ItemID,
+			Variant:   level.Variant,
+			OnHand:    level.OnHand,
+			Reserved:  level.Reserved,
+			Available: level.Available(),
+			InStock:   inStock[level.ItemID],
+			Reason:    reason,
+		})
+	}
+}
+
+// Determine whether items have any variant available for sale. Items
+// that aren't stock-controlled are always in stock.
+func (s *Server) inStock(ids []string) (map[string]bool, error) {
+	levels, err := s.db.StockLevels(ids)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]bool{}
+	for _, id := range ids {
+		result[id] = true
+	}
+	tracked := map[string]bool{}
+	for _, level := range levels {
+		if !tracked[level.ItemID] {
+			tracked[level.ItemID] = true
+			result[level.ItemID] = false
+		}
+		if level.Available() > 0 {
+			result[level.ItemID] = true
+		}
+	}
+	return result, nil
+}
+
+func viewStockLevels(levels []model.StockLevel) []model.StockLevelView {
+	views := []model.StockLevelView{}
+	for _, level := range levels {
+		views = append(views, model.ViewStockLevel(level))
+	}
+	return views
+}
+
+// Keep the stock ledger in step with an item's available_quantity
+// attribute when the item is created or updated: for items sold
+// without variants, a change to the attribute sets the available
+// stock. Items with variants have their stock set per variant, so the
+// attribute is only a total and changes to it are ignored. Otherwise,
+// the attribute is rewritten from the ledger, in case the update
+// overwrote it with a stale value.
+func (s *Server) syncItemStock(item *model.Item, before *int) error {
+	if !stockControlled[item.ItemType] {
+		return nil
+	}
+	after := availableQuantity(item)
+	if after != nil && (before == nil || *after != *before) {
+		levels, err := s.db.StockLevels([]string{item.ID})
+		if err != nil {
+			return err
+		}
+		if !hasVariants(levels) {
+			level, err := s.db.SetStock(item.ID, "", *after)
+			if err != nil {
+				return err
+			}
+			s.emitStock([]model.StockLevel{*level}, events.StockAdjusted)
+			return nil
+		}
+		total := 0
+		for _, level := range levels {
+			total += level.Available()
+		}
+		item.Attrs["available_quantity"] = float64(total)
+	}
+	return s.db.SyncAvailableQuantity(item.ID)
+}
+
+// Determine whether an item is sold in variants from its stock levels.
+func hasVariants(levels []model.StockLevel) bool {
+	for _, level := range levels {
+		if level.Variant != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// The available_quantity attribute of an item, if it has one.
+func availableQuantity(item *model.Item) *int {
+	switch v := item.Attrs["available_quantity"].(type) {
+	case float64:
+		qty := int(v)
+		return &qty
+	case int:
+		return &v
+	}
+	return nil
+}
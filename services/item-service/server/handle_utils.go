@@ -38,6 +38,11 @@ func (s *Server) searchInfo(w http.ResponseWriter, r *http.Request) (interface{}
 		Description: item.Description,
 		Tags:        item.Tags,
 	}
+	inStock, err := s.inStock([]string{item.ID})
+	if err != nil {
+		return nil, err
+	}
+	resp.InStock = inStock[item.ID]
 	if c, ok := item.Attrs["content"]; ok {
 		content, ok := c.(string)
 		if ok {
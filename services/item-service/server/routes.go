@@ -32,6 +32,12 @@ func (s *Server) routes() chi.Router {
 	r.Post("/item/{id}/claim-ownership", chassis.SimpleHandler(s.claimItemOwnership))
 	r.Post("/item/{item_id}/links", chassis.SimpleHandler(s.createLink))
 	r.Get("/item/{item_id}/links", chassis.SimpleHandler(s.getItemLinks))
+	r.Get("/item/{id}/stock", chassis.SimpleHandler(s.getStock))
+	r.Put("/item/{id}/stock", chassis.SimpleHandler(s.setStock))
+	r.Get("/item/{id}/history", chassis.SimpleHandler(s.getItemHistory))
+	r.Get("/item/{id}/revision/{n}", chassis.SimpleHandler(s.getItemRevision))
+	r.Get("/item/{id}/diff", chassis.SimpleHandler(s.diffItemRevisions))
+	r.Post("/item/{id}/rollback", chassis.SimpleHandler(s.rollbackItem))
 
 	r.Get("/me/tags", chassis.SimpleHandler(s.tagsForUser))
 	r.Get("/me/items", chassis.SimpleHandler(s.itemsForUser))
@@ -68,8 +74,12 @@ func (s *Server) routes() chi.Router {
 
 	r.Get("/ids", chassis.SimpleHandler(s.ids))
 	r.Get("/search_info/{id}", chassis.SimpleHandler(s.searchInfo))
-	r.Patch("/internal/item/{id}", chassis.SimpleHandler(s.updateAvailability))
 	r.Get("/internal/info", chassis.SimpleHandler(s.getItemsBasicInfo))
+	r.Get("/internal/collections", chassis.SimpleHandler(s.getItemsCollections))
+	r.Get("/internal/stock", chassis.SimpleHandler(s.getStockLevels))
+	r.Post("/internal/stock/reservations", chassis.SimpleHandler(s.reserveStock))
+	r.Post("/internal/stock/reservation/{ref}/commit", chassis.SimpleHandler(s.commitReservation))
+	r.Post("/internal/stock/reservation/{ref}/release", chassis.SimpleHandler(s.releaseReservation))
 
 
 	return r
@@ -28,6 +28,7 @@ type Server struct {
 	imageBaseURL string
 	// merchantID                uint64 // hardcoded into content_api.go
 	contentAPICredentialsFile string
+	reservationTTL            time.Duration
 }
 
 // Config contains the configuration information needed to start
@@ -46,7 +47,9 @@ type Config struct {
 	SocialServiceURL   string `env:"SOCIAL_SERVICE_URL,default=http://social-service"`
 	ImageBaseURL       string `env:"IMAGE_BASE_URL"`
 	// MerchantID                uint64 `env:"MERCHANT_ID"`
-	ContentAPICredentialsFile string `env:"CONTENT_API_CREDENTIALS_FILE"`
+	ContentAPICredentialsFile string        `env:"CONTENT_API_CREDENTIALS_FILE"`
+	ReservationTTL            time.Duration `env:"STOCK_RESERVATION_TTL,default=15m"`
+	ReservationExpiryInterval time.Duration `env:"STOCK_RESERVATION_EXPIRY_INTERVAL,default=1m"`
 }
 
 // NewServer creates the server structure for the user service.
@@ -59,14 +62,18 @@ func NewServer(cfg *Config) *Server {
 
 	// Common server initialisation.
 	s := &Server{
-		blobSvc:      blob.New(cfg.BlobServiceURL),
 		searchSvc:    search.New(cfg.SearchServiceURL),
 		imageBaseURL: cfg.ImageBaseURL,
 		// merchantID:                cfg.MerchantID,
 		contentAPICredentialsFile: cfg.ContentAPICredentialsFile,
+		reservationTTL:            cfg.ReservationTTL,
 	}
 	s.Init(cfg.AppName, cfg.Project, cfg.Port, cfg.Credentials, s.routes())
 	var err error
+	s.blobSvc, err = blob.Connect(cfg.BlobServiceURL)
+	if err != nil {
+		log.Fatal().Err(err).Msg("couldn't initialise blob service client")
+	}
 	s.userSvc, err = user.New(cfg.UserServiceURL, s.PubSub, s.AppName)
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't initialise user service client")
@@ -75,10 +82,15 @@ func NewServer(cfg *Config) *Server {
 	s.socialSvc = social.New(cfg.SocialServiceURL)
 	// Connect to item database.
 	timeout, _ := context.WithTimeout(context.Background(), time.Second*10)
-	s.db, err = db.NewPGClient(timeout, cfg.DBURL)
+	pg, err := db.NewPGClient(timeout, cfg.DBURL)
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't connect to user database")
 	}
+	s.db = pg
+	s.StartEventRelay(pg.DB)
+	if cfg.ReservationExpiryInterval != 0 {
+		s.startReservationExpiry(cfg.ReservationExpiryInterval)
+	}
 
 	// Load JSON validation schemas.
 	model.LoadSchemas(s.categorySvc)
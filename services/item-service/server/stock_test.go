@@ -0,0 +1,91 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/veganbase/backend/services/item-service/db"
+	"github.com/veganbase/backend/services/item-service/model"
+	"github.com/veganbase/backend/services/item-service/model/types"
+)
+
+// stockDB keeps the stock levels of items in memory.
+type stockDB struct {
+	db.DB
+	levels []model.StockLevel
+	synced []string
+}
+
+func (d *stockDB) StockLevels(itemIDs []string) ([]model.StockLevel, error) {
+	levels := []model.StockLevel{}
+	for _, level := range d.levels {
+		for _, id := range itemIDs {
+			if level.ItemID == id {
+				levels = append(levels, level)
+			}
+		}
+	}
+	return levels, nil
+}
+
+func (d *stockDB) SetStock(itemID, variant string, available int) (*model.StockLevel, error) {
+	for i := range d.levels {
+		if d.levels[i].ItemID == itemID && d.levels[i].Variant == variant {
+			d.levels[i].OnHand = d.levels[i].Reserved + available
+			return &d.levels[i], nil
+		}
+	}
+	d.levels = append(d.levels, model.StockLevel{ItemID: itemID, Variant: variant, OnHand: available})
+	return &d.levels[len(d.levels)-1], nil
+}
+
+func (d *stockDB) SyncAvailableQuantity(itemID string) error {
+	d.synced = append(d.synced, itemID)
+	return nil
+}
+
+func (d *stockDB) SaveEvent(topic string, eventData interface{}, inTx func() error) error {
+	return nil
+}
+
+func TestSyncItemStock(t *testing.T) {
+	before := func(qty int) *int { return &qty }
+	item := func(id string, qty float64) *model.Item {
+		return &model.Item{ID: id, ItemType: model.ProductOfferingItem,
+			Attrs: types.AttrMap{"available_quantity": qty}}
+	}
+	d := &stockDB{levels: []model.StockLevel{
+		{ItemID: "itm_PLAIN", OnHand: 5, Reserved: 1},
+		{ItemID: "itm_SIZES", Variant: "large", OnHand: 3, Reserved: 1},
+		{ItemID: "itm_SIZES", Variant: "small", OnHand: 4},
+	}}
+	s := &Server{db: d}
+
+	// Changing the quantity of an item without variants sets its stock.
+	assert.Nil(t, s.syncItemStock(item("itm_PLAIN", 10), before(4)))
+	assert.Equal(t, model.StockLevel{ItemID: "itm_PLAIN", OnHand: 11, Reserved: 1}, d.levels[0])
+	assert.Empty(t, d.synced)
+
+	// New items start off their stock.
+	assert.Nil(t, s.syncItemStock(item("itm_NEW", 2), nil))
+	assert.Equal(t, model.StockLevel{ItemID: "itm_NEW", OnHand: 2}, d.levels[3])
+
+	// Items with variants don't get a default variant: the quantity is
+	// put back to the total available.
+	sizes := item("itm_SIZES", 20)
+	assert.Nil(t, s.syncItemStock(sizes, before(6)))
+	assert.Len(t, d.levels, 4)
+	assert.Equal(t, 6.0, sizes.Attrs["available_quantity"])
+	assert.Equal(t, []string{"itm_SIZES"}, d.synced)
+
+	// Unchanged quantities are rewritten from the ledger.
+	assert.Nil(t, s.syncItemStock(item("itm_PLAIN", 10), before(10)))
+	assert.Equal(t, []string{"itm_SIZES", "itm_PLAIN"}, d.synced)
+	assert.Equal(t, 11, d.levels[0].OnHand)
+
+	// Items without stock control are left alone.
+	post := &model.Item{ID: "itm_POST", ItemType: model.PostItem, Attrs: types.AttrMap{}}
+	assert.Nil(t, s.syncItemStock(post, nil))
+	assert.Len(t, d.synced, 2)
+}
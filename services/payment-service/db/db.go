@@ -2,6 +2,8 @@ package db
 
 import (
 	"errors"
+	"time"
+
 	"github.com/veganbase/backend/services/payment-service/model"
 )
 
@@ -17,6 +19,18 @@ var ErrPendingEventNotFound = errors.New("pending event not found")
 
 var ErrPendingTransferNotFound = errors.New("pending transfer not found")
 
+// ErrRefundNotFound is the error returned when an attempt is made to
+// access or manipulate a refund with an unknown ID.
+var ErrRefundNotFound = errors.New("refund not found")
+
+// ErrLedgerTransactionNotFound is the error returned when an attempt is
+// made to update a ledger transaction with an unknown ID.
+var ErrLedgerTransactionNotFound = errors.New("ledger transaction not found")
+
+// ErrUnbalancedTransaction is the error returned when an attempt is
+// made to record a ledger transaction whose entries don't sum to zero.
+var ErrUnbalancedTransaction = errors.New("ledger transaction entries don't balance")
+
 // DB describes the database operations used by the payment service.
 type DB interface {
 	// PaymentIntents
@@ -31,6 +45,24 @@ type DB interface {
 	TransfersByOrigin(origin string) (*[]model.Transfer, error)
 	CreateTransfer(tr *model.Transfer) error
 	CreateTransfers(remainder *model.TransferRemainder, origin string) error
+	TransferRemaindersBySale(saleId string) (*[]model.TransferRemainder, error)
+
+	//Refunds
+	RefundsByOrigin(origin string) (*[]model.Refund, error)
+	RefundByRefundId(id string) (*model.Refund, error)
+	CreateRefund(refund *model.Refund) error
+	UpdateRefund(refund *model.Refund) error
+	LockSales(saleIds []string, locked func() error) error
+	CreateTransferReversal(rev *model.TransferReversal) error
+
+	//Ledger
+	CreateLedgerTransactions(txs []*model.LedgerTransaction) error
+	LedgerTransactionsBySale(saleId string) (*[]model.LedgerTransaction, error)
+	SellerLedgerTotals(seller string) (*[]model.LedgerTotal, error)
+	SellerLedgerLines(seller string, from, to time.Time) (*[]model.LedgerLine, error)
+	LedgerTransfersToReconcile(since time.Time) (*[]model.LedgerTransaction, error)
+	LedgerReversedByTransfer(transferId string) (int, error)
+	UpdateLedgerReconciliation(id int64, discrepancy *string) error
 
 	//Audit
 	ReceivedEventByEventId(id string) (*model.ReceivedEvent, error)
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/veganbase/backend/services/payment-service/model"
+)
+
+// CreateLedgerTransactions records ledger transactions and their
+// entries together. Transactions already recorded (with the same kind,
+// reference and sale) are skipped, so the same postings can be made
+// again safely when events are handled more than once.
+func (pg *PGClient) CreateLedgerTransactions(txs []*model.LedgerTransaction) error {
+	for _, lt := range txs {
+		if !lt.Balanced() {
+			return ErrUnbalancedTransaction
+		}
+	}
+
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	for _, lt := range txs {
+		var rows *sqlx.Rows
+		rows, err = tx.NamedQuery(qCreateLedgerTransaction, lt)
+		if err != nil {
+			return err
+		}
+		created := rows.Next()
+		if created {
+			if err = rows.Scan(&lt.ID, &lt.CreatedAt); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		if err = rows.Close(); err != nil {
+			return err
+		}
+		if !created {
+			continue
+		}
+
+		for i := range lt.Entries {
+			lt.Entries[i].TransactionID = lt.ID
+			if _, err = tx.NamedExec(qCreateLedgerEntry, lt.Entries[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return err
+}
+
+const qCreateLedgerTransaction = `
+INSERT INTO
+	ledger_transactions (kind, seller, origin, sale_id, reference, currency, amount, description)
+VALUES (:kind, :seller, :origin, :sale_id, :reference, :currency, :amount, :description)
+ON CONFLICT (kind, reference, COALESCE(sale_id, '')) DO NOTHING
+RETURNING id, created_at`
+
+const qCreateLedgerEntry = `
+INSERT INTO
+	ledger_entries (transaction_id, account, owner, amount)
+VALUES (:transaction_id, :account, :owner, :amount)`
+
+const qLedgerTransactionBy = `
+SELECT id, kind, seller, origin, sale_id, reference, currency, amount, description,
+       reconciled_at, discrepancy, created_at
+FROM ledger_transactions WHERE `
+
+// LedgerTransactionsBySale retrieves the ledger transactions recorded
+// for an order or booking, without their entries.
+func (pg *PGClient) LedgerTransactionsBySale(saleId string) (*[]model.LedgerTransaction, error) {
+	txs := &[]model.LedgerTransaction{}
+	err := sqlx.Select(pg.DB, txs, qLedgerTransactionBy+`sale_id = $1 ORDER BY id`, saleId)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// SellerLedgerTotals sums up a seller's ledger transactions by month,
+// currency and kind, as credits to the seller's account.
+func (pg *PGClient) SellerLedgerTotals(seller string) (*[]model.LedgerTotal, error) {
+	totals := &[]model.LedgerTotal{}
+	err := sqlx.Select(pg.DB, totals, qSellerLedgerTotals, model.AccountSeller, seller)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return totals, nil
+}
+
+const qSellerLedgerTotals = `
+SELECT date_trunc('month', t.created_at AT TIME ZONE 'UTC') AS month, t.currency, t.kind,
+       -SUM(e.amount) AS amount
+  FROM ledger_entries e JOIN ledger_transactions t ON t.id = e.transaction_id
+ WHERE e.account = $1 AND e.owner = $2
+ GROUP BY 1, 2, 3
+ ORDER BY 1, 2, 3`
+
+// SellerLedgerLines retrieves the ledger transactions affecting a
+// seller's account between two times, with the amount credited to the
+// account.
+func (pg *PGClient) SellerLedgerLines(seller string, from, to time.Time) (*[]model.LedgerLine, error) {
+	lines := &[]model.LedgerLine{}
+	err := sqlx.Select(pg.DB, lines, qSellerLedgerLines, model.AccountSeller, seller, from, to)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return lines, nil
+}
+
+const qSellerLedgerLines = `
+SELECT t.id, t.kind, t.origin, t.sale_id, t.reference, t.currency, -SUM(e.amount) AS amount,
+       t.description, t.created_at
+  FROM ledger_entries e JOIN ledger_transactions t ON t.id = e.transaction_id
+ WHERE e.account = $1 AND e.owner = $2 AND t.created_at >= $3 AND t.created_at < $4
+ GROUP BY t.id
+ ORDER BY t.created_at, t.id`
+
+// LedgerTransfersToReconcile retrieves the transfers recorded in the
+// ledger that need checking against Stripe: those never checked, and
+// those made since a given time, which may still be reversed.
+func (pg *PGClient) LedgerTransfersToReconcile(since time.Time) (*[]model.LedgerTransaction, error) {
+	txs := &[]model.LedgerTransaction{}
+	err := sqlx.Select(pg.DB, txs, qLedgerTransactionBy+
+		`kind = $1 AND (reconciled_at IS NULL OR created_at >= $2) ORDER BY id`,
+		model.LedgerTransfer, since)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// LedgerReversedByTransfer returns the total of the reversals of a
+// transfer recorded in the ledger.
+func (pg *PGClient) LedgerReversedByTransfer(transferId string) (int, error) {
+	total := 0
+	err := sqlx.Get(pg.DB, &total, qLedgerReversedByTransfer, model.LedgerReversal, transferId)
+	return total, err
+}
+
+const qLedgerReversedByTransfer = `
+SELECT COALESCE(SUM(t.amount), 0)
+  FROM ledger_transactions t JOIN transfer_reversals r ON r.reversal_id = t.reference
+ WHERE t.kind = $1 AND r.transfer_id = $2`
+
+// UpdateLedgerReconciliation records the result of checking a ledger
+// transaction against Stripe: the discrepancy found, or nil if the two
+// agree.
+func (pg *PGClient) UpdateLedgerReconciliation(id int64, discrepancy *string) error {
+	result, err := pg.DB.Exec(qUpdateLedgerReconciliation, id, discrepancy)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrLedgerTransactionNotFound
+	}
+	return nil
+}
+
+const qUpdateLedgerReconciliation = `
+UPDATE ledger_transactions
+SET reconciled_at = now(), discrepancy = $2
+WHERE id = $1`
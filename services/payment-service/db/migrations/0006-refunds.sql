@@ -0,0 +1,56 @@
+-- +migrate Up
+
+SET ROLE vb_payments;
+
+-- Transfers are linked to the order or booking they pay out, so that
+-- they can be reversed when the order or booking is refunded.
+-- Transfers made before this migration have no sale ID and have to be
+-- reversed by hand.
+ALTER TABLE transfer_remainders
+  ADD COLUMN origin         VARCHAR(24) NOT NULL DEFAULT '',
+  ADD COLUMN sale_id        VARCHAR(24),
+  ADD COLUMN reversed_value INTEGER     NOT NULL DEFAULT 0;
+CREATE INDEX transfer_remainders_sale_idx ON transfer_remainders(sale_id);
+
+ALTER TABLE pending_transfers ADD COLUMN sale_id VARCHAR(24);
+
+CREATE TABLE refunds
+(
+    refund_id   TEXT PRIMARY KEY,
+    origin      VARCHAR(24) NOT NULL, -- purchase ID
+    sale_id     VARCHAR(24),          -- order or booking ID (NULL if made outside the service)
+    intent_id   TEXT        NOT NULL,
+    currency    VARCHAR(3)  NOT NULL,
+    amount      INTEGER     NOT NULL,
+    status      TEXT        NOT NULL,
+    reason      TEXT,
+    refunded_by TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_update TIMESTAMPTZ
+);
+CREATE INDEX refunds_origin_idx ON refunds(origin);
+
+CREATE TABLE transfer_reversals
+(
+    reversal_id TEXT PRIMARY KEY,
+    transfer_id TEXT        NOT NULL REFERENCES transfer_remainders(transfer_id),
+    refund_id   TEXT        NOT NULL REFERENCES refunds(refund_id),
+    amount      INTEGER     NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+
+-- +migrate Down
+
+SET ROLE vb_payments;
+
+DROP TABLE transfer_reversals;
+DROP TABLE refunds;
+
+ALTER TABLE pending_transfers DROP COLUMN sale_id;
+
+DROP INDEX transfer_remainders_sale_idx;
+ALTER TABLE transfer_remainders
+  DROP COLUMN origin,
+  DROP COLUMN sale_id,
+  DROP COLUMN reversed_value;
@@ -0,0 +1,16 @@
+-- +migrate Up
+
+SET ROLE vb_payments;
+
+-- Tax collected by sellers is paid out to them in full, with no fee
+-- taken, and recorded so that payouts can be reconciled with sellers'
+-- tax returns.
+ALTER TABLE transfer_remainders ADD COLUMN tax_value INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE pending_transfers ADD COLUMN tax_value INTEGER NOT NULL DEFAULT 0;
+
+-- +migrate Down
+
+SET ROLE vb_payments;
+
+ALTER TABLE transfer_remainders DROP COLUMN tax_value;
+ALTER TABLE pending_transfers DROP COLUMN tax_value;
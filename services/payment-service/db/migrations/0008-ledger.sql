@@ -0,0 +1,136 @@
+-- +migrate Up
+
+SET ROLE vb_payments;
+
+-- Double-entry ledger of the money flowing through the platform for
+-- each seller. Every transaction has entries that sum to zero, with
+-- debits positive and credits negative, against these accounts:
+--
+--   cash        funds in the platform's Stripe balance
+--   fees        fees earned by the platform
+--   remainders  fractions of a cent left over when fees are taken
+--   seller      funds owed to a seller (owner is the user or org ID)
+CREATE TABLE ledger_transactions
+(
+    id            BIGSERIAL PRIMARY KEY,
+    kind          TEXT        NOT NULL, -- charge, fee, remainder, transfer, refund, refund_failed, reversal
+    seller        TEXT        NOT NULL,
+    origin        VARCHAR(24) NOT NULL DEFAULT '', -- purchase ID
+    sale_id       VARCHAR(24),                     -- order or booking ID
+    reference     TEXT        NOT NULL,            -- Stripe charge, transfer, refund or reversal ID
+    currency      VARCHAR(3)  NOT NULL,
+    amount        INTEGER     NOT NULL,
+    description   TEXT        NOT NULL DEFAULT '',
+    reconciled_at TIMESTAMPTZ,
+    discrepancy   TEXT,                            -- disagreement with Stripe found by reconciliation
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX ledger_transactions_reference_idx
+  ON ledger_transactions(kind, reference, COALESCE(sale_id, ''));
+CREATE INDEX ledger_transactions_sale_idx ON ledger_transactions(sale_id);
+CREATE INDEX ledger_transactions_seller_idx ON ledger_transactions(seller, created_at);
+
+CREATE TABLE ledger_entries
+(
+    id             BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT  NOT NULL REFERENCES ledger_transactions(id) ON DELETE CASCADE,
+    account        TEXT    NOT NULL,
+    owner          TEXT    NOT NULL DEFAULT '',
+    amount         INTEGER NOT NULL
+);
+CREATE INDEX ledger_entries_transaction_idx ON ledger_entries(transaction_id);
+CREATE INDEX ledger_entries_account_idx ON ledger_entries(account, owner);
+
+-- Record what has already happened. Transfers made before sale IDs
+-- were recorded, or queued with the purchase ID as their destination,
+-- can't be attributed to a seller and are left out.
+WITH sales AS (
+  SELECT transfer_id AS reference, origin, sale_id, destination AS seller, currency,
+         total_value, fee_value, transferred_value, created_at
+    FROM transfer_remainders
+   WHERE destination ~ '^(usr|org)_'
+  UNION ALL
+  SELECT source_transaction, origin, sale_id, destination, currency,
+         total_value, fee_value, transferred_value, created_at
+    FROM pending_transfers
+   WHERE destination ~ '^(usr|org)_'
+), t AS (
+  INSERT INTO ledger_transactions (kind, seller, origin, sale_id, reference, currency, amount, description, created_at)
+  SELECT 'charge', seller, origin, sale_id, reference, currency, total_value, 'Sale', created_at FROM sales
+  UNION ALL
+  SELECT 'fee', seller, origin, sale_id, reference, currency, fee_value, 'Platform fee', created_at
+    FROM sales WHERE fee_value <> 0
+  UNION ALL
+  SELECT 'remainder', seller, origin, sale_id, reference, currency,
+         total_value - fee_value - transferred_value, 'Rounding', created_at
+    FROM sales WHERE total_value - fee_value - transferred_value <> 0
+  ON CONFLICT DO NOTHING
+  RETURNING id, kind, seller, amount
+)
+INSERT INTO ledger_entries (transaction_id, account, owner, amount)
+SELECT id, CASE kind WHEN 'charge' THEN 'cash' ELSE 'seller' END,
+       CASE kind WHEN 'charge' THEN '' ELSE seller END, amount
+  FROM t
+UNION ALL
+SELECT id, CASE kind WHEN 'charge' THEN 'seller' WHEN 'fee' THEN 'fees' ELSE 'remainders' END,
+       CASE kind WHEN 'charge' THEN seller ELSE '' END, -amount
+  FROM t;
+
+WITH t AS (
+  INSERT INTO ledger_transactions (kind, seller, origin, sale_id, reference, currency, amount, description, created_at)
+  SELECT 'transfer', destination, origin, sale_id, transfer_id, currency, transferred_value,
+         'Transfer to ' || destination_account, created_at
+    FROM transfer_remainders
+   WHERE destination ~ '^(usr|org)_'
+  ON CONFLICT DO NOTHING
+  RETURNING id, seller, amount
+)
+INSERT INTO ledger_entries (transaction_id, account, owner, amount)
+SELECT id, 'seller', seller, amount FROM t
+UNION ALL
+SELECT id, 'cash', '', -amount FROM t;
+
+-- The sellers' share of refunds made so far is what was reversed from
+-- their transfers, and the platform gives back the rest.
+WITH r AS (
+  SELECT rf.refund_id, rf.origin, rf.sale_id, rf.currency, rf.amount, rf.created_at,
+         (SELECT tr.destination FROM transfer_remainders tr
+           WHERE tr.sale_id = rf.sale_id AND tr.destination ~ '^(usr|org)_' LIMIT 1) AS seller,
+         (SELECT COALESCE(SUM(rv.amount), 0) FROM transfer_reversals rv
+           WHERE rv.refund_id = rf.refund_id) AS seller_share
+    FROM refunds rf
+   WHERE rf.sale_id IS NOT NULL AND rf.status NOT IN ('failed', 'canceled')
+), t AS (
+  INSERT INTO ledger_transactions (kind, seller, origin, sale_id, reference, currency, amount, description, created_at)
+  SELECT 'refund', seller, origin, sale_id, refund_id, currency, amount, 'Refund', created_at
+    FROM r WHERE seller IS NOT NULL
+  ON CONFLICT DO NOTHING
+  RETURNING id, seller, reference, amount
+)
+INSERT INTO ledger_entries (transaction_id, account, owner, amount)
+SELECT t.id, 'cash', '', -t.amount FROM t
+UNION ALL
+SELECT t.id, 'seller', t.seller, r.seller_share FROM t JOIN r ON r.refund_id = t.reference
+UNION ALL
+SELECT t.id, 'fees', '', t.amount - r.seller_share FROM t JOIN r ON r.refund_id = t.reference;
+
+WITH t AS (
+  INSERT INTO ledger_transactions (kind, seller, origin, sale_id, reference, currency, amount, description, created_at)
+  SELECT 'reversal', tr.destination, tr.origin, tr.sale_id, rv.reversal_id, tr.currency, rv.amount,
+         'Transfer reversed for refund ' || rv.refund_id, rv.created_at
+    FROM transfer_reversals rv JOIN transfer_remainders tr ON tr.transfer_id = rv.transfer_id
+   WHERE tr.destination ~ '^(usr|org)_'
+  ON CONFLICT DO NOTHING
+  RETURNING id, seller, amount
+)
+INSERT INTO ledger_entries (transaction_id, account, owner, amount)
+SELECT id, 'cash', '', amount FROM t
+UNION ALL
+SELECT id, 'seller', seller, -amount FROM t;
+
+-- +migrate Down
+
+SET ROLE vb_payments;
+
+DROP TABLE ledger_entries;
+DROP TABLE ledger_transactions;
@@ -170,8 +170,8 @@ func (pg *PGClient) PendingTransfers() (*[]model.PendingTransfer, error) {
 //}
 
 const qPendingTransferBy = `
-SELECT id, origin, destination, currency, source_transaction, total_value, fee_value, transferred_value, 
-       fee_remainder, transferred_remainder, reason, created_at
+SELECT id, origin, sale_id, destination, currency, source_transaction, total_value, tax_value, fee_value,
+       transferred_value, fee_remainder, transferred_remainder, reason, created_at
 FROM pending_transfers WHERE `
 
 func (pg *PGClient) CreatePendingTransfer(pt *model.PendingTransfer) error {
@@ -205,10 +205,10 @@ func (pg *PGClient) CreatePendingTransfer(pt *model.PendingTransfer) error {
 }
 
 const qCreatePendingTransfer = `
-INSERT INTO	pending_transfers (origin, destination, currency, source_transaction,
-                               total_value, fee_value, transferred_value, 
+INSERT INTO	pending_transfers (origin, sale_id, destination, currency, source_transaction,
+                               total_value, tax_value, fee_value, transferred_value,
                                fee_remainder, transferred_remainder, reason)
-VALUES (:origin, :destination, :currency, :source_transaction, :total_value, :fee_value,
+VALUES (:origin, :sale_id, :destination, :currency, :source_transaction, :total_value, :tax_value, :fee_value,
         :transferred_value, :fee_remainder, :transferred_remainder, :reason)
 ON CONFLICT DO NOTHING
 RETURNING created_at`
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/veganbase/backend/services/payment-service/model"
+)
+
+const qRefundBy = `
+SELECT refund_id, origin, sale_id, intent_id, currency, amount, status, reason, refunded_by,
+       created_at, last_update
+FROM refunds WHERE `
+
+// RefundsByOrigin retrieves all the refunds made for a purchase.
+func (pg *PGClient) RefundsByOrigin(origin string) (*[]model.Refund, error) {
+	refunds := &[]model.Refund{}
+	err := sqlx.Select(pg.DB, refunds, qRefundBy+`origin = $1 ORDER BY created_at`, origin)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// RefundByRefundId retrieves a refund by its Stripe ID.
+func (pg *PGClient) RefundByRefundId(id string) (*model.Refund, error) {
+	refund := &model.Refund{}
+	if err := sqlx.Get(pg.DB, refund, qRefundBy+`refund_id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+	return refund, nil
+}
+
+// CreateRefund records a refund. The webhook event for a refund can
+// arrive before the refund made through the service is recorded, so
+// an existing entry for the refund is updated with the sale
+// information instead of being duplicated.
+func (pg *PGClient) CreateRefund(refund *model.Refund) error {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	rows, err := tx.NamedQuery(qCreateRefund, refund)
+	if err != nil {
+		return err
+	}
+	if rows.Next() {
+		if err = rows.Scan(&refund.CreatedAt); err != nil {
+			return err
+		}
+		if err = rows.Close(); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+const qCreateRefund = `
+INSERT INTO
+	refunds (refund_id, origin, sale_id, intent_id, currency, amount, status, reason, refunded_by)
+VALUES (:refund_id, :origin, :sale_id, :intent_id, :currency, :amount, :status, :reason, :refunded_by)
+ON CONFLICT (refund_id) DO UPDATE
+SET sale_id = COALESCE(EXCLUDED.sale_id, refunds.sale_id),
+    reason = COALESCE(EXCLUDED.reason, refunds.reason),
+    refunded_by = COALESCE(EXCLUDED.refunded_by, refunds.refunded_by)
+RETURNING created_at`
+
+// UpdateRefund updates the status of a refund.
+func (pg *PGClient) UpdateRefund(refund *model.Refund) error {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	check := &model.Refund{}
+	err = tx.Get(check, qRefundBy+`refund_id = $1`, refund.RefundId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRefundNotFound
+		}
+		return err
+	}
+
+	rows, err := tx.NamedQuery(qUpdateRefund, refund)
+	if err != nil {
+		return err
+	}
+	if rows.Next() {
+		if err = rows.Scan(&refund.LastUpdate); err != nil {
+			return err
+		}
+		if err = rows.Close(); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+const qUpdateRefund = `
+UPDATE refunds
+SET status = :status, last_update = now()
+WHERE refund_id = :refund_id
+RETURNING last_update`
+
+// CreateTransferReversal records a transfer reversal, adding the
+// reversed value to the transfer's remainder entry.
+func (pg *PGClient) CreateTransferReversal(rev *model.TransferReversal) error {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	rows, err := tx.NamedQuery(qCreateTransferReversal, rev)
+	if err != nil {
+		return err
+	}
+	if rows.Next() {
+		if err = rows.Scan(&rev.CreatedAt); err != nil {
+			return err
+		}
+		if err = rows.Close(); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(qAddReversedValue, rev.Amount, rev.TransferId)
+	return err
+}
+
+const qCreateTransferReversal = `
+INSERT INTO
+	transfer_reversals (reversal_id, transfer_id, refund_id, amount)
+VALUES (:reversal_id, :transfer_id, :refund_id, :amount)
+RETURNING created_at`
+
+const qAddReversedValue = `
+UPDATE transfer_remainders
+SET reversed_value = reversed_value + $1
+WHERE transfer_id = $2`
+
+// LockSales runs a function while holding locks on a set of orders
+// and bookings, so that refunds for the same sale are worked out and
+// made one at a time. The locks are transaction-level advisory locks,
+// taken in a fixed order to avoid deadlocks, and are released when the
+// function returns.
+func (pg *PGClient) LockSales(saleIds []string, locked func() error) error {
+	ids := append([]string{}, saleIds...)
+	sort.Strings(ids)
+
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	for _, id := range ids {
+		if _, err = tx.Exec(qLockSale, "refund:"+id); err != nil {
+			return err
+		}
+	}
+	err = locked()
+	return err
+}
+
+const qLockSale = `SELECT pg_advisory_xact_lock(hashtext($1))`
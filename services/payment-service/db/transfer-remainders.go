@@ -1,6 +1,26 @@
 package db
 
-import "github.com/veganbase/backend/services/payment-service/model"
+import (
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"github.com/veganbase/backend/services/payment-service/model"
+)
+
+const qTransferRemainderBy = `
+SELECT transfer_id, origin, sale_id, destination, destination_account, currency, total_value, tax_value,
+       fee_value, transferred_value, fee_remainder, transferred_remainder, reversed_value, created_at
+FROM transfer_remainders WHERE `
+
+// TransferRemaindersBySale retrieves the transfers made to pay out an
+// order or booking.
+func (pg *PGClient) TransferRemaindersBySale(saleId string) (*[]model.TransferRemainder, error) {
+	remainders := &[]model.TransferRemainder{}
+	err := sqlx.Select(pg.DB, remainders, qTransferRemainderBy+`sale_id = $1 ORDER BY created_at`, saleId)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return remainders, nil
+}
 
 func (pg *PGClient) CreateTransferRemainder(remainder *model.TransferRemainder) error {
 	tx, err := pg.DB.Beginx()
@@ -34,8 +54,8 @@ func (pg *PGClient) CreateTransferRemainder(remainder *model.TransferRemainder)
 
 const qCreateTransferRemainder = `
 INSERT INTO
-	transfer_remainders ( transfer_id, destination,currency, destination_account, total_value, transferred_value, fee_value, fee_remainder, transferred_remainder)
-VALUES (:transfer_id, :destination, :currency, :destination_account, :total_value, :transferred_value, :fee_value, :fee_remainder, :transferred_remainder)
+	transfer_remainders ( transfer_id, origin, sale_id, destination,currency, destination_account, total_value, tax_value, transferred_value, fee_value, fee_remainder, transferred_remainder)
+VALUES (:transfer_id, :origin, :sale_id, :destination, :currency, :destination_account, :total_value, :tax_value, :transferred_value, :fee_value, :fee_remainder, :transferred_remainder)
 ON CONFLICT DO NOTHING
 RETURNING created_at`
 
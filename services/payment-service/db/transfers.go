@@ -83,7 +83,9 @@ func (pg *PGClient) CreateTransfers(remainder *model.TransferRemainder, origin s
 		}
 	}()
 
+	remainder.Origin = origin
 	transfer := model.Transfer{
+		TransferId:         remainder.TransferId,
 		Origin:             origin,
 		DestinationAccount: remainder.DestinationAccount,
 		Destination:        remainder.Destination,
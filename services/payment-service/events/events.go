@@ -19,6 +19,8 @@ const (
 	PaymentStatusTopic   = "payment-status-topic"
 	PaymentReceivedTopic = "payment-received-topic"
 	SaleCompleteTopic    = "sale-complete-topic"
+	RefundIssuedTopic    = "refund-issued-topic"
+	SaleRefundedTopic    = "sale-refunded-topic"
 )
 
 func BuildOrderPlacedEventPayload(ord purModel.Order) json.RawMessage {
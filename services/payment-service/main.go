@@ -21,5 +21,6 @@ func main() {
 	serv := server.NewServer(&cfg)
 	go serv.HandlePendingTransfers()
 	go serv.HandlePendingEvents()
+	go serv.ReconcileLedger()
 	serv.Serve()
 }
@@ -0,0 +1,153 @@
+package model
+
+import (
+	"time"
+)
+
+// Ledger accounts. Seller accounts are held for each seller, with the
+// seller's user or org ID as their owner; platform accounts have no
+// owner.
+const (
+	AccountCash       = "cash"       //Funds in the platform's Stripe balance
+	AccountFees       = "fees"       //Fees earned by the platform
+	AccountRemainders = "remainders" //Fractions of a cent left over when fees are taken
+	AccountSeller     = "seller"     //Funds owed to a seller
+)
+
+// Kinds of ledger transaction.
+const (
+	LedgerCharge       = "charge"        //Buyer's payment for a sale
+	LedgerFee          = "fee"           //Platform fee taken from a sale
+	LedgerRemainder    = "remainder"     //Rounding left over after the fee is taken
+	LedgerTransfer     = "transfer"      //Payout to the seller's connected account
+	LedgerRefund       = "refund"        //Refund of all or part of a sale
+	LedgerRefundFailed = "refund_failed" //Refund that failed after being recorded
+	LedgerReversal     = "reversal"      //Transfer taken back from the seller for a refund
+)
+
+// LedgerTransaction is a movement of money between ledger accounts for
+// one seller. The amounts of its entries always sum to zero.
+type LedgerTransaction struct {
+	ID           int64         `db:"id" json:"id"`
+	Kind         string        `db:"kind" json:"kind"`
+	Seller       string        `db:"seller" json:"seller"`       //User or org ID
+	Origin       string        `db:"origin" json:"origin"`       //Purchase ID
+	SaleId       *string       `db:"sale_id" json:"sale_id"`     //Order or booking ID
+	Reference    string        `db:"reference" json:"reference"` //Stripe charge, transfer, refund or reversal ID
+	Currency     string        `db:"currency" json:"currency"`
+	Amount       int           `db:"amount" json:"amount"` //Value moved, always positive
+	Description  string        `db:"description" json:"description"`
+	ReconciledAt *time.Time    `db:"reconciled_at" json:"reconciled_at,omitempty"`
+	Discrepancy  *string       `db:"discrepancy" json:"discrepancy,omitempty"` //Disagreement with Stripe's records
+	CreatedAt    time.Time     `db:"created_at" json:"created_at"`
+	Entries      []LedgerEntry `db:"-" json:"entries,omitempty"`
+}
+
+// LedgerEntry is a debit (positive amount) or credit (negative
+// amount) to a ledger account.
+type LedgerEntry struct {
+	ID            int64  `db:"id" json:"-"`
+	TransactionID int64  `db:"transaction_id" json:"-"`
+	Account       string `db:"account" json:"account"`
+	Owner         string `db:"owner" json:"owner,omitempty"`
+	Amount        int    `db:"amount" json:"amount"`
+}
+
+// Balanced reports whether the entries of a transaction sum to zero.
+func (tx *LedgerTransaction) Balanced() bool {
+	total := 0
+	for _, e := range tx.Entries {
+		total += e.Amount
+	}
+	return total == 0
+}
+
+// LedgerTotal is the total effect of a seller's ledger transactions
+// of one kind in one currency during a month, as a credit to the
+// seller's account.
+type LedgerTotal struct {
+	Month    time.Time `db:"month"`
+	Currency string    `db:"currency"`
+	Kind     string    `db:"kind"`
+	Amount   int       `db:"amount"`
+}
+
+// LedgerLine is a ledger transaction as it appears in a seller's
+// statement, with the amount credited to the seller's account
+// (negative for debits).
+type LedgerLine struct {
+	TransactionID int64     `db:"id" json:"id"`
+	Kind          string    `db:"kind" json:"kind"`
+	Origin        string    `db:"origin" json:"origin"`
+	SaleId        *string   `db:"sale_id" json:"sale_id"`
+	Reference     string    `db:"reference" json:"reference"`
+	Currency      string    `db:"currency" json:"currency"`
+	Amount        int       `db:"amount" json:"amount"`
+	Description   string    `db:"description" json:"description"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+}
+
+// LedgerActivity sums up the ledger transactions of a seller in one
+// currency. All the amounts are positive: the balance owed to the
+// seller is the sales less fees, rounding, refunds and transfers, plus
+// transfers reversed.
+type LedgerActivity struct {
+	Sales       int `json:"sales"`
+	Fees        int `json:"fees"`
+	Rounding    int `json:"rounding"`
+	Refunds     int `json:"refunds"`
+	Transferred int `json:"transferred"`
+	Reversed    int `json:"reversed"`
+}
+
+// Add adds the amount credited to a seller's account by a transaction
+// of the given kind.
+func (a *LedgerActivity) Add(kind string, amount int) {
+	switch kind {
+	case LedgerCharge:
+		a.Sales += amount
+	case LedgerFee:
+		a.Fees -= amount
+	case LedgerRemainder:
+		a.Rounding -= amount
+	case LedgerRefund, LedgerRefundFailed:
+		a.Refunds -= amount
+	case LedgerTransfer:
+		a.Transferred -= amount
+	case LedgerReversal:
+		a.Reversed += amount
+	}
+}
+
+// Net returns the change in the balance owed to the seller.
+func (a *LedgerActivity) Net() int {
+	return a.Sales - a.Fees - a.Rounding - a.Refunds - a.Transferred + a.Reversed
+}
+
+// SellerBalance is the balance held for a seller in one currency,
+// awaiting transfer to their payout account, with their activity to
+// date.
+type SellerBalance struct {
+	Currency string `json:"currency"`
+	Balance  int    `json:"balance"`
+	LedgerActivity
+}
+
+// PayoutStatement is a seller's monthly statement, with their
+// activity in each currency and, for a single statement, the ledger
+// lines making it up.
+type PayoutStatement struct {
+	Seller     string              `json:"seller"`
+	Month      string              `json:"month"` //YYYY-MM
+	Currencies []StatementCurrency `json:"currencies"`
+	Lines      []LedgerLine        `json:"lines,omitempty"`
+}
+
+// StatementCurrency is a seller's activity in one currency during the
+// month of a statement.
+type StatementCurrency struct {
+	Currency       string `json:"currency"`
+	OpeningBalance int    `json:"opening_balance"`
+	LedgerActivity
+	ClosingBalance int `json:"closing_balance"`
+}
@@ -14,10 +14,12 @@ type PendingEvent struct {
 type PendingTransfer struct {
 	ID                   int64     `db:"id"`
 	Origin               string    `db:"origin"`
+	SaleId               *string   `db:"sale_id"`
 	Destination          string    `db:"destination"`
 	Currency             string    `db:"currency"`
 	SourceTransaction    string    `db:"source_transaction"`
 	TotalValue           int       `db:"total_value"`
+	TaxValue             int       `db:"tax_value"`
 	FeeValue             int       `db:"fee_value"`
 	TransferredValue     int       `db:"transferred_value"`
 	FeeRemainder         float64   `db:"fee_remainder"`
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// Refund is a refund of all or part of a payment, recorded against the
+// order or booking being refunded.
+type Refund struct {
+	RefundId   string     `db:"refund_id" json:"refund_id"`     //Stripe's refund ID
+	Origin     string     `db:"origin" json:"origin"`           //Purchase ID
+	SaleId     *string    `db:"sale_id" json:"sale_id"`         //Order or booking ID, nil if refunded outside the service
+	IntentId   string     `db:"intent_id" json:"intent_id"`     //Stripe's payment intent ID
+	Currency   string     `db:"currency" json:"currency"`       //Currency of the refunded payment
+	Amount     int        `db:"amount" json:"amount"`           //Value given back to the buyer
+	Status     string     `db:"status" json:"status"`           //Stripe's refund status
+	Reason     *string    `db:"reason" json:"reason,omitempty"` //Reason given for the refund
+	RefundedBy *string    `db:"refunded_by" json:"refunded_by,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastUpdate *time.Time `db:"last_update" json:"last_update,omitempty"`
+}
+
+// Failed reports whether a refund failed or was cancelled, in which
+// case nothing was given back to the buyer.
+func (r *Refund) Failed() bool {
+	return r.Status == "failed" || r.Status == "canceled"
+}
+
+// TransferReversal records the part of a transfer taken back from a
+// seller's account when their sale is refunded.
+type TransferReversal struct {
+	ReversalId string    `db:"reversal_id" json:"reversal_id"` //Stripe's transfer reversal ID
+	TransferId string    `db:"transfer_id" json:"transfer_id"` //Stripe's transfer ID
+	RefundId   string    `db:"refund_id" json:"refund_id"`     //Refund that caused the reversal
+	Amount     int       `db:"amount" json:"amount"`           //Value taken back from the destination account
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// RefundRequest is the body of a refund request. The amount is in the
+// smallest unit of the currency. If no amount is given, everything not
+// yet refunded is refunded. The currency is only needed for orders paid
+// in more than one currency.
+type RefundRequest struct {
+	Amount   *int    `json:"amount,omitempty"`
+	Currency *string `json:"currency,omitempty"`
+	Reason   *string `json:"reason,omitempty"`
+}
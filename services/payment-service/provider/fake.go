@@ -0,0 +1,438 @@
+package provider
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stripe/stripe-go"
+
+	"github.com/veganbase/backend/chassis"
+)
+
+// Payment method IDs recognised by the fake provider. These are the
+// same as Stripe's test payment methods, so the same test data works
+// against both. Any other payment method ID starting with "pm_"
+// behaves like FakeCardSuccess.
+const (
+	FakeCardSuccess  = "pm_card_visa"
+	FakeCardDeclined = "pm_card_chargeDeclined"
+	FakeCard3DS      = "pm_card_threeDSecure2Required"
+)
+
+// Fake is an in-process payment provider for development and
+// end-to-end tests. It simulates successful payments, card declines
+// and payments needing 3D Secure authentication (based on the payment
+// method used), records refunds and transfer reversals, and delivers
+// webhook events for payment intents and refunded charges after a
+// configurable delay.
+type Fake struct {
+	mu        sync.Mutex
+	intents   map[string]*stripe.PaymentIntent
+	charges   map[string]*stripe.Charge
+	transfers []*stripe.Transfer
+	refunds   map[string]*stripe.Refund
+	events    map[string]*stripe.Event
+	held      []*stripe.Event
+	delay     time.Duration
+	deliver   func(event *stripe.Event)
+}
+
+// NewFake creates a fake payment provider. Webhook events are passed
+// to the deliver function after the given delay. If the delay is
+// negative, events are held until DeliverWebhooks is called, so that
+// tests can control exactly when webhooks arrive.
+func NewFake(webhookDelay time.Duration, deliver func(event *stripe.Event)) *Fake {
+	return &Fake{
+		intents: map[string]*stripe.PaymentIntent{},
+		charges: map[string]*stripe.Charge{},
+		refunds: map[string]*stripe.Refund{},
+		events:  map[string]*stripe.Event{},
+		delay:   webhookDelay,
+		deliver: deliver,
+	}
+}
+
+// Livemode is always false for the fake provider.
+func (f *Fake) Livemode() bool {
+	return false
+}
+
+// ValidatePaymentMethod accepts any payment method ID that looks like
+// a Stripe payment method ID.
+func (f *Fake) ValidatePaymentMethod(id string) error {
+	if !strings.HasPrefix(id, "pm_") {
+		return missing("payment_method", id)
+	}
+	return nil
+}
+
+// CreateIntent creates and confirms a new payment intent, with the
+// outcome determined by the payment method.
+func (f *Fake) CreateIntent(params *IntentParams) (*stripe.PaymentIntent, error) {
+	if err := f.ValidatePaymentMethod(params.PaymentMethod); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := chassis.NewID("pi")
+	pi := &stripe.PaymentIntent{
+		ID:            id,
+		Amount:        params.Amount,
+		Currency:      strings.ToLower(params.Currency),
+		ClientSecret:  id + "_secret_" + chassis.NewBareID(16),
+		Created:       time.Now().Unix(),
+		PaymentMethod: &stripe.PaymentMethod{ID: params.PaymentMethod},
+	}
+	f.intents[id] = pi
+
+	switch params.PaymentMethod {
+	case FakeCardDeclined:
+		pi.Status = stripe.PaymentIntentStatusRequiresPaymentMethod
+		pi.LastPaymentError = &stripe.Error{
+			Code:          stripe.ErrorCodeCardDeclined,
+			DeclineCode:   stripe.DeclineCodeGenericDecline,
+			Msg:           "Your card was declined.",
+			Type:          stripe.ErrorTypeCard,
+			PaymentMethod: &stripe.PaymentMethod{ID: params.PaymentMethod},
+		}
+		f.emit("payment_intent.payment_failed", "payment_intent", pi)
+		return nil, &DeclineError{
+			Code:        string(stripe.ErrorCodeCardDeclined),
+			DeclineCode: string(stripe.DeclineCodeGenericDecline),
+			Intent:      copyIntent(pi),
+		}
+
+	case FakeCard3DS:
+		pi.Status = stripe.PaymentIntentStatusRequiresAction
+		pi.NextAction = &stripe.PaymentIntentNextAction{Type: "use_stripe_sdk"}
+
+	default:
+		f.succeed(pi)
+	}
+
+	return copyIntent(pi), nil
+}
+
+// GetIntent retrieves a payment intent.
+func (f *Fake) GetIntent(id string) (*stripe.PaymentIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pi, ok := f.intents[id]
+	if !ok {
+		return nil, missing("payment_intent", id)
+	}
+	return copyIntent(pi), nil
+}
+
+// ConfirmIntent confirms a payment intent, which always succeeds for
+// intents waiting for authentication or confirmation (and for intents
+// needing a new payment method, if a source is given).
+func (f *Fake) ConfirmIntent(id string, sourceID *string) (*stripe.PaymentIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pi, ok := f.intents[id]
+	if !ok {
+		return nil, missing("payment_intent", id)
+	}
+	switch pi.Status {
+	case stripe.PaymentIntentStatusRequiresAction,
+		stripe.PaymentIntentStatusRequiresConfirmation:
+	case stripe.PaymentIntentStatusRequiresPaymentMethod:
+		if sourceID == nil {
+			return nil, unexpectedState(pi)
+		}
+	default:
+		return nil, unexpectedState(pi)
+	}
+
+	pi.NextAction = nil
+	pi.LastPaymentError = nil
+	f.succeed(pi)
+	return copyIntent(pi), nil
+}
+
+// CancelIntent cancels a payment intent that hasn't yet succeeded.
+func (f *Fake) CancelIntent(id string) (*stripe.PaymentIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pi, ok := f.intents[id]
+	if !ok {
+		return nil, missing("payment_intent", id)
+	}
+	if pi.Status == stripe.PaymentIntentStatusSucceeded ||
+		pi.Status == stripe.PaymentIntentStatusCanceled {
+		return nil, unexpectedState(pi)
+	}
+
+	pi.Status = stripe.PaymentIntentStatusCanceled
+	pi.NextAction = nil
+	f.emit("payment_intent.canceled", "payment_intent", pi)
+	return copyIntent(pi), nil
+}
+
+// CreateTransfer records a transfer to a connected account.
+func (f *Fake) CreateTransfer(params *TransferParams) (*stripe.Transfer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tr := &stripe.Transfer{
+		ID:          chassis.NewID("tr"),
+		Amount:      params.Amount,
+		Currency:    stripe.Currency(params.Currency),
+		Destination: &stripe.TransferDestination{ID: params.Destination},
+		Created:     time.Now().Unix(),
+		SourceTransaction: &stripe.BalanceTransactionSource{
+			ID: params.SourceTransaction,
+		},
+		Reversals: &stripe.ReversalList{},
+	}
+	f.transfers = append(f.transfers, tr)
+	return tr, nil
+}
+
+// GetTransfer retrieves a transfer made by the fake provider.
+func (f *Fake) GetTransfer(id string) (*stripe.Transfer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, tr := range f.transfers {
+		if tr.ID == id {
+			result := *tr
+			return &result, nil
+		}
+	}
+	return nil, missing("transfer", id)
+}
+
+// CreateRefund refunds part or all of a charge made by the fake
+// provider. Refunds succeed immediately, and generate a
+// "charge.refunded" webhook event. Refunds repeated with the same
+// idempotency key return the original refund.
+func (f *Fake) CreateRefund(params *RefundParams) (*stripe.Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if refund, ok := f.refunds[params.IdempotencyKey]; ok {
+		result := *refund
+		return &result, nil
+	}
+
+	ch, ok := f.charges[params.Charge]
+	if !ok {
+		return nil, missing("charge", params.Charge)
+	}
+	if ch.Refunded {
+		return nil, invalidRequest(stripe.ErrorCodeChargeAlreadyRefunded,
+			"Charge "+ch.ID+" has already been refunded.")
+	}
+	if params.Amount <= 0 || params.Amount > ch.Amount-ch.AmountRefunded {
+		return nil, invalidRequest(stripe.ErrorCodeAmountTooLarge,
+			"Refund amount is greater than unrefunded amount on charge")
+	}
+
+	refund := &stripe.Refund{
+		ID:       chassis.NewID("re"),
+		Amount:   params.Amount,
+		Charge:   &stripe.Charge{ID: ch.ID},
+		Created:  time.Now().Unix(),
+		Currency: ch.Currency,
+		Metadata: params.Metadata,
+		Object:   "refund",
+		Status:   stripe.RefundStatusSucceeded,
+	}
+	ch.AmountRefunded += params.Amount
+	ch.Refunded = ch.AmountRefunded == ch.Amount
+	ch.Refunds.Data = append(ch.Refunds.Data, refund)
+	if params.IdempotencyKey != "" {
+		f.refunds[params.IdempotencyKey] = refund
+	}
+	f.emit("charge.refunded", "charge", ch)
+
+	result := *refund
+	return &result, nil
+}
+
+// ReverseTransfer records a reversal of part or all of a transfer made
+// by the fake provider.
+func (f *Fake) ReverseTransfer(params *ReversalParams) (*stripe.Reversal, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var tr *stripe.Transfer
+	for _, t := range f.transfers {
+		if t.ID == params.Transfer {
+			tr = t
+			break
+		}
+	}
+	if tr == nil {
+		return nil, missing("transfer", params.Transfer)
+	}
+	if params.Amount <= 0 || params.Amount > tr.Amount-tr.AmountReversed {
+		return nil, invalidRequest(stripe.ErrorCodeAmountTooLarge,
+			"Reversal amount is greater than unreversed amount on transfer")
+	}
+
+	reversal := &stripe.Reversal{
+		ID:       chassis.NewID("trr"),
+		Amount:   params.Amount,
+		Created:  time.Now().Unix(),
+		Currency: tr.Currency,
+		Metadata: params.Metadata,
+		Transfer: tr.ID,
+	}
+	tr.AmountReversed += params.Amount
+	tr.Reversed = tr.AmountReversed == tr.Amount
+	tr.Reversals.Data = append(tr.Reversals.Data, reversal)
+
+	result := *reversal
+	return &result, nil
+}
+
+// Transfers returns all the transfers made using the fake provider.
+func (f *Fake) Transfers() []*stripe.Transfer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]*stripe.Transfer{}, f.transfers...)
+}
+
+// ConstructEvent builds a webhook event from a request payload.
+// Request signatures are not checked.
+func (f *Fake) ConstructEvent(payload []byte, signature string) (*stripe.Event, error) {
+	event := stripe.Event{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// GetEvent retrieves a webhook event generated by the fake provider.
+func (f *Fake) GetEvent(id string) (*stripe.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	event, ok := f.events[id]
+	if !ok {
+		return nil, missing("event", id)
+	}
+	return event, nil
+}
+
+// DeliverWebhooks delivers any held webhook events immediately, in
+// the order they were generated.
+func (f *Fake) DeliverWebhooks() {
+	f.mu.Lock()
+	held := f.held
+	f.held = nil
+	f.mu.Unlock()
+
+	for _, event := range held {
+		f.deliver(event)
+	}
+}
+
+// Mark a payment intent as succeeded, with a charge for the full
+// amount. Must be called with the lock held.
+func (f *Fake) succeed(pi *stripe.PaymentIntent) {
+	pi.Status = stripe.PaymentIntentStatusSucceeded
+	ch := &stripe.Charge{
+		ID:            chassis.NewID("ch"),
+		Amount:        pi.Amount,
+		Currency:      stripe.Currency(pi.Currency),
+		Paid:          true,
+		PaymentIntent: pi.ID,
+		Refunds:       &stripe.RefundList{},
+		Status:        "succeeded",
+	}
+	f.charges[ch.ID] = ch
+	pi.Charges = &stripe.ChargeList{Data: []*stripe.Charge{ch}}
+	f.emit("payment_intent.succeeded", "payment_intent", pi)
+}
+
+// Generate a webhook event for a payment intent or charge. The event
+// is built from JSON, as it would be when received from Stripe, so
+// that the event's raw data is filled in. Must be called with the lock
+// held.
+func (f *Fake) emit(eventType string, objectType string, obj interface{}) {
+	object := map[string]interface{}{}
+	objJSON, err := json.Marshal(obj)
+	if err == nil {
+		err = json.Unmarshal(objJSON, &object)
+	}
+	if err != nil {
+		return
+	}
+	object["object"] = objectType
+
+	eventJSON, err := json.Marshal(map[string]interface{}{
+		"id":       chassis.NewID("evt"),
+		"object":   "event",
+		"type":     eventType,
+		"created":  time.Now().Unix(),
+		"livemode": false,
+		"request": map[string]string{
+			"id":              chassis.NewID("req"),
+			"idempotency_key": chassis.NewBareID(32),
+		},
+		"data": map[string]interface{}{"object": object},
+	})
+	if err != nil {
+		return
+	}
+	event := &stripe.Event{}
+	if err = json.Unmarshal(eventJSON, event); err != nil {
+		return
+	}
+	f.events[event.ID] = event
+
+	switch {
+	case f.deliver == nil:
+	case f.delay < 0:
+		f.held = append(f.held, event)
+	default:
+		time.AfterFunc(f.delay, func() { f.deliver(event) })
+	}
+}
+
+func copyIntent(pi *stripe.PaymentIntent) *stripe.PaymentIntent {
+	result := *pi
+	return &result
+}
+
+// Errors returned by the fake provider mimic Stripe's errors.
+
+func missing(resource, id string) error {
+	return &stripe.Error{
+		Code:           stripe.ErrorCodeResourceMissing,
+		HTTPStatusCode: 404,
+		Msg:            "No such " + resource + ": " + id,
+		Type:           stripe.ErrorTypeInvalidRequest,
+	}
+}
+
+func invalidRequest(code stripe.ErrorCode, msg string) error {
+	return &stripe.Error{
+		Code:           code,
+		HTTPStatusCode: 400,
+		Msg:            msg,
+		Type:           stripe.ErrorTypeInvalidRequest,
+	}
+}
+
+func unexpectedState(pi *stripe.PaymentIntent) error {
+	return &stripe.Error{
+		Code:           stripe.ErrorCodePaymentIntentUnexpectedState,
+		HTTPStatusCode: 400,
+		Msg:            "PaymentIntent " + pi.ID + " has a status of " + string(pi.Status),
+		Type:           stripe.ErrorTypeInvalidRequest,
+		PaymentIntent:  copyIntent(pi),
+	}
+}
@@ -0,0 +1,229 @@
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stripe/stripe-go"
+)
+
+func heldFake() (*Fake, *[]*stripe.Event) {
+	delivered := []*stripe.Event{}
+	f := NewFake(-1, func(event *stripe.Event) {
+		delivered = append(delivered, event)
+	})
+	return f, &delivered
+}
+
+func TestFakeSuccess(t *testing.T) {
+	f, delivered := heldFake()
+
+	pi, err := f.CreateIntent(&IntentParams{
+		PaymentMethod: FakeCardSuccess, Amount: 1250, Currency: "EUR",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, stripe.PaymentIntentStatusSucceeded, pi.Status)
+	assert.Equal(t, "eur", pi.Currency)
+	if assert.NotNil(t, pi.Charges) && assert.Len(t, pi.Charges.Data, 1) {
+		assert.Equal(t, int64(1250), pi.Charges.Data[0].Amount)
+	}
+
+	// Webhooks are held until explicitly delivered.
+	assert.Len(t, *delivered, 0)
+	f.DeliverWebhooks()
+	if assert.Len(t, *delivered, 1) {
+		event := (*delivered)[0]
+		assert.Equal(t, "payment_intent.succeeded", event.Type)
+		assert.Equal(t, "payment_intent", event.Data.Object["object"])
+		whpi := stripe.PaymentIntent{}
+		assert.Nil(t, json.Unmarshal(event.Data.Raw, &whpi))
+		assert.Equal(t, pi.ID, whpi.ID)
+
+		got, err := f.GetEvent(event.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, event.ID, got.ID)
+	}
+
+	// Succeeded intents can't be confirmed or cancelled.
+	_, err = f.ConfirmIntent(pi.ID, nil)
+	assert.NotNil(t, err)
+	_, err = f.CancelIntent(pi.ID)
+	assert.NotNil(t, err)
+
+	tr, err := f.CreateTransfer(&TransferParams{
+		Amount: 1000, Currency: "eur", Destination: "acct_TEST",
+		SourceTransaction: pi.Charges.Data[0].ID,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "acct_TEST", tr.Destination.ID)
+	assert.Len(t, f.Transfers(), 1)
+}
+
+func TestFakeDecline(t *testing.T) {
+	f, delivered := heldFake()
+
+	_, err := f.CreateIntent(&IntentParams{
+		PaymentMethod: FakeCardDeclined, Amount: 500, Currency: "gbp",
+	})
+	declineErr, ok := err.(*DeclineError)
+	if !assert.True(t, ok, "expected decline error") {
+		return
+	}
+	assert.Equal(t, "card_declined", declineErr.Code)
+	assert.Equal(t, stripe.PaymentIntentStatusRequiresPaymentMethod, declineErr.Intent.Status)
+
+	f.DeliverWebhooks()
+	if assert.Len(t, *delivered, 1) {
+		event := (*delivered)[0]
+		assert.Equal(t, "payment_intent.payment_failed", event.Type)
+		whpi := stripe.PaymentIntent{}
+		assert.Nil(t, json.Unmarshal(event.Data.Raw, &whpi))
+		if assert.NotNil(t, whpi.LastPaymentError) &&
+			assert.NotNil(t, whpi.LastPaymentError.PaymentMethod) {
+			assert.Equal(t, FakeCardDeclined, whpi.LastPaymentError.PaymentMethod.ID)
+		}
+	}
+
+	// Declined intents can be cancelled.
+	pi, err := f.CancelIntent(declineErr.Intent.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, stripe.PaymentIntentStatusCanceled, pi.Status)
+}
+
+func TestFake3DS(t *testing.T) {
+	f, delivered := heldFake()
+
+	pi, err := f.CreateIntent(&IntentParams{
+		PaymentMethod: FakeCard3DS, Amount: 999, Currency: "usd",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, stripe.PaymentIntentStatusRequiresAction, pi.Status)
+	assert.Equal(t, stripe.PaymentIntentNextActionType("use_stripe_sdk"), pi.NextAction.Type)
+	assert.NotEmpty(t, pi.ClientSecret)
+	f.DeliverWebhooks()
+	assert.Len(t, *delivered, 0)
+
+	pi, err = f.ConfirmIntent(pi.ID, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, stripe.PaymentIntentStatusSucceeded, pi.Status)
+	assert.Nil(t, pi.NextAction)
+	f.DeliverWebhooks()
+	if assert.Len(t, *delivered, 1) {
+		assert.Equal(t, "payment_intent.succeeded", (*delivered)[0].Type)
+	}
+}
+
+func TestFakeDelayedWebhooks(t *testing.T) {
+	events := make(chan *stripe.Event, 1)
+	f := NewFake(50*time.Millisecond, func(event *stripe.Event) {
+		events <- event
+	})
+
+	start := time.Now()
+	_, err := f.CreateIntent(&IntentParams{
+		PaymentMethod: FakeCardSuccess, Amount: 100, Currency: "eur",
+	})
+	assert.Nil(t, err)
+	select {
+	case event := <-events:
+		assert.Equal(t, "payment_intent.succeeded", event.Type)
+		assert.True(t, time.Since(start) >= 50*time.Millisecond, "webhook not delayed")
+	case <-time.After(time.Second):
+		t.Error("webhook not delivered")
+	}
+}
+
+func TestFakeRefund(t *testing.T) {
+	f, delivered := heldFake()
+
+	pi, err := f.CreateIntent(&IntentParams{
+		PaymentMethod: FakeCardSuccess, Amount: 2000, Currency: "eur",
+	})
+	assert.Nil(t, err)
+	chargeID := pi.Charges.Data[0].ID
+	tr, err := f.CreateTransfer(&TransferParams{
+		Amount: 1700, Currency: "eur", Destination: "acct_TEST",
+		SourceTransaction: chargeID,
+	})
+	assert.Nil(t, err)
+	f.DeliverWebhooks()
+	*delivered = nil
+
+	// Partial refund.
+	rf, err := f.CreateRefund(&RefundParams{
+		Charge: chargeID, Amount: 500,
+		Metadata: map[string]string{"sale_id": "ORD-000001"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, stripe.RefundStatusSucceeded, rf.Status)
+	assert.Equal(t, int64(500), rf.Amount)
+	f.DeliverWebhooks()
+	if assert.Len(t, *delivered, 1) {
+		event := (*delivered)[0]
+		assert.Equal(t, "charge.refunded", event.Type)
+		assert.Equal(t, "charge", event.Data.Object["object"])
+		ch := stripe.Charge{}
+		assert.Nil(t, json.Unmarshal(event.Data.Raw, &ch))
+		assert.Equal(t, pi.ID, ch.PaymentIntent)
+		assert.Equal(t, int64(500), ch.AmountRefunded)
+		assert.False(t, ch.Refunded)
+		if assert.NotNil(t, ch.Refunds) && assert.Len(t, ch.Refunds.Data, 1) {
+			assert.Equal(t, rf.ID, ch.Refunds.Data[0].ID)
+			assert.Equal(t, "ORD-000001", ch.Refunds.Data[0].Metadata["sale_id"])
+		}
+	}
+
+	// Refunds can't exceed what's left of the charge.
+	_, err = f.CreateRefund(&RefundParams{Charge: chargeID, Amount: 1501})
+	assert.NotNil(t, err)
+
+	// Refunds repeated with the same idempotency key aren't made again.
+	first, err := f.CreateRefund(&RefundParams{Charge: chargeID, Amount: 1000, IdempotencyKey: "key-1"})
+	assert.Nil(t, err)
+	again, err := f.CreateRefund(&RefundParams{Charge: chargeID, Amount: 1000, IdempotencyKey: "key-1"})
+	assert.Nil(t, err)
+	assert.Equal(t, first.ID, again.ID)
+	_, err = f.CreateRefund(&RefundParams{Charge: chargeID, Amount: 500})
+	assert.Nil(t, err)
+	_, err = f.CreateRefund(&RefundParams{Charge: chargeID, Amount: 1})
+	stripeErr, ok := err.(*stripe.Error)
+	if assert.True(t, ok) {
+		assert.Equal(t, stripe.ErrorCodeChargeAlreadyRefunded, stripeErr.Code)
+	}
+
+	// Transfer reversals.
+	rev, err := f.ReverseTransfer(&ReversalParams{Transfer: tr.ID, Amount: 425})
+	assert.Nil(t, err)
+	assert.Equal(t, tr.ID, rev.Transfer)
+	_, err = f.ReverseTransfer(&ReversalParams{Transfer: tr.ID, Amount: 1276})
+	assert.NotNil(t, err)
+	_, err = f.ReverseTransfer(&ReversalParams{Transfer: tr.ID, Amount: 1275})
+	assert.Nil(t, err)
+	if assert.Len(t, f.Transfers(), 1) {
+		assert.True(t, f.Transfers()[0].Reversed)
+	}
+	_, err = f.ReverseTransfer(&ReversalParams{Transfer: "tr_UNKNOWN", Amount: 1})
+	assert.NotNil(t, err)
+
+	got, err := f.GetTransfer(tr.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1700), got.AmountReversed)
+	assert.Len(t, got.Reversals.Data, 2)
+	_, err = f.GetTransfer("tr_UNKNOWN")
+	assert.NotNil(t, err)
+}
+
+func TestFakeUnknown(t *testing.T) {
+	f, _ := heldFake()
+
+	assert.NotNil(t, f.ValidatePaymentMethod("card_123"))
+	_, err := f.GetIntent("pi_UNKNOWN")
+	stripeErr, ok := err.(*stripe.Error)
+	if assert.True(t, ok) {
+		assert.Equal(t, stripe.ErrorCodeResourceMissing, stripeErr.Code)
+	}
+	_, err = f.GetEvent("evt_UNKNOWN")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"github.com/stripe/stripe-go"
+)
+
+// PaymentProvider is the interface to the payment provider used by
+// the payment service. Stripe's types are used for the values passed
+// across the interface, since those are what the webhook handlers
+// work with, but all calls to the provider's API go through here so
+// that the fake provider can be used in their place.
+type PaymentProvider interface {
+	// Livemode reports whether the provider is processing real
+	// payments.
+	Livemode() bool
+
+	// ValidatePaymentMethod checks that a payment method exists.
+	ValidatePaymentMethod(id string) error
+
+	// CreateIntent creates and confirms a new payment intent. If the
+	// payment is declined, the error is a *DeclineError.
+	CreateIntent(params *IntentParams) (*stripe.PaymentIntent, error)
+
+	// GetIntent retrieves a payment intent.
+	GetIntent(id string) (*stripe.PaymentIntent, error)
+
+	// ConfirmIntent confirms a payment intent, optionally using a
+	// payment source.
+	ConfirmIntent(id string, sourceID *string) (*stripe.PaymentIntent, error)
+
+	// CancelIntent cancels a payment intent.
+	CancelIntent(id string) (*stripe.PaymentIntent, error)
+
+	// CreateTransfer transfers funds to a connected account.
+	CreateTransfer(params *TransferParams) (*stripe.Transfer, error)
+
+	// GetTransfer retrieves a transfer, with its reversals.
+	GetTransfer(id string) (*stripe.Transfer, error)
+
+	// CreateRefund refunds all or part of a charge.
+	CreateRefund(params *RefundParams) (*stripe.Refund, error)
+
+	// ReverseTransfer reverses part of a transfer to a connected
+	// account, taking the funds back from the account.
+	ReverseTransfer(params *ReversalParams) (*stripe.Reversal, error)
+
+	// ConstructEvent builds a webhook event from a request payload,
+	// checking the request signature.
+	ConstructEvent(payload []byte, signature string) (*stripe.Event, error)
+
+	// GetEvent retrieves a webhook event.
+	GetEvent(id string) (*stripe.Event, error)
+}
+
+// IntentParams are the parameters used to create a payment intent.
+type IntentParams struct {
+	PaymentMethod       string
+	Customer            *string
+	Amount              int64
+	Currency            string
+	StatementDescriptor *string
+}
+
+// TransferParams are the parameters used to create a transfer.
+type TransferParams struct {
+	Amount            int64
+	Currency          string
+	Destination       string
+	SourceTransaction string
+}
+
+// RefundParams are the parameters used to refund a charge. The
+// metadata is attached to the refund, and comes back with it in
+// webhook events. Repeating a refund with the same idempotency key
+// returns the original refund instead of making a new one.
+type RefundParams struct {
+	Charge         string
+	Amount         int64
+	Metadata       map[string]string
+	IdempotencyKey string
+}
+
+// ReversalParams are the parameters used to reverse a transfer.
+type ReversalParams struct {
+	Transfer string
+	Amount   int64
+	Metadata map[string]string
+}
+
+// DeclineError is the error returned when a card payment is declined.
+// The payment intent created for the failed attempt is included.
+type DeclineError struct {
+	Code        string
+	DeclineCode string
+	Intent      *stripe.PaymentIntent
+}
+
+func (e *DeclineError) Error() string {
+	return "payment declined: " + e.Code + " (" + e.DeclineCode + ")"
+}
@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/stripe/stripe-go"
+	"github.com/stripe/stripe-go/client"
+	"github.com/stripe/stripe-go/webhook"
+)
+
+// Stripe is the payment provider implementation using the Stripe API.
+type Stripe struct {
+	api                  *client.API
+	webhookSecret        string
+	connectWebhookSecret string
+	livemode             bool
+}
+
+// Test mode Stripe keys look like "sk_test_...".
+var stripeTestKeyRE = regexp.MustCompile(`\w+(_test_)\w+`)
+
+// NewStripe creates a Stripe payment provider using the given API key
+// and webhook signing secrets. Events about connected accounts come
+// from a separate Connect webhook endpoint with its own secret, which
+// may be empty if that endpoint isn't set up. If the main webhook
+// secret is empty, webhook signatures are not checked.
+func NewStripe(key, webhookSecret, connectWebhookSecret string) *Stripe {
+	return &Stripe{
+		api:                  client.New(key, nil),
+		webhookSecret:        webhookSecret,
+		connectWebhookSecret: connectWebhookSecret,
+		livemode:             !stripeTestKeyRE.MatchString(key),
+	}
+}
+
+// Livemode reports whether a live mode Stripe key is in use.
+func (s *Stripe) Livemode() bool {
+	return s.livemode
+}
+
+// ValidatePaymentMethod checks that a payment method exists.
+func (s *Stripe) ValidatePaymentMethod(id string) error {
+	_, err := s.api.PaymentMethods.Get(id, nil)
+	return err
+}
+
+// CreateIntent creates and confirms a new payment intent.
+func (s *Stripe) CreateIntent(params *IntentParams) (*stripe.PaymentIntent, error) {
+	pi, err := s.api.PaymentIntents.New(&stripe.PaymentIntentParams{
+		PaymentMethod:       stripe.String(params.PaymentMethod),
+		Customer:            params.Customer,
+		Amount:              stripe.Int64(params.Amount),
+		Currency:            stripe.String(params.Currency),
+		Confirm:             stripe.Bool(true),
+		StatementDescriptor: params.StatementDescriptor,
+	})
+	if err != nil {
+		// Card errors come with the payment intent for the failed
+		// attempt.
+		if stripeErr, ok := err.(*stripe.Error); ok {
+			if cardErr, ok := stripeErr.Err.(*stripe.CardError); ok && stripeErr.PaymentIntent != nil {
+				return nil, &DeclineError{
+					Code:        string(stripeErr.Code),
+					DeclineCode: string(cardErr.DeclineCode),
+					Intent:      stripeErr.PaymentIntent,
+				}
+			}
+		}
+		return nil, err
+	}
+	return pi, nil
+}
+
+// GetIntent retrieves a payment intent.
+func (s *Stripe) GetIntent(id string) (*stripe.PaymentIntent, error) {
+	return s.api.PaymentIntents.Get(id, nil)
+}
+
+// ConfirmIntent confirms a payment intent.
+func (s *Stripe) ConfirmIntent(id string, sourceID *string) (*stripe.PaymentIntent, error) {
+	var params *stripe.PaymentIntentConfirmParams
+	if sourceID != nil {
+		params = &stripe.PaymentIntentConfirmParams{Source: sourceID}
+	}
+	return s.api.PaymentIntents.Confirm(id, params)
+}
+
+// CancelIntent cancels a payment intent.
+func (s *Stripe) CancelIntent(id string) (*stripe.PaymentIntent, error) {
+	return s.api.PaymentIntents.Cancel(id, nil)
+}
+
+// CreateTransfer transfers funds to a connected account.
+func (s *Stripe) CreateTransfer(params *TransferParams) (*stripe.Transfer, error) {
+	return s.api.Transfers.New(&stripe.TransferParams{
+		Amount:            stripe.Int64(params.Amount),
+		Currency:          stripe.String(params.Currency),
+		Destination:       stripe.String(params.Destination),
+		SourceTransaction: stripe.String(params.SourceTransaction),
+	})
+}
+
+// GetTransfer retrieves a transfer. Only the first page of the
+// transfer's reversals is included, but the total reversed is always
+// complete.
+func (s *Stripe) GetTransfer(id string) (*stripe.Transfer, error) {
+	return s.api.Transfers.Get(id, nil)
+}
+
+// CreateRefund refunds all or part of a charge.
+func (s *Stripe) CreateRefund(params *RefundParams) (*stripe.Refund, error) {
+	refundParams := &stripe.RefundParams{
+		Charge: stripe.String(params.Charge),
+		Amount: stripe.Int64(params.Amount),
+	}
+	for k, v := range params.Metadata {
+		refundParams.AddMetadata(k, v)
+	}
+	if params.IdempotencyKey != "" {
+		refundParams.SetIdempotencyKey(params.IdempotencyKey)
+	}
+	return s.api.Refunds.New(refundParams)
+}
+
+// ReverseTransfer reverses part of a transfer to a connected account.
+func (s *Stripe) ReverseTransfer(params *ReversalParams) (*stripe.Reversal, error) {
+	reversalParams := &stripe.ReversalParams{
+		Transfer: stripe.String(params.Transfer),
+		Amount:   stripe.Int64(params.Amount),
+	}
+	for k, v := range params.Metadata {
+		reversalParams.AddMetadata(k, v)
+	}
+	return s.api.Reversals.New(reversalParams)
+}
+
+// ConstructEvent builds a webhook event from a request payload,
+// validating that the request came from Stripe.
+func (s *Stripe) ConstructEvent(payload []byte, signature string) (*stripe.Event, error) {
+	event := stripe.Event{}
+	if s.webhookSecret == "" {
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	}
+	event, err := webhook.ConstructEvent(payload, signature, s.webhookSecret)
+	if err != nil && s.connectWebhookSecret != "" {
+		event, err = webhook.ConstructEvent(payload, signature, s.connectWebhookSecret)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// GetEvent retrieves a webhook event.
+func (s *Stripe) GetEvent(id string) (*stripe.Event, error) {
+	return s.api.Events.Get(id, nil)
+}
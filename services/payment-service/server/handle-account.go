@@ -0,0 +1,58 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/stripe/stripe-go"
+	usr "github.com/veganbase/backend/services/user-service/model"
+)
+
+// HandleAccountUpdated records the status of a seller's connected
+// account on their payout account whenever it changes. This is what
+// enables transfers to the account once onboarding is complete, and
+// disables them if the account becomes restricted.
+func (s *Server) HandleAccountUpdated(event *stripe.Event, acc *stripe.Account) {
+	fmt.Printf("🔔  Webhook received! Account %s updated\n", acc.ID)
+
+	payoutAcc, err := s.userSvc.UpdatePayoutAccountStatus(acc.ID, accountStatus(acc))
+	if err != nil {
+		s.LogError(event.ID, "error while updating status of payout account "+acc.ID+" on user-service: "+err.Error())
+		return
+	}
+	if !payoutAcc.TransfersEnabled {
+		fmt.Printf("🔔  Payout account %s of %s can't receive transfers\n", acc.ID, payoutAcc.Owner)
+	}
+}
+
+// accountStatus extracts the status of a connected account that's
+// recorded on payout accounts. The requirements recorded are those
+// currently due, which include any that are past due.
+func accountStatus(acc *stripe.Account) *usr.PayoutAccountStatus {
+	status := usr.PayoutAccountStatus{
+		DetailsSubmitted: acc.DetailsSubmitted,
+		ChargesEnabled:   acc.ChargesEnabled,
+		PayoutsEnabled:   acc.PayoutsEnabled,
+		Capabilities:     usr.AccountCapabilities{},
+		Requirements:     []string{},
+	}
+	if acc.Capabilities != nil {
+		caps := map[string]stripe.AccountCapabilityStatus{
+			"card_payments":   acc.Capabilities.CardPayments,
+			"legacy_payments": acc.Capabilities.LegacyPayments,
+			"transfers":       acc.Capabilities.Transfers,
+		}
+		for name, st := range caps {
+			if st != "" {
+				status.Capabilities[name] = string(st)
+			}
+		}
+	}
+	if acc.Requirements != nil {
+		status.Requirements = append(status.Requirements, acc.Requirements.CurrentlyDue...)
+		if acc.Requirements.DisabledReason != "" {
+			reason := string(acc.Requirements.DisabledReason)
+			status.DisabledReason = &reason
+		}
+	}
+	return &status
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stripe/stripe-go"
+)
+
+func TestAccountStatus(t *testing.T) {
+	acc := &stripe.Account{
+		ID:             "acct_1",
+		PayoutsEnabled: true,
+		Capabilities:   &stripe.AccountCapabilities{Transfers: stripe.AccountCapabilityStatusActive},
+		Requirements:   &stripe.AccountRequirements{},
+	}
+	status := accountStatus(acc)
+	assert.Equal(t, "active", status.Capabilities["transfers"])
+	assert.Empty(t, status.Requirements)
+	assert.Nil(t, status.DisabledReason)
+	assert.True(t, status.CanReceiveTransfers())
+
+	acc.Capabilities.Transfers = stripe.AccountCapabilityStatusInactive
+	acc.Requirements = &stripe.AccountRequirements{
+		CurrentlyDue:   []string{"individual.verification.document"},
+		DisabledReason: "requirements.past_due",
+	}
+	status = accountStatus(acc)
+	assert.Equal(t, []string{"individual.verification.document"}, []string(status.Requirements))
+	assert.Equal(t, "requirements.past_due", *status.DisabledReason)
+	assert.False(t, status.CanReceiveTransfers())
+
+	status = accountStatus(&stripe.Account{ID: "acct_2"})
+	assert.False(t, status.CanReceiveTransfers())
+}
@@ -0,0 +1,261 @@
+package server
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/payment-service/model"
+	site "github.com/veganbase/backend/services/site-service/model"
+)
+
+// statementMonth is the layout of the months of payout statements.
+const statementMonth = "2006-01"
+
+// sellerBalance returns the balance held for a user or organisation in
+// each currency, awaiting transfer to their payout account.
+func (s *Server) sellerBalance(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	seller, ok, err := s.sellerFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return chassis.NotFound(w)
+	}
+
+	totals, err := s.db.SellerLedgerTotals(seller)
+	if err != nil {
+		return nil, err
+	}
+	return balances(*totals), nil
+}
+
+// payoutStatements lists the monthly statements of a user or
+// organisation, newest first, without their ledger lines.
+func (s *Server) payoutStatements(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	seller, ok, err := s.sellerFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return chassis.NotFound(w)
+	}
+
+	totals, err := s.db.SellerLedgerTotals(seller)
+	if err != nil {
+		return nil, err
+	}
+	return statements(seller, *totals), nil
+}
+
+// payoutStatement returns the statement of a user or organisation for
+// a month, with its ledger lines, as JSON or, with format=csv, as a
+// CSV file.
+func (s *Server) payoutStatement(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	seller, ok, err := s.sellerFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return chassis.NotFound(w)
+	}
+
+	month, err := time.Parse(statementMonth, chi.URLParam(r, "month"))
+	if err != nil {
+		return chassis.BadRequest(w, "invalid month: expected YYYY-MM")
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		return chassis.BadRequest(w, "invalid format parameter")
+	}
+
+	totals, err := s.db.SellerLedgerTotals(seller)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.db.SellerLedgerLines(seller, month, month.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+	st := statement(seller, month, *totals)
+	st.Lines = *lines
+
+	if format != "csv" {
+		return st, nil
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="statement-%s-%s.csv"`, seller, st.Month))
+	if err = writeStatementCSV(w, st); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// sellerFromRequest works out whose balance or statements are being
+// asked for: the authenticated user for /me routes, another user
+// (administrators only) or an organisation (its members and
+// administrators only). Returns false if the request isn't allowed.
+func (s *Server) sellerFromRequest(r *http.Request) (string, bool, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return "", false, nil
+	}
+
+	if idOrSlug := chi.URLParam(r, "id_or_slug"); idOrSlug != "" {
+		info, err := s.userSvc.Info([]string{idOrSlug})
+		if err != nil {
+			return "", false, err
+		}
+		orgID := ""
+		for _, v := range info {
+			if v != nil && strings.HasPrefix(v.ID, "org_") {
+				orgID = v.ID
+			}
+		}
+		if orgID == "" {
+			return "", false, nil
+		}
+		if authInfo.UserIsAdmin {
+			return orgID, true, nil
+		}
+		isMember, err := s.userSvc.IsUserOrgMember(authInfo.UserID, orgID)
+		return orgID, isMember, err
+	}
+
+	if id := chi.URLParam(r, "id"); id != "" && id != authInfo.UserID {
+		return id, authInfo.UserIsAdmin, nil
+	}
+	return authInfo.UserID, true, nil
+}
+
+// balances works out a seller's balance in each currency from their
+// ledger totals.
+func balances(totals []model.LedgerTotal) []model.SellerBalance {
+	byCurrency := map[string]*model.SellerBalance{}
+	for _, t := range totals {
+		b, ok := byCurrency[t.Currency]
+		if !ok {
+			b = &model.SellerBalance{Currency: t.Currency}
+			byCurrency[t.Currency] = b
+		}
+		b.Add(t.Kind, t.Amount)
+	}
+
+	result := []model.SellerBalance{}
+	for _, b := range byCurrency {
+		b.Balance = b.Net()
+		result = append(result, *b)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Currency < result[j].Currency })
+	return result
+}
+
+// statement works out a seller's statement for a month from their
+// ledger totals, covering each currency with a balance or activity.
+func statement(seller string, month time.Time, totals []model.LedgerTotal) *model.PayoutStatement {
+	byCurrency := map[string]*model.StatementCurrency{}
+	for _, t := range totals {
+		if !t.Month.Before(month.AddDate(0, 1, 0)) {
+			continue
+		}
+		c, ok := byCurrency[t.Currency]
+		if !ok {
+			c = &model.StatementCurrency{Currency: t.Currency}
+			byCurrency[t.Currency] = c
+		}
+		if t.Month.Before(month) {
+			c.OpeningBalance += t.Amount
+		} else {
+			c.Add(t.Kind, t.Amount)
+		}
+	}
+
+	st := &model.PayoutStatement{
+		Seller:     seller,
+		Month:      month.Format(statementMonth),
+		Currencies: []model.StatementCurrency{},
+	}
+	for _, c := range byCurrency {
+		c.ClosingBalance = c.OpeningBalance + c.Net()
+		if c.OpeningBalance == 0 && c.LedgerActivity == (model.LedgerActivity{}) {
+			continue
+		}
+		st.Currencies = append(st.Currencies, *c)
+	}
+	sort.Slice(st.Currencies, func(i, j int) bool {
+		return st.Currencies[i].Currency < st.Currencies[j].Currency
+	})
+	return st
+}
+
+// statements works out a seller's statements for each month with
+// ledger activity, newest first.
+func statements(seller string, totals []model.LedgerTotal) []model.PayoutStatement {
+	months := []time.Time{}
+	seen := map[time.Time]bool{}
+	for _, t := range totals {
+		if !seen[t.Month] {
+			seen[t.Month] = true
+			months = append(months, t.Month)
+		}
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].After(months[j]) })
+
+	result := []model.PayoutStatement{}
+	for _, month := range months {
+		result = append(result, *statement(seller, month, totals))
+	}
+	return result
+}
+
+// writeStatementCSV writes the ledger lines of a statement as CSV,
+// with the running balance in each currency. Amounts are in major
+// currency units.
+func writeStatementCSV(w io.Writer, st *model.PayoutStatement) error {
+	balance := map[string]int{}
+	for _, c := range st.Currencies {
+		balance[c.Currency] = c.OpeningBalance
+	}
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"date", "kind", "description", "purchase", "sale", "reference",
+		"currency", "amount", "balance"})
+	for _, l := range st.Lines {
+		balance[l.Currency] += l.Amount
+		sale := ""
+		if l.SaleId != nil {
+			sale = *l.SaleId
+		}
+		out.Write([]string{
+			l.CreatedAt.UTC().Format(time.RFC3339), l.Kind, l.Description, l.Origin, sale, l.Reference,
+			strings.ToUpper(l.Currency), decimalAmount(l.Amount, l.Currency), decimalAmount(balance[l.Currency], l.Currency),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// decimalAmount formats an amount in the smallest currency unit in
+// major units, with as many decimal places as the currency has.
+func decimalAmount(v int, currency string) string {
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	exp := site.CurrencyExponent(currency)
+	if exp == 0 {
+		return sign + strconv.Itoa(v)
+	}
+	scale := int(math.Pow10(exp))
+	return sign + strconv.Itoa(v/scale) + "." + fmt.Sprintf("%0*d", exp, v%scale)
+}
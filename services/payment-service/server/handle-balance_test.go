@@ -0,0 +1,88 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/veganbase/backend/services/payment-service/model"
+)
+
+func TestBalancesAndStatements(t *testing.T) {
+	aug := time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)
+	sep := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	totals := []model.LedgerTotal{
+		{Month: aug, Currency: "eur", Kind: model.LedgerCharge, Amount: 3000},
+		{Month: aug, Currency: "eur", Kind: model.LedgerFee, Amount: -450},
+		{Month: aug, Currency: "eur", Kind: model.LedgerTransfer, Amount: -1700},
+		{Month: sep, Currency: "eur", Kind: model.LedgerTransfer, Amount: -850},
+		{Month: sep, Currency: "eur", Kind: model.LedgerRefund, Amount: -425},
+		{Month: sep, Currency: "eur", Kind: model.LedgerReversal, Amount: 425},
+		{Month: sep, Currency: "gbp", Kind: model.LedgerCharge, Amount: 1000},
+		{Month: sep, Currency: "gbp", Kind: model.LedgerFee, Amount: -150},
+	}
+
+	bs := balances(totals)
+	if assert.Len(t, bs, 2) {
+		assert.Equal(t, "eur", bs[0].Currency)
+		assert.Equal(t, 0, bs[0].Balance)
+		assert.Equal(t, 3000, bs[0].Sales)
+		assert.Equal(t, 2550, bs[0].Transferred)
+		assert.Equal(t, "gbp", bs[1].Currency)
+		assert.Equal(t, 850, bs[1].Balance)
+	}
+
+	sts := statements("usr_SELLER", totals)
+	if assert.Len(t, sts, 2) {
+		assert.Equal(t, "2026-09", sts[0].Month)
+		assert.Equal(t, "2026-08", sts[1].Month)
+		if assert.Len(t, sts[1].Currencies, 1) {
+			assert.Equal(t, 0, sts[1].Currencies[0].OpeningBalance)
+			assert.Equal(t, 850, sts[1].Currencies[0].ClosingBalance)
+		}
+		if assert.Len(t, sts[0].Currencies, 2) {
+			eur := sts[0].Currencies[0]
+			assert.Equal(t, 850, eur.OpeningBalance)
+			assert.Equal(t, 425, eur.Refunds)
+			assert.Equal(t, 425, eur.Reversed)
+			assert.Equal(t, 0, eur.ClosingBalance)
+		}
+	}
+
+	// Months without activity carry balances forward.
+	st := statement("usr_SELLER", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), totals)
+	if assert.Len(t, st.Currencies, 1) {
+		assert.Equal(t, "gbp", st.Currencies[0].Currency)
+		assert.Equal(t, 850, st.Currencies[0].OpeningBalance)
+		assert.Equal(t, 850, st.Currencies[0].ClosingBalance)
+	}
+}
+
+func TestStatementCSV(t *testing.T) {
+	saleId := "ORD-000001"
+	created := time.Date(2026, 9, 3, 10, 0, 0, 0, time.UTC)
+	st := &model.PayoutStatement{
+		Seller:     "usr_SELLER",
+		Month:      "2026-09",
+		Currencies: []model.StatementCurrency{{Currency: "eur", OpeningBalance: 100}},
+		Lines: []model.LedgerLine{
+			{Kind: model.LedgerCharge, Origin: "PUR-000001", SaleId: &saleId, Reference: "ch_1",
+				Currency: "eur", Amount: 1001, Description: "Payment for ORD-000001", CreatedAt: created},
+			{Kind: model.LedgerFee, Origin: "PUR-000001", SaleId: &saleId, Reference: "ch_1",
+				Currency: "eur", Amount: -150, Description: "Platform fee", CreatedAt: created},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, writeStatementCSV(buf, st))
+	assert.Equal(t, "date,kind,description,purchase,sale,reference,currency,amount,balance\n"+
+		"2026-09-03T10:00:00Z,charge,Payment for ORD-000001,PUR-000001,ORD-000001,ch_1,EUR,10.01,11.01\n"+
+		"2026-09-03T10:00:00Z,fee,Platform fee,PUR-000001,ORD-000001,ch_1,EUR,-1.50,9.51\n", buf.String())
+
+	assert.Equal(t, "-0.05", decimalAmount(-5, "eur"))
+	assert.Equal(t, "0.00", decimalAmount(0, "eur"))
+	assert.Equal(t, "1500", decimalAmount(1500, "jpy"))
+	assert.Equal(t, "-1.250", decimalAmount(-1250, "kwd"))
+}
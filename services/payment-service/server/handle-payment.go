@@ -8,12 +8,11 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/stripe/stripe-go"
-	"github.com/stripe/stripe-go/paymentintent"
-	"github.com/stripe/stripe-go/paymentmethod"
 	"github.com/veganbase/backend/chassis"
 	_ "github.com/veganbase/backend/services/payment-service/db"
 	"github.com/veganbase/backend/services/payment-service/events"
 	"github.com/veganbase/backend/services/payment-service/model"
+	"github.com/veganbase/backend/services/payment-service/provider"
 	pur "github.com/veganbase/backend/services/purchase-service/model"
 )
 
@@ -39,17 +38,11 @@ func (s *Server) createPaymentIntent(w http.ResponseWriter, r *http.Request) (in
 		return chassis.BadRequest(w, err.Error())
 	}
 
-	totalAmount := make(map[string]int)
-
-	//summing up all values with the same currency
-	//different currencies will be charged separately
-	for _, item := range purchase.Items {
-		totalAmount[item.Currency] += item.Price * item.Quantity
-	}
-
-	//summing up delivery fees with the items total
-	for _, fee := range *purchase.DeliveryFees {
-		totalAmount[fee.Currency] += fee.Price
+	//different currencies are charged separately, unless the purchase
+	//is settled in a single currency
+	totalAmount, err := purchaseTotals(&purchase)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
 	}
 
 	var isSimplePurchase bool
@@ -73,8 +66,8 @@ func (s *Server) createPaymentIntent(w http.ResponseWriter, r *http.Request) (in
 		customerID = customer.CustomerID
 	}
 
-	//validate payment method using Stripe API
-	if _, err = paymentmethod.Get(purchase.PaymentMethod, nil); err != nil {
+	//validate payment method with the payment provider
+	if err = s.provider.ValidatePaymentMethod(purchase.PaymentMethod); err != nil {
 		return nil, errors.New("error validating your payment method within Stripe" + err.Error())
 	}
 
@@ -91,40 +84,35 @@ func (s *Server) createPaymentIntent(w http.ResponseWriter, r *http.Request) (in
 	var errorOccurred bool
 	intents := []model.PaymentIntent{}
 	for currency, amount := range totalAmount {
-		params := &stripe.PaymentIntentParams{
-			PaymentMethod:       stripe.String(purchase.PaymentMethod),
-			Amount:              stripe.Int64(int64(amount)),
-			Currency:            stripe.String(strings.ToLower(currency)),
-			Confirm:             stripe.Bool(true),
+		params := &provider.IntentParams{
+			PaymentMethod:       purchase.PaymentMethod,
+			Amount:              int64(amount),
+			Currency:            strings.ToLower(currency),
 			StatementDescriptor: descriptor,
-			//TransferGroup: stripe.String(purchase.Id),
 		}
 		if !isSimplePurchase {
 			params.Customer = stripe.String(customerID)
 		}
 
-		pi, err := paymentintent.New(params)
+		pi, err := s.provider.CreateIntent(params)
 		if err != nil {
-			//casting a generic error to stripe.Error
-			if stripeErr, ok := err.(*stripe.Error); ok {
-				if cardErr, ok := stripeErr.Err.(*stripe.CardError); ok && stripeErr.PaymentIntent != nil {
-					payInt := model.PaymentIntent{
-						StripeIntentId: stripeErr.PaymentIntent.ID,
-						Origin:         purchase.Id,
-						Status:         string(stripeErr.Code),
-						OriginAmount:   stripeErr.PaymentIntent.Amount,
-						Currency:       stripeErr.PaymentIntent.Currency,
-					}
-					if err = s.db.CreatePaymentIntent(&payInt); err != nil {
-						j, _ := json.Marshal(payInt)
-						s.LogError(payInt.StripeIntentId, "error while saving intent to database: "+string(j))
-					}
-					//even when failing, we must return the payment attempt
-					intents = append(intents, payInt)
-					chassis.Emit(s, events.PaymentCreated, payInt.StripeIntentId)
-
-					s.LogError(payInt.StripeIntentId, "card declined with code: "+string(cardErr.DeclineCode))
+			if declineErr, ok := err.(*provider.DeclineError); ok {
+				payInt := model.PaymentIntent{
+					StripeIntentId: declineErr.Intent.ID,
+					Origin:         purchase.Id,
+					Status:         declineErr.Code,
+					OriginAmount:   declineErr.Intent.Amount,
+					Currency:       declineErr.Intent.Currency,
 				}
+				if err = s.db.CreatePaymentIntent(&payInt); err != nil {
+					j, _ := json.Marshal(payInt)
+					s.LogError(payInt.StripeIntentId, "error while saving intent to database: "+string(j))
+				}
+				//even when failing, we must return the payment attempt
+				intents = append(intents, payInt)
+				chassis.Emit(s, events.PaymentCreated, payInt.StripeIntentId)
+
+				s.LogError(payInt.StripeIntentId, "card declined with code: "+declineErr.DeclineCode)
 			}
 			errorOccurred = true
 			break
@@ -166,11 +154,9 @@ func (s *Server) createPaymentIntent(w http.ResponseWriter, r *http.Request) (in
 
 // confirmPaymentIntent confirm the payment on stripe and updates vb database with the latest status
 func (s *Server) confirmPaymentIntent(w http.ResponseWriter, r *http.Request) (interface{}, error) {
-	stripe.Key = s.stripeKey
-
 	paymentIntentId := chi.URLParam(r, "pi_id")
 
-	stripeIntent, err := paymentintent.Confirm(paymentIntentId, nil)
+	stripeIntent, err := s.provider.ConfirmIntent(paymentIntentId, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -197,6 +183,9 @@ func (s *Server) confirmPaymentIntent(w http.ResponseWriter, r *http.Request) (i
 // rollback a set of payment intents by canceling them on stripe and saving the status on database
 // useful when we couldn't fulfill all payments of a certain purchase (one of them fails, in case of multiple payments)
 func (s *Server) rollback(intents []model.PaymentIntent) {
+	if len(intents) > 0 {
+		s.releaseStock(intents[0].Origin)
+	}
 	for _, intent := range intents {
 		if err := s.CancelIntent(intent.StripeIntentId); err != nil {
 			s.LogError(intent.StripeIntentId, "error occurred canceling intent: "+err.Error())
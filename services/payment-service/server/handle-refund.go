@@ -0,0 +1,570 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/payment-service/model"
+	"github.com/veganbase/backend/services/payment-service/provider"
+	pur "github.com/veganbase/backend/services/purchase-service/model"
+	"github.com/veganbase/backend/services/purchase-service/model/types"
+)
+
+// sale is an order or booking that can be refunded.
+type sale struct {
+	id     string
+	kind   string // "order" or "booking"
+	seller string
+	status types.PaymentStatus
+	totals map[string]int // total value paid, by (lower case) currency
+}
+
+// All the orders and bookings of a purchase, with what was paid for
+// each of them. Amounts are in the currency the buyer was charged in:
+// for purchases settled in a single currency, that's the settlement
+// currency (see settleSales).
+func purchaseSales(purchase *pur.FullPurchase) ([]*sale, error) {
+	paid, err := settleSales(purchase)
+	if err != nil {
+		return nil, err
+	}
+	sales := []*sale{}
+	if purchase.Orders != nil {
+		for _, order := range *purchase.Orders {
+			sales = append(sales, &sale{order.Id, "order", order.Seller, order.PaymentStatus, paid[order.Id]})
+		}
+	}
+	if purchase.Bookings != nil {
+		for _, booking := range *purchase.Bookings {
+			sales = append(sales, &sale{booking.Id, "booking", booking.Host, booking.PaymentStatus, paid[booking.Id]})
+		}
+	}
+	return sales, nil
+}
+
+// A single order or booking of a purchase, by ID.
+func purchaseSale(purchase *pur.FullPurchase, id string) (*sale, error) {
+	sales, err := purchaseSales(purchase)
+	if err != nil {
+		return nil, err
+	}
+	for _, sl := range sales {
+		if sl.id == id {
+			return sl, nil
+		}
+	}
+	return nil, errors.New(id + " not found in purchase " + purchase.Id)
+}
+
+// refundable checks that a sale has been paid for and not yet fully
+// refunded.
+func (sl *sale) refundable() error {
+	switch sl.status {
+	case types.Completed, types.PartiallyRefunded:
+		return nil
+	case types.Refunded:
+		return errors.New(sl.kind + " " + sl.id + " has already been refunded")
+	default:
+		return errors.New(sl.kind + " " + sl.id + " has not been paid for")
+	}
+}
+
+// refundAmounts works out how much to refund in each currency for a
+// refund request, given what has already been refunded. With no
+// amount in the request, everything not yet refunded is refunded.
+func (sl *sale) refundAmounts(refunded map[string]int, req *model.RefundRequest) (map[string]int, error) {
+	amounts := map[string]int{}
+	if req.Amount == nil {
+		for currency, total := range sl.totals {
+			if left := total - refunded[currency]; left > 0 {
+				amounts[currency] = left
+			}
+		}
+		if len(amounts) == 0 {
+			return nil, errors.New(sl.kind + " " + sl.id + " has already been refunded")
+		}
+		return amounts, nil
+	}
+
+	var currency string
+	switch {
+	case req.Currency != nil:
+		currency = strings.ToLower(*req.Currency)
+		if _, ok := sl.totals[currency]; !ok {
+			return nil, errors.New(sl.kind + " " + sl.id + " was not paid in " + *req.Currency)
+		}
+	case len(sl.totals) == 1:
+		for c := range sl.totals {
+			currency = c
+		}
+	default:
+		return nil, errors.New("currency must be given for " + sl.kind + " paid in more than one currency")
+	}
+
+	left := sl.totals[currency] - refunded[currency]
+	if *req.Amount <= 0 || *req.Amount > left {
+		return nil, errors.New("refund amount must be between 1 and " + strconv.Itoa(left))
+	}
+	amounts[currency] = *req.Amount
+	return amounts, nil
+}
+
+// refundStatus works out the payment status of a sale from the
+// amounts refunded for it.
+func (sl *sale) refundStatus(refunded map[string]int) types.PaymentStatus {
+	status := sl.status
+	if len(refunded) == 0 {
+		return status
+	}
+	status = types.Refunded
+	for currency, total := range sl.totals {
+		if refunded[currency] < total {
+			status = types.PartiallyRefunded
+		}
+	}
+	return status
+}
+
+// Amounts refunded for each sale of a purchase, by currency. Refunds
+// that failed don't count.
+func refundedBySale(refunds *[]model.Refund) map[string]map[string]int {
+	result := map[string]map[string]int{}
+	for _, refund := range *refunds {
+		if refund.Failed() || refund.SaleId == nil {
+			continue
+		}
+		if _, ok := result[*refund.SaleId]; !ok {
+			result[*refund.SaleId] = map[string]int{}
+		}
+		result[*refund.SaleId][refund.Currency] += refund.Amount
+	}
+	return result
+}
+
+// refundPurchase makes a full refund of all the paid-for orders and
+// bookings of a purchase. Only administrators can do this: sellers
+// refund their own orders and bookings individually.
+func (s *Server) refundPurchase(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+	if !authInfo.UserIsAdmin {
+		return chassis.Forbidden(w)
+	}
+
+	req, err := readRefundRequest(r)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if req.Amount != nil {
+		return chassis.BadRequest(w, "partial refunds must be made for individual orders or bookings")
+	}
+
+	purchase, err := s.purchaseSvc.GetPurchaseInfo(chi.URLParam(r, "pur_id"))
+	if err != nil {
+		return chassis.NotFoundWithMessage(w, "purchase not found")
+	}
+
+	all, err := purchaseSales(purchase)
+	if err != nil {
+		return nil, err
+	}
+	sales := []*sale{}
+	for _, sl := range all {
+		if sl.refundable() == nil {
+			sales = append(sales, sl)
+		}
+	}
+	if len(sales) == 0 {
+		return chassis.BadRequest(w, "purchase has nothing to refund")
+	}
+
+	return s.refundSales(w, purchase, sales, req, authInfo.UserID)
+}
+
+// refundOrder refunds all or part of an order. Administrators and the
+// seller can do this.
+func (s *Server) refundOrder(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	order, err := s.purchaseSvc.GetOrder(chi.URLParam(r, "ord_id"))
+	if err != nil {
+		return chassis.NotFoundWithMessage(w, "order not found")
+	}
+
+	purchase, err := s.purchaseSvc.GetPurchaseInfo(order.Origin)
+	if err != nil {
+		return nil, err
+	}
+	sl, err := purchaseSale(purchase, order.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refundSale(w, r, authInfo, purchase, sl)
+}
+
+// refundBooking refunds all or part of a booking. Administrators and
+// the host can do this.
+func (s *Server) refundBooking(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	booking, err := s.purchaseSvc.GetBooking(chi.URLParam(r, "bok_id"))
+	if err != nil {
+		return chassis.NotFoundWithMessage(w, "booking not found")
+	}
+
+	purchase, err := s.purchaseSvc.GetPurchaseInfo(booking.Origin)
+	if err != nil {
+		return nil, err
+	}
+	sl, err := purchaseSale(purchase, booking.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refundSale(w, r, authInfo, purchase, sl)
+}
+
+func (s *Server) refundSale(w http.ResponseWriter, r *http.Request, authInfo *chassis.AuthInfo,
+	purchase *pur.FullPurchase, sl *sale) (interface{}, error) {
+	if !authInfo.UserIsAdmin && authInfo.UserID != sl.seller {
+		isMember, err := s.userSvc.IsUserOrgMember(authInfo.UserID, sl.seller)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return chassis.NotFound(w)
+		}
+	}
+
+	req, err := readRefundRequest(r)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if err = sl.refundable(); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	return s.refundSales(w, purchase, []*sale{sl}, req, authInfo.UserID)
+}
+
+// refundError is the error returned when a refund can't be made as
+// requested.
+type refundError struct {
+	msg string
+}
+
+func (e *refundError) Error() string {
+	return e.msg
+}
+
+// Refund some of the sales of a purchase, reverse the transfers made
+// to the sellers, update the payment statuses of the sales and the
+// purchase and notify everyone concerned.
+func (s *Server) refundSales(w http.ResponseWriter, purchase *pur.FullPurchase, sales []*sale,
+	req *model.RefundRequest, refundedBy string) (interface{}, error) {
+	made, reversed, err := s.makeRefunds(purchase, sales, req, refundedBy)
+	if rerr, ok := err.(*refundError); ok {
+		return chassis.BadRequest(w, rerr.Error())
+	}
+
+	// Refunds already made stand, even if a later one failed, so the
+	// statuses still need updating and everyone still needs notifying.
+	result := []model.Refund{}
+	for _, sl := range sales {
+		if len(made[sl.id]) == 0 {
+			continue
+		}
+		go s.sendRefundNotifications(purchase, sl, made[sl.id], reversed)
+		result = append(result, made[sl.id]...)
+	}
+	if len(result) > 0 {
+		s.updateRefundStatuses(purchase)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// makeRefunds refunds sales and reverses the transfers made for them,
+// returning the refunds made for each sale and the value reversed for
+// each refund. The sales are locked while this happens, and what has
+// already been refunded is only read once the locks are held, so
+// concurrent requests can't both refund the same amounts.
+func (s *Server) makeRefunds(purchase *pur.FullPurchase, sales []*sale, req *model.RefundRequest,
+	refundedBy string) (map[string][]model.Refund, map[string]int, error) {
+	made := map[string][]model.Refund{}
+	reversed := map[string]int{}
+	ids := []string{}
+	for _, sl := range sales {
+		ids = append(ids, sl.id)
+	}
+
+	err := s.db.LockSales(ids, func() error {
+		previous, err := s.db.RefundsByOrigin(purchase.Id)
+		if err != nil {
+			return err
+		}
+		refunded := refundedBySale(previous)
+
+		// Check everything before refunding anything.
+		amounts := map[string]map[string]int{}
+		for _, sl := range sales {
+			if amounts[sl.id], err = sl.refundAmounts(refunded[sl.id], req); err != nil {
+				return &refundError{err.Error()}
+			}
+		}
+
+		for _, sl := range sales {
+			for _, currency := range sortedCurrencies(amounts[sl.id]) {
+				refund, err := s.refund(purchase.Id, sl, currency, amounts[sl.id][currency],
+					refunded[sl.id][currency], req.Reason, refundedBy)
+				if err != nil {
+					return err
+				}
+				reversed[refund.RefundId] = s.reverseTransfers(sl, refund)
+				made[sl.id] = append(made[sl.id], *refund)
+			}
+		}
+		return nil
+	})
+	return made, reversed, err
+}
+
+// Refund an amount paid for a sale in one currency, given the amount
+// already refunded. The two amounts make up the idempotency key for
+// the refund, so a request that's repeated after the refund was made
+// but before it was recorded doesn't refund the buyer twice. Saving
+// the refund is retried while the sale is still locked; if it can't
+// be saved, an error is returned so that the refund isn't reported as
+// made, and a repeat of the same request records it.
+func (s *Server) refund(purchaseId string, sl *sale, currency string, amount, refunded int,
+	reason *string, refundedBy string) (*model.Refund, error) {
+	intents, err := s.db.SuccessfulPaymentIntentsByOrigin(purchaseId)
+	if err != nil {
+		return nil, err
+	}
+	var payInt *model.PaymentIntent
+	for i, pi := range *intents {
+		if pi.Status == "succeeded" && strings.ToLower(pi.Currency) == currency {
+			payInt = &(*intents)[i]
+			break
+		}
+	}
+	if payInt == nil {
+		return nil, errors.New("no successful payment in " + currency + " for purchase " + purchaseId)
+	}
+
+	pi, err := s.provider.GetIntent(payInt.StripeIntentId)
+	if err != nil {
+		return nil, err
+	}
+	if pi.Charges == nil || len(pi.Charges.Data) == 0 {
+		return nil, errors.New("no charge found for payment " + pi.ID)
+	}
+
+	rf, err := s.provider.CreateRefund(&provider.RefundParams{
+		Charge: pi.Charges.Data[0].ID,
+		Amount: int64(amount),
+		Metadata: map[string]string{
+			"origin":  purchaseId,
+			"sale_id": sl.id,
+		},
+		IdempotencyKey: "refund-" + sl.id + "-" + currency + "-" +
+			strconv.Itoa(refunded) + "-" + strconv.Itoa(amount),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refund := model.Refund{
+		RefundId:   rf.ID,
+		Origin:     purchaseId,
+		SaleId:     &sl.id,
+		IntentId:   payInt.StripeIntentId,
+		Currency:   currency,
+		Amount:     int(rf.Amount),
+		Status:     string(rf.Status),
+		Reason:     reason,
+		RefundedBy: &refundedBy,
+	}
+	for attempt := 1; ; attempt++ {
+		if err = s.db.CreateRefund(&refund); err == nil {
+			break
+		}
+		j, _ := json.Marshal(refund)
+		s.LogError(refund.RefundId, "error while saving refund on database: "+string(j)+": "+err.Error())
+		if attempt == refundSaveAttempts {
+			return nil, err
+		}
+		time.Sleep(time.Duration(attempt) * refundSaveRetryDelay)
+	}
+	s.recordRefund(&refund)
+	return &refund, nil
+}
+
+// How many times saving a refund is tried, and the delay before the
+// first retry (which grows with each attempt).
+const refundSaveAttempts = 3
+
+var refundSaveRetryDelay = 200 * time.Millisecond
+
+// Reverse the transfers made to the seller for a sale in proportion to
+// the amount refunded: the seller gives back their share of the
+// refund, and Veganbase gives back the fee it collected on it. Returns
+// the total value reversed. Errors are logged rather than returned,
+// since the buyer has been refunded by this point.
+func (s *Server) reverseTransfers(sl *sale, refund *model.Refund) int {
+	remainders, err := s.db.TransferRemaindersBySale(sl.id)
+	if err != nil {
+		s.LogError(refund.RefundId, "error while getting transfers for "+sl.kind+" "+sl.id+": "+err.Error())
+		return 0
+	}
+
+	reversed := 0
+	found := false
+	for _, tr := range *remainders {
+		if tr.Currency != refund.Currency || tr.TotalValue == 0 {
+			continue
+		}
+		found = true
+		amount := refund.Amount * tr.TransferredValue / tr.TotalValue
+		if left := tr.TransferredValue - tr.ReversedValue; amount > left {
+			amount = left
+		}
+		if amount <= 0 {
+			continue
+		}
+
+		rev, err := s.provider.ReverseTransfer(&provider.ReversalParams{
+			Transfer: tr.TransferId,
+			Amount:   int64(amount),
+			Metadata: map[string]string{"refund_id": refund.RefundId},
+		})
+		if err != nil {
+			s.LogError(refund.RefundId, "error while reversing transfer "+tr.TransferId+": "+err.Error())
+			continue
+		}
+		reversal := model.TransferReversal{
+			ReversalId: rev.ID,
+			TransferId: tr.TransferId,
+			RefundId:   refund.RefundId,
+			Amount:     int(rev.Amount),
+		}
+		if err = s.db.CreateTransferReversal(&reversal); err != nil {
+			s.LogError(refund.RefundId, "error while saving reversal "+rev.ID+" on database: "+err.Error())
+		}
+		s.recordLedger(rev.ID, reversalPosting(&reversal, &tr))
+		reversed += reversal.Amount
+	}
+
+	if !found {
+		s.LogError(refund.RefundId, "no transfer in "+refund.Currency+" found to reverse for "+sl.kind+" "+sl.id)
+	}
+	return reversed
+}
+
+// Update the payment statuses of the sales of a purchase, and the
+// status of the purchase itself, from the refunds recorded for it.
+func (s *Server) updateRefundStatuses(purchase *pur.FullPurchase) {
+	refunds, err := s.db.RefundsByOrigin(purchase.Id)
+	if err != nil {
+		s.LogError(purchase.Id, "error while getting refunds on database: "+err.Error())
+		return
+	}
+	if len(*refunds) == 0 {
+		return
+	}
+
+	refunded := refundedBySale(refunds)
+	sales, err := purchaseSales(purchase)
+	if err != nil {
+		s.LogError(purchase.Id, "error while working out sale totals: "+err.Error())
+		return
+	}
+	for _, sl := range sales {
+		status := sl.refundStatus(refunded[sl.id])
+		if status == sl.status {
+			continue
+		}
+		if sl.kind == "order" {
+			_, err = s.purchaseSvc.UpdateOrderPaymentStatus(sl.id, status.String())
+		} else {
+			_, err = s.purchaseSvc.UpdateBookingPaymentStatus(sl.id, status.String())
+		}
+		if err != nil {
+			s.LogError(sl.id, "error while updating "+sl.kind+" payment status on purchase-service: "+err.Error())
+		}
+	}
+
+	// Refunds made outside the service aren't linked to a sale, so the
+	// purchase status is based on all the refunds and payments made.
+	intents, err := s.db.SuccessfulPaymentIntentsByOrigin(purchase.Id)
+	if err != nil {
+		s.LogError(purchase.Id, "error while getting payment-intents on database: "+err.Error())
+		return
+	}
+	paid, refundedTotal := 0, 0
+	for _, pi := range *intents {
+		if pi.Status == "succeeded" {
+			paid += int(pi.OriginAmount)
+		}
+	}
+	for _, refund := range *refunds {
+		if !refund.Failed() {
+			refundedTotal += refund.Amount
+		}
+	}
+	if refundedTotal == 0 {
+		return
+	}
+	status := types.PurchaseStatus(types.PartiallyRefunded)
+	if refundedTotal >= paid {
+		status = types.Refunded
+	}
+	if status != purchase.Status {
+		if _, err = s.purchaseSvc.UpdatePurchaseStatus(purchase.Id, status.String()); err != nil {
+			s.LogError(purchase.Id, "error while updating purchase status on purchase-service: "+err.Error())
+		}
+	}
+}
+
+func readRefundRequest(r *http.Request) (*model.RefundRequest, error) {
+	req := model.RefundRequest{}
+	body, err := chassis.ReadBody(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > 0 {
+		if err = json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+	}
+	return &req, nil
+}
+
+func sortedCurrencies(amounts map[string]int) []string {
+	currencies := []string{}
+	for currency := range amounts {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
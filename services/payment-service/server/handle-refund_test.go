@@ -0,0 +1,304 @@
+package server
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/veganbase/backend/services/payment-service/db"
+	"github.com/veganbase/backend/services/payment-service/model"
+	"github.com/veganbase/backend/services/payment-service/provider"
+	pur "github.com/veganbase/backend/services/purchase-service/model"
+	"github.com/veganbase/backend/services/purchase-service/model/types"
+	site "github.com/veganbase/backend/services/site-service/model"
+)
+
+func intPtr(i int) *int       { return &i }
+func strPtr(s string) *string { return &s }
+
+// The sale for the only order or booking of a purchase.
+func orderSale(purchase *pur.Purchase, order *pur.Order) (*sale, error) {
+	return purchaseSale(&pur.FullPurchase{Purchase: *purchase, Orders: &[]pur.Order{*order}}, order.Id)
+}
+
+func bookingSale(purchase *pur.Purchase, booking *pur.Booking) (*sale, error) {
+	return purchaseSale(&pur.FullPurchase{Purchase: *purchase, Bookings: &[]pur.Booking{*booking}}, booking.Id)
+}
+
+func TestSaleRefundAmounts(t *testing.T) {
+	order := &pur.Order{
+		Id:            "ORD-000001",
+		Seller:        "usr_SELLER",
+		PaymentStatus: types.Completed,
+		Items: types.PurchaseItems{
+			{Price: 1000, Quantity: 2, Currency: "EUR"},
+			{Price: 500, Quantity: 1, Currency: "GBP"},
+		},
+		DeliveryFee: &pur.DeliveryFee{Price: 300, Currency: "EUR"},
+	}
+	sl, err := orderSale(&pur.Purchase{}, order)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 2300, "gbp": 500}, sl.totals)
+	assert.Nil(t, sl.refundable())
+
+	// Full refunds cover everything not yet refunded.
+	amounts, err := sl.refundAmounts(map[string]int{"eur": 300}, &model.RefundRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 2000, "gbp": 500}, amounts)
+
+	// Partial refunds need a currency when more than one was used.
+	_, err = sl.refundAmounts(nil, &model.RefundRequest{Amount: intPtr(100)})
+	assert.NotNil(t, err)
+	amounts, err = sl.refundAmounts(nil, &model.RefundRequest{Amount: intPtr(100), Currency: strPtr("GBP")})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"gbp": 100}, amounts)
+	_, err = sl.refundAmounts(map[string]int{"gbp": 450}, &model.RefundRequest{Amount: intPtr(100), Currency: strPtr("gbp")})
+	assert.NotNil(t, err)
+	_, err = sl.refundAmounts(nil, &model.RefundRequest{Amount: intPtr(100), Currency: strPtr("usd")})
+	assert.NotNil(t, err)
+	_, err = sl.refundAmounts(map[string]int{"eur": 2300, "gbp": 500}, &model.RefundRequest{})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, types.PaymentStatus(types.Completed), sl.refundStatus(nil))
+	assert.Equal(t, types.PaymentStatus(types.PartiallyRefunded), sl.refundStatus(map[string]int{"eur": 2300}))
+	assert.Equal(t, types.PaymentStatus(types.Refunded), sl.refundStatus(map[string]int{"eur": 2300, "gbp": 500}))
+}
+
+func TestDiscountedOrderSale(t *testing.T) {
+	order := &pur.Order{
+		Id:            "ORD-000002",
+		PaymentStatus: types.Completed,
+		Items:         types.PurchaseItems{{Price: 1000, Quantity: 2, Currency: "EUR"}},
+		Discounts: &pur.Discounts{
+			{Amount: 400, Currency: "EUR", FundedBy: pur.FundedByPlatform},
+		},
+	}
+	sl, err := orderSale(&pur.Purchase{}, order)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 1600}, sl.totals)
+
+	// Orders priced in more than one currency have a discount in each.
+	order.Items = append(order.Items, types.PurchaseItem{Price: 1500, Quantity: 1, Currency: "GBP"})
+	*order.Discounts = append(*order.Discounts, pur.Discount{Amount: 300, Currency: "GBP", FundedBy: pur.FundedBySeller})
+	sl, err = orderSale(&pur.Purchase{}, order)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 1600, "gbp": 1200}, sl.totals)
+}
+
+func TestSettledSale(t *testing.T) {
+	// A purchase charged in EUR, with an order priced in GBP.
+	purchase := &pur.Purchase{
+		Currency: "EUR",
+		ExchangeRates: &site.ExchangeRates{
+			Base:  "EUR",
+			Rates: map[string]float64{"EUR": 1, "GBP": 0.8, "USD": 0.8},
+		},
+		Items:        types.PurchaseItems{{Price: 1000, Quantity: 2, Currency: "GBP"}},
+		DeliveryFees: &pur.DeliveryFees{{Price: 400, Currency: "GBP"}},
+	}
+	order := &pur.Order{
+		Id:            "ORD-000003",
+		PaymentStatus: types.Completed,
+		Items:         types.PurchaseItems{{Price: 1000, Quantity: 2, Currency: "GBP"}},
+		DeliveryFee:   &pur.DeliveryFee{Price: 400, Currency: "GBP"},
+		ExchangeRate:  &site.ExchangeRate{From: "GBP", To: "EUR", Rate: 1.25},
+	}
+	sl, err := orderSale(purchase, order)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 3000}, sl.totals)
+
+	// Refunds are made in the currency the buyer was charged in.
+	amounts, err := sl.refundAmounts(map[string]int{"eur": 880}, &model.RefundRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 2120}, amounts)
+	_, err = sl.refundAmounts(nil, &model.RefundRequest{Amount: intPtr(100), Currency: strPtr("GBP")})
+	assert.NotNil(t, err)
+	assert.Equal(t, types.PaymentStatus(types.PartiallyRefunded), sl.refundStatus(map[string]int{"eur": 880}))
+	assert.Equal(t, types.PaymentStatus(types.Refunded), sl.refundStatus(map[string]int{"eur": 3000}))
+
+	booking := &pur.Booking{Id: "BOK-000002", PaymentStatus: types.Completed}
+	booking.BookingInfo.Price = 5000
+	booking.BookingInfo.Quantity = 1
+	booking.BookingInfo.Currency = "USD"
+	purchase.Items = types.PurchaseItems{{Price: 5000, Quantity: 1, Currency: "USD"}}
+	purchase.DeliveryFees = nil
+	sl, err = bookingSale(purchase, booking)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 6250}, sl.totals)
+}
+
+func TestSaleRefundable(t *testing.T) {
+	booking := &pur.Booking{Id: "BOK-000001", PaymentStatus: types.Pending}
+	booking.BookingInfo.Price = 4500
+	booking.BookingInfo.Quantity = 2
+	booking.BookingInfo.Currency = "EUR"
+	sl, err := bookingSale(&pur.Purchase{}, booking)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 9000}, sl.totals)
+	assert.NotNil(t, sl.refundable())
+	sl.status = types.PartiallyRefunded
+	assert.Nil(t, sl.refundable())
+	sl.status = types.Refunded
+	assert.NotNil(t, sl.refundable())
+
+	// A single currency doesn't need to be given.
+	amounts, err := sl.refundAmounts(nil, &model.RefundRequest{Amount: intPtr(9000)})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 9000}, amounts)
+}
+
+func TestRefundedBySale(t *testing.T) {
+	ord := "ORD-000001"
+	refunds := []model.Refund{
+		{SaleId: &ord, Currency: "eur", Amount: 100, Status: "succeeded"},
+		{SaleId: &ord, Currency: "eur", Amount: 200, Status: "pending"},
+		{SaleId: &ord, Currency: "eur", Amount: 400, Status: "failed"},
+		{Currency: "eur", Amount: 800, Status: "succeeded"},
+	}
+	assert.Equal(t, map[string]map[string]int{ord: {"eur": 300}}, refundedBySale(&refunds))
+}
+
+// refundDB keeps the payments and refunds of a purchase in memory, with
+// a single lock standing in for the locks on sales.
+type refundDB struct {
+	db.DB
+	lock    sync.Mutex
+	mu      sync.Mutex
+	intents []model.PaymentIntent
+	refunds []model.Refund
+
+	// Number of times saving a refund should fail.
+	saveFailures int
+}
+
+func (d *refundDB) LockSales(saleIds []string, locked func() error) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return locked()
+}
+
+func (d *refundDB) RefundsByOrigin(origin string) (*[]model.Refund, error) {
+	d.mu.Lock()
+	refunds := append([]model.Refund{}, d.refunds...)
+	d.mu.Unlock()
+
+	// Give concurrent requests a chance to overlap.
+	time.Sleep(10 * time.Millisecond)
+	return &refunds, nil
+}
+
+func (d *refundDB) CreateRefund(refund *model.Refund) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.saveFailures > 0 {
+		d.saveFailures--
+		return errors.New("database unavailable")
+	}
+	d.refunds = append(d.refunds, *refund)
+	return nil
+}
+
+func (d *refundDB) SuccessfulPaymentIntentsByOrigin(origin string) (*[]model.PaymentIntent, error) {
+	return &d.intents, nil
+}
+
+func (d *refundDB) TransferRemaindersBySale(saleId string) (*[]model.TransferRemainder, error) {
+	return &[]model.TransferRemainder{}, nil
+}
+
+func (d *refundDB) LedgerTransactionsBySale(saleId string) (*[]model.LedgerTransaction, error) {
+	return &[]model.LedgerTransaction{}, nil
+}
+
+func (d *refundDB) CreateErrorLog(log *model.ErrorLog) error {
+	return nil
+}
+
+func TestConcurrentRefunds(t *testing.T) {
+	f := provider.NewFake(-1, nil)
+	pi, err := f.CreateIntent(&provider.IntentParams{
+		PaymentMethod: provider.FakeCardSuccess, Amount: 3000, Currency: "eur",
+	})
+	assert.Nil(t, err)
+	d := &refundDB{intents: []model.PaymentIntent{{
+		StripeIntentId: pi.ID, Origin: "PUR-000001", Status: "succeeded",
+		Currency: "eur", OriginAmount: 3000,
+	}}}
+	s := &Server{db: d, provider: f}
+
+	// Several requests to refund most of the same order arrive at once.
+	// Only one of them can be made.
+	purchase := &pur.FullPurchase{Purchase: pur.Purchase{Id: "PUR-000001"}}
+	sl := &sale{"ORD-000001", "order", "usr_SELLER", types.Completed, map[string]int{"eur": 2000}}
+	var wg sync.WaitGroup
+	results := make([]error, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, results[i] = s.makeRefunds(purchase, []*sale{sl},
+				&model.RefundRequest{Amount: intPtr(1500)}, "usr_SELLER")
+		}(i)
+	}
+	wg.Wait()
+
+	made := 0
+	for _, err := range results {
+		if err == nil {
+			made++
+			continue
+		}
+		_, ok := err.(*refundError)
+		assert.True(t, ok, "unexpected error: %v", err)
+	}
+	assert.Equal(t, 1, made)
+	assert.Len(t, d.refunds, 1)
+	pi, err = f.GetIntent(pi.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1500), pi.Charges.Data[0].AmountRefunded)
+
+	// The rest can still be refunded.
+	refunds, _, err := s.makeRefunds(purchase, []*sale{sl}, &model.RefundRequest{}, "usr_SELLER")
+	assert.Nil(t, err)
+	if assert.Len(t, refunds[sl.id], 1) {
+		assert.Equal(t, 500, refunds[sl.id][0].Amount)
+	}
+}
+
+func TestRefundSaveFailure(t *testing.T) {
+	refundSaveRetryDelay = time.Millisecond
+	f := provider.NewFake(-1, nil)
+	pi, err := f.CreateIntent(&provider.IntentParams{
+		PaymentMethod: provider.FakeCardSuccess, Amount: 3000, Currency: "eur",
+	})
+	assert.Nil(t, err)
+	d := &refundDB{intents: []model.PaymentIntent{{
+		StripeIntentId: pi.ID, Origin: "PUR-000001", Status: "succeeded",
+		Currency: "eur", OriginAmount: 3000,
+	}}}
+	s := &Server{db: d, provider: f}
+	purchase := &pur.FullPurchase{Purchase: pur.Purchase{Id: "PUR-000001"}}
+	sl := &sale{"ORD-000001", "order", "usr_SELLER", types.Completed, map[string]int{"eur": 2000}}
+	req := &model.RefundRequest{Amount: intPtr(1500)}
+
+	// A refund that can't be saved isn't reported as made.
+	d.saveFailures = refundSaveAttempts
+	refunds, _, err := s.makeRefunds(purchase, []*sale{sl}, req, "usr_SELLER")
+	assert.NotNil(t, err)
+	assert.Empty(t, refunds[sl.id])
+	assert.Empty(t, d.refunds)
+
+	// Repeating the request records the refund already made, without
+	// refunding the buyer again, retrying if saving fails.
+	d.saveFailures = refundSaveAttempts - 1
+	refunds, _, err = s.makeRefunds(purchase, []*sale{sl}, req, "usr_SELLER")
+	assert.Nil(t, err)
+	assert.Len(t, refunds[sl.id], 1)
+	assert.Len(t, d.refunds, 1)
+	pi, err = f.GetIntent(pi.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1500), pi.Charges.Data[0].AmountRefunded)
+}
@@ -2,15 +2,14 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/stripe/stripe-go"
-	"github.com/stripe/stripe-go/paymentintent"
-	"github.com/stripe/stripe-go/transfer"
-	"github.com/stripe/stripe-go/webhook"
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/payment-service/db"
 	"github.com/veganbase/backend/services/payment-service/events"
 	"github.com/veganbase/backend/services/payment-service/model"
+	"github.com/veganbase/backend/services/payment-service/provider"
 	pur "github.com/veganbase/backend/services/purchase-service/model"
 	"io/ioutil"
 	"math"
@@ -23,6 +22,10 @@ const (
 	MinFraction         = 0.00005
 )
 
+// errTransfersDisabled is the reason recorded for transfers queued
+// because the seller's payout account can't receive them.
+var errTransfersDisabled = errors.New("payout account can't receive transfers")
+
 func (s *Server) webhookHandler(w http.ResponseWriter, r *http.Request) (interface{}, error) {
 
 	payload, err := ioutil.ReadAll(r.Body)
@@ -30,21 +33,26 @@ func (s *Server) webhookHandler(w http.ResponseWriter, r *http.Request) (interfa
 		return nil, err
 	}
 
-	var event stripe.Event
+	//this also validates that the one calling the webhook path is Stripe
+	event, err := s.provider.ConstructEvent(payload, r.Header.Get("Stripe-Signature"))
+	if err != nil {
+		return chassis.BadRequest(w, "error occurred while validating Stripe's signature")
+	}
 
-	if s.webhookSecret != "" {
-		//this is required to validate that the one calling the webhook path is Stripe
-		event, err = webhook.ConstructEvent(payload, r.Header.Get("Stripe-Signature"), s.webhookSecret)
-		if err != nil {
-			return chassis.BadRequest(w, "error occurred while validating Stripe's signature")
-		}
-	} else {
-		err := json.Unmarshal(payload, &event)
-		if err != nil {
-			return nil, err
-		}
+	return nil, s.handleEvent(event)
+}
+
+// handleFakeEvent handles webhook events sent by the fake payment
+// provider.
+func (s *Server) handleFakeEvent(event *stripe.Event) {
+	if err := s.handleEvent(event); err != nil {
+		s.LogError(event.ID, "error handling fake provider event: "+err.Error())
 	}
+}
 
+// handleEvent records and dispatches a webhook event.
+func (s *Server) handleEvent(event *stripe.Event) error {
+	var err error
 	objectType := event.Data.Object["object"].(string)
 	if check, err := s.db.ReceivedEventByEventId(event.ID); err != nil {
 		if err == db.ErrReceivedEventNotFound {
@@ -64,7 +72,7 @@ func (s *Server) webhookHandler(w http.ResponseWriter, r *http.Request) (interfa
 		if check != nil {
 			fmt.Printf("🔔  Webhook received, but the event is already in the database and wont be handled: %s\n", event.Type)
 			s.LogError(event.ID, "event "+event.ID+" of type '"+event.Type+"' received but is duplicated. Will not be handled.")
-			return nil, nil
+			return nil
 		}
 	}
 
@@ -73,17 +81,37 @@ func (s *Server) webhookHandler(w http.ResponseWriter, r *http.Request) (interfa
 	case "payment_intent":
 		var pi *stripe.PaymentIntent
 		if err = json.Unmarshal(event.Data.Raw, &pi); err != nil {
-			return nil, err
+			return err
 		}
 		handled = true
-		go s.HandlePaymentIntent(&event, pi, true)
+		go s.HandlePaymentIntent(event, pi, true)
 	case "source":
 		var source *stripe.Source
 		if err := json.Unmarshal(event.Data.Raw, &source); err != nil {
-			return nil, err
+			return err
+		}
+		handled = true
+		go s.HandleSource(*event, source)
+	case "charge":
+		if event.Type != "charge.refunded" {
+			break
+		}
+		var ch *stripe.Charge
+		if err = json.Unmarshal(event.Data.Raw, &ch); err != nil {
+			return err
+		}
+		handled = true
+		go s.HandleChargeRefunded(event, ch)
+	case "account":
+		if event.Type != "account.updated" {
+			break
+		}
+		var acc *stripe.Account
+		if err = json.Unmarshal(event.Data.Raw, &acc); err != nil {
+			return err
 		}
 		handled = true
-		go s.HandleSource(event, source)
+		go s.HandleAccountUpdated(event, acc)
 	}
 
 	if handled {
@@ -93,28 +121,7 @@ func (s *Server) webhookHandler(w http.ResponseWriter, r *http.Request) (interfa
 		fmt.Printf("🔔  Webhook received and not handled! %s\n", event.Type)
 	}
 
-	return nil, err
-}
-
-func (s *Server) confirmIntent(paymentIntent string, source *stripe.Source) error {
-	pi, err := paymentintent.Get(paymentIntent, nil)
-	if err != nil {
-		return fmt.Errorf("payments: error fetching payment intent for confirmation: %v", err)
-	}
-
-	if pi.Status != "requires_payment_method" {
-		return fmt.Errorf("payments: PaymentIntent already has a status of %s", pi.Status)
-	}
-
-	params := &stripe.PaymentIntentConfirmParams{
-		Source: stripe.String(source.ID),
-	}
-	_, err = paymentintent.Confirm(pi.ID, params)
-	if err != nil {
-		return fmt.Errorf("payments: error confirming PaymentIntent: %v", err)
-	}
-
-	return nil
+	return err
 }
 
 func (s *Server) HandlePaymentIntent(event *stripe.Event, pi *stripe.PaymentIntent, isFirstAttempt bool) {
@@ -163,10 +170,6 @@ func (s *Server) HandlePaymentIntent(event *stripe.Event, pi *stripe.PaymentInte
 			if err := s.updateStatus(pi, payInt); err != nil {
 				s.LogError(event.ID, "Error while updating status of payment-intent: "+err.Error())
 			}
-			//updating purchase on purchase-service
-			if _, err := s.purchaseSvc.UpdatePurchaseStatus(payInt.Origin, "failed"); err != nil {
-				s.LogError(event.ID, "Error while updating purchase status on purchase-service: "+err.Error())
-			}
 			//TODO: EMIT MSG TELLING THE BUYER THAT ONE PAYMENT FAILED
 		} else {
 			fmt.Printf(
@@ -178,16 +181,84 @@ func (s *Server) HandlePaymentIntent(event *stripe.Event, pi *stripe.PaymentInte
 			if err := s.updateStatus(pi, payInt); err != nil {
 				s.LogError(event.ID, "Error while updating status of payment-intent: "+err.Error())
 			}
-			//updating purchase on purchase-service
-			if _, err := s.purchaseSvc.UpdatePurchaseStatus(payInt.Origin, "failed"); err != nil {
-				s.LogError(event.ID, "Error while updating purchase status on purchase-service: "+err.Error())
-			}
 		}
+		//updating purchase on purchase-service
+		if _, err := s.purchaseSvc.UpdatePurchaseStatus(payInt.Origin, "failed"); err != nil {
+			s.LogError(event.ID, "Error while updating purchase status on purchase-service: "+err.Error())
+		}
+		//returning the stock reserved for the purchase to sale
+		s.releaseStock(payInt.Origin)
 		go s.sendPaymentReceivedNotification(payInt, false)
 	}
 
 }
 
+// HandleChargeRefunded records the refunds of a charge and updates
+// the purchase's payment statuses. Refunds made through the service
+// are already recorded, but refunds can also be made from Stripe's
+// dashboard, and the buyer is told about those here.
+func (s *Server) HandleChargeRefunded(event *stripe.Event, ch *stripe.Charge) {
+	fmt.Printf("🔔  Webhook received! Charge %s refunded\n", ch.ID)
+
+	payInt, err := s.db.PaymentIntentByIntentId(ch.PaymentIntent)
+	if err != nil {
+		s.LogError(event.ID, "error while getting payment-intent "+ch.PaymentIntent+" of refunded charge: "+err.Error())
+		return
+	}
+	if ch.Refunds == nil {
+		return
+	}
+
+	outside := []model.Refund{}
+	for _, rf := range ch.Refunds.Data {
+		refund, err := s.db.RefundByRefundId(rf.ID)
+		switch err {
+		case nil:
+			if refund.Status != string(rf.Status) {
+				wasFailed := refund.Failed()
+				refund.Status = string(rf.Status)
+				if err = s.db.UpdateRefund(refund); err != nil {
+					s.LogError(event.ID, "error while updating refund "+rf.ID+": "+err.Error())
+				}
+				if refund.Failed() && !wasFailed {
+					s.recordRefund(refund)
+				}
+			}
+		case db.ErrRefundNotFound:
+			refund = &model.Refund{
+				RefundId: rf.ID,
+				Origin:   payInt.Origin,
+				IntentId: payInt.StripeIntentId,
+				Currency: strings.ToLower(string(rf.Currency)),
+				Amount:   int(rf.Amount),
+				Status:   string(rf.Status),
+			}
+			// Refunds made through the service carry the sale ID, and
+			// may get here before they're saved.
+			if saleId, ok := rf.Metadata["sale_id"]; ok {
+				refund.SaleId = &saleId
+			} else {
+				outside = append(outside, *refund)
+			}
+			if err = s.db.CreateRefund(refund); err != nil {
+				s.LogError(event.ID, "error while saving refund "+rf.ID+": "+err.Error())
+			}
+		default:
+			s.LogError(event.ID, "error while checking refund "+rf.ID+" on database: "+err.Error())
+		}
+	}
+
+	purchaseInfo, err := s.purchaseSvc.GetPurchaseInfo(payInt.Origin)
+	if err != nil {
+		s.LogError(event.ID, "error while getting purchase information on purchase-service: "+err.Error())
+		return
+	}
+	s.updateRefundStatuses(purchaseInfo)
+	if len(outside) > 0 {
+		s.sendRefundNotifications(purchaseInfo, nil, outside, nil)
+	}
+}
+
 func (s *Server) HandleSource(event stripe.Event, source *stripe.Source) (bool, error) {
 	paymentIntent := source.Metadata["paymentIntent"]
 	if paymentIntent == "" {
@@ -229,17 +300,19 @@ func (s *Server) validatePayments(payInt *model.PaymentIntent) (string, error) {
 		return "Error while getting purchase information on purchase-service: ", err
 	}
 
-	//summing the absolute prices of items inside the purchase (ignoring currency)
-	var expectedValue int
-	for _, item := range purchaseInfo.Items {
-		expectedValue += item.Price * item.Quantity
+	//summing the amounts charged for the purchase (ignoring currency)
+	totals, err := purchaseTotals(&purchaseInfo.Purchase)
+	if err != nil {
+		return "Error while working out purchase total: ", err
 	}
-	for _, fee := range *purchaseInfo.DeliveryFees {
-		expectedValue += fee.Price
+	var expectedValue int
+	for _, amount := range totals {
+		expectedValue += amount
 	}
 
 	// if the sum of payments equals the expected value, trigger payouts
 	if int64(expectedValue) == total {
+		s.commitStock(payInt.Origin)
 		return s.triggerPayouts(purchaseInfo, successPayments)
 	}
 
@@ -251,10 +324,7 @@ func (s *Server) triggerPayouts(pur *pur.FullPurchase, successPayments *[]model.
 	// getting all stripe's payment intent information so we can wire the transfer to it
 	// in order to only transfer when the funds are released
 	for _, pi := range *successPayments {
-		paymentIntent, _ := paymentintent.Get(
-			pi.StripeIntentId,
-			nil,
-		)
+		paymentIntent, _ := s.provider.GetIntent(pi.StripeIntentId)
 		payments[paymentIntent.Currency] = paymentIntent
 	}
 
@@ -270,23 +340,23 @@ func (s *Server) triggerPayouts(pur *pur.FullPurchase, successPayments *[]model.
 		}
 	}
 
+	// What was paid for each order and booking, which adds up to what
+	// the buyer was charged.
+	paid, err := settleSales(pur)
+	if err != nil {
+		return "Error while converting sale totals to settlement currency: ", err
+	}
+
 	//creating transfers related to sellers
+	invoices := []chassis.EmailAttachment{}
 	for _, order := range *pur.Orders {
-		orderTotal := map[string]int{}
-		//summing all items with the same currency
-		for _, item := range order.Items {
-			orderTotal[strings.ToLower(item.Currency)] += item.Price * item.Quantity
-		}
-
-		//adding delivery fee order total
-		if order.DeliveryFee != nil {
-			if order.DeliveryFee.Currency != "" {
-				orderTotal[strings.ToLower(order.DeliveryFee.Currency)] += order.DeliveryFee.Price
-			}
+		orderTotal, orderTax, err := orderPayout(&pur.Purchase, &order, paid[order.Id])
+		if err != nil {
+			return "Error while converting order total to settlement currency: ", err
 		}
 
 		for currency, total := range orderTotal {
-			if message, err := s.performTransfer(total, fee, currency, order.Seller, order.Origin, payments); err != nil {
+			if message, err := s.performTransfer(total, orderTax[currency], fee, currency, order.Seller, order.Origin, order.Id, payments); err != nil {
 				return message, err
 			}
 		}
@@ -294,8 +364,18 @@ func (s *Server) triggerPayouts(pur *pur.FullPurchase, successPayments *[]model.
 		if _, err := s.purchaseSvc.UpdateOrderPaymentStatus(order.Id, "completed"); err != nil {
 			return "Error while setting the order status as 'complete' on purchase-service: ", err
 		}
+		// The order is now paid for, so issue its invoice, which goes to
+		// both the seller and the buyer.
+		invoice := s.orderInvoice(order.Id)
+		if invoice != nil {
+			invoices = append(invoices, *invoice)
+		}
+
 		//notify seller by email
 		if msg, err := s.buildOrderEmailMsg(order.Id, order.Seller); err == nil {
+			if invoice != nil {
+				msg.Attach(*invoice)
+			}
 			chassis.Emit(s, events.SaleCompleteTopic, msg)
 		}
 	}
@@ -303,11 +383,16 @@ func (s *Server) triggerPayouts(pur *pur.FullPurchase, successPayments *[]model.
 	//creating transfers related to each booking
 	for _, booking := range *pur.Bookings {
 
-		bookingTotal := booking.BookingInfo.Price * booking.BookingInfo.Quantity
+		bookingTotal, bookingTax, err := bookingPayout(&pur.Purchase, &booking, paid[booking.Id])
+		if err != nil {
+			return "Error while converting booking total to settlement currency: ", err
+		}
 
-		if message, err := s.performTransfer(bookingTotal, fee, strings.ToLower(booking.BookingInfo.Currency),
-			booking.Host, booking.Origin, payments); err != nil {
-			return message, err
+		for currency, total := range bookingTotal {
+			if message, err := s.performTransfer(total, bookingTax[currency], fee, currency,
+				booking.Host, booking.Origin, booking.Id, payments); err != nil {
+				return message, err
+			}
 		}
 
 		if _, err := s.purchaseSvc.UpdateBookingPaymentStatus(booking.Id, "completed"); err != nil {
@@ -326,13 +411,14 @@ func (s *Server) triggerPayouts(pur *pur.FullPurchase, successPayments *[]model.
 	}
 	//notify buyer by email
 	if msg, err := s.buildPaymentEmailMsg(pur.Id, pur.BuyerID, "success"); err == nil {
+		msg.Attach(invoices...)
 		chassis.Emit(s, events.PaymentStatusTopic, msg)
 	}
 
 	for _, order := range *pur.Orders {
 		payload := events.BuildOrderPlacedEventPayload(order)
 
-		if err := chassis.TriggerWebhookEvent(s, order.Seller, events.OrderPlaced, s.livemode, payload); err != nil {
+		if err := chassis.TriggerWebhookEvent(s, order.Seller, events.OrderPlaced, s.provider.Livemode(), payload); err != nil {
 			s.LogError(order.Id, "Error triggering webhook event: "+ string(payload))
 		}
 	}
@@ -342,45 +428,52 @@ func (s *Server) triggerPayouts(pur *pur.FullPurchase, successPayments *[]model.
 
 //performTransfer will perform a stripe transfer to the destination account number and all the logging related to it.
 // these logs include a transfer row on transfers table and one in transfer_remainders for accounting purposes.
-func (s *Server) performTransfer(total int, fee float64, currency, destinationId, origin string, payments map[string]*stripe.PaymentIntent) (string, error) {
+// tax is the part of the total collected as tax by the seller: no fee is taken on it.
+func (s *Server) performTransfer(total, tax int, fee float64, currency, destinationId, origin, saleId string, payments map[string]*stripe.PaymentIntent) (string, error) {
 
 	totalAsFloat := float64(total)
-	feeToBeCollected := totalAsFloat * fee // this is the fee that should be collected by VB
+	feeToBeCollected := float64(total-tax) * fee // this is the fee that should be collected by VB
 	//we cannot collect or transfer fraction of cents, so we store the remainders
 	feeCollected, feeRemainder := math.Modf(feeToBeCollected)
 	totalAfterFee, totalRemainder := math.Modf(totalAsFloat - feeToBeCollected)
 	sourceTransaction := stripe.String(payments[currency].Charges.Data[0].ID)
+
+	//the sale is recorded in the ledger whether or not the transfer can be made now
+	s.recordLedger(saleId, salePostings(destinationId, origin, saleId, *sourceTransaction, currency,
+		total, int(feeCollected), int(totalAfterFee))...)
+
 	payoutAccount, err := s.userSvc.GetPayoutAccount(destinationId)
 	if err != nil {
 		//if an error occurred while getting payout account, we add the transfer to a queue to be processed later
-		return s.CreatePendingTransfer(origin, destinationId, currency, *sourceTransaction, total, totalAfterFee, feeCollected, feeRemainder, totalRemainder, err.Error())
+		return s.CreatePendingTransfer(origin, saleId, destinationId, currency, *sourceTransaction, total, tax, totalAfterFee, feeCollected, feeRemainder, totalRemainder, err.Error())
 	}
 
-	//TODO: CHECK IF ACCOUNT IS ALRIGHT IN STRIPE
-	//stripeAccount, err := account.GetByID(
-	//	payoutAccount.AccountNumber,
-	//	nil,
-	//)
+	//the transfer is also queued if the account can't receive transfers at the moment,
+	//until the seller completes their account onboarding
+	if !payoutAccount.TransfersEnabled {
+		return s.CreatePendingTransfer(origin, saleId, destinationId, currency, *sourceTransaction, total, tax, totalAfterFee, feeCollected, feeRemainder, totalRemainder, errTransfersDisabled.Error())
+	}
 
-	transferParams := &stripe.TransferParams{
-		Amount:      stripe.Int64(int64(totalAfterFee)),
-		Currency:    stripe.String(currency),
-		Destination: stripe.String(payoutAccount.AccountNumber),
-		//TransferGroup: stripe.String(order.Id),
-		SourceTransaction: sourceTransaction,
+	transferParams := &provider.TransferParams{
+		Amount:            int64(totalAfterFee),
+		Currency:          currency,
+		Destination:       payoutAccount.AccountNumber,
+		SourceTransaction: *sourceTransaction,
 	}
 
-	tr, err := transfer.New(transferParams)
+	tr, err := s.provider.CreateTransfer(transferParams)
 	if err != nil {
-		return s.CreatePendingTransfer(origin, destinationId, currency, *sourceTransaction, total, totalAfterFee, feeCollected, feeRemainder, totalRemainder, err.Error())
+		return s.CreatePendingTransfer(origin, saleId, destinationId, currency, *sourceTransaction, total, tax, totalAfterFee, feeCollected, feeRemainder, totalRemainder, err.Error())
 	}
 
 	transRemainder := model.TransferRemainder{
 		TransferId:           tr.ID,
+		SaleId:               &saleId,
 		Destination:          destinationId,
 		DestinationAccount:   payoutAccount.AccountNumber,
 		Currency:             currency,
 		TotalValue:           total,
+		TaxValue:             tax,
 		TransferredValue:     int(totalAfterFee),
 		FeeValue:             int(feeCollected),
 		FeeRemainder:         feeRemainder,
@@ -389,19 +482,22 @@ func (s *Server) performTransfer(total int, fee float64, currency, destinationId
 	if err = s.db.CreateTransfers(&transRemainder, origin); err != nil {
 		return "Error while saving a transfer remainder on database: ", err
 	}
+	s.recordLedger(tr.ID, transferPosting(&transRemainder))
 
 	return "success", nil
 }
 
-func (s *Server) CreatePendingTransfer(origin, destinationId, currency, sourceTransaction string, total int,
+func (s *Server) CreatePendingTransfer(origin, saleId, destinationId, currency, sourceTransaction string, total, tax int,
 	totalAfterFee, feeCollected, feeRemainder, totalRemainder float64, err string) (string, error) {
 
 	pending := model.PendingTransfer{
 		Origin:               origin,
+		SaleId:               &saleId,
 		Destination:          destinationId,
 		Currency:             currency,
 		SourceTransaction:    sourceTransaction,
 		TotalValue:           total,
+		TaxValue:             tax,
 		TransferredValue:     int(totalAfterFee),
 		FeeValue:             int(feeCollected),
 		FeeRemainder:         feeRemainder,
@@ -4,10 +4,8 @@ import (
 	"errors"
 	"github.com/rs/zerolog/log"
 	"github.com/stripe/stripe-go"
-	"github.com/stripe/stripe-go/event"
-	"github.com/stripe/stripe-go/paymentintent"
-	"github.com/stripe/stripe-go/transfer"
 	"github.com/veganbase/backend/services/payment-service/model"
+	"github.com/veganbase/backend/services/payment-service/provider"
 	"strconv"
 	"time"
 )
@@ -28,14 +26,17 @@ func (s *Server) HandlePendingTransfers() {
 				log.Error().Err(err).Msg("obtaining payout account for " + pt.Destination + ".")
 				continue
 			}
-			transferParams := &stripe.TransferParams{
-				Amount:            stripe.Int64(int64(pt.TotalValue)),
-				Currency:          stripe.String(pt.Currency),
-				Destination:       stripe.String(payoutAcc.AccountNumber),
-				SourceTransaction: stripe.String(pt.SourceTransaction),
+			if !payoutAcc.TransfersEnabled {
+				continue
+			}
+			transferParams := &provider.TransferParams{
+				Amount:            int64(pt.TransferredValue),
+				Currency:          pt.Currency,
+				Destination:       payoutAcc.AccountNumber,
+				SourceTransaction: pt.SourceTransaction,
 			}
 
-			tr, err := transfer.New(transferParams)
+			tr, err := s.provider.CreateTransfer(transferParams)
 			if err != nil {
 				if stripeErr, ok := err.(*stripe.Error); ok {
 					log.Error().Err(errors.New(stripeErr.Msg)).Msg("while wiring a transfer on Stripe for " + pt.Destination)
@@ -47,10 +48,12 @@ func (s *Server) HandlePendingTransfers() {
 
 			transRemainder := model.TransferRemainder{
 				TransferId:           tr.ID,
-				Destination:          pt.Origin,
+				SaleId:               pt.SaleId,
+				Destination:          pt.Destination,
 				DestinationAccount:   payoutAcc.AccountNumber,
 				Currency:             pt.Currency,
 				TotalValue:           pt.TotalValue,
+				TaxValue:             pt.TaxValue,
 				TransferredValue:     pt.TransferredValue,
 				FeeValue:             pt.FeeValue,
 				FeeRemainder:         pt.FeeRemainder,
@@ -60,6 +63,7 @@ func (s *Server) HandlePendingTransfers() {
 				log.Error().Err(err).Msg("while creating transfers for " + pt.Destination + ".")
 				continue
 			}
+			s.recordLedger(tr.ID, transferPosting(&transRemainder))
 			if err = s.db.DeletePendingTransfer(pt.ID); err != nil {
 				log.Error().Err(err).Msg("while deleting pending transfer with ID= " + strconv.FormatInt(pt.ID, 10) + ".")
 				continue
@@ -81,7 +85,7 @@ func (s *Server) HandlePendingEvents() {
 			continue
 		}
 		for _, pe := range *pendingEvents {
-			event, err := event.Get(pe.EventID, nil)
+			event, err := s.provider.GetEvent(pe.EventID)
 			if err != nil {
 				if stripeErr, ok := err.(*stripe.Error); ok {
 					log.Error().Err(errors.New(stripeErr.Msg)).Msg("while acquiring event from Stripe " + pe.EventID + ".")
@@ -90,7 +94,7 @@ func (s *Server) HandlePendingEvents() {
 				}
 				continue
 			}
-			intent, err := paymentintent.Get(pe.IntentID, nil)
+			intent, err := s.provider.GetIntent(pe.IntentID)
 			if err != nil {
 				if stripeErr, ok := err.(*stripe.Error); ok {
 					log.Error().Err(errors.New(stripeErr.Msg)).Msg("while acquiring intent from Stripe " + pe.IntentID + ".")
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"github.com/veganbase/backend/services/payment-service/model"
+)
+
+// Postings to the ledger. Each function builds the ledger transactions
+// for one kind of money movement; recording them is left to the
+// caller.
+
+func ledgerEntry(account, owner string, amount int) model.LedgerEntry {
+	return model.LedgerEntry{Account: account, Owner: owner, Amount: amount}
+}
+
+// salePostings records the payment for a sale in one currency: the
+// total is owed to the seller, less the platform fee and the rounding
+// left over when the fee is taken, which is whatever the transfer to
+// the seller doesn't cover.
+func salePostings(seller, origin, saleId, charge, currency string, total, fee, transferred int) []*model.LedgerTransaction {
+	posting := func(kind string, amount int, description string, entries ...model.LedgerEntry) *model.LedgerTransaction {
+		return &model.LedgerTransaction{
+			Kind:        kind,
+			Seller:      seller,
+			Origin:      origin,
+			SaleId:      &saleId,
+			Reference:   charge,
+			Currency:    currency,
+			Amount:      amount,
+			Description: description,
+			Entries:     entries,
+		}
+	}
+
+	txs := []*model.LedgerTransaction{
+		posting(model.LedgerCharge, total, "Payment for "+saleId,
+			ledgerEntry(model.AccountCash, "", total),
+			ledgerEntry(model.AccountSeller, seller, -total)),
+	}
+	if fee != 0 {
+		txs = append(txs, posting(model.LedgerFee, fee, "Platform fee",
+			ledgerEntry(model.AccountSeller, seller, fee),
+			ledgerEntry(model.AccountFees, "", -fee)))
+	}
+	if remainder := total - fee - transferred; remainder != 0 {
+		txs = append(txs, posting(model.LedgerRemainder, remainder, "Rounding",
+			ledgerEntry(model.AccountSeller, seller, remainder),
+			ledgerEntry(model.AccountRemainders, "", -remainder)))
+	}
+	return txs
+}
+
+// transferPosting records a transfer to a seller's payout account.
+func transferPosting(tr *model.TransferRemainder) *model.LedgerTransaction {
+	return &model.LedgerTransaction{
+		Kind:        model.LedgerTransfer,
+		Seller:      tr.Destination,
+		Origin:      tr.Origin,
+		SaleId:      tr.SaleId,
+		Reference:   tr.TransferId,
+		Currency:    tr.Currency,
+		Amount:      tr.TransferredValue,
+		Description: "Transfer to " + tr.DestinationAccount,
+		Entries: []model.LedgerEntry{
+			ledgerEntry(model.AccountSeller, tr.Destination, tr.TransferredValue),
+			ledgerEntry(model.AccountCash, "", -tr.TransferredValue),
+		},
+	}
+}
+
+// refundShare works out the seller's share of a refund for a sale,
+// from the sale's payment recorded in the ledger: the seller gives
+// back what they were due from the refunded amount, and the platform
+// gives back the fee and rounding. Returns false if no payment for the
+// sale in the refund's currency is recorded.
+func refundShare(saleTxs []model.LedgerTransaction, currency string, amount int) (string, int, bool) {
+	seller := ""
+	charged, net := 0, 0
+	for _, lt := range saleTxs {
+		if lt.Currency != currency {
+			continue
+		}
+		switch lt.Kind {
+		case model.LedgerCharge:
+			seller = lt.Seller
+			charged += lt.Amount
+			net += lt.Amount
+		case model.LedgerFee, model.LedgerRemainder:
+			net -= lt.Amount
+		}
+	}
+	if charged == 0 {
+		return "", 0, false
+	}
+	return seller, amount * net / charged, true
+}
+
+// refundPosting records a refund of a sale, with the seller's share
+// of it as worked out by refundShare.
+func refundPosting(refund *model.Refund, seller string, share int) *model.LedgerTransaction {
+	return &model.LedgerTransaction{
+		Kind:        model.LedgerRefund,
+		Seller:      seller,
+		Origin:      refund.Origin,
+		SaleId:      refund.SaleId,
+		Reference:   refund.RefundId,
+		Currency:    refund.Currency,
+		Amount:      refund.Amount,
+		Description: "Refund",
+		Entries: []model.LedgerEntry{
+			ledgerEntry(model.AccountCash, "", -refund.Amount),
+			ledgerEntry(model.AccountSeller, seller, share),
+			ledgerEntry(model.AccountFees, "", refund.Amount-share),
+		},
+	}
+}
+
+// refundFailedPosting undoes the posting of a refund that failed.
+func refundFailedPosting(refund *model.Refund, seller string, share int) *model.LedgerTransaction {
+	lt := refundPosting(refund, seller, share)
+	lt.Kind = model.LedgerRefundFailed
+	lt.Description = "Refund failed"
+	for i := range lt.Entries {
+		lt.Entries[i].Amount = -lt.Entries[i].Amount
+	}
+	return lt
+}
+
+// reversalPosting records the reversal of part of a transfer to a
+// seller, which takes funds back from their payout account.
+func reversalPosting(rev *model.TransferReversal, tr *model.TransferRemainder) *model.LedgerTransaction {
+	return &model.LedgerTransaction{
+		Kind:        model.LedgerReversal,
+		Seller:      tr.Destination,
+		Origin:      tr.Origin,
+		SaleId:      tr.SaleId,
+		Reference:   rev.ReversalId,
+		Currency:    tr.Currency,
+		Amount:      rev.Amount,
+		Description: "Transfer reversed for refund " + rev.RefundId,
+		Entries: []model.LedgerEntry{
+			ledgerEntry(model.AccountCash, "", rev.Amount),
+			ledgerEntry(model.AccountSeller, tr.Destination, -rev.Amount),
+		},
+	}
+}
+
+// recordLedger records ledger transactions. Errors are logged rather
+// than returned, since the money has already moved by the time the
+// postings are made.
+func (s *Server) recordLedger(reference string, txs ...*model.LedgerTransaction) {
+	if err := s.db.CreateLedgerTransactions(txs); err != nil {
+		s.LogError(reference, "error while recording ledger transactions: "+err.Error())
+	}
+}
+
+// recordRefund records a refund made for a sale in the ledger. Failed
+// refunds are recorded as a refund and its failure, so that the
+// ledger shows both.
+func (s *Server) recordRefund(refund *model.Refund) {
+	if refund.SaleId == nil {
+		return
+	}
+	saleTxs, err := s.db.LedgerTransactionsBySale(*refund.SaleId)
+	if err != nil {
+		s.LogError(refund.RefundId, "error while getting ledger transactions for "+*refund.SaleId+": "+err.Error())
+		return
+	}
+	seller, share, ok := refundShare(*saleTxs, refund.Currency, refund.Amount)
+	if !ok {
+		s.LogError(refund.RefundId, "no payment in "+refund.Currency+" recorded in ledger for "+*refund.SaleId)
+		return
+	}
+
+	txs := []*model.LedgerTransaction{refundPosting(refund, seller, share)}
+	if refund.Failed() {
+		txs = append(txs, refundFailedPosting(refund, seller, share))
+	}
+	s.recordLedger(refund.RefundId, txs...)
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stripe/stripe-go"
+
+	"github.com/veganbase/backend/services/payment-service/model"
+)
+
+// Credit to the seller's account made by ledger transactions.
+func sellerCredit(txs []*model.LedgerTransaction, seller string) int {
+	total := 0
+	for _, lt := range txs {
+		for _, e := range lt.Entries {
+			if e.Account == model.AccountSeller && e.Owner == seller {
+				total -= e.Amount
+			}
+		}
+	}
+	return total
+}
+
+func TestSalePostings(t *testing.T) {
+	// 15% of 1001 is 150.15: the fee is 150, the transfer 850, and the
+	// cent left over is rounding.
+	txs := salePostings("usr_SELLER", "PUR-000001", "ORD-000001", "ch_1", "eur", 1001, 150, 850)
+	if assert.Len(t, txs, 3) {
+		assert.Equal(t, model.LedgerCharge, txs[0].Kind)
+		assert.Equal(t, model.LedgerFee, txs[1].Kind)
+		assert.Equal(t, model.LedgerRemainder, txs[2].Kind)
+		assert.Equal(t, 1, txs[2].Amount)
+	}
+	for _, lt := range txs {
+		assert.True(t, lt.Balanced())
+		assert.Equal(t, "ch_1", lt.Reference)
+	}
+	assert.Equal(t, 850, sellerCredit(txs, "usr_SELLER"))
+
+	// Once the transfer is made, nothing more is owed to the seller.
+	saleId := "ORD-000001"
+	tr := transferPosting(&model.TransferRemainder{
+		TransferId: "tr_1", Origin: "PUR-000001", SaleId: &saleId, Destination: "usr_SELLER",
+		DestinationAccount: "acct_1", Currency: "eur", TotalValue: 1001, TransferredValue: 850,
+	})
+	assert.True(t, tr.Balanced())
+	assert.Equal(t, 0, sellerCredit(append(txs, tr), "usr_SELLER"))
+
+	// No rounding when the fee divides exactly.
+	txs = salePostings("usr_SELLER", "PUR-000001", "ORD-000001", "ch_1", "eur", 1000, 150, 850)
+	assert.Len(t, txs, 2)
+}
+
+func TestRefundPostings(t *testing.T) {
+	saleId := "ORD-000001"
+	saleTxs := []model.LedgerTransaction{}
+	for _, lt := range salePostings("usr_SELLER", "PUR-000001", saleId, "ch_1", "eur", 2000, 300, 1700) {
+		saleTxs = append(saleTxs, *lt)
+	}
+
+	seller, share, ok := refundShare(saleTxs, "eur", 500)
+	assert.True(t, ok)
+	assert.Equal(t, "usr_SELLER", seller)
+	assert.Equal(t, 425, share)
+	_, _, ok = refundShare(saleTxs, "gbp", 500)
+	assert.False(t, ok)
+
+	refund := &model.Refund{RefundId: "re_1", Origin: "PUR-000001", SaleId: &saleId, Currency: "eur", Amount: 500}
+	rf := refundPosting(refund, seller, share)
+	assert.True(t, rf.Balanced())
+	assert.Equal(t, -425, sellerCredit([]*model.LedgerTransaction{rf}, "usr_SELLER"))
+
+	// The seller's share is taken back by reversing their transfer.
+	rev := reversalPosting(&model.TransferReversal{ReversalId: "trr_1", TransferId: "tr_1", RefundId: "re_1", Amount: 425},
+		&model.TransferRemainder{TransferId: "tr_1", SaleId: &saleId, Destination: "usr_SELLER", Currency: "eur"})
+	assert.True(t, rev.Balanced())
+	assert.Equal(t, 0, sellerCredit([]*model.LedgerTransaction{rf, rev}, "usr_SELLER"))
+
+	// A failed refund undoes the refund.
+	failed := refundFailedPosting(refund, seller, share)
+	assert.True(t, failed.Balanced())
+	assert.Equal(t, model.LedgerRefundFailed, failed.Kind)
+	assert.Equal(t, 0, sellerCredit([]*model.LedgerTransaction{rf, failed}, "usr_SELLER"))
+}
+
+func TestTransferDiscrepancy(t *testing.T) {
+	lt := &model.LedgerTransaction{Kind: model.LedgerTransfer, Reference: "tr_1", Currency: "eur", Amount: 850}
+
+	tr := &stripe.Transfer{ID: "tr_1", Amount: 850, Currency: "eur"}
+	assert.Nil(t, transferDiscrepancy(lt, tr, 0))
+
+	tr.AmountReversed = 425
+	if d := transferDiscrepancy(lt, tr, 0); assert.NotNil(t, d) {
+		assert.Equal(t, "amount reversed is 0 in ledger, 425 on Stripe", *d)
+	}
+	assert.Nil(t, transferDiscrepancy(lt, tr, 425))
+
+	tr = &stripe.Transfer{ID: "tr_1", Amount: 1001, Currency: "gbp"}
+	if d := transferDiscrepancy(lt, tr, 0); assert.NotNil(t, d) {
+		assert.Equal(t, "amount is 850 in ledger, 1001 on Stripe; currency is eur in ledger, gbp on Stripe", *d)
+	}
+}
@@ -35,6 +35,91 @@ func (s *Server) sendPaymentReceivedNotification(p *model.PaymentIntent, success
 	return
 }
 
+// sendRefundNotifications tells the buyer about refunds made for a
+// purchase and, for refunds of an order or booking, tells the seller
+// how much was taken back from them. One message is sent per refund.
+func (s *Server) sendRefundNotifications(purchase *pur.FullPurchase, sl *sale, refunds []model.Refund, reversed map[string]int) {
+	userInfo, err := s.userSvc.Info([]string{purchase.BuyerID})
+	if err != nil {
+		log.Error().Err(err).Msg("refund-issued: could not obtain users' information from user-service")
+		return
+	}
+
+	var sellerInfo *usr.EmailNotificationInfo
+	if sl != nil {
+		if sellerInfo, err = s.userSvc.GetNotificationInfo(sl.seller); err != nil {
+			log.Error().Err(err).Msg("sale-refunded: could not obtain seller information from user-service")
+		}
+	}
+
+	for _, refund := range refunds {
+		notification := buildRefundIssuedNotificationMsg(&refund, sl, purchase, userInfo[purchase.BuyerID])
+		if err = chassis.Emit(s, events.RefundIssuedTopic, notification); err != nil {
+			log.Error().Err(err).Msg("refund-issued: could not send event")
+		}
+		if sellerInfo != nil {
+			notification := buildSaleRefundedNotificationMsg(&refund, sl, reversed[refund.RefundId], sellerInfo)
+			if err = chassis.Emit(s, events.SaleRefundedTopic, notification); err != nil {
+				log.Error().Err(err).Msg("sale-refunded: could not send event")
+			}
+		}
+	}
+}
+
+func buildRefundIssuedNotificationMsg(r *model.Refund, sl *sale, purchase *pur.FullPurchase, info *usr.Info) *chassis.GenericEmailMsg {
+	data := chassis.GenericMap{}
+
+	data["purchase_id"] = r.Origin
+	data["customer_name"] = info.Name
+	if sl != nil {
+		data["sale_id"] = sl.id
+		data["sale_type"] = sl.kind
+	}
+	data["refund_amount"] = strconv.Itoa(r.Amount)
+	data["refund_currency"] = r.Currency
+	data["refund_formatted_value"] = chassis.FormatCurrencyValue(r.Currency, r.Amount)
+	if r.Reason != nil {
+		data["refund_reason"] = *r.Reason
+	}
+
+	msg := chassis.GenericEmailMsg{
+		FixedFields: chassis.FixedFields{
+			Site:     *purchase.Site,
+			Language: "en",
+			Email:    *info.Email,
+		},
+		Data: data,
+	}
+
+	return &msg
+}
+
+func buildSaleRefundedNotificationMsg(r *model.Refund, sl *sale, reversed int, info *usr.EmailNotificationInfo) *chassis.GenericEmailMsg {
+	data := chassis.GenericMap{}
+
+	data["dst_name"] = info.Name
+	data["sale_id"] = sl.id
+	data["sale_type"] = sl.kind
+	data["refund_amount"] = strconv.Itoa(r.Amount)
+	data["refund_currency"] = r.Currency
+	data["refund_formatted_value"] = chassis.FormatCurrencyValue(r.Currency, r.Amount)
+	data["reversed_amount"] = strconv.Itoa(reversed)
+	data["reversed_formatted_value"] = chassis.FormatCurrencyValue(r.Currency, reversed)
+	if r.Reason != nil {
+		data["refund_reason"] = *r.Reason
+	}
+
+	msg := chassis.GenericEmailMsg{
+		FixedFields: chassis.FixedFields{
+			Email:    info.Email,
+			Language: "en",
+			Site:     "veganbase",
+		},
+		Data: data,
+	}
+
+	return &msg
+}
 
 func buildPaymentReceivedNotificationMsg(p *model.PaymentIntent, purchase *pur.FullPurchase, info *usr.Info ) *chassis.GenericEmailMsg {
 	data := chassis.GenericMap{}
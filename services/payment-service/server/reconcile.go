@@ -0,0 +1,88 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stripe/stripe-go"
+	"github.com/veganbase/backend/services/payment-service/model"
+)
+
+// reconcileWindow is how long transfers recorded in the ledger keep
+// being checked against Stripe after they're made, since they can be
+// reversed when sales are refunded.
+const reconcileWindow = 90 * 24 * time.Hour
+
+// ReconcileLedger checks the transfers recorded in the ledger against
+// Stripe's transfer records once a day, flagging the ledger
+// transactions that disagree with them.
+func (s *Server) ReconcileLedger() {
+	period := time.Tick(24 * time.Hour)
+	for range period {
+		s.reconcileTransfers(time.Now().Add(-reconcileWindow))
+	}
+}
+
+func (s *Server) reconcileTransfers(since time.Time) {
+	txs, err := s.db.LedgerTransfersToReconcile(since)
+	if err != nil {
+		log.Error().Err(err).Msg("obtaining ledger transfers to reconcile on database")
+		return
+	}
+
+	flagged := 0
+	for _, lt := range *txs {
+		var discrepancy *string
+		tr, err := s.provider.GetTransfer(lt.Reference)
+		if err != nil {
+			stripeErr, ok := err.(*stripe.Error)
+			if !ok || stripeErr.HTTPStatusCode != 404 {
+				log.Error().Err(err).Msg("while acquiring transfer from Stripe " + lt.Reference + ".")
+				continue
+			}
+			msg := "transfer not found on Stripe"
+			discrepancy = &msg
+		} else {
+			reversed, err := s.db.LedgerReversedByTransfer(lt.Reference)
+			if err != nil {
+				log.Error().Err(err).Msg("obtaining ledger reversals for transfer " + lt.Reference + ".")
+				continue
+			}
+			discrepancy = transferDiscrepancy(&lt, tr, reversed)
+		}
+
+		if discrepancy != nil {
+			flagged++
+			log.Warn().Int64("ledger_transaction", lt.ID).Str("transfer", lt.Reference).
+				Msg("ledger disagrees with Stripe: " + *discrepancy)
+		}
+		if err = s.db.UpdateLedgerReconciliation(lt.ID, discrepancy); err != nil {
+			log.Error().Err(err).Msg("while updating reconciliation of ledger transaction " + lt.Reference + ".")
+		}
+	}
+	log.Info().Int("checked", len(*txs)).Int("flagged", flagged).Msg("ledger reconciliation finished")
+}
+
+// transferDiscrepancy compares a transfer recorded in the ledger, and
+// the total of its reversals recorded there, with Stripe's record of
+// the transfer. Returns a description of the differences, or nil if
+// there are none.
+func transferDiscrepancy(lt *model.LedgerTransaction, tr *stripe.Transfer, reversed int) *string {
+	problems := []string{}
+	if tr.Amount != int64(lt.Amount) {
+		problems = append(problems, fmt.Sprintf("amount is %d in ledger, %d on Stripe", lt.Amount, tr.Amount))
+	}
+	if !strings.EqualFold(string(tr.Currency), lt.Currency) {
+		problems = append(problems, fmt.Sprintf("currency is %s in ledger, %s on Stripe", lt.Currency, tr.Currency))
+	}
+	if tr.AmountReversed != int64(reversed) {
+		problems = append(problems, fmt.Sprintf("amount reversed is %d in ledger, %d on Stripe", reversed, tr.AmountReversed))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	msg := strings.Join(problems, "; ")
+	return &msg
+}
@@ -23,6 +23,20 @@ func (s *Server) routes() chi.Router {
 	r.Post("/webhook/stripe", chassis.SimpleHandler(s.webhookHandler))
 	r.Post("/payment-intent", chassis.SimpleHandler(s.createPaymentIntentWithAuth))
 	r.Post("/payment-intent/{pi_id:pi_[a-zA-Z0-9]+}/confirm", chassis.SimpleHandler(s.confirmPaymentIntent))
+	r.Post("/purchase/{pur_id}/refund", chassis.SimpleHandler(s.refundPurchase))
+	r.Post("/order/{ord_id}/refund", chassis.SimpleHandler(s.refundOrder))
+	r.Post("/booking/{bok_id}/refund", chassis.SimpleHandler(s.refundBooking))
+
+	// Seller balances and monthly payout statements.
+	r.Get("/me/balance", chassis.SimpleHandler(s.sellerBalance))
+	r.Get("/me/payout-statements", chassis.SimpleHandler(s.payoutStatements))
+	r.Get("/me/payout-statement/{month:[0-9]{4}-[0-9]{2}}", chassis.SimpleHandler(s.payoutStatement))
+	r.Get("/user/{id:usr_[a-zA-Z0-9]+}/balance", chassis.SimpleHandler(s.sellerBalance))
+	r.Get("/user/{id:usr_[a-zA-Z0-9]+}/payout-statements", chassis.SimpleHandler(s.payoutStatements))
+	r.Get("/user/{id:usr_[a-zA-Z0-9]+}/payout-statement/{month:[0-9]{4}-[0-9]{2}}", chassis.SimpleHandler(s.payoutStatement))
+	r.Get("/org/{id_or_slug:[a-zA-Z0-9-_]+}/balance", chassis.SimpleHandler(s.sellerBalance))
+	r.Get("/org/{id_or_slug:[a-zA-Z0-9-_]+}/payout-statements", chassis.SimpleHandler(s.payoutStatements))
+	r.Get("/org/{id_or_slug:[a-zA-Z0-9-_]+}/payout-statement/{month:[0-9]{4}-[0-9]{2}}", chassis.SimpleHandler(s.payoutStatement))
 
 	// PATHS FOR INTERNAL USE
 
@@ -2,16 +2,16 @@ package server
 
 import (
 	"context"
-	"github.com/stripe/stripe-go"
 	site "github.com/veganbase/backend/services/site-service/client"
-	"regexp"
 
 	"time"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/veganbase/backend/chassis"
+	item "github.com/veganbase/backend/services/item-service/client"
 	"github.com/veganbase/backend/services/payment-service/db"
+	"github.com/veganbase/backend/services/payment-service/provider"
 	purchase "github.com/veganbase/backend/services/purchase-service/client"
 	user "github.com/veganbase/backend/services/user-service/client"
 )
@@ -23,10 +23,9 @@ type Server struct {
 	userSvc      user.Client
 	siteSvc      site.Client
 	purchaseSvc  purchase.Client
+	itemSvc      item.Client
 	imageBaseURL string
-	stripeKey    string
-	webhookSecret   string
-	livemode bool
+	provider     provider.PaymentProvider
 }
 
 // Config contains the configuration information needed to start
@@ -41,8 +40,19 @@ type Config struct {
 	UserServiceURL     string `env:"USER_SERVICE_URL,default=http://user-service"`
 	SiteServiceURL     string `env:"SITE_SERVICE_URL,default=http://site-service"`
 	PurchaseServiceURL string `env:"PURCHASE_SERVICE_URL,default=http://purchase-service"`
-	StripeKey          string `env:"STRIPE_KEY,required"`
-	WebhookSecret      string `env:"WEBHOOK_SECRET_KEY,required"`
+	ItemServiceURL     string `env:"ITEM_SERVICE_URL,default=http://item-service"`
+	StripeKey          string `env:"STRIPE_KEY"`
+	WebhookSecret      string `env:"WEBHOOK_SECRET_KEY"`
+
+	// Signing secret for the Connect webhook endpoint, which reports
+	// changes to sellers' connected accounts.
+	ConnectWebhookSecret string `env:"CONNECT_WEBHOOK_SECRET_KEY"`
+
+	// The payment provider is either "stripe" or (in development mode
+	// only) "fake", an in-process fake that sends webhook events to
+	// the service after a delay.
+	PaymentProvider  string        `env:"PAYMENT_PROVIDER,default=stripe"`
+	FakeWebhookDelay time.Duration `env:"FAKE_WEBHOOK_DELAY,default=2s"`
 }
 
 // NewServer creates the server structure for the payment service.
@@ -52,18 +62,25 @@ func NewServer(cfg *Config) *Server {
 	chassis.CheckURL(cfg.UserServiceURL, "user service")
 	chassis.CheckURL(cfg.PurchaseServiceURL, "purchase service")
 	chassis.CheckURL(cfg.SiteServiceURL, "site service")
+	chassis.CheckURL(cfg.ItemServiceURL, "item service")
 
 	// Common server initialisation.
 	s := &Server{}
 	s.Init(cfg.AppName, cfg.Project, cfg.Port, cfg.Credentials, s.routes())
 
-	s.stripeKey = cfg.StripeKey
-	s.webhookSecret = cfg.WebhookSecret
-
-	s.livemode = true
-	matched, _ := regexp.MatchString(`\w+(_test_)\w+`, s.stripeKey)
-	if matched {
-		s.livemode = false
+	switch cfg.PaymentProvider {
+	case "stripe":
+		if cfg.StripeKey == "" || cfg.WebhookSecret == "" {
+			log.Fatal().Msg("STRIPE_KEY and WEBHOOK_SECRET_KEY must be set for Stripe payment provider")
+		}
+		s.provider = provider.NewStripe(cfg.StripeKey, cfg.WebhookSecret, cfg.ConnectWebhookSecret)
+	case "fake":
+		if !cfg.DevMode {
+			log.Fatal().Msg("fake payment provider can only be used in development mode")
+		}
+		s.provider = provider.NewFake(cfg.FakeWebhookDelay, s.handleFakeEvent)
+	default:
+		log.Fatal().Str("provider", cfg.PaymentProvider).Msg("unknown payment provider")
 	}
 
 	var err error
@@ -72,16 +89,18 @@ func NewServer(cfg *Config) *Server {
 	}
 	s.purchaseSvc = purchase.New(cfg.PurchaseServiceURL)
 	s.siteSvc = site.New(cfg.SiteServiceURL, s.PubSub, s.AppName)
+	s.itemSvc = item.New(cfg.ItemServiceURL)
 
 	// Connect to payment's database.
 	timeout, _ := context.WithTimeout(context.Background(), time.Second*10)
 
-	s.db, err = db.NewPGClient(timeout, cfg.DBURL)
+	pg, err := db.NewPGClient(timeout, cfg.DBURL)
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't connect to user database")
 	}
+	s.db = pg
+	s.StartEventRelay(pg.DB)
 
-	stripe.Key = s.stripeKey
 	return s
 }
 
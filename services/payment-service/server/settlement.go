@@ -0,0 +1,278 @@
+package server
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	pur "github.com/veganbase/backend/services/purchase-service/model"
+	site "github.com/veganbase/backend/services/site-service/model"
+)
+
+// purchaseTotals works out the amounts to charge for a purchase, keyed
+// by lower case currency code: items plus delivery fees, less any
+// promotion discounts, plus any tax not included in prices.
+func purchaseTotals(purchase *pur.Purchase) (map[string]int, error) {
+	totals := map[string]int{}
+	for _, item := range purchase.Items {
+		totals[strings.ToLower(item.Currency)] += item.Price * item.Quantity
+	}
+	if purchase.DeliveryFees != nil {
+		for _, fee := range *purchase.DeliveryFees {
+			totals[strings.ToLower(fee.Currency)] += fee.Price
+		}
+	}
+	if purchase.Discounts != nil {
+		for _, discount := range *purchase.Discounts {
+			totals[strings.ToLower(discount.Currency)] -= discount.Amount
+		}
+	}
+	if purchase.Taxes != nil {
+		for _, tax := range *purchase.Taxes {
+			for currency, amount := range tax.Added() {
+				totals[strings.ToLower(currency)] += amount
+			}
+		}
+	}
+	return settle(purchase, totals)
+}
+
+// settle converts amounts in several currencies into the settlement
+// currency of a purchase, using the exchange rates recorded when the
+// purchase was made. Purchases without a settlement currency are paid
+// in each currency separately, so the amounts are returned unchanged.
+func settle(purchase *pur.Purchase, totals map[string]int) (map[string]int, error) {
+	if purchase.Currency == "" || purchase.ExchangeRates == nil {
+		return totals, nil
+	}
+	total, _, err := purchase.ExchangeRates.Convert(totals, purchase.Currency)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int{strings.ToLower(purchase.Currency): total}, nil
+}
+
+// settleOrder converts amounts for an order into the settlement
+// currency of its purchase. The rate recorded on the order when the
+// purchase was made is used for the order's own currency, so payouts
+// and refunds match what the buyer was charged; anything else is
+// converted at the purchase's rates.
+func settleOrder(purchase *pur.Purchase, order *pur.Order, totals map[string]int) (map[string]int, error) {
+	if purchase.Currency == "" || purchase.ExchangeRates == nil {
+		return totals, nil
+	}
+	total := 0
+	for currency, amount := range totals {
+		rate, err := settlementRate(purchase, order.ExchangeRate, currency)
+		if err != nil {
+			return nil, err
+		}
+		total += rate.Convert(amount)
+	}
+	return map[string]int{strings.ToLower(purchase.Currency): total}, nil
+}
+
+// The rate used to convert amounts in a currency into the settlement
+// currency of a purchase: the rate recorded on an order, if it's for
+// the currency, or the purchase's rate.
+func settlementRate(purchase *pur.Purchase, orderRate *site.ExchangeRate, currency string) (*site.ExchangeRate, error) {
+	if orderRate != nil && strings.EqualFold(orderRate.From, currency) &&
+		strings.EqualFold(orderRate.To, purchase.Currency) {
+		return orderRate, nil
+	}
+	return purchase.ExchangeRates.Rate(currency, purchase.Currency)
+}
+
+// orderPaid works out what the buyer paid for an order, in the
+// currencies it is priced in: items plus delivery fee, less any
+// promotion discounts, plus any tax not included in prices.
+func orderPaid(order *pur.Order) map[string]int {
+	totals := map[string]int{}
+	for _, item := range order.Items {
+		totals[strings.ToLower(item.Currency)] += item.Price * item.Quantity
+	}
+	if order.DeliveryFee != nil && order.DeliveryFee.Currency != "" {
+		totals[strings.ToLower(order.DeliveryFee.Currency)] += order.DeliveryFee.Price
+	}
+	if order.Discounts != nil {
+		for _, discount := range *order.Discounts {
+			totals[strings.ToLower(discount.Currency)] -= discount.Amount
+		}
+	}
+	if order.Tax != nil {
+		for currency, tax := range order.Tax.Added() {
+			totals[strings.ToLower(currency)] += tax
+		}
+	}
+	return totals
+}
+
+// bookingPaid works out what the buyer paid for a booking, in the
+// currency it is priced in.
+func bookingPaid(booking *pur.Booking) map[string]int {
+	totals := map[string]int{
+		strings.ToLower(booking.BookingInfo.Currency): booking.BookingInfo.Price * booking.BookingInfo.Quantity,
+	}
+	if booking.Tax != nil {
+		for currency, tax := range booking.Tax.Added() {
+			totals[strings.ToLower(currency)] += tax
+		}
+	}
+	return totals
+}
+
+// settleSales works out what the buyer paid for each order and
+// booking of a purchase, keyed by sale ID and then by lower case
+// currency code. For purchases settled in a single currency, each
+// sale's amount is converted without rounding, and the amounts are
+// then rounded so that they add up to exactly what was charged for the
+// purchase (see purchaseTotals). Refunds and payouts are worked out
+// from these amounts, so refunding every sale never asks for more
+// than was charged.
+func settleSales(purchase *pur.FullPurchase) (map[string]map[string]int, error) {
+	ids := []string{}
+	paid := map[string]map[string]int{}
+	rates := map[string]*site.ExchangeRate{}
+	if purchase.Orders != nil {
+		for i := range *purchase.Orders {
+			order := &(*purchase.Orders)[i]
+			ids = append(ids, order.Id)
+			paid[order.Id] = orderPaid(order)
+			rates[order.Id] = order.ExchangeRate
+		}
+	}
+	if purchase.Bookings != nil {
+		for i := range *purchase.Bookings {
+			booking := &(*purchase.Bookings)[i]
+			ids = append(ids, booking.Id)
+			paid[booking.Id] = bookingPaid(booking)
+		}
+	}
+	if purchase.Currency == "" || purchase.ExchangeRates == nil {
+		return paid, nil
+	}
+
+	exact := make([]float64, len(ids))
+	for i, id := range ids {
+		for currency, amount := range paid[id] {
+			rate, err := settlementRate(&purchase.Purchase, rates[id], currency)
+			if err != nil {
+				return nil, err
+			}
+			exact[i] += rate.ConvertExact(amount)
+		}
+	}
+	charged, err := purchaseTotals(&purchase.Purchase)
+	if err != nil {
+		return nil, err
+	}
+	currency := strings.ToLower(purchase.Currency)
+	for i, amount := range allocate(charged[currency], exact) {
+		paid[ids[i]] = map[string]int{currency: amount}
+	}
+	return paid, nil
+}
+
+// allocate rounds amounts so that they add up to a total, using the
+// largest remainder method: each amount is rounded down, and the units
+// left over go to the amounts with the largest fractional parts. (If
+// the amounts add up to more than the total, units are taken from the
+// amounts with the smallest fractional parts.)
+func allocate(total int, amounts []float64) []int {
+	rounded := make([]int, len(amounts))
+	order := make([]int, len(amounts))
+	left := total
+	for i, amount := range amounts {
+		rounded[i] = int(math.Floor(amount))
+		left -= rounded[i]
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		fi := amounts[order[i]] - math.Floor(amounts[order[i]])
+		fj := amounts[order[j]] - math.Floor(amounts[order[j]])
+		return fi > fj
+	})
+	for left > 0 && len(order) > 0 {
+		for i := 0; i < len(order) && left > 0; i++ {
+			rounded[order[i]]++
+			left--
+		}
+	}
+	for taken := true; left < 0 && taken; {
+		taken = false
+		for i := len(order) - 1; i >= 0 && left < 0; i-- {
+			if rounded[order[i]] > 0 {
+				rounded[order[i]]--
+				left++
+				taken = true
+			}
+		}
+	}
+	return rounded
+}
+
+// orderPayout works out what a seller is paid for an order, and the
+// tax they collect on it, in the currencies they're paid in, given
+// what the buyer paid for the order (from settleSales).
+func orderPayout(purchase *pur.Purchase, order *pur.Order, paid map[string]int) (map[string]int, map[string]int, error) {
+	total := map[string]int{}
+	for currency, amount := range paid {
+		total[currency] += amount
+	}
+
+	// Sellers pay for their own promotions. Platform promotions are paid
+	// for out of the platform's share, so the seller gets the full order
+	// total.
+	platform := map[string]int{}
+	if order.Discounts != nil {
+		for _, discount := range *order.Discounts {
+			if !discount.SellerFunded() {
+				platform[strings.ToLower(discount.Currency)] += discount.Amount
+			}
+		}
+	}
+	if len(platform) > 0 {
+		platform, err := settleOrder(purchase, order, platform)
+		if err != nil {
+			return nil, nil, err
+		}
+		for currency, amount := range platform {
+			total[currency] += amount
+		}
+	}
+
+	// Sellers collect the tax on their orders, which is included in what
+	// the buyer paid.
+	tax := map[string]int{}
+	if order.Tax != nil {
+		for currency, amount := range order.Tax.Totals() {
+			tax[strings.ToLower(currency)] += amount
+		}
+	}
+	tax, err := settleOrder(purchase, order, tax)
+	if err != nil {
+		return nil, nil, err
+	}
+	return total, tax, nil
+}
+
+// bookingPayout works out what a host is paid for a booking, and the
+// tax they collect on it, in the currencies they're paid in, given
+// what the buyer paid for the booking (from settleSales).
+func bookingPayout(purchase *pur.Purchase, booking *pur.Booking, paid map[string]int) (map[string]int, map[string]int, error) {
+	total := map[string]int{}
+	for currency, amount := range paid {
+		total[currency] += amount
+	}
+	tax := map[string]int{}
+	if booking.Tax != nil {
+		for currency, amount := range booking.Tax.Totals() {
+			tax[strings.ToLower(currency)] += amount
+		}
+	}
+	tax, err := settle(purchase, tax)
+	if err != nil {
+		return nil, nil, err
+	}
+	return total, tax, nil
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/veganbase/backend/services/payment-service/model"
+	pur "github.com/veganbase/backend/services/purchase-service/model"
+	"github.com/veganbase/backend/services/purchase-service/model/types"
+	site "github.com/veganbase/backend/services/site-service/model"
+)
+
+func TestPurchaseTotals(t *testing.T) {
+	purchase := &pur.Purchase{
+		Items: types.PurchaseItems{
+			{Price: 1000, Quantity: 2, Currency: "EUR"},
+			{Price: 500, Quantity: 1, Currency: "GBP"},
+		},
+		DeliveryFees: &pur.DeliveryFees{{Price: 300, Currency: "EUR"}},
+	}
+	totals, err := purchaseTotals(purchase)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 2300, "gbp": 500}, totals)
+
+	purchase.Currency = "EUR"
+	purchase.ExchangeRates = &site.ExchangeRates{
+		Base:  "EUR",
+		Rates: map[string]float64{"EUR": 1, "GBP": 0.8},
+	}
+	totals, err = purchaseTotals(purchase)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 2925}, totals)
+
+	purchase.Items = append(purchase.Items, types.PurchaseItem{Price: 100, Quantity: 1, Currency: "USD"})
+	_, err = purchaseTotals(purchase)
+	assert.Equal(t, site.ErrUnknownCurrency, err)
+}
+
+func TestOrderPayout(t *testing.T) {
+	// A seller with items in two currencies has a discount in each, one
+	// of which the platform pays for.
+	order := &pur.Order{
+		Items: types.PurchaseItems{
+			{Price: 1000, Quantity: 2, Currency: "EUR"},
+			{Price: 1500, Quantity: 1, Currency: "GBP"},
+		},
+		DeliveryFee: &pur.DeliveryFee{Price: 300, Currency: "EUR"},
+		Discounts: &pur.Discounts{
+			{Amount: 400, Currency: "EUR", FundedBy: pur.FundedBySeller},
+			{Amount: 300, Currency: "GBP", FundedBy: pur.FundedByPlatform},
+		},
+	}
+	total, tax, err := orderPayout(&pur.Purchase{}, order, orderPaid(order))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 1900, "gbp": 1500}, total)
+	assert.Empty(t, tax)
+
+	// Settled in a single currency, both discounts still count.
+	order.Discounts = &pur.Discounts{
+		{Amount: 400, Currency: "EUR", FundedBy: pur.FundedBySeller},
+		{Amount: 300, Currency: "GBP", FundedBy: pur.FundedBySeller},
+	}
+	purchase := &pur.Purchase{
+		Currency: "EUR",
+		ExchangeRates: &site.ExchangeRates{
+			Base:  "EUR",
+			Rates: map[string]float64{"EUR": 1, "GBP": 0.8},
+		},
+	}
+	paid, err := settleOrder(purchase, order, orderPaid(order))
+	assert.Nil(t, err)
+	total, _, err = orderPayout(purchase, order, paid)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 1900 + 1500}, total)
+}
+
+func TestBookingPayout(t *testing.T) {
+	// Tax added to the host's prices is paid out to them along with the
+	// booking.
+	booking := &pur.Booking{
+		BookingInfo: types.BookingInfo{Price: 1000, Quantity: 2, Currency: "EUR"},
+		Tax: &pur.Tax{Lines: []pur.TaxLine{
+			{Rate: 19, Taxable: 2000, Amount: 380, Currency: "EUR"},
+		}},
+	}
+	total, tax, err := bookingPayout(&pur.Purchase{}, booking, bookingPaid(booking))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 2380}, total)
+	assert.Equal(t, map[string]int{"eur": 380}, tax)
+
+	sl, err := bookingSale(&pur.Purchase{}, booking)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 2380}, sl.totals)
+
+	// Tax included in prices is collected out of the booking total.
+	booking.Tax.Inclusive = true
+	total, tax, err = bookingPayout(&pur.Purchase{}, booking, bookingPaid(booking))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 2000}, total)
+	assert.Equal(t, map[string]int{"eur": 380}, tax)
+}
+
+func TestSettleSales(t *testing.T) {
+	// Three orders whose amounts all round up when converted on their
+	// own: 1003 GBP is 1253.75 EUR. The buyer is charged 3761.25,
+	// rounded to 3761, for all three.
+	rate := &site.ExchangeRate{From: "GBP", To: "EUR", Rate: 1.25}
+	orders := []pur.Order{}
+	purchase := &pur.FullPurchase{Purchase: pur.Purchase{
+		Currency: "EUR",
+		ExchangeRates: &site.ExchangeRates{
+			Base:  "EUR",
+			Rates: map[string]float64{"EUR": 1, "GBP": 0.8},
+		},
+	}}
+	for _, id := range []string{"ORD-000001", "ORD-000002", "ORD-000003"} {
+		item := types.PurchaseItem{Price: 1003, Quantity: 1, Currency: "GBP"}
+		purchase.Items = append(purchase.Items, item)
+		orders = append(orders, pur.Order{
+			Id:            id,
+			PaymentStatus: types.Completed,
+			Items:         types.PurchaseItems{item},
+			ExchangeRate:  rate,
+		})
+	}
+	purchase.Orders = &orders
+	charged, err := purchaseTotals(&purchase.Purchase)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"eur": 3761}, charged)
+
+	// Each order is settled at 1253 or 1254, adding up to the charge.
+	paid, err := settleSales(purchase)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]map[string]int{
+		"ORD-000001": {"eur": 1254},
+		"ORD-000002": {"eur": 1254},
+		"ORD-000003": {"eur": 1253},
+	}, paid)
+
+	// Refunding every order in full, and paying out every order, comes
+	// to exactly what was charged.
+	sales, err := purchaseSales(purchase)
+	assert.Nil(t, err)
+	refunds := 0
+	payouts := 0
+	for i, sl := range sales {
+		amounts, err := sl.refundAmounts(nil, &model.RefundRequest{})
+		assert.Nil(t, err)
+		refunds += amounts["eur"]
+		total, _, err := orderPayout(&purchase.Purchase, &orders[i], paid[sl.id])
+		assert.Nil(t, err)
+		payouts += total["eur"]
+	}
+	assert.Equal(t, 3761, refunds)
+	assert.Equal(t, 3761, payouts)
+}
+
+func TestAllocate(t *testing.T) {
+	assert.Equal(t, []int{3, 3, 4}, allocate(10, []float64{3.3, 3.3, 3.4}))
+	assert.Equal(t, []int{2, 1, 1}, allocate(4, []float64{1.5, 1.5, 1.5}))
+	assert.Equal(t, []int{1, 1, 0}, allocate(2, []float64{1.2, 1.1, 0}))
+	assert.Equal(t, []int{}, allocate(0, []float64{}))
+}
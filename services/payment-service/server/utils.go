@@ -3,13 +3,12 @@ package server
 import (
 	"fmt"
 	"github.com/stripe/stripe-go"
-	"github.com/stripe/stripe-go/paymentintent"
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/payment-service/model"
 )
 
 func (s *Server) RetrieveIntent(paymentIntent string) (*stripe.PaymentIntent, error) {
-	pi, err := paymentintent.Get(paymentIntent, nil)
+	pi, err := s.provider.GetIntent(paymentIntent)
 	if err != nil {
 		return nil, fmt.Errorf("payments: error fetching payment intent: %v", err)
 	}
@@ -18,7 +17,7 @@ func (s *Server) RetrieveIntent(paymentIntent string) (*stripe.PaymentIntent, er
 }
 
 func (s *Server) ConfirmIntent(paymentIntent string, source *stripe.Source) error {
-	pi, err := paymentintent.Get(paymentIntent, nil)
+	pi, err := s.provider.GetIntent(paymentIntent)
 	if err != nil {
 		return fmt.Errorf("payments: error fetching payment intent for confirmation: %v", err)
 	}
@@ -27,10 +26,7 @@ func (s *Server) ConfirmIntent(paymentIntent string, source *stripe.Source) erro
 		return fmt.Errorf("payments: PaymentIntent already has a status of %s", pi.Status)
 	}
 
-	params := &stripe.PaymentIntentConfirmParams{
-		Source: stripe.String(source.ID),
-	}
-	pi, err = paymentintent.Confirm(pi.ID, params)
+	pi, err = s.provider.ConfirmIntent(pi.ID, stripe.String(source.ID))
 	if  err != nil {
 		return fmt.Errorf("payments: error confirming PaymentIntent: %v", err)
 	}
@@ -48,7 +44,7 @@ func (s *Server) ConfirmIntent(paymentIntent string, source *stripe.Source) erro
 }
 
 func (s *Server) CancelIntent(paymentIntent string) error {
-	if _, err := paymentintent.Cancel(paymentIntent, nil); err != nil {
+	if _, err := s.provider.CancelIntent(paymentIntent); err != nil {
 		return fmt.Errorf("payments: error canceling PaymentIntent: %v", err)
 	}
 
@@ -64,6 +60,35 @@ func (s *Server) CancelIntent(paymentIntent string) error {
 
 }
 
+// commitStock commits the stock reserved for a purchase once the
+// purchase has been paid for in full.
+func (s *Server) commitStock(purchaseID string) {
+	if err := s.itemSvc.CommitStock(purchaseID); err != nil {
+		s.LogError(purchaseID, "Error while committing reserved stock on item-service: "+err.Error())
+	}
+}
+
+// releaseStock returns the stock reserved for a purchase to sale
+// when payment for the purchase fails.
+func (s *Server) releaseStock(purchaseID string) {
+	if err := s.itemSvc.ReleaseStock(purchaseID); err != nil {
+		s.LogError(purchaseID, "Error while releasing reserved stock on item-service: "+err.Error())
+	}
+}
+
+// orderInvoice issues the invoice for an order that has been paid for,
+// returning it as an email attachment, or nil if it couldn't be
+// issued.
+func (s *Server) orderInvoice(orderID string) *chassis.EmailAttachment {
+	inv, err := s.purchaseSvc.GetOrderInvoice(orderID)
+	if err != nil {
+		s.LogError(orderID, "Error while issuing the order invoice on purchase-service: "+err.Error())
+		return nil
+	}
+	att := chassis.NewEmailAttachment(inv.Filename(), "application/pdf", inv.Content)
+	return &att
+}
+
 func (s *Server) LogError(eventId, error string) {
 	log := model.ErrorLog{
 		EventId:   eventId,
@@ -8,6 +8,9 @@ type Client interface {
 	UpdateOrderPaymentStatus(orderId string, status string) (*model.Order, error)
 	UpdateBookingPaymentStatus(bookingId string, status string) (*model.Booking, error)
 	GetPurchaseInfo(purchaseId string) (*model.FullPurchase, error)
+	GetOrder(orderId string) (*model.Order, error)
+	GetOrderInvoice(orderId string) (*model.Invoice, error)
+	GetBooking(bookingId string) (*model.Booking, error)
 	UserBoughtItem(itemId, userId string) (*bool, error)
 }
 
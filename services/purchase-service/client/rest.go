@@ -46,6 +46,78 @@ func (c *RESTClient) GetPurchaseInfo(purchaseId string) (*model.FullPurchase, er
 	return nil, chassis.BuildErrorFromErrMsg(rsp)
 	}
 
+// GetOrder retrieves an order by calling /internal/order/{ord_id}.
+func (c *RESTClient) GetOrder(orderId string) (*model.Order, error) {
+	rsp, err := http.Get(c.baseURL + "/internal/order/" + orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode response.
+	if rsp.StatusCode == http.StatusOK {
+		order := model.Order{}
+		rspBody, err := ioutil.ReadAll(rsp.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer rsp.Body.Close()
+		if err = json.Unmarshal(rspBody, &order); err != nil {
+			return nil, err
+		}
+
+		return &order, nil
+	}
+	return nil, chassis.BuildErrorFromErrMsg(rsp)
+}
+
+// GetOrderInvoice retrieves the invoice for an order, including the
+// PDF document, by calling /internal/order/{ord_id}/invoice. The
+// invoice is issued if the order hasn't been invoiced yet.
+func (c *RESTClient) GetOrderInvoice(orderId string) (*model.Invoice, error) {
+	rsp, err := http.Get(c.baseURL + "/internal/order/" + orderId + "/invoice")
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, chassis.BuildErrorFromErrMsg(rsp)
+	}
+
+	rspBody, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	inv := model.Invoice{}
+	if err = json.Unmarshal(rspBody, &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// GetBooking retrieves a booking by calling /internal/booking/{bok_id}.
+func (c *RESTClient) GetBooking(bookingId string) (*model.Booking, error) {
+	rsp, err := http.Get(c.baseURL + "/internal/booking/" + bookingId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode response.
+	if rsp.StatusCode == http.StatusOK {
+		bk := model.Booking{}
+		rspBody, err := ioutil.ReadAll(rsp.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer rsp.Body.Close()
+		if err = json.Unmarshal(rspBody, &bk); err != nil {
+			return nil, err
+		}
+
+		return &bk, nil
+	}
+	return nil, chassis.BuildErrorFromErrMsg(rsp)
+}
+
 // UpdatePurchaseStatus invokes the purchase-service internal path PATCH /internal/purchase/{pur_id} to
 // update the purchase status with the desired value
 func (c *RESTClient) UpdatePurchaseStatus(purchaseId string, status string) (*model.Purchase, error) {
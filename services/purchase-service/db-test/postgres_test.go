@@ -0,0 +1,111 @@
+// The following environment variables will be used to get a Postgres
+// connection string: VB_TEST_DB.
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	migrate "github.com/rubenv/sql-migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/veganbase/backend/chassis/test_utils"
+	"github.com/veganbase/backend/services/purchase-service/db"
+	"github.com/veganbase/backend/services/purchase-service/model"
+)
+
+var pg *db.PGClient
+
+func init() {
+	pgdsn := test_utils.InitTestDB(false)
+	var err error
+	pg, err = db.NewPGClient(context.Background(), pgdsn)
+	if err != nil {
+		log.Fatal().Err(err).Msg("couldn't connect to test database")
+	}
+}
+
+func RunWithSchema(t *testing.T, test func(pg *db.PGClient, t *testing.T)) {
+	defer func() {
+		test_utils.ResetSchema(pg.DB, false)
+	}()
+
+	migrations := &migrate.AssetMigrationSource{
+		Asset:    db.Asset,
+		AssetDir: db.AssetDir,
+		Dir:      "migrations",
+	}
+	_, err := migrate.Exec(pg.DB.DB, "postgres", migrations, migrate.Up)
+	assert.Nil(t, err, "database migrations failed!", err)
+
+	test(pg, t)
+}
+
+const ordersFixture = `
+INSERT INTO purchases (id, buyer_id, items, status)
+VALUES ('pur_TEST1', 'usr_BUYER', '[]', 'completed');
+INSERT INTO orders (id, origin, buyer_id, seller, payment_status, items, other_status)
+VALUES ('ord_A1', 'pur_TEST1', 'usr_BUYER', 'usr_SELLERA', 'completed', '[]', '{}'),
+       ('ord_A2', 'pur_TEST1', 'usr_BUYER', 'usr_SELLERA', 'completed', '[]', '{}'),
+       ('ord_A3', 'pur_TEST1', 'usr_BUYER', 'usr_SELLERA', 'completed', '[]', '{}'),
+       ('ord_B1', 'pur_TEST1', 'usr_BUYER', 'usr_SELLERB', 'completed', '[]', '{}');`
+
+func TestInvoiceNumbering(t *testing.T) {
+	RunWithSchema(t, func(pg *db.PGClient, t *testing.T) {
+		tx := pg.DB.MustBegin()
+		test_utils.MultiExec(tx, ordersFixture)
+		tx.Commit()
+
+		store := func(inv *model.Invoice) error {
+			inv.URL = "https://invoices.test/" + inv.Filename()
+			return nil
+		}
+		invoice := func(orderID, seller string, store func(inv *model.Invoice) error) (*model.Invoice, error) {
+			inv := &model.Invoice{OrderID: orderID, Seller: seller}
+			return inv, pg.CreateInvoice(inv, store)
+		}
+
+		// Each seller has their own sequence.
+		var tests = []struct {
+			order  string
+			seller string
+			number string
+		}{
+			{"ord_A1", "usr_SELLERA", "INV-000001"},
+			{"ord_B1", "usr_SELLERB", "INV-000001"},
+			{"ord_A2", "usr_SELLERA", "INV-000002"},
+		}
+		for _, test := range tests {
+			inv, err := invoice(test.order, test.seller, store)
+			assert.Nil(t, err)
+			assert.Equal(t, test.number, inv.Number, test.order)
+			assert.False(t, inv.IssuedAt.IsZero())
+		}
+
+		saved, err := pg.InvoiceByOrder("ord_A2")
+		assert.Nil(t, err)
+		assert.Equal(t, "INV-000002", saved.Number)
+		assert.Equal(t, "https://invoices.test/invoice-INV-000002.pdf", saved.URL)
+
+		// A failure to store the document doesn't use up a number.
+		_, err = invoice("ord_A3", "usr_SELLERA", func(inv *model.Invoice) error {
+			return errors.New("storage unavailable")
+		})
+		assert.NotNil(t, err)
+		_, err = pg.InvoiceByOrder("ord_A3")
+		assert.Equal(t, db.ErrInvoiceNotFound, err)
+		inv, err := invoice("ord_A3", "usr_SELLERA", store)
+		assert.Nil(t, err)
+		assert.Equal(t, "INV-000003", inv.Number)
+
+		// Orders are only invoiced once, and trying again doesn't use
+		// up a number either.
+		_, err = invoice("ord_A1", "usr_SELLERA", store)
+		assert.Equal(t, db.ErrInvoiceExists, err)
+		saved, err = pg.InvoiceByOrder("ord_A1")
+		assert.Nil(t, err)
+		assert.Equal(t, "INV-000001", saved.Number)
+	})
+}
@@ -75,7 +75,7 @@ func (pg *PGClient) BookingsByBuyer(buyerId string, params *chassis.Pagination)
 
 
 const qBookingBy = `
-SELECT id, origin, buyer_id, host, item_id, booking_info, payment_status, created_at
+SELECT id, origin, buyer_id, host, item_id, booking_info, tax, payment_status, created_at
 FROM bookings WHERE `
 
 
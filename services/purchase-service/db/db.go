@@ -27,6 +27,18 @@ var ErrSubscriptionItemNotFound = errors.New("subscription item ID not found")
 // access or manipulate a subscription purchase processing with an unknown ID.
 var ErrSubscriptionPurchaseProcessingNotFound = errors.New("subscription purchase processing not found")
 
+// ErrTaxRatesNotFound is the error returned when an attempt is made to
+// manipulate the tax rates of a country that has none.
+var ErrTaxRatesNotFound = errors.New("tax rates not found")
+
+// ErrInvoiceNotFound is the error returned when an attempt is made to
+// access the invoice of an order that hasn't been invoiced.
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+// ErrInvoiceExists is the error returned when an attempt is made to
+// invoice an order that has already been invoiced.
+var ErrInvoiceExists = errors.New("order already invoiced")
+
 type DB interface {
 	// PURCHASE
 	PurchaseById(purchaseId string) (*model.Purchase, error)
@@ -73,6 +85,15 @@ type DB interface {
 	CreateSubscriptionPurchases(ref string) error
 	UpdateSubscriptionPurchase(subs *model.SubscriptionPurchase) error
 
+	// TAX RATES
+	TaxRates(country string) (model.TaxRates, error)
+	SetTaxRates(country string, rates model.TaxRates) error
+	DeleteTaxRates(country string) error
+
+	// INVOICES
+	InvoiceByOrder(orderID string) (*model.Invoice, error)
+	CreateInvoice(inv *model.Invoice, store func(inv *model.Invoice) error) error
+
 	// SaveEvent saves an event to the database.
 	SaveEvent(topic string, eventData interface{}, inTx func() error) error
 }
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/veganbase/backend/services/purchase-service/model"
+)
+
+// InvoiceByOrder returns the invoice issued for an order.
+func (pg *PGClient) InvoiceByOrder(orderID string) (*model.Invoice, error) {
+	inv := &model.Invoice{}
+	err := pg.DB.Get(inv, qInvoiceByOrder, orderID)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+const qInvoiceByOrder = `
+SELECT order_id, seller, number, url, issued_at
+  FROM invoices
+ WHERE order_id = $1`
+
+// CreateInvoice issues an invoice for an order, giving it the next
+// number in the seller's invoice sequence. The store function is
+// called with the number and issue date filled in, to produce and
+// store the invoice document and set its URL; if it fails, no
+// invoice is created and the number is not used.
+func (pg *PGClient) CreateInvoice(inv *model.Invoice, store func(inv *model.Invoice) error) error {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	// Taking the next number locks the seller's sequence until the
+	// transaction ends, so the check for an existing invoice can't
+	// race with another attempt to invoice the same order.
+	seq := 0
+	if err = tx.Get(&seq, qNextInvoiceNumber, inv.Seller); err != nil {
+		return err
+	}
+	exists := false
+	if err = tx.Get(&exists, qInvoiceExists, inv.OrderID); err != nil {
+		return err
+	}
+	if exists {
+		err = ErrInvoiceExists
+		return err
+	}
+
+	inv.Number = model.InvoiceNumber(seq)
+	inv.IssuedAt = time.Now()
+	if err = store(inv); err != nil {
+		return err
+	}
+	_, err = tx.NamedExec(qCreateInvoice, inv)
+	return err
+}
+
+const qNextInvoiceNumber = `
+INSERT INTO invoice_sequences (seller, last_number) VALUES ($1, 1)
+ON CONFLICT (seller)
+DO UPDATE SET last_number = invoice_sequences.last_number + 1
+RETURNING last_number`
+
+const qInvoiceExists = `
+SELECT EXISTS (SELECT 1 FROM invoices WHERE order_id = $1)`
+
+const qCreateInvoice = `
+INSERT INTO invoices (order_id, seller, number, url, issued_at)
+VALUES (:order_id, :seller, :number, :url, :issued_at)`
@@ -0,0 +1,51 @@
+-- +migrate Up notransaction
+
+SET ROLE vb_purchases;
+
+-- Enum values can't be added inside a transaction block on older
+-- PostgreSQL versions, hence "notransaction" above.
+ALTER TYPE purchase_status ADD VALUE IF NOT EXISTS 'refunded';
+ALTER TYPE purchase_status ADD VALUE IF NOT EXISTS 'partially_refunded';
+ALTER TYPE payment_status ADD VALUE IF NOT EXISTS 'refunded';
+ALTER TYPE payment_status ADD VALUE IF NOT EXISTS 'partially_refunded';
+
+
+-- +migrate Down
+
+SET ROLE vb_purchases;
+
+-- PostgreSQL can't drop values from an enum type, so the types are
+-- recreated without them. Refunded purchases, orders and bookings go
+-- back to being completed.
+UPDATE purchases SET status = 'completed'
+ WHERE status IN ('refunded', 'partially_refunded');
+UPDATE subscription_purchases SET status = 'completed'
+ WHERE status IN ('refunded', 'partially_refunded');
+UPDATE orders SET payment_status = 'completed'
+ WHERE payment_status IN ('refunded', 'partially_refunded');
+UPDATE bookings SET payment_status = 'completed'
+ WHERE payment_status IN ('refunded', 'partially_refunded');
+
+ALTER TYPE purchase_status RENAME TO purchase_status_old;
+CREATE TYPE purchase_status AS ENUM ('pending', 'failed', 'completed');
+ALTER TABLE purchases
+  ALTER COLUMN status DROP DEFAULT,
+  ALTER COLUMN status TYPE purchase_status USING status::text::purchase_status,
+  ALTER COLUMN status SET DEFAULT 'pending';
+ALTER TABLE subscription_purchases
+  ALTER COLUMN status DROP DEFAULT,
+  ALTER COLUMN status TYPE purchase_status USING status::text::purchase_status,
+  ALTER COLUMN status SET DEFAULT 'pending';
+DROP TYPE purchase_status_old;
+
+ALTER TYPE payment_status RENAME TO payment_status_old;
+CREATE TYPE payment_status AS ENUM ('pending', 'failed', 'completed');
+ALTER TABLE orders
+  ALTER COLUMN payment_status DROP DEFAULT,
+  ALTER COLUMN payment_status TYPE payment_status USING payment_status::text::payment_status,
+  ALTER COLUMN payment_status SET DEFAULT 'pending';
+ALTER TABLE bookings
+  ALTER COLUMN payment_status DROP DEFAULT,
+  ALTER COLUMN payment_status TYPE payment_status USING payment_status::text::payment_status,
+  ALTER COLUMN payment_status SET DEFAULT 'pending';
+DROP TYPE payment_status_old;
@@ -0,0 +1,16 @@
+-- +migrate Up
+
+SET ROLE vb_purchases;
+
+ALTER TABLE purchases
+    ADD COLUMN discounts JSONB DEFAULT '[]';
+
+ALTER TABLE orders
+    ADD COLUMN discount JSONB DEFAULT '{}';
+
+-- +migrate Down
+
+SET ROLE vb_purchases;
+
+ALTER TABLE purchases DROP COLUMN discounts;
+ALTER TABLE orders DROP COLUMN discount;
@@ -0,0 +1,17 @@
+-- +migrate Up
+
+SET ROLE vb_purchases;
+
+ALTER TABLE purchases
+    ADD COLUMN currency TEXT NOT NULL DEFAULT '',
+    ADD COLUMN exchange_rates JSONB;
+
+ALTER TABLE orders
+    ADD COLUMN exchange_rate JSONB;
+
+-- +migrate Down
+
+SET ROLE vb_purchases;
+
+ALTER TABLE purchases DROP COLUMN currency, DROP COLUMN exchange_rates;
+ALTER TABLE orders DROP COLUMN exchange_rate;
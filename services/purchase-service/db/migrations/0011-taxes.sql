@@ -0,0 +1,26 @@
+-- +migrate Up
+
+SET ROLE vb_purchases;
+
+CREATE TABLE tax_rates (
+  country  CHAR(2)      NOT NULL,
+  category TEXT         NOT NULL DEFAULT '',
+  rate     NUMERIC(5,2) NOT NULL CHECK (rate >= 0 AND rate < 100),
+
+  PRIMARY KEY (country, category)
+);
+
+ALTER TABLE purchases
+    ADD COLUMN taxes JSONB DEFAULT '[]';
+
+ALTER TABLE orders
+    ADD COLUMN tax JSONB;
+
+-- +migrate Down
+
+SET ROLE vb_purchases;
+
+ALTER TABLE purchases DROP COLUMN taxes;
+ALTER TABLE orders DROP COLUMN tax;
+
+DROP TABLE tax_rates;
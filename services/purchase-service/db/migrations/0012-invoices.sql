@@ -0,0 +1,26 @@
+-- +migrate Up
+
+SET ROLE vb_purchases;
+
+-- Invoices are numbered in sequence separately for each seller.
+CREATE TABLE invoice_sequences (
+  seller      VARCHAR(24) PRIMARY KEY,
+  last_number INTEGER     NOT NULL DEFAULT 0
+);
+
+CREATE TABLE invoices (
+  order_id  VARCHAR(24) PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+  seller    VARCHAR(24) NOT NULL,
+  number    TEXT        NOT NULL,
+  url       TEXT        NOT NULL,
+  issued_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+  UNIQUE (seller, number)
+);
+
+-- +migrate Down
+
+SET ROLE vb_purchases;
+
+DROP TABLE invoices;
+DROP TABLE invoice_sequences;
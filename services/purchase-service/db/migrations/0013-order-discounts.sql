@@ -0,0 +1,26 @@
+-- +migrate Up
+
+SET ROLE vb_purchases;
+
+-- A seller's order can have more than one discount, e.g. one for each
+-- currency that the seller's items are priced in.
+ALTER TABLE orders
+    ADD COLUMN discounts JSONB DEFAULT '[]';
+
+UPDATE orders SET discounts = jsonb_build_array(discount)
+ WHERE discount IS NOT NULL AND discount <> '{}';
+
+ALTER TABLE orders DROP COLUMN discount;
+
+-- +migrate Down
+
+SET ROLE vb_purchases;
+
+-- Only the first discount of each order can be kept.
+ALTER TABLE orders
+    ADD COLUMN discount JSONB DEFAULT '{}';
+
+UPDATE orders SET discount = discounts->0
+ WHERE jsonb_array_length(discounts) > 0;
+
+ALTER TABLE orders DROP COLUMN discounts;
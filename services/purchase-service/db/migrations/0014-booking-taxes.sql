@@ -0,0 +1,13 @@
+-- +migrate Up
+
+SET ROLE vb_purchases;
+
+-- Bookings are taxed at the rates of the host's country.
+ALTER TABLE bookings
+    ADD COLUMN tax JSONB;
+
+-- +migrate Down
+
+SET ROLE vb_purchases;
+
+ALTER TABLE bookings DROP COLUMN tax;
@@ -86,7 +86,7 @@ func (pg *PGClient) OrdersByBuyer(buyerId string, params *chassis.Pagination) (*
 
 
 const qOrderBy = `
-SELECT id, origin, buyer_id, seller, payment_status, items, delivery_fee, order_info, created_at
+SELECT id, origin, buyer_id, seller, payment_status, items, delivery_fee, discounts, exchange_rate, tax, order_info, created_at
 FROM orders WHERE `
 
 
@@ -8,7 +8,7 @@ import (
 )
 
 const qPurchaseBy = `
-SELECT id, buyer_id, items, delivery_fees, status, site, created_at
+SELECT id, buyer_id, items, delivery_fees, discounts, taxes, currency, exchange_rates, status, site, created_at
 FROM purchases WHERE `
 
 // PurchaseById looks up a purchase by its ID.
@@ -57,10 +57,12 @@ func (pg *PGClient) CreatePurchase(purchase *model.Purchase, orders *[]model.Ord
 			tx.Rollback()
 		}
 	}()
-	//creating a new purchaseId
+	//creating a new purchaseId, unless the caller has already chosen one
 
 	//inserting the purchase
-	purchase.Id = chassis.NewPurchaseID()
+	if purchase.Id == "" {
+		purchase.Id = chassis.NewPurchaseID()
+	}
 	rows, err := tx.NamedQuery(qCreatePurchase, purchase)
 	if err != nil {
 		return err
@@ -114,22 +116,22 @@ func (pg *PGClient) CreatePurchase(purchase *model.Purchase, orders *[]model.Ord
 
 const qCreatePurchase = `
 INSERT INTO
-	purchases (id, buyer_id, items, delivery_fees, status, site)
-VALUES (:id, :buyer_id, :items, :delivery_fees, :status, :site)
+	purchases (id, buyer_id, items, delivery_fees, discounts, taxes, currency, exchange_rates, status, site)
+VALUES (:id, :buyer_id, :items, :delivery_fees, :discounts, :taxes, :currency, :exchange_rates, :status, :site)
 ON CONFLICT DO NOTHING
 RETURNING created_at`
 
 const qCreateOrder = `
 INSERT INTO
-	orders (id, origin, buyer_id, seller, payment_status, items, delivery_fee, order_info)
-VALUES (:id, :origin, :buyer_id, :seller, :payment_status, :items, :delivery_fee, :order_info)
+	orders (id, origin, buyer_id, seller, payment_status, items, delivery_fee, discounts, exchange_rate, tax, order_info)
+VALUES (:id, :origin, :buyer_id, :seller, :payment_status, :items, :delivery_fee, :discounts, :exchange_rate, :tax, :order_info)
 ON CONFLICT DO NOTHING
 RETURNING created_at`
 
 const qCreateBooking = `
 INSERT INTO
-  bookings (id, origin, buyer_id, host, item_id, booking_info, payment_status)
- VALUES ( :id, :origin, :buyer_id, :host, :item_id, :booking_info, :payment_status)
+  bookings (id, origin, buyer_id, host, item_id, booking_info, tax, payment_status)
+ VALUES ( :id, :origin, :buyer_id, :host, :item_id, :booking_info, :tax, :payment_status)
  ON CONFLICT DO NOTHING
  RETURNING created_at`
 
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"github.com/veganbase/backend/services/purchase-service/model"
+)
+
+const qTaxRates = `
+SELECT country, category, rate FROM tax_rates`
+
+// TaxRates returns the tax rates for a country, or for all countries
+// if the country is empty.
+func (pg *PGClient) TaxRates(country string) (model.TaxRates, error) {
+	rates := model.TaxRates{}
+	q := qTaxRates + ` ORDER BY country, category`
+	args := []interface{}{}
+	if country != "" {
+		q = qTaxRates + ` WHERE country = $1 ORDER BY category`
+		args = append(args, country)
+	}
+	if err := pg.DB.Select(&rates, q, args...); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// SetTaxRates replaces the tax rates for a country.
+func (pg *PGClient) SetTaxRates(country string, rates model.TaxRates) error {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(`DELETE FROM tax_rates WHERE country = $1`, country); err != nil {
+		return err
+	}
+	for _, rate := range rates {
+		if _, err = tx.NamedExec(qCreateTaxRate, rate); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+const qCreateTaxRate = `
+INSERT INTO tax_rates (country, category, rate)
+VALUES (:country, :category, :rate)`
+
+// DeleteTaxRates deletes the tax rates for a country.
+func (pg *PGClient) DeleteTaxRates(country string) error {
+	result, err := pg.DB.Exec(`DELETE FROM tax_rates WHERE country = $1`, country)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrTaxRatesNotFound
+	}
+	return nil
+}
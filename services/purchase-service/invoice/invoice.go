@@ -0,0 +1,225 @@
+package invoice
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/veganbase/backend/chassis"
+)
+
+// Invoice is the content of an invoice for an order.
+type Invoice struct {
+	Number   string
+	OrderID  string
+	IssuedAt time.Time
+
+	Seller Party
+	Buyer  Party
+
+	// What was charged for the order, grouped by currency. Most orders
+	// have items in a single currency, and so a single section.
+	Sections []Section
+
+	// Whether tax is included in the item prices or charged on top of
+	// them.
+	TaxInclusive bool
+}
+
+// Section is the part of an invoice charged in one currency, with all
+// amounts in the smallest unit of the currency.
+type Section struct {
+	Currency string
+	Lines    []Line
+	Delivery int
+	Discount int
+	Taxes    []TaxLine
+}
+
+// Party is the seller or buyer named on an invoice.
+type Party struct {
+	Name      string
+	Address   []string
+	Email     string
+	VATNumber string
+}
+
+// Line is an invoice line for an item in an order.
+type Line struct {
+	Description string
+	Quantity    int
+	UnitPrice   int
+}
+
+// TaxLine is the tax charged at one rate.
+type TaxLine struct {
+	Label   string
+	Taxable int
+	Amount  int
+}
+
+// Subtotal returns the total of the item lines and delivery, less any
+// discount.
+func (sec *Section) Subtotal() int {
+	total := sec.Delivery - sec.Discount
+	for _, l := range sec.Lines {
+		total += l.UnitPrice * l.Quantity
+	}
+	return total
+}
+
+// Total returns the total amount due for a section of an invoice.
+func (sec *Section) Total(taxInclusive bool) int {
+	total := sec.Subtotal()
+	if !taxInclusive {
+		for _, t := range sec.Taxes {
+			total += t.Amount
+		}
+	}
+	return total
+}
+
+// Column positions for invoice lines.
+const (
+	colQuantity  = 370.0
+	colUnitPrice = 460.0
+	colAmount    = pageWidth - margin
+	lineHeight   = 16.0
+)
+
+// Render lays out an invoice as a PDF document. Each currency the
+// order was charged in gets its own lines and totals.
+func Render(inv *Invoice) []byte {
+	d := newDocument()
+
+	y := pageHeight - margin - 10
+	d.text(margin, y, bold, 22, "INVOICE")
+	d.textRight(colAmount, y, bold, 10, "Invoice "+inv.Number)
+	y -= lineHeight
+	d.textRight(colAmount, y, regular, 10, "Date: "+inv.IssuedAt.Format("2 January 2006"))
+	y -= lineHeight
+	d.textRight(colAmount, y, regular, 10, "Order: "+inv.OrderID)
+
+	// Seller and buyer details side by side.
+	y -= 2 * lineHeight
+	top := y
+	sellerEnd := party(d, margin, top, "From", &inv.Seller)
+	buyerEnd := party(d, pageWidth/2, top, "Bill to", &inv.Buyer)
+	y = sellerEnd
+	if buyerEnd < y {
+		y = buyerEnd
+	}
+
+	// Invoice lines, continuing on new pages as needed.
+	header := func() {
+		y -= lineHeight
+		d.text(margin, y, bold, 10, "Description")
+		d.textRight(colQuantity, y, bold, 10, "Qty")
+		d.textRight(colUnitPrice, y, bold, 10, "Unit price")
+		d.textRight(colAmount, y, bold, 10, "Amount")
+		y -= 6
+		d.line(margin, y, colAmount, y)
+		y -= lineHeight
+	}
+	row := func(desc, qty, unit, amount string) {
+		if y < margin+lineHeight {
+			d.addPage()
+			y = pageHeight - margin
+			header()
+		}
+		d.text(margin, y, regular, 10, truncate(desc, colQuantity-margin-40))
+		d.textRight(colQuantity, y, regular, 10, qty)
+		d.textRight(colUnitPrice, y, regular, 10, unit)
+		d.textRight(colAmount, y, regular, 10, amount)
+		y -= lineHeight
+	}
+	total := func(label, amount string, font string) {
+		d.textRight(colUnitPrice, y, font, 10, label)
+		d.textRight(colAmount, y, font, 10, amount)
+		y -= lineHeight
+	}
+
+	taxed := false
+	for i := range inv.Sections {
+		sec := &inv.Sections[i]
+		money := func(v int) string { return chassis.FormatCurrencyValue(sec.Currency, v) }
+
+		if len(inv.Sections) > 1 {
+			if y < margin+6*lineHeight {
+				d.addPage()
+				y = pageHeight - margin
+			}
+			y -= lineHeight
+			d.text(margin, y, bold, 11, "Charged in "+strings.ToUpper(sec.Currency))
+		}
+		header()
+		for _, l := range sec.Lines {
+			row(l.Description, strconv.Itoa(l.Quantity), money(l.UnitPrice), money(l.UnitPrice*l.Quantity))
+		}
+		if sec.Delivery > 0 {
+			row("Delivery", "", "", money(sec.Delivery))
+		}
+		if sec.Discount > 0 {
+			row("Discount", "", "", money(-sec.Discount))
+		}
+
+		// Totals, kept together on one page.
+		if y < margin+float64(len(sec.Taxes)+4)*lineHeight {
+			d.addPage()
+			y = pageHeight - margin
+		}
+		d.line(colUnitPrice-100, y+lineHeight-6, colAmount, y+lineHeight-6)
+		y -= 4
+		total("Subtotal", money(sec.Subtotal()), regular)
+		for _, t := range sec.Taxes {
+			label := t.Label + " on " + money(t.Taxable)
+			if inv.TaxInclusive {
+				label += " (included)"
+			}
+			total(label, money(t.Amount), regular)
+		}
+		total("Total", money(sec.Total(inv.TaxInclusive)), bold)
+		taxed = taxed || len(sec.Taxes) > 0
+	}
+
+	if inv.TaxInclusive && taxed {
+		y -= lineHeight
+		d.text(margin, y, regular, 9, "Prices include VAT.")
+	}
+
+	return d.bytes()
+}
+
+// party writes the details of a seller or buyer in a block starting
+// at (x, y), returning the position below the block.
+func party(d *document, x, y float64, title string, p *Party) float64 {
+	d.text(x, y, bold, 10, title)
+	y -= lineHeight
+	lines := append([]string{p.Name}, p.Address...)
+	if p.Email != "" {
+		lines = append(lines, p.Email)
+	}
+	if p.VATNumber != "" {
+		lines = append(lines, "VAT number: "+p.VATNumber)
+	}
+	for _, l := range lines {
+		if l == "" {
+			continue
+		}
+		d.text(x, y, regular, 10, truncate(l, pageWidth/2-margin-10))
+		y -= lineHeight - 3
+	}
+	return y
+}
+
+// truncate shortens a string to fit a width in the regular font.
+func truncate(s string, width float64) string {
+	if textWidth(regular, 10, s) <= width {
+		return s
+	}
+	rs := []rune(s)
+	for len(rs) > 0 && textWidth(regular, 10, string(rs)+"...") > width {
+		rs = rs[:len(rs)-1]
+	}
+	return string(rs) + "..."
+}
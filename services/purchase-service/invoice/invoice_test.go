@@ -0,0 +1,176 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSectionTotals(t *testing.T) {
+	sec := Section{
+		Currency: "EUR",
+		Lines:    []Line{{"Oat milk", 3, 250}, {"Tofu", 2, 400}},
+		Delivery: 500,
+		Discount: 150,
+		Taxes:    []TaxLine{{"VAT 10%", 1455, 146}},
+	}
+	assert.Equal(t, 1900, sec.Subtotal())
+	assert.Equal(t, 2046, sec.Total(false))
+	assert.Equal(t, 1900, sec.Total(true))
+}
+
+func TestRender(t *testing.T) {
+	inv := &Invoice{
+		Number:   "INV-000042",
+		OrderID:  "ORD-000001",
+		IssuedAt: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		Seller:   Party{Name: "Green Grocer", VATNumber: "DE123456789"},
+		Buyer:    Party{Name: "Zoë Buyer", Address: []string{"1 Main St", "10115 Berlin", "DE"}},
+		Sections: []Section{
+			{
+				Currency: "EUR",
+				Lines:    []Line{{"Oat milk (1 l)", 3, 250}},
+				Delivery: 500,
+				Taxes:    []TaxLine{{"VAT 7%", 1168, 82}},
+			},
+			{
+				Currency: "GBP",
+				Lines:    []Line{{"Marmite", 1, 400}},
+				Discount: 100,
+			},
+		},
+		TaxInclusive: true,
+	}
+
+	pdf := parsePDF(t, Render(inv))
+	assert.Equal(t, 1, pdf.pages)
+	text := strings.Join(pdf.text, "\n")
+	for _, s := range []string{
+		"INVOICE", "Invoice INV-000042", "Date: 1 October 2026", "Order: ORD-000001",
+		"Green Grocer", "VAT number: DE123456789", "Zoë Buyer", "10115 Berlin",
+		"Charged in EUR", "Oat milk (1 l)", "€ 2.50", "€ 7.50", "VAT 7% on € 11.68 (included)",
+		"€ 12.50", "Charged in GBP", "Marmite", "£ -1.00", "£ 3.00", "Prices include VAT.",
+	} {
+		assert.Contains(t, pdf.text, s, "missing text %q in:\n%s", s, text)
+	}
+
+	// A single currency doesn't need a heading.
+	inv.Sections = inv.Sections[:1]
+	pdf = parsePDF(t, Render(inv))
+	assert.NotContains(t, strings.Join(pdf.text, "\n"), "Charged in")
+
+	// Long invoices continue on more pages, repeating the column
+	// headings.
+	for i := 0; i < 60; i++ {
+		inv.Sections[0].Lines = append(inv.Sections[0].Lines, Line{"Item " + strconv.Itoa(i), 1, 100})
+	}
+	pdf = parsePDF(t, Render(inv))
+	assert.Equal(t, 2, pdf.pages)
+	assert.Contains(t, pdf.text, "Item 59")
+	headings := 0
+	for _, s := range pdf.text {
+		if s == "Unit price" {
+			headings++
+		}
+	}
+	assert.Equal(t, 2, headings)
+}
+
+// parsedPDF is what's read back from a rendered document.
+type parsedPDF struct {
+	pages int
+	text  []string
+}
+
+var (
+	startXRef  = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	objHeader  = regexp.MustCompile(`^(\d+) 0 obj\n`)
+	pageCount  = regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`)
+	streamObj  = regexp.MustCompile(`(?s)^<< /Length (\d+) >>\nstream\n`)
+	textString = regexp.MustCompile(`\(((?:\\.|[^\\)])*)\) Tj`)
+)
+
+// parsePDF reads back a document produced by the PDF writer, checking
+// its structure: the cross-reference table has to point at each
+// object, and stream lengths have to match their content. The text
+// drawn on the pages is decoded from WinAnsi.
+func parsePDF(t *testing.T, data []byte) *parsedPDF {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) {
+		t.Fatal("missing PDF header")
+	}
+	m := startXRef.FindSubmatch(data)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	lines := strings.Split(string(data[xref:]), "\n")
+	if lines[0] != "xref" {
+		t.Fatalf("startxref points at %q", lines[0])
+	}
+	var first, size int
+	if _, err := fmt.Sscanf(lines[1], "%d %d", &first, &size); err != nil || first != 0 {
+		t.Fatalf("bad xref subsection %q", lines[1])
+	}
+	trailer := strings.Join(lines[size+2:], "\n")
+	if !strings.Contains(trailer, "/Size "+strconv.Itoa(size)+" /Root 1 0 R") {
+		t.Fatalf("bad trailer %q", trailer)
+	}
+
+	pdf := &parsedPDF{}
+	for i := 1; i < size; i++ {
+		off, err := strconv.Atoi(strings.Fields(lines[i+2])[0])
+		if err != nil {
+			t.Fatalf("bad xref entry %q", lines[i+2])
+		}
+		obj := data[off:]
+		hm := objHeader.FindSubmatch(obj)
+		if hm == nil || string(hm[1]) != strconv.Itoa(i) {
+			t.Fatalf("xref entry %d doesn't point at its object", i)
+		}
+		obj = obj[len(hm[0]):]
+		obj = obj[:bytes.Index(obj, []byte("\nendobj\n"))]
+
+		if pm := pageCount.FindSubmatch(obj); pm != nil {
+			pdf.pages, _ = strconv.Atoi(string(pm[1]))
+		}
+		sm := streamObj.FindSubmatch(obj)
+		if sm == nil {
+			continue
+		}
+		length, _ := strconv.Atoi(string(sm[1]))
+		content := obj[len(sm[0]):]
+		if !bytes.HasSuffix(content, []byte("endstream")) ||
+			len(content)-len("endstream") != length {
+			t.Fatalf("stream length of object %d doesn't match its content", i)
+		}
+		for _, tm := range textString.FindAllSubmatch(content[:length], -1) {
+			pdf.text = append(pdf.text, decode(tm[1]))
+		}
+	}
+	return pdf
+}
+
+// decode unescapes a PDF string and converts it from WinAnsi.
+func decode(s []byte) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			i++
+			c = s[i]
+		}
+		if c == 0x80 {
+			b.WriteRune('€')
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
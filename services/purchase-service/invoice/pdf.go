@@ -0,0 +1,168 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page size and margins, in points.
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 50.0
+)
+
+// Fonts available to documents: the standard Helvetica fonts, which
+// every PDF reader provides, so no font data needs to be embedded.
+const (
+	regular = "F1"
+	bold    = "F2"
+)
+
+// document is a minimal PDF writer, supporting just what's needed for
+// invoices: multiple pages of text in the standard Helvetica fonts,
+// and ruled lines.
+type document struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+}
+
+func newDocument() *document {
+	d := &document{}
+	d.addPage()
+	return d
+}
+
+// addPage starts a new page, which becomes the target for text and
+// lines.
+func (d *document) addPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+}
+
+// text writes a string with its baseline starting at (x, y), measured
+// from the bottom left of the page.
+func (d *document) text(x, y float64, font string, size float64, s string) {
+	fmt.Fprintf(d.page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		font, size, x, y, escape(encode(s)))
+}
+
+// textRight writes a string right-aligned at x.
+func (d *document) textRight(x, y float64, font string, size float64, s string) {
+	d.text(x-textWidth(font, size, s), y, font, size, s)
+}
+
+// line draws a thin line from (x1, y1) to (x2, y2).
+func (d *document) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// bytes renders the document as a PDF file.
+func (d *document) bytes() []byte {
+	// Objects are numbered from 1: the catalog, the page tree, the two
+	// fonts, then a page and its content stream for each page.
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+	kids := []string{}
+	for _, p := range d.pages {
+		pageObj := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageObj))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+				"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, pageObj+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.Len(), p.String()))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>",
+		strings.Join(kids, " "), len(d.pages))
+
+	out := &bytes.Buffer{}
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// encode converts a string to the WinAnsi encoding used by the
+// standard fonts. Characters that can't be encoded are replaced by
+// question marks.
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '€':
+			out = append(out, 0x80)
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// escape escapes the characters that are special in PDF strings.
+func escape(s []byte) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c == '\\' || c == '(' || c == ')' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// textWidth works out the width of a string, from the character
+// widths of the standard fonts. Characters outside printable ASCII
+// are given the width of a digit, which is close enough for layout.
+func textWidth(font string, size float64, s string) float64 {
+	widths := helveticaWidths
+	if font == bold {
+		widths = helveticaBoldWidths
+	}
+	w := 0
+	for _, r := range s {
+		if r >= 0x20 && r < 0x7f {
+			w += widths[r-0x20]
+		} else {
+			w += 556
+		}
+	}
+	return float64(w) * size / 1000
+}
+
+// Character widths for printable ASCII characters, in thousandths of
+// the font size.
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [...]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
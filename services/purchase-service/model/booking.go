@@ -16,6 +16,7 @@ type Booking struct {
 	ItemID        string              `db:"item_id" json:"item_id"`
 	PaymentStatus types.PaymentStatus `db:"payment_status" json:"payment_status"`
 	BookingInfo   types.BookingInfo   `db:"booking_info" json:"booking_info"`
+	Tax           *Tax                `db:"tax" json:"tax,omitempty"`
 	CreatedAt     time.Time           `db:"created_at" json:"created_at"`
 	//OtherStatus   types.OtherMap      `db:"other_status"`
 }
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Who pays for a discount: the seller's payout is reduced for
+// seller-funded discounts, while the platform covers platform-funded
+// ones.
+const (
+	FundedBySeller   = "seller"
+	FundedByPlatform = "platform"
+)
+
+type Discounts []Discount
+
+// Discount is the amount taken off an order by a promotion code.
+type Discount struct {
+	PromotionID string `json:"promotion_id"`
+	Code        string `json:"code"`
+	Seller      string `json:"seller"`
+	Amount      int    `json:"amount"`
+	Currency    string `json:"currency"`
+	FundedBy    string `json:"funded_by"`
+}
+
+// SellerFunded says whether the seller pays for a discount.
+func (d *Discount) SellerFunded() bool {
+	return d.FundedBy == FundedBySeller
+}
+
+// Make the Discount struct implement the driver.Valuer interface. This method
+// simply returns the JSON-encoded representation of the struct.
+func (d *Discount) Value() (driver.Value, error) {
+	v, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return types.JSONText(v).Value()
+}
+
+// Make the Discounts struct implement the driver.Valuer interface. This method
+// simply returns the JSON-encoded representation of the struct.
+func (ds *Discounts) Value() (driver.Value, error) {
+	v, err := json.Marshal(ds)
+	if err != nil {
+		return nil, err
+	}
+	return types.JSONText(v).Value()
+}
+
+// Make the Discount struct implement the sql.Scanner interface. This method
+// simply decodes a JSON-encoded value into the struct fields.
+func (d *Discount) Scan(src interface{}) error {
+	j := types.JSONText{}
+	err := j.Scan(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(j, d)
+}
+
+// Make the Discounts struct implement the sql.Scanner interface. This method
+// simply decodes a JSON-encoded value into the struct fields.
+func (ds *Discounts) Scan(src interface{}) error {
+	j := types.JSONText{}
+	err := j.Scan(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(j, ds)
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Invoice is a row in the invoices table, recording the invoice issued
+// for an order. The invoice document itself is held in blob storage.
+type Invoice struct {
+	OrderID  string    `db:"order_id" json:"order_id"`
+	Seller   string    `db:"seller" json:"seller"`
+	Number   string    `db:"number" json:"number"`
+	URL      string    `db:"url" json:"url"`
+	IssuedAt time.Time `db:"issued_at" json:"issued_at"`
+
+	// The PDF document, included only when invoices are passed between
+	// services.
+	Content []byte `db:"-" json:"content,omitempty"`
+}
+
+// InvoiceNumber formats a seller's invoice sequence number.
+func InvoiceNumber(seq int) string {
+	return fmt.Sprintf("INV-%06d", seq)
+}
+
+// Filename returns the file name used for an invoice document.
+func (inv *Invoice) Filename() string {
+	return "invoice-" + inv.Number + ".pdf"
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"github.com/pkg/errors"
 	"github.com/veganbase/backend/services/purchase-service/model/types"
+	site "github.com/veganbase/backend/services/site-service/model"
 	"time"
 )
 
@@ -16,6 +17,9 @@ type Order struct {
 	PaymentStatus types.PaymentStatus `db:"payment_status" json:"payment_status"`
 	Items         types.PurchaseItems `db:"items" json:"items"`
 	DeliveryFee   *DeliveryFee        `db:"delivery_fee" json:"delivery_fee,omitempty"`
+	Discounts     *Discounts          `db:"discounts" json:"discounts,omitempty"`
+	ExchangeRate  *site.ExchangeRate  `db:"exchange_rate" json:"exchange_rate,omitempty"`
+	Tax           *Tax                `db:"tax" json:"tax,omitempty"`
 	OrderInfo     types.InfoMap       `db:"order_info" json:"order_info,omitempty"`
 	CreatedAt     time.Time           `db:"created_at" json:"created_at"`
 }
@@ -30,13 +34,16 @@ func (o *Order) Patch(body []byte) error {
 
 	// Step 2 - verify if one of the fields that are present is read-only
 	roFields := map[string]string{
-		"id":         "id",
-		"code":       "code",
-		"origin":     "origin",
-		"buyer_id":   "buyer_id",
-		"seller":     "seller",
-		"items":      "items",
-		"created_at": "created_at",
+		"id":            "id",
+		"code":          "code",
+		"origin":        "origin",
+		"buyer_id":      "buyer_id",
+		"seller":        "seller",
+		"items":         "items",
+		"discounts":     "discounts",
+		"exchange_rate": "exchange_rate",
+		"tax":           "tax",
+		"created_at":    "created_at",
 	}
 
 	for fld, label := range roFields {
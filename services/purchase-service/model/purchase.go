@@ -7,20 +7,29 @@ import (
 	cartUtils "github.com/veganbase/backend/services/cart-service/server"
 	"github.com/veganbase/backend/services/item-service/model"
 	"github.com/veganbase/backend/services/purchase-service/model/types"
+	siteModel "github.com/veganbase/backend/services/site-service/model"
 	"strings"
 	"time"
 )
 
 // Purchase is the base model for all purchases.
 type Purchase struct {
-	Id            string               `db:"id" json:"id"`
-	Status        types.PurchaseStatus `db:"status" json:"status"`
-	BuyerID       string               `db:"buyer_id" json:"buyer_id"`
-	Items         types.PurchaseItems  `db:"items" json:"items"`
-	DeliveryFees  *DeliveryFees        `db:"delivery_fees" json:"delivery_fees,omitempty"`
-	Site          *string              `db:"site" json:"site"`
-	PaymentMethod string               `json:"payment_method"`
-	CreatedAt     time.Time            `db:"created_at" json:"created_at"`
+	Id           string               `db:"id" json:"id"`
+	Status       types.PurchaseStatus `db:"status" json:"status"`
+	BuyerID      string               `db:"buyer_id" json:"buyer_id"`
+	Items        types.PurchaseItems  `db:"items" json:"items"`
+	DeliveryFees *DeliveryFees        `db:"delivery_fees" json:"delivery_fees,omitempty"`
+	Discounts    *Discounts           `db:"discounts" json:"discounts,omitempty"`
+	Taxes        *Taxes               `db:"taxes" json:"taxes,omitempty"`
+
+	// Currency that the purchase is charged in, if it's settled in a
+	// single currency, and the exchange rates current when the purchase
+	// was made.
+	Currency      string                   `db:"currency" json:"currency,omitempty"`
+	ExchangeRates *siteModel.ExchangeRates `db:"exchange_rates" json:"exchange_rates,omitempty"`
+	Site          *string                  `db:"site" json:"site"`
+	PaymentMethod string                   `json:"payment_method"`
+	CreatedAt     time.Time                `db:"created_at" json:"created_at"`
 }
 
 func (p *Purchase) Patch(body []byte) error {
@@ -33,12 +42,16 @@ func (p *Purchase) Patch(body []byte) error {
 
 	// Step 2 - verify if one of the fields that are present is read-only
 	roFields := map[string]string{
-		"id":            "id",
-		"created_at":    "created_at",
-		"items":         "items",
-		"buyer_id":      "buyer_id",
-		"site":          "site",
-		"delivery_fees": "delivery_fees",
+		"id":             "id",
+		"created_at":     "created_at",
+		"items":          "items",
+		"buyer_id":       "buyer_id",
+		"site":           "site",
+		"delivery_fees":  "delivery_fees",
+		"discounts":      "discounts",
+		"taxes":          "taxes",
+		"currency":       "currency",
+		"exchange_rates": "exchange_rates",
 	}
 	for fld, label := range roFields {
 		if _, ok := updates[fld]; ok {
@@ -69,6 +82,7 @@ type SimplePurchaseRequestFixed struct {
 	Email           string         `json:"email"`
 	PaymentMethodID string         `json:"payment_method_id"`
 	ItemID          string         `json:"item_id"`
+	Variant         string         `json:"variant,omitempty"`
 	ItemType        model.ItemType `json:"-"`
 	Quantity        int            `json:"quantity"`
 }
@@ -99,6 +113,7 @@ func (sp *SimplePurchaseRequest) UnmarshalJSON(data []byte) error {
 	stringField(&sp.Name, fields, "name")
 	stringField(&sp.Email, fields, "email")
 	stringField(&sp.ItemID, fields, "item_id")
+	stringField(&sp.Variant, fields, "variant")
 	stringField(&sp.PaymentMethodID, fields, "payment_method_id")
 	intField(&sp.Quantity, fields, "quantity")
 
@@ -0,0 +1,279 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// DeliveryTaxCategory is the tax category used for delivery fees.
+// Delivery is taxed at a country's standard rate unless a rate is
+// set up for this category.
+const DeliveryTaxCategory = "delivery"
+
+// TaxRate is the percentage rate of tax charged in a country on a
+// category of item. Each country has a standard rate, with an empty
+// category, and may have reduced rates for particular categories
+// (item types such as "packaged-food" or "dish").
+type TaxRate struct {
+	Country  string  `db:"country" json:"country"`
+	Category string  `db:"category" json:"category,omitempty"`
+	Rate     float64 `db:"rate" json:"rate"`
+}
+
+// TaxRates is a set of tax rates.
+type TaxRates []TaxRate
+
+var taxCountryRegexp = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Validate checks a set of tax rates for a country, normalising the
+// country code and setting it on each rate. The set must include a
+// standard rate.
+func (rs TaxRates) Validate(country string) error {
+	country = strings.ToUpper(country)
+	if !taxCountryRegexp.MatchString(country) {
+		return errors.New("country must be a two-letter ISO country code")
+	}
+	seen := map[string]bool{}
+	for i := range rs {
+		rs[i].Country = country
+		rs[i].Category = strings.ToLower(strings.TrimSpace(rs[i].Category))
+		if rs[i].Rate < 0 || rs[i].Rate >= 100 {
+			return errors.New("tax rates must be percentages between 0 and 100")
+		}
+		if seen[rs[i].Category] {
+			return errors.New("duplicate tax rate for category '" + rs[i].Category + "'")
+		}
+		seen[rs[i].Category] = true
+	}
+	if !seen[""] {
+		return errors.New("a standard tax rate (with no category) is required")
+	}
+	return nil
+}
+
+// Lookup finds the tax rate for a category of item in a country,
+// falling back to the country's standard rate.
+func (rs TaxRates) Lookup(country, category string) (float64, bool) {
+	standard, found := 0.0, false
+	for _, r := range rs {
+		if r.Country != country {
+			continue
+		}
+		if r.Category == category {
+			return r.Rate, true
+		}
+		if r.Category == "" {
+			standard, found = r.Rate, true
+		}
+	}
+	return standard, found
+}
+
+// Taxes is the tax charged on each order and booking of a purchase.
+type Taxes []Tax
+
+// Tax is the tax charged on an order or booking, at the rates of the
+// country where it is supplied.
+type Tax struct {
+	Seller  string `json:"seller"`
+	Country string `json:"country"`
+
+	// Whether the seller's prices include the tax. If not, the tax is
+	// charged to the buyer on top of the order total.
+	Inclusive bool      `json:"inclusive"`
+	Lines     []TaxLine `json:"lines"`
+}
+
+// TaxLine is the tax charged on the items of an order in one tax
+// category. The taxable amount is the net amount, excluding tax.
+type TaxLine struct {
+	Category string  `json:"category,omitempty"`
+	Rate     float64 `json:"rate"`
+	Taxable  int     `json:"taxable"`
+	Amount   int     `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// Totals returns the total tax charged, keyed by currency.
+func (t *Tax) Totals() map[string]int {
+	totals := map[string]int{}
+	for _, l := range t.Lines {
+		totals[l.Currency] += l.Amount
+	}
+	return totals
+}
+
+// Added returns the tax charged on top of prices, keyed by currency.
+// This is empty when prices include tax.
+func (t *Tax) Added() map[string]int {
+	if t.Inclusive {
+		return map[string]int{}
+	}
+	return t.Totals()
+}
+
+// CalculateTax works out the tax due on an order delivered to a
+// country. Items are taxed at the rate for their tax category and
+// delivery fees at the rate for delivery, and any discount on the
+// order reduces the taxable amounts in proportion. Returns nil if no
+// tax rates are set up for the country.
+func CalculateTax(rates TaxRates, country string, inclusive bool, order *Order) *Tax {
+	if _, ok := rates.Lookup(country, ""); !ok {
+		return nil
+	}
+
+	amounts := map[taxKey]int{}
+	for _, item := range order.Items {
+		category := item.TaxCategory
+		if category == "" {
+			category = item.ProductType
+		}
+		amounts[taxKey{category, item.Currency}] += item.Price * item.Quantity
+	}
+	if order.DeliveryFee != nil && order.DeliveryFee.Price > 0 {
+		amounts[taxKey{DeliveryTaxCategory, order.DeliveryFee.Currency}] += order.DeliveryFee.Price
+	}
+	keys := sortedTaxKeys(amounts)
+
+	// Share out the discounts over the amounts in their currencies. The
+	// last amount in each currency takes whatever is left after rounding
+	// down the others' shares, so the whole discount is accounted for.
+	if order.Discounts != nil {
+		discounts := map[string]int{}
+		for _, d := range *order.Discounts {
+			discounts[strings.ToUpper(d.Currency)] += d.Amount
+		}
+		for currency, discount := range discounts {
+			shared := []taxKey{}
+			gross := 0
+			for _, k := range keys {
+				if strings.EqualFold(k.currency, currency) {
+					shared = append(shared, k)
+					gross += amounts[k]
+				}
+			}
+			if gross <= 0 || discount <= 0 {
+				continue
+			}
+			if discount > gross {
+				discount = gross
+			}
+			left := discount
+			for i, k := range shared {
+				share := discount * amounts[k] / gross
+				if i == len(shared)-1 {
+					share = left
+				}
+				amounts[k] -= share
+				left -= share
+			}
+		}
+	}
+
+	return taxLines(rates, country, inclusive, order.Seller, keys, amounts)
+}
+
+// CalculateBookingTax works out the tax due on a booking at the rates
+// of a country. The whole price of the booking is taxed at the rate
+// for the tax category given. Returns nil if no tax rates are set up
+// for the country.
+func CalculateBookingTax(rates TaxRates, country string, inclusive bool,
+	booking *Booking, category string) *Tax {
+	if _, ok := rates.Lookup(country, ""); !ok {
+		return nil
+	}
+	k := taxKey{category, booking.BookingInfo.Currency}
+	amounts := map[taxKey]int{k: booking.BookingInfo.Price * booking.BookingInfo.Quantity}
+	return taxLines(rates, country, inclusive, booking.Host, []taxKey{k}, amounts)
+}
+
+// taxKey identifies the amounts taxed together on one tax line.
+type taxKey struct{ category, currency string }
+
+// sortedTaxKeys orders the tax lines of a sale by currency and
+// category.
+func sortedTaxKeys(amounts map[taxKey]int) []taxKey {
+	keys := []taxKey{}
+	for k := range amounts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].currency != keys[j].currency {
+			return keys[i].currency < keys[j].currency
+		}
+		return keys[i].category < keys[j].category
+	})
+	return keys
+}
+
+// taxLines works out the tax on the amounts of a sale in each tax
+// category and currency.
+func taxLines(rates TaxRates, country string, inclusive bool, seller string,
+	keys []taxKey, amounts map[taxKey]int) *Tax {
+	tax := Tax{Seller: seller, Country: country, Inclusive: inclusive, Lines: []TaxLine{}}
+	for _, k := range keys {
+		amount := amounts[k]
+		if amount <= 0 {
+			continue
+		}
+		rate, _ := rates.Lookup(country, k.category)
+		line := TaxLine{Category: k.category, Rate: rate, Currency: k.currency}
+		if inclusive {
+			net := int(math.Round(float64(amount) / (1 + rate/100)))
+			line.Taxable = net
+			line.Amount = amount - net
+		} else {
+			line.Taxable = amount
+			line.Amount = int(math.Round(float64(amount) * rate / 100))
+		}
+		tax.Lines = append(tax.Lines, line)
+	}
+	return &tax
+}
+
+// Make the Tax struct implement the driver.Valuer interface. This method
+// simply returns the JSON-encoded representation of the struct.
+func (t *Tax) Value() (driver.Value, error) {
+	v, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return types.JSONText(v).Value()
+}
+
+// Make the Taxes struct implement the driver.Valuer interface. This method
+// simply returns the JSON-encoded representation of the struct.
+func (ts *Taxes) Value() (driver.Value, error) {
+	v, err := json.Marshal(ts)
+	if err != nil {
+		return nil, err
+	}
+	return types.JSONText(v).Value()
+}
+
+// Make the Tax struct implement the sql.Scanner interface. This method
+// simply decodes a JSON-encoded value into the struct fields.
+func (t *Tax) Scan(src interface{}) error {
+	j := types.JSONText{}
+	if err := j.Scan(src); err != nil {
+		return err
+	}
+	return json.Unmarshal(j, t)
+}
+
+// Make the Taxes struct implement the sql.Scanner interface. This method
+// simply decodes a JSON-encoded value into the struct fields.
+func (ts *Taxes) Scan(src interface{}) error {
+	j := types.JSONText{}
+	if err := j.Scan(src); err != nil {
+		return err
+	}
+	return json.Unmarshal(j, ts)
+}
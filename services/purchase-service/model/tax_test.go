@@ -0,0 +1,190 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/veganbase/backend/services/purchase-service/model/types"
+)
+
+var testRates = TaxRates{
+	{Country: "DE", Rate: 19},
+	{Country: "DE", Category: "packaged-food", Rate: 7},
+	{Country: "FR", Rate: 20},
+}
+
+func TestTaxRatesValidate(t *testing.T) {
+	var tests = []struct {
+		name    string
+		country string
+		rates   TaxRates
+		ok      bool
+	}{
+		{"standard and reduced", "de", TaxRates{{Rate: 19}, {Category: " Packaged-Food ", Rate: 7}}, true},
+		{"zero rate", "GB", TaxRates{{Rate: 0}}, true},
+		{"bad country", "DEU", TaxRates{{Rate: 19}}, false},
+		{"negative rate", "DE", TaxRates{{Rate: 19}, {Category: "dish", Rate: -1}}, false},
+		{"rate of 100", "DE", TaxRates{{Rate: 100}}, false},
+		{"duplicate category", "DE", TaxRates{{Rate: 19}, {Category: "dish", Rate: 7}, {Category: "DISH ", Rate: 9}}, false},
+		{"no standard rate", "DE", TaxRates{{Category: "dish", Rate: 7}}, false},
+	}
+	for _, test := range tests {
+		err := test.rates.Validate(test.country)
+		if !test.ok {
+			assert.NotNil(t, err, test.name)
+			continue
+		}
+		assert.Nil(t, err, test.name)
+	}
+
+	rates := TaxRates{{Rate: 19}, {Category: " Packaged-Food ", Rate: 7}}
+	assert.Nil(t, rates.Validate("de"))
+	assert.Equal(t, TaxRates{
+		{Country: "DE", Rate: 19},
+		{Country: "DE", Category: "packaged-food", Rate: 7},
+	}, rates)
+}
+
+func TestTaxRatesLookup(t *testing.T) {
+	var tests = []struct {
+		country  string
+		category string
+		rate     float64
+		found    bool
+	}{
+		{"DE", "packaged-food", 7, true},
+		{"DE", "dish", 19, true},
+		{"DE", "", 19, true},
+		{"FR", "packaged-food", 20, true},
+		{"US", "", 0, false},
+		{"US", "dish", 0, false},
+	}
+	for _, test := range tests {
+		rate, found := testRates.Lookup(test.country, test.category)
+		assert.Equal(t, test.rate, rate, test.country+"/"+test.category)
+		assert.Equal(t, test.found, found, test.country+"/"+test.category)
+	}
+}
+
+func TestCalculateTax(t *testing.T) {
+	food := func(price, quantity int) types.PurchaseItem {
+		return types.PurchaseItem{Price: price, Quantity: quantity, Currency: "EUR",
+			ProductType: "product-offering", TaxCategory: "packaged-food"}
+	}
+	dish := func(price int, currency string) types.PurchaseItem {
+		return types.PurchaseItem{Price: price, Quantity: 1, Currency: currency, ProductType: "dish"}
+	}
+
+	var tests = []struct {
+		name      string
+		country   string
+		inclusive bool
+		order     Order
+		lines     []TaxLine
+	}{
+		{
+			"reduced rate and delivery at the standard rate", "DE", false,
+			Order{
+				Items:       types.PurchaseItems{food(1000, 2)},
+				DeliveryFee: &DeliveryFee{Price: 500, Currency: "EUR"},
+			},
+			[]TaxLine{
+				{Category: "delivery", Rate: 19, Taxable: 500, Amount: 95, Currency: "EUR"},
+				{Category: "packaged-food", Rate: 7, Taxable: 2000, Amount: 140, Currency: "EUR"},
+			},
+		},
+		{
+			"half a cent rounds up", "DE", false,
+			Order{Items: types.PurchaseItems{food(50, 1), dish(150, "EUR")}},
+			[]TaxLine{
+				{Category: "dish", Rate: 19, Taxable: 150, Amount: 29, Currency: "EUR"},
+				{Category: "packaged-food", Rate: 7, Taxable: 50, Amount: 4, Currency: "EUR"},
+			},
+		},
+		{
+			"less than half a cent rounds down", "DE", false,
+			Order{Items: types.PurchaseItems{dish(149, "EUR")}},
+			[]TaxLine{{Category: "dish", Rate: 19, Taxable: 149, Amount: 28, Currency: "EUR"}},
+		},
+		{
+			"prices including tax", "DE", true,
+			Order{Items: types.PurchaseItems{dish(1190, "EUR"), food(107, 1)}},
+			[]TaxLine{
+				{Category: "dish", Rate: 19, Taxable: 1000, Amount: 190, Currency: "EUR"},
+				{Category: "packaged-food", Rate: 7, Taxable: 100, Amount: 7, Currency: "EUR"},
+			},
+		},
+		{
+			// The 500 discount is more than the 300 dish, so it's shared
+			// out over both lines: 115 from the dish and the remaining 385
+			// from the food.
+			"discount larger than one line", "DE", false,
+			Order{
+				Items:     types.PurchaseItems{food(1000, 1), dish(300, "EUR")},
+				Discounts: &Discounts{{Amount: 500, Currency: "eur"}},
+			},
+			[]TaxLine{
+				{Category: "dish", Rate: 19, Taxable: 185, Amount: 35, Currency: "EUR"},
+				{Category: "packaged-food", Rate: 7, Taxable: 615, Amount: 43, Currency: "EUR"},
+			},
+		},
+		{
+			"discount larger than the order", "DE", false,
+			Order{
+				Items:     types.PurchaseItems{food(1000, 1), dish(300, "EUR")},
+				Discounts: &Discounts{{Amount: 2000, Currency: "EUR"}},
+			},
+			[]TaxLine{},
+		},
+		{
+			"discounts only apply in their own currency", "FR", false,
+			Order{
+				Items:     types.PurchaseItems{dish(1000, "EUR"), dish(500, "GBP")},
+				Discounts: &Discounts{{Amount: 100, Currency: "GBP"}},
+			},
+			[]TaxLine{
+				{Category: "dish", Rate: 20, Taxable: 1000, Amount: 200, Currency: "EUR"},
+				{Category: "dish", Rate: 20, Taxable: 400, Amount: 80, Currency: "GBP"},
+			},
+		},
+	}
+	for _, test := range tests {
+		test.order.Seller = "usr_SELLER"
+		tax := CalculateTax(testRates, test.country, test.inclusive, &test.order)
+		if !assert.NotNil(t, tax, test.name) {
+			continue
+		}
+		assert.Equal(t, "usr_SELLER", tax.Seller, test.name)
+		assert.Equal(t, test.country, tax.Country, test.name)
+		assert.Equal(t, test.inclusive, tax.Inclusive, test.name)
+		assert.Equal(t, test.lines, tax.Lines, test.name)
+	}
+
+	// Countries without tax rates aren't taxed.
+	order := Order{Items: types.PurchaseItems{dish(1000, "EUR")}}
+	assert.Nil(t, CalculateTax(testRates, "US", false, &order))
+}
+
+func TestCalculateBookingTax(t *testing.T) {
+	booking := Booking{
+		Host:        "usr_HOST",
+		BookingInfo: types.BookingInfo{Price: 2500, Quantity: 2, Currency: "EUR"},
+	}
+	tax := CalculateBookingTax(testRates, "DE", false, &booking, "event")
+	if assert.NotNil(t, tax) {
+		assert.Equal(t, "usr_HOST", tax.Seller)
+		assert.Equal(t, []TaxLine{
+			{Category: "event", Rate: 19, Taxable: 5000, Amount: 950, Currency: "EUR"},
+		}, tax.Lines)
+		assert.Equal(t, map[string]int{"EUR": 950}, tax.Added())
+	}
+
+	tax = CalculateBookingTax(testRates, "DE", true, &booking, "event")
+	if assert.NotNil(t, tax) {
+		assert.Equal(t, 798, tax.Lines[0].Amount)
+		assert.Empty(t, tax.Added())
+	}
+
+	assert.Nil(t, CalculateBookingTax(testRates, "US", false, &booking, "event"))
+}
@@ -19,11 +19,21 @@ func (p PaymentStatus) String() string {
 		return "failed"
 	case Completed:
 		return "completed"
+	case Refunded:
+		return "refunded"
+	case PartiallyRefunded:
+		return "partially_refunded"
 	default:
 		return "<unknown status>"
 	}
 }
 
+// Paid says whether a payment has been made, including payments that
+// have since been refunded.
+func (p PaymentStatus) Paid() bool {
+	return p == Completed || p == Refunded || p == PartiallyRefunded
+}
+
 // FromString does checked conversion from a string to a PurchaseStatus.
 func (p *PaymentStatus) FromString(s string) error {
 	switch s {
@@ -33,6 +43,10 @@ func (p *PaymentStatus) FromString(s string) error {
 		*p = Failed
 	case "completed":
 		*p = Completed
+	case "refunded":
+		*p = Refunded
+	case "partially_refunded":
+		*p = PartiallyRefunded
 	default:
 		return errors.New("unknown payment status '" + s + "'")
 	}
@@ -16,11 +16,13 @@ type FullPurchaseItems []FullPurchaseItem
 // PurchaseItem represents one entry on the array of items stored on column items in table purchases and orders
 type PurchaseItem struct {
 	ItemId           string  `json:"item_id"`
+	Variant          string  `json:"variant,omitempty"`
 	ProductType      string  `json:"product_type,omitempty"`
 	ItemOwner        string  `json:"item_owner,omitempty"`
 	Quantity         int     `json:"quantity"`
 	Price            int     `json:"price"`
 	Currency         string  `json:"currency"`
+	TaxCategory      string  `json:"tax_category,omitempty"`
 	UniqueIdentifier string  `json:"unique_identifier"`
 	OtherInfo        InfoMap `json:"other_info,omitempty"`
 }
@@ -49,6 +51,7 @@ func (pi *PurchaseItems) Scan(value interface{}) error {
 // PurchaseItem represents one entry on the array of items stored on column items in table purchases and orders
 type FullPurchaseItem struct {
 	Item        itemModel.Info `json:"item"`
+	Variant     string         `json:"variant,omitempty"`
 	ProductType string         `json:"product_type,omitempty"`
 	ItemOwner   string         `json:"item_owner,omitempty"`
 	Quantity    int            `json:"quantity"`
@@ -60,6 +63,7 @@ type FullPurchaseItem struct {
 func GetFullPurchaseItem(rawPurItem *PurchaseItem, itemInfo *itemModel.Info) *FullPurchaseItem {
 	view := FullPurchaseItem{}
 	view.Item = *itemInfo
+	view.Variant = rawPurItem.Variant
 	view.ProductType = rawPurItem.ProductType
 	view.Quantity = rawPurItem.Quantity
 	view.Price = rawPurItem.Price
@@ -17,7 +17,11 @@ const (
 	Failed
 	// Completed means that the purchase happened without any issue.
 	Completed
-
+	// Refunded means that the whole amount paid was given back to the buyer.
+	Refunded
+	// PartiallyRefunded means that only part of the amount paid was given
+	// back to the buyer.
+	PartiallyRefunded
 )
 
 // String converts a purchase status to its string representation.
@@ -29,11 +33,21 @@ func (p PurchaseStatus) String() string {
 		return "failed"
 	case Completed:
 		return "completed"
+	case Refunded:
+		return "refunded"
+	case PartiallyRefunded:
+		return "partially_refunded"
 	default:
 		return "<unknown status>"
 	}
 }
 
+// Paid says whether a purchase has been paid for, including purchases
+// that have since been refunded.
+func (p PurchaseStatus) Paid() bool {
+	return p == Completed || p == Refunded || p == PartiallyRefunded
+}
+
 // FromString does checked conversion from a string to a PurchaseStatus.
 func (p *PurchaseStatus) FromString(s string) error {
 	switch s {
@@ -43,6 +57,10 @@ func (p *PurchaseStatus) FromString(s string) error {
 		*p = Failed
 	case "completed":
 		*p = Completed
+	case "refunded":
+		*p = Refunded
+	case "partially_refunded":
+		*p = PartiallyRefunded
 	default:
 		return errors.New("unknown purchase status '" + s + "'")
 	}
@@ -4,6 +4,7 @@ import (
 	itemModel "github.com/veganbase/backend/services/item-service/model"
 	"github.com/veganbase/backend/services/payment-service/model"
 	"github.com/veganbase/backend/services/purchase-service/model/types"
+	siteModel "github.com/veganbase/backend/services/site-service/model"
 	userModel "github.com/veganbase/backend/services/user-service/model"
 	"time"
 )
@@ -32,6 +33,9 @@ type FullOrder struct {
 	PaymentStatus types.PaymentStatus     `json:"payment_status"`
 	Items         types.FullPurchaseItems `json:"items"`
 	DeliveryFee   *DeliveryFee            `json:"delivery_fee,omitempty"`
+	Discounts     *Discounts              `json:"discounts,omitempty"`
+	ExchangeRate  *siteModel.ExchangeRate `json:"exchange_rate,omitempty"`
+	Tax           *Tax                    `json:"tax,omitempty"`
 	OrderInfo     types.InfoMap           `json:"order_info,omitempty"`
 	CreatedAt     time.Time               `json:"created_at"`
 }
@@ -44,6 +48,7 @@ type FullBooking struct {
 	Item          *itemModel.Info     `json:"item"`
 	PaymentStatus types.PaymentStatus `json:"payment_status"`
 	BookingInfo   types.BookingInfo   `json:"booking_info"`
+	Tax           *Tax                `json:"tax,omitempty"`
 	CreatedAt     time.Time           `json:"created_at"`
 }
 
@@ -58,6 +63,11 @@ func GetFullOrder(rawOrder *Order, buyer, seller *userModel.Info, items types.Fu
 	if *rawOrder.DeliveryFee != (DeliveryFee{}) {
 		view.DeliveryFee = rawOrder.DeliveryFee
 	}
+	if rawOrder.Discounts != nil && len(*rawOrder.Discounts) > 0 {
+		view.Discounts = rawOrder.Discounts
+	}
+	view.ExchangeRate = rawOrder.ExchangeRate
+	view.Tax = rawOrder.Tax
 	view.OrderInfo = rawOrder.OrderInfo
 	view.CreatedAt = rawOrder.CreatedAt
 	return &view
@@ -72,6 +82,7 @@ func GetFullBooking(rawBooking *Booking, buyer, host *userModel.Info, item *item
 	view.PaymentStatus = rawBooking.PaymentStatus
 	view.Item = item
 	view.BookingInfo = rawBooking.BookingInfo
+	view.Tax = rawBooking.Tax
 	view.CreatedAt = rawBooking.CreatedAt
 	return &view
 }
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/veganbase/backend/services/purchase-service/model"
+)
+
+// errNoExchangeRates is the error returned when a purchase has to be
+// settled in a single currency but the exchange rates aren't known
+// yet.
+var errNoExchangeRates = errors.New("exchange rates are not available")
+
+// snapshotRates records the exchange rates current when a purchase is
+// made. If purchases are settled in a single currency, the purchase is
+// charged in that currency, and the rate used to convert each order's
+// prices is recorded on the order so that seller payouts can be
+// audited.
+func (s *Server) snapshotRates(purchase *model.Purchase, orders []model.Order) error {
+	rates := s.siteSvc.ExchangeRates()
+	if rates == nil {
+		if s.settlementCurrency != "" {
+			return errNoExchangeRates
+		}
+		return nil
+	}
+	snapshot := *rates
+	purchase.ExchangeRates = &snapshot
+	if s.settlementCurrency == "" {
+		return nil
+	}
+
+	// Check that everything in the purchase can be converted.
+	currencies := []string{}
+	for _, item := range purchase.Items {
+		currencies = append(currencies, item.Currency)
+	}
+	if purchase.DeliveryFees != nil {
+		for _, fee := range *purchase.DeliveryFees {
+			currencies = append(currencies, fee.Currency)
+		}
+	}
+	for _, currency := range currencies {
+		if _, err := rates.Rate(currency, s.settlementCurrency); err != nil {
+			return errors.New("no exchange rate for " + currency)
+		}
+	}
+
+	purchase.Currency = s.settlementCurrency
+	for i := range orders {
+		if len(orders[i].Items) == 0 {
+			continue
+		}
+		orders[i].ExchangeRate, _ = rates.Rate(orders[i].Items[0].Currency, s.settlementCurrency)
+	}
+	return nil
+}
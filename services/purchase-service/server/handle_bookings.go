@@ -144,6 +144,21 @@ func (s *Server) bookingSearch(w http.ResponseWriter, r *http.Request) (interfac
 	return *model.GetFullBooking(booking, userInfo[booking.BuyerID], userInfo[booking.Host], itemInfo[booking.ItemID]), err
 }
 
+// bookingSearchInternal is the same as the public one, except for the
+// auth and ownership validations and the expansion of user and item
+// information.
+func (s *Server) bookingSearchInternal(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	bokId := chi.URLParam(r, "bok_id")
+	booking, err := s.db.BookingById(bokId)
+	if err != nil {
+		if err == db.ErrBookingNotFound {
+			return chassis.NotFoundWithMessage(w, "booking not found")
+		}
+		return nil, err
+	}
+	return booking, nil
+}
+
 // patchBookingInternal performs a patch operation in a booking item.
 // the existence of the booking is evaluated, so as its ownership
 func (s *Server) patchBooking(w http.ResponseWriter, r *http.Request) (interface{}, error) {
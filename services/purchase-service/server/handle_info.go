@@ -7,7 +7,7 @@ import (
 	"net/url"
 )
 
-//userPurchaseItem verifies if the user already purchase a certain item.
+//userPurchaseItem verifies if the user has bought and paid for a certain item.
 func (s *Server) userPurchaseItem(w http.ResponseWriter, r *http.Request) (interface{}, error) {
 	// Get purchase ID from URL parameters
 
@@ -31,7 +31,11 @@ func (s *Server) userPurchaseItem(w http.ResponseWriter, r *http.Request) (inter
 		return nil, err
 	}
 
+	// Purchases that haven't been paid for don't count.
 	for _, purchase := range *purchases {
+		if !purchase.Status.Paid() {
+			continue
+		}
 		for _, item := range purchase.Items {
 			if item.ItemId == itemId {
 				return true, nil
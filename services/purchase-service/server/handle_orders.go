@@ -178,6 +178,21 @@ func (s *Server) orderSearch(w http.ResponseWriter, r *http.Request) (interface{
 
 }
 
+// orderSearchInternal is the same as the public one, except for the
+// auth and ownership validations and the expansion of user and item
+// information.
+func (s *Server) orderSearchInternal(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	ordId := chi.URLParam(r, "ord_id")
+	order, err := s.db.OrderById(ordId)
+	if err != nil {
+		if err == db.ErrOrderNotFound {
+			return chassis.NotFoundWithMessage(w, "order not found")
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
 // patchOrder performs a patch operation in an order
 // the existence of the order is evaluated, so as its ownership and the link with purchase.
 func (s *Server) patchOrder(w http.ResponseWriter, r *http.Request) (interface{}, error) {
@@ -118,7 +118,12 @@ func (s *Server) createPurchase(w http.ResponseWriter, r *http.Request) (interfa
 		return chassis.NotFound(w)
 	}
 
-	cart, err := s.cartSvc.Active(authInfo.UserID)
+	site := defaultSite
+	if origins, ok := r.Header["Origin"]; ok && len(origins) > 0 {
+		site = origins[0]
+	}
+
+	cart, err := s.cartSvc.Active(authInfo.UserID, site)
 	if err != nil {
 		if err == db2.ErrCartNotFound {
 			return chassis.NotFoundWithMessage(w, "cart not found")
@@ -140,6 +145,9 @@ func (s *Server) createPurchase(w http.ResponseWriter, r *http.Request) (interfa
 		}
 		return chassis.BadRequest(w, "cannot purchase cart with item errors: \n"+ strings.Join(errors, "\n"))
 	}
+	if cart.PromoCodeError != "" {
+		return chassis.BadRequest(w, "cannot use promotion code "+cart.PromoCode+": "+cart.PromoCodeError)
+	}
 
 	// Read request body.
 	body, err := chassis.ReadBody(r, 0)
@@ -207,21 +215,54 @@ func (s *Server) createPurchase(w http.ResponseWriter, r *http.Request) (interfa
 			itemsByOwner[itemInfo.Owner.ID] = append(itemsByOwner[itemInfo.Owner.ID], purInfo)
 		}
 	}
+	//sales are blocked for sellers who can't be paid at the moment
+	if seller := s.blockedSeller(purchaseItems); seller != "" {
+		return chassis.BadRequest(w, "seller "+seller+" cannot accept payments at the moment")
+	}
+
 	//creating purchase
 	purchase := model.Purchase{}
 	purchase.BuyerID = cart.Owner
 	err = purchase.Status.FromString("pending")
 	purchase.Items = purchaseItems
 	purchase.DeliveryFees = &deliveries
-	purchase.Site = &defaultSite
-	if origins, ok := r.Header["Origin"]; ok && len(origins) > 0 {
-		purchase.Site = &origins[0]
-	}
+	purchase.Site = &site
 
 	//creating orders and bookings
 	orders, bookings := fillOrdersAndBookings(purchase.BuyerID, itemsByOwner, deliveriesBySeller, purchaseItems, address)
+	discounts := applyDiscounts(cart.Discounts, orders)
+	purchase.Discounts = &discounts
+	if err = s.addTaxes(&purchase, orders, bookings, address); err != nil {
+		return nil, err
+	}
+	if err = s.snapshotRates(&purchase, orders); err != nil {
+		if err == errNoExchangeRates {
+			return nil, err
+		}
+		return chassis.BadRequest(w, "cannot purchase cart: "+err.Error())
+	}
+
+	//reserving stock
+	purchase.Id = chassis.NewPurchaseID()
+	if err = s.reserveStock(&purchase); err != nil {
+		if msg, ok := stockConflict(err); ok {
+			return chassis.Conflict(w, "cannot purchase cart: "+msg)
+		}
+		return nil, err
+	}
+
+	//recording promotion code use
+	if err = s.redeemPromotions(&purchase); err != nil {
+		s.releaseStock(purchase.Id)
+		if msg, ok := promotionConflict(err); ok {
+			return chassis.Conflict(w, "cannot use promotion code "+cart.PromoCode+": "+msg)
+		}
+		return nil, err
+	}
 
 	if err = s.db.CreatePurchase(&purchase, &orders, &bookings); err != nil {
+		s.releaseStock(purchase.Id)
+		s.releasePromotions(purchase.Id)
 		return nil, err
 	}
 
@@ -234,13 +275,6 @@ func (s *Server) createPurchase(w http.ResponseWriter, r *http.Request) (interfa
 		chassis.Emit(s, events.BookingCreated, b)
 	}
 
-	//updating stock availability
-	for _, cartItem := range cart.Items {
-		if err = s.itemSvc.UpdateItemAvailability(cartItem.ItemID, -cartItem.Quantity); err != nil {
-			//TODO: do nothing or log
-		}
-	}
-
 	//finishing cart
 	s.cartSvc.FinishCart(cart.ID)
 
@@ -323,18 +357,24 @@ func (s *Server) createSimplePurchase(w http.ResponseWriter, r *http.Request) (i
 	}
 
 	//checking if quantity is available in stock
-	if err = cartUtils.IsAvailableForSale(req.Quantity, *itemInfo); err != nil {
+	stock, err := s.itemSvc.StockLevels([]string{itemInfo.ID})
+	if err != nil {
+		return nil, err
+	}
+	if err = cartUtils.IsAvailableForSale(req.Quantity, req.Variant, *itemInfo, stock[itemInfo.ID]); err != nil {
 		return chassis.BadRequest(w, "error while processing item '"+itemInfo.Name+" :" + err.Error())
 	}
 
 	//creating purchase item entry and adding to the collection
 	purInfo := types.PurchaseItem{
 		ItemId:      itemInfo.ID,
+		Variant:     req.Variant,
 		ProductType: itemInfo.ItemType.String(),
 		ItemOwner:   itemInfo.Owner.ID,
 		Quantity:    req.Quantity,
 		Price:       int(itemInfo.Attrs["price"].(float64)),
 		Currency:    itemInfo.Attrs["currency"].(string),
+		TaxCategory: taxCategory(*itemInfo),
 		OtherInfo:   make(map[string]interface{}),
 	}
 	if req.OtherInfo != nil {
@@ -343,6 +383,10 @@ func (s *Server) createSimplePurchase(w http.ResponseWriter, r *http.Request) (i
 		}
 	}
 
+	if seller := s.blockedSeller([]types.PurchaseItem{purInfo}); seller != "" {
+		return chassis.BadRequest(w, "seller "+seller+" cannot accept payments at the moment")
+	}
+
 	//creating purchase
 	purchase := model.Purchase{}
 	purchase.Items = types.PurchaseItems{purInfo}
@@ -356,7 +400,27 @@ func (s *Server) createSimplePurchase(w http.ResponseWriter, r *http.Request) (i
 	}
 
 	orders, bookings := fillOrdersAndBookings(rsp.ID, map[string][]types.PurchaseItem{},nil, purchase.Items, nil )
+	if err = s.addTaxes(&purchase, orders, bookings, nil); err != nil {
+		return nil, err
+	}
+	if err = s.snapshotRates(&purchase, orders); err != nil {
+		if err == errNoExchangeRates {
+			return nil, err
+		}
+		return chassis.BadRequest(w, "cannot purchase item: "+err.Error())
+	}
+
+	//reserving stock
+	purchase.Id = chassis.NewPurchaseID()
+	if err = s.reserveStock(&purchase); err != nil {
+		if msg, ok := stockConflict(err); ok {
+			return chassis.Conflict(w, "cannot purchase item: "+msg)
+		}
+		return nil, err
+	}
+
 	if err = s.db.CreatePurchase(&purchase, &orders, &bookings); err != nil {
+		s.releaseStock(purchase.Id)
 		return nil, err
 	}
 
@@ -369,11 +433,6 @@ func (s *Server) createSimplePurchase(w http.ResponseWriter, r *http.Request) (i
 		chassis.Emit(s, events.BookingCreated, b)
 	}
 
-	//updating stock availability
-	if err = s.itemSvc.UpdateItemAvailability(req.ItemID, - req.Quantity); err != nil {
-		//TODO: do nothing or log
-	}
-
 	//trigger payments
 	intents, _ := s.paymentSvc.CreatePaymentIntent(purchase)
 	// TODO: handle payment errors. not sure if we must break the flow here or just log somewhere to enable
@@ -467,7 +526,7 @@ func (s *Server) patchPurchaseInternal(w http.ResponseWriter, r *http.Request) (
 		return nil, err
 	}
 
-
+	wasFailed := purchase.Status == types.Failed
 	if err = purchase.Patch(body); err != nil {
 		return chassis.BadRequest(w, err.Error())
 	}
@@ -477,6 +536,12 @@ func (s *Server) patchPurchaseInternal(w http.ResponseWriter, r *http.Request) (
 		return nil, err
 	}
 
+	//promotion codes used for purchases that weren't paid for can be used again
+	if purchase.Status == types.Failed && !wasFailed &&
+		purchase.Discounts != nil && len(*purchase.Discounts) > 0 {
+		s.releasePromotions(purchase.Id)
+	}
+
 	chassis.Emit(s, events.PurchaseUpdated, purchase)
 
 	return purchase, nil
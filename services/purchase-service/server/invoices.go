@@ -0,0 +1,271 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog/log"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/chassis/storage"
+	"github.com/veganbase/backend/services/purchase-service/db"
+	"github.com/veganbase/backend/services/purchase-service/invoice"
+	"github.com/veganbase/backend/services/purchase-service/model"
+)
+
+// orderInvoice downloads the invoice for an order as a PDF document.
+// Invoices are available to the buyer and the seller once the order
+// has been paid for, including after it has been refunded.
+func (s *Server) orderInvoice(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	order, err := s.db.OrderById(chi.URLParam(r, "ord_id"))
+	if err == db.ErrOrderNotFound {
+		return chassis.NotFoundWithMessage(w, "order not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !authInfo.UserIsAdmin {
+		canView, err := s.canViewOrder(order, authInfo.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !canView {
+			return chassis.NotFound(w)
+		}
+	}
+	if !order.PaymentStatus.Paid() {
+		return chassis.NotFoundWithMessage(w, "order has not been paid for")
+	}
+
+	inv, err := s.invoiceForOrder(order)
+	if err != nil {
+		return nil, err
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="`+inv.Filename()+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(inv.Content)))
+	w.Write(inv.Content)
+	return nil, nil
+}
+
+// orderInvoiceInternal returns the invoice for an order, including
+// the PDF document, issuing the invoice if needed. This is used to
+// attach invoices to the emails sent when orders are paid for.
+func (s *Server) orderInvoiceInternal(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	order, err := s.db.OrderById(chi.URLParam(r, "ord_id"))
+	if err == db.ErrOrderNotFound {
+		return chassis.NotFoundWithMessage(w, "order not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.invoiceForOrder(order)
+}
+
+// invoiceForOrder returns the invoice for an order along with its
+// document, issuing the invoice with the next number in the seller's
+// sequence if the order hasn't been invoiced yet.
+func (s *Server) invoiceForOrder(order *model.Order) (*model.Invoice, error) {
+	inv, err := s.db.InvoiceByOrder(order.Id)
+	if err == nil {
+		inv.Content, err = s.invoiceStore.Read(invoiceStorageID(order.Id), "pdf")
+		if err != storage.ErrNotFound {
+			return inv, err
+		}
+
+		// The document has gone missing from storage, so render it
+		// again with the details it was issued with.
+		log.Warn().Str("order", order.Id).Msg("re-rendering missing invoice document")
+		if err = s.renderInvoice(order, inv); err != nil {
+			return nil, err
+		}
+		return inv, nil
+	}
+	if err != db.ErrInvoiceNotFound {
+		return nil, err
+	}
+
+	inv = &model.Invoice{OrderID: order.Id, Seller: order.Seller}
+	err = s.db.CreateInvoice(inv, func(inv *model.Invoice) error {
+		return s.renderInvoice(order, inv)
+	})
+	if err == db.ErrInvoiceExists {
+		// Issued by a concurrent request.
+		return s.invoiceForOrder(order)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// renderInvoice produces the invoice document for an order and writes
+// it to invoice storage, setting the invoice's content and URL.
+func (s *Server) renderInvoice(order *model.Order, inv *model.Invoice) error {
+	doc, err := s.invoiceDocument(order)
+	if err != nil {
+		return err
+	}
+	doc.Number = inv.Number
+	doc.IssuedAt = inv.IssuedAt
+
+	inv.Content = invoice.Render(doc)
+	inv.URL, _, err = s.invoiceStore.Write(invoiceStorageID(order.Id), "pdf", inv.Content)
+	return err
+}
+
+// invoiceDocument collects the seller, buyer and item details needed
+// for the invoice of an order.
+func (s *Server) invoiceDocument(order *model.Order) (*invoice.Invoice, error) {
+	seller, err := s.userSvc.GetSellerDetails(order.Seller)
+	if err != nil {
+		return nil, fmt.Errorf("getting seller details: %v", err)
+	}
+	itemIDs := []string{}
+	for _, it := range order.Items {
+		itemIDs = append(itemIDs, it.ItemId)
+	}
+	itemsInfo, err := s.itemSvc.GetItemsInfo(itemIDs)
+	if err != nil {
+		return nil, fmt.Errorf("getting item information: %v", err)
+	}
+
+	doc := &invoice.Invoice{
+		OrderID: order.Id,
+		Seller: invoice.Party{
+			Name:  seller.Name,
+			Email: seller.Email,
+		},
+		Buyer: s.invoiceBuyer(order),
+	}
+	if seller.Address != nil {
+		doc.Seller.Address = addressLines(seller.Address.StreetAddress,
+			seller.Address.Postcode, seller.Address.City, seller.Address.Country)
+	}
+	if seller.VATNumber != nil {
+		doc.Seller.VATNumber = *seller.VATNumber
+	}
+
+	// Items priced in different currencies are invoiced separately, each
+	// with their own delivery, discounts and tax.
+	sections := map[string]*invoice.Section{}
+	section := func(currency string) *invoice.Section {
+		currency = strings.ToUpper(currency)
+		if sec, ok := sections[currency]; ok {
+			return sec
+		}
+		sec := &invoice.Section{Currency: currency}
+		sections[currency] = sec
+		return sec
+	}
+	for _, it := range order.Items {
+		desc := it.ItemId
+		if info, ok := itemsInfo[it.ItemId]; ok && info.Name != nil {
+			desc = *info.Name
+		}
+		if it.Variant != "" {
+			desc += " (" + it.Variant + ")"
+		}
+		sec := section(it.Currency)
+		sec.Lines = append(sec.Lines, invoice.Line{
+			Description: desc,
+			Quantity:    it.Quantity,
+			UnitPrice:   it.Price,
+		})
+	}
+	if order.DeliveryFee != nil && order.DeliveryFee.Price != 0 {
+		// Older delivery fees have no currency of their own: they were
+		// charged in the currency of the items.
+		currency := order.DeliveryFee.Currency
+		if currency == "" && len(order.Items) > 0 {
+			currency = order.Items[0].Currency
+		}
+		section(currency).Delivery += order.DeliveryFee.Price
+	}
+	if order.Discounts != nil {
+		for _, d := range *order.Discounts {
+			section(d.Currency).Discount += d.Amount
+		}
+	}
+	if order.Tax != nil {
+		doc.TaxInclusive = order.Tax.Inclusive
+		for _, l := range order.Tax.Lines {
+			label := "VAT " + strconv.FormatFloat(l.Rate, 'f', -1, 64) + "%"
+			if l.Category != "" {
+				label += " (" + l.Category + ")"
+			}
+			sec := section(l.Currency)
+			sec.Taxes = append(sec.Taxes, invoice.TaxLine{
+				Label:   label,
+				Taxable: l.Taxable,
+				Amount:  l.Amount,
+			})
+		}
+	}
+
+	currencies := []string{}
+	for currency := range sections {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		doc.Sections = append(doc.Sections, *sections[currency])
+	}
+	return doc, nil
+}
+
+// invoiceBuyer sets out the buyer's details for an invoice, from the
+// delivery address recorded on the order, falling back to the buyer's
+// user name.
+func (s *Server) invoiceBuyer(order *model.Order) invoice.Party {
+	buyer := invoice.Party{}
+	if recipient, ok := order.OrderInfo["recipient"].(map[string]interface{}); ok {
+		name := strings.TrimSpace(infoString(recipient, "firstname") + " " + infoString(recipient, "lastname"))
+		buyer.Name = name
+		if company := infoString(recipient, "company"); company != "" {
+			buyer.Address = append(buyer.Address, company)
+		}
+		buyer.Email = infoString(recipient, "contact_email")
+	}
+	if addr, ok := order.OrderInfo["address"].(map[string]interface{}); ok {
+		street := infoString(addr, "street_address")
+		if number := infoString(addr, "house_number"); number != "" {
+			street = number + " " + street
+		}
+		buyer.Address = append(buyer.Address, addressLines(street,
+			infoString(addr, "postcode"), infoString(addr, "city"), infoString(addr, "country"))...)
+	}
+	if buyer.Name == "" {
+		info, err := s.userSvc.Info([]string{order.BuyerID})
+		if err == nil && info[order.BuyerID] != nil && info[order.BuyerID].Name != nil {
+			buyer.Name = *info[order.BuyerID].Name
+		}
+	}
+	return buyer
+}
+
+// addressLines lays out an address for an invoice.
+func addressLines(street, postcode, city, country string) []string {
+	return []string{street, strings.TrimSpace(postcode + " " + city), country}
+}
+
+func infoString(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// invoiceStorageID is the ID under which an order's invoice document
+// is held in invoice storage.
+func invoiceStorageID(orderID string) string {
+	return "invoice-" + orderID
+}
@@ -168,6 +168,7 @@ func buildOrderCreatedNotificationMsg(o model.Order, itemsInfo map[string]*item.
 		data["formatted_delivery_cost"] = "FREE"
 	}
 
+	total += addTaxFields(data, o.Tax, currency)
 	data["total"] = chassis.FormatCurrencyValue(currency, total)
 
 	msg := chassis.GenericEmailMsg{
@@ -182,6 +183,30 @@ func buildOrderCreatedNotificationMsg(o model.Order, itemsInfo map[string]*item.
 	return &msg
 }
 
+// addTaxFields adds the tax charged on an order to the data for an
+// order email, returning the amount of tax to add to the order total
+// (zero if prices include tax).
+func addTaxFields(data chassis.GenericMap, tax *model.Tax, currency string) int {
+	if tax == nil || len(tax.Lines) == 0 {
+		data["has_tax"] = "false"
+		return 0
+	}
+
+	lines := []chassis.GenericMap{}
+	for _, l := range tax.Lines {
+		lines = append(lines, chassis.GenericMap{
+			"category":         l.Category,
+			"rate":             strconv.FormatFloat(l.Rate, 'f', -1, 64),
+			"formatted_amount": chassis.FormatCurrencyValue(l.Currency, l.Amount),
+		})
+	}
+	data["has_tax"] = "true"
+	data["tax_lines"] = lines
+	data["tax_inclusive"] = strconv.FormatBool(tax.Inclusive)
+	data["formatted_tax"] = chassis.FormatCurrencyValue(currency, tax.Totals()[currency])
+	return tax.Added()[currency]
+}
+
 func buildPurchaseCreatedNotificationMsg(p *model.FullPurchase, itemsInfo map[string]*item.Info, info *usr.Info) *chassis.GenericEmailMsg {
 
 	data := chassis.GenericMap{}
@@ -226,6 +251,7 @@ func buildPurchaseCreatedNotificationMsg(p *model.FullPurchase, itemsInfo map[st
 		} else {
 			order["formatted_delivery_cost"] = "FREE"
 		}
+		total += addTaxFields(order, o.Tax, currency)
 		orders = append(orders, order)
 	}
 	data["orders"] = orders
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"github.com/veganbase/backend/services/purchase-service/model/types"
+)
+
+// blockedSeller checks that the sellers of the items in a purchase can
+// be paid for them, returning the ID of the first seller whose payout
+// account can't currently receive transfers, or "" if there is none.
+// Sellers without a payout account aren't blocked: transfers to them
+// are held by the payment service until they connect one.
+func (s *Server) blockedSeller(items []types.PurchaseItem) string {
+	checked := map[string]bool{}
+	for _, it := range items {
+		if it.ItemOwner == "" || checked[it.ItemOwner] {
+			continue
+		}
+		checked[it.ItemOwner] = true
+
+		acc, err := s.userSvc.GetPayoutAccount(it.ItemOwner)
+		if err != nil {
+			log.Info().Err(err).Str("seller", it.ItemOwner).Msg("no payout account found for seller")
+			continue
+		}
+		if !acc.TransfersEnabled {
+			return it.ItemOwner
+		}
+	}
+	return ""
+}
@@ -225,8 +225,17 @@ func (s *Server) createSubscriptionPurchase(sub model.SubscriptionPurchase) erro
 
 	//creating orders and bookings
 	orders, bookings := fillOrdersAndBookings(purchase.BuyerID, itemsByOwner, deliveriesBySeller, purchaseItems, addr)
+	if err = s.addTaxes(&purchase, orders, bookings, addr); err != nil {
+		return err
+	}
+
+	purchase.Id = chassis.NewPurchaseID()
+	if err = s.reserveStock(&purchase); err != nil {
+		return err
+	}
 
 	if err = s.db.CreatePurchase(&purchase, &orders, &bookings); err != nil {
+		s.releaseStock(purchase.Id)
 		return err
 	}
 
@@ -239,13 +248,6 @@ func (s *Server) createSubscriptionPurchase(sub model.SubscriptionPurchase) erro
 		chassis.Emit(s, events.BookingCreated, b)
 	}
 
-	// STEP 10 - updating stock availability
-	for _, item := range *subItems {
-		if err = s.itemSvc.UpdateItemAvailability(item.ItemID, -item.Quantity); err != nil {
-			//TODO: do nothing or log
-		}
-	}
-
 	//STEP 11 - updating subscription purchase status
 	now := time.Now()
 	sub.PurchaseID = &purchase.Id
@@ -353,6 +355,10 @@ func (s *Server) CheckInvalidItems(addr *usr.Address, items []model.Subscription
 	if err != nil {
 		return nil, err
 	}
+	stock, err := s.itemSvc.StockLevels(ids)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, info := range *itemInfo {
 		//check if product can be delivered for the user default address
@@ -381,7 +387,8 @@ func (s *Server) CheckInvalidItems(addr *usr.Address, items []model.Subscription
 
 			}
 		}
-		if err = cartUtils.IsAvailableForSale(subMap[info.ID].Quantity, info); err != nil {
+		variant, _ := subMap[info.ID].OtherInfo["variant"].(string)
+		if err = cartUtils.IsAvailableForSale(subMap[info.ID].Quantity, variant, info, stock[info.ID]); err != nil {
 			cartItemID := subMap[info.ID].ID
 			subErrors[cartItemID] = append(subErrors[cartItemID],
 				fmt.Sprintf("item '%s' is not available for sale: %v", info.ID, err))
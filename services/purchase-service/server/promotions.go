@@ -0,0 +1,102 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	cart "github.com/veganbase/backend/services/cart-service/client"
+	cartModel "github.com/veganbase/backend/services/cart-service/model"
+	"github.com/veganbase/backend/services/purchase-service/model"
+)
+
+// applyDiscounts copies the discounts given by a cart's promotion code
+// to the orders of the sellers they apply to, returning the discounts
+// for the whole purchase. A seller whose items are priced in more than
+// one currency gets a discount in each currency, so orders keep all of
+// their seller's discounts.
+func applyDiscounts(discounts []cartModel.Discount, orders []model.Order) model.Discounts {
+	type key struct{ seller, currency string }
+	all := model.Discounts{}
+	byKey := map[key]model.Discounts{}
+	keys := []key{}
+	for _, d := range discounts {
+		discount := model.Discount{
+			PromotionID: d.PromotionID,
+			Code:        d.Code,
+			Seller:      d.Seller,
+			Amount:      d.Amount,
+			Currency:    d.Currency,
+			FundedBy:    d.FundedBy,
+		}
+		all = append(all, discount)
+		k := key{d.Seller, strings.ToUpper(d.Currency)}
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], discount)
+	}
+	for i := range orders {
+		ds := model.Discounts{}
+		for _, k := range keys {
+			if k.seller == orders[i].Seller {
+				ds = append(ds, byKey[k]...)
+			}
+		}
+		if len(ds) > 0 {
+			orders[i].Discounts = &ds
+		}
+	}
+	return all
+}
+
+// redeemPromotions records the use of the promotion codes that give a
+// purchase's discounts, checking their usage limits. The purchase ID
+// must already be set. Returns a *cart.PromotionError if a promotion
+// can't be used any more.
+func (s *Server) redeemPromotions(purchase *model.Purchase) error {
+	if purchase.Discounts == nil {
+		return nil
+	}
+	redemptions := map[string]*cartModel.Redemption{}
+	order := []string{}
+	for _, d := range *purchase.Discounts {
+		r, ok := redemptions[d.PromotionID]
+		if !ok {
+			r = &cartModel.Redemption{
+				PromotionID: d.PromotionID,
+				Reference:   purchase.Id,
+				UserID:      purchase.BuyerID,
+				Currency:    d.Currency,
+			}
+			redemptions[d.PromotionID] = r
+			order = append(order, d.PromotionID)
+		}
+		if d.Currency == r.Currency {
+			r.Amount += d.Amount
+		}
+	}
+	for _, id := range order {
+		if err := s.cartSvc.RedeemPromotion(redemptions[id]); err != nil {
+			s.releasePromotions(purchase.Id)
+			return err
+		}
+	}
+	return nil
+}
+
+// releasePromotions removes the promotion uses recorded for a purchase
+// that couldn't be created or wasn't paid for.
+func (s *Server) releasePromotions(purchaseID string) {
+	if err := s.cartSvc.ReleasePromotions(purchaseID); err != nil {
+		log.Error().Err(err).Str("purchase", purchaseID).Msg("releasing promotion uses")
+	}
+}
+
+// promotionConflict says whether an error means that a promotion code
+// can't be used any more.
+func promotionConflict(err error) (string, bool) {
+	if promoErr, ok := err.(*cart.PromotionError); ok {
+		return promoErr.Message, true
+	}
+	return "", false
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	cartModel "github.com/veganbase/backend/services/cart-service/model"
+	"github.com/veganbase/backend/services/purchase-service/model"
+)
+
+func TestApplyDiscounts(t *testing.T) {
+	// The first seller's items are priced in two currencies, so they get
+	// a discount in each.
+	discounts := []cartModel.Discount{
+		{PromotionID: "prm_1", Code: "SPRING", Seller: "usr_A", Amount: 400, Currency: "EUR", FundedBy: model.FundedBySeller},
+		{PromotionID: "prm_1", Code: "SPRING", Seller: "usr_A", Amount: 300, Currency: "GBP", FundedBy: model.FundedBySeller},
+		{PromotionID: "prm_1", Code: "SPRING", Seller: "usr_B", Amount: 200, Currency: "EUR", FundedBy: model.FundedBySeller},
+	}
+	orders := []model.Order{{Seller: "usr_A"}, {Seller: "usr_B"}, {Seller: "usr_C"}}
+
+	all := applyDiscounts(discounts, orders)
+	assert.Len(t, all, 3)
+	if assert.NotNil(t, orders[0].Discounts) {
+		assert.Equal(t, model.Discounts{
+			{PromotionID: "prm_1", Code: "SPRING", Seller: "usr_A", Amount: 400, Currency: "EUR", FundedBy: model.FundedBySeller},
+			{PromotionID: "prm_1", Code: "SPRING", Seller: "usr_A", Amount: 300, Currency: "GBP", FundedBy: model.FundedBySeller},
+		}, *orders[0].Discounts)
+	}
+	if assert.NotNil(t, orders[1].Discounts) {
+		assert.Len(t, *orders[1].Discounts, 1)
+		assert.Equal(t, 200, (*orders[1].Discounts)[0].Amount)
+	}
+	assert.Nil(t, orders[2].Discounts)
+}
@@ -34,9 +34,15 @@ func (s *Server) routes() chi.Router {
 
 	r.Get("/orders", chassis.SimpleHandler(s.ordersSearch))
 	r.Get("/order/{ord_id}", chassis.SimpleHandler(s.orderSearch))
+	r.Get("/order/{ord_id}/invoice", chassis.SimpleHandler(s.orderInvoice))
 	r.Get("/bookings", chassis.SimpleHandler(s.bookingsSearch))
 	r.Get("/booking/{bok_id}", chassis.SimpleHandler(s.bookingSearch))
 
+	//TAX RATES
+	r.Get("/tax-rates", chassis.SimpleHandler(s.taxRates))
+	r.Put("/tax-rates/{country:[a-zA-Z]{2}}", chassis.SimpleHandler(s.setTaxRates))
+	r.Delete("/tax-rates/{country:[a-zA-Z]{2}}", chassis.SimpleHandler(s.deleteTaxRates))
+
 	//ITEM SUBSCRIPTIONS
 	// r.Get("/item-subscriptions", chassis.SimpleHandler(s.subscriptionItemSearch))
 	// r.Get("/item-subscription/{sub_id}", chassis.SimpleHandler(s.subscriptionItemSearch))
@@ -50,6 +56,9 @@ func (s *Server) routes() chi.Router {
 	r.Patch("/internal/booking/{bok_id}", chassis.SimpleHandler(s.patchBookingInternal))
 	r.Patch("/internal/order/{ord_id}", chassis.SimpleHandler(s.patchOrderInternal))
 	r.Get("/internal/purchase/{pur_id}", chassis.SimpleHandler(s.purchaseSearchInternal))
+	r.Get("/internal/order/{ord_id}", chassis.SimpleHandler(s.orderSearchInternal))
+	r.Get("/internal/order/{ord_id}/invoice", chassis.SimpleHandler(s.orderInvoiceInternal))
+	r.Get("/internal/booking/{bok_id}", chassis.SimpleHandler(s.bookingSearchInternal))
 	r.Get("/internal/purchase/item-bought", chassis.SimpleHandler(s.userPurchaseItem))
 	return r
 }
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/veganbase/backend/services/purchase-service/model"
@@ -9,11 +10,13 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/chassis/storage"
 	cart "github.com/veganbase/backend/services/cart-service/client"
 	item "github.com/veganbase/backend/services/item-service/client"
 	payment "github.com/veganbase/backend/services/payment-service/client"
 	"github.com/veganbase/backend/services/purchase-service/db"
 	search "github.com/veganbase/backend/services/search-service/client"
+	site "github.com/veganbase/backend/services/site-service/client"
 	user "github.com/veganbase/backend/services/user-service/client"
 )
 
@@ -26,9 +29,12 @@ type Server struct {
 	paymentSvc   payment.Client
 	userSvc      user.Client
 	searchSvc    search.Client
+	siteSvc      site.Client
 	imageBaseURL string
 	// redisClient  chassis.RedisClient
-	isDevMode bool
+	isDevMode          bool
+	settlementCurrency string
+	invoiceStore       storage.Storage
 }
 
 // Config contains the configuration information needed to start
@@ -45,6 +51,25 @@ type Config struct {
 	PaymentServiceURL string `env:"PAYMENT_SERVICE_URL,default=http://payment-service"`
 	UserServiceURL    string `env:"USER_SERVICE_URL,default=http://user-service"`
 	SearchServiceURL  string `env:"SEARCH_SERVICE_URL,default=http://search-service"`
+	SiteServiceURL    string `env:"SITE_SERVICE_URL,default=http://site-service"`
+
+	// If a settlement currency is set, purchases are charged in that
+	// currency, converting prices at the exchange rates current when the
+	// purchase is made. Otherwise, buyers are charged separately in each
+	// currency used in their purchase.
+	SettlementCurrency string `env:"SETTLEMENT_CURRENCY"`
+
+	// Storage for invoice documents: "gcs", "file" or "s3". If not
+	// set, Google Cloud Storage is used, except in development mode,
+	// where invoices are stored in a local directory.
+	StorageDriver  string `env:"STORAGE_DRIVER"`
+	StorageDir     string `env:"STORAGE_DIR,default=invoices"`
+	StorageBaseURL string `env:"STORAGE_BASE_URL"`
+	BucketName     string `env:"BUCKET_NAME"`
+	S3Endpoint     string `env:"S3_ENDPOINT"`
+	S3Region       string `env:"S3_REGION"`
+	S3AccessKey    string `env:"S3_ACCESS_KEY"`
+	S3SecretKey    string `env:"S3_SECRET_KEY"`
 	// RedisAddress      string `env:"REDIS_CLIENT,default=http://redis-service"`
 	// RedisPWD          string `env:"REDIS_PWD,required"`
 }
@@ -68,6 +93,8 @@ func NewServer(cfg *Config) *Server {
 	s.cartSvc = cart.New(cfg.CartServiceURL)
 	s.paymentSvc = payment.New(cfg.PaymentServiceURL)
 	s.searchSvc = search.New(cfg.SearchServiceURL)
+	s.siteSvc = site.New(cfg.SiteServiceURL, s.PubSub, s.AppName)
+	s.settlementCurrency = strings.ToUpper(cfg.SettlementCurrency)
 
 	s.isDevMode = cfg.DevMode
 
@@ -77,10 +104,41 @@ func NewServer(cfg *Config) *Server {
 
 	// Connect to purchase database.
 	timeout, _ := context.WithTimeout(context.Background(), time.Second*10)
-	s.db, err = db.NewPGClient(timeout, cfg.DBURL)
+	pg, err := db.NewPGClient(timeout, cfg.DBURL)
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't connect to user database")
 	}
+	s.db = pg
+	s.StartEventRelay(pg.DB)
+
+	// Connect to invoice storage.
+	driver := cfg.StorageDriver
+	if driver == "" {
+		driver = "gcs"
+		if cfg.DevMode {
+			driver = "file"
+		}
+	}
+	switch driver {
+	case "gcs":
+		s.invoiceStore, err = storage.NewGoogleClient(s.Ctx, cfg.Credentials, cfg.BucketName)
+	case "file":
+		s.invoiceStore, err = storage.NewFileStorage(cfg.StorageDir, cfg.StorageBaseURL)
+	case "s3":
+		s.invoiceStore, err = storage.NewS3Storage(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.BucketName,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PublicURL: cfg.StorageBaseURL,
+		})
+	default:
+		log.Fatal().Str("driver", driver).Msg("unknown invoice storage driver")
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("couldn't connect to invoice storage")
+	}
 	// XXX: I've disabled the redis client and the APIs associated with it to make
 	// the base purchase service work. Recurring subscriptions cannot be configured until
 	// this is resolved, which requires creating a new redis instance in the k8s cluster
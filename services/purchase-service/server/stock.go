@@ -0,0 +1,45 @@
+package server
+
+import (
+	"github.com/rs/zerolog/log"
+	item "github.com/veganbase/backend/services/item-service/client"
+	it "github.com/veganbase/backend/services/item-service/model"
+	"github.com/veganbase/backend/services/purchase-service/model"
+)
+
+// reserveStock reserves stock of the items in a purchase before the purchase is
+// created, so that two buyers can't both buy the last of an item. The reservation
+// is made under the purchase ID, which must already be set: the payment service
+// commits it once the purchase is paid for, and releases it if payment fails.
+// Unpaid reservations expire in the item service. Returns an *item.StockError if
+// there isn't enough stock.
+func (s *Server) reserveStock(purchase *model.Purchase) error {
+	req := it.ReservationRequest{Reference: purchase.Id}
+	for _, pi := range purchase.Items {
+		if pi.Quantity <= 0 {
+			continue
+		}
+		req.Lines = append(req.Lines, it.StockLine{
+			ItemID:   pi.ItemId,
+			Variant:  pi.Variant,
+			Quantity: pi.Quantity,
+		})
+	}
+	_, err := s.itemSvc.ReserveStock(&req)
+	return err
+}
+
+// releaseStock releases the stock reserved for a purchase that couldn't be created.
+func (s *Server) releaseStock(purchaseID string) {
+	if err := s.itemSvc.ReleaseStock(purchaseID); err != nil {
+		log.Error().Err(err).Str("purchase", purchaseID).Msg("releasing reserved stock")
+	}
+}
+
+// stockConflict says whether an error means that there isn't enough stock.
+func stockConflict(err error) (string, bool) {
+	if stockErr, ok := err.(*item.StockError); ok {
+		return stockErr.Message, true
+	}
+	return "", false
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/purchase-service/db"
+	"github.com/veganbase/backend/services/purchase-service/model"
+	usr "github.com/veganbase/backend/services/user-service/model"
+)
+
+// taxRates lists the tax rates for a country (given by the "country"
+// query parameter), or for all countries.
+func (s *Server) taxRates(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	return s.db.TaxRates(strings.ToUpper(r.URL.Query().Get("country")))
+}
+
+// setTaxRates replaces the tax rates for a country (administrators
+// only).
+func (s *Server) setTaxRates(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth || !authInfo.UserIsAdmin {
+		return chassis.Forbidden(w)
+	}
+	country := strings.ToUpper(chi.URLParam(r, "country"))
+
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	rates := model.TaxRates{}
+	if err = json.Unmarshal(body, &rates); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if err = rates.Validate(country); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	if err = s.db.SetTaxRates(country, rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// deleteTaxRates deletes the tax rates for a country (administrators
+// only), so that orders delivered there are no longer taxed.
+func (s *Server) deleteTaxRates(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth || !authInfo.UserIsAdmin {
+		return chassis.Forbidden(w)
+	}
+	country := strings.ToUpper(chi.URLParam(r, "country"))
+
+	if err := s.db.DeleteTaxRates(country); err != nil {
+		if err == db.ErrTaxRatesNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	return chassis.NoContent(w)
+}
+
+// addTaxes works out the tax due on each order and booking of a
+// purchase. Orders are taxed at the rates of the country that they
+// are delivered to, and orders delivered to countries without tax
+// rates (e.g. exports from the EU) are not taxed. Bookings are
+// services supplied by their host, so they're taxed at the rates of
+// the host's country, and purchases without a delivery address only
+// have bookings. Tax is only charged by sellers registered for VAT in
+// a country with tax rates set up.
+func (s *Server) addTaxes(purchase *model.Purchase, orders []model.Order,
+	bookings []model.Booking, address *usr.Address) error {
+	taxes := model.Taxes{}
+	purchase.Taxes = &taxes
+	if len(orders)+len(bookings) == 0 {
+		return nil
+	}
+
+	rates, err := s.db.TaxRates("")
+	if err != nil {
+		return err
+	}
+	country := ""
+	if address != nil {
+		country = strings.ToUpper(address.Country)
+	}
+	if _, ok := rates.Lookup(country, ""); !ok && len(bookings) == 0 {
+		return nil
+	}
+
+	sellers := []string{}
+	for _, o := range orders {
+		sellers = append(sellers, o.Seller)
+	}
+	for _, b := range bookings {
+		sellers = append(sellers, b.Host)
+	}
+	settings, err := s.userSvc.GetTaxSettings(sellers)
+	if err != nil {
+		return err
+	}
+
+	for i := range orders {
+		ts, ok := settings[orders[i].Seller]
+		if !ok || country == "" {
+			continue
+		}
+		if _, ok := rates.Lookup(ts.Country, ""); !ok {
+			continue
+		}
+		orders[i].Tax = model.CalculateTax(rates, country, ts.PricesIncludeTax, &orders[i])
+		if orders[i].Tax != nil {
+			taxes = append(taxes, *orders[i].Tax)
+		}
+	}
+
+	for i := range bookings {
+		ts, ok := settings[bookings[i].Host]
+		if !ok {
+			continue
+		}
+		category := ""
+		for _, item := range purchase.Items {
+			if item.ItemId == bookings[i].ItemID {
+				category = item.TaxCategory
+				if category == "" {
+					category = item.ProductType
+				}
+				break
+			}
+		}
+		bookings[i].Tax = model.CalculateBookingTax(rates, ts.Country,
+			ts.PricesIncludeTax, &bookings[i], category)
+		if bookings[i].Tax != nil {
+			taxes = append(taxes, *bookings[i].Tax)
+		}
+	}
+	return nil
+}
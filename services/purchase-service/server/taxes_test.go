@@ -0,0 +1,103 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/veganbase/backend/services/purchase-service/db"
+	"github.com/veganbase/backend/services/purchase-service/model"
+	"github.com/veganbase/backend/services/purchase-service/model/types"
+	user "github.com/veganbase/backend/services/user-service/client"
+	usr "github.com/veganbase/backend/services/user-service/model"
+)
+
+// taxDB holds the tax rates used to work out taxes.
+type taxDB struct {
+	db.DB
+	rates model.TaxRates
+}
+
+func (d *taxDB) TaxRates(country string) (model.TaxRates, error) {
+	return d.rates, nil
+}
+
+// taxUsers holds the tax settings of sellers.
+type taxUsers struct {
+	user.Client
+	settings map[string]usr.TaxSettings
+}
+
+func (u *taxUsers) GetTaxSettings(ids []string) (map[string]usr.TaxSettings, error) {
+	settings := map[string]usr.TaxSettings{}
+	for _, id := range ids {
+		if ts, ok := u.settings[id]; ok {
+			settings[id] = ts
+		}
+	}
+	return settings, nil
+}
+
+func TestAddTaxes(t *testing.T) {
+	s := &Server{
+		db: &taxDB{rates: model.TaxRates{
+			{Country: "DE", Rate: 19},
+			{Country: "DE", Category: "packaged-food", Rate: 7},
+			{Country: "FR", Rate: 20},
+		}},
+		userSvc: &taxUsers{settings: map[string]usr.TaxSettings{
+			"usr_SELLER": {Owner: "usr_SELLER", Country: "DE"},
+			"usr_HOST":   {Owner: "usr_HOST", Country: "FR", PricesIncludeTax: true},
+		}},
+	}
+
+	// A purchase made without an address, like a simple purchase, only
+	// has bookings, which are taxed in the host's country.
+	purchase := model.Purchase{Items: types.PurchaseItems{
+		{ItemId: "itm_EVENT", ItemOwner: "usr_HOST", ProductType: "event", TaxCategory: "event",
+			Price: 1200, Quantity: 1, Currency: "EUR"},
+	}}
+	bookings := []model.Booking{{Host: "usr_HOST", ItemID: "itm_EVENT",
+		BookingInfo: types.BookingInfo{Price: 1200, Quantity: 1, Currency: "EUR"}}}
+	assert.Nil(t, s.addTaxes(&purchase, nil, bookings, nil))
+	if assert.NotNil(t, bookings[0].Tax) {
+		assert.Equal(t, "FR", bookings[0].Tax.Country)
+		assert.Equal(t, []model.TaxLine{
+			{Category: "event", Rate: 20, Taxable: 1000, Amount: 200, Currency: "EUR"},
+		}, bookings[0].Tax.Lines)
+	}
+	assert.Equal(t, model.Taxes{*bookings[0].Tax}, *purchase.Taxes)
+
+	// Orders are taxed in the country they're delivered to, and sellers
+	// without tax settings don't charge tax.
+	orders := []model.Order{
+		{Seller: "usr_SELLER", Items: types.PurchaseItems{
+			{TaxCategory: "packaged-food", Price: 1000, Quantity: 1, Currency: "EUR"},
+		}},
+		{Seller: "usr_OTHER", Items: types.PurchaseItems{
+			{TaxCategory: "packaged-food", Price: 1000, Quantity: 1, Currency: "EUR"},
+		}},
+	}
+	bookings[0].Tax = nil
+	assert.Nil(t, s.addTaxes(&purchase, orders, bookings, deliverTo("de")))
+	if assert.NotNil(t, orders[0].Tax) {
+		assert.Equal(t, "DE", orders[0].Tax.Country)
+		assert.Equal(t, map[string]int{"EUR": 70}, orders[0].Tax.Added())
+	}
+	assert.Nil(t, orders[1].Tax)
+	assert.NotNil(t, bookings[0].Tax)
+	assert.Len(t, *purchase.Taxes, 2)
+
+	// Orders delivered to countries without tax rates aren't taxed.
+	orders[0].Tax = nil
+	assert.Nil(t, s.addTaxes(&purchase, orders, nil, deliverTo("US")))
+	assert.Nil(t, orders[0].Tax)
+	assert.Empty(t, *purchase.Taxes)
+}
+
+// deliverTo makes an address in a country.
+func deliverTo(country string) *usr.Address {
+	address := &usr.Address{}
+	address.Country = country
+	return address
+}
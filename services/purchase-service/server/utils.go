@@ -24,11 +24,13 @@ func fillPurchaseItem(itemInfo it.ItemFullWithLink, cartItem cartModel.CartItemF
 
 	purItem := types.PurchaseItem{
 		ItemId:      itemInfo.ID,
+		Variant:     cartItem.Variant,
 		ProductType: itemInfo.ItemType.String(),
 		ItemOwner:   itemInfo.Owner.ID,
 		Quantity:    cartItem.Quantity,
 		Price:       int(itemInfo.Attrs["price"].(float64)),
 		Currency:    itemInfo.Attrs["currency"].(string),
+		TaxCategory: taxCategory(itemInfo),
 		OtherInfo:   make(map[string]interface{}),
 	}
 	// try to cast to string, ignore an empty value
@@ -66,6 +68,15 @@ func fillPurchaseItem(itemInfo it.ItemFullWithLink, cartItem cartModel.CartItemF
 
 	return purItem
 }
+// taxCategory gives the tax category of an item: the type of product
+// for product offerings, and the item type for everything else.
+func taxCategory(itemInfo it.ItemFullWithLink) string {
+	if itemInfo.ItemType == it.ProductOfferingItem && itemInfo.Link != nil && itemInfo.Link.Target != nil {
+		return itemInfo.Link.Target.ItemType.String()
+	}
+	return itemInfo.ItemType.String()
+}
+
 func fillPurchaseItemFromSubscription(itemInfo it.ItemFullWithLink, sub model.SubscriptionItem) types.PurchaseItem {
 
 	purItem := types.PurchaseItem{
@@ -75,8 +86,11 @@ func fillPurchaseItemFromSubscription(itemInfo it.ItemFullWithLink, sub model.Su
 		Quantity:    sub.Quantity,
 		Price:       int(itemInfo.Attrs["price"].(float64)),
 		Currency:    itemInfo.Attrs["currency"].(string),
+		TaxCategory: taxCategory(itemInfo),
 		OtherInfo:   make(map[string]interface{}),
 	}
+	// subscriptions keep the variant with the other cart item information
+	purItem.Variant, _ = sub.OtherInfo["variant"].(string)
 	if sub.OtherInfo != nil {
 
 		for k, v := range sub.OtherInfo {
@@ -120,6 +134,9 @@ func fillSubscriptionItem(itemInfo it.ItemFullWithLink, cartItem cartModel.CartI
 			subsItem.OtherInfo[k] = v
 		}
 	}
+	if cartItem.Variant != "" {
+		subsItem.OtherInfo["variant"] = cartItem.Variant
+	}
 
 	if subsItem.ItemType == it.ProductOfferingItem {
 
@@ -71,7 +71,7 @@ func TestFullText(t *testing.T) {
 			{"restaurant", []string{}},
 		}
 		for _, test := range tests {
-			ids, err := pg.FullText(test.query, nil, nil)
+			ids, err := pg.FullText(test.query, nil, nil, nil)
 			assert.Nil(t, err)
 			assert.NotNil(t, ids)
 			fmt.Println(ids)
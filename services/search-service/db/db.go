@@ -19,10 +19,12 @@ type DB interface {
 		approval *[]itemTypes.ApprovalState) ([]string, error)
 
 	// FullText performs a full text search for a given query string,
-	// returning a list of item IDs in order of relevance.
+	// returning a list of item IDs in order of relevance. If inStock is
+	// given, only items whose stock status matches are returned.
 	FullText(query string,
 		itemType *itemModel.ItemType,
-		approval *[]itemTypes.ApprovalState) ([]string, error)
+		approval *[]itemTypes.ApprovalState,
+		inStock *bool) ([]string, error)
 
 	// Region performs a named region search, returning a list of item
 	// IDs lying within the boundaries of the region.
@@ -40,7 +42,10 @@ type DB interface {
 	AddFullText(id string,
 		itemType itemModel.ItemType,
 		approval itemTypes.ApprovalState,
-		name, description, content string, tags []string) error
+		name, description, content string, tags []string, inStock bool) error
+
+	// SetInStock records whether an item is available for sale.
+	SetInStock(id string, inStock bool) error
 
 	// ItemRemoved deletes search index information for an item.
 	ItemRemoved(id string)
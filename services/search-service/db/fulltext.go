@@ -2,6 +2,8 @@ package db
 
 import (
 	"errors"
+	"strconv"
+
 	"github.com/rs/zerolog/log"
 	itemModel "github.com/veganbase/backend/services/item-service/model"
 	itemTypes "github.com/veganbase/backend/services/item-service/model/types"
@@ -13,10 +15,14 @@ import (
 // returning a list of item IDs in order of relevance.
 func (pg *PGClient) FullText(query string,
 	itemType *itemModel.ItemType,
-	approval *[]itemTypes.ApprovalState) ([]string, error) {
+	approval *[]itemTypes.ApprovalState,
+	inStock *bool) ([]string, error) {
+	q := fullText + typeApprovalWhere(itemType, approval)
+	if inStock != nil {
+		q += " AND in_stock = " + strconv.FormatBool(*inStock)
+	}
 	ids := []string{}
-	err := pg.DB.Select(&ids,
-		fullText+typeApprovalWhere(itemType, approval) + fullTextOrder, query)
+	err := pg.DB.Select(&ids, q+fullTextOrder, query)
 	if err != nil {
 		return nil, err
 	}
@@ -58,9 +64,9 @@ func typeApprovalWhere(itemType *itemModel.ItemType,
 func (pg *PGClient) AddFullText(id string,
 	itemType itemModel.ItemType,
 	approval itemTypes.ApprovalState,
-	name, description, content string, tags []string) error {
+	name, description, content string, tags []string, inStock bool) error {
 	result, err := pg.DB.Exec(addFullText, id, itemType, approval,
-		name, description, strings.Join(tags, " "), content)
+		name, description, strings.Join(tags, " "), content, inStock)
 	if err != nil {
 		return err
 	}
@@ -75,19 +81,27 @@ func (pg *PGClient) AddFullText(id string,
 }
 
 const addFullText = `
-INSERT INTO item_full_text(item_id, item_type, approval, full_text)
+INSERT INTO item_full_text(item_id, item_type, approval, full_text, in_stock)
  VALUES ($1, $2, $3,
          setweight(to_tsvector($4), 'A') ||
          setweight(to_tsvector($5), 'B') ||
          setweight(to_tsvector($6), 'C') ||
-         setweight(to_tsvector($7), 'D'))
+         setweight(to_tsvector($7), 'D'), $8)
  ON CONFLICT (item_id)
- DO UPDATE SET item_type = $2, approval = $3,
+ DO UPDATE SET item_type = $2, approval = $3, in_stock = $8,
    full_text = setweight(to_tsvector($4), 'A') ||
                setweight(to_tsvector($5), 'B') ||
                setweight(to_tsvector($6), 'C') ||
                setweight(to_tsvector($7), 'D')`
 
+// SetInStock records whether an item is available for sale.
+func (pg *PGClient) SetInStock(id string, inStock bool) error {
+	_, err := pg.DB.Exec(setInStock, id, inStock)
+	return err
+}
+
+const setInStock = `UPDATE item_full_text SET in_stock = $2 WHERE item_id = $1`
+
 // ItemRemoved deletes search index information for an item.
 func (pg *PGClient) ItemRemoved(id string) {
 	_, err := pg.DB.Exec(deleteFullText, id)
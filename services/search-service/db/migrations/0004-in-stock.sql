@@ -0,0 +1,14 @@
+-- +migrate Up
+
+SET ROLE vb_search;
+
+-- Whether any variant of a stock-controlled item is available for
+-- sale. Items that aren't stock-controlled are always in stock.
+ALTER TABLE item_full_text ADD COLUMN in_stock BOOLEAN NOT NULL DEFAULT true;
+
+
+-- +migrate Down
+
+SET ROLE vb_search;
+
+ALTER TABLE item_full_text DROP COLUMN in_stock;
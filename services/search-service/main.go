@@ -20,5 +20,6 @@ func main() {
 	chassis.LogSetup(appname, cfg.DevMode)
 	serv := server.NewServer(&cfg)
 	go serv.Sync()
+	go serv.HandleStockEvents()
 	serv.Serve()
 }
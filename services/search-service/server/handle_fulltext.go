@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/item-service/model"
@@ -33,7 +34,16 @@ func (s *Server) fullText(w http.ResponseWriter, r *http.Request) (interface{},
 		return chassis.BadRequest(w, "invalid approval parameter")
 	}
 
-	res, err := s.db.FullText(*q, itemType, approval)
+	var inStock *bool
+	if param := qs.Get("in_stock"); param != "" {
+		b, err := strconv.ParseBool(param)
+		if err != nil {
+			return chassis.BadRequest(w, "invalid in_stock parameter")
+		}
+		inStock = &b
+	}
+
+	res, err := s.db.FullText(*q, itemType, approval, inStock)
 	if err != nil {
 		return nil, err
 	}
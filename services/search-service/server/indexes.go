@@ -11,5 +11,5 @@ func (s *Server) addLocationInfo(id string, info *item.SearchInfo) error {
 
 func (s *Server) addFullTextInfo(id string, info *item.SearchInfo) error {
 	return s.db.AddFullText(id, info.ItemType, info.Approval,
-		info.Name, info.Description, info.Content, info.Tags)
+		info.Name, info.Description, info.Content, info.Tags, info.InStock)
 }
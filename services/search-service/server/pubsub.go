@@ -9,6 +9,7 @@ import (
 )
 
 const subName = "search-service-item-changes"
+const stockSubName = "search-service-stock-changes"
 
 func (s *Server) handleItemEvents() {
 	// Use a single subscription name to process changes by competing
@@ -37,3 +38,29 @@ func (s *Server) handleItemEvents() {
 		}
 	}
 }
+
+// HandleStockEvents keeps the stock status of items in the search
+// index up to date as stock is adjusted, reserved and sold.
+func (s *Server) HandleStockEvents() {
+	ch, _, err := s.PubSub.Subscribe(item_events.StockChange, stockSubName,
+		pubsub.CompetingConsumers)
+	if err != nil {
+		log.Fatal().Err(err).
+			Msg("couldn't subscribe to stock change events")
+	}
+
+	for {
+		data := <-ch
+		event := item_events.StockEvent{}
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Error().Err(err).
+				Msg("unmarshalling stock change event")
+			continue
+		}
+
+		if err := s.db.SetInStock(event.ItemID, event.InStock); err != nil {
+			log.Error().Err(err).
+				Msgf("updating stock status for item ID '%s'", event.ItemID)
+		}
+	}
+}
@@ -23,4 +23,9 @@ type Client interface {
 	// site list is updated, along with a cancel function to stop
 	// watching for updates.
 	SiteUpdates() (chan bool, func(), error)
+
+	// ExchangeRates returns the current exchange rates (which are
+	// populated and updated asynchronously based on events from the
+	// site service), or nil if they're not known yet.
+	ExchangeRates() *model.ExchangeRates
 }
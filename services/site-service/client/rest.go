@@ -19,6 +19,8 @@ type RESTClient struct {
 	baseURL       string
 	muSites       sync.RWMutex
 	sites         SiteMap
+	muRates       sync.RWMutex
+	rates         *model.ExchangeRates
 	pubsub        pubsub.PubSub
 	subName       string
 	listenerCount int
@@ -37,6 +39,7 @@ func New(baseURL string, pubsub pubsub.PubSub, subname string) *RESTClient {
 		listeners:     map[int]chan bool{},
 	}
 	go populate(client)
+	go populateRates(client)
 	return client
 }
 
@@ -158,3 +161,86 @@ func (c *RESTClient) SiteUpdates() (chan bool, func(), error) {
 
 	return ch, fn, nil
 }
+
+func populateRates(c *RESTClient) {
+	// First get the current exchange rates by doing a REST call.
+	populateRatesFromREST(c)
+
+	// Now wait for update events.
+	if c.pubsub != nil {
+		handleRateUpdates(c)
+	}
+}
+
+func populateRatesFromREST(c *RESTClient) {
+	first := true
+	for {
+		if !first {
+			time.Sleep(10 * time.Second)
+		}
+		first = false
+
+		// GET exchange rates from site service endpoint.
+		url := c.baseURL + "/exchange-rates"
+		rsp, err := http.Get(url)
+		if err != nil {
+			log.Error().Err(err).Str("url", url).
+				Msg("couldn't retrieve exchange rates")
+			continue
+		}
+
+		// Decode response.
+		rates := model.ExchangeRates{}
+		rspBody, err := ioutil.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		if err != nil {
+			log.Error().Err(err).
+				Msg("couldn't read exchange rates response body")
+			continue
+		}
+		err = json.Unmarshal(rspBody, &rates)
+		if err != nil {
+			log.Error().Err(err).Str("body", string(rspBody)).
+				Msg("couldn't decode exchange rates response body")
+			continue
+		}
+
+		c.setRates(&rates)
+		return
+	}
+}
+
+func handleRateUpdates(c *RESTClient) {
+	subCh, _, err := c.pubsub.Subscribe(site_events.ExchangeRatesUpdate, c.subName, pubsub.Fanout)
+	if err != nil {
+		log.Fatal().Err(err).
+			Msg("unable to subscribe to exchange rate updates")
+	}
+
+	for {
+		newRateData := <-subCh
+		newRates := model.ExchangeRates{}
+		err := json.Unmarshal(newRateData, &newRates)
+		if err != nil {
+			log.Error().Err(err).
+				Msg("unmarshalling exchange rates update")
+			continue
+		}
+		c.setRates(&newRates)
+	}
+}
+
+func (c *RESTClient) setRates(rates *model.ExchangeRates) {
+	c.muRates.Lock()
+	defer c.muRates.Unlock()
+	c.rates = rates
+}
+
+// ExchangeRates returns the current exchange rates (which are
+// populated and updated asynchronously based on events from the site
+// service), or nil if they're not known yet.
+func (c *RESTClient) ExchangeRates() *model.ExchangeRates {
+	c.muRates.RLock()
+	defer c.muRates.RUnlock()
+	return c.rates
+}
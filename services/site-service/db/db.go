@@ -9,6 +9,13 @@ type DB interface {
 	// Sites gets the list of sites.
 	Sites() (map[string]*model.Site, error)
 
+	// ExchangeRates gets the current exchange rates. The base currency
+	// is not set.
+	ExchangeRates() (*model.ExchangeRates, error)
+
+	// SetExchangeRates replaces the current exchange rates.
+	SetExchangeRates(rates *model.ExchangeRates) error
+
 	// SaveEvent saves an event to the database.
 	SaveEvent(label string, eventData interface{}, inTx func() error) error
 }
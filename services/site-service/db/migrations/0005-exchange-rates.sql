@@ -0,0 +1,18 @@
+-- +migrate Up
+
+SET ROLE vb_sites;
+
+-- Exchange rates against the base currency configured for the site
+-- service: the number of units of each currency that one unit of the
+-- base currency buys.
+CREATE TABLE exchange_rates (
+  currency   CHAR(3)         PRIMARY KEY,
+  rate       NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+  updated_at TIMESTAMPTZ     NOT NULL DEFAULT NOW()
+);
+
+
+-- +migrate Down
+
+SET ROLE vb_sites;
+DROP TABLE exchange_rates;
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 
@@ -44,6 +45,62 @@ func (pg *PGClient) Sites() (map[string]*model.Site, error) {
 const selectSites = `
 SELECT id, name, url, email_domain, signature, fee, created_at FROM sites`
 
+// ExchangeRates retrieves the current exchange rates.
+func (pg *PGClient) ExchangeRates() (*model.ExchangeRates, error) {
+	rows := []struct {
+		Currency  string    `db:"currency"`
+		Rate      float64   `db:"rate"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}{}
+	if err := pg.DB.Select(&rows, selectExchangeRates); err != nil {
+		return nil, err
+	}
+	rates := &model.ExchangeRates{Rates: map[string]float64{}}
+	for _, r := range rows {
+		rates.Rates[r.Currency] = r.Rate
+		if r.UpdatedAt.After(rates.UpdatedAt) {
+			rates.UpdatedAt = r.UpdatedAt
+		}
+	}
+	return rates, nil
+}
+
+const selectExchangeRates = `
+SELECT currency, rate, updated_at FROM exchange_rates`
+
+// SetExchangeRates replaces the current exchange rates.
+func (pg *PGClient) SetExchangeRates(rates *model.ExchangeRates) error {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(`DELETE FROM exchange_rates`); err != nil {
+		return err
+	}
+	for currency, rate := range rates.Rates {
+		if currency == rates.Base {
+			continue
+		}
+		_, err = tx.Exec(insertExchangeRate, currency, rate, rates.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+const insertExchangeRate = `
+INSERT INTO exchange_rates (currency, rate, updated_at) VALUES ($1, $2, $3)`
+
 // SaveEvent saves an event to the database.
 func (pg *PGClient) SaveEvent(label string, eventData interface{}, inTx func() error) error {
 	tx, err := pg.DB.Beginx()
@@ -2,5 +2,6 @@ package events
 
 // Event names used in site service.
 const (
-	SiteUpdate = "site-update"
+	SiteUpdate          = "site-update"
+	ExchangeRatesUpdate = "exchange-rates-update"
 )
@@ -4,12 +4,29 @@ package mocks
 
 import client "github.com/veganbase/backend/services/site-service/client"
 import mock "github.com/stretchr/testify/mock"
+import model "github.com/veganbase/backend/services/site-service/model"
 
 // Client is an autogenerated mock type for the Client type
 type Client struct {
 	mock.Mock
 }
 
+// ExchangeRates provides a mock function with given fields:
+func (_m *Client) ExchangeRates() *model.ExchangeRates {
+	ret := _m.Called()
+
+	var r0 *model.ExchangeRates
+	if rf, ok := ret.Get(0).(func() *model.ExchangeRates); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.ExchangeRates)
+		}
+	}
+
+	return r0
+}
+
 // SiteUpdates provides a mock function with given fields:
 func (_m *Client) SiteUpdates() (chan bool, func(), error) {
 	ret := _m.Called()
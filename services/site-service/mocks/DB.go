@@ -10,6 +10,29 @@ type DB struct {
 	mock.Mock
 }
 
+// ExchangeRates provides a mock function with given fields:
+func (_m *DB) ExchangeRates() (*model.ExchangeRates, error) {
+	ret := _m.Called()
+
+	var r0 *model.ExchangeRates
+	if rf, ok := ret.Get(0).(func() *model.ExchangeRates); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.ExchangeRates)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SaveEvent provides a mock function with given fields: label, eventData, inTx
 func (_m *DB) SaveEvent(label string, eventData interface{}, inTx func() error) error {
 	ret := _m.Called(label, eventData, inTx)
@@ -24,6 +47,20 @@ func (_m *DB) SaveEvent(label string, eventData interface{}, inTx func() error)
 	return r0
 }
 
+// SetExchangeRates provides a mock function with given fields: rates
+func (_m *DB) SetExchangeRates(rates *model.ExchangeRates) error {
+	ret := _m.Called(rates)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.ExchangeRates) error); ok {
+		r0 = rf(rates)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Sites provides a mock function with given fields:
 func (_m *DB) Sites() (map[string]*model.Site, error) {
 	ret := _m.Called()
@@ -0,0 +1,174 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// ErrUnknownCurrency is the error returned when there is no exchange
+// rate for a currency.
+var ErrUnknownCurrency = errors.New("no exchange rate for currency")
+
+// ExchangeRates is a set of currency exchange rates, each giving the
+// number of units of a currency that one unit of the base currency
+// buys.
+type ExchangeRates struct {
+	// The currency that the rates are given against.
+	Base string `json:"base"`
+
+	// Exchange rates, keyed by upper case ISO 4217 currency code.
+	Rates map[string]float64 `json:"rates"`
+
+	// When the rates were last updated.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks a set of exchange rates, normalising currency codes
+// to upper case.
+func (xr *ExchangeRates) Validate() error {
+	xr.Base = strings.ToUpper(xr.Base)
+	rates := map[string]float64{}
+	for currency, rate := range xr.Rates {
+		currency = strings.ToUpper(currency)
+		if len(currency) != 3 {
+			return errors.New("invalid currency code '" + currency + "'")
+		}
+		if rate <= 0 {
+			return errors.New("exchange rate for " + currency + " must be positive")
+		}
+		rates[currency] = rate
+	}
+	if rate, ok := rates[xr.Base]; ok && rate != 1 {
+		return errors.New("exchange rate for base currency must be 1")
+	}
+	rates[xr.Base] = 1
+	xr.Rates = rates
+	return nil
+}
+
+// Rate looks up the exchange rate for converting amounts from one
+// currency to another.
+func (xr *ExchangeRates) Rate(from, to string) (*ExchangeRate, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	rate := ExchangeRate{From: from, To: to, Rate: 1, At: xr.UpdatedAt}
+	if from == to {
+		return &rate, nil
+	}
+	fromRate, ok := xr.Rates[from]
+	if !ok {
+		return nil, ErrUnknownCurrency
+	}
+	toRate, ok := xr.Rates[to]
+	if !ok {
+		return nil, ErrUnknownCurrency
+	}
+	rate.Rate = toRate / fromRate
+	return &rate, nil
+}
+
+// Convert converts amounts in a number of currencies into a single
+// currency, returning the total and the rates used.
+func (xr *ExchangeRates) Convert(amounts map[string]int, to string) (int, []ExchangeRate, error) {
+	total := 0
+	rates := []ExchangeRate{}
+	for currency, amount := range amounts {
+		rate, err := xr.Rate(currency, to)
+		if err != nil {
+			return 0, nil, err
+		}
+		total += rate.Convert(amount)
+		if rate.From != rate.To {
+			rates = append(rates, *rate)
+		}
+	}
+	return total, rates, nil
+}
+
+// ExchangeRate is a single exchange rate, as recorded for auditing
+// when an amount is converted between currencies.
+type ExchangeRate struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	Rate float64   `json:"rate"`
+	At   time.Time `json:"at"`
+}
+
+// Convert converts an amount in the smallest unit of the source
+// currency into the smallest unit of the target currency, rounding to
+// the nearest unit. Rates are between major units, so the amount is
+// scaled for currencies with different numbers of decimal places.
+func (r *ExchangeRate) Convert(amount int) int {
+	return int(math.Round(r.ConvertExact(amount)))
+}
+
+// ConvertExact converts an amount like Convert, without rounding, for
+// callers that need to control how rounding errors are distributed.
+func (r *ExchangeRate) ConvertExact(amount int) float64 {
+	scale := math.Pow10(CurrencyExponent(r.To) - CurrencyExponent(r.From))
+	return float64(amount) * r.Rate * scale
+}
+
+// Currencies whose smallest unit isn't a hundredth of the major unit,
+// keyed by ISO 4217 code, with the number of decimal places they use.
+var currencyExponents = map[string]int{
+	"BHD": 3, "BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "IQD": 3,
+	"ISK": 0, "JOD": 3, "JPY": 0, "KMF": 0, "KRW": 0, "KWD": 3,
+	"LYD": 3, "OMR": 3, "PYG": 0, "RWF": 0, "TND": 3, "UGX": 0,
+	"UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+// CurrencyExponent gives the number of decimal places used by a
+// currency, i.e. the power of ten that the smallest unit of the
+// currency divides the major unit by.
+func CurrencyExponent(currency string) int {
+	if exp, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Make the ExchangeRates struct implement the driver.Valuer interface. This
+// method simply returns the JSON-encoded representation of the struct.
+func (xr *ExchangeRates) Value() (driver.Value, error) {
+	v, err := json.Marshal(xr)
+	if err != nil {
+		return nil, err
+	}
+	return types.JSONText(v).Value()
+}
+
+// Make the ExchangeRates struct implement the sql.Scanner interface. This
+// method simply decodes a JSON-encoded value into the struct fields.
+func (xr *ExchangeRates) Scan(src interface{}) error {
+	j := types.JSONText{}
+	if err := j.Scan(src); err != nil {
+		return err
+	}
+	return json.Unmarshal(j, xr)
+}
+
+// Make the ExchangeRate struct implement the driver.Valuer interface. This
+// method simply returns the JSON-encoded representation of the struct.
+func (r *ExchangeRate) Value() (driver.Value, error) {
+	v, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return types.JSONText(v).Value()
+}
+
+// Make the ExchangeRate struct implement the sql.Scanner interface. This
+// method simply decodes a JSON-encoded value into the struct fields.
+func (r *ExchangeRate) Scan(src interface{}) error {
+	j := types.JSONText{}
+	if err := j.Scan(src); err != nil {
+		return err
+	}
+	return json.Unmarshal(j, r)
+}
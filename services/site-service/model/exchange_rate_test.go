@@ -0,0 +1,51 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExchangeRates(t *testing.T) {
+	xr := ExchangeRates{Base: "eur", Rates: map[string]float64{"gbp": 0.85, "USD": 1.25}}
+	assert.Nil(t, xr.Validate())
+	assert.Equal(t, map[string]float64{"EUR": 1, "GBP": 0.85, "USD": 1.25}, xr.Rates)
+
+	rate, err := xr.Rate("usd", "gbp")
+	assert.Nil(t, err)
+	assert.Equal(t, 680, rate.Convert(1000))
+	_, err = xr.Rate("EUR", "JPY")
+	assert.Equal(t, ErrUnknownCurrency, err)
+
+	total, rates, err := xr.Convert(map[string]int{"EUR": 1000, "gbp": 850}, "EUR")
+	assert.Nil(t, err)
+	assert.Equal(t, 2000, total)
+	assert.Len(t, rates, 1)
+
+	// Amounts are scaled between currencies with different numbers of
+	// decimal places.
+	xr = ExchangeRates{Base: "EUR", Rates: map[string]float64{"JPY": 160, "KWD": 0.33}}
+	assert.Nil(t, xr.Validate())
+	rate, err = xr.Rate("EUR", "JPY")
+	assert.Nil(t, err)
+	assert.Equal(t, 1600, rate.Convert(1000))
+	rate, err = xr.Rate("jpy", "eur")
+	assert.Nil(t, err)
+	assert.Equal(t, 1000, rate.Convert(1600))
+	assert.Equal(t, 1, rate.Convert(1))
+	rate, err = xr.Rate("JPY", "KWD")
+	assert.Nil(t, err)
+	assert.Equal(t, 3300, rate.Convert(1600))
+	total, _, err = xr.Convert(map[string]int{"EUR": 500, "JPY": 800}, "JPY")
+	assert.Nil(t, err)
+	assert.Equal(t, 1600, total)
+
+	assert.Equal(t, 0, CurrencyExponent("jpy"))
+	assert.Equal(t, 3, CurrencyExponent("KWD"))
+	assert.Equal(t, 2, CurrencyExponent("EUR"))
+
+	bad := ExchangeRates{Base: "EUR", Rates: map[string]float64{"GBP": -1}}
+	assert.NotNil(t, bad.Validate())
+	bad = ExchangeRates{Base: "EUR", Rates: map[string]float64{"EUR": 2}}
+	assert.NotNil(t, bad.Validate())
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/site-service/events"
+	"github.com/veganbase/backend/services/site-service/model"
+)
+
+// Return the current exchange rates.
+func (s *Server) exchangeRates(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.rates, nil
+}
+
+// Replace the current exchange rates (administrators only).
+func (s *Server) setExchangeRates(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth || !authInfo.UserIsAdmin {
+		return chassis.Forbidden(w)
+	}
+
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	rates := model.ExchangeRates{}
+	if err = json.Unmarshal(body, &rates); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if err = s.checkExchangeRates(&rates); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	if err = s.saveExchangeRates(&rates); err != nil {
+		return nil, err
+	}
+	return &rates, nil
+}
+
+// Load exchange rates from a JSON file, replacing the stored rates.
+func (s *Server) loadExchangeRates(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	rates := model.ExchangeRates{}
+	if err = json.Unmarshal(data, &rates); err != nil {
+		return err
+	}
+	if err = s.checkExchangeRates(&rates); err != nil {
+		return err
+	}
+	return s.db.SetExchangeRates(&rates)
+}
+
+// Check a new set of exchange rates, which must be given against the
+// service's base currency.
+func (s *Server) checkExchangeRates(rates *model.ExchangeRates) error {
+	if rates.Base == "" {
+		rates.Base = s.baseCurrency
+	}
+	if err := rates.Validate(); err != nil {
+		return err
+	}
+	if rates.Base != s.baseCurrency {
+		return errors.New("exchange rates must be given against " + s.baseCurrency)
+	}
+	rates.UpdatedAt = time.Now()
+	return nil
+}
+
+// Save a new set of exchange rates and tell other services about them.
+func (s *Server) saveExchangeRates(rates *model.ExchangeRates) error {
+	if err := s.db.SetExchangeRates(rates); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	s.rates = rates
+	s.lock.Unlock()
+
+	if err := chassis.Emit(s, events.ExchangeRatesUpdate, rates); err != nil {
+		log.Error().Err(err).Msg("failed emitting exchange rates update event")
+	}
+	return nil
+}
@@ -12,6 +12,9 @@ func (s *Server) routes() chi.Router {
 	// Add common middleware.
 	chassis.AddCommonMiddleware(r, true)
 
+	// Inject authentication information into request context.
+	r.Use(chassis.AuthCtx)
+
 	// Service health checks.
 	r.Get("/", chassis.Health)
 	r.Get("/healthz", chassis.Health)
@@ -19,5 +22,9 @@ func (s *Server) routes() chi.Router {
 	// Get site list.
 	r.Get("/sites", chassis.SimpleHandler(s.list))
 
+	// Exchange rates.
+	r.Get("/exchange-rates", chassis.SimpleHandler(s.exchangeRates))
+	r.Put("/exchange-rates", chassis.SimpleHandler(s.setExchangeRates))
+
 	return r
 }
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"strings"
 	"sync"
 
 	"github.com/rs/zerolog/log"
@@ -16,9 +17,11 @@ import (
 // Server is the server structure for the user service.
 type Server struct {
 	chassis.Server
-	db    db.DB
-	lock  sync.RWMutex
-	sites map[string]*model.Site
+	db           db.DB
+	lock         sync.RWMutex
+	sites        map[string]*model.Site
+	baseCurrency string
+	rates        *model.ExchangeRates
 }
 
 // Config contains the configuration information needed to start
@@ -30,6 +33,12 @@ type Config struct {
 	DBURL       string `env:"DATABASE_URL,required"`
 	Port        int    `env:"PORT,default=8080"`
 	Credentials string `env:"CREDENTIALS_PATH"`
+
+	// Exchange rates are given against the base currency. If a rates
+	// file is given, the rates in it replace the stored rates at
+	// startup; rates can also be set by administrators.
+	BaseCurrency      string `env:"BASE_CURRENCY,default=EUR"`
+	ExchangeRatesFile string `env:"EXCHANGE_RATES_FILE"`
 }
 
 // NewServer creates the server structure for the user service.
@@ -41,10 +50,12 @@ func NewServer(cfg *Config) *Server {
 	// Connect to site database.
 	timeout, _ := context.WithTimeout(context.Background(), time.Second*10)
 	var err error
-	s.db, err = db.NewPGClient(timeout, cfg.DBURL)
+	pg, err := db.NewPGClient(timeout, cfg.DBURL)
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't connect to site database")
 	}
+	s.db = pg
+	s.StartEventRelay(pg.DB)
 
 	s.sites, err = s.db.Sites()
 	if err != nil {
@@ -55,6 +66,24 @@ func NewServer(cfg *Config) *Server {
 		log.Error().Err(err).Msg("failed emitting site update event")
 	}
 
+	s.baseCurrency = strings.ToUpper(cfg.BaseCurrency)
+	if cfg.ExchangeRatesFile != "" {
+		if err = s.loadExchangeRates(cfg.ExchangeRatesFile); err != nil {
+			log.Fatal().Err(err).Str("file", cfg.ExchangeRatesFile).
+				Msg("couldn't load exchange rates file")
+		}
+	}
+	s.rates, err = s.db.ExchangeRates()
+	if err != nil {
+		log.Fatal().Err(err).Msg("couldn't read exchange rates from database")
+	}
+	s.rates.Base = s.baseCurrency
+	s.rates.Rates[s.baseCurrency] = 1
+	err = chassis.Emit(s, events.ExchangeRatesUpdate, s.rates)
+	if err != nil {
+		log.Error().Err(err).Msg("failed emitting exchange rates update event")
+	}
+
 	return s
 }
 
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/social-service/model"
+	"github.com/veganbase/backend/services/social-service/model/types"
 )
 
 var ErrPostNotFound = errors.New("post not found")
@@ -12,6 +13,8 @@ var ErrUpvoteNotFound = errors.New("upvote not found")
 var ErrThreadNotFound = errors.New("thread not found")
 var ErrMessageNotFound = errors.New("message not found")
 var ErrAlreadyUpvoted = errors.New("already upvoted")
+var ErrAlreadyReported = errors.New("already reported")
+var ErrAlreadyResponded = errors.New("already has an official response")
 var ErrReadOnlyField = errors.New("attempt to modify read-only field")
 
 type DatabaseParams struct {
@@ -42,18 +45,28 @@ type DB interface {
 	PostById(postId string) (*model.Post, error)
 	UpdatePost(post *model.Post) error
 	DeletePost(postId string) error
+	HidePost(postId string, hidden bool) error
+	SetPostAnswer(postId string, answerId *string) error
+	MarkReviewsBought(owner string, subjects []string) ([]string, error)
 
 	CreateReply(reply *model.Reply) error
 	ReplyById(replyId string) (*model.Reply, error)
 	RepliesByParentId(parentId string) (*[]model.Reply, error)
 	UpdateReply(reply *model.Reply) error
 	DeleteReply(replyId string) error
+	RepliesByIDs(ids []string) (*[]model.Reply, error)
+	OfficialResponse(postId string) (*model.Reply, error)
+	HideReply(replyId string, hidden bool) error
 
 	ThreadByID(threadID string) (*model.Thread, error)
 	GetThreads(params *DatabaseParams) (*[]model.Thread, *uint, error)
 	CreateThread(th *model.Thread) error
 	UpdateThread(th *model.Thread) error
 	ChangeThreadStatus(threadID, status string) error
+	LockThread(threadID string, lock bool) error
+	ThreadInbox(participant string, status string, pagination *chassis.Pagination) (*[]model.InboxThread, *uint, error)
+	ThreadUnread(threadID string, readers []string) (map[string]int, error)
+	MarkThreadRead(threadID string, readers []string) error
 
 	MessageByID(messageID string) (*model.Message, error)
 	MessagesByParentID(parentId string) (*[]model.Message, error)
@@ -62,7 +75,19 @@ type DB interface {
 	DeleteMessage(msgID string) error
 	AuthorsByThreadID(ids []string) ([]string, error)
 
-	AvgReviewRank(subject string) (*float64, error)
+	AvgReviewRank(subject string, unverifiedWeight float64) (*float64, error)
+
+	//MODERATION
+	CreateReport(report *model.Report) error
+	ReportQueue(status types.ReportStatus, pagination *chassis.Pagination) (*[]model.ReportedContent, *uint, error)
+	ReportsByTargets(targetIDs []string, status types.ReportStatus) (*[]model.Report, error)
+	ResolveReports(targetID string, status types.ReportStatus, resolvedBy string) (int64, error)
+
+	//ACTIVITY FEED
+	AddFeedEntries(actor, kind, ref string, collection *string) (int64, error)
+	DeleteFeedEntries(ref string, collection *string) error
+	FeedEntries(userID string, before int64, limit uint) (*[]model.FeedEntry, error)
+	PostsByIDs(ids []string) (*[]model.Post, error)
 
 	SaveEvent(topic string, eventData interface{}, inTx func() error) error
 }
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/veganbase/backend/services/social-service/model"
+)
+
+const qAddFeedEntries = `
+INSERT INTO
+	feed_entries (user_id, actor, kind, ref, collection)
+SELECT user_id, $1, $2, $3, $4
+FROM subscriptions
+WHERE subscription_id = $1
+ON CONFLICT DO NOTHING
+`
+
+// AddFeedEntries adds an entry for something a user or organisation
+// did to the feeds of all their followers. Entries already in a feed
+// aren't repeated. Returns the number of feeds the entry was added to.
+func (pg *PGClient) AddFeedEntries(actor, kind, ref string, collection *string) (int64, error) {
+	result, err := pg.DB.Exec(qAddFeedEntries, actor, kind, ref, collection)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const qDeleteFeedEntries = `DELETE FROM feed_entries WHERE ref = $1`
+
+const qDeleteCollectionFeedEntries = `
+DELETE FROM feed_entries
+WHERE ref = $1 AND kind = 'collection' AND collection = $2
+`
+
+// DeleteFeedEntries removes the entries about an item or post from
+// all feeds. If a collection is given, only the entries for the
+// item's addition to that collection are removed.
+func (pg *PGClient) DeleteFeedEntries(ref string, collection *string) error {
+	var err error
+	if collection == nil {
+		_, err = pg.DB.Exec(qDeleteFeedEntries, ref)
+	} else {
+		_, err = pg.DB.Exec(qDeleteCollectionFeedEntries, ref, *collection)
+	}
+	return err
+}
+
+const qFeedEntries = `
+SELECT id, user_id, actor, kind, ref, collection, created_at
+FROM feed_entries
+WHERE user_id = $1 AND ($2 = 0 OR id < $2)
+ORDER BY id DESC
+LIMIT $3
+`
+
+// FeedEntries returns the entries in a user's feed, newest first,
+// starting after the entry with the given ID (or from the newest
+// entry if it's zero).
+func (pg *PGClient) FeedEntries(userID string, before int64, limit uint) (*[]model.FeedEntry, error) {
+	entries := []model.FeedEntry{}
+	if err := pg.DB.Select(&entries, qFeedEntries, userID, before, limit); err != nil {
+		return nil, err
+	}
+	return &entries, nil
+}
+
+// PostsByIDs returns the posts with the given IDs.
+func (pg *PGClient) PostsByIDs(ids []string) (*[]model.Post, error) {
+	posts := []model.Post{}
+	if len(ids) == 0 {
+		return &posts, nil
+	}
+	if err := pg.DB.Select(&posts, qPostBy+" WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+		return nil, err
+	}
+	return &posts, nil
+}
@@ -39,7 +39,10 @@ ON CONFLICT DO NOTHING
 RETURNING created_at
 `
 
-// CreateMessage creates a new message
+const qThreadLastMessage = `UPDATE threads SET last_message_at = $1 WHERE id = $2`
+
+// CreateMessage creates a new message, keeping track of when its
+// thread was last active.
 func (pg *PGClient) CreateMessage(msg *model.Message) error {
 	tx, err := pg.DB.Beginx()
 	if err != nil {
@@ -61,9 +64,13 @@ func (pg *PGClient) CreateMessage(msg *model.Message) error {
 	if rows.Next() {
 		err = rows.Scan(&msg.CreatedAt)
 		if err != nil {
+			rows.Close()
 			return err
 		}
 	}
+	rows.Close()
+
+	_, err = tx.Exec(qThreadLastMessage, msg.CreatedAt, msg.ParentID)
 	return err
 }
 
@@ -0,0 +1,30 @@
+-- +migrate Up
+
+SET ROLE vb_social;
+
+ALTER TABLE threads ADD COLUMN last_message_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+UPDATE threads t
+   SET last_message_at = COALESCE((SELECT MAX(m.created_at) FROM messages m WHERE m.parent_id = t.id),
+                                  t.created_at);
+
+CREATE INDEX threads_participants_index ON threads USING GIN (participants);
+
+-- When each participant (a user or an organisation) last read a
+-- thread, for counting unread messages.
+CREATE TABLE thread_reads
+(
+    thread_id    VARCHAR(24) NOT NULL REFERENCES threads (id) ON DELETE CASCADE,
+    reader       VARCHAR(24) NOT NULL,
+    last_read_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (thread_id, reader)
+);
+
+
+-- +migrate Down
+
+SET ROLE vb_social;
+
+DROP TABLE thread_reads;
+DROP INDEX threads_participants_index;
+ALTER TABLE threads DROP COLUMN last_message_at;
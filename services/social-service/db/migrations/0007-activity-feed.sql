@@ -0,0 +1,27 @@
+-- +migrate Up
+
+SET ROLE vb_social;
+
+-- Activity of followed users and organisations, copied into the feed
+-- of each of their followers as it happens.
+CREATE TABLE feed_entries
+(
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    VARCHAR(24) NOT NULL,
+    actor      VARCHAR(24) NOT NULL,
+    kind       TEXT        NOT NULL,
+    ref        TEXT        NOT NULL,
+    collection TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX feed_entries_unique_index ON feed_entries (user_id, kind, ref, COALESCE(collection, ''));
+CREATE INDEX feed_entries_user_index ON feed_entries (user_id, id DESC);
+CREATE INDEX feed_entries_ref_index ON feed_entries (ref);
+
+
+-- +migrate Down
+
+SET ROLE vb_social;
+
+DROP TABLE feed_entries;
@@ -0,0 +1,36 @@
+-- +migrate Up
+
+SET ROLE vb_social;
+
+-- Posts and replies hidden by a moderator stay in the database so
+-- that they can be restored.
+ALTER TABLE posts ADD COLUMN is_hidden BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE replies ADD COLUMN is_hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Abuse reports for posts and replies. Each user can report a given
+-- post or reply once.
+CREATE TABLE reports
+(
+    id          VARCHAR(24) PRIMARY KEY,
+    target_type VARCHAR(8)  NOT NULL,
+    target_id   VARCHAR(24) NOT NULL,
+    reporter    VARCHAR(24) NOT NULL,
+    reason      VARCHAR(16) NOT NULL,
+    details     TEXT,
+    status      VARCHAR(16) NOT NULL DEFAULT 'open',
+    resolved_by VARCHAR(24),
+    resolved_at TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (target_id, reporter)
+);
+
+CREATE INDEX reports_status_index ON reports (status);
+
+
+-- +migrate Down
+
+SET ROLE vb_social;
+
+DROP TABLE reports;
+ALTER TABLE replies DROP COLUMN is_hidden;
+ALTER TABLE posts DROP COLUMN is_hidden;
@@ -0,0 +1,24 @@
+-- +migrate Up
+
+SET ROLE vb_social;
+
+-- Replies made by item owners, on behalf of the user or organisation
+-- owning the item, as the official response to a review.
+ALTER TABLE replies ADD COLUMN is_official BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE replies ADD COLUMN official_for VARCHAR(24);
+
+CREATE UNIQUE INDEX reply_official_index ON replies (parent_id)
+  WHERE is_official AND NOT is_deleted;
+
+-- The reply item owners marked as the answer to a question.
+ALTER TABLE posts ADD COLUMN answer_id VARCHAR(24) REFERENCES replies (id) ON DELETE SET NULL;
+
+
+-- +migrate Down
+
+SET ROLE vb_social;
+
+ALTER TABLE posts DROP COLUMN answer_id;
+DROP INDEX reply_official_index;
+ALTER TABLE replies DROP COLUMN official_for;
+ALTER TABLE replies DROP COLUMN is_official;
@@ -2,13 +2,14 @@ package db
 
 import (
 	"database/sql"
+	"github.com/lib/pq"
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/social-service/model"
 	"math"
 )
 
 const qPostBy = `
-	SELECT id, post_type, owner, subject, is_edited, pictures, attrs, is_deleted, created_at
+	SELECT id, post_type, owner, subject, is_edited, pictures, attrs, is_deleted, is_hidden, answer_id, created_at
 	FROM posts `
 
 // PostByUser returns a post by its id.
@@ -25,10 +26,18 @@ func (pg *PGClient) PostById(postId string) (*model.Post, error) {
 
 
 
+// GetPosts lists posts matching search parameters. Posts hidden by
+// moderators are left out.
 func (pg *PGClient) GetPosts(params *DatabaseParams) (*[]model.Post, *uint, error) {
 	results := []model.Post{}
 	var total uint
-	q := qPostBy + paramsWhere(params) + paramsOrderBy(params)
+	where := paramsWhere(params)
+	if where == "" {
+		where = " WHERE is_hidden = FALSE"
+	} else {
+		where += " AND is_hidden = FALSE"
+	}
+	q := qPostBy + where + paramsOrderBy(params)
 	if params.Pagination != nil {
 		q += chassis.Paginate(params.Pagination.Page, params.Pagination.PerPage)
 	}
@@ -103,7 +112,7 @@ func (pg *PGClient) UpdatePost(post *model.Post) error {
 	}()
 
 	check := &model.Post{}
-	if err = tx.Get(check, qPostBy+`id = $1`, post.Id); err != nil {
+	if err = tx.Get(check, qPostBy+` WHERE id = $1`, post.Id); err != nil {
 		if err == sql.ErrNoRows {
 			return ErrPostNotFound
 		}
@@ -168,18 +177,87 @@ func (pg *PGClient) DeletePost(postId string) error {
 }
 
 
+const qHidePost = `UPDATE posts SET is_hidden = $2 WHERE id = $1`
+
+// HidePost hides a post from everyone but moderators, or makes a
+// hidden post visible again.
+func (pg *PGClient) HidePost(postId string, hidden bool) error {
+	result, err := pg.DB.Exec(qHidePost, postId, hidden)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+const qSetPostAnswer = `UPDATE posts SET answer_id = $2 WHERE id = $1`
+
+// SetPostAnswer marks a reply as the answer to a question, or clears
+// the answer if answerId is nil.
+func (pg *PGClient) SetPostAnswer(postId string, answerId *string) error {
+	result, err := pg.DB.Exec(qSetPostAnswer, postId, answerId)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+const qMarkReviewsBought = `
+UPDATE posts
+SET attrs = jsonb_set(attrs, '{user_bought}', 'true')
+WHERE post_type = 'review' AND owner = $1 AND subject = ANY($2)
+  AND COALESCE(attrs->>'user_bought', 'false') <> 'true'
+RETURNING subject
+`
+
+// MarkReviewsBought marks a user's reviews of items as verified
+// purchases, returning the items whose reviews changed.
+func (pg *PGClient) MarkReviewsBought(owner string, subjects []string) ([]string, error) {
+	changed := []string{}
+	if len(subjects) == 0 {
+		return changed, nil
+	}
+	if err := pg.DB.Select(&changed, qMarkReviewsBought, owner, pq.Array(subjects)); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// The average rank of reviews for a subject. Reviews from users who
+// haven't bought the subject count with a weight given as a parameter
+// (so that a weight of zero excludes them), and deleted or hidden
+// reviews aren't counted at all.
 const qAvgReviewRankBySubject = `
-SELECT COALESCE(AVG((attrs->'rank')::NUMERIC), 0) as rank
-FROM posts 
-WHERE post_type = 'review' AND subject = $1 `
+SELECT COALESCE(SUM((attrs->>'rank')::NUMERIC * weight) / NULLIF(SUM(weight), 0), 0) AS rank
+FROM (SELECT attrs,
+             CASE WHEN attrs->>'user_bought' = 'true' THEN 1 ELSE $2::NUMERIC END AS weight
+      FROM posts
+      WHERE post_type = 'review' AND subject = $1
+        AND is_deleted = FALSE AND is_hidden = FALSE) reviews
+`
 
 
-// AvgReviewRank returns the average rank of a given subject
-func (pg *PGClient) AvgReviewRank(subject string) (*float64, error) {
+// AvgReviewRank returns the average rank of a given subject, with
+// reviews by users who haven't bought it weighted by
+// unverifiedWeight.
+func (pg *PGClient) AvgReviewRank(subject string, unverifiedWeight float64) (*float64, error) {
 	result := struct {
 		Rank float64 `db:"rank"`
 	}{}
-	if err := pg.DB.Get(&result, qAvgReviewRankBySubject, subject); err != nil && err != sql.ErrNoRows{
+	if err := pg.DB.Get(&result, qAvgReviewRankBySubject, subject, unverifiedWeight); err != nil && err != sql.ErrNoRows{
 		return nil, err
 	}
 	roundedValue := math.Round(result.Rank*10)/10
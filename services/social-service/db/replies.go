@@ -2,13 +2,15 @@ package db
 
 import (
 	"database/sql"
+	"github.com/lib/pq"
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/social-service/model"
 )
 
 
 const qReplyBy = `
-	SELECT id, parent_id, owner, is_edited, is_deleted, pictures, attrs, created_at
+	SELECT id, parent_id, owner, is_edited, is_deleted, is_hidden, is_official, official_for,
+	       pictures, attrs, created_at
 	FROM replies
 	 `
 // ReplyById returns a reply with a certain id
@@ -32,16 +34,62 @@ func (pg *PGClient) RepliesByParentId(parentId string) (*[]model.Reply, error) {
 	return &replies, nil
 }
 
+// OfficialResponse returns the official response to a post, if it
+// has one.
+func (pg *PGClient) OfficialResponse(postId string) (*model.Reply, error) {
+	reply := model.Reply{}
+	q := qReplyBy + " WHERE parent_id = $1 AND is_official AND NOT is_deleted"
+	if err := pg.DB.Get(&reply, q, postId); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReplyNotFound
+		}
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// RepliesByIDs returns the replies with the given IDs.
+func (pg *PGClient) RepliesByIDs(ids []string) (*[]model.Reply, error) {
+	replies := []model.Reply{}
+	if len(ids) == 0 {
+		return &replies, nil
+	}
+	if err := pg.DB.Select(&replies, qReplyBy+" WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+		return nil, err
+	}
+	return &replies, nil
+}
+
+const qHideReply = `UPDATE replies SET is_hidden = $2 WHERE id = $1`
+
+// HideReply hides a reply from everyone but moderators, or makes a
+// hidden reply visible again.
+func (pg *PGClient) HideReply(replyId string, hidden bool) error {
+	result, err := pg.DB.Exec(qHideReply, replyId, hidden)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		return ErrReplyNotFound
+	}
+	return nil
+}
+
 
 const qCreateReply = `
 INSERT INTO
-	replies (id, parent_id, owner, is_edited, is_deleted, pictures, attrs)
-VALUES (:id, :parent_id, :owner, :is_edited, :is_deleted, :pictures, :attrs)
+	replies (id, parent_id, owner, is_edited, is_deleted, is_official, official_for, pictures, attrs)
+VALUES (:id, :parent_id, :owner, :is_edited, :is_deleted, :is_official, :official_for, :pictures, :attrs)
 ON CONFLICT DO NOTHING
 RETURNING created_at
 `
 
-// CreateReply creates a new reply
+// CreateReply creates a new reply. ErrAlreadyResponded is returned
+// for an official response to a review that already has one.
 func (pg *PGClient) CreateReply(reply *model.Reply) error {
 	tx, err := pg.DB.Beginx()
 	if err != nil {
@@ -60,12 +108,18 @@ func (pg *PGClient) CreateReply(reply *model.Reply) error {
 	if err != nil {
 		return err
 	}
-	if rows.Next() {
-		err = rows.Scan(&reply.CreatedAt)
-		if err != nil {
+	defer rows.Close()
+
+	// The only conflict that can happen is with the unique index
+	// allowing one official response per review.
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
 			return err
 		}
+		err = ErrAlreadyResponded
+		return err
 	}
+	err = rows.Scan(&reply.CreatedAt)
 	return err
 }
 
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"github.com/lib/pq"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/social-service/model"
+	"github.com/veganbase/backend/services/social-service/model/types"
+)
+
+const qCreateReport = `
+INSERT INTO
+	reports (id, target_type, target_id, reporter, reason, details, status)
+VALUES (:id, :target_type, :target_id, :reporter, :reason, :details, :status)
+ON CONFLICT DO NOTHING
+RETURNING created_at
+`
+
+// CreateReport saves a new abuse report. Users can only report each
+// post or reply once.
+func (pg *PGClient) CreateReport(report *model.Report) error {
+	report.ID = chassis.NewID("rpt")
+	rows, err := pg.DB.NamedQuery(qCreateReport, report)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		return ErrAlreadyReported
+	}
+	return rows.Scan(&report.CreatedAt)
+}
+
+const qReportQueue = `
+SELECT target_type, target_id, COUNT(*) AS report_count,
+       ARRAY_AGG(DISTINCT reason) AS reasons,
+       MIN(created_at) AS first_reported_at, MAX(created_at) AS last_reported_at
+FROM reports
+WHERE status = $1
+GROUP BY target_type, target_id
+ORDER BY COUNT(*) DESC, MIN(created_at) ASC`
+
+const qReportQueueCount = `
+SELECT COUNT(DISTINCT target_id) FROM reports WHERE status = $1`
+
+// ReportQueue lists the posts and replies that have reports with a
+// given status, most reported first.
+func (pg *PGClient) ReportQueue(status types.ReportStatus,
+	pagination *chassis.Pagination) (*[]model.ReportedContent, *uint, error) {
+	q := qReportQueue
+	if pagination != nil {
+		q += chassis.Paginate(pagination.Page, pagination.PerPage)
+	}
+
+	results := []model.ReportedContent{}
+	if err := pg.DB.Select(&results, q, status); err != nil {
+		return nil, nil, err
+	}
+	var total uint
+	if err := pg.DB.Get(&total, qReportQueueCount, status); err != nil {
+		return nil, nil, err
+	}
+	return &results, &total, nil
+}
+
+const qReportsByTargets = `
+SELECT id, target_type, target_id, reporter, reason, details, status,
+       resolved_by, resolved_at, created_at
+FROM reports
+WHERE target_id = ANY($1) AND status = $2
+ORDER BY created_at ASC`
+
+// ReportsByTargets returns the reports with a given status about a
+// list of posts and replies.
+func (pg *PGClient) ReportsByTargets(targetIDs []string, status types.ReportStatus) (*[]model.Report, error) {
+	reports := []model.Report{}
+	if len(targetIDs) == 0 {
+		return &reports, nil
+	}
+	if err := pg.DB.Select(&reports, qReportsByTargets, pq.Array(targetIDs), status); err != nil {
+		return nil, err
+	}
+	return &reports, nil
+}
+
+const qResolveReports = `
+UPDATE reports
+SET status = $2, resolved_by = $3, resolved_at = NOW()
+WHERE target_id = $1 AND status = 'open'`
+
+// ResolveReports closes all the open reports about a post or reply,
+// recording the moderator who dealt with them. Returns the number of
+// reports closed.
+func (pg *PGClient) ResolveReports(targetID string, status types.ReportStatus, resolvedBy string) (int64, error) {
+	result, err := pg.DB.Exec(qResolveReports, targetID, status, resolvedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -45,7 +45,13 @@ DELETE FROM subscriptions
 WHERE user_id = $1 AND subscription_id = $2
 `
 
-// DeleteUserSubscription removes a subscriptionID from the userID
+const qDeleteFollowedFeedEntries = `
+DELETE FROM feed_entries
+WHERE user_id = $1 AND actor = $2
+`
+
+// DeleteUserSubscription removes a subscriptionID from the userID,
+// along with the activity of subscriptionID in the user's feed.
 func (pg *PGClient) DeleteUserSubscription(userID, subscriptionID string) error {
 	tx, err := pg.DB.Beginx()
 	if err != nil {
@@ -61,6 +67,11 @@ func (pg *PGClient) DeleteUserSubscription(userID, subscriptionID string) error
 	}()
 
 	_, err = tx.Exec(qDeleteUserSubscription, userID, subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(qDeleteFollowedFeedEntries, userID, subscriptionID)
 
 	return err
 }
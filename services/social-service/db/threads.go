@@ -2,14 +2,17 @@ package db
 
 import (
 	"database/sql"
+	"strings"
+
+	"github.com/lib/pq"
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/social-service/model"
-
 )
 
 
 const qThreadBy = `
-	SELECT id, subject, author, content, attachments, lock_reply, participants, status, is_edited, created_at
+	SELECT id, subject, author, content, attachments, lock_reply, participants, status, is_edited,
+	       last_message_at, created_at
 	FROM threads `
 
 // PostByUser returns a thread by its id.
@@ -48,7 +51,7 @@ INSERT INTO
 	threads(id, subject, author, content, attachments, lock_reply, participants, status, is_edited)
 VALUES (:id, :subject, :author, :content, :attachments, :lock_reply, :participants, 'open', :is_edited)
 ON CONFLICT DO NOTHING
-RETURNING created_at
+RETURNING last_message_at, created_at
 `
 
 // CreateThread creates a new thread
@@ -71,8 +74,9 @@ func (pg *PGClient) CreateThread(th *model.Thread) error {
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 	if rows.Next() {
-		err = rows.Scan(&th.CreatedAt)
+		err = rows.Scan(&th.LastMessageAt, &th.CreatedAt)
 		if err != nil {
 			return err
 		}
@@ -157,3 +161,123 @@ func (pg *PGClient) ChangeThreadStatus(threadID, status string) error {
 	}
 	return err
 }
+
+const qThreadLock = `
+UPDATE threads
+SET lock_reply = $1
+WHERE id = $2
+`
+
+// LockThread locks a thread against new messages, or unlocks it.
+func (pg *PGClient) LockThread(threadID string, lock bool) error {
+	result, err := pg.DB.Exec(qThreadLock, lock, threadID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		return ErrThreadNotFound
+	}
+	return nil
+}
+
+// qUnread counts the messages in a thread that a reader hasn't read,
+// with the thread's opening message counted as unread until the
+// thread is first read. READER is replaced by the SQL expression for
+// the reader's ID, and the thread_reads row for the reader must be
+// joined as r.
+const qUnread = `
+	(CASE WHEN r.last_read_at IS NULL AND t.author <> READER THEN 1 ELSE 0 END) +
+	(SELECT COUNT(*) FROM messages m
+	  WHERE m.parent_id = t.id AND m.author <> READER AND NOT m.is_deleted
+	    AND m.created_at > COALESCE(r.last_read_at, '-infinity'))`
+
+func unreadCount(reader string) string {
+	return strings.Replace(qUnread, "READER", reader, -1)
+}
+
+const qInbox = `
+	SELECT t.id, t.subject, t.author, t.content, t.attachments, t.lock_reply, t.participants,
+	       t.status, t.is_edited, t.last_message_at, t.created_at,
+	       UNREAD AS unread
+	FROM threads t
+	LEFT JOIN thread_reads r ON r.thread_id = t.id AND r.reader = $1
+	WHERE $1 = ANY(t.participants) AND t.status <> 'deleted'`
+
+// ThreadInbox lists the threads a user or organisation takes part in,
+// most recently active first, with the number of messages they
+// haven't read in each. Deleted threads are left out, and the threads
+// can be restricted to those with a given status.
+func (pg *PGClient) ThreadInbox(participant string, status string,
+	pagination *chassis.Pagination) (*[]model.InboxThread, *uint, error) {
+	q := strings.Replace(qInbox, "UNREAD", unreadCount("$1"), 1)
+	qCount := `SELECT COUNT(*) FROM threads t WHERE $1 = ANY(t.participants) AND t.status <> 'deleted'`
+	args := []interface{}{participant}
+	if status != "" {
+		q += ` AND t.status = $2`
+		qCount += ` AND t.status = $2`
+		args = append(args, status)
+	}
+	q += ` ORDER BY t.last_message_at DESC`
+	if pagination != nil {
+		q += chassis.Paginate(pagination.Page, pagination.PerPage)
+	}
+
+	results := []model.InboxThread{}
+	if err := pg.DB.Select(&results, q, args...); err != nil {
+		return nil, nil, err
+	}
+	var total uint
+	if err := pg.DB.Get(&total, qCount, args...); err != nil {
+		return nil, nil, err
+	}
+	return &results, &total, nil
+}
+
+const qThreadUnread = `
+	SELECT p.reader, UNREAD AS unread
+	FROM threads t
+	CROSS JOIN unnest($2::text[]) AS p(reader)
+	LEFT JOIN thread_reads r ON r.thread_id = t.id AND r.reader = p.reader
+	WHERE t.id = $1`
+
+// ThreadUnread returns the number of messages in a thread each of a
+// list of participants hasn't read.
+func (pg *PGClient) ThreadUnread(threadID string, readers []string) (map[string]int, error) {
+	rows, err := pg.DB.Queryx(strings.Replace(qThreadUnread, "UNREAD", unreadCount("p.reader"), 1),
+		threadID, pq.Array(readers))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	unread := map[string]int{}
+	for rows.Next() {
+		reader := ""
+		count := 0
+		if err = rows.Scan(&reader, &count); err != nil {
+			return nil, err
+		}
+		unread[reader] = count
+	}
+	return unread, rows.Err()
+}
+
+const qMarkThreadRead = `
+INSERT INTO thread_reads (thread_id, reader, last_read_at)
+SELECT $1, reader, NOW() FROM unnest($2::text[]) AS reader
+ON CONFLICT (thread_id, reader) DO UPDATE SET last_read_at = EXCLUDED.last_read_at
+`
+
+// MarkThreadRead records that participants in a thread have read all
+// the messages in it.
+func (pg *PGClient) MarkThreadRead(threadID string, readers []string) error {
+	if len(readers) == 0 {
+		return nil
+	}
+	_, err := pg.DB.Exec(qMarkThreadRead, threadID, pq.Array(readers))
+	return err
+}
@@ -11,8 +11,26 @@ const (
 	ReplyCreated  = "reply-created"
 	ReplyDeleted  = "reply-deleted"
 	ReplyUpdated  = "reply-updated"
+	ThreadCreated  = "thread-created"
+	ThreadUpdated  = "thread-updated"
+	MessageCreated = "message-created"
+	ReportCreated  = "report-created"
+	PostHidden     = "post-hidden"
+	PostRestored   = "post-restored"
+	ReplyHidden    = "reply-hidden"
+	ReplyRestored  = "reply-restored"
+	PostAnswered   = "post-answered"
 )
 
+// NewMessage events carry the emails sent to thread participants who
+// have a new message to read.
+const NewMessage = "new-message"
+
+// QuestionAnswered events carry the emails sent to the askers of
+// questions about items, and to the items' followers, when an item
+// owner chooses an answer.
+const QuestionAnswered = "question-answered"
+
 const (
 	ItemRankTopic = "item-rank-topic"
 	ItemUpvoteTopic = "item-upvote-topic"
@@ -19,5 +19,8 @@ func main() {
 	}
 	chassis.LogSetup(appname, cfg.DevMode)
 	serv := server.NewServer(&cfg)
+	go serv.HandleFeedItemEvents()
+	go serv.HandleFeedPostEvents()
+	go serv.HandlePurchaseEvents()
 	serv.Serve()
 }
@@ -4,9 +4,12 @@ package mocks
 
 import (
 	mock "github.com/stretchr/testify/mock"
+	chassis "github.com/veganbase/backend/chassis"
 	db "github.com/veganbase/backend/services/social-service/db"
 
 	model "github.com/veganbase/backend/services/social-service/model"
+
+	types "github.com/veganbase/backend/services/social-service/model/types"
 )
 
 // DB is an autogenerated mock type for the DB type
@@ -14,13 +17,57 @@ type DB struct {
 	mock.Mock
 }
 
-// AvgReviewRank provides a mock function with given fields: subject
-func (_m *DB) AvgReviewRank(subject string) (*float64, error) {
-	ret := _m.Called(subject)
+// AddFeedEntries provides a mock function with given fields: actor, kind, ref, collection
+func (_m *DB) AddFeedEntries(actor string, kind string, ref string, collection *string) (int64, error) {
+	ret := _m.Called(actor, kind, ref, collection)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(string, string, string, *string) int64); ok {
+		r0 = rf(actor, kind, ref, collection)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string, *string) error); ok {
+		r1 = rf(actor, kind, ref, collection)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthorsByThreadID provides a mock function with given fields: ids
+func (_m *DB) AuthorsByThreadID(ids []string) ([]string, error) {
+	ret := _m.Called(ids)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func([]string) []string); ok {
+		r0 = rf(ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]string) error); ok {
+		r1 = rf(ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AvgReviewRank provides a mock function with given fields: subject, unverifiedWeight
+func (_m *DB) AvgReviewRank(subject string, unverifiedWeight float64) (*float64, error) {
+	ret := _m.Called(subject, unverifiedWeight)
 
 	var r0 *float64
-	if rf, ok := ret.Get(0).(func(string) *float64); ok {
-		r0 = rf(subject)
+	if rf, ok := ret.Get(0).(func(string, float64) *float64); ok {
+		r0 = rf(subject, unverifiedWeight)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*float64)
@@ -28,8 +75,8 @@ func (_m *DB) AvgReviewRank(subject string) (*float64, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(subject)
+	if rf, ok := ret.Get(1).(func(string, float64) error); ok {
+		r1 = rf(subject, unverifiedWeight)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -37,6 +84,34 @@ func (_m *DB) AvgReviewRank(subject string) (*float64, error) {
 	return r0, r1
 }
 
+// ChangeThreadStatus provides a mock function with given fields: threadID, status
+func (_m *DB) ChangeThreadStatus(threadID string, status string) error {
+	ret := _m.Called(threadID, status)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(threadID, status)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateMessage provides a mock function with given fields: msg
+func (_m *DB) CreateMessage(msg *model.Message) error {
+	ret := _m.Called(msg)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.Message) error); ok {
+		r0 = rf(msg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreatePost provides a mock function with given fields: post
 func (_m *DB) CreatePost(post *model.Post) error {
 	ret := _m.Called(post)
@@ -65,6 +140,34 @@ func (_m *DB) CreateReply(reply *model.Reply) error {
 	return r0
 }
 
+// CreateReport provides a mock function with given fields: report
+func (_m *DB) CreateReport(report *model.Report) error {
+	ret := _m.Called(report)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.Report) error); ok {
+		r0 = rf(report)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateThread provides a mock function with given fields: th
+func (_m *DB) CreateThread(th *model.Thread) error {
+	ret := _m.Called(th)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.Thread) error); ok {
+		r0 = rf(th)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreateUpvote provides a mock function with given fields: upvote
 func (_m *DB) CreateUpvote(upvote *model.Upvote) error {
 	ret := _m.Called(upvote)
@@ -93,6 +196,34 @@ func (_m *DB) CreateUserSubscription(userID string, subscriptionID string) error
 	return r0
 }
 
+// DeleteFeedEntries provides a mock function with given fields: ref, collection
+func (_m *DB) DeleteFeedEntries(ref string, collection *string) error {
+	ret := _m.Called(ref, collection)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, *string) error); ok {
+		r0 = rf(ref, collection)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteMessage provides a mock function with given fields: msgID
+func (_m *DB) DeleteMessage(msgID string) error {
+	ret := _m.Called(msgID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(msgID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeletePost provides a mock function with given fields: postId
 func (_m *DB) DeletePost(postId string) error {
 	ret := _m.Called(postId)
@@ -149,6 +280,29 @@ func (_m *DB) DeleteUserSubscription(userID string, subscriptionID string) error
 	return r0
 }
 
+// FeedEntries provides a mock function with given fields: userID, before, limit
+func (_m *DB) FeedEntries(userID string, before int64, limit uint) (*[]model.FeedEntry, error) {
+	ret := _m.Called(userID, before, limit)
+
+	var r0 *[]model.FeedEntry
+	if rf, ok := ret.Get(0).(func(string, int64, uint) *[]model.FeedEntry); ok {
+		r0 = rf(userID, before, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*[]model.FeedEntry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int64, uint) error); ok {
+		r1 = rf(userID, before, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetPosts provides a mock function with given fields: params
 func (_m *DB) GetPosts(params *db.DatabaseParams) (*[]model.Post, *uint, error) {
 	ret := _m.Called(params)
@@ -181,6 +335,66 @@ func (_m *DB) GetPosts(params *db.DatabaseParams) (*[]model.Post, *uint, error)
 	return r0, r1, r2
 }
 
+// GetThreads provides a mock function with given fields: params
+func (_m *DB) GetThreads(params *db.DatabaseParams) (*[]model.Thread, *uint, error) {
+	ret := _m.Called(params)
+
+	var r0 *[]model.Thread
+	if rf, ok := ret.Get(0).(func(*db.DatabaseParams) *[]model.Thread); ok {
+		r0 = rf(params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*[]model.Thread)
+		}
+	}
+
+	var r1 *uint
+	if rf, ok := ret.Get(1).(func(*db.DatabaseParams) *uint); ok {
+		r1 = rf(params)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*uint)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*db.DatabaseParams) error); ok {
+		r2 = rf(params)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// HidePost provides a mock function with given fields: postId, hidden
+func (_m *DB) HidePost(postId string, hidden bool) error {
+	ret := _m.Called(postId, hidden)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, bool) error); ok {
+		r0 = rf(postId, hidden)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HideReply provides a mock function with given fields: replyId, hidden
+func (_m *DB) HideReply(replyId string, hidden bool) error {
+	ret := _m.Called(replyId, hidden)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, bool) error); ok {
+		r0 = rf(replyId, hidden)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ListFollowers provides a mock function with given fields: targetID
 func (_m *DB) ListFollowers(targetID string) ([]string, error) {
 	ret := _m.Called(targetID)
@@ -273,6 +487,126 @@ func (_m *DB) ListWhoUpvoted(itemId string) ([]string, error) {
 	return r0, r1
 }
 
+// LockThread provides a mock function with given fields: threadID, lock
+func (_m *DB) LockThread(threadID string, lock bool) error {
+	ret := _m.Called(threadID, lock)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, bool) error); ok {
+		r0 = rf(threadID, lock)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkReviewsBought provides a mock function with given fields: owner, subjects
+func (_m *DB) MarkReviewsBought(owner string, subjects []string) ([]string, error) {
+	ret := _m.Called(owner, subjects)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string, []string) []string); ok {
+		r0 = rf(owner, subjects)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []string) error); ok {
+		r1 = rf(owner, subjects)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkThreadRead provides a mock function with given fields: threadID, readers
+func (_m *DB) MarkThreadRead(threadID string, readers []string) error {
+	ret := _m.Called(threadID, readers)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []string) error); ok {
+		r0 = rf(threadID, readers)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MessageByID provides a mock function with given fields: messageID
+func (_m *DB) MessageByID(messageID string) (*model.Message, error) {
+	ret := _m.Called(messageID)
+
+	var r0 *model.Message
+	if rf, ok := ret.Get(0).(func(string) *model.Message); ok {
+		r0 = rf(messageID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Message)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(messageID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MessagesByParentID provides a mock function with given fields: parentId
+func (_m *DB) MessagesByParentID(parentId string) (*[]model.Message, error) {
+	ret := _m.Called(parentId)
+
+	var r0 *[]model.Message
+	if rf, ok := ret.Get(0).(func(string) *[]model.Message); ok {
+		r0 = rf(parentId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*[]model.Message)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(parentId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OfficialResponse provides a mock function with given fields: postId
+func (_m *DB) OfficialResponse(postId string) (*model.Reply, error) {
+	ret := _m.Called(postId)
+
+	var r0 *model.Reply
+	if rf, ok := ret.Get(0).(func(string) *model.Reply); ok {
+		r0 = rf(postId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Reply)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(postId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // PostById provides a mock function with given fields: postId
 func (_m *DB) PostById(postId string) (*model.Post, error) {
 	ret := _m.Called(postId)
@@ -296,6 +630,52 @@ func (_m *DB) PostById(postId string) (*model.Post, error) {
 	return r0, r1
 }
 
+// PostsByIDs provides a mock function with given fields: ids
+func (_m *DB) PostsByIDs(ids []string) (*[]model.Post, error) {
+	ret := _m.Called(ids)
+
+	var r0 *[]model.Post
+	if rf, ok := ret.Get(0).(func([]string) *[]model.Post); ok {
+		r0 = rf(ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*[]model.Post)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]string) error); ok {
+		r1 = rf(ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RepliesByIDs provides a mock function with given fields: ids
+func (_m *DB) RepliesByIDs(ids []string) (*[]model.Reply, error) {
+	ret := _m.Called(ids)
+
+	var r0 *[]model.Reply
+	if rf, ok := ret.Get(0).(func([]string) *[]model.Reply); ok {
+		r0 = rf(ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*[]model.Reply)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]string) error); ok {
+		r1 = rf(ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RepliesByParentId provides a mock function with given fields: parentId
 func (_m *DB) RepliesByParentId(parentId string) (*[]model.Reply, error) {
 	ret := _m.Called(parentId)
@@ -342,6 +722,82 @@ func (_m *DB) ReplyById(replyId string) (*model.Reply, error) {
 	return r0, r1
 }
 
+// ReportQueue provides a mock function with given fields: status, pagination
+func (_m *DB) ReportQueue(status types.ReportStatus, pagination *chassis.Pagination) (*[]model.ReportedContent, *uint, error) {
+	ret := _m.Called(status, pagination)
+
+	var r0 *[]model.ReportedContent
+	if rf, ok := ret.Get(0).(func(types.ReportStatus, *chassis.Pagination) *[]model.ReportedContent); ok {
+		r0 = rf(status, pagination)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*[]model.ReportedContent)
+		}
+	}
+
+	var r1 *uint
+	if rf, ok := ret.Get(1).(func(types.ReportStatus, *chassis.Pagination) *uint); ok {
+		r1 = rf(status, pagination)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*uint)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(types.ReportStatus, *chassis.Pagination) error); ok {
+		r2 = rf(status, pagination)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ReportsByTargets provides a mock function with given fields: targetIDs, status
+func (_m *DB) ReportsByTargets(targetIDs []string, status types.ReportStatus) (*[]model.Report, error) {
+	ret := _m.Called(targetIDs, status)
+
+	var r0 *[]model.Report
+	if rf, ok := ret.Get(0).(func([]string, types.ReportStatus) *[]model.Report); ok {
+		r0 = rf(targetIDs, status)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*[]model.Report)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]string, types.ReportStatus) error); ok {
+		r1 = rf(targetIDs, status)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResolveReports provides a mock function with given fields: targetID, status, resolvedBy
+func (_m *DB) ResolveReports(targetID string, status types.ReportStatus, resolvedBy string) (int64, error) {
+	ret := _m.Called(targetID, status, resolvedBy)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(string, types.ReportStatus, string) int64); ok {
+		r0 = rf(targetID, status, resolvedBy)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, types.ReportStatus, string) error); ok {
+		r1 = rf(targetID, status, resolvedBy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SaveEvent provides a mock function with given fields: topic, eventData, inTx
 func (_m *DB) SaveEvent(topic string, eventData interface{}, inTx func() error) error {
 	ret := _m.Called(topic, eventData, inTx)
@@ -356,6 +812,112 @@ func (_m *DB) SaveEvent(topic string, eventData interface{}, inTx func() error)
 	return r0
 }
 
+// SetPostAnswer provides a mock function with given fields: postId, answerId
+func (_m *DB) SetPostAnswer(postId string, answerId *string) error {
+	ret := _m.Called(postId, answerId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, *string) error); ok {
+		r0 = rf(postId, answerId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ThreadByID provides a mock function with given fields: threadID
+func (_m *DB) ThreadByID(threadID string) (*model.Thread, error) {
+	ret := _m.Called(threadID)
+
+	var r0 *model.Thread
+	if rf, ok := ret.Get(0).(func(string) *model.Thread); ok {
+		r0 = rf(threadID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Thread)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(threadID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ThreadInbox provides a mock function with given fields: participant, status, pagination
+func (_m *DB) ThreadInbox(participant string, status string, pagination *chassis.Pagination) (*[]model.InboxThread, *uint, error) {
+	ret := _m.Called(participant, status, pagination)
+
+	var r0 *[]model.InboxThread
+	if rf, ok := ret.Get(0).(func(string, string, *chassis.Pagination) *[]model.InboxThread); ok {
+		r0 = rf(participant, status, pagination)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*[]model.InboxThread)
+		}
+	}
+
+	var r1 *uint
+	if rf, ok := ret.Get(1).(func(string, string, *chassis.Pagination) *uint); ok {
+		r1 = rf(participant, status, pagination)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*uint)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, *chassis.Pagination) error); ok {
+		r2 = rf(participant, status, pagination)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ThreadUnread provides a mock function with given fields: threadID, readers
+func (_m *DB) ThreadUnread(threadID string, readers []string) (map[string]int, error) {
+	ret := _m.Called(threadID, readers)
+
+	var r0 map[string]int
+	if rf, ok := ret.Get(0).(func(string, []string) map[string]int); ok {
+		r0 = rf(threadID, readers)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]int)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []string) error); ok {
+		r1 = rf(threadID, readers)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateMessage provides a mock function with given fields: msg
+func (_m *DB) UpdateMessage(msg *model.Message) error {
+	ret := _m.Called(msg)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.Message) error); ok {
+		r0 = rf(msg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdatePost provides a mock function with given fields: post
 func (_m *DB) UpdatePost(post *model.Post) error {
 	ret := _m.Called(post)
@@ -384,6 +946,20 @@ func (_m *DB) UpdateReply(reply *model.Reply) error {
 	return r0
 }
 
+// UpdateThread provides a mock function with given fields: th
+func (_m *DB) UpdateThread(th *model.Thread) error {
+	ret := _m.Called(th)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.Thread) error); ok {
+		r0 = rf(th)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpvoteByUserAndItemId provides a mock function with given fields: userId, itemId
 func (_m *DB) UpvoteByUserAndItemId(userId string, itemId string) (*model.Upvote, error) {
 	ret := _m.Called(userId, itemId)
@@ -8,6 +8,15 @@ import (
 	"github.com/veganbase/backend/chassis"
 )
 
+// Attachment types: blobs are pictures or files uploaded to the blob
+// service, referred to by URL, and items and orders are referred to
+// by ID.
+const (
+	BlobAttachment  = "blob"
+	ItemAttachment  = "item"
+	OrderAttachment = "order"
+)
+
 // Attachment is used to represent blobs, products or orders/bookings attached to a message or thread
 type AttachmentFixed struct {
 	AttachType string             `json:"type"`
@@ -31,7 +40,17 @@ func (a *Attachment) UnmarshalJSON(data []byte) error {
 	if err = chassis.StringField(&a.Ref, fields, "ref"); err != nil {
 		return errors.Wrap(err, "invalid field ref")
 	}
+	switch a.AttachType {
+	case BlobAttachment, ItemAttachment, OrderAttachment:
+	default:
+		return errors.New("unknown attachment type '" + a.AttachType + "'")
+	}
+	if a.Ref == "" {
+		return errors.New("missing attachment ref")
+	}
 
+	delete(fields, "type")
+	delete(fields, "ref")
 	a.Attrs = fields
 
 	return nil
@@ -71,6 +90,17 @@ func (a *Attachment) MarshalJSON() ([]byte, error) {
 }
 
 type Attachments []Attachment
+
+// Blobs returns the URLs of the blobs attached.
+func (a Attachments) Blobs() []string {
+	blobs := []string{}
+	for _, att := range a {
+		if att.AttachType == BlobAttachment {
+			blobs = append(blobs, att.Ref)
+		}
+	}
+	return blobs
+}
 // Make the Attachments struct implement the driver.Valuer interface. This method
 // simply returns the JSON-encoded representation of the struct.
 func (a Attachments) Value() (driver.Value, error) {
@@ -80,6 +110,10 @@ func (a Attachments) Value() (driver.Value, error) {
 // Make the Attachments struct implement the sql.Scanner interface. This method
 // simply decodes a JSON-encoded value into the struct fields.
 func (a *Attachments) Scan(value interface{}) error {
+	if value == nil {
+		*a = Attachments{}
+		return nil
+	}
 	b, ok := value.([]byte)
 	if !ok {
 		return errors.New("type assertion to []byte failed")
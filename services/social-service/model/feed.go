@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	itemModel "github.com/veganbase/backend/services/item-service/model"
+	userModel "github.com/veganbase/backend/services/user-service/model"
+)
+
+// Kinds of activity shown in feeds.
+const (
+	FeedItem       = "item"
+	FeedPost       = "post"
+	FeedReview     = "review"
+	FeedCollection = "collection"
+)
+
+// FeedEntry is an entry in a user's activity feed: something done by
+// a user or organisation they follow. The reference is to an item for
+// new items and collection additions, and to a post for posts and
+// reviews.
+type FeedEntry struct {
+	ID         int64     `db:"id" json:"id"`
+	UserID     string    `db:"user_id" json:"user_id"`
+	Actor      string    `db:"actor" json:"actor"`
+	Kind       string    `db:"kind" json:"kind"`
+	Ref        string    `db:"ref" json:"ref"`
+	Collection *string   `db:"collection" json:"collection,omitempty"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// FeedEntryView is a feed entry with information about who did what.
+type FeedEntryView struct {
+	Kind       string          `json:"kind"`
+	Actor      *userModel.Info `json:"actor"`
+	Item       *itemModel.Info `json:"item,omitempty"`
+	Post       *Post           `json:"post,omitempty"`
+	Collection *string         `json:"collection,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Feed is a page of a user's activity feed, newest first. The next
+// cursor is given when there are older entries to fetch.
+type Feed struct {
+	Entries    []FeedEntryView `json:"entries"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
@@ -18,6 +18,8 @@ type PostFixed struct {
 	IsEdited  bool           `db:"is_edited" json:"is_edited"`
 	Pictures  pq.StringArray `db:"pictures" json:"pictures"`
 	IsDeleted bool           `db:"is_deleted" json:"is_deleted"`
+	IsHidden  bool           `db:"is_hidden" json:"is_hidden"`
+	AnswerId  *string        `db:"answer_id" json:"answer_id,omitempty"`
 	CreatedAt time.Time      `db:"created_at" json:"created_at"`
 }
 type Post struct {
@@ -37,6 +39,9 @@ func (p *Post) UnmarshalJSON(data []byte) error {
 	roBad := []string{}
 	readOnlyField(fields, "id", &roBad)
 	readOnlyField(fields, "owner", &roBad)
+	readOnlyField(fields, "is_hidden", &roBad)
+	readOnlyField(fields, "user_bought", &roBad)
+	readOnlyField(fields, "answer_id", &roBad)
 	readOnlyField(fields, "created_at", &roBad)
 	if len(roBad) > 0 {
 		return errors.New("attempt to set read-only fields: " + strings.Join(roBad, ","))
@@ -111,11 +116,14 @@ func (p *Post) Patch(patch []byte) error {
 
 	// Step 2.
 	roFields := map[string]string{
-		"id":         "ID",
-		"owner":      "owner",
-		"subject":    "subject",
-		"post_type":  "post_type",
-		"created_at": "creation date",
+		"id":          "ID",
+		"owner":       "owner",
+		"subject":     "subject",
+		"post_type":   "post_type",
+		"is_hidden":   "hidden flag",
+		"user_bought": "verified purchase flag",
+		"answer_id":   "answer",
+		"created_at":  "creation date",
 	}
 	for fld, label := range roFields {
 		if _, ok := updates[fld]; ok {
@@ -11,13 +11,16 @@ import (
 )
 
 type ReplyFixed struct {
-	Id        string             `db:"id" json:"id"`
-	ParentId  string             `db:"parent_id" json:"parent_id"`
-	Owner     string             `db:"owner" json:"owner"`
-	IsEdited  bool               `db:"is_edited" json:"is_edited"`
-	Pictures  pq.StringArray     `db:"pictures" json:"pictures"`
-	IsDeleted bool               `db:"is_deleted" json:"is_deleted"`
-	CreatedAt time.Time          `db:"created_at" json:"created_at"`
+	Id          string         `db:"id" json:"id"`
+	ParentId    string         `db:"parent_id" json:"parent_id"`
+	Owner       string         `db:"owner" json:"owner"`
+	IsEdited    bool           `db:"is_edited" json:"is_edited"`
+	Pictures    pq.StringArray `db:"pictures" json:"pictures"`
+	IsDeleted   bool           `db:"is_deleted" json:"is_deleted"`
+	IsHidden    bool           `db:"is_hidden" json:"is_hidden"`
+	IsOfficial  bool           `db:"is_official" json:"is_official"`
+	OfficialFor *string        `db:"official_for" json:"official_for,omitempty"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
 }
 type Reply struct {
 	ReplyFixed
@@ -36,6 +39,9 @@ func (rpl *Reply) UnmarshalJSON(data []byte) error {
 	roBad := []string{}
 	readOnlyField(fields, "id", &roBad)
 	readOnlyField(fields, "owner", &roBad)
+	readOnlyField(fields, "is_hidden", &roBad)
+	readOnlyField(fields, "is_official", &roBad)
+	readOnlyField(fields, "official_for", &roBad)
 	readOnlyField(fields, "created_at", &roBad)
 	if len(roBad) > 0 {
 		return errors.New("attempt to set read-only fields: " + strings.Join(roBad, ","))
@@ -104,11 +110,14 @@ func (rpl *Reply) Patch(patch []byte) error {
 
 	// Step 2.
 	roFields := map[string]string{
-		"id":         "ID",
-		"owner":      "owner",
-		"subject":    "subject",
-		"post_type":  "post_type",
-		"created_at": "creation date",
+		"id":           "ID",
+		"owner":        "owner",
+		"subject":      "subject",
+		"post_type":    "post_type",
+		"is_hidden":    "hidden flag",
+		"is_official":  "official response flag",
+		"official_for": "official response owner",
+		"created_at":   "creation date",
 	}
 	for fld, label := range roFields {
 		if _, ok := updates[fld]; ok {
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/veganbase/backend/services/social-service/model/types"
+)
+
+// Kinds of content that can be reported.
+const (
+	ReportedPost  = "post"
+	ReportedReply = "reply"
+)
+
+// MaxReportDetailsLength is the maximum length, in characters, of
+// the free text explanation included in a report.
+const MaxReportDetailsLength = 2000
+
+// Report is an abuse report made by a user about a post or a reply.
+type Report struct {
+	ID         string             `db:"id" json:"id"`
+	TargetType string             `db:"target_type" json:"target_type"`
+	TargetID   string             `db:"target_id" json:"target_id"`
+	Reporter   string             `db:"reporter" json:"reporter"`
+	Reason     types.ReportReason `db:"reason" json:"reason"`
+	Details    *string            `db:"details" json:"details,omitempty"`
+	Status     types.ReportStatus `db:"status" json:"status"`
+	ResolvedBy *string            `db:"resolved_by" json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time         `db:"resolved_at" json:"resolved_at,omitempty"`
+	CreatedAt  time.Time          `db:"created_at" json:"created_at"`
+}
+
+// UnmarshalJSON unmarshals a report from the body of a report
+// request. Only the reason and details can be given: everything else
+// is filled in from the request context.
+func (r *Report) UnmarshalJSON(data []byte) error {
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return errors.New("invalid JSON in report data")
+	}
+
+	roBad := []string{}
+	for _, fld := range []string{"id", "target_type", "target_id", "reporter", "status",
+		"resolved_by", "resolved_at", "created_at"} {
+		readOnlyField(fields, fld, &roBad)
+	}
+	if len(roBad) > 0 {
+		return errors.New("attempt to set read-only fields: " + strings.Join(roBad, ","))
+	}
+
+	*r = Report{Status: types.ReportOpen}
+	reason := ""
+	if err := stringField(&reason, fields, "reason"); err != nil {
+		return err
+	}
+	if reason == "" {
+		return errors.New("missing report reason")
+	}
+	if err := r.Reason.FromString(reason); err != nil {
+		return err
+	}
+
+	details := ""
+	if err := stringField(&details, fields, "details"); err != nil {
+		return err
+	}
+	details = strings.TrimSpace(details)
+	if len([]rune(details)) > MaxReportDetailsLength {
+		return errors.New("report details too long")
+	}
+	if details != "" {
+		r.Details = &details
+	}
+	if r.Reason == types.OtherReason && r.Details == nil {
+		return errors.New("details are required for reports with reason 'other'")
+	}
+
+	if len(fields) > 0 {
+		unknown := []string{}
+		for k := range fields {
+			unknown = append(unknown, k)
+		}
+		return errors.New("unknown fields in report: " + strings.Join(unknown, ","))
+	}
+	return nil
+}
+
+// ReportedContent summarises the reports made about a post or reply,
+// for the moderation queue.
+type ReportedContent struct {
+	TargetType      string         `db:"target_type" json:"target_type"`
+	TargetID        string         `db:"target_id" json:"target_id"`
+	ReportCount     int            `db:"report_count" json:"report_count"`
+	Reasons         pq.StringArray `db:"reasons" json:"reasons"`
+	FirstReportedAt time.Time      `db:"first_reported_at" json:"first_reported_at"`
+	LastReportedAt  time.Time      `db:"last_reported_at" json:"last_reported_at"`
+	Post            *Post          `db:"-" json:"post,omitempty"`
+	Reply           *Reply         `db:"-" json:"reply,omitempty"`
+	Reports         []Report       `db:"-" json:"reports"`
+}
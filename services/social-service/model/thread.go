@@ -12,16 +12,24 @@ import (
 
 //Thread is a representation of a conversation between entities (users and orgs)
 type Thread struct {
-	ID           string         `db:"id" json:"id"`
-	Subject      string         `db:"subject" json:"subject"`
-	Author       string         `db:"author" json:"author"`
-	Content      string         `db:"content" json:"content"`
-	Attachments  Attachments    `db:"attachments" json:"attachments"`
-	LockReply    bool           `db:"lock_reply" json:"lock_reply"`
-	Participants pq.StringArray `db:"participants" json:"participants"`
-	Status       t.ThreadStatus `db:"status" json:"status"`
-	IsEdited     bool           `db:"is_edited" json:"is_edited"`
-	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
+	ID            string         `db:"id" json:"id"`
+	Subject       string         `db:"subject" json:"subject"`
+	Author        string         `db:"author" json:"author"`
+	Content       string         `db:"content" json:"content"`
+	Attachments   Attachments    `db:"attachments" json:"attachments"`
+	LockReply     bool           `db:"lock_reply" json:"lock_reply"`
+	Participants  pq.StringArray `db:"participants" json:"participants"`
+	Status        t.ThreadStatus `db:"status" json:"status"`
+	IsEdited      bool           `db:"is_edited" json:"is_edited"`
+	LastMessageAt time.Time      `db:"last_message_at" json:"last_message_at"`
+	CreatedAt     time.Time      `db:"created_at" json:"created_at"`
+}
+
+// InboxThread is a thread as listed in a participant's inbox, with
+// the number of messages in it they haven't read yet.
+type InboxThread struct {
+	Thread
+	Unread int `db:"unread" json:"unread"`
 }
 
 type Message struct {
@@ -46,7 +54,9 @@ func (t *Thread) UnmarshalJSON(data []byte) error {
 	// Part of Step 3: check that read-only fields aren't included.
 	roBad := []string{}
 	chassis.ReadOnlyField(fields, "id", &roBad)
-	chassis.ReadOnlyField(fields, "owner", &roBad)
+	chassis.ReadOnlyField(fields, "author", &roBad)
+	chassis.ReadOnlyField(fields, "status", &roBad)
+	chassis.ReadOnlyField(fields, "last_message_at", &roBad)
 	chassis.ReadOnlyField(fields, "created_at", &roBad)
 	if len(roBad) > 0 {
 		return errors.New("attempt to set read-only fields: " + strings.Join(roBad, ","))
@@ -155,13 +165,13 @@ func (t *Thread) Patch(patch []byte) error {
 	}
 
 	roFields := map[string]string{
-		"id":           "ID",
-		"subject":      "subject",
-		"author":       "author",
-		"status":       "status",
-		"is_edited":    "is_edited",
-		"participants": "participants",
-		"created_at":   "creation date",
+		"id":              "ID",
+		"subject":         "subject",
+		"author":          "author",
+		"is_edited":       "is_edited",
+		"participants":    "participants",
+		"last_message_at": "last message date",
+		"created_at":      "creation date",
 	}
 	for fld, label := range roFields {
 		if _, ok := updates[fld]; ok {
@@ -0,0 +1,98 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ReportReason is an enumeration that represents the reasons users
+// can give for reporting a post or reply.
+type ReportReason int
+
+// Constants for all report reasons.
+const (
+	UnknownReportReason ReportReason = iota
+	Spam
+	Offensive
+	OffTopic
+	Fake
+	OtherReason
+)
+
+// UnmarshalJSON unmarshals a report reason from a JSON string.
+func (rr *ReportReason) UnmarshalJSON(d []byte) error {
+	var s string
+	if err := json.Unmarshal(d, &s); err != nil {
+		return errors.Wrap(err, "can't unmarshal report reason")
+	}
+	return rr.FromString(s)
+}
+
+// FromString converts a string to a report reason.
+func (rr *ReportReason) FromString(s string) error {
+	switch strings.ToLower(s) {
+	default:
+		return errors.New("unknown report reason '" + s + "'")
+	case "spam":
+		*rr = Spam
+	case "offensive":
+		*rr = Offensive
+	case "off_topic":
+		*rr = OffTopic
+	case "fake":
+		*rr = Fake
+	case "other":
+		*rr = OtherReason
+	}
+	return nil
+}
+
+// String converts a report reason from its internal representation
+// to a string.
+func (rr ReportReason) String() string {
+	switch rr {
+	default:
+		return "<unknown report reason>"
+	case Spam:
+		return "spam"
+	case Offensive:
+		return "offensive"
+	case OffTopic:
+		return "off_topic"
+	case Fake:
+		return "fake"
+	case OtherReason:
+		return "other"
+	}
+}
+
+// MarshalJSON converts an internal report reason to JSON.
+func (rr ReportReason) MarshalJSON() ([]byte, error) {
+	s := rr.String()
+	if s == "<unknown report reason>" {
+		return nil, errors.New("unknown report reason")
+	}
+	return json.Marshal(s)
+}
+
+// Scan implements the sql.Scanner interface.
+func (rr *ReportReason) Scan(src interface{}) error {
+	var s string
+	switch src := src.(type) {
+	case string:
+		s = src
+	case []byte:
+		s = string(src)
+	default:
+		return errors.New("incompatible type for report reason")
+	}
+	return rr.FromString(s)
+}
+
+// Value implements the driver.Value interface.
+func (rr ReportReason) Value() (driver.Value, error) {
+	return rr.String(), nil
+}
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ReportStatus is an enumeration that represents the states of an
+// abuse report.
+type ReportStatus int
+
+// Constants for all report statuses.
+const (
+	UnknownReportStatus ReportStatus = iota
+	ReportOpen
+	ReportActioned
+	ReportDismissed
+)
+
+// UnmarshalJSON unmarshals a report status from a JSON string.
+func (rs *ReportStatus) UnmarshalJSON(d []byte) error {
+	var s string
+	if err := json.Unmarshal(d, &s); err != nil {
+		return errors.Wrap(err, "can't unmarshal report status")
+	}
+	return rs.FromString(s)
+}
+
+// FromString converts a string to a report status.
+func (rs *ReportStatus) FromString(s string) error {
+	switch strings.ToLower(s) {
+	default:
+		return errors.New("unknown report status '" + s + "'")
+	case "open":
+		*rs = ReportOpen
+	case "actioned":
+		*rs = ReportActioned
+	case "dismissed":
+		*rs = ReportDismissed
+	}
+	return nil
+}
+
+// String converts a report status from its internal representation
+// to a string.
+func (rs ReportStatus) String() string {
+	switch rs {
+	default:
+		return "<unknown report status>"
+	case ReportOpen:
+		return "open"
+	case ReportActioned:
+		return "actioned"
+	case ReportDismissed:
+		return "dismissed"
+	}
+}
+
+// MarshalJSON converts an internal report status to JSON.
+func (rs ReportStatus) MarshalJSON() ([]byte, error) {
+	s := rs.String()
+	if s == "<unknown report status>" {
+		return nil, errors.New("unknown report status")
+	}
+	return json.Marshal(s)
+}
+
+// Scan implements the sql.Scanner interface.
+func (rs *ReportStatus) Scan(src interface{}) error {
+	var s string
+	switch src := src.(type) {
+	case string:
+		s = src
+	case []byte:
+		s = string(src)
+	default:
+		return errors.New("incompatible type for report status")
+	}
+	return rs.FromString(s)
+}
+
+// Value implements the driver.Value interface.
+func (rs ReportStatus) Value() (driver.Value, error) {
+	return rs.String(), nil
+}
@@ -34,12 +34,12 @@ func (ts *ThreadStatus) UnmarshalJSON(d []byte) error {
 func (ts *ThreadStatus) FromString(s string) error {
 	switch strings.ToLower(s) {
 	default:
-		return errors.New("unknown item type '" + s + "'")
+		return errors.New("unknown thread status '" + s + "'")
 	case "open":
 		*ts = Open
 	case "closed":
 		*ts = Closed
-	case "comment":
+	case "archived":
 		*ts = Archived
 	case "deleted":
 		*ts = Deleted
@@ -18,7 +18,9 @@ type PostFullFixed struct {
 	Owner     userModel.Info `json:"owner"`
 	IsEdited  bool            `json:"is_edited"`
 	IsDeleted bool            `json:"is_deleted"`
+	IsHidden  bool            `json:"is_hidden"`
 	Pictures  pq.StringArray  `json:"pictures"`
+	AnswerId  *string         `json:"answer_id,omitempty"`
 }
 
 type PostFull struct {
@@ -36,6 +38,13 @@ type ReplyFullFixed struct {
 	IsEdited  bool            `json:"is_edited"`
 	Pictures  pq.StringArray  `json:"pictures"`
 	IsDeleted bool            `json:"is_deleted"`
+	IsHidden  bool            `json:"is_hidden"`
+
+	// Official responses to reviews, with the user or organisation
+	// they're made for, and answers to questions chosen by item owners.
+	IsOfficial  bool            `json:"is_official"`
+	OfficialFor *userModel.Info `json:"official_for,omitempty"`
+	IsAnswer    bool            `json:"is_answer"`
 }
 type ReplyFull struct {
 	ReplyFullFixed
@@ -78,7 +87,9 @@ func GetPostFull(p Post, owner *userModel.Info, replies *[]ReplyFull) *PostFull
 			Owner:     *owner,
 			IsEdited:  p.IsEdited,
 			IsDeleted: p.IsDeleted,
+			IsHidden:  p.IsHidden,
 			Pictures:  p.Pictures,
+			AnswerId:  p.AnswerId,
 		},
 		Attrs:       p.Attrs,
 		Replies:     *replies,
@@ -122,12 +133,14 @@ func GetMessageFull(m Message, userInfo *userModel.Info) MessageFull {
 func GetReplyFull(r Reply, owner *userModel.Info, replies *[]ReplyFull) *ReplyFull {
 	return &ReplyFull{
 		ReplyFullFixed: ReplyFullFixed{
-			Id:        r.Id,
-			ParentId:  r.ParentId,
-			Owner:     *owner,
-			IsEdited:  r.IsEdited,
-			Pictures:  r.Pictures,
-			IsDeleted: r.IsDeleted,
+			Id:         r.Id,
+			ParentId:   r.ParentId,
+			Owner:      *owner,
+			IsEdited:   r.IsEdited,
+			Pictures:   r.Pictures,
+			IsDeleted:  r.IsDeleted,
+			IsHidden:   r.IsHidden,
+			IsOfficial: r.IsOfficial,
 		},
 		Attrs:       r.Attrs,
 		Replies:     *replies,
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/chassis/pubsub"
+	item_events "github.com/veganbase/backend/services/item-service/events"
+	itemTypes "github.com/veganbase/backend/services/item-service/model/types"
+	"github.com/veganbase/backend/services/social-service/events"
+	"github.com/veganbase/backend/services/social-service/model"
+)
+
+// Subscription names for building feeds: feed entries are added by
+// competing consumers, so that each event is processed once.
+const feedItemSubName = "social-service-feed-item-changes"
+const feedPostCreatedSubName = "social-service-feed-post-created"
+const feedPostDeletedSubName = "social-service-feed-post-deleted"
+
+// Default and maximum numbers of entries in a page of a feed.
+const feedPageSize = 30
+const feedMaxPageSize = 100
+
+// HandleFeedItemEvents adds new items and collection additions made by
+// users and organisations to their followers' feeds. Items are added
+// when they're approved, since they're not visible before then.
+func (s *Server) HandleFeedItemEvents() {
+	ch, _, err := s.PubSub.Subscribe(item_events.ItemChange, feedItemSubName,
+		pubsub.CompetingConsumers)
+	if err != nil {
+		log.Fatal().Err(err).
+			Msg("couldn't subscribe to item change events")
+	}
+
+	for {
+		data := <-ch
+		event := item_events.ItemEvent{}
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Error().Err(err).
+				Msg("unmarshalling item service event")
+			continue
+		}
+
+		if err := s.processFeedItemEvent(&event); err != nil {
+			log.Error().Err(err).
+				Msgf("updating feeds for item ID '%s'", event.ItemID)
+		}
+	}
+}
+
+func (s *Server) processFeedItemEvent(event *item_events.ItemEvent) error {
+	switch event.EventType {
+	case item_events.ItemCreated, item_events.ItemUpdated:
+		item, err := s.itemSvc.ItemInfo(event.ItemID)
+		if err != nil {
+			return err
+		}
+		switch item.Approval {
+		case itemTypes.Approved:
+			_, err = s.db.AddFeedEntries(item.Owner, model.FeedItem, item.ID, nil)
+		case itemTypes.Rejected:
+			err = s.db.DeleteFeedEntries(item.ID, nil)
+		}
+		return err
+
+	case item_events.ItemAddedToCollection:
+		coll, err := s.itemSvc.CollectionInfo(event.CollectionID)
+		if err != nil {
+			return err
+		}
+		item, err := s.itemSvc.ItemInfo(event.ItemID)
+		if err != nil {
+			return err
+		}
+		if item.Approval != itemTypes.Approved {
+			return nil
+		}
+		_, err = s.db.AddFeedEntries(coll.Owner, model.FeedCollection, item.ID, &event.CollectionID)
+		return err
+
+	case item_events.ItemRemovedFromCollection:
+		return s.db.DeleteFeedEntries(event.ItemID, &event.CollectionID)
+
+	case item_events.ItemDeleted:
+		return s.db.DeleteFeedEntries(event.ItemID, nil)
+	}
+	return nil
+}
+
+// HandleFeedPostEvents adds new posts and reviews to the feeds of
+// their authors' followers, and removes them when they're deleted.
+func (s *Server) HandleFeedPostEvents() {
+	createdCh, _, err := s.PubSub.Subscribe(events.PostCreated, feedPostCreatedSubName,
+		pubsub.CompetingConsumers)
+	if err != nil {
+		log.Fatal().Err(err).
+			Msg("couldn't subscribe to post creation events")
+	}
+	deletedCh, _, err := s.PubSub.Subscribe(events.PostDeleted, feedPostDeletedSubName,
+		pubsub.CompetingConsumers)
+	if err != nil {
+		log.Fatal().Err(err).
+			Msg("couldn't subscribe to post deletion events")
+	}
+
+	for {
+		var data []byte
+		created := false
+		select {
+		case data = <-createdCh:
+			created = true
+		case data = <-deletedCh:
+		}
+
+		post := model.PostFixed{}
+		if err := json.Unmarshal(data, &post); err != nil {
+			log.Error().Err(err).
+				Msg("unmarshalling post event")
+			continue
+		}
+
+		if created {
+			_, err = s.db.AddFeedEntries(post.Owner, feedKind(post.PostType), post.Id, nil)
+		} else {
+			err = s.db.DeleteFeedEntries(post.Id, nil)
+		}
+		if err != nil {
+			log.Error().Err(err).
+				Msgf("updating feeds for post ID '%s'", post.Id)
+		}
+	}
+}
+
+// feedKind is the kind of feed entry for a post.
+func feedKind(postType model.PostType) string {
+	if postType == model.ReviewPost {
+		return model.FeedReview
+	}
+	return model.FeedPost
+}
+
+// getFeed returns a page of the authenticated user's activity feed,
+// newest first. Pages after the first are fetched by passing the
+// previous page's next cursor as the cursor parameter.
+func (s *Server) getFeed(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	qs := r.URL.Query()
+	var cursor int64
+	if c := qs.Get("cursor"); c != "" {
+		var err error
+		if cursor, err = strconv.ParseInt(c, 10, 64); err != nil || cursor <= 0 {
+			return chassis.BadRequest(w, "invalid cursor parameter")
+		}
+	}
+	perPage := uint(feedPageSize)
+	if err := chassis.IntParam(qs, "per_page", &perPage); err != nil || perPage == 0 {
+		return chassis.BadRequest(w, "invalid per_page parameter")
+	}
+	if perPage > feedMaxPageSize {
+		perPage = feedMaxPageSize
+	}
+
+	// Fetch one entry more than needed to find out if there's another
+	// page.
+	entries, err := s.db.FeedEntries(authInfo.UserID, cursor, perPage+1)
+	if err != nil {
+		return nil, err
+	}
+	page, next := feedPage(*entries, perPage)
+
+	views, err := s.feedViews(page)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Feed{Entries: views, NextCursor: next}, nil
+}
+
+// feedPage cuts a list of feed entries down to a page, returning the
+// cursor for the next page if there are more entries.
+func feedPage(entries []model.FeedEntry, perPage uint) ([]model.FeedEntry, string) {
+	if uint(len(entries)) <= perPage {
+		return entries, ""
+	}
+	page := entries[:perPage]
+	return page, strconv.FormatInt(page[len(page)-1].ID, 10)
+}
+
+// feedViews adds information about the users, organisations, items
+// and posts in feed entries. Entries for items or posts that no
+// longer exist are left out.
+func (s *Server) feedViews(entries []model.FeedEntry) ([]model.FeedEntryView, error) {
+	views := []model.FeedEntryView{}
+	if len(entries) == 0 {
+		return views, nil
+	}
+
+	actors := []string{}
+	itemIDs := []string{}
+	postIDs := []string{}
+	for _, e := range entries {
+		actors = append(actors, e.Actor)
+		switch e.Kind {
+		case model.FeedItem, model.FeedCollection:
+			itemIDs = append(itemIDs, e.Ref)
+		case model.FeedPost, model.FeedReview:
+			postIDs = append(postIDs, e.Ref)
+		}
+	}
+
+	actorInfo, err := s.participantInfo(uniqueIDs(actors))
+	if err != nil {
+		return nil, err
+	}
+	itemInfo, err := s.itemSvc.GetItemsInfo(uniqueIDs(itemIDs))
+	if err != nil {
+		return nil, err
+	}
+	posts, err := s.db.PostsByIDs(uniqueIDs(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	postMap := map[string]*model.Post{}
+	for i := range *posts {
+		p := &(*posts)[i]
+		if !p.IsDeleted && !p.IsHidden {
+			postMap[p.Id] = p
+		}
+	}
+
+	for _, e := range entries {
+		view := model.FeedEntryView{
+			Kind:       e.Kind,
+			Actor:      actorInfo[e.Actor],
+			Collection: e.Collection,
+			CreatedAt:  e.CreatedAt,
+		}
+		switch e.Kind {
+		case model.FeedItem, model.FeedCollection:
+			if view.Item = itemInfo[e.Ref]; view.Item == nil {
+				continue
+			}
+		case model.FeedPost, model.FeedReview:
+			if view.Post = postMap[e.Ref]; view.Post == nil {
+				continue
+			}
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog/log"
+
+	"github.com/veganbase/backend/chassis"
+	itemModel "github.com/veganbase/backend/services/item-service/model"
+	"github.com/veganbase/backend/services/social-service/db"
+	"github.com/veganbase/backend/services/social-service/events"
+	"github.com/veganbase/backend/services/social-service/model"
+	userModel "github.com/veganbase/backend/services/user-service/model"
+)
+
+// createOfficialResponse lets the owner of an item (the user owning
+// it, or a member of the organisation owning it) respond to a review
+// of the item. Each review has at most one official response, which
+// is edited or deleted like any other reply.
+func (s *Server) createOfficialResponse(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	post, err := s.visiblePost(chi.URLParam(r, "post_id"))
+	if err != nil {
+		if err == db.ErrPostNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	if post.PostType != model.ReviewPost {
+		return chassis.BadRequest(w, "official responses can only be made to reviews")
+	}
+	item, isOwner, err := s.itemOwnerAccess(authInfo.UserID, post.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if !isOwner {
+		return chassis.Forbidden(w)
+	}
+	if _, err = s.db.OfficialResponse(post.Id); err == nil {
+		return chassis.Conflict(w, "review already has an official response")
+	} else if err != db.ErrReplyNotFound {
+		return nil, err
+	}
+
+	// The response is a reply to the review, so the parent ID comes
+	// from the URL rather than the request body.
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	fields := map[string]interface{}{}
+	if err = json.Unmarshal(body, &fields); err != nil {
+		return chassis.BadRequest(w, "invalid JSON in reply data")
+	}
+	if parent, ok := fields["parent_id"]; ok && parent != post.Id {
+		return chassis.BadRequest(w, "official response must be a reply to the review")
+	}
+	fields["parent_id"] = post.Id
+	if body, err = json.Marshal(fields); err != nil {
+		return nil, err
+	}
+	reply := model.Reply{}
+	if err = reply.UnmarshalJSON(body); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	reply.Owner = authInfo.UserID
+	reply.IsOfficial = true
+	reply.OfficialFor = &item.Owner
+
+	// Another response may have been created since the check above.
+	if err = s.db.CreateReply(&reply); err != nil {
+		if err == db.ErrAlreadyResponded {
+			return chassis.Conflict(w, "review already has an official response")
+		}
+		return nil, err
+	}
+	chassis.Emit(s, events.ReplyCreated, reply)
+
+	if err = s.addBlobs(reply.Id, reply.Pictures); err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// setAnswer lets the owner of an item mark one of the direct replies
+// to a question about the item as its answer. The asker and the
+// item's followers are emailed the first time a question is answered.
+func (s *Server) setAnswer(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	post, err := s.visiblePost(chi.URLParam(r, "post_id"))
+	if err != nil {
+		if err == db.ErrPostNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	if post.PostType != model.QuestionPost {
+		return chassis.BadRequest(w, "answers can only be chosen for questions")
+	}
+	item, isOwner, err := s.itemOwnerAccess(authInfo.UserID, post.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if !isOwner && !authInfo.UserIsAdmin {
+		return chassis.Forbidden(w)
+	}
+
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	req := struct {
+		ReplyId string `json:"reply_id"`
+	}{}
+	if err = json.Unmarshal(body, &req); err != nil || req.ReplyId == "" {
+		return chassis.BadRequest(w, "a reply_id must be given")
+	}
+	answer, err := s.db.ReplyById(req.ReplyId)
+	if err != nil {
+		if err == db.ErrReplyNotFound {
+			return chassis.BadRequest(w, err.Error())
+		}
+		return nil, err
+	}
+	if answer.ParentId != post.Id || answer.IsDeleted || answer.IsHidden {
+		return chassis.BadRequest(w, "answer must be a reply to the question")
+	}
+
+	firstAnswer := post.AnswerId == nil
+	if err = s.db.SetPostAnswer(post.Id, &answer.Id); err != nil {
+		return nil, err
+	}
+	post.AnswerId = &answer.Id
+	chassis.Emit(s, events.PostAnswered, post)
+
+	if firstAnswer {
+		go s.notifyQuestionAnswered(post, answer, item)
+	}
+	return post, nil
+}
+
+// clearAnswer removes the answer chosen for a question.
+func (s *Server) clearAnswer(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	post, err := s.visiblePost(chi.URLParam(r, "post_id"))
+	if err != nil {
+		if err == db.ErrPostNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	if post.AnswerId == nil {
+		return chassis.BadRequest(w, "question has no answer")
+	}
+	_, isOwner, err := s.itemOwnerAccess(authInfo.UserID, post.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if !isOwner && !authInfo.UserIsAdmin {
+		return chassis.Forbidden(w)
+	}
+
+	if err = s.db.SetPostAnswer(post.Id, nil); err != nil {
+		return nil, err
+	}
+	post.AnswerId = nil
+	chassis.Emit(s, events.PostAnswered, post)
+
+	return chassis.NoContent(w)
+}
+
+// visiblePost looks up a post, treating deleted and hidden posts as
+// missing.
+func (s *Server) visiblePost(postId string) (*model.Post, error) {
+	post, err := s.db.PostById(postId)
+	if err != nil {
+		return nil, err
+	}
+	if post.IsDeleted || post.IsHidden {
+		return nil, db.ErrPostNotFound
+	}
+	return post, nil
+}
+
+// itemOwnerAccess looks up an item and determines whether a user can
+// act for its owner, either by being the owner or by being a member
+// of the organisation that owns it.
+func (s *Server) itemOwnerAccess(userID, itemID string) (*itemModel.Item, bool, error) {
+	item, err := s.itemSvc.ItemInfo(itemID)
+	if err != nil {
+		return nil, false, err
+	}
+	if item.Owner == userID {
+		return item, true, nil
+	}
+	if !strings.HasPrefix(item.Owner, "org_") {
+		return item, false, nil
+	}
+	isMember, err := s.userSvc.IsUserOrgMember(userID, item.Owner)
+	if err != nil {
+		return nil, false, err
+	}
+	return item, isMember, nil
+}
+
+// notifyQuestionAnswered emails the asker of a question and the
+// followers of the item it's about (users who upvoted the item) when
+// an answer is chosen. Errors are logged, since the answer has been
+// saved already.
+func (s *Server) notifyQuestionAnswered(question *model.Post, answer *model.Reply, item *itemModel.Item) {
+	followers, err := s.db.ListWhoUpvoted(item.ID)
+	if err != nil {
+		log.Error().Err(err).Str("post", question.Id).
+			Msg("question-answered: could not list item followers")
+		followers = []string{}
+	}
+
+	recipients := uniqueIDs(append([]string{question.Owner}, followers...))
+	for _, id := range recipients {
+		if id == answer.Owner {
+			continue
+		}
+		contact, err := s.userSvc.GetNotificationInfo(id)
+		if err != nil {
+			log.Error().Err(err).Str("post", question.Id).Str("recipient", id).
+				Msg("question-answered: could not obtain notification information from user-service")
+			continue
+		}
+		if contact.Email == "" {
+			continue
+		}
+		notification := buildQuestionAnsweredMsg(question, answer, item, contact, id == question.Owner)
+		if err = chassis.Emit(s, events.QuestionAnswered, notification); err != nil {
+			log.Error().Err(err).Msg("question-answered: could not send event")
+		}
+	}
+}
+
+// Build the message used by the email service to tell the asker of a
+// question, or a follower of the item it's about, that the question
+// has been answered.
+func buildQuestionAnsweredMsg(question *model.Post, answer *model.Reply, item *itemModel.Item,
+	contact *userModel.EmailNotificationInfo, isAsker bool) *chassis.GenericEmailMsg {
+	data := chassis.GenericMap{}
+	data["item_id"] = item.ID
+	data["item_slug"] = item.Slug
+	data["item_name"] = item.Name
+	data["post_id"] = question.Id
+	data["question"] = excerpt(question.Attrs["content"])
+	data["answer"] = excerpt(answer.Attrs["content"])
+	data["recipient_name"] = contact.Name
+	data["is_asker"] = isAsker
+
+	return &chassis.GenericEmailMsg{
+		FixedFields: chassis.FixedFields{
+			Site:     "ethical.id",
+			Language: "en",
+			Email:    contact.Email,
+		},
+		Data: data,
+	}
+}
+
+// surfaceReplies moves official responses and chosen answers to the
+// front of the replies to a post, keeping the order of the others.
+func surfaceReplies(replies []model.ReplyFull, answerId *string) {
+	for i := range replies {
+		replies[i].IsAnswer = answerId != nil && replies[i].Id == *answerId &&
+			!replies[i].IsDeleted && !replies[i].IsHidden
+	}
+	rank := func(rpl *model.ReplyFull) int {
+		switch {
+		case rpl.IsOfficial && !rpl.IsDeleted && !rpl.IsHidden:
+			return 0
+		case rpl.IsAnswer:
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(replies, func(i, j int) bool {
+		return rank(&replies[i]) < rank(&replies[j])
+	})
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/social-service/db"
+	"github.com/veganbase/backend/services/social-service/events"
+	"github.com/veganbase/backend/services/social-service/model"
+	"github.com/veganbase/backend/services/social-service/model/types"
+)
+
+// Content shown in place of hidden posts and replies that are kept
+// in listings because they have replies of their own.
+const hiddenContent = "This content was hidden by a moderator."
+
+func (s *Server) reportPost(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	return s.report(w, r, model.ReportedPost, chi.URLParam(r, "post_id"))
+}
+
+func (s *Server) reportReply(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	return s.report(w, r, model.ReportedReply, chi.URLParam(r, "reply_id"))
+}
+
+// report lets an authenticated user report a post or reply that they
+// think breaks the site's rules. Reports go into the moderation queue.
+func (s *Server) report(w http.ResponseWriter, r *http.Request,
+	targetType, targetID string) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+	if targetID == "" {
+		return chassis.BadRequest(w, "missing "+targetType+" ID")
+	}
+
+	owner, deleted, err := s.reportTarget(targetType, targetID)
+	if err != nil {
+		if err == db.ErrPostNotFound || err == db.ErrReplyNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	if deleted {
+		return chassis.NotFound(w)
+	}
+	if owner == authInfo.UserID {
+		return chassis.BadRequest(w, "can't report your own "+targetType)
+	}
+
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	report := model.Report{}
+	if err = report.UnmarshalJSON(body); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	report.TargetType = targetType
+	report.TargetID = targetID
+	report.Reporter = authInfo.UserID
+
+	if err = s.db.CreateReport(&report); err != nil {
+		if err == db.ErrAlreadyReported {
+			return chassis.BadRequest(w, "you have already reported this "+targetType)
+		}
+		return nil, err
+	}
+	chassis.Emit(s, events.ReportCreated, report)
+
+	return &report, nil
+}
+
+// reportTarget returns the owner of a reported post or reply, and
+// whether it's been deleted.
+func (s *Server) reportTarget(targetType, targetID string) (string, bool, error) {
+	if targetType == model.ReportedPost {
+		post, err := s.db.PostById(targetID)
+		if err != nil {
+			return "", false, err
+		}
+		return post.Owner, post.IsDeleted, nil
+	}
+	rpl, err := s.db.ReplyById(targetID)
+	if err != nil {
+		return "", false, err
+	}
+	return rpl.Owner, rpl.IsDeleted, nil
+}
+
+// moderationQueue lists reported posts and replies for
+// administrators, most reported first, with their reports. By default
+// the queue holds content with open reports: the status parameter
+// selects content with actioned or dismissed reports instead.
+func (s *Server) moderationQueue(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth || !authInfo.UserIsAdmin {
+		return chassis.NotFound(w)
+	}
+
+	qs := r.URL.Query()
+	pagination := chassis.Pagination{}
+	if err := chassis.PaginationParams(qs, &pagination.Page, &pagination.PerPage); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	status := types.ReportOpen
+	if st := qs.Get("status"); st != "" {
+		if err := status.FromString(st); err != nil {
+			return chassis.BadRequest(w, err.Error())
+		}
+	}
+
+	queue, total, err := s.db.ReportQueue(status, &pagination)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.addReportedContent(*queue, status); err != nil {
+		return nil, err
+	}
+
+	chassis.BuildPaginationResponse(w, r, pagination.Page, pagination.PerPage, *total)
+	return queue, nil
+}
+
+// addReportedContent fills in the posts, replies and individual
+// reports for entries in the moderation queue.
+func (s *Server) addReportedContent(queue []model.ReportedContent, status types.ReportStatus) error {
+	postIDs := []string{}
+	replyIDs := []string{}
+	targetIDs := []string{}
+	for _, rc := range queue {
+		targetIDs = append(targetIDs, rc.TargetID)
+		if rc.TargetType == model.ReportedPost {
+			postIDs = append(postIDs, rc.TargetID)
+		} else {
+			replyIDs = append(replyIDs, rc.TargetID)
+		}
+	}
+
+	posts, err := s.db.PostsByIDs(postIDs)
+	if err != nil {
+		return err
+	}
+	postMap := map[string]*model.Post{}
+	for i := range *posts {
+		postMap[(*posts)[i].Id] = &(*posts)[i]
+	}
+	replies, err := s.db.RepliesByIDs(replyIDs)
+	if err != nil {
+		return err
+	}
+	replyMap := map[string]*model.Reply{}
+	for i := range *replies {
+		replyMap[(*replies)[i].Id] = &(*replies)[i]
+	}
+	reports, err := s.db.ReportsByTargets(targetIDs, status)
+	if err != nil {
+		return err
+	}
+	reportMap := map[string][]model.Report{}
+	for _, rpt := range *reports {
+		reportMap[rpt.TargetID] = append(reportMap[rpt.TargetID], rpt)
+	}
+
+	for i := range queue {
+		rc := &queue[i]
+		rc.Post = postMap[rc.TargetID]
+		rc.Reply = replyMap[rc.TargetID]
+		rc.Reports = reportMap[rc.TargetID]
+		if rc.Reports == nil {
+			rc.Reports = []model.Report{}
+		}
+	}
+	return nil
+}
+
+func (s *Server) hidePost(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	return s.moderatePost(w, r, true)
+}
+
+func (s *Server) restorePost(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	return s.moderatePost(w, r, false)
+}
+
+// moderatePost hides a post or restores a hidden post. Hiding a post
+// closes its open reports as actioned, while restoring it dismisses
+// them. Changes to reviews trigger recalculation of the item's rank.
+func (s *Server) moderatePost(w http.ResponseWriter, r *http.Request, hide bool) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth || !authInfo.UserIsAdmin {
+		return chassis.NotFound(w)
+	}
+
+	post, err := s.db.PostById(chi.URLParam(r, "post_id"))
+	if err != nil {
+		if err == db.ErrPostNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	if post.IsHidden == hide {
+		if hide {
+			return chassis.BadRequest(w, "post already hidden")
+		}
+		return chassis.BadRequest(w, "post isn't hidden")
+	}
+
+	if err = s.db.HidePost(post.Id, hide); err != nil {
+		return nil, err
+	}
+	post.IsHidden = hide
+	if _, err = s.db.ResolveReports(post.Id, resolution(hide), authInfo.UserID); err != nil {
+		return nil, err
+	}
+
+	if hide {
+		chassis.Emit(s, events.PostHidden, post)
+	} else {
+		chassis.Emit(s, events.PostRestored, post)
+	}
+	if post.PostType == model.ReviewPost {
+		chassis.Emit(s, events.ItemRankTopic, post.Subject)
+	}
+
+	return post, nil
+}
+
+func (s *Server) hideReply(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	return s.moderateReply(w, r, true)
+}
+
+func (s *Server) restoreReply(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	return s.moderateReply(w, r, false)
+}
+
+// moderateReply hides a reply or restores a hidden reply, resolving
+// its open reports in the same way as moderatePost.
+func (s *Server) moderateReply(w http.ResponseWriter, r *http.Request, hide bool) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth || !authInfo.UserIsAdmin {
+		return chassis.NotFound(w)
+	}
+
+	rpl, err := s.db.ReplyById(chi.URLParam(r, "reply_id"))
+	if err != nil {
+		if err == db.ErrReplyNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	if rpl.IsHidden == hide {
+		if hide {
+			return chassis.BadRequest(w, "reply already hidden")
+		}
+		return chassis.BadRequest(w, "reply isn't hidden")
+	}
+
+	if err = s.db.HideReply(rpl.Id, hide); err != nil {
+		return nil, err
+	}
+	rpl.IsHidden = hide
+	if _, err = s.db.ResolveReports(rpl.Id, resolution(hide), authInfo.UserID); err != nil {
+		return nil, err
+	}
+
+	if hide {
+		chassis.Emit(s, events.ReplyHidden, rpl)
+	} else {
+		chassis.Emit(s, events.ReplyRestored, rpl)
+	}
+
+	return rpl, nil
+}
+
+// dismissReports closes the open reports about a post or reply
+// without hiding it.
+func (s *Server) dismissReports(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth || !authInfo.UserIsAdmin {
+		return chassis.NotFound(w)
+	}
+
+	targetID := chi.URLParam(r, "post_id")
+	if targetID == "" {
+		targetID = chi.URLParam(r, "reply_id")
+	}
+	dismissed, err := s.db.ResolveReports(targetID, types.ReportDismissed, authInfo.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if dismissed == 0 {
+		return chassis.NotFoundWithMessage(w, "no open reports")
+	}
+	return chassis.NoContent(w)
+}
+
+// resolution is the status that open reports are closed with when a
+// moderator hides or restores the content they're about.
+func resolution(hide bool) types.ReportStatus {
+	if hide {
+		return types.ReportActioned
+	}
+	return types.ReportDismissed
+}
@@ -2,9 +2,11 @@ package server
 
 import (
 	"errors"
+	"github.com/rs/zerolog/log"
 	"github.com/veganbase/backend/services/social-service/events"
 
 	"github.com/go-chi/chi"
+	"github.com/lib/pq"
 
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/social-service/db"
@@ -51,11 +53,7 @@ func (s *Server) createPost(w http.ResponseWriter, r *http.Request, subject stri
 	post.Subject = subject
 
 	if post.PostType == model.ReviewPost && isItem{
-		bought, err := s.purSvc.UserBoughtItem(post.Subject, post.Owner);
-		if err != nil {
-			return nil, err
-		}
-		post.Attrs["user_bought"] = bought
+		post.Attrs["user_bought"] = s.verifiedPurchase(post.Subject, post.Owner)
 	}
 	// Create the post.
 	if err = s.db.CreatePost(&post); err != nil {
@@ -244,6 +242,7 @@ func (s *Server) doPostsSearch(w http.ResponseWriter, r *http.Request, subject s
 		if err != nil {
 			return chassis.BadRequest(w, "error getting nested replies :" + err.Error())
 		}
+		surfaceReplies(*replies, post.AnswerId)
 		postFull := model.GetPostFull(post, ownerMap[post.Owner], replies)
 		//Masking deleted message.
 		if postFull.IsDeleted == true {
@@ -273,6 +272,9 @@ func (s *Server) getNestedReplies(subject string) (*[]model.ReplyFull, error) {
 	owners := map[string]bool{}
 	for _, r := range *replies {
 		owners[r.Owner] = true
+		if r.OfficialFor != nil {
+			owners[*r.OfficialFor] = true
+		}
 	}
 
 	uniqueOwners := []string{}
@@ -291,9 +293,17 @@ func (s *Server) getNestedReplies(subject string) (*[]model.ReplyFull, error) {
 			return nil, err
 		}
 		fullRpl := model.GetReplyFull(rpl, ownerMap[rpl.Owner], nested)
-		if fullRpl.IsDeleted == true {
+		if rpl.OfficialFor != nil {
+			fullRpl.OfficialFor = ownerMap[*rpl.OfficialFor]
+		}
+		if fullRpl.IsDeleted == true || fullRpl.IsHidden == true {
 			if len(fullRpl.Replies) > 0 {
-				fullRpl.Attrs["content"] = "This post was deleted."
+				if fullRpl.IsHidden {
+					fullRpl.Attrs["content"] = hiddenContent
+					fullRpl.Pictures = pq.StringArray{}
+				} else {
+					fullRpl.Attrs["content"] = "This post was deleted."
+				}
 			}else {
 				//if there are no nested replies, omit reply
 				continue
@@ -312,9 +322,24 @@ func (s *Server) getOverallRank(w http.ResponseWriter, r *http.Request) (interfa
 	if itemId == "" {
 		return chassis.BadRequest(w, "missing item ID")
 	}
-	rank, err := s.db.AvgReviewRank(itemId)
+	rank, err := s.db.AvgReviewRank(itemId, s.unverifiedReviewWeight)
 	if err != nil {
 		return nil, err
 	}
 	return rank, nil
-}
\ No newline at end of file
+}
+
+// verifiedPurchase checks whether a user bought and paid for an item,
+// for marking their reviews of it as verified purchases. Purchases
+// that haven't been paid for don't count. Reviews aren't blocked if
+// the purchase service can't be reached: they're just left
+// unverified, and are marked later when a purchase is paid for.
+func (s *Server) verifiedPurchase(itemId, userId string) bool {
+	bought, err := s.purSvc.UserBoughtItem(itemId, userId)
+	if err != nil {
+		log.Error().Err(err).
+			Msgf("checking purchases of item '%s' by '%s'", itemId, userId)
+		return false
+	}
+	return bought != nil && *bought
+}
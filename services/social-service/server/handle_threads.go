@@ -0,0 +1,544 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog/log"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/social-service/db"
+	"github.com/veganbase/backend/services/social-service/events"
+	"github.com/veganbase/backend/services/social-service/model"
+	"github.com/veganbase/backend/services/social-service/model/types"
+	userModel "github.com/veganbase/backend/services/user-service/model"
+)
+
+// purchaseID matches the IDs of purchases, orders and bookings, which
+// threads can be about as well as items.
+var purchaseID = regexp.MustCompile(`^[A-Za-z]{3}-[0-9]{6}$`)
+
+// Length of the excerpts of messages, questions and answers included
+// in notification emails.
+const messageExcerptLength = 200
+
+// createThread starts a conversation about an item or an order. A
+// thread about an order is between its buyer and seller, and can only
+// be started by one of them. A thread about an item is between the
+// user starting it, the item's owner and any other users or
+// organisations they invite.
+func (s *Server) createThread(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	// Get authentication information from context and only allow
+	// authenticated users to proceed.
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	// Read request body.
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	// Unmarshal request data -- validates JSON request body.
+	th := model.Thread{}
+	if err = th.UnmarshalJSON(body); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if th.Subject == "" {
+		return chassis.BadRequest(w, "missing thread subject")
+	}
+	if strings.TrimSpace(th.Content) == "" {
+		return chassis.BadRequest(w, "missing thread content")
+	}
+	th.Author = authInfo.UserID
+
+	// Work out who takes part in the thread.
+	if purchaseID.MatchString(th.Subject) {
+		order, err := s.purSvc.GetOrder(th.Subject)
+		if err != nil {
+			return chassis.BadRequest(w, "error validating order: "+err.Error())
+		}
+		th.Participants = []string{order.BuyerID, order.Seller}
+	} else {
+		item, err := s.itemSvc.ItemInfo(th.Subject)
+		if err != nil {
+			return chassis.BadRequest(w, "error validating item: "+err.Error())
+		}
+		th.Participants = append(th.Participants, authInfo.UserID, item.Owner)
+	}
+	th.Participants = uniqueIDs(th.Participants)
+	if len(th.Participants) < 2 {
+		return chassis.BadRequest(w, "a thread needs at least two participants")
+	}
+	info, err := s.userSvc.Info(th.Participants)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range th.Participants {
+		if _, ok := info[p]; !ok {
+			return chassis.BadRequest(w, "unknown participant '"+p+"'")
+		}
+	}
+
+	acting, err := s.actingParticipants(authInfo.UserID, th.Participants)
+	if err != nil {
+		return nil, err
+	}
+	if len(acting) == 0 {
+		return chassis.NotFound(w)
+	}
+
+	// Create the thread: whoever starts it has read it.
+	if err = s.db.CreateThread(&th); err != nil {
+		return nil, err
+	}
+	th.Status = types.Open
+	chassis.Emit(s, events.ThreadCreated, th)
+	if err = s.db.MarkThreadRead(th.ID, acting); err != nil {
+		return nil, err
+	}
+
+	// Add thread/blob associations for attachments.
+	if err = s.addBlobs(th.ID, th.Attachments.Blobs()); err != nil {
+		return nil, err
+	}
+
+	s.notifyParticipants(&th, authInfo.UserID, th.Content, otherParticipants(th.Participants, acting), nil)
+
+	return &th, nil
+}
+
+// getThread returns a thread with all its messages, marking it as read
+// by the participants the user acts for.
+func (s *Server) getThread(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	th, acting, err := s.threadForRequest(r)
+	if err != nil {
+		if err == db.ErrThreadNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	if th == nil {
+		return chassis.NotFound(w)
+	}
+
+	msgs, err := s.db.MessagesByParentID(th.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get information about everyone involved.
+	ids := append([]string{th.Author}, th.Participants...)
+	for _, m := range *msgs {
+		ids = append(ids, m.Author)
+	}
+	info, err := s.participantInfo(uniqueIDs(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []model.MessageFull{}
+	for _, m := range *msgs {
+		msg := model.GetMessageFull(m, info[m.Author])
+		//Masking deleted message.
+		if msg.IsDeleted {
+			msg.Content = "This message was deleted."
+			msg.Attachments = []model.Attachment{}
+		}
+		messages = append(messages, msg)
+	}
+
+	if err = s.db.MarkThreadRead(th.ID, acting); err != nil {
+		return nil, err
+	}
+
+	view := model.GetThreadFull(*th, info, &messages)
+	return &view, nil
+}
+
+// patchThread updates a thread. Any participant can lock or close a
+// thread, but only its author can edit it or delete it.
+func (s *Server) patchThread(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	th, acting, err := s.threadForRequest(r)
+	if err != nil {
+		if err == db.ErrThreadNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	if th == nil {
+		return chassis.NotFound(w)
+	}
+	if len(acting) == 0 && !authInfo.UserIsAdmin {
+		return chassis.Forbidden(w)
+	}
+	isAuthor := th.Author == authInfo.UserID || authInfo.UserIsAdmin
+
+	// Read patch request body.
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	picsBefore := map[string]bool{}
+	for _, pic := range th.Attachments.Blobs() {
+		picsBefore[pic] = true
+	}
+	lockBefore := th.LockReply
+	statusBefore := th.Status
+
+	if err = th.Patch(body); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	updates := map[string]interface{}{}
+	json.Unmarshal(body, &updates)
+	_, editContent := updates["content"]
+	_, editAttachments := updates["attachments"]
+	if (editContent || editAttachments) && !isAuthor {
+		return chassis.Forbidden(w)
+	}
+	if th.Status == types.Deleted && statusBefore != types.Deleted && !isAuthor {
+		return chassis.Forbidden(w)
+	}
+
+	// Do the updates.
+	if editContent || editAttachments {
+		if strings.TrimSpace(th.Content) == "" {
+			return chassis.BadRequest(w, "missing thread content")
+		}
+		if err = s.db.UpdateThread(th); err != nil {
+			return nil, err
+		}
+		picsAfter := map[string]bool{}
+		for _, pic := range th.Attachments.Blobs() {
+			picsAfter[pic] = true
+		}
+		if err = s.updateBlobs(th.ID, picsBefore, picsAfter); err != nil {
+			return nil, err
+		}
+	}
+	if th.LockReply != lockBefore {
+		if err = s.db.LockThread(th.ID, th.LockReply); err != nil {
+			return nil, err
+		}
+	}
+	if th.Status != statusBefore {
+		if err = s.db.ChangeThreadStatus(th.ID, th.Status.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	chassis.Emit(s, events.ThreadUpdated, th)
+
+	return th, nil
+}
+
+// createMessage posts a message to a thread. Closed and locked threads
+// don't accept new messages.
+func (s *Server) createMessage(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	th, acting, err := s.threadForRequest(r)
+	if err != nil {
+		if err == db.ErrThreadNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	if th == nil {
+		return chassis.NotFound(w)
+	}
+	if len(acting) == 0 {
+		return chassis.Forbidden(w)
+	}
+	if th.Status != types.Open {
+		return chassis.BadRequest(w, "thread is "+th.Status.String())
+	}
+	if th.LockReply {
+		return chassis.BadRequest(w, "thread is locked")
+	}
+
+	// Read request body.
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	// Unmarshal request data -- validates JSON request body.
+	msg := model.Message{}
+	if err = msg.UnmarshalJSON(body); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if strings.TrimSpace(msg.Content) == "" && len(msg.Attachments) == 0 {
+		return chassis.BadRequest(w, "missing message content")
+	}
+	msg.Author = authInfo.UserID
+	msg.ParentID = th.ID
+	msg.IsEdited = false
+
+	// Find out who had read everything before this message, to notify
+	// them of it.
+	recipients := otherParticipants(th.Participants, acting)
+	unread, err := s.db.ThreadUnread(th.ID, recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the message: whoever sends it has read the thread.
+	if err = s.db.CreateMessage(&msg); err != nil {
+		return nil, err
+	}
+	chassis.Emit(s, events.MessageCreated, msg)
+	if err = s.db.MarkThreadRead(th.ID, acting); err != nil {
+		return nil, err
+	}
+
+	// Add message/blob associations for attachments.
+	if err = s.addBlobs(msg.ID, msg.Attachments.Blobs()); err != nil {
+		return nil, err
+	}
+
+	s.notifyParticipants(th, authInfo.UserID, msg.Content, recipients, unread)
+
+	return &msg, nil
+}
+
+// listUserThreads returns the inbox of the authenticated user.
+func (s *Server) listUserThreads(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+	return s.threadInbox(w, r, authInfo.UserID)
+}
+
+// listOrgThreads returns the inbox of an organisation, for its members
+// and administrators.
+func (s *Server) listOrgThreads(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return chassis.NotFound(w)
+	}
+
+	idOrSlug := chi.URLParam(r, "id_or_slug")
+	info, err := s.userSvc.Info([]string{idOrSlug})
+	if err != nil {
+		return nil, err
+	}
+	orgID := ""
+	for _, v := range info {
+		if v != nil && strings.HasPrefix(v.ID, "org_") {
+			orgID = v.ID
+		}
+	}
+	if orgID == "" {
+		return chassis.NotFound(w)
+	}
+	if !authInfo.UserIsAdmin {
+		isMember, err := s.userSvc.IsUserOrgMember(authInfo.UserID, orgID)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return chassis.NotFound(w)
+		}
+	}
+	return s.threadInbox(w, r, orgID)
+}
+
+// threadInbox lists the threads a user or organisation takes part in,
+// with their unread message counts, optionally filtered by status.
+func (s *Server) threadInbox(w http.ResponseWriter, r *http.Request, participant string) (interface{}, error) {
+	qs := r.URL.Query()
+	pagination := chassis.Pagination{}
+	if err := chassis.PaginationParams(qs, &pagination.Page, &pagination.PerPage); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	status := qs.Get("status")
+	if status != "" {
+		st := types.Unknown
+		if err := st.FromString(status); err != nil {
+			return chassis.BadRequest(w, err.Error())
+		}
+		status = st.String()
+	}
+
+	threads, total, err := s.db.ThreadInbox(participant, status, &pagination)
+	if err != nil {
+		return nil, err
+	}
+	chassis.BuildPaginationResponse(w, r, pagination.Page, pagination.PerPage, *total)
+	return threads, nil
+}
+
+// threadForRequest looks up the thread a request is for and works out
+// which of its participants the authenticated user acts for. The
+// thread is nil if the user can't see it: administrators can see all
+// threads, and other users the threads they or their organisations
+// take part in.
+func (s *Server) threadForRequest(r *http.Request) (*model.Thread, []string, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod == chassis.NoAuth {
+		return nil, nil, nil
+	}
+
+	th, err := s.db.ThreadByID(chi.URLParam(r, "thread_id"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if th.Status == types.Deleted && !authInfo.UserIsAdmin {
+		return nil, nil, db.ErrThreadNotFound
+	}
+
+	acting, err := s.actingParticipants(authInfo.UserID, th.Participants)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(acting) == 0 && !authInfo.UserIsAdmin {
+		return nil, nil, nil
+	}
+	return th, acting, nil
+}
+
+// actingParticipants returns the participants in a thread a user acts
+// for: the user themselves and the organisations they're a member of.
+func (s *Server) actingParticipants(userID string, participants []string) ([]string, error) {
+	acting := []string{}
+	var orgs map[string]bool
+	for _, p := range participants {
+		if p == userID {
+			acting = append(acting, p)
+			continue
+		}
+		if !strings.HasPrefix(p, "org_") {
+			continue
+		}
+		if orgs == nil {
+			var err error
+			if orgs, err = s.userSvc.OrgsForUser(userID); err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := orgs[p]; ok {
+			acting = append(acting, p)
+		}
+	}
+	return acting, nil
+}
+
+// participantInfo gets information about users and organisations,
+// making do with their IDs for any that no longer exist.
+func (s *Server) participantInfo(ids []string) (map[string]*userModel.Info, error) {
+	info, err := s.userSvc.Info(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if info[id] == nil {
+			info[id] = &userModel.Info{ID: id}
+		}
+	}
+	return info, nil
+}
+
+// notifyParticipants emails the recipients of a new message in a
+// thread. Recipients who still haven't read earlier messages in the
+// thread have already been emailed about it, so only those who had
+// read everything are emailed again: with unread nil, everyone is.
+// Errors are logged, since the message has been sent already.
+func (s *Server) notifyParticipants(th *model.Thread, sender, content string,
+	recipients []string, unread map[string]int) {
+	senderName := ""
+	if info, err := s.userSvc.Info([]string{sender}); err != nil {
+		log.Error().Err(err).Str("thread", th.ID).
+			Msg("new-message: could not obtain sender's information from user-service")
+	} else if v, ok := info[sender]; ok && v != nil && v.Name != nil {
+		senderName = *v.Name
+	}
+
+	for _, p := range recipients {
+		if unread[p] > 0 {
+			continue
+		}
+		contact, err := s.userSvc.GetNotificationInfo(p)
+		if err != nil {
+			log.Error().Err(err).Str("thread", th.ID).Str("recipient", p).
+				Msg("new-message: could not obtain notification information from user-service")
+			continue
+		}
+		if contact.Email == "" {
+			continue
+		}
+		notification := buildNewMessageMsg(th, senderName, content, contact)
+		if err = chassis.Emit(s, events.NewMessage, notification); err != nil {
+			log.Error().Err(err).Msg("new-message: could not send event")
+		}
+	}
+}
+
+// Build the message used by the email service to tell a participant
+// in a thread about a new message in it.
+func buildNewMessageMsg(th *model.Thread, senderName, content string,
+	contact *userModel.EmailNotificationInfo) *chassis.GenericEmailMsg {
+	data := chassis.GenericMap{}
+	data["thread_id"] = th.ID
+	data["thread_path"] = "/thread/" + th.ID
+	data["subject"] = th.Subject
+	data["sender_name"] = senderName
+	data["recipient_name"] = contact.Name
+	data["message"] = excerpt(content)
+
+	return &chassis.GenericEmailMsg{
+		FixedFields: chassis.FixedFields{
+			Site:     "ethical.id",
+			Language: "en",
+			Email:    contact.Email,
+		},
+		Data: data,
+	}
+}
+
+// excerpt shortens the content of a post or reply for including in
+// emails.
+func excerpt(content interface{}) string {
+	s, _ := content.(string)
+	if utf8.RuneCountInString(s) > messageExcerptLength {
+		s = string([]rune(s)[:messageExcerptLength]) + "…"
+	}
+	return s
+}
+
+// otherParticipants returns the participants in a thread other than
+// those the sender of a message acts for.
+func otherParticipants(participants, acting []string) []string {
+	sender := map[string]bool{}
+	for _, p := range acting {
+		sender[p] = true
+	}
+	others := []string{}
+	for _, p := range participants {
+		if !sender[p] {
+			others = append(others, p)
+		}
+	}
+	return others
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
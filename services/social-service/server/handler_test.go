@@ -3,7 +3,17 @@ package server
 import (
 	"fmt"
 	"github.com/gavv/httpexpect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/veganbase/backend/chassis"
+	item "github.com/veganbase/backend/services/item-service/client"
+	itemModel "github.com/veganbase/backend/services/item-service/model"
+	"github.com/veganbase/backend/services/social-service/db"
+	"github.com/veganbase/backend/services/social-service/events"
 	"github.com/veganbase/backend/services/social-service/mocks"
+	"github.com/veganbase/backend/services/social-service/model"
+	"github.com/veganbase/backend/services/social-service/model/types"
+	userModel "github.com/veganbase/backend/services/user-service/model"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -18,10 +28,21 @@ var (
 	}
 )
 
+// itemOwners looks up item owners in memory.
+type itemOwners struct {
+	item.Client
+	owners map[string]string
+}
+
+func (c *itemOwners) ItemInfo(id string) (*itemModel.Item, error) {
+	return &itemModel.Item{ID: id, Owner: c.owners[id]}, nil
+}
+
 func RunWithServer(t *testing.T, test func(e *httpexpect.Expect)) {
 	s := &Server{}
 	s.Init("user-service", "dev", 8090, "dev", s.routes())
 	s.db = &dbMock
+	s.itemSvc = &itemOwners{owners: map[string]string{"ABC-000001": "usr_follower"}}
 
 	srv := httptest.NewServer(s.Srv.Handler)
 	defer srv.Close()
@@ -94,3 +115,314 @@ func TestListFollowers(t *testing.T) {
 			Value("followers").Equal(followers)
 	})
 }
+
+func TestThreadInbox(t *testing.T) {
+	userID := "usr_follower"
+	threads := []model.InboxThread{
+		{Thread: model.Thread{ID: "thr_one", Subject: "ABC-000001", Author: "usr_seller",
+			Participants: []string{"usr_seller", userID}, Status: types.Open}, Unread: 2},
+	}
+	total := uint(1)
+
+	RunWithServer(t, func(e *httpexpect.Expect) {
+		dbMock.On("ThreadInbox", userID, "open", mock.Anything).
+			Return(&threads, &total, nil)
+
+		e.GET("/me/threads").
+			WithQuery("status", "open").
+			WithHeaders(auth).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Array().
+			Element(0).Object().
+			ValueEqual("id", "thr_one").
+			ValueEqual("unread", 2)
+
+		e.GET("/me/threads").
+			WithQuery("status", "unknown").
+			WithHeaders(auth).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+}
+
+func TestThreadMessages(t *testing.T) {
+	RunWithServer(t, func(e *httpexpect.Expect) {
+		dbMock.On("ThreadByID", "thr_locked").
+			Return(&model.Thread{ID: "thr_locked", Author: "usr_seller", LockReply: true,
+				Participants: []string{"usr_seller", "usr_follower"}, Status: types.Open}, nil)
+		dbMock.On("ThreadByID", "thr_closed").
+			Return(&model.Thread{ID: "thr_closed", Author: "usr_seller",
+				Participants: []string{"usr_seller", "usr_follower"}, Status: types.Closed}, nil)
+		dbMock.On("ThreadByID", "thr_private").
+			Return(&model.Thread{ID: "thr_private", Author: "usr_seller",
+				Participants: []string{"usr_seller", "usr_buyer"}, Status: types.Open}, nil)
+
+		msg := map[string]interface{}{"content": "Hello"}
+
+		e.POST("/thread/thr_locked/messages").
+			WithHeaders(auth).
+			WithJSON(msg).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().ValueEqual("message", "thread is locked")
+
+		e.POST("/thread/thr_closed/messages").
+			WithHeaders(auth).
+			WithJSON(msg).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().ValueEqual("message", "thread is closed")
+
+		// Threads are invisible to users who don't take part in them.
+		e.POST("/thread/thr_private/messages").
+			WithHeaders(auth).
+			WithJSON(msg).
+			Expect().
+			Status(http.StatusNotFound)
+		e.GET("/thread/thr_private").
+			WithHeaders(auth).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+}
+
+func TestNewMessageNotification(t *testing.T) {
+	others := otherParticipants([]string{"usr_buyer", "org_seller", "usr_other"}, []string{"org_seller"})
+	assert.Equal(t, []string{"usr_buyer", "usr_other"}, others)
+
+	long := ""
+	for i := 0; i < messageExcerptLength+10; i++ {
+		long += "é"
+	}
+	th := &model.Thread{ID: "thr_one", Subject: "ABC-000001"}
+	email := buildNewMessageMsg(th, "Seller", long, &userModel.EmailNotificationInfo{Name: "Buyer", Email: "buyer@example.com"})
+	assert.Equal(t, "buyer@example.com", email.Email)
+	assert.Equal(t, "/thread/thr_one", email.Data["thread_path"])
+	excerpt, _ := email.Data["message"].(string)
+	assert.Len(t, []rune(excerpt), messageExcerptLength+1)
+}
+
+func TestFeed(t *testing.T) {
+	RunWithServer(t, func(e *httpexpect.Expect) {
+		dbMock.On("FeedEntries", "usr_follower", int64(0), uint(31)).
+			Return(&[]model.FeedEntry{}, nil)
+
+		e.GET("/me/feed").
+			WithHeaders(auth).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			NotContainsKey("next_cursor").
+			Value("entries").Array().Empty()
+
+		e.GET("/me/feed").
+			WithQuery("cursor", "abc").
+			WithHeaders(auth).
+			Expect().
+			Status(http.StatusBadRequest)
+
+		e.GET("/me/feed").
+			Expect().
+			Status(http.StatusNotFound)
+	})
+}
+
+func TestFeedPage(t *testing.T) {
+	entries := []model.FeedEntry{{ID: 9}, {ID: 7}, {ID: 4}}
+
+	page, next := feedPage(entries, 2)
+	assert.Len(t, page, 2)
+	assert.Equal(t, "7", next)
+
+	page, next = feedPage(entries, 3)
+	assert.Len(t, page, 3)
+	assert.Equal(t, "", next)
+
+	assert.Equal(t, model.FeedReview, feedKind(model.ReviewPost))
+	assert.Equal(t, model.FeedPost, feedKind(model.QuestionPost))
+}
+
+func TestReportPost(t *testing.T) {
+	RunWithServer(t, func(e *httpexpect.Expect) {
+		dbMock.On("PostById", "rev_own").
+			Return(&model.Post{PostFixed: model.PostFixed{Id: "rev_own", Owner: "usr_follower"}}, nil)
+		dbMock.On("PostById", "rev_reported").
+			Return(&model.Post{PostFixed: model.PostFixed{Id: "rev_reported", Owner: "usr_seller"}}, nil)
+		dbMock.On("CreateReport", mock.Anything).
+			Return(db.ErrAlreadyReported)
+
+		e.POST("/post/rev_own/report").
+			WithHeaders(auth).
+			WithJSON(map[string]interface{}{"reason": "spam"}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().ValueEqual("message", "can't report your own post")
+
+		e.POST("/post/rev_reported/report").
+			WithHeaders(auth).
+			WithJSON(map[string]interface{}{"reason": "rude"}).
+			Expect().
+			Status(http.StatusBadRequest)
+
+		e.POST("/post/rev_reported/report").
+			WithHeaders(auth).
+			WithJSON(map[string]interface{}{"reason": "other"}).
+			Expect().
+			Status(http.StatusBadRequest)
+
+		e.POST("/post/rev_reported/report").
+			WithHeaders(auth).
+			WithJSON(map[string]interface{}{"reason": "fake", "details": "Never sold this."}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().ValueEqual("message", "you have already reported this post")
+	})
+}
+
+func TestModeration(t *testing.T) {
+	admin := map[string]string{
+		"X-Auth-Method":   "session",
+		"X-Auth-User-Id":  "usr_admin",
+		"X-Auth-Is-Admin": "true",
+	}
+
+	RunWithServer(t, func(e *httpexpect.Expect) {
+		dbMock.On("PostById", "rev_hidden").
+			Return(&model.Post{PostFixed: model.PostFixed{Id: "rev_hidden", IsHidden: true}}, nil)
+		dbMock.On("ResolveReports", "rev_clean", types.ReportDismissed, "usr_admin").
+			Return(int64(0), nil)
+
+		// Only administrators can see the moderation queue or moderate.
+		e.GET("/moderation/queue").
+			WithHeaders(auth).
+			Expect().
+			Status(http.StatusNotFound)
+		e.POST("/moderation/post/rev_hidden/restore").
+			WithHeaders(auth).
+			Expect().
+			Status(http.StatusNotFound)
+
+		e.GET("/moderation/queue").
+			WithQuery("status", "pending").
+			WithHeaders(admin).
+			Expect().
+			Status(http.StatusBadRequest)
+
+		e.POST("/moderation/post/rev_hidden/hide").
+			WithHeaders(admin).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().ValueEqual("message", "post already hidden")
+
+		e.POST("/moderation/post/rev_clean/dismiss").
+			WithHeaders(admin).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+}
+
+func TestOfficialResponsesAndAnswers(t *testing.T) {
+	RunWithServer(t, func(e *httpexpect.Expect) {
+		dbMock.On("PostById", "qst_one").
+			Return(&model.Post{PostFixed: model.PostFixed{Id: "qst_one", PostType: model.QuestionPost}}, nil)
+		dbMock.On("PostById", "rev_one").
+			Return(&model.Post{PostFixed: model.PostFixed{Id: "rev_one", PostType: model.ReviewPost}}, nil)
+		dbMock.On("PostById", "rev_deleted").
+			Return(&model.Post{PostFixed: model.PostFixed{Id: "rev_deleted", PostType: model.ReviewPost,
+				IsDeleted: true}}, nil)
+
+		e.POST("/post/qst_one/response").
+			WithHeaders(auth).
+			WithJSON(map[string]interface{}{"content": "Thanks!"}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().ValueEqual("message", "official responses can only be made to reviews")
+
+		e.POST("/post/rev_deleted/response").
+			WithHeaders(auth).
+			WithJSON(map[string]interface{}{"content": "Thanks!"}).
+			Expect().
+			Status(http.StatusNotFound)
+
+		// A review that already has a response is a conflict.
+		dbMock.On("PostById", "rev_answered").
+			Return(&model.Post{PostFixed: model.PostFixed{Id: "rev_answered", PostType: model.ReviewPost,
+				Subject: "ABC-000001"}}, nil)
+		dbMock.On("OfficialResponse", "rev_answered").Return(&model.Reply{}, nil)
+
+		e.POST("/post/rev_answered/response").
+			WithHeaders(auth).
+			WithJSON(map[string]interface{}{"content": "Thanks!"}).
+			Expect().
+			Status(http.StatusConflict).
+			JSON().Object().ValueEqual("message", "review already has an official response")
+
+		// A response created by someone else between the check for an
+		// existing response and the insert is a conflict, and no event
+		// is emitted.
+		dbMock.On("PostById", "rev_raced").
+			Return(&model.Post{PostFixed: model.PostFixed{Id: "rev_raced", PostType: model.ReviewPost,
+				Subject: "ABC-000001"}}, nil)
+		dbMock.On("OfficialResponse", "rev_raced").Return(nil, db.ErrReplyNotFound)
+		dbMock.On("CreateReply", mock.MatchedBy(func(r *model.Reply) bool {
+			return r.ParentId == "rev_raced"
+		})).Return(db.ErrAlreadyResponded)
+
+		e.POST("/post/rev_raced/response").
+			WithHeaders(auth).
+			WithJSON(map[string]interface{}{"content": "Thanks!"}).
+			Expect().
+			Status(http.StatusConflict).
+			JSON().Object().ValueEqual("message", "review already has an official response")
+		dbMock.AssertNotCalled(t, "SaveEvent", events.ReplyCreated, mock.Anything, mock.Anything)
+
+		e.PUT("/post/rev_one/answer").
+			WithHeaders(auth).
+			WithJSON(map[string]interface{}{"reply_id": "rpl_one"}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().ValueEqual("message", "answers can only be chosen for questions")
+
+		e.DELETE("/post/qst_one/answer").
+			WithHeaders(auth).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().ValueEqual("message", "question has no answer")
+	})
+}
+
+func TestSurfaceReplies(t *testing.T) {
+	reply := func(id string, official, deleted bool) model.ReplyFull {
+		return model.ReplyFull{ReplyFullFixed: model.ReplyFullFixed{Id: id, IsOfficial: official, IsDeleted: deleted}}
+	}
+	replies := []model.ReplyFull{
+		reply("rpl_1", false, false),
+		reply("rpl_2", false, false),
+		reply("rpl_3", true, true),
+		reply("rpl_4", false, false),
+		reply("rpl_5", true, false),
+	}
+	answer := "rpl_4"
+	surfaceReplies(replies, &answer)
+
+	ids := []string{}
+	for _, r := range replies {
+		ids = append(ids, r.Id)
+	}
+	assert.Equal(t, []string{"rpl_5", "rpl_4", "rpl_1", "rpl_2", "rpl_3"}, ids)
+	assert.True(t, replies[1].IsAnswer)
+	assert.False(t, replies[0].IsAnswer)
+
+	name := "Vegan cheese"
+	question := &model.Post{PostFixed: model.PostFixed{Id: "qst_one", Owner: "usr_asker"},
+		Attrs: chassis.GenericMap{"content": "Is it nut-free?"}}
+	ans := &model.Reply{Attrs: chassis.GenericMap{"content": "Yes"}}
+	item := &itemModel.Item{ID: "prd_one", Slug: "vegan-cheese", Name: name}
+	email := buildQuestionAnsweredMsg(question, ans, item,
+		&userModel.EmailNotificationInfo{Name: "Asker", Email: "asker@example.com"}, true)
+	assert.Equal(t, "asker@example.com", email.Email)
+	assert.Equal(t, "Is it nut-free?", email.Data["question"])
+	assert.Equal(t, "Yes", email.Data["answer"])
+	assert.Equal(t, true, email.Data["is_asker"])
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/chassis/pubsub"
+	pur_events "github.com/veganbase/backend/services/purchase-service/events"
+	"github.com/veganbase/backend/services/social-service/events"
+)
+
+// Subscription name for marking reviews as verified purchases.
+const verifiedPurchaseSubName = "social-service-paid-purchases"
+
+// purchaseEvent holds the parts of purchase service purchase events
+// needed to verify reviews.
+type purchaseEvent struct {
+	BuyerID string `json:"buyer_id"`
+	Status  string `json:"status"`
+	Items   []struct {
+		ItemID string `json:"item_id"`
+	} `json:"items"`
+}
+
+// HandlePurchaseEvents marks reviews as verified purchases when their
+// authors pay for the items they reviewed, and triggers recalculation
+// of the items' ranks. Purchases are created before they're paid for,
+// so it's purchase updates that are watched, for the purchase's
+// payment being completed.
+func (s *Server) HandlePurchaseEvents() {
+	ch, _, err := s.PubSub.Subscribe(pur_events.PurchaseUpdated, verifiedPurchaseSubName,
+		pubsub.CompetingConsumers)
+	if err != nil {
+		log.Fatal().Err(err).
+			Msg("couldn't subscribe to purchase update events")
+	}
+
+	for {
+		s.handlePurchaseEvent(<-ch)
+	}
+}
+
+// Mark reviews as verified purchases for a single purchase event.
+func (s *Server) handlePurchaseEvent(data []byte) {
+	purchase := purchaseEvent{}
+	if err := json.Unmarshal(data, &purchase); err != nil {
+		log.Error().Err(err).
+			Msg("unmarshalling purchase event")
+		return
+	}
+	if purchase.Status != "completed" {
+		return
+	}
+
+	itemIDs := []string{}
+	for _, it := range purchase.Items {
+		itemIDs = append(itemIDs, it.ItemID)
+	}
+	changed, err := s.db.MarkReviewsBought(purchase.BuyerID, uniqueIDs(itemIDs))
+	if err != nil {
+		log.Error().Err(err).
+			Msgf("marking reviews by '%s' as verified purchases", purchase.BuyerID)
+		return
+	}
+	for _, itemID := range uniqueIDs(changed) {
+		chassis.Emit(s, events.ItemRankTopic, itemID)
+	}
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/veganbase/backend/services/social-service/events"
+	"github.com/veganbase/backend/services/social-service/mocks"
+)
+
+func TestHandlePurchaseEvent(t *testing.T) {
+	dbm := &mocks.DB{}
+	s := &Server{db: dbm}
+	dbm.On("MarkReviewsBought", "usr_BUYER", []string{"itm_1", "itm_2"}).
+		Return([]string{"itm_1"}, nil)
+	dbm.On("SaveEvent", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	// Purchases that haven't been paid for don't verify reviews.
+	for _, status := range []string{"pending", "failed"} {
+		s.handlePurchaseEvent([]byte(`{"buyer_id": "usr_BUYER", "status": "` + status + `",
+"items": [{"item_id": "itm_1"}, {"item_id": "itm_2"}]}`))
+	}
+	dbm.AssertNotCalled(t, "MarkReviewsBought", mock.Anything, mock.Anything)
+	dbm.AssertNotCalled(t, "SaveEvent", mock.Anything, mock.Anything, mock.Anything)
+
+	// Once the payment is completed, they do.
+	s.handlePurchaseEvent([]byte(`{"buyer_id": "usr_BUYER", "status": "completed",
+"items": [{"item_id": "itm_1"}, {"item_id": "itm_2"}, {"item_id": "itm_1"}]}`))
+	dbm.AssertCalled(t, "MarkReviewsBought", "usr_BUYER", []string{"itm_1", "itm_2"})
+	dbm.AssertCalled(t, "SaveEvent", events.ItemRankTopic, "itm_1", mock.Anything)
+}
@@ -40,6 +40,10 @@ func (s *Server) routes() chi.Router {
 	r.Route("/post", func(r chi.Router) {
 		r.Delete("/{post_id}", chassis.SimpleHandler(s.deletePost))
 		r.Patch("/{post_id}", chassis.SimpleHandler(s.patchPost))
+		r.Post("/{post_id}/report", chassis.SimpleHandler(s.reportPost))
+		r.Post("/{post_id}/response", chassis.SimpleHandler(s.createOfficialResponse))
+		r.Put("/{post_id}/answer", chassis.SimpleHandler(s.setAnswer))
+		r.Delete("/{post_id}/answer", chassis.SimpleHandler(s.clearAnswer))
 
 	})
 
@@ -47,6 +51,31 @@ func (s *Server) routes() chi.Router {
 		r.Post("/", chassis.SimpleHandler(s.createReply))
 		r.Delete("/{reply_id}", chassis.SimpleHandler(s.deleteReply))
 		r.Patch("/{reply_id}", chassis.SimpleHandler(s.patchReply))
+		r.Post("/{reply_id}/report", chassis.SimpleHandler(s.reportReply))
+	})
+
+	//MODERATION (administrators only)
+	r.Route("/moderation", func(r chi.Router) {
+		r.Get("/queue", chassis.SimpleHandler(s.moderationQueue))
+		r.Post("/post/{post_id}/hide", chassis.SimpleHandler(s.hidePost))
+		r.Post("/post/{post_id}/restore", chassis.SimpleHandler(s.restorePost))
+		r.Post("/post/{post_id}/dismiss", chassis.SimpleHandler(s.dismissReports))
+		r.Post("/reply/{reply_id}/hide", chassis.SimpleHandler(s.hideReply))
+		r.Post("/reply/{reply_id}/restore", chassis.SimpleHandler(s.restoreReply))
+		r.Post("/reply/{reply_id}/dismiss", chassis.SimpleHandler(s.dismissReports))
+	})
+
+	//ACTIVITY FEED
+	r.Get("/me/feed", chassis.SimpleHandler(s.getFeed))
+
+	//DIRECT MESSAGING
+	r.Post("/threads", chassis.SimpleHandler(s.createThread))
+	r.Get("/me/threads", chassis.SimpleHandler(s.listUserThreads))
+	r.Get("/org/{id_or_slug}/threads", chassis.SimpleHandler(s.listOrgThreads))
+	r.Route("/thread/{thread_id:thr_[a-zA-Z0-9]+}", func(r chi.Router) {
+		r.Get("/", chassis.SimpleHandler(s.getThread))
+		r.Patch("/", chassis.SimpleHandler(s.patchThread))
+		r.Post("/messages", chassis.SimpleHandler(s.createMessage))
 	})
 
 
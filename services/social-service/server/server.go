@@ -24,6 +24,10 @@ type Server struct {
 	purSvc       pur.Client
 	userSvc      user.Client
 	imageBaseURL string
+
+	// Weight given to reviews by users who haven't bought the item
+	// being reviewed when calculating item ranks.
+	unverifiedReviewWeight float64
 }
 
 // Config contains the configuration information needed to start
@@ -40,6 +44,10 @@ type Config struct {
 	BlobServiceURL     string `env:"BLOB_SERVICE_URL,default=http://blob-service"`
 	UserServiceURL     string `env:"USER_SERVICE_URL,default=http://user-service"`
 	ImageBaseURL       string `env:"IMAGE_BASE_URL"`
+
+	// Between 0 (ignore unverified reviews) and 1 (count them the same
+	// as verified purchase reviews).
+	UnverifiedReviewWeight float64 `env:"UNVERIFIED_REVIEW_WEIGHT,default=1"`
 }
 
 // NewServer creates the server structure for the user service.
@@ -47,6 +55,10 @@ func NewServer(cfg *Config) *Server {
 
 	// Common server initialisation.
 	s := &Server{imageBaseURL: cfg.ImageBaseURL,}
+	if cfg.UnverifiedReviewWeight < 0 || cfg.UnverifiedReviewWeight > 1 {
+		log.Fatal().Msg("UNVERIFIED_REVIEW_WEIGHT must be between 0 and 1")
+	}
+	s.unverifiedReviewWeight = cfg.UnverifiedReviewWeight
 	s.Init(cfg.AppName, cfg.Project, cfg.Port, cfg.Credentials, s.routes())
 	chassis.CheckURL(cfg.BlobServiceURL, "blob service")
 	chassis.CheckURL(cfg.ItemServiceURL, "item service")
@@ -59,13 +71,18 @@ func NewServer(cfg *Config) *Server {
 	}
 	timeout, _ := context.WithTimeout(context.Background(), time.Second*10)
 	s.itemSvc = item.New(cfg.ItemServiceURL)
-	s.blobSvc = blob.New(cfg.BlobServiceURL)
+	s.blobSvc, err = blob.Connect(cfg.BlobServiceURL)
+	if err != nil {
+		log.Fatal().Err(err).Msg("couldn't initialise blob service client")
+	}
 	s.purSvc = pur.New(cfg.PurchaseServiceURL)
 
-	s.db, err = db.NewPGClient(timeout, cfg.DBURL)
+	pg, err := db.NewPGClient(timeout, cfg.DBURL)
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't connect to social database")
 	}
+	s.db = pg
+	s.StartEventRelay(pg.DB)
 	model.LoadSchemas()
 	return s
 }
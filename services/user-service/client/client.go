@@ -22,11 +22,14 @@ type Client interface {
 	IsUserOrgAdmin(userID string, orgID string) (bool, error)
 	GetCustomer(userID string) (*model.Customer, error)
 	GetPayoutAccount(ownerId string) (*model.PayoutAccount, error)
+	UpdatePayoutAccountStatus(account string, status *model.PayoutAccountStatus) (*model.PayoutAccount, error)
 	GetDefaultPaymentMethod(userID string) (*model.PaymentMethod, error)
 	GetAddress(userId, addressId string) (*model.Address, error)
 	GetDefaultAddress(userId string) (*model.Address, error)
 	GetNotificationInfo(userId string) (*model.EmailNotificationInfo, error)
-	GetUserByApiKey(apiKey, apiSecret string) (*model.User, error)
+	VerifyAPIKey(apiKey, apiSecret string) (*model.APIKeyAuth, error)
 	GetDeliveryFees(ids []string) (*map[string]model.DeliveryFees, error)
+	GetTaxSettings(ids []string) (map[string]model.TaxSettings, error)
+	GetSellerDetails(id string) (*model.SellerDetails, error)
 	GetSSOSecret(orgIDorSlug string) (*string, error)
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/chassis/pubsub"
 	"github.com/veganbase/backend/services/user-service/events"
+	"github.com/veganbase/backend/services/user-service/messages"
 	"github.com/veganbase/backend/services/user-service/model"
 )
 
@@ -299,6 +300,41 @@ func (c *RESTClient) GetPayoutAccount(ownerId string) (*model.PayoutAccount, err
 	return nil, chassis.BuildErrorFromErrMsg(rsp)
 }
 
+// UpdatePayoutAccountStatus records the status of a connected account
+// reported by the payment provider, identified by its account number,
+// by calling PUT /internal/payout-account-status/{account}.
+func (c *RESTClient) UpdatePayoutAccountStatus(account string, status *model.PayoutAccountStatus) (*model.PayoutAccount, error) {
+	body, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPut,
+		c.baseURL+"/internal/payout-account-status/"+account, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, chassis.BuildErrorFromErrMsg(rsp)
+	}
+
+	rspBody, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp := model.PayoutAccount{}
+	if err = json.Unmarshal(rspBody, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // GetAddress gets the an address of a certain user.
 func (c *RESTClient) GetAddress(userID, addressId string) (*model.Address, error) {
 	url := fmt.Sprintf("%s/internal/user/%s/address/%s", c.baseURL, userID, addressId)
@@ -376,17 +412,25 @@ func (c *RESTClient) GetNotificationInfo(contactId string) (*model.EmailNotifica
 }
 
 
-//GetUserByApiKey returns an user if there is a match with the passed api_key
-func (c *RESTClient) GetUserByApiKey(apiKey, apiSecret string) (*model.User, error) {
-	url := fmt.Sprintf("%s/internal/api-key/%s?secret=%s", c.baseURL, apiKey, apiSecret)
+// VerifyAPIKey checks an API key and secret, returning the
+// authentication information for the key's owner if they are valid.
+func (c *RESTClient) VerifyAPIKey(apiKey, apiSecret string) (*model.APIKeyAuth, error) {
+	// Encode JSON request body: the secret is sent in the body so that
+	// it doesn't appear in request logs.
+	req := messages.APIKeyVerifyRequest{APIKey: apiKey, APISecret: apiSecret}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
 
-	// Do GET to endpoint.
-	rsp, err := http.Get(url)
+	// Do POST to endpoint.
+	buf := bytes.NewBuffer(body)
+	rsp, err := http.Post(c.baseURL+"/internal/api-key/verify", "application/json", buf)
 	if err != nil {
 		return nil, err
 	}
 	if rsp.StatusCode == http.StatusOK {
-		resp := model.User{}
+		resp := model.APIKeyAuth{}
 		rspBody, err := ioutil.ReadAll(rsp.Body)
 		if err != nil {
 			return nil, err
@@ -427,4 +471,58 @@ func (c *RESTClient) GetDeliveryFees(ids []string) (*map[string]model.DeliveryFe
 	}
 
 	return nil, chassis.BuildErrorFromErrMsg(rsp)
-}
\ No newline at end of file
+}
+
+// GetTaxSettings looks up the tax settings of a list of users and
+// organisations. Owners without tax settings are left out of the
+// result.
+func (c *RESTClient) GetTaxSettings(ids []string) (map[string]model.TaxSettings, error) {
+	if len(ids) == 0 {
+		return map[string]model.TaxSettings{}, nil
+	}
+	url := fmt.Sprintf("%s/internal/tax-settings?ids=%s", c.baseURL, strings.Join(ids, ","))
+
+	rsp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, chassis.BuildErrorFromErrMsg(rsp)
+	}
+
+	rspBody, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp := map[string]model.TaxSettings{}
+	if err = json.Unmarshal(rspBody, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetSellerDetails looks up the details of a user or organisation
+// needed to show them as the seller on an invoice.
+func (c *RESTClient) GetSellerDetails(id string) (*model.SellerDetails, error) {
+	url := fmt.Sprintf("%s/internal/seller-details/%s", c.baseURL, id)
+
+	rsp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, chassis.BuildErrorFromErrMsg(rsp)
+	}
+
+	rspBody, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp := model.SellerDetails{}
+	if err = json.Unmarshal(rspBody, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
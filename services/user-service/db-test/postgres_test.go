@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/rs/zerolog/log"
@@ -161,28 +162,70 @@ func TestAPIKeys(t *testing.T) {
 		loadDefaultFixture(pg, t)
 		apikey := "testApiKey1234567"
 		apikey2 := "testApiKey123456789101112"
+		apikey3 := "testApiKeyCI1234567"
 		secret := "maskedAPiSecret1234567"
-		user, err := pg.UserByID("usr_TESTUSER1")
+		keys, err := pg.APIKeys("usr_TESTUSER1")
 		assert.Nil(t, err)
-		assert.Nil(t, user.APIKey, "already has API key")
+		assert.Len(t, keys, 0, "already has API key")
 
-		err = pg.SaveHashedAPIKey(apikey, secret, "usr_TESTUSER1")
+		replaced, err := pg.SaveAPIKey(&model.APIKey{
+			Key: apikey, UserID: "usr_TESTUSER1",
+			Name: model.DefaultAPIKeyName, SecretHash: secret,
+		})
 		assert.Nil(t, err)
-		user, err = pg.UserByID("usr_TESTUSER1")
+		assert.Nil(t, replaced, "nothing should be replaced")
+		key, err := pg.APIKeyByKey(apikey)
 		assert.Nil(t, err)
-		assert.Equal(t, *user.APIKey, apikey, "API key mismatch")
+		assert.Equal(t, key.UserID, "usr_TESTUSER1", "API key owner mismatch")
+		assert.Equal(t, key.SecretHash, secret, "API secret hash mismatch")
+		assert.Nil(t, key.LastUsedAt)
 
-		err = pg.SaveHashedAPIKey(apikey2, secret, "usr_TESTUSER1")
+		// Rotating the default key replaces the old one.
+		replaced, err = pg.SaveAPIKey(&model.APIKey{
+			Key: apikey2, UserID: "usr_TESTUSER1",
+			Name: model.DefaultAPIKeyName, SecretHash: secret,
+		})
 		assert.Nil(t, err)
-		user, err = pg.UserByID("usr_TESTUSER1")
+		if assert.NotNil(t, replaced) {
+			assert.Equal(t, *replaced, apikey, "replaced API key mismatch")
+		}
+		_, err = pg.APIKeyByKey(apikey)
+		assert.Equal(t, err, db.ErrAPIKeyNotFound, "old API key not removed")
+
+		// A second named key.
+		expiry := time.Now().Add(time.Hour)
+		_, err = pg.SaveAPIKey(&model.APIKey{
+			Key: apikey3, UserID: "usr_TESTUSER1",
+			Name: "ci", SecretHash: secret, ExpiresAt: &expiry,
+		})
+		assert.Nil(t, err)
+		keys, err = pg.APIKeys("usr_TESTUSER1")
 		assert.Nil(t, err)
-		assert.Equal(t, *user.APIKey, apikey2, "API key mismatch")
+		if assert.Len(t, keys, 2) {
+			assert.Equal(t, keys[0].Name, "ci")
+			assert.NotNil(t, keys[0].ExpiresAt)
+			assert.Equal(t, keys[1].Key, apikey2)
+		}
 
-		err = pg.DeleteAPIKey("usr_TESTUSER1")
+		assert.Nil(t, pg.TouchAPIKey(apikey3, time.Now()))
+		key, err = pg.APIKeyByKey(apikey3)
 		assert.Nil(t, err)
-		user, err = pg.UserByID("usr_TESTUSER1")
+		assert.NotNil(t, key.LastUsedAt, "last used time not set")
+
+		_, err = pg.SaveAPIKey(&model.APIKey{
+			Key: "testApiKeyUnknown", UserID: "usr_UNKNOWN",
+			Name: model.DefaultAPIKeyName, SecretHash: secret,
+		})
+		assert.Equal(t, err, db.ErrUserNotFound)
+
+		deleted, err := pg.DeleteAPIKey("usr_TESTUSER1", model.DefaultAPIKeyName)
+		assert.Nil(t, err)
+		assert.Equal(t, deleted, apikey2, "deleted API key mismatch")
+		_, err = pg.DeleteAPIKey("usr_TESTUSER1", model.DefaultAPIKeyName)
+		assert.Equal(t, err, db.ErrAPIKeyNotFound)
+		keys, err = pg.APIKeys("usr_TESTUSER1")
 		assert.Nil(t, err)
-		assert.Nil(t, user.APIKey, "API key not deleted")
+		assert.Len(t, keys, 1, "API key not deleted")
 	})
 }
 
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/veganbase/backend/services/user-service/model"
+)
+
+// APIKeys returns all the API keys belonging to a user, in name
+// order.
+func (pg *PGClient) APIKeys(userID string) ([]model.APIKey, error) {
+	keys := []model.APIKey{}
+	if err := pg.DB.Select(&keys, apiKeysByUser, userID); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+const apiKeysByUser = `
+SELECT key, user_id, name, secret_hash, created_at, last_used_at, expires_at
+  FROM api_keys
+ WHERE user_id = $1
+ ORDER BY name`
+
+// APIKeyByKey looks up an API key, including the hash of its secret.
+func (pg *PGClient) APIKeyByKey(key string) (*model.APIKey, error) {
+	apiKey := &model.APIKey{}
+	if err := pg.DB.Get(apiKey, apiKeyByKey, key); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return apiKey, nil
+}
+
+const apiKeyByKey = `
+SELECT key, user_id, name, secret_hash, created_at, last_used_at, expires_at
+  FROM api_keys
+ WHERE key = $1`
+
+// SaveAPIKey saves a new API key, replacing any existing key with the
+// same name belonging to the same user. The replaced key (if any) is
+// returned so that cached copies can be invalidated.
+func (pg *PGClient) SaveAPIKey(key *model.APIKey) (*string, error) {
+	tx, err := pg.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		switch err {
+		case nil:
+			err = tx.Commit()
+		default:
+			tx.Rollback()
+		}
+	}()
+
+	check := 0
+	err = tx.Get(&check, `SELECT COUNT(*) FROM users WHERE id = $1`, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if check != 1 {
+		err = ErrUserNotFound
+		return nil, err
+	}
+
+	var replaced *string
+	old := ""
+	err = tx.Get(&old, deleteNamedAPIKey, key.UserID, key.Name)
+	switch err {
+	case nil:
+		replaced = &old
+	case sql.ErrNoRows:
+		err = nil
+	default:
+		return nil, err
+	}
+
+	rows, err := tx.NamedQuery(createAPIKey, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		if err = rows.Scan(&key.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	return replaced, nil
+}
+
+const deleteNamedAPIKey = `
+DELETE FROM api_keys WHERE user_id = $1 AND name = $2 RETURNING key`
+
+const createAPIKey = `
+INSERT INTO api_keys (key, user_id, name, secret_hash, expires_at)
+     VALUES (:key, :user_id, :name, :secret_hash, :expires_at)
+  RETURNING created_at`
+
+// TouchAPIKey records the time at which an API key was last used.
+func (pg *PGClient) TouchAPIKey(key string, at time.Time) error {
+	_, err := pg.DB.Exec(touchAPIKey, key, at)
+	return err
+}
+
+const touchAPIKey = `UPDATE api_keys SET last_used_at = $2 WHERE key = $1`
+
+// DeleteAPIKey deletes a user's API key by name, returning the
+// deleted key.
+func (pg *PGClient) DeleteAPIKey(userID, name string) (string, error) {
+	key := ""
+	err := pg.DB.Get(&key, deleteNamedAPIKey, userID, name)
+	if err == sql.ErrNoRows {
+		return "", ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 	"github.com/veganbase/backend/services/user-service/messages"
 	"github.com/veganbase/backend/services/user-service/model"
@@ -35,9 +37,16 @@ var ErrAddressNotFound = errors.New("address not found")
 // access or manipulate an delivery fee with an unknown ID or owner.
 var ErrDeliveryFeesNotFound = errors.New("delivery fees not found")
 
+// ErrTaxSettingsNotFound is the error returned when an attempt is made
+// to access or manipulate tax settings for an owner that has none.
+var ErrTaxSettingsNotFound = errors.New("tax settings not found")
+
+// ErrAPIKeyNotFound is the error returned when an attempt is made to
+// access or manipulate an unknown API key.
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
 // ErrReadOnlyField is the error returned when an attempt is made to
-// update a read-only field for a user (e.g. email, last login time,
-// API key).
+// update a read-only field for a user (e.g. email, last login time).
 var ErrReadOnlyField = errors.New("attempt to modify read-only field")
 
 // ErrUserAlreadyInOrg is the error returned when an attempt is made
@@ -54,9 +63,6 @@ type DB interface {
 	// IDs as a map indexed by the ID.
 	UsersByIDs(ids []string) (map[string]*model.User, error)
 
-	// UserByAPIKey returns a user with an specific apy_key
-	UserByAPIKey(apiKey string) (*model.User, error)
-
 	// Users gets a list of users in reverse last login date order,
 	// optionally filtered by a search term and paginated.
 	Users(search string, page, perPage uint) ([]model.User, error)
@@ -80,16 +86,32 @@ type DB interface {
 	LoginUser(email string, avatarGen func() string) (*model.User, bool, error)
 
 	// UpdateUser updates the user's details in the database. The id,
-	// email, last_login and api_key fields are read-only using this
-	// method.
+	// email and last_login fields are read-only using this method.
 	UpdateUser(user *model.User) error
 
 	// DeleteUser deletes the given user account.
 	// TODO: ADD SOME SORT OF ARCHIVAL MECHANISM INSTEAD.
 	DeleteUser(id string) error
 
-	SaveHashedAPIKey(key, secret, userID string) error
-	DeleteAPIKey(id string) error
+	// APIKeys returns all the API keys belonging to a user, in name
+	// order.
+	APIKeys(userID string) ([]model.APIKey, error)
+
+	// APIKeyByKey looks up an API key, including the hash of its
+	// secret.
+	APIKeyByKey(key string) (*model.APIKey, error)
+
+	// SaveAPIKey saves a new API key, replacing any existing key with
+	// the same name belonging to the same user. The replaced key (if
+	// any) is returned so that cached copies can be invalidated.
+	SaveAPIKey(key *model.APIKey) (*string, error)
+
+	// TouchAPIKey records the time at which an API key was last used.
+	TouchAPIKey(key string, at time.Time) error
+
+	// DeleteAPIKey deletes a user's API key by name, returning the
+	// deleted key.
+	DeleteAPIKey(userID, name string) (string, error)
 
 	// CreateOrg creates a new organisation.
 	CreateOrg(org *model.Organisation) error
@@ -140,6 +162,7 @@ type DB interface {
 	PayoutAccountByOwner(ownerId string) (*model.PayoutAccount, error)
 	PayoutAccountById(id string) (*model.PayoutAccount, error)
 	UpdatePayoutAccount(acc *model.PayoutAccount) error
+	UpdatePayoutAccountStatus(account string, status *model.PayoutAccountStatus) (*model.PayoutAccount, error)
 	DeletePayoutAccount(id string) error
 
 	//Payment-methods
@@ -173,6 +196,13 @@ type DB interface {
 	DeleteDeliveryFees(id string) error
 	//used internally to get delivery fees of multiple users/orgs with one request
 	GetDeliveryFees(ids []string) (map[string]model.DeliveryFees, error)
+
+	//Tax settings
+	TaxSettingsByOwner(owner string) (*model.TaxSettings, error)
+	SaveTaxSettings(settings *model.TaxSettings) error
+	DeleteTaxSettings(owner string) error
+	//used internally to get tax settings of multiple users/orgs with one request
+	GetTaxSettings(ids []string) (map[string]model.TaxSettings, error)
 	// SaveEvent saves an event to the database.
 	SaveEvent(topic string, eventData interface{}, inTx func() error) error
 }
@@ -0,0 +1,42 @@
+-- +migrate Up
+
+SET ROLE vb_users;
+
+CREATE TABLE api_keys (
+  key          TEXT        PRIMARY KEY,
+  user_id      TEXT        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name         TEXT        NOT NULL,
+  secret_hash  TEXT        NOT NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_used_at TIMESTAMPTZ,
+  expires_at   TIMESTAMPTZ,
+
+  UNIQUE (user_id, name)
+);
+
+CREATE INDEX api_keys_user_idx ON api_keys(user_id);
+
+-- Existing keys (whose secrets are already stored as bcrypt hashes)
+-- become each user's "default" key.
+INSERT INTO api_keys (key, user_id, name, secret_hash)
+  SELECT api_key, id, 'default', secret_key
+    FROM users
+   WHERE api_key IS NOT NULL AND secret_key IS NOT NULL;
+
+ALTER TABLE users DROP CONSTRAINT unique_api_keys;
+ALTER TABLE users DROP COLUMN api_key;
+ALTER TABLE users DROP COLUMN secret_key;
+
+-- +migrate Down
+
+SET ROLE vb_users;
+
+ALTER TABLE users ADD COLUMN api_key TEXT;
+ALTER TABLE users ADD COLUMN secret_key TEXT;
+ALTER TABLE users ADD CONSTRAINT unique_api_keys UNIQUE (api_key);
+
+UPDATE users SET api_key = k.key, secret_key = k.secret_hash
+  FROM api_keys k
+ WHERE k.user_id = users.id AND k.name = 'default';
+
+DROP TABLE api_keys;
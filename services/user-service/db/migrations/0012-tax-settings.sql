@@ -0,0 +1,18 @@
+-- +migrate Up
+
+SET ROLE vb_users;
+
+CREATE TABLE tax_settings (
+  owner              VARCHAR(24) PRIMARY KEY,
+  country            CHAR(2)     NOT NULL,
+  vat_number         TEXT,
+  prices_include_tax BOOLEAN     NOT NULL DEFAULT TRUE,
+  created_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at         TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +migrate Down
+
+SET ROLE vb_users;
+
+DROP TABLE tax_settings;
@@ -0,0 +1,34 @@
+-- +migrate Up
+
+SET ROLE vb_users;
+
+-- Status of sellers' connected accounts, synchronised from the
+-- payment provider's account update webhooks. Existing accounts keep
+-- receiving transfers until their status is first reported.
+ALTER TABLE payout_accounts
+  ADD COLUMN details_submitted BOOLEAN     NOT NULL DEFAULT false,
+  ADD COLUMN charges_enabled   BOOLEAN     NOT NULL DEFAULT false,
+  ADD COLUMN payouts_enabled   BOOLEAN     NOT NULL DEFAULT false,
+  ADD COLUMN capabilities      JSONB       NOT NULL DEFAULT '{}',
+  ADD COLUMN requirements      TEXT[]      NOT NULL DEFAULT '{}',
+  ADD COLUMN disabled_reason   TEXT,
+  ADD COLUMN status_updated_at TIMESTAMPTZ,
+  ADD COLUMN transfers_enabled BOOLEAN     NOT NULL DEFAULT true;
+
+CREATE INDEX payout_accounts_account_idx ON payout_accounts(account);
+
+-- +migrate Down
+
+SET ROLE vb_users;
+
+DROP INDEX payout_accounts_account_idx;
+
+ALTER TABLE payout_accounts
+  DROP COLUMN details_submitted,
+  DROP COLUMN charges_enabled,
+  DROP COLUMN payouts_enabled,
+  DROP COLUMN capabilities,
+  DROP COLUMN requirements,
+  DROP COLUMN disabled_reason,
+  DROP COLUMN status_updated_at,
+  DROP COLUMN transfers_enabled;
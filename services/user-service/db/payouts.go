@@ -43,8 +43,8 @@ func (pg *PGClient) CreatePayoutAccount(acc *model.PayoutAccount) error {
 
 const qCreatePayoutAccount = `
 INSERT INTO
-  payout_accounts (id, account, owner)
- VALUES (:id, :account, :owner)
+  payout_accounts (id, account, owner, transfers_enabled)
+ VALUES (:id, :account, :owner, :transfers_enabled)
  RETURNING created_at`
 
 // PayoutAccountByOwner looks up for a payout account of a certain owner.
@@ -73,7 +73,9 @@ func (pg *PGClient) PayoutAccountById(id string) (*model.PayoutAccount, error) {
 }
 
 const payoutAccountBy = `
-SELECT id, account, owner, created_at
+SELECT id, account, owner, created_at,
+       details_submitted, charges_enabled, payouts_enabled, capabilities,
+       requirements, disabled_reason, status_updated_at, transfers_enabled
   FROM payout_accounts
  WHERE `
 
@@ -114,6 +116,37 @@ UPDATE payout_accounts
 SET account=:account, owner =:owner
 WHERE id = :id`
 
+// UpdatePayoutAccountStatus records the status of a connected account
+// reported by the payment provider, working out from it whether the
+// account can receive transfers. Returns the updated payout account.
+func (pg *PGClient) UpdatePayoutAccountStatus(account string, status *model.PayoutAccountStatus) (*model.PayoutAccount, error) {
+	if status.Requirements == nil {
+		status.Requirements = []string{}
+	}
+	acc := &model.PayoutAccount{}
+	err := pg.DB.Get(acc, qUpdatePayoutAccountStatus, account,
+		status.DetailsSubmitted, status.ChargesEnabled, status.PayoutsEnabled,
+		status.Capabilities, status.Requirements, status.DisabledReason,
+		status.CanReceiveTransfers())
+	if err == sql.ErrNoRows {
+		return nil, ErrPayoutAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+const qUpdatePayoutAccountStatus = `
+UPDATE payout_accounts
+   SET details_submitted = $2, charges_enabled = $3, payouts_enabled = $4,
+       capabilities = $5, requirements = $6, disabled_reason = $7,
+       transfers_enabled = $8, status_updated_at = now()
+ WHERE account = $1
+RETURNING id, account, owner, created_at,
+       details_submitted, charges_enabled, payouts_enabled, capabilities,
+       requirements, disabled_reason, status_updated_at, transfers_enabled`
+
 // DeletePayoutAccount deletes the given payout account.
 // TODO: ADD SOME SORT OF ARCHIVAL MECHANISM INSTEAD.
 func (pg *PGClient) DeletePayoutAccount(id string) error {
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/veganbase/backend/services/user-service/model"
+)
+
+const qTaxSettingsBy = `
+SELECT owner, country, vat_number, prices_include_tax, created_at, updated_at
+  FROM tax_settings WHERE `
+
+// TaxSettingsByOwner looks up the tax settings of a user or
+// organisation.
+func (pg *PGClient) TaxSettingsByOwner(owner string) (*model.TaxSettings, error) {
+	settings := &model.TaxSettings{}
+	if err := pg.DB.Get(settings, qTaxSettingsBy+`owner = $1`, owner); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaxSettingsNotFound
+		}
+		return nil, err
+	}
+	return settings, nil
+}
+
+// SaveTaxSettings creates or replaces the tax settings of a user or
+// organisation.
+func (pg *PGClient) SaveTaxSettings(settings *model.TaxSettings) error {
+	rows, err := pg.DB.NamedQuery(qSaveTaxSettings, settings)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return rows.Scan(&settings.CreatedAt, &settings.UpdatedAt)
+	}
+	return nil
+}
+
+const qSaveTaxSettings = `
+INSERT INTO tax_settings (owner, country, vat_number, prices_include_tax)
+VALUES (:owner, :country, :vat_number, :prices_include_tax)
+ON CONFLICT (owner) DO UPDATE
+   SET country = EXCLUDED.country,
+       vat_number = EXCLUDED.vat_number,
+       prices_include_tax = EXCLUDED.prices_include_tax,
+       updated_at = now()
+RETURNING created_at, updated_at`
+
+// DeleteTaxSettings deletes the tax settings of a user or
+// organisation.
+func (pg *PGClient) DeleteTaxSettings(owner string) error {
+	result, err := pg.DB.Exec(`DELETE FROM tax_settings WHERE owner = $1`, owner)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		return ErrTaxSettingsNotFound
+	}
+	return nil
+}
+
+// GetTaxSettings looks up the tax settings of a list of users and
+// organisations, returning a map keyed by owner. Owners without tax
+// settings are left out of the result.
+func (pg *PGClient) GetTaxSettings(ids []string) (map[string]model.TaxSettings, error) {
+	q, args, err := sqlx.In(qTaxSettingsBy+`owner IN (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	settings := []model.TaxSettings{}
+	if err = pg.DB.Select(&settings, pg.DB.Rebind(q), args...); err != nil {
+		return nil, err
+	}
+	result := map[string]model.TaxSettings{}
+	for _, ts := range settings {
+		result[ts.Owner] = ts
+	}
+	return result, nil
+}
@@ -30,31 +30,10 @@ func (pg *PGClient) UserByID(id string) (*model.User, error) {
 
 const userBy = `
 SELECT id, email, name, display_name, avatar,
-       country, is_admin, last_login, api_key
+       country, is_admin, last_login
   FROM users
  WHERE `
 
-const userWithSecretBy = `
-SELECT id, email, name, display_name, avatar,
-       country, is_admin, last_login, api_key, secret_key
-  FROM users
- WHERE `
-
-
-// UserByAPIKey looks up a user by it's API key.
-func (pg *PGClient) UserByAPIKey(apiKey string) (*model.User, error) {
-	user := &model.User{}
-
-	if err := pg.DB.Get(user, userWithSecretBy + "api_key = $1", apiKey); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrUserNotFound
-		}
-		return nil, err
-	}
-	return user, nil
-}
-
-
 // UsersByIDs returns the full user model for a given list of user
 // IDs as a map indexed by the ID.
 func (pg *PGClient) UsersByIDs(ids []string) (map[string]*model.User, error) {
@@ -85,7 +64,7 @@ func (pg *PGClient) UsersByIDs(ids []string) (map[string]*model.User, error) {
 
 const usersByIDs = `
 SELECT id, email, name, display_name, avatar,
-       country, is_admin, last_login, api_key
+       country, is_admin, last_login
   FROM users
  WHERE id IN (?)`
 
@@ -109,13 +88,13 @@ func (pg *PGClient) Users(search string, page, perPage uint) ([]model.User, erro
 
 const userList = `
 SELECT id, email, name, display_name, avatar,
-       country, is_admin, last_login, api_key
+       country, is_admin, last_login
   FROM users
  ORDER BY last_login DESC`
 
 const userListWithSearch = `
 SELECT id, email, name, display_name, avatar,
-       country, is_admin, last_login, api_key
+       country, is_admin, last_login
   FROM users
  WHERE email LIKE $1 OR name LIKE $1 OR display_name LIKE $1
  ORDER BY last_login DESC`
@@ -184,8 +163,7 @@ INSERT INTO users (id, email, name, display_name, avatar, is_admin, last_login)
      VALUES (:id, :email, :name, :display_name, :avatar, :is_admin, :last_login)`
 
 // UpdateUser updates the user's details in the database. The id,
-// email, last_login and api_key fields are read-only using this
-// method.
+// email and last_login fields are read-only using this method.
 func (pg *PGClient) UpdateUser(user *model.User) error {
 	tx, err := pg.DB.Beginx()
 	if err != nil {
@@ -214,8 +192,7 @@ func (pg *PGClient) UpdateUser(user *model.User) error {
 	// themselves. This needs to be checked in the handler that calls
 	// the update, since we don't have the authentication information
 	// needed to make this decision here.
-	if user.Email != check.Email ||
-		user.LastLogin != check.LastLogin || user.APIKey != check.APIKey {
+	if user.Email != check.Email || user.LastLogin != check.LastLogin {
 		return ErrReadOnlyField
 	}
 
@@ -261,51 +238,6 @@ func (pg *PGClient) DeleteUser(id string) error {
 
 const deleteUser = "DELETE FROM users WHERE id = $1"
 
-// SaveHashedAPIKey saves the hashed api key.
-func (pg *PGClient) SaveHashedAPIKey(key, secret, userID string) error {
-	result, err := pg.DB.Exec(rotateAPIKey, key, secret, userID)
-	if err != nil {
-		return err
-	}
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows != 1 {
-		return ErrUserNotFound
-	}
-	return  nil
-}
-
-const rotateAPIKey = `
-UPDATE users 
-SET api_key = $1,
- 	secret_key = $2 
-WHERE id = $3`
-
-// DeleteAPIKey deletes the API key for a user.
-func (pg *PGClient) DeleteAPIKey(id string) error {
-	result, err := pg.DB.Exec(deleteAPIKey, id)
-	if err != nil {
-		return err
-	}
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows != 1 {
-		return ErrUserNotFound
-	}
-	return nil
-}
-
-const deleteAPIKey = `
-UPDATE users 
-SET api_key = NULL, 
-    secret_key = NULL 
-WHERE id = $1`
-
-
 func (pg *PGClient) NotificationInfoByUserId(userId string) (*model.EmailNotificationInfo, error) {
 	info := &model.EmailNotificationInfo{}
 	if err := pg.DB.Get(info, qNotificationInfoByUserID, userId); err != nil {
@@ -24,8 +24,16 @@ const (
 	DeliveryFeesCreated  = "delivery-fees-created"
 	DeliveryFeesUpdated  = "delivery-fees-updated"
 	DeliveryFeesDeleted  = "delivery-fees-deleted"
+	TaxSettingsUpdated   = "tax-settings-updated"
+	TaxSettingsDeleted   = "tax-settings-deleted"
 )
 
 // UserCacheInvalTopic is a Pub/Sub topic used to invalidate cached
 // user information when users are updated or deleted.
 const UserCacheInvalTopic = "invalidate-cached-user"
+
+// APIKeyCacheInvalTopic is a Pub/Sub topic used to invalidate cached
+// API key verification results when keys are replaced or deleted, or
+// when the user owning them is updated or deleted. Messages contain
+// the API key to invalidate.
+const APIKeyCacheInvalTopic = "invalidate-cached-api-key"
@@ -1,11 +1,29 @@
 package messages
 
+import "time"
+
+// APIKeyRequest is the (optional) request body used to create a new
+// API key.
+type APIKeyRequest struct {
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
 // APIKeyResponse is the response sent containing a new API key.
 type APIKeyResponse struct {
+	APIKey    string     `json:"api_key"`
+	APISecret string     `json:"api_secret"`
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyVerifyRequest is the request sent by the API gateway to
+// verify an API key and secret.
+type APIKeyVerifyRequest struct {
 	APIKey    string `json:"api_key"`
 	APISecret string `json:"api_secret"`
 }
 
 type SSOSecret struct {
 	Secret *string `json:"secret" db:"sso_secret"`
-}
\ No newline at end of file
+}
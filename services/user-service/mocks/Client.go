@@ -198,22 +198,45 @@ func (_m *Client) GetSSOSecret(orgIDorSlug string) (*string, error) {
 	return r0, r1
 }
 
-// GetUserByApiKey provides a mock function with given fields: apiKey, apiSecret
-func (_m *Client) GetUserByApiKey(apiKey string, apiSecret string) (*model.User, error) {
-	ret := _m.Called(apiKey, apiSecret)
+// GetSellerDetails provides a mock function with given fields: id
+func (_m *Client) GetSellerDetails(id string) (*model.SellerDetails, error) {
+	ret := _m.Called(id)
 
-	var r0 *model.User
-	if rf, ok := ret.Get(0).(func(string, string) *model.User); ok {
-		r0 = rf(apiKey, apiSecret)
+	var r0 *model.SellerDetails
+	if rf, ok := ret.Get(0).(func(string) *model.SellerDetails); ok {
+		r0 = rf(id)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.User)
+			r0 = ret.Get(0).(*model.SellerDetails)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(apiKey, apiSecret)
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTaxSettings provides a mock function with given fields: ids
+func (_m *Client) GetTaxSettings(ids []string) (map[string]model.TaxSettings, error) {
+	ret := _m.Called(ids)
+
+	var r0 map[string]model.TaxSettings
+	if rf, ok := ret.Get(0).(func([]string) map[string]model.TaxSettings); ok {
+		r0 = rf(ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]model.TaxSettings)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]string) error); ok {
+		r1 = rf(ids)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -331,3 +354,49 @@ func (_m *Client) OrgsForUser(id string) (map[string]bool, error) {
 
 	return r0, r1
 }
+
+// UpdatePayoutAccountStatus provides a mock function with given fields: account, status
+func (_m *Client) UpdatePayoutAccountStatus(account string, status *model.PayoutAccountStatus) (*model.PayoutAccount, error) {
+	ret := _m.Called(account, status)
+
+	var r0 *model.PayoutAccount
+	if rf, ok := ret.Get(0).(func(string, *model.PayoutAccountStatus) *model.PayoutAccount); ok {
+		r0 = rf(account, status)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.PayoutAccount)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, *model.PayoutAccountStatus) error); ok {
+		r1 = rf(account, status)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VerifyAPIKey provides a mock function with given fields: apiKey, apiSecret
+func (_m *Client) VerifyAPIKey(apiKey string, apiSecret string) (*model.APIKeyAuth, error) {
+	ret := _m.Called(apiKey, apiSecret)
+
+	var r0 *model.APIKeyAuth
+	if rf, ok := ret.Get(0).(func(string, string) *model.APIKeyAuth); ok {
+		r0 = rf(apiKey, apiSecret)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.APIKeyAuth)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(apiKey, apiSecret)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	time "time"
+
 	mock "github.com/stretchr/testify/mock"
 	messages "github.com/veganbase/backend/services/user-service/messages"
 
@@ -14,6 +16,52 @@ type DB struct {
 	mock.Mock
 }
 
+// APIKeyByKey provides a mock function with given fields: key
+func (_m *DB) APIKeyByKey(key string) (*model.APIKey, error) {
+	ret := _m.Called(key)
+
+	var r0 *model.APIKey
+	if rf, ok := ret.Get(0).(func(string) *model.APIKey); ok {
+		r0 = rf(key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// APIKeys provides a mock function with given fields: userID
+func (_m *DB) APIKeys(userID string) ([]model.APIKey, error) {
+	ret := _m.Called(userID)
+
+	var r0 []model.APIKey
+	if rf, ok := ret.Get(0).(func(string) []model.APIKey); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // AddressById provides a mock function with given fields: id
 func (_m *DB) AddressById(id string) (*model.Address, error) {
 	ret := _m.Called(id)
@@ -213,18 +261,25 @@ func (_m *DB) DefaultPaymentMethodByUserId(id string) (*model.PaymentMethod, err
 	return r0, r1
 }
 
-// DeleteAPIKey provides a mock function with given fields: id
-func (_m *DB) DeleteAPIKey(id string) error {
-	ret := _m.Called(id)
+// DeleteAPIKey provides a mock function with given fields: userID, name
+func (_m *DB) DeleteAPIKey(userID string, name string) (string, error) {
+	ret := _m.Called(userID, name)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(id)
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, string) string); ok {
+		r0 = rf(userID, name)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(string)
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(userID, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // DeleteAddress provides a mock function with given fields: id
@@ -325,6 +380,20 @@ func (_m *DB) DeleteSSOSecret(id string) error {
 	return r0
 }
 
+// DeleteTaxSettings provides a mock function with given fields: owner
+func (_m *DB) DeleteTaxSettings(owner string) error {
+	ret := _m.Called(owner)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(owner)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteUser provides a mock function with given fields: id
 func (_m *DB) DeleteUser(id string) error {
 	ret := _m.Called(id)
@@ -431,6 +500,29 @@ func (_m *DB) GetSSOSecretByOrgIDOrSlug(id string) (*messages.SSOSecret, error)
 	return r0, r1
 }
 
+// GetTaxSettings provides a mock function with given fields: ids
+func (_m *DB) GetTaxSettings(ids []string) (map[string]model.TaxSettings, error) {
+	ret := _m.Called(ids)
+
+	var r0 map[string]model.TaxSettings
+	if rf, ok := ret.Get(0).(func([]string) map[string]model.TaxSettings); ok {
+		r0 = rf(ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]model.TaxSettings)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]string) error); ok {
+		r1 = rf(ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Info provides a mock function with given fields: ids
 func (_m *DB) Info(ids []string) (map[string]model.Info, error) {
 	ret := _m.Called(ids)
@@ -747,6 +839,29 @@ func (_m *DB) RotateSSOSecret(secret string, orgID string) error {
 	return r0
 }
 
+// SaveAPIKey provides a mock function with given fields: key
+func (_m *DB) SaveAPIKey(key *model.APIKey) (*string, error) {
+	ret := _m.Called(key)
+
+	var r0 *string
+	if rf, ok := ret.Get(0).(func(*model.APIKey) *string); ok {
+		r0 = rf(key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*model.APIKey) error); ok {
+		r1 = rf(key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SaveEvent provides a mock function with given fields: topic, eventData, inTx
 func (_m *DB) SaveEvent(topic string, eventData interface{}, inTx func() error) error {
 	ret := _m.Called(topic, eventData, inTx)
@@ -761,13 +876,50 @@ func (_m *DB) SaveEvent(topic string, eventData interface{}, inTx func() error)
 	return r0
 }
 
-// SaveHashedAPIKey provides a mock function with given fields: key, secret, userID
-func (_m *DB) SaveHashedAPIKey(key string, secret string, userID string) error {
-	ret := _m.Called(key, secret, userID)
+// SaveTaxSettings provides a mock function with given fields: settings
+func (_m *DB) SaveTaxSettings(settings *model.TaxSettings) error {
+	ret := _m.Called(settings)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
-		r0 = rf(key, secret, userID)
+	if rf, ok := ret.Get(0).(func(*model.TaxSettings) error); ok {
+		r0 = rf(settings)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TaxSettingsByOwner provides a mock function with given fields: owner
+func (_m *DB) TaxSettingsByOwner(owner string) (*model.TaxSettings, error) {
+	ret := _m.Called(owner)
+
+	var r0 *model.TaxSettings
+	if rf, ok := ret.Get(0).(func(string) *model.TaxSettings); ok {
+		r0 = rf(owner)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.TaxSettings)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(owner)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TouchAPIKey provides a mock function with given fields: key, at
+func (_m *DB) TouchAPIKey(key string, at time.Time) error {
+	ret := _m.Called(key, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, time.Time) error); ok {
+		r0 = rf(key, at)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -845,36 +997,22 @@ func (_m *DB) UpdatePayoutAccount(acc *model.PayoutAccount) error {
 	return r0
 }
 
-// UpdateUser provides a mock function with given fields: user
-func (_m *DB) UpdateUser(user *model.User) error {
-	ret := _m.Called(user)
-
-	var r0 error
-	if rf, ok := ret.Get(0).(func(*model.User) error); ok {
-		r0 = rf(user)
-	} else {
-		r0 = ret.Error(0)
-	}
-
-	return r0
-}
-
-// UserByAPIKey provides a mock function with given fields: apiKey
-func (_m *DB) UserByAPIKey(apiKey string) (*model.User, error) {
-	ret := _m.Called(apiKey)
+// UpdatePayoutAccountStatus provides a mock function with given fields: account, status
+func (_m *DB) UpdatePayoutAccountStatus(account string, status *model.PayoutAccountStatus) (*model.PayoutAccount, error) {
+	ret := _m.Called(account, status)
 
-	var r0 *model.User
-	if rf, ok := ret.Get(0).(func(string) *model.User); ok {
-		r0 = rf(apiKey)
+	var r0 *model.PayoutAccount
+	if rf, ok := ret.Get(0).(func(string, *model.PayoutAccountStatus) *model.PayoutAccount); ok {
+		r0 = rf(account, status)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.User)
+			r0 = ret.Get(0).(*model.PayoutAccount)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(apiKey)
+	if rf, ok := ret.Get(1).(func(string, *model.PayoutAccountStatus) error); ok {
+		r1 = rf(account, status)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -882,6 +1020,20 @@ func (_m *DB) UserByAPIKey(apiKey string) (*model.User, error) {
 	return r0, r1
 }
 
+// UpdateUser provides a mock function with given fields: user
+func (_m *DB) UpdateUser(user *model.User) error {
+	ret := _m.Called(user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.User) error); ok {
+		r0 = rf(user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UserByID provides a mock function with given fields: id
 func (_m *DB) UserByID(id string) (*model.User, error) {
 	ret := _m.Called(id)
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+)
+
+// DefaultAPIKeyName is the name given to API keys created without an
+// explicit name.
+const DefaultAPIKeyName = "default"
+
+// APIKey is a named API key belonging to a user. Only a salted hash
+// of the key's secret is stored: the secret itself is returned to the
+// user once, when the key is created.
+type APIKey struct {
+	// The API key itself, passed in the X-Api-Key header.
+	Key string `json:"api_key" db:"key"`
+
+	// The ID of the user owning the key.
+	UserID string `json:"user_id" db:"user_id"`
+
+	// The key's name, unique for each user.
+	Name string `json:"name" db:"name"`
+
+	// The salted hash of the key's secret.
+	SecretHash string `json:"-" db:"secret_hash"`
+
+	// Key creation time.
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// The last time the key was used to authenticate a request (this
+	// is approximate, since the API gateway caches verified keys).
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+
+	// Optional key expiry time.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+}
+
+// Expired determines whether an API key has expired.
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// APIKeyAuth is the authentication information returned to the API
+// gateway after successful verification of an API key and secret.
+type APIKeyAuth struct {
+	UserID    string     `json:"user_id"`
+	IsAdmin   bool       `json:"is_admin"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
@@ -24,7 +24,6 @@ func (u *User) Patch(patch []byte, actingID string, adminAction bool) error {
 		"id":         "ID",
 		"email":      "email",
 		"last_login": "last login time",
-		"api_key":    "API key",
 	}
 	for fld, label := range roFields {
 		if _, ok := updates[fld]; ok {
@@ -156,9 +155,17 @@ func (u *PayoutAccount) Patch(patch []byte) error {
 		return errors.Wrap(err, "unmarshaling patch")
 	}
 	roFields := map[string]string{
-		"id":         "ID",
-		"created_at": "created_at",
-		"owner":      "owner",
+		"id":                "ID",
+		"created_at":        "created_at",
+		"owner":             "owner",
+		"details_submitted": "details_submitted",
+		"charges_enabled":   "charges_enabled",
+		"payouts_enabled":   "payouts_enabled",
+		"capabilities":      "capabilities",
+		"requirements":      "requirements",
+		"disabled_reason":   "disabled_reason",
+		"status_updated_at": "status_updated_at",
+		"transfers_enabled": "transfers_enabled",
 	}
 
 	for fld, label := range roFields {
@@ -1,7 +1,12 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"time"
+
+	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
 )
 
 // PayoutAccount are the accounts that the payments will be forwarded to each seller or host when a purchase is fulfilled.
@@ -10,9 +15,76 @@ type PayoutAccount struct {
 	AccountNumber string    `json:"account" db:"account"`
 	Owner         string    `json:"owner" db:"owner"`
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	PayoutAccountStatus
+
+	// Whether the account can currently receive transfers. Sales of
+	// the owner's items are blocked while it can't.
+	TransfersEnabled bool `json:"transfers_enabled" db:"transfers_enabled"`
 }
 
 type PayoutAccountRequest struct {
 	PayoutAccount
 	Code string `json:"code,omitempty"`
 }
+
+// PayoutAccountStatus is the state of a seller's connected account
+// at the payment provider, as reported by account update webhooks.
+// Accounts whose status has never been reported were connected
+// before status tracking was introduced, and are assumed to be able
+// to receive transfers.
+type PayoutAccountStatus struct {
+	DetailsSubmitted bool                `json:"details_submitted" db:"details_submitted"`
+	ChargesEnabled   bool                `json:"charges_enabled" db:"charges_enabled"`
+	PayoutsEnabled   bool                `json:"payouts_enabled" db:"payouts_enabled"`
+	Capabilities     AccountCapabilities `json:"capabilities" db:"capabilities"`
+
+	// Information the payment provider needs from the seller before the
+	// account is fully enabled, and the reason it's disabled, if it is.
+	Requirements    pq.StringArray `json:"requirements" db:"requirements"`
+	DisabledReason  *string        `json:"disabled_reason,omitempty" db:"disabled_reason"`
+	StatusUpdatedAt *time.Time     `json:"status_updated_at,omitempty" db:"status_updated_at"`
+}
+
+// AccountCapabilities maps the capabilities of a connected account
+// ("transfers", "card_payments", etc.) to their status ("active",
+// "inactive" or "pending").
+type AccountCapabilities map[string]string
+
+// CanReceiveTransfers works out whether an account in this state can
+// receive transfers: it needs an active transfers capability and no
+// reason to be disabled.
+func (st *PayoutAccountStatus) CanReceiveTransfers() bool {
+	if st.DisabledReason != nil && *st.DisabledReason != "" {
+		return false
+	}
+	return st.Capabilities["transfers"] == "active"
+}
+
+// Scan implements the sql.Scanner interface.
+func (c *AccountCapabilities) Scan(src interface{}) error {
+	j := types.JSONText{}
+	if err := j.Scan(src); err != nil {
+		return err
+	}
+	return json.Unmarshal(j, c)
+}
+
+// Value implements the driver.Valuer interface.
+func (c AccountCapabilities) Value() (driver.Value, error) {
+	if c == nil {
+		c = AccountCapabilities{}
+	}
+	v, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return types.JSONText(v).Value()
+}
+
+// PayoutOnboardingLink is a link to the payment provider's hosted
+// onboarding flow, where a seller enters the details needed to set up
+// their payout account.
+type PayoutOnboardingLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+// SellerDetails is the view of a user or organisation used by other
+// services to identify them as the seller on invoices.
+type SellerDetails struct {
+	ID      string         `json:"id"`
+	Name    string         `json:"name"`
+	Email   string         `json:"email,omitempty"`
+	Phone   string         `json:"phone,omitempty"`
+	Address *SellerAddress `json:"address,omitempty"`
+
+	// The seller's VAT registration, if they have tax settings.
+	TaxCountry string  `json:"tax_country,omitempty"`
+	VATNumber  *string `json:"vat_number,omitempty"`
+}
+
+// SellerAddress is the postal address of a seller.
+type SellerAddress struct {
+	StreetAddress string `json:"street_address"`
+	City          string `json:"city"`
+	Postcode      string `json:"postcode,omitempty"`
+	Country       string `json:"country"`
+}
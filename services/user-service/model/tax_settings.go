@@ -0,0 +1,47 @@
+package model
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TaxSettings holds the tax registration details of a seller, used to
+// work out the tax due on their orders. Sellers without tax settings
+// are treated as not registered for VAT, and no tax is charged on
+// their orders.
+type TaxSettings struct {
+	Owner string `json:"owner" db:"owner"`
+
+	// The country where the seller is registered for VAT, as a
+	// two-letter ISO country code.
+	Country   string  `json:"country" db:"country"`
+	VATNumber *string `json:"vat_number,omitempty" db:"vat_number"`
+
+	// Whether the seller's prices include tax. If not, tax is added to
+	// the prices of the seller's items when they are bought.
+	PricesIncludeTax bool      `json:"prices_include_tax" db:"prices_include_tax"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+var countryRegexp = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Validate checks a seller's tax settings, normalising the country
+// code to upper case.
+func (ts *TaxSettings) Validate() error {
+	ts.Country = strings.ToUpper(ts.Country)
+	if !countryRegexp.MatchString(ts.Country) {
+		return errors.New("country must be a two-letter ISO country code")
+	}
+	if ts.VATNumber != nil {
+		vat := strings.ToUpper(strings.ReplaceAll(*ts.VATNumber, " ", ""))
+		if vat == "" {
+			ts.VATNumber = nil
+		} else {
+			ts.VATNumber = &vat
+		}
+	}
+	return nil
+}
@@ -37,11 +37,4 @@ type User struct {
 	// Time of last login (never null because user accounts are created
 	// only when the user first logs in).
 	LastLogin time.Time `json:"last_login" db:"last_login"`
-
-	// The user's API key (which may be empty).
-	APIKey *string `json:"api_key,omitempty" db:"api_key"`
-
-	// The user's API secret key (which may be empty).
-	APISecretKey *string `json:"secret_key,omitempty" db:"secret_key"`
-
 }
@@ -1,51 +1,130 @@
 package server
 
 import (
+	"encoding/json"
 	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog/log"
 
 	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/user-service/db"
 	"github.com/veganbase/backend/services/user-service/events"
 	"github.com/veganbase/backend/services/user-service/messages"
+	"github.com/veganbase/backend/services/user-service/model"
 )
 
+// Length of generated API keys and secrets.
+const apiKeyLength = 32
+
+// API key names are used in URLs, so are restricted to a simple
+// character set.
+var apiKeyNameRE = regexp.MustCompile("^[a-zA-Z0-9_-]{1,64}$")
+
+// Hash compared against when verifying an unknown API key, so that
+// verification takes the same time whether or not the key exists.
+var dummySecretHash string
+
+func init() {
+	var err error
+	dummySecretHash, err = chassis.HashAndSalt(chassis.NewBareID(apiKeyLength))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (s *Server) apiKeys(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	userID, _, _ := accessControl(r)
+	if userID == nil {
+		return chassis.NotFound(w)
+	}
+
+	return s.db.APIKeys(*userID)
+}
+
 func (s *Server) createAPIKey(w http.ResponseWriter, r *http.Request) (interface{}, error) {
 	userID, _, _ := accessControl(r)
 	if userID == nil {
 		return chassis.NotFound(w)
 	}
 
-	rawKey, rawSecret, encryptedSecret, err := s.generateNewAPIKey()
+	// The request body is optional: without it, the user's default key
+	// is created (or rotated).
+	body, err := chassis.ReadBody(r, 1)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	req := messages.APIKeyRequest{}
+	if len(body) > 0 {
+		if err = json.Unmarshal(body, &req); err != nil {
+			return chassis.BadRequest(w, err.Error())
+		}
+	}
+	if req.Name == "" {
+		req.Name = model.DefaultAPIKeyName
+	}
+	if !apiKeyNameRE.MatchString(req.Name) {
+		return chassis.BadRequest(w, "invalid API key name")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return chassis.BadRequest(w, "API key expiry time must be in the future")
+	}
+
+	rawKey, rawSecret, hashedSecret, err := s.generateNewAPIKey()
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.db.SaveHashedAPIKey(*rawKey, *encryptedSecret, *userID); err != nil {
+	key := model.APIKey{
+		Key:        rawKey,
+		UserID:     *userID,
+		Name:       req.Name,
+		SecretHash: hashedSecret,
+		ExpiresAt:  req.ExpiresAt,
+	}
+	replaced, err := s.db.SaveAPIKey(&key)
+	if err != nil {
 		if err == db.ErrUserNotFound {
 			return chassis.NotFound(w)
 		}
 		return nil, err
 	}
+	if replaced != nil {
+		s.invalidateAPIKey(*replaced)
+	}
 
-	chassis.Emit(s, events.CreateAPIKey, map[string]string{"user_id": *userID})
+	chassis.Emit(s, events.CreateAPIKey, map[string]string{
+		"user_id": *userID,
+		"api_key": key.Key,
+		"name":    key.Name,
+	})
 	response := messages.APIKeyResponse{
-		APIKey: *rawKey,
-		APISecret: *rawSecret,
+		APIKey:    rawKey,
+		APISecret: rawSecret,
+		Name:      key.Name,
+		ExpiresAt: key.ExpiresAt,
 	}
 	return &response, nil
 }
 
-func (s *Server) generateNewAPIKey() (*string,*string,*string, error) {
-
-	rawKey := chassis.NewBareID(32)
-	rawSecret := chassis.NewBareID(32)
+func (s *Server) generateNewAPIKey() (string, string, string, error) {
+	rawKey, err := chassis.NewSecretID(apiKeyLength)
+	if err != nil {
+		return "", "", "", err
+	}
+	rawSecret, err := chassis.NewSecretID(apiKeyLength)
+	if err != nil {
+		return "", "", "", err
+	}
 
-	hashedSecret, err :=  chassis.HashAndSalt(rawSecret)
+	hashedSecret, err := chassis.HashAndSalt(rawSecret)
 	if err != nil {
-		return nil, nil, nil, err
+		return "", "", "", err
 	}
 
-	return &rawKey, &rawSecret, &hashedSecret, nil
+	return rawKey, rawSecret, hashedSecret, nil
 }
 
 func (s *Server) deleteAPIKey(w http.ResponseWriter, r *http.Request) (interface{}, error) {
@@ -53,15 +132,102 @@ func (s *Server) deleteAPIKey(w http.ResponseWriter, r *http.Request) (interface
 	if userID == nil {
 		return chassis.NotFound(w)
 	}
+	name := chi.URLParam(r, "name")
+	if name == "" {
+		name = model.DefaultAPIKeyName
+	}
 
-	err := s.db.DeleteAPIKey(*userID)
-	if err == db.ErrUserNotFound {
+	key, err := s.db.DeleteAPIKey(*userID, name)
+	if err == db.ErrAPIKeyNotFound {
 		return chassis.NotFound(w)
 	}
 	if err != nil {
 		return nil, err
 	}
+	s.invalidateAPIKey(key)
 
-	chassis.Emit(s, events.DeleteAPIKey, map[string]string{"user_id": *userID})
+	chassis.Emit(s, events.DeleteAPIKey, map[string]string{
+		"user_id": *userID,
+		"api_key": key,
+		"name":    name,
+	})
 	return chassis.NoContent(w)
 }
+
+// verifyAPIKeyInternal checks an API key and secret on behalf of the
+// API gateway, returning the authentication information for the
+// key's owner. Unknown keys, expired keys and incorrect secrets all
+// result in the same "not found" response.
+func (s *Server) verifyAPIKeyInternal(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	req := messages.APIKeyVerifyRequest{}
+	if err = json.Unmarshal(body, &req); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if req.APIKey == "" || req.APISecret == "" {
+		return chassis.BadRequest(w, "API key or secret is missing")
+	}
+
+	key, err := s.db.APIKeyByKey(req.APIKey)
+	if err != nil && err != db.ErrAPIKeyNotFound {
+		return nil, err
+	}
+
+	// The bcrypt comparison is constant-time with respect to the
+	// secret, and is done against a dummy hash for unknown keys.
+	hash := dummySecretHash
+	if key != nil {
+		hash = key.SecretHash
+	}
+	if !chassis.CompareHashedKeys(hash, req.APISecret) || key == nil {
+		return chassis.NotFound(w)
+	}
+	now := time.Now()
+	if key.Expired(now) {
+		return chassis.NotFound(w)
+	}
+
+	user, err := s.db.UserByID(key.UserID)
+	if err == db.ErrUserNotFound {
+		return chassis.NotFound(w)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.db.TouchAPIKey(key.Key, now); err != nil {
+		log.Error().Err(err).Str("user_id", key.UserID).
+			Msg("recording API key use")
+	}
+
+	return &model.APIKeyAuth{
+		UserID:    user.ID,
+		IsAdmin:   user.IsAdmin,
+		ExpiresAt: key.ExpiresAt,
+	}, nil
+}
+
+// invalidateAPIKey removes an API key from API key caches in other
+// services.
+func (s *Server) invalidateAPIKey(key string) {
+	s.PubSub.Publish(events.APIKeyCacheInvalTopic, key)
+}
+
+// userAPIKeys returns the keys belonging to a user, for invalidation
+// of cached keys after changes to the user.
+func (s *Server) userAPIKeys(userID string) []string {
+	keys, err := s.db.APIKeys(userID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID).
+			Msg("listing API keys for cache invalidation")
+		return nil
+	}
+	result := make([]string, len(keys))
+	for i, k := range keys {
+		result[i] = k.Key
+	}
+	return result
+}
@@ -102,6 +102,10 @@ func (s *Server) delete(w http.ResponseWriter, r *http.Request) (interface{}, er
 		return chassis.NotFound(w)
 	}
 
+	// The user's API keys are deleted along with the user, so must be
+	// found before deletion for cache invalidation.
+	apiKeys := s.userAPIKeys(*userID)
+
 	err := s.db.DeleteUser(*userID)
 	if err == db.ErrUserNotFound {
 		return chassis.NotFound(w)
@@ -111,6 +115,9 @@ func (s *Server) delete(w http.ResponseWriter, r *http.Request) (interface{}, er
 	}
 	chassis.Emit(s, events.UserDeleted, map[string]string{"user_id": *userID})
 	s.Invalidate(*userID)
+	for _, k := range apiKeys {
+		s.invalidateAPIKey(k)
+	}
 	w.WriteHeader(http.StatusNoContent)
 	return nil, nil
 }
@@ -143,6 +150,9 @@ func (s *Server) update(w http.ResponseWriter, r *http.Request) (interface{}, er
 	}
 	chassis.Emit(s, events.UserUpdated, user)
 	s.Invalidate(*userID)
+	for _, k := range s.userAPIKeys(*userID) {
+		s.invalidateAPIKey(k)
+	}
 
 	return user, nil
 }
@@ -162,33 +172,3 @@ func (s *Server) getNotificationInfoInternal(w http.ResponseWriter, r *http.Requ
 	return s.db.NotificationInfoByOrgId(id)
 
 }
-
-func (s *Server) getUserByApiKeyInternal(w http.ResponseWriter, r *http.Request) (interface{}, error) {
-	key := chi.URLParam(r, "key")
-	if key == "" {
-		return chassis.BadRequest(w, "key is missing")
-	}
-
-	qs, err := url.ParseQuery(r.URL.RawQuery)
-	if err != nil {
-		return chassis.BadRequest(w, "invalid query parameters")
-	}
-
-	secret := qs.Get("secret")
-	if secret == "" {
-		return chassis.BadRequest(w, "secret is missing")
-	}
-
-	//getting user by key
-	user, err := s.db.UserByAPIKey(key)
-	if err != nil {
-		return nil, err
-	}
-
-	//validating if secret matches
-	if chassis.CompareHashedKeys(*user.APISecretKey, secret) {
-		user.APISecretKey = nil
-		return user, err
-	}
-	return chassis.NotFound(w)
-}
\ No newline at end of file
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"github.com/go-chi/chi"
 	"github.com/stripe/stripe-go"
+	"github.com/stripe/stripe-go/account"
+	"github.com/stripe/stripe-go/accountlink"
 	"github.com/stripe/stripe-go/oauth"
+	"strings"
 	"github.com/veganbase/backend/services/user-service/db"
 	"github.com/veganbase/backend/services/user-service/events"
 	"time"
@@ -83,8 +86,11 @@ func (s *Server) createPayoutAccount(w http.ResponseWriter, r *http.Request) (in
 		return chassis.BadRequest(w, "error while acquiring user-credentials on Stripe")
 	}
 
-	//setting account number and saving
+	//setting account number and saving: the status of accounts connected
+	//this way isn't known until the payment provider reports it, so they
+	//can receive transfers until then
 	acc.AccountNumber = auth.StripeUserID
+	acc.TransfersEnabled = true
 	if err = s.db.CreatePayoutAccount(&acc.PayoutAccount); err != nil {
 		return nil, err
 	}
@@ -245,4 +251,142 @@ func (s *Server) fetchUserCredentials(code string) (*stripe.OAuthToken, error) {
 	}
 	return oauth.New(params)
 
-}
\ No newline at end of file
+}
+
+// userPayoutOnboarding starts or continues onboarding for the
+// authenticated user's payout account.
+func (s *Server) userPayoutOnboarding(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth {
+		return chassis.NotFound(w)
+	}
+	user, err := s.db.UserByID(authInfo.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.payoutOnboarding(w, r, user.ID, user.Email)
+}
+
+// orgPayoutOnboarding starts or continues onboarding for an
+// organisation's payout account.
+func (s *Server) orgPayoutOnboarding(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	org, allowed, err := s.orgModAllowed(w, r, false)
+	if !allowed {
+		return nil, err
+	}
+	return s.payoutOnboarding(w, r, org.ID, org.Email)
+}
+
+// payoutOnboarding returns a link to the payment provider's hosted
+// onboarding flow for an owner's payout account. If the owner doesn't
+// have a payout account yet, a connected account is created for them
+// first, which can't receive transfers until onboarding is complete.
+// The account's country may be given in the request body, and
+// otherwise defaults to the country of the owner's tax settings.
+func (s *Server) payoutOnboarding(w http.ResponseWriter, r *http.Request, owner, email string) (interface{}, error) {
+	req := struct {
+		Country string `json:"country"`
+	}{}
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if len(body) > 0 {
+		if err = json.Unmarshal(body, &req); err != nil {
+			return chassis.BadRequest(w, err.Error())
+		}
+	}
+
+	acc, err := s.db.PayoutAccountByOwner(owner)
+	if err != nil && err != db.ErrPayoutAccountNotFound {
+		return nil, err
+	}
+	if acc == nil {
+		country := strings.ToUpper(req.Country)
+		if country == "" {
+			settings, err := s.db.TaxSettingsByOwner(owner)
+			if err != nil && err != db.ErrTaxSettingsNotFound {
+				return nil, err
+			}
+			if settings != nil {
+				country = settings.Country
+			}
+		}
+
+		connected, err := s.createConnectedAccount(owner, email, country)
+		if err != nil {
+			return chassis.BadRequest(w, "error while creating connected account on Stripe: "+err.Error())
+		}
+		acc = &model.PayoutAccount{AccountNumber: connected.ID, Owner: owner}
+		if err = s.db.CreatePayoutAccount(acc); err != nil {
+			return nil, err
+		}
+		chassis.Emit(s, events.PayoutAccountCreated, acc)
+	}
+
+	link, err := s.createOnboardingLink(acc.AccountNumber)
+	if err != nil {
+		return chassis.BadRequest(w, "error while creating onboarding link on Stripe: "+err.Error())
+	}
+	return &model.PayoutOnboardingLink{
+		URL:       link.URL,
+		ExpiresAt: time.Unix(link.ExpiresAt, 0),
+	}, nil
+}
+
+// updatePayoutStatusInternal records the status of a connected
+// account reported by the payment provider. This method is used
+// internally (by payment-service, when it receives account update
+// webhooks) and there is no authentication.
+func (s *Server) updatePayoutStatusInternal(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	status := model.PayoutAccountStatus{}
+	if err = json.Unmarshal(body, &status); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	acc, err := s.db.UpdatePayoutAccountStatus(chi.URLParam(r, "account"), &status)
+	if err != nil {
+		if err == db.ErrPayoutAccountNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+
+	chassis.Emit(s, events.PayoutAccountUpdated, acc)
+	return acc, nil
+}
+
+//createConnectedAccount creates an Express connected account on Stripe that can receive transfers.
+func (s *Server) createConnectedAccount(owner, email, country string) (*stripe.Account, error) {
+	stripe.Key = s.stripeKey
+
+	params := &stripe.AccountParams{
+		Type:                  stripe.String(string(stripe.AccountTypeExpress)),
+		RequestedCapabilities: []*string{stripe.String("transfers")},
+	}
+	if email != "" {
+		params.Email = stripe.String(email)
+	}
+	if country != "" {
+		params.Country = stripe.String(country)
+	}
+	params.AddMetadata("owner", owner)
+	return account.New(params)
+}
+
+//createOnboardingLink creates a single-use link to Stripe's hosted onboarding flow for a connected account.
+func (s *Server) createOnboardingLink(accountNumber string) (*stripe.AccountLink, error) {
+	stripe.Key = s.stripeKey
+
+	params := &stripe.AccountLinkParams{
+		Account: stripe.String(accountNumber),
+		Type:    stripe.String("account_onboarding"),
+	}
+	params.AddExtra("refresh_url", s.onboardingRefreshURL)
+	params.AddExtra("return_url", s.onboardingReturnURL)
+	return accountlink.New(params)
+}
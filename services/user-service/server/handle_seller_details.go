@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/user-service/db"
+	"github.com/veganbase/backend/services/user-service/model"
+)
+
+// getSellerDetailsInternal returns the details of a user or
+// organisation needed to identify them as a seller on invoices: name,
+// contact details, address and VAT registration.
+func (s *Server) getSellerDetailsInternal(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	id := chi.URLParam(r, "id")
+
+	details := model.SellerDetails{ID: id}
+	if id[:4] == "usr_" {
+		user, err := s.db.UserByID(id)
+		if err == db.ErrUserNotFound {
+			return chassis.NotFoundWithMessage(w, "user not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		details.Email = user.Email
+		if user.Name != nil {
+			details.Name = *user.Name
+		} else if user.DisplayName != nil {
+			details.Name = *user.DisplayName
+		}
+
+		// Users don't have a business address, so use their default
+		// address, if they have one.
+		addr, err := s.db.DefaultAddressByUserId(id)
+		if err != nil && err != db.ErrAddressNotFound {
+			return nil, err
+		}
+		if addr != nil {
+			details.Address = &model.SellerAddress{
+				StreetAddress: addr.StreetAddress,
+				City:          addr.City,
+				Postcode:      addr.Postcode,
+				Country:       addr.Country,
+			}
+		}
+	} else {
+		org, err := s.db.OrgByIDorSlug(id)
+		if err == db.ErrOrgNotFound {
+			return chassis.NotFoundWithMessage(w, "organisation not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		details.Name = org.Name
+		details.Email = org.Email
+		details.Phone = org.Phone
+		if len(org.Address) > 0 && string(org.Address) != "null" {
+			addr := model.SellerAddress{}
+			if err = json.Unmarshal(org.Address, &addr); err != nil {
+				return nil, err
+			}
+			details.Address = &addr
+		}
+	}
+
+	settings, err := s.db.TaxSettingsByOwner(id)
+	if err != nil && err != db.ErrTaxSettingsNotFound {
+		return nil, err
+	}
+	if settings != nil {
+		details.TaxCountry = settings.Country
+		details.VATNumber = settings.VATNumber
+	}
+
+	return &details, nil
+}
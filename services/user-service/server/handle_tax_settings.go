@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/veganbase/backend/chassis"
+	"github.com/veganbase/backend/services/user-service/db"
+	"github.com/veganbase/backend/services/user-service/events"
+	"github.com/veganbase/backend/services/user-service/model"
+)
+
+// getUserTaxSettings returns the tax settings of the authenticated user.
+func (s *Server) getUserTaxSettings(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth {
+		return chassis.NotFound(w)
+	}
+	return s.getTaxSettings(w, authInfo.UserID)
+}
+
+// getOrgTaxSettings returns the tax settings of an organisation.
+func (s *Server) getOrgTaxSettings(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	org, allowed, err := s.orgModAllowed(w, r, false)
+	if !allowed {
+		return nil, err
+	}
+	return s.getTaxSettings(w, org.ID)
+}
+
+func (s *Server) getTaxSettings(w http.ResponseWriter, owner string) (interface{}, error) {
+	settings, err := s.db.TaxSettingsByOwner(owner)
+	if err != nil {
+		if err == db.ErrTaxSettingsNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+	return settings, nil
+}
+
+// putUserTaxSettings sets the tax settings of the authenticated user.
+func (s *Server) putUserTaxSettings(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth {
+		return chassis.NotFound(w)
+	}
+	return s.putTaxSettings(w, r, authInfo.UserID)
+}
+
+// putOrgTaxSettings sets the tax settings of an organisation.
+func (s *Server) putOrgTaxSettings(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	org, allowed, err := s.orgModAllowed(w, r, false)
+	if !allowed {
+		return nil, err
+	}
+	return s.putTaxSettings(w, r, org.ID)
+}
+
+// putTaxSettings creates or replaces the tax settings of a user or
+// organisation.
+func (s *Server) putTaxSettings(w http.ResponseWriter, r *http.Request, owner string) (interface{}, error) {
+	body, err := chassis.ReadBody(r, 0)
+	if err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	settings := model.TaxSettings{}
+	if err = json.Unmarshal(body, &settings); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+	if settings.Owner != "" && settings.Owner != owner {
+		return chassis.BadRequest(w, "can't set read-only field 'owner'")
+	}
+	settings.Owner = owner
+	if err = settings.Validate(); err != nil {
+		return chassis.BadRequest(w, err.Error())
+	}
+
+	if err = s.db.SaveTaxSettings(&settings); err != nil {
+		return nil, err
+	}
+
+	chassis.Emit(s, events.TaxSettingsUpdated, settings)
+	return settings, nil
+}
+
+// deleteUserTaxSettings deletes the tax settings of the authenticated
+// user.
+func (s *Server) deleteUserTaxSettings(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	authInfo := chassis.AuthInfoFromContext(r.Context())
+	if authInfo.AuthMethod != chassis.SessionAuth {
+		return chassis.NotFound(w)
+	}
+	return s.deleteTaxSettings(w, authInfo.UserID)
+}
+
+// deleteOrgTaxSettings deletes the tax settings of an organisation.
+func (s *Server) deleteOrgTaxSettings(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	org, allowed, err := s.orgModAllowed(w, r, false)
+	if !allowed {
+		return nil, err
+	}
+	return s.deleteTaxSettings(w, org.ID)
+}
+
+func (s *Server) deleteTaxSettings(w http.ResponseWriter, owner string) (interface{}, error) {
+	if err := s.db.DeleteTaxSettings(owner); err != nil {
+		if err == db.ErrTaxSettingsNotFound {
+			return chassis.NotFoundWithMessage(w, err.Error())
+		}
+		return nil, err
+	}
+
+	chassis.Emit(s, events.TaxSettingsDeleted, owner)
+	return chassis.NoContent(w)
+}
+
+// getTaxSettingsInternal returns the tax settings of a list of users
+// and organisations, keyed by owner.
+func (s *Server) getTaxSettingsInternal(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	qs, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		return chassis.BadRequest(w, "error while parsing the query")
+	}
+	rawIds := qs.Get("ids")
+	if rawIds == "" {
+		return chassis.BadRequest(w, "missing ids")
+	}
+
+	return s.db.GetTaxSettings(strings.Split(rawIds, ","))
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/gavv/httpexpect"
 	"github.com/stretchr/testify/mock"
+	"github.com/veganbase/backend/chassis"
 	"github.com/veganbase/backend/services/user-service/db"
 	"github.com/veganbase/backend/services/user-service/mocks"
 	"github.com/veganbase/backend/services/user-service/model"
@@ -74,6 +75,25 @@ var (
 		"X-Auth-Is-Admin": "true",
 	}
 	dbMock = mocks.DB{}
+
+	k1Secret = "k1secret"
+	k1Hash, _ = chassis.HashAndSalt(k1Secret)
+	k1 = model.APIKey{
+		Key:        "K1K1K1K1K1K1K1K1K1K1K1K1K1K1K1K1",
+		UserID:     "usr_TESTUSER1",
+		Name:       "default",
+		SecretHash: k1Hash,
+		CreatedAt:  loginTime,
+	}
+	k2Expiry = loginTime.Add(time.Hour)
+	k2       = model.APIKey{
+		Key:        "K2K2K2K2K2K2K2K2K2K2K2K2K2K2K2K2",
+		UserID:     "usr_TESTUSER1",
+		Name:       "ci",
+		SecretHash: k1Hash,
+		CreatedAt:  loginTime,
+		ExpiresAt:  &k2Expiry,
+	}
 )
 
 func RunWithServer(t *testing.T, test func(e *httpexpect.Expect)) {
@@ -243,6 +263,7 @@ func TestUpdate(t *testing.T) {
 		dbMock.On("UserByID", mock.Anything).Return(nil, db.ErrUserNotFound)
 		dbMock.
 			On("UpdateUser", mock.Anything).Return(nil)
+		dbMock.On("APIKeys", mock.Anything).Return([]model.APIKey{k1}, nil)
 		dbMock.
 			On("SaveEvent", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
@@ -336,6 +357,7 @@ func TestDelete(t *testing.T) {
 		dbMock.On("DeleteUser", "usr_TESTUSER1").Return(nil)
 		dbMock.On("DeleteUser", "usr_TESTUSER2").Return(nil)
 		dbMock.On("DeleteUser", "usr_TESTUSER3").Return(nil)
+		dbMock.On("APIKeys", mock.Anything).Return([]model.APIKey{}, nil)
 		dbMock.
 			On("SaveEvent", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
@@ -352,50 +374,132 @@ func TestDelete(t *testing.T) {
 	})
 }
 
-func setupAPIKeys(k string) {
+func setupAPIKeys() {
 	dbMock.ExpectedCalls = []*mock.Call{}
 	dbMock.On("UserByID", "usr_TESTUSER1").Return(&u1, nil)
 	dbMock.On("UserByID", "usr_TESTUSER2").Return(&u2, nil)
 	dbMock.On("UserByID", "usr_TESTUSER3").Return(&u3, nil)
 	dbMock.On("UserByID", mock.Anything).Return(nil, db.ErrUserNotFound)
-	dbMock.On("SaveHashedAPIKey", mock.Anything, mock.Anything, "usr_TESTUSER1").Return(nil)
-	dbMock.On("SaveHashedAPIKey", mock.Anything, mock.Anything, "usr_TESTUSER2").Return(nil)
-	dbMock.On("SaveHashedAPIKey", mock.Anything, mock.Anything, "usr_TESTUSER3").Return(nil)
-	dbMock.On("SaveHashedAPIKey", mock.Anything, mock.Anything, "usr_TESTUSER4").Return( db.ErrUserNotFound)
-	dbMock.On("DeleteAPIKey", mock.Anything).Return(nil)
+	replaced := "oldkey"
+	dbMock.On("SaveAPIKey", mock.MatchedBy(func(k *model.APIKey) bool {
+		return k.UserID == "usr_TESTUSER1" && k.Name == model.DefaultAPIKeyName
+	})).Return(&replaced, nil)
+	dbMock.On("SaveAPIKey", mock.MatchedBy(func(k *model.APIKey) bool {
+		return k.UserID == "usr_TESTUSER4"
+	})).Return(nil, db.ErrUserNotFound)
+	dbMock.On("SaveAPIKey", mock.Anything).Return(nil, nil)
+	dbMock.On("APIKeys", "usr_TESTUSER1").Return([]model.APIKey{k1, k2}, nil)
+	dbMock.On("DeleteAPIKey", "usr_TESTUSER1", model.DefaultAPIKeyName).Return(k1.Key, nil)
+	dbMock.On("DeleteAPIKey", "usr_TESTUSER1", "ci").Return(k2.Key, nil)
+	dbMock.On("DeleteAPIKey", mock.Anything, mock.Anything).Return("", db.ErrAPIKeyNotFound)
+	dbMock.On("APIKeyByKey", k1.Key).Return(&k1, nil)
+	dbMock.On("APIKeyByKey", k2.Key).Return(&k2, nil)
+	dbMock.On("APIKeyByKey", mock.Anything).Return(nil, db.ErrAPIKeyNotFound)
+	dbMock.On("TouchAPIKey", mock.Anything, mock.Anything).Return(nil)
 	dbMock.
 		On("SaveEvent", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 }
 
 func TestAPIKeys(t *testing.T) {
 	RunWithServer(t, func(e *httpexpect.Expect) {
-		setupAPIKeys("abcdef")
+		setupAPIKeys()
 
 		// Create an API key.
 		e.POST("/me/api-key").WithHeaders(sess).
 			Expect().
 			Status(http.StatusOK).
 			JSON().Object().
-			ContainsKey("api_key").Value("api_key").String()
+			ContainsKey("api_secret").
+			ValueEqual("name", "default").
+			ContainsKey("api_key").Value("api_key").String().Length().Equal(32)
 
-		setupAPIKeys("123456")
-
-		// Rotate API key.
+		// Create a named API key with an expiry time.
+		expiry := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
 		e.POST("/me/api-key").WithHeaders(sess).
+			WithJSON(map[string]interface{}{"name": "ci", "expires_at": expiry}).
 			Expect().
 			Status(http.StatusOK).
 			JSON().Object().
-			ContainsKey("api_key").Value("api_key").String()
+			ValueEqual("name", "ci").
+			ContainsKey("expires_at")
+
+		// Invalid names and expiry times.
+		e.POST("/me/api-key").WithHeaders(sess).
+			WithJSON(map[string]interface{}{"name": "bad name!"}).
+			Expect().
+			Status(http.StatusBadRequest)
+		e.POST("/me/api-key").WithHeaders(sess).
+			WithJSON(map[string]interface{}{"expires_at": time.Now().Add(-time.Hour)}).
+			Expect().
+			Status(http.StatusBadRequest)
 
-		// Delete API key.
+		// List API keys: secret hashes are never returned.
+		keys := e.GET("/me/api-keys").WithHeaders(sess).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Array()
+		keys.Length().Equal(2)
+		keys.Element(0).Object().
+			ValueEqual("name", "default").
+			NotContainsKey("secret_hash")
+
+		// Delete API keys.
 		e.DELETE("/me/api-key").WithHeaders(sess).
 			Expect().
 			Status(http.StatusNoContent)
+		e.DELETE("/me/api-key/ci").WithHeaders(sess).
+			Expect().
+			Status(http.StatusNoContent)
+		e.DELETE("/me/api-key/unknown").WithHeaders(sess).
+			Expect().
+			Status(http.StatusNotFound)
 
 		// Admin + unknown user => not found.
 		e.POST("/user/usr_TESTUSER4/api-key").WithHeaders(sessAdmin).
 			Expect().
 			Status(http.StatusNotFound)
+
+		// Other user => not found.
+		e.GET("/user/usr_TESTUSER1/api-keys").WithHeaders(sess3).
+			Expect().
+			Status(http.StatusOK)
+		e.GET("/user/usr_TESTUSER2/api-keys").WithHeaders(sess).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+}
+
+func TestVerifyAPIKey(t *testing.T) {
+	RunWithServer(t, func(e *httpexpect.Expect) {
+		setupAPIKeys()
+
+		// Valid key and secret.
+		e.POST("/internal/api-key/verify").
+			WithJSON(map[string]string{"api_key": k1.Key, "api_secret": k1Secret}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			ValueEqual("user_id", "usr_TESTUSER1").
+			ContainsKey("is_admin")
+		dbMock.AssertCalled(t, "TouchAPIKey", k1.Key, mock.Anything)
+
+		// Wrong secret, unknown key, expired key, missing secret.
+		e.POST("/internal/api-key/verify").
+			WithJSON(map[string]string{"api_key": k1.Key, "api_secret": "wrong"}).
+			Expect().
+			Status(http.StatusNotFound)
+		e.POST("/internal/api-key/verify").
+			WithJSON(map[string]string{"api_key": "unknown", "api_secret": k1Secret}).
+			Expect().
+			Status(http.StatusNotFound)
+		e.POST("/internal/api-key/verify").
+			WithJSON(map[string]string{"api_key": k2.Key, "api_secret": k1Secret}).
+			Expect().
+			Status(http.StatusNotFound)
+		e.POST("/internal/api-key/verify").
+			WithJSON(map[string]string{"api_key": k1.Key}).
+			Expect().
+			Status(http.StatusBadRequest)
 	})
 }
 
@@ -26,14 +26,17 @@ func (s *Server) routes() chi.Router {
 		r.Get("/", chassis.SimpleHandler(s.detail))
 		r.Patch("/", chassis.SimpleHandler(s.update))
 		r.Delete("/", chassis.SimpleHandler(s.delete))
+		r.Get("/api-keys", chassis.SimpleHandler(s.apiKeys))
 		r.Post("/api-key", chassis.SimpleHandler(s.createAPIKey))
 		r.Delete("/api-key", chassis.SimpleHandler(s.deleteAPIKey))
+		r.Delete("/api-key/{name:[a-zA-Z0-9_-]+}", chassis.SimpleHandler(s.deleteAPIKey))
 		r.Get("/orgs", chassis.SimpleHandler(s.userOrgs))
 
 		r.Get("/payout-account", chassis.SimpleHandler(s.getUserPayoutAccount))
 		r.Post("/payout-account", chassis.SimpleHandler(s.createPayoutAccount))
 		r.Delete("/payout-account", chassis.SimpleHandler(s.deleteUserPayoutAccount))
 		r.Patch("/payout-account", chassis.SimpleHandler(s.updateUserPayoutAccount))
+		r.Post("/payout-account/onboarding", chassis.SimpleHandler(s.userPayoutOnboarding))
 
 		r.Get("/payment-methods", chassis.SimpleHandler(s.getPaymentMethods))
 		r.Get("/payment-method/default", chassis.SimpleHandler(s.getDefaultPaymentMethod))
@@ -57,6 +60,10 @@ func (s *Server) routes() chi.Router {
 		r.Post("/delivery-fees", chassis.SimpleHandler(s.createDeliveryFees))
 		r.Delete("/delivery-fees", chassis.SimpleHandler(s.deleteUserDeliveryFees))
 		r.Patch("/delivery-fees", chassis.SimpleHandler(s.updateUserDeliveryFees))
+
+		r.Get("/tax-settings", chassis.SimpleHandler(s.getUserTaxSettings))
+		r.Put("/tax-settings", chassis.SimpleHandler(s.putUserTaxSettings))
+		r.Delete("/tax-settings", chassis.SimpleHandler(s.deleteUserTaxSettings))
 	})
 
 	// Routes for other user (administrator only apart from GET
@@ -65,8 +72,10 @@ func (s *Server) routes() chi.Router {
 		r.Get("/", chassis.SimpleHandler(s.detail))
 		r.Patch("/", chassis.SimpleHandler(s.update))
 		r.Delete("/", chassis.SimpleHandler(s.delete))
+		r.Get("/api-keys", chassis.SimpleHandler(s.apiKeys))
 		r.Post("/api-key", chassis.SimpleHandler(s.createAPIKey))
 		r.Delete("/api-key", chassis.SimpleHandler(s.deleteAPIKey))
+		r.Delete("/api-key/{name:[a-zA-Z0-9_-]+}", chassis.SimpleHandler(s.deleteAPIKey))
 		r.Get("/orgs", chassis.SimpleHandler(s.userOrgs))
 	})
 
@@ -88,12 +97,17 @@ func (s *Server) routes() chi.Router {
 		r.Post("/payout-account", chassis.SimpleHandler(s.createPayoutAccount))
 		r.Delete("/payout-account", chassis.SimpleHandler(s.deleteOrgPayoutAccount))
 		r.Patch("/payout-account", chassis.SimpleHandler(s.updateOrgPayoutAccount))
+		r.Post("/payout-account/onboarding", chassis.SimpleHandler(s.orgPayoutOnboarding))
 
 		r.Get("/delivery-fees", chassis.SimpleHandler(s.getOrgDeliveryFees))
 		r.Post("/delivery-fees", chassis.SimpleHandler(s.createDeliveryFees))
 		r.Delete("/delivery-fees", chassis.SimpleHandler(s.deleteOrgDeliveryFees))
 		r.Patch("/delivery-fees", chassis.SimpleHandler(s.updateOrgDeliveryFees))
 
+		r.Get("/tax-settings", chassis.SimpleHandler(s.getOrgTaxSettings))
+		r.Put("/tax-settings", chassis.SimpleHandler(s.putOrgTaxSettings))
+		r.Delete("/tax-settings", chassis.SimpleHandler(s.deleteOrgTaxSettings))
+
 		// SSO work has been disabled as the Kube manifest on prod doesn't contain an ENCRYPTION_KEY value
 		// r.Get("/sso-secret", chassis.SimpleHandler(s.getSSOSecret))
 		// r.Post("/sso-secret", chassis.SimpleHandler(s.createSSOSecret))
@@ -120,8 +134,11 @@ func (s *Server) routes() chi.Router {
 	r.Get("/internal/user/{user_id:usr_[a-zA-Z0-9]+}/address/default", chassis.SimpleHandler(s.getAddressInternal))
 	r.Get("/internal/user/{user_id:usr_[a-zA-Z0-9]+}/payment-method/default", chassis.SimpleHandler(s.getDefaultPaymentMethodInternal))
 	r.Get("/internal/payout-account/{id:(usr|org)_[a-zA-Z0-9]+}", chassis.SimpleHandler(s.getPayoutInternal))
-	r.Get("/internal/api-key/{key}", chassis.SimpleHandler(s.getUserByApiKeyInternal))
+	r.Put("/internal/payout-account-status/{account}", chassis.SimpleHandler(s.updatePayoutStatusInternal))
+	r.Post("/internal/api-key/verify", chassis.SimpleHandler(s.verifyAPIKeyInternal))
 	r.Get("/internal/delivery-fees", chassis.SimpleHandler(s.getDeliveryFeesInternal))
+	r.Get("/internal/tax-settings", chassis.SimpleHandler(s.getTaxSettingsInternal))
+	r.Get("/internal/seller-details/{id:(usr|org)_[a-zA-Z0-9]+}", chassis.SimpleHandler(s.getSellerDetailsInternal))
 	// r.Get("/internal/org/{id_or_slug:[a-zA-Z0-9-_]+}/sso-secret", chassis.SimpleHandler(s.getSSOSecretInternal))
 
 	return r
@@ -20,6 +20,11 @@ type Server struct {
 	avatarGen  func() string
 	stripeKey  string
 	mapsClient *maps.Client
+
+	// Where the payment provider's onboarding flow sends sellers when
+	// they finish, or when their onboarding link has expired.
+	onboardingReturnURL  string
+	onboardingRefreshURL string
 	// Disabled encryption key, used for the SSO routes
 	// encryptionKey []byte
 }
@@ -37,6 +42,12 @@ type Config struct {
 	AvatarFormat string `env:"AVATAR_FORMAT"`
 	StripeKey    string `env:"STRIPE_KEY,required"`
 	MapsKey      string `env:"GOOGLE_API_KEY,required"`
+
+	// Front end pages that sellers return to from payout account
+	// onboarding: the refresh page should request a new onboarding
+	// link, since links can only be used once.
+	OnboardingReturnURL  string `env:"PAYOUT_ONBOARDING_RETURN_URL,default=https://veganbase.com/payout-account/done"`
+	OnboardingRefreshURL string `env:"PAYOUT_ONBOARDING_REFRESH_URL,default=https://veganbase.com/payout-account/refresh"`
 	// Disabled encryption key, used for the SSO routes
 	// EncryptionKey string `env:"ENCRYPTION_KEY,required"`
 }
@@ -50,16 +61,20 @@ func NewServer(cfg *Config) *Server {
 	s.Init(cfg.AppName, cfg.Project, cfg.Port, cfg.Credentials, s.routes())
 
 	s.stripeKey = cfg.StripeKey
+	s.onboardingReturnURL = cfg.OnboardingReturnURL
+	s.onboardingRefreshURL = cfg.OnboardingRefreshURL
 
 	// s.encryptionKey = []byte(cfg.EncryptionKey)
 
 	// Connect to user database.
 	timeout, _ := context.WithTimeout(context.Background(), time.Second*10)
 	var err error
-	s.db, err = db.NewPGClient(timeout, cfg.DBURL)
+	pg, err := db.NewPGClient(timeout, cfg.DBURL)
 	if err != nil {
 		log.Fatal().Err(err).Msg("couldn't connect to user database")
 	}
+	s.db = pg
+	s.StartEventRelay(pg.DB)
 
 	if cfg.MapsKey != "" {
 		if s.mapsClient, err = maps.NewClient(maps.WithAPIKey(cfg.MapsKey)); err != nil {